@@ -21,12 +21,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	sshv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1beta1 "github.com/crossplane/provider-ssh/apis/v1beta1"
 )
 
 func init() {
 	// Register the types with the Scheme so the components can map objects to GroupVersionKinds and back
 	AddToSchemes = append(AddToSchemes,
 		sshv1alpha1.SchemeBuilder.AddToScheme,
+		sshv1beta1.SchemeBuilder.AddToScheme,
 	)
 }
 
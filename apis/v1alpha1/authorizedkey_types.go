@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// AuthorizedKeyParameters are the configurable fields of an AuthorizedKey.
+type AuthorizedKeyParameters struct {
+	// User whose authorized_keys file holds the key. Defaults to the user
+	// of the ProviderConfig. Managing the keys of another user usually
+	// requires SudoEnabled.
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// Key is the public key in authorized_keys format, e.g.
+	// "ssh-ed25519 AAAA... alice@example.com".
+	Key string `json:"key"`
+
+	// Options restrict the key, e.g. `from="10.0.0.0/8"` or `no-pty`.
+	// +optional
+	Options []string `json:"options,omitempty"`
+
+	SudoEnabled bool `json:"sudoEnabled,omitempty"`
+}
+
+// AuthorizedKeyObservation are the observable fields of an AuthorizedKey.
+type AuthorizedKeyObservation struct {
+	// Path of the authorized_keys file holding the key.
+	Path string `json:"path,omitempty"`
+
+	// Fingerprint is the SHA-256 fingerprint of the key.
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// An AuthorizedKeySpec defines the desired state of an AuthorizedKey.
+type AuthorizedKeySpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       AuthorizedKeyParameters `json:"forProvider"`
+}
+
+// An AuthorizedKeyStatus represents the observed state of an AuthorizedKey.
+type AuthorizedKeyStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          AuthorizedKeyObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// An AuthorizedKey is a single entry of the authorized_keys file of a user on
+// a remote host.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="USER",type="string",JSONPath=".spec.forProvider.user"
+// +kubebuilder:printcolumn:name="FINGERPRINT",type="string",JSONPath=".status.atProvider.fingerprint",priority=1
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type AuthorizedKey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AuthorizedKeySpec   `json:"spec"`
+	Status AuthorizedKeyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AuthorizedKeyList contains a list of AuthorizedKey
+type AuthorizedKeyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AuthorizedKey `json:"items"`
+}
+
+// AuthorizedKey type metadata.
+var (
+	AuthorizedKeyKind             = reflect.TypeOf(AuthorizedKey{}).Name()
+	AuthorizedKeyGroupKind        = schema.GroupKind{Group: Group, Kind: AuthorizedKeyKind}.String()
+	AuthorizedKeyKindAPIVersion   = AuthorizedKeyKind + "." + SchemeGroupVersion.String()
+	AuthorizedKeyGroupVersionKind = SchemeGroupVersion.WithKind(AuthorizedKeyKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&AuthorizedKey{}, &AuthorizedKeyList{})
+}
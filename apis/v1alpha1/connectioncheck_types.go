@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// An Endpoint is the address and port of an SSH server.
+type Endpoint struct {
+	// Host is the IP address or DNS name of the SSH server.
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^([A-Za-z0-9]([-A-Za-z0-9]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([-A-Za-z0-9]{0,61}[A-Za-z0-9])?)*\.?|[0-9A-Fa-f.]*:[0-9A-Fa-f:.]*)$`
+	Host string `json:"host"`
+
+	// Port of the SSH server.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=22
+	// +optional
+	Port *int `json:"port,omitempty"`
+}
+
+// ConnectionCheckParameters are the configurable fields of a ConnectionCheck.
+type ConnectionCheckParameters struct {
+	// Endpoint overrides the host of the ProviderConfig. The credentials of
+	// the ProviderConfig are used to authenticate.
+	// +optional
+	Endpoint *Endpoint `json:"endpoint,omitempty"`
+
+	// IntervalSeconds is the interval of the checks. Defaults to the poll
+	// interval of the provider.
+	// +kubebuilder:validation:Minimum=10
+	// +optional
+	IntervalSeconds *int64 `json:"intervalSeconds,omitempty"`
+}
+
+// ConnectionCheckObservation are the observable fields of a ConnectionCheck.
+type ConnectionCheckObservation struct {
+	// Reachable reports whether the last check connected and authenticated
+	// to the host.
+	Reachable bool `json:"reachable"`
+
+	// LatencyMilliseconds is the time it took the last successful check to
+	// connect and authenticate.
+	// +optional
+	LatencyMilliseconds int64 `json:"latencyMilliseconds,omitempty"`
+
+	// LastCheckTime is the time of the last check.
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// LastSuccessTime is the time of the last successful check.
+	// +optional
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+
+	// ConsecutiveFailures is the number of checks that failed since the last
+	// successful check.
+	// +optional
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+
+	// Message describes why the last check failed, if it did.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// A ConnectionCheckSpec defines the desired state of a ConnectionCheck.
+type ConnectionCheckSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       ConnectionCheckParameters `json:"forProvider"`
+}
+
+// A ConnectionCheckStatus represents the observed state of a ConnectionCheck.
+type ConnectionCheckStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          ConnectionCheckObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A ConnectionCheck periodically verifies that the host of a ProviderConfig
+// is reachable and accepts its credentials, without running any script.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="LATENCY-MS",type="integer",JSONPath=".status.atProvider.latencyMilliseconds"
+// +kubebuilder:printcolumn:name="LAST-SUCCESS",type="date",JSONPath=".status.atProvider.lastSuccessTime"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type ConnectionCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ConnectionCheckSpec   `json:"spec"`
+	Status ConnectionCheckStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ConnectionCheckList contains a list of ConnectionCheck
+type ConnectionCheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ConnectionCheck `json:"items"`
+}
+
+// ConnectionCheck type metadata.
+var (
+	ConnectionCheckKind             = reflect.TypeOf(ConnectionCheck{}).Name()
+	ConnectionCheckGroupKind        = schema.GroupKind{Group: Group, Kind: ConnectionCheckKind}.String()
+	ConnectionCheckKindAPIVersion   = ConnectionCheckKind + "." + SchemeGroupVersion.String()
+	ConnectionCheckGroupVersionKind = SchemeGroupVersion.WithKind(ConnectionCheckKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&ConnectionCheck{}, &ConnectionCheckList{})
+}
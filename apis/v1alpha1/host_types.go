@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// A HostSpec defines an entry of the host inventory.
+type HostSpec struct {
+	// Address of the host, as hostname or IP address. It replaces the
+	// hostIP of the credentials.
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^([A-Za-z0-9]([-A-Za-z0-9]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([-A-Za-z0-9]{0,61}[A-Za-z0-9])?)*\.?|[0-9A-Fa-f.]*:[0-9A-Fa-f:.]*)$`
+	Address string `json:"address"`
+
+	// Port of the SSH server of the host. It replaces the hostPort of the
+	// credentials.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=22
+	// +optional
+	Port *int `json:"port,omitempty"`
+
+	// Credentials used to log in to the host.
+	// +optional
+	Credentials *ProviderCredentials `json:"credentials,omitempty"`
+
+	// ProviderConfigRef references a ProviderConfig whose credentials are
+	// used to log in to the host if no credentials are set, so that many
+	// hosts can share one set of credentials.
+	// +optional
+	ProviderConfigRef *xpv1.Reference `json:"providerConfigRef,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A Host is an entry of the host inventory. Scripts select Hosts by label
+// through their hostSelector.
+// +kubebuilder:printcolumn:name="ADDRESS",type="string",JSONPath=".spec.address"
+// +kubebuilder:printcolumn:name="PORT",type="integer",JSONPath=".spec.port"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,ssh}
+type Host struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HostSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// HostList contains a list of Host.
+type HostList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Host `json:"items"`
+}
+
+// Host type metadata. The group kind of a Host is not declared, since its
+// name would collide with the kind of a HostGroup.
+var (
+	HostKind             = reflect.TypeOf(Host{}).Name()
+	HostKindAPIVersion   = HostKind + "." + SchemeGroupVersion.String()
+	HostGroupVersionKind = SchemeGroupVersion.WithKind(HostKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&Host{}, &HostList{})
+}
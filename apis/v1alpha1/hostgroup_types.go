@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// A HostGroupSpec defines the members of a HostGroup.
+type HostGroupSpec struct {
+	// Hosts lists the names of the member Hosts.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+
+	// HostSelector selects member Hosts by label in addition to the listed
+	// Hosts.
+	// +optional
+	HostSelector *metav1.LabelSelector `json:"hostSelector,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A HostGroup groups Hosts of the inventory, so that Scripts and ScriptSets
+// can target them together.
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,ssh}
+type HostGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HostGroupSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// HostGroupList contains a list of HostGroup.
+type HostGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HostGroup `json:"items"`
+}
+
+// A RolloutStrategy controls how scripts are rolled out across multiple
+// hosts. Hosts that need a script are executed in batches, one batch per
+// reconcile.
+type RolloutStrategy struct {
+	// BatchSize is the maximum number of hosts executed per batch. Defaults
+	// to all hosts.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BatchSize *int `json:"batchSize,omitempty"`
+
+	// MaxUnavailable is the number or percentage of hosts that may be not
+	// ready at the same time, including the hosts of the running batch.
+	// Defaults to all hosts.
+	// +kubebuilder:validation:XIntOrString
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// PauseOnFailure stops starting new batches while any host failed or is
+	// unreachable, until the host is ready again.
+	// +optional
+	PauseOnFailure bool `json:"pauseOnFailure,omitempty"`
+}
+
+// HostGroup type metadata.
+var (
+	HostGroupKind             = reflect.TypeOf(HostGroup{}).Name()
+	HostGroupGroupKind        = schema.GroupKind{Group: Group, Kind: HostGroupKind}.String()
+	HostGroupKindAPIVersion   = HostGroupKind + "." + SchemeGroupVersion.String()
+	HostGroupGroupVersionKind = SchemeGroupVersion.WithKind(HostGroupKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&HostGroup{}, &HostGroupList{})
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// HostKeyScanParameters are the configurable fields of a HostKeyScan.
+type HostKeyScanParameters struct {
+	// Host to scan, as hostname or IP address.
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^([A-Za-z0-9]([-A-Za-z0-9]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([-A-Za-z0-9]{0,61}[A-Za-z0-9])?)*\.?|[0-9A-Fa-f.]*:[0-9A-Fa-f:.]*)$`
+	Host string `json:"host"`
+
+	// Port of the SSH server of the host.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=22
+	// +optional
+	Port *int `json:"port,omitempty"`
+
+	// KeyTypes to scan for.
+	// +kubebuilder:default={ed25519,ecdsa,rsa}
+	// +optional
+	KeyTypes []HostKeyType `json:"keyTypes,omitempty"`
+}
+
+// A HostKeyType is a type of host key.
+// +kubebuilder:validation:Enum=ed25519;ecdsa;rsa
+type HostKeyType string
+
+// A ScannedHostKey is a host key discovered by a HostKeyScan.
+type ScannedHostKey struct {
+	// Type of the key, e.g. ssh-ed25519.
+	Type string `json:"type"`
+
+	// Fingerprint is the SHA-256 fingerprint of the key.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// HostKeyScanObservation are the observable fields of a HostKeyScan.
+type HostKeyScanObservation struct {
+	// Keys discovered by the last scan.
+	// +optional
+	Keys []ScannedHostKey `json:"keys,omitempty"`
+
+	// LastScanTime is the time the host was last scanned.
+	// +optional
+	LastScanTime *metav1.Time `json:"lastScanTime,omitempty"`
+}
+
+// A HostKeyScanSpec defines the desired state of a HostKeyScan.
+type HostKeyScanSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       HostKeyScanParameters `json:"forProvider"`
+}
+
+// A HostKeyScanStatus represents the observed state of a HostKeyScan.
+type HostKeyScanStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          HostKeyScanObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A HostKeyScan publishes the host keys of a remote host in known_hosts format.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="HOST",type="string",JSONPath=".spec.forProvider.host"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type HostKeyScan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HostKeyScanSpec   `json:"spec"`
+	Status HostKeyScanStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// HostKeyScanList contains a list of HostKeyScan
+type HostKeyScanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HostKeyScan `json:"items"`
+}
+
+// HostKeyScan type metadata.
+var (
+	HostKeyScanKind             = reflect.TypeOf(HostKeyScan{}).Name()
+	HostKeyScanGroupKind        = schema.GroupKind{Group: Group, Kind: HostKeyScanKind}.String()
+	HostKeyScanKindAPIVersion   = HostKeyScanKind + "." + SchemeGroupVersion.String()
+	HostKeyScanGroupVersionKind = SchemeGroupVersion.WithKind(HostKeyScanKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&HostKeyScan{}, &HostKeyScanList{})
+}
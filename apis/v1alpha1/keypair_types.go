@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// KeyInstallation configures the installation of the public key of a
+// KeyPair on the host of its ProviderConfig.
+type KeyInstallation struct {
+	// User whose authorized_keys file holds the key. Defaults to the user
+	// of the ProviderConfig.
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// Options restrict the key, e.g. `from="10.0.0.0/8"` or `no-pty`.
+	// +optional
+	Options []string `json:"options,omitempty"`
+
+	SudoEnabled bool `json:"sudoEnabled,omitempty"`
+}
+
+// KeyPairParameters are the configurable fields of a KeyPair.
+type KeyPairParameters struct {
+	// Type of the generated key.
+	// +kubebuilder:validation:Enum=ed25519;rsa
+	// +kubebuilder:default=ed25519
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Bits is the size of RSA keys.
+	// +kubebuilder:validation:Minimum=2048
+	// +kubebuilder:default=4096
+	// +optional
+	Bits *int `json:"bits,omitempty"`
+
+	// Comment of the public key.
+	// +optional
+	Comment string `json:"comment,omitempty"`
+
+	// Install the public key on the host of the ProviderConfig. The key is
+	// removed from the host when the KeyPair is deleted.
+	// +optional
+	Install *KeyInstallation `json:"install,omitempty"`
+}
+
+// KeyPairObservation are the observable fields of a KeyPair.
+type KeyPairObservation struct {
+	// PublicKey is the generated public key in authorized_keys format.
+	PublicKey string `json:"publicKey,omitempty"`
+
+	// Fingerprint is the SHA-256 fingerprint of the public key.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Installed reports whether the public key is installed on the host.
+	Installed bool `json:"installed,omitempty"`
+}
+
+// A KeyPairSpec defines the desired state of a KeyPair.
+type KeyPairSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       KeyPairParameters `json:"forProvider"`
+}
+
+// A KeyPairStatus represents the observed state of a KeyPair.
+type KeyPairStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          KeyPairObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A KeyPair is an SSH key pair stored in its connection secret.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="TYPE",type="string",JSONPath=".spec.forProvider.type"
+// +kubebuilder:printcolumn:name="FINGERPRINT",type="string",JSONPath=".status.atProvider.fingerprint",priority=1
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type KeyPair struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KeyPairSpec   `json:"spec"`
+	Status KeyPairStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KeyPairList contains a list of KeyPair
+type KeyPairList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KeyPair `json:"items"`
+}
+
+// KeyPair type metadata.
+var (
+	KeyPairKind             = reflect.TypeOf(KeyPair{}).Name()
+	KeyPairGroupKind        = schema.GroupKind{Group: Group, Kind: KeyPairKind}.String()
+	KeyPairKindAPIVersion   = KeyPairKind + "." + SchemeGroupVersion.String()
+	KeyPairGroupVersionKind = SchemeGroupVersion.WithKind(KeyPairKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&KeyPair{}, &KeyPairList{})
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// NodeScriptParameters are the configurable fields of a NodeScript.
+type NodeScriptParameters struct {
+	// NodeSelector selects the Nodes of the cluster the scripts are executed
+	// on. All Nodes are selected if it is not set.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+
+	// AddressType is the type of the Node address the provider connects to.
+	// +kubebuilder:validation:Enum=InternalIP;ExternalIP;Hostname
+	// +kubebuilder:default=InternalIP
+	// +optional
+	AddressType string `json:"addressType,omitempty"`
+
+	// Port of the SSH server of the Nodes.
+	// +kubebuilder:default=22
+	// +optional
+	Port *int `json:"port,omitempty"`
+
+	// +optional
+	Variables []Variable `json:"variables,omitempty"`
+
+	// StatusCheckScript observes the state of a Node. Exit code 0 reports an
+	// up to date Node, 100 a Node the initScript has not been executed on,
+	// and any other code a Node that needs the updateScript.
+	StatusCheckScript string `json:"statusCheckScript"`
+
+	// InitScript is executed on Nodes the statusCheckScript exits with 100
+	// on.
+	InitScript string `json:"initScript"`
+
+	// UpdateScript is executed on Nodes that are not up to date.
+	// +optional
+	UpdateScript string `json:"updateScript,omitempty"`
+
+	// CleanupScript is executed on every Node when the NodeScript is
+	// deleted.
+	// +optional
+	CleanupScript string `json:"cleanupScript,omitempty"`
+
+	// Rollout controls how the scripts are rolled out across the Nodes. By
+	// default all Nodes are executed at once.
+	// +optional
+	Rollout *RolloutStrategy `json:"rollout,omitempty"`
+
+	SudoEnabled bool `json:"sudoEnabled,omitempty"`
+}
+
+// NodeStatus is the observed state of a NodeScript on a single Node.
+type NodeStatus struct {
+	// Name of the Node.
+	Name string `json:"name"`
+
+	// Address of the Node the provider connected to.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// Ready reports whether the Node is up to date.
+	Ready bool `json:"ready"`
+
+	// StatusCode is the exit status code of the last script executed on the
+	// Node.
+	StatusCode int `json:"statusCode"`
+
+	// Message describes why the Node is not ready, if it is not.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// NodeScriptObservation are the observable fields of a NodeScript.
+type NodeScriptObservation struct {
+	// Nodes reports the state of each selected Node.
+	// +optional
+	Nodes []NodeStatus `json:"nodes,omitempty"`
+}
+
+// A NodeScriptSpec defines the desired state of a NodeScript.
+type NodeScriptSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       NodeScriptParameters `json:"forProvider"`
+}
+
+// A NodeScriptStatus represents the observed state of a NodeScript.
+type NodeScriptStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          NodeScriptObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A NodeScript executes scripts on the Nodes of the cluster, using the
+// credentials of its ProviderConfig shared by all Nodes.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type NodeScript struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeScriptSpec   `json:"spec"`
+	Status NodeScriptStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NodeScriptList contains a list of NodeScript
+type NodeScriptList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeScript `json:"items"`
+}
+
+// NodeScript type metadata.
+var (
+	NodeScriptKind             = reflect.TypeOf(NodeScript{}).Name()
+	NodeScriptGroupKind        = schema.GroupKind{Group: Group, Kind: NodeScriptKind}.String()
+	NodeScriptKindAPIVersion   = NodeScriptKind + "." + SchemeGroupVersion.String()
+	NodeScriptGroupVersionKind = SchemeGroupVersion.WithKind(NodeScriptKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&NodeScript{}, &NodeScriptList{})
+}
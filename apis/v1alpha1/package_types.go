@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// Package states.
+const (
+	PackageStatePresent = "present"
+	PackageStateAbsent  = "absent"
+	PackageStateLatest  = "latest"
+)
+
+// PackageParameters are the configurable fields of a Package.
+type PackageParameters struct {
+	// Name of the package.
+	Name string `json:"name"`
+
+	// Version of the package when the state is present. A version ending
+	// in '*' matches all versions with this prefix, e.g. "1.24.*". Any
+	// installed version satisfies an empty version.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// State of the package: present installs the package, latest keeps it
+	// at the latest available version and absent removes it.
+	// +kubebuilder:validation:Enum=present;absent;latest
+	// +kubebuilder:default=present
+	// +optional
+	State string `json:"state,omitempty"`
+
+	// Manager is the package manager of the host. It is detected if not
+	// set.
+	// +kubebuilder:validation:Enum=apt;dnf;zypper;apk
+	// +optional
+	Manager string `json:"manager,omitempty"`
+
+	SudoEnabled bool `json:"sudoEnabled,omitempty"`
+}
+
+// PackageObservation are the observable fields of a Package.
+type PackageObservation struct {
+	// Manager is the package manager used for the package.
+	Manager string `json:"manager,omitempty"`
+
+	// Version is the installed version of the package.
+	Version string `json:"version,omitempty"`
+
+	// LatestVersion is the latest version available to the host. It is
+	// only observed when the state is latest.
+	LatestVersion string `json:"latestVersion,omitempty"`
+}
+
+// A PackageSpec defines the desired state of a Package.
+type PackageSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       PackageParameters `json:"forProvider"`
+}
+
+// A PackageStatus represents the observed state of a Package.
+type PackageStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          PackageObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A Package is an operating system package on a remote host.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="PACKAGE",type="string",JSONPath=".spec.forProvider.name"
+// +kubebuilder:printcolumn:name="VERSION",type="string",JSONPath=".status.atProvider.version"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type Package struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PackageSpec   `json:"spec"`
+	Status PackageStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PackageList contains a list of Package
+type PackageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Package `json:"items"`
+}
+
+// Package type metadata.
+var (
+	PackageKind             = reflect.TypeOf(Package{}).Name()
+	PackageGroupKind        = schema.GroupKind{Group: Group, Kind: PackageKind}.String()
+	PackageKindAPIVersion   = PackageKind + "." + SchemeGroupVersion.String()
+	PackageGroupVersionKind = SchemeGroupVersion.WithKind(PackageKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&Package{}, &PackageList{})
+}
@@ -26,18 +26,281 @@ import (
 )
 
 // A ProviderConfigSpec defines the desired state of a ProviderConfig.
+// +kubebuilder:validation:XValidation:rule="!has(self.teleport) || !has(self.webSocket)",message="teleport and webSocket are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.teleport) || !has(self.keyProvisioner)",message="teleport and keyProvisioner are mutually exclusive"
 type ProviderConfigSpec struct {
+	// Alias is a human-friendly name of the host, such as paris-edge-01,
+	// identifying it in the log lines and Events of the provider instead of
+	// its address.
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	Alias string `json:"alias,omitempty"`
+
 	// Credentials required to authenticate to this provider.
 	Credentials ProviderCredentials `json:"credentials"`
+
+	// Service resolves the host and port of the credentials from the ready
+	// endpoints of a Kubernetes Service every time the provider connects,
+	// instead of a hard-coded address that may go stale. The first ready
+	// address, in lexical order, is connected to.
+	// +optional
+	Service *ServiceEndpoints `json:"service,omitempty"`
+
+	// Transfer configures the file transfers to the remote host, such as the
+	// uploads of scripts and files.
+	// +optional
+	Transfer *TransferSettings `json:"transfer,omitempty"`
+
+	// Teleport connects to the host through a Teleport proxy instead of
+	// directly.
+	// +optional
+	Teleport *TeleportSettings `json:"teleport,omitempty"`
+
+	// WebSocket connects to the host through a WebSocket gateway instead of
+	// directly, for hosts only reachable over HTTP(S).
+	// +optional
+	WebSocket *WebSocketSettings `json:"webSocket,omitempty"`
+
+	// KeyProvisioner pushes an ephemeral public key to the host before every
+	// connection, which authenticates with it instead of the privateKey or
+	// password of the credentials.
+	// +optional
+	KeyProvisioner *KeyProvisionerSettings `json:"keyProvisioner,omitempty"`
+
+	// AllowedNamespaces restricts the use of the ProviderConfig to the
+	// managed resources of the claims of these namespaces, identified by
+	// their crossplane.io/claim-namespace label. Every managed resource may
+	// use the ProviderConfig if it is empty.
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
+	// AllowedResourceSelector restricts the use of the ProviderConfig to
+	// the managed resources whose labels it selects, on top of the
+	// allowedNamespaces. Every managed resource may use the ProviderConfig
+	// if it is not set.
+	// +optional
+	AllowedResourceSelector *metav1.LabelSelector `json:"allowedResourceSelector,omitempty"`
+
+	// MaxManagedResources bounds the number of managed resources using the
+	// ProviderConfig through their providerConfigRef, so that a shared host
+	// is not targeted by an unbounded number of resources. Resources beyond
+	// the limit are rejected at admission and never connect to the host.
+	// The number of resources is not bounded if it is not set.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxManagedResources *int64 `json:"maxManagedResources,omitempty"`
+
+	// Defaults are the execution settings of the Scripts using the
+	// ProviderConfig that do not set them, so that the conventions of a host
+	// are configured in one place.
+	// +optional
+	Defaults *ScriptDefaults `json:"defaults,omitempty"`
+}
+
+// ServiceEndpoints resolve the SSH server of the remote host from the
+// EndpointSlices of a Kubernetes Service every time the provider connects,
+// such as a headless Service or one with external Endpoints.
+type ServiceEndpoints struct {
+	// Name of the Service.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the Service.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// Port is the name of the port of the EndpointSlices of the Service to
+	// connect to, or its number. The only port of the EndpointSlices is
+	// used if it is empty, and port 22 if they have none.
+	// +optional
+	Port string `json:"port,omitempty"`
+}
+
+// TeleportSettings connect to the host through a Teleport proxy, which
+// brokers the SSH access to the nodes of a Teleport cluster.
+type TeleportSettings struct {
+	// ProxyAddress is the address of the SSH endpoint of the Teleport proxy,
+	// such as teleport.example.com:3023.
+	// +kubebuilder:validation:MinLength=1
+	ProxyAddress string `json:"proxyAddress"`
+
+	// Cluster is the name of the leaf cluster of the host. The host is a
+	// node of the cluster of the proxy if it is empty.
+	// +optional
+	Cluster string `json:"cluster,omitempty"`
+
+	// Identity is an identity file of the Teleport user, as written by
+	// tctl auth sign or tbot, holding the private key, the SSH certificate
+	// and the host certificate authorities of the cluster. It replaces the
+	// privateKey, password and knownHosts of the credentials.
+	Identity CredentialValue `json:"identity"`
+}
+
+// WebSocketSettings connect to the host through a WebSocket gateway, which
+// carries the SSH stream in binary messages, such as cloudflared or
+// websockify.
+type WebSocketSettings struct {
+	// URL of the gateway, which chooses the host the stream is forwarded
+	// to, such as wss://ssh.example.com.
+	// +kubebuilder:validation:Pattern=`^wss?://`
+	URL string `json:"url"`
+
+	// Token authenticates to the gateway. It is sent as a bearer token in
+	// the Authorization header, or as it is in the tokenHeader. No token is
+	// sent if it is not set.
+	// +optional
+	Token *CredentialValue `json:"token,omitempty"`
+
+	// TokenHeader is the HTTP header carrying the token instead of the
+	// Authorization header, such as cf-access-token for Cloudflare Access.
+	// +optional
+	TokenHeader string `json:"tokenHeader,omitempty"`
+}
+
+// KeyProvisionerSettings push an ephemeral public key to the host before
+// every connection, for hosts without static authorized_keys entries.
+type KeyProvisionerSettings struct {
+	// Webhook provisions the keys through a user-supplied webhook.
+	Webhook WebhookKeyProvisioner `json:"webhook"`
+}
+
+// A WebhookKeyProvisioner is POSTed the host, port, username, alias and
+// publicKey of every connection as JSON, and answers with a 2xx status once
+// the key can log in, e.g. after calling the SendSSHPublicKey API of EC2
+// Instance Connect.
+type WebhookKeyProvisioner struct {
+	// URL of the webhook.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Token is sent as a bearer token to the webhook. No token is sent if it
+	// is not set.
+	// +optional
+	Token *CredentialValue `json:"token,omitempty"`
 }
 
-// ProviderCredentials required to authenticate.
+// ScriptDefaults are the execution settings of the Scripts using a
+// ProviderConfig that do not set them. The settings of a Script take
+// precedence.
+type ScriptDefaults struct {
+	// SudoEnabled executes the scripts with sudo.
+	// +optional
+	SudoEnabled *bool `json:"sudoEnabled,omitempty"`
+
+	// Interpreter executes the scripts that do not start with a #! line.
+	// +optional
+	Interpreter string `json:"interpreter,omitempty"`
+
+	// RemoteTempDir is the directory of the host the scripts are uploaded to
+	// before they are executed.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	RemoteTempDir string `json:"remoteTempDir,omitempty"`
+
+	// TimeoutSeconds is the time after which a script is killed.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=600
+	// +optional
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+
+	// MaxOutputBytes is the number of bytes of the stdout and stderr of a
+	// script recorded in the status.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxOutputBytes *int64 `json:"maxOutputBytes,omitempty"`
+
+	// RecordSession records a transcript of every execution of the scripts
+	// to the session recording sink of the provider.
+	// +optional
+	RecordSession *bool `json:"recordSession,omitempty"`
+}
+
+// LabelKeyClaimNamespace is the label Crossplane sets on the managed
+// resources composed for a claim, holding the namespace of the claim.
+const LabelKeyClaimNamespace = "crossplane.io/claim-namespace"
+
+// TransferCompression is the compression of the uploads to a remote host.
+type TransferCompression string
+
+// Compressions of the uploads to a remote host.
+const (
+	// TransferCompressionNone uploads the content as it is.
+	TransferCompressionNone TransferCompression = "None"
+
+	// TransferCompressionGzip uploads the content compressed by gzip, and
+	// decompresses it on the host, which must have gzip installed. Uploads
+	// fall back to uncompressed transfers if it fails.
+	TransferCompressionGzip TransferCompression = "Gzip"
+)
+
+// TransferSettings configure the file transfers to a remote host.
+type TransferSettings struct {
+	// Compression of the uploads larger than 16 KiB, None by default.
+	// +kubebuilder:validation:Enum=None;Gzip
+	// +optional
+	Compression TransferCompression `json:"compression,omitempty"`
+
+	// BandwidthLimitKB bounds the rate of the file transfers over SFTP, in
+	// KiB per second in each direction, so that large transfers do not
+	// saturate thin links shared with production traffic. The limit is
+	// shared by the connections of the ProviderConfig. Transfers are not
+	// limited by default.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BandwidthLimitKB *int64 `json:"bandwidthLimitKB,omitempty"`
+}
+
+// ProviderCredentials required to authenticate. The source holds the JSON
+// encoded credentials, whose fields can be overridden from separate sources.
+// Use source None to assemble the credentials from the separate sources only.
 type ProviderCredentials struct {
 	// Source of the provider credentials.
 	// +kubebuilder:validation:Enum=None;Secret;InjectedIdentity;Environment;Filesystem
 	Source xpv1.CredentialsSource `json:"source"`
 
 	xpv1.CommonCredentialSelectors `json:",inline"`
+
+	// HostIP is the address of the remote host.
+	// +optional
+	HostIP *CredentialValue `json:"hostIP,omitempty"`
+
+	// HostPort is the port of the SSH server of the remote host, a number
+	// between 1 and 65535.
+	// +optional
+	HostPort *CredentialValue `json:"hostPort,omitempty"`
+
+	// Username of the remote user.
+	// +optional
+	Username *CredentialValue `json:"username,omitempty"`
+
+	// Password of the remote user.
+	// +optional
+	Password *CredentialValue `json:"password,omitempty"`
+
+	// PrivateKey is the PEM encoded private key of the remote user. Unlike
+	// the privateKey field of the JSON encoded credentials, it is not base64
+	// encoded, so mounted key files can be used as they are.
+	// +optional
+	PrivateKey *CredentialValue `json:"privateKey,omitempty"`
+
+	// KnownHosts are the known_hosts entries of the remote host.
+	// +optional
+	KnownHosts *CredentialValue `json:"knownHosts,omitempty"`
+}
+
+// A CredentialValue is a single field of the credentials, either set
+// literally or read from a Secret, an environment variable or a file.
+type CredentialValue struct {
+	// Value of the field.
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// Source of the value, if it is not set literally.
+	// +kubebuilder:validation:Enum=Secret;Environment;Filesystem
+	// +optional
+	Source xpv1.CredentialsSource `json:"source,omitempty"`
+
+	xpv1.CommonCredentialSelectors `json:",inline"`
 }
 
 // A ProviderConfigStatus reflects the observed state of a ProviderConfig.
@@ -48,6 +311,7 @@ type ProviderConfigStatus struct {
 // +kubebuilder:object:root=true
 
 // A ProviderConfig configures a SSH provider.
+// +kubebuilder:storageversion
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:printcolumn:name="SECRET-NAME",type="string",JSONPath=".spec.credentials.secretRef.name",priority=1
@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// A ConfigMapReference references a ConfigMap.
+type ConfigMapReference struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap.
+	Namespace string `json:"namespace"`
+}
+
+// A TarballSource references a tarball served over HTTP.
+type TarballSource struct {
+	// URL of the tarball. Gzip compressed tarballs are detected by their
+	// content.
+	URL string `json:"url"`
+
+	// SHA256 is the expected hex encoded SHA-256 hash of the tarball.
+	// +optional
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// A DirectorySource is the source of the files of a RemoteDirectory. Exactly
+// one of its fields must be set.
+type DirectorySource struct {
+	// ConfigMapRef references a ConfigMap whose keys are the names of the
+	// files and whose values are their content.
+	// +optional
+	ConfigMapRef *ConfigMapReference `json:"configMapRef,omitempty"`
+
+	// Tarball references a tarball whose regular files are synced.
+	// +optional
+	Tarball *TarballSource `json:"tarball,omitempty"`
+}
+
+// RemoteDirectoryParameters are the configurable fields of a RemoteDirectory.
+type RemoteDirectoryParameters struct {
+	// Path of the directory on the remote host.
+	Path string `json:"path"`
+
+	// Source of the files of the directory.
+	Source DirectorySource `json:"source"`
+
+	// FileMode of the synced files in octal notation.
+	// +kubebuilder:validation:Pattern=`^0?[0-7]{3}$`
+	// +kubebuilder:default="0644"
+	// +optional
+	FileMode string `json:"fileMode,omitempty"`
+
+	// Prune removes files below the directory that are not part of the
+	// source.
+	// +kubebuilder:default=true
+	// +optional
+	Prune *bool `json:"prune,omitempty"`
+}
+
+// RemoteDirectoryObservation are the observable fields of a RemoteDirectory.
+type RemoteDirectoryObservation struct {
+	// Files is the number of regular files below the remote directory.
+	Files int `json:"files,omitempty"`
+
+	// OutOfSync lists the files that differ from the source, are missing or
+	// are to be pruned.
+	// +optional
+	OutOfSync []string `json:"outOfSync,omitempty"`
+}
+
+// A RemoteDirectorySpec defines the desired state of a RemoteDirectory.
+type RemoteDirectorySpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       RemoteDirectoryParameters `json:"forProvider"`
+}
+
+// A RemoteDirectoryStatus represents the observed state of a RemoteDirectory.
+type RemoteDirectoryStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          RemoteDirectoryObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A RemoteDirectory syncs a tree of files to a remote directory over SFTP.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="PATH",type="string",JSONPath=".spec.forProvider.path"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type RemoteDirectory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RemoteDirectorySpec   `json:"spec"`
+	Status RemoteDirectoryStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RemoteDirectoryList contains a list of RemoteDirectory
+type RemoteDirectoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RemoteDirectory `json:"items"`
+}
+
+// RemoteDirectory type metadata.
+var (
+	RemoteDirectoryKind             = reflect.TypeOf(RemoteDirectory{}).Name()
+	RemoteDirectoryGroupKind        = schema.GroupKind{Group: Group, Kind: RemoteDirectoryKind}.String()
+	RemoteDirectoryKindAPIVersion   = RemoteDirectoryKind + "." + SchemeGroupVersion.String()
+	RemoteDirectoryGroupVersionKind = SchemeGroupVersion.WithKind(RemoteDirectoryKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&RemoteDirectory{}, &RemoteDirectoryList{})
+}
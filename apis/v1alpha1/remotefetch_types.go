@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// A FetchedFile is a remote file published as a connection detail.
+type FetchedFile struct {
+	// Path of the file on the remote host.
+	Path string `json:"path"`
+
+	// Key of the connection detail the content of the file is published
+	// under.
+	Key string `json:"key"`
+}
+
+// RemoteFetchParameters are the configurable fields of a RemoteFetch.
+type RemoteFetchParameters struct {
+	// Files to download from the remote host.
+	// +kubebuilder:validation:MinItems=1
+	Files []FetchedFile `json:"files"`
+
+	// MaxSizeBytes is the maximum size of each downloaded file.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1048576
+	// +optional
+	MaxSizeBytes *int64 `json:"maxSizeBytes,omitempty"`
+}
+
+// A FetchedFileStatus is the observed state of a downloaded file.
+type FetchedFileStatus struct {
+	// Path of the file on the remote host.
+	Path string `json:"path"`
+
+	// Checksum is the SHA-256 hash of the downloaded content.
+	Checksum string `json:"checksum"`
+}
+
+// RemoteFetchObservation are the observable fields of a RemoteFetch.
+type RemoteFetchObservation struct {
+	// Files that were downloaded by the last observation.
+	// +optional
+	Files []FetchedFileStatus `json:"files,omitempty"`
+
+	// LastFetchTime is the time the files were last downloaded.
+	// +optional
+	LastFetchTime *metav1.Time `json:"lastFetchTime,omitempty"`
+}
+
+// A RemoteFetchSpec defines the desired state of a RemoteFetch.
+type RemoteFetchSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       RemoteFetchParameters `json:"forProvider"`
+}
+
+// A RemoteFetchStatus represents the observed state of a RemoteFetch.
+type RemoteFetchStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          RemoteFetchObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A RemoteFetch periodically downloads files from a remote host and publishes
+// them as connection details.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="LAST-FETCH",type="date",JSONPath=".status.atProvider.lastFetchTime"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type RemoteFetch struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RemoteFetchSpec   `json:"spec"`
+	Status RemoteFetchStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RemoteFetchList contains a list of RemoteFetch
+type RemoteFetchList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RemoteFetch `json:"items"`
+}
+
+// RemoteFetch type metadata.
+var (
+	RemoteFetchKind             = reflect.TypeOf(RemoteFetch{}).Name()
+	RemoteFetchGroupKind        = schema.GroupKind{Group: Group, Kind: RemoteFetchKind}.String()
+	RemoteFetchKindAPIVersion   = RemoteFetchKind + "." + SchemeGroupVersion.String()
+	RemoteFetchGroupVersionKind = SchemeGroupVersion.WithKind(RemoteFetchKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&RemoteFetch{}, &RemoteFetchList{})
+}
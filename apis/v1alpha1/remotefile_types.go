@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// A ConfigMapKeySelector references a key of a ConfigMap.
+type ConfigMapKeySelector struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap.
+	Namespace string `json:"namespace"`
+
+	// Key of the ConfigMap.
+	Key string `json:"key"`
+}
+
+// A ContentSource references the content of a file stored in the cluster.
+type ContentSource struct {
+	// ConfigMapKeyRef references the content in a key of a ConfigMap.
+	// +optional
+	ConfigMapKeyRef *ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// SecretKeyRef references the content in a key of a Secret.
+	// +optional
+	SecretKeyRef *xpv1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// RemoteFileParameters are the configurable fields of a RemoteFile.
+type RemoteFileParameters struct {
+	// Path of the file on the remote host.
+	Path string `json:"path"`
+
+	// Content of the file.
+	// +optional
+	Content *string `json:"content,omitempty"`
+
+	// ContentFrom references the content of the file, if Content is not set.
+	// +optional
+	ContentFrom *ContentSource `json:"contentFrom,omitempty"`
+
+	// Mode of the file in octal notation.
+	// +kubebuilder:validation:Pattern=`^0?[0-7]{3}$`
+	// +kubebuilder:default="0644"
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// Owner of the file, as a user name or id. Defaults to the user of the
+	// ssh connection.
+	// +optional
+	Owner string `json:"owner,omitempty"`
+
+	// Group of the file, as a group name or id. Defaults to the primary
+	// group of the user of the ssh connection.
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+// RemoteFileObservation are the observable fields of a RemoteFile.
+type RemoteFileObservation struct {
+	// Checksum is the SHA-256 hash of the content of the remote file.
+	Checksum string `json:"checksum,omitempty"`
+
+	// Mode of the remote file in octal notation.
+	Mode string `json:"mode,omitempty"`
+
+	// UID of the owner of the remote file.
+	UID *int `json:"uid,omitempty"`
+
+	// GID of the group of the remote file.
+	GID *int `json:"gid,omitempty"`
+}
+
+// A RemoteFileSpec defines the desired state of a RemoteFile.
+type RemoteFileSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       RemoteFileParameters `json:"forProvider"`
+}
+
+// A RemoteFileStatus represents the observed state of a RemoteFile.
+type RemoteFileStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          RemoteFileObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A RemoteFile manages a file on a remote host over SFTP.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="PATH",type="string",JSONPath=".spec.forProvider.path"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type RemoteFile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RemoteFileSpec   `json:"spec"`
+	Status RemoteFileStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RemoteFileList contains a list of RemoteFile
+type RemoteFileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RemoteFile `json:"items"`
+}
+
+// RemoteFile type metadata.
+var (
+	RemoteFileKind             = reflect.TypeOf(RemoteFile{}).Name()
+	RemoteFileGroupKind        = schema.GroupKind{Group: Group, Kind: RemoteFileKind}.String()
+	RemoteFileKindAPIVersion   = RemoteFileKind + "." + SchemeGroupVersion.String()
+	RemoteFileGroupVersionKind = SchemeGroupVersion.WithKind(RemoteFileKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&RemoteFile{}, &RemoteFileList{})
+}
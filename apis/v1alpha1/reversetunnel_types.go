@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// A ServiceReference references a port of an in-cluster Service.
+type ServiceReference struct {
+	// Name of the Service.
+	Name string `json:"name"`
+
+	// Namespace of the Service.
+	Namespace string `json:"namespace"`
+
+	// Port of the Service.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port"`
+}
+
+// A TunnelTarget is the endpoint connections through a tunnel are forwarded
+// to. Exactly one of its fields must be set.
+type TunnelTarget struct {
+	// Service forwards connections to an in-cluster Service.
+	// +optional
+	Service *ServiceReference `json:"service,omitempty"`
+
+	// Address forwards connections to a host:port reachable from the
+	// provider.
+	// +optional
+	Address string `json:"address,omitempty"`
+}
+
+// ReverseTunnelParameters are the configurable fields of a ReverseTunnel.
+type ReverseTunnelParameters struct {
+	// RemoteBindAddress is the address the remote host listens on.
+	// +kubebuilder:default="127.0.0.1"
+	// +optional
+	RemoteBindAddress string `json:"remoteBindAddress,omitempty"`
+
+	// RemotePort is the port the remote host listens on.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	RemotePort int `json:"remotePort"`
+
+	// Target of the connections accepted on the remote host.
+	Target TunnelTarget `json:"target"`
+
+	// KeepAliveIntervalSeconds is the interval of the keepalive requests
+	// that detect a broken connection.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=30
+	// +optional
+	KeepAliveIntervalSeconds *int64 `json:"keepAliveIntervalSeconds,omitempty"`
+}
+
+// ReverseTunnelObservation are the observable fields of a ReverseTunnel.
+type ReverseTunnelObservation struct {
+	// Established reports whether the remote host currently listens for
+	// connections.
+	Established bool `json:"established,omitempty"`
+
+	// LastEstablishedTime is the time the tunnel was last established.
+	// +optional
+	LastEstablishedTime *metav1.Time `json:"lastEstablishedTime,omitempty"`
+
+	// Reconnects is the number of times the tunnel was re-established.
+	Reconnects int32 `json:"reconnects,omitempty"`
+
+	// LastError is the error that last broke the tunnel.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// A ReverseTunnelSpec defines the desired state of a ReverseTunnel.
+type ReverseTunnelSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       ReverseTunnelParameters `json:"forProvider"`
+}
+
+// A ReverseTunnelStatus represents the observed state of a ReverseTunnel.
+type ReverseTunnelStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          ReverseTunnelObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A ReverseTunnel exposes an in-cluster endpoint on a port of a remote host
+// through the SSH connection.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="REMOTE-PORT",type="integer",JSONPath=".spec.forProvider.remotePort"
+// +kubebuilder:printcolumn:name="RECONNECTS",type="integer",JSONPath=".status.atProvider.reconnects"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type ReverseTunnel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReverseTunnelSpec   `json:"spec"`
+	Status ReverseTunnelStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReverseTunnelList contains a list of ReverseTunnel
+type ReverseTunnelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReverseTunnel `json:"items"`
+}
+
+// ReverseTunnel type metadata.
+var (
+	ReverseTunnelKind             = reflect.TypeOf(ReverseTunnel{}).Name()
+	ReverseTunnelGroupKind        = schema.GroupKind{Group: Group, Kind: ReverseTunnelKind}.String()
+	ReverseTunnelKindAPIVersion   = ReverseTunnelKind + "." + SchemeGroupVersion.String()
+	ReverseTunnelGroupVersionKind = SchemeGroupVersion.WithKind(ReverseTunnelKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&ReverseTunnel{}, &ReverseTunnelList{})
+}
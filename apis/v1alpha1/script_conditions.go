@@ -0,0 +1,201 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// Condition types of a Script.
+const (
+	// TypeSuspended indicates whether the reconciliation of a Script has been
+	// suspended through its spec.
+	TypeSuspended xpv1.ConditionType = "Suspended"
+
+	// TypeWaitingForDependencies indicates whether a Script is blocked until
+	// the Scripts it depends on are Ready.
+	TypeWaitingForDependencies xpv1.ConditionType = "WaitingForDependencies"
+
+	// TypeHalted indicates whether a Script stopped executing scripts after
+	// too many consecutive failures.
+	TypeHalted xpv1.ConditionType = "Halted"
+
+	// TypeSkipped indicates whether a Script executes no script because its
+	// skipIf expression is true on its host.
+	TypeSkipped xpv1.ConditionType = "Skipped"
+
+	// TypeDrifted indicates whether the status check of a Script whose
+	// updatePolicy is Never reported drift that is not repaired.
+	TypeDrifted xpv1.ConditionType = "Drifted"
+
+	// TypeOrphanedOnDelete indicates whether a deleted Script was removed
+	// without its cleanup, leaving the resource on its host.
+	TypeOrphanedOnDelete xpv1.ConditionType = "OrphanedOnDelete"
+)
+
+// Condition reasons of a Script.
+const (
+	ReasonSuspended xpv1.ConditionReason = "SuspendedBySpec"
+	ReasonResumed   xpv1.ConditionReason = "Resumed"
+
+	ReasonDependenciesNotReady xpv1.ConditionReason = "DependenciesNotReady"
+	ReasonDependenciesReady    xpv1.ConditionReason = "DependenciesReady"
+
+	ReasonConsecutiveFailures xpv1.ConditionReason = "ConsecutiveFailures"
+	ReasonHaltResumed         xpv1.ConditionReason = "Resumed"
+
+	ReasonSkipIfTrue  xpv1.ConditionReason = "SkipIfTrue"
+	ReasonSkipIfFalse xpv1.ConditionReason = "SkipIfFalse"
+
+	ReasonDriftDetected xpv1.ConditionReason = "DriftDetected"
+	ReasonNoDrift       xpv1.ConditionReason = "NoDrift"
+
+	ReasonDeleteRetriesExhausted xpv1.ConditionReason = "DeleteRetriesExhausted"
+)
+
+// Suspended returns a condition that indicates the Script is not reconciled
+// because spec.suspend is set.
+func Suspended() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeSuspended,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonSuspended,
+		Message:            "Reconciliation is suspended through spec.suspend",
+	}
+}
+
+// Resumed returns a condition that indicates the Script is reconciled again
+// after being suspended.
+func Resumed() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeSuspended,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonResumed,
+	}
+}
+
+// WaitingForDependencies returns a condition that indicates the Script is
+// blocked until the named Scripts are Ready.
+func WaitingForDependencies(names []string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeWaitingForDependencies,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDependenciesNotReady,
+		Message:            "Waiting for Scripts to become Ready: " + strings.Join(names, ", "),
+	}
+}
+
+// DependenciesReady returns a condition that indicates all Scripts the Script
+// depends on are Ready.
+func DependenciesReady() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeWaitingForDependencies,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDependenciesReady,
+	}
+}
+
+// Halted returns a condition that indicates the Script executes no script
+// after the supplied number of consecutive failures.
+func Halted(failures int32) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeHalted,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonConsecutiveFailures,
+		Message:            fmt.Sprintf("Halted after %d consecutive failures, set the %s annotation or change the spec to resume", failures, AnnotationKeyResume),
+	}
+}
+
+// HaltResumed returns a condition that indicates the Script executes scripts
+// again after being halted.
+func HaltResumed() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeHalted,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonHaltResumed,
+	}
+}
+
+// Skipped returns a condition that indicates the Script executes no script
+// because its skipIf expression is true on its host.
+func Skipped() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeSkipped,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonSkipIfTrue,
+		Message:            "The skipIf expression is true on the host, no script is executed",
+	}
+}
+
+// NotSkipped returns a condition that indicates the Script executes its
+// scripts again because its skipIf expression is no longer true.
+func NotSkipped() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeSkipped,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonSkipIfFalse,
+	}
+}
+
+// Drifted returns a condition that indicates the status check of the Script
+// reported drift that is not repaired because its updatePolicy is Never.
+func Drifted() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDrifted,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDriftDetected,
+		Message:            "The status check reported drift, the updateScript is not executed as the updatePolicy is Never",
+	}
+}
+
+// NotDrifted returns a condition that indicates the status check of the
+// Script no longer reports drift.
+func NotDrifted() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDrifted,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoDrift,
+	}
+}
+
+// OrphanedOnDelete returns a condition that indicates the deleted Script is
+// removed without its cleanup after the supplied number of failed attempts.
+func OrphanedOnDelete(attempts int32) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeOrphanedOnDelete,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDeleteRetriesExhausted,
+		Message:            fmt.Sprintf("Cleanup abandoned after %d failed attempts, the resource is left on the host", attempts),
+	}
+}
@@ -20,26 +20,890 @@ import (
 	"reflect"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 )
 
+// AnnotationKeyRunNow is the key of an annotation whose value is a token
+// that forces the next reconcile to execute the updateScript, or the
+// initScript if no updateScript is set. Each token value is executed once.
+const AnnotationKeyRunNow = "ssh.crossplane.io/run-now"
+
+// AnnotationKeyResume is the key of an annotation whose value is a token that
+// resumes a Script halted after too many consecutive failures. Each token
+// value resumes the Script once.
+const AnnotationKeyResume = "ssh.crossplane.io/resume"
+
+// AnnotationKeySignature is the key of an annotation whose value is the ASCII
+// armored OpenPGP detached signature of the scripts of a Script, ScriptSet,
+// NodeScript or Command. It is verified when the provider requires signed
+// scripts.
+const AnnotationKeySignature = "ssh.crossplane.io/signature"
+
+// Defaults of the execution of the scripts of a Script, applied by the
+// defaulting webhook and assumed by the controller for unset fields.
+const (
+	DefaultPort            = 22
+	DefaultInterpreter     = "/bin/sh"
+	DefaultTimeoutSeconds  = 300
+	DefaultMaxOutputBytes  = 16 << 10
+	DefaultExitCodeMissing = 100
+	DefaultExitCodeFailed  = 1
+
+	// DefaultStrictModePreamble is set -euo pipefail, with pipefail only set
+	// by the shells that support it, such as bash, so that it also runs
+	// with the default interpreter on hosts whose /bin/sh is dash.
+	DefaultStrictModePreamble = "set -eu; (set -o pipefail) 2>/dev/null && set -o pipefail"
+)
+
+// ExpectedOutput is the stdout of the statusCheckScript of an up to date
+// resource, either an exact string or a regular expression.
+// +kubebuilder:validation:XValidation:rule="has(self.exact) != has(self.regex)",message="exactly one of exact and regex is required"
+type ExpectedOutput struct {
+	// Exact is the expected stdout, without its trailing newlines. The
+	// variables are replaced in it as in the scripts.
+	// +optional
+	Exact string `json:"exact,omitempty"`
+
+	// Regex is a regular expression in the RE2 syntax matching the expected
+	// stdout without its trailing newlines, e.g. ^1\.2\.[0-9]+$. It is not
+	// anchored unless it says so.
+	// +optional
+	Regex string `json:"regex,omitempty"`
+}
+
+// OutputFormat is the format of the stdout of the statusCheckScript.
+type OutputFormat string
+
+const (
+	// OutputFormatKeyValue reads the name=value lines of the stdout.
+	OutputFormatKeyValue OutputFormat = "KeyValue"
+
+	// OutputFormatJSON parses the stdout as a JSON object.
+	OutputFormatJSON OutputFormat = "JSON"
+
+	// OutputFormatYAML parses the stdout as a YAML mapping.
+	OutputFormatYAML OutputFormat = "YAML"
+)
+
+// StdoutFormat is the format the stdout of the scripts is recorded in the
+// status with.
+type StdoutFormat string
+
+const (
+	// StdoutFormatText records the stdout as a single string.
+	StdoutFormatText StdoutFormat = "Text"
+
+	// StdoutFormatLines records the stdout as an array of its lines.
+	StdoutFormatLines StdoutFormat = "Lines"
+)
+
+// MaxStdoutLines is the number of lines of the stdout recorded with the Lines
+// stdoutFormat. The first lines of longer outputs are dropped.
+const MaxStdoutLines = 100
+
+// StatusCheckExitCodes map the exit codes of the statusCheckScript onto the
+// state of the resource. Exit code 0 reports a ready resource, and other
+// exit codes drift repaired by the updateScript.
+type StatusCheckExitCodes struct {
+	// Missing is the exit code reporting that the resource does not exist,
+	// in which case the initScript is executed.
+	// +optional
+	Missing *int `json:"missing,omitempty"`
+
+	// Failed lists the exit codes reporting a failure that cannot be
+	// repaired automatically.
+	// +optional
+	Failed []int `json:"failed,omitempty"`
+}
+
 type Variable struct {
 	// Name of the variable
 	Name string `json:"name"`
 	// Value of the variable
 	Value string `json:"value"`
+	// Sensitive values are masked in the logs of the provider.
+	// +optional
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// A ScriptReference references another Script by name.
+type ScriptReference struct {
+	// Name of the referenced Script.
+	Name string `json:"name"`
+}
+
+// A HostReference references a ProviderConfig or a Host that identifies a
+// target host, or a HostGroup whose member Hosts are targeted.
+type HostReference struct {
+	// Kind of the referenced object.
+	// +kubebuilder:validation:Enum=ProviderConfig;Host;HostGroup
+	// +kubebuilder:default=ProviderConfig
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the referenced object.
+	Name string `json:"name"`
+}
+
+// ExecutionPolicy controls how often the scripts of a Script are executed.
+type ExecutionPolicy string
+
+const (
+	// ExecutionPolicyReconcile executes the scripts whenever the status check
+	// reports that the remote state does not exist or has drifted.
+	ExecutionPolicyReconcile ExecutionPolicy = "Reconcile"
+
+	// ExecutionPolicyRunOnce executes the initScript exactly once. The
+	// updateScript is never executed and the recorded result of the
+	// initScript is reported on every subsequent observation.
+	ExecutionPolicyRunOnce ExecutionPolicy = "RunOnce"
+)
+
+// UpdateStrategy controls how changes of the rendered initScript are applied.
+type UpdateStrategy string
+
+const (
+	// UpdateStrategyInPlace applies drift by running the updateScript.
+	UpdateStrategyInPlace UpdateStrategy = "InPlace"
+
+	// UpdateStrategyRecreate runs the cleanupScript followed by the initScript
+	// whenever the rendered initScript changes.
+	UpdateStrategyRecreate UpdateStrategy = "Recreate"
+)
+
+// UpdatePolicy controls when the updateScript of a Script is executed.
+type UpdatePolicy string
+
+const (
+	// UpdatePolicyOnDrift executes the updateScript whenever the status
+	// check reports drift.
+	UpdatePolicyOnDrift UpdatePolicy = "OnDrift"
+
+	// UpdatePolicyAlways executes the updateScript on every status check of
+	// an existing resource, enforcing its state idempotently.
+	UpdatePolicyAlways UpdatePolicy = "Always"
+
+	// UpdatePolicyNever never executes the updateScript on drift, which is
+	// only reported through the Drifted condition.
+	UpdatePolicyNever UpdatePolicy = "Never"
+)
+
+// CleanupPolicy controls whether the cleanupScript is executed when a Script
+// is deleted.
+type CleanupPolicy string
+
+const (
+	// CleanupPolicyRun executes the cleanupScript and blocks the deletion
+	// until it succeeds.
+	CleanupPolicyRun CleanupPolicy = "Run"
+
+	// CleanupPolicySkip never executes the cleanupScript.
+	CleanupPolicySkip CleanupPolicy = "Skip"
+
+	// CleanupPolicyBestEffort executes the cleanupScript but lets the
+	// deletion proceed after a number of failed attempts.
+	CleanupPolicyBestEffort CleanupPolicy = "BestEffort"
+)
+
+// An InitRetryPolicy retries a failing initScript within the reconcile.
+type InitRetryPolicy struct {
+	// Attempts is the number of executions of the initScript, the first
+	// one included.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=5
+	Attempts int32 `json:"attempts"`
+
+	// Delay between two attempts.
+	// +optional
+	Delay *metav1.Duration `json:"delay,omitempty"`
+}
+
+// A DeleteRetryPolicy bounds the retries of the cleanup of a deleted Script.
+type DeleteRetryPolicy struct {
+	// Attempts is the number of failed attempts to connect to the host or to
+	// run the cleanupScript after which the retries are exhausted.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	Attempts *int32 `json:"attempts,omitempty"`
+
+	// Backoff is the delay before the cleanup is attempted again after a
+	// failed attempt. The provider retries on its own schedule if unset.
+	// +optional
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+
+	// ThenOrphan lets the deletion proceed once the retries are exhausted,
+	// leaving the resource on the host, and records the OrphanedOnDelete
+	// condition. Otherwise the cleanup is retried until it succeeds.
+	// +optional
+	ThenOrphan bool `json:"thenOrphan,omitempty"`
+}
+
+// RebootPolicy controls whether a Script detects the reboots of its host.
+type RebootPolicy string
+
+const (
+	// RebootPolicyIgnore does not detect reboots.
+	RebootPolicyIgnore RebootPolicy = "Ignore"
+
+	// RebootPolicyRecord records the boot ID of the host and reports its
+	// reboots through an Event.
+	RebootPolicyRecord RebootPolicy = "Record"
+
+	// RebootPolicyUpdate also reports the resource as not up to date after
+	// a reboot, so that the postRebootScript is executed.
+	RebootPolicyUpdate RebootPolicy = "Update"
+)
+
+// Templating is the syntax of the references to variables in scripts.
+type Templating string
+
+const (
+	// TemplatingBraces substitutes the references of the form {{VAR}}.
+	TemplatingBraces Templating = "braces"
+
+	// TemplatingEnvsubst substitutes the references of the form $VAR and
+	// ${VAR}, like envsubst. References to other names, such as the
+	// environment variables of the shell, are left as they are.
+	TemplatingEnvsubst Templating = "envsubst"
+)
+
+// ContainerEngine is the engine running the container scripts are executed
+// in.
+type ContainerEngine string
+
+// Container engines the scripts of a Script may be executed through.
+const (
+	ContainerEngineDocker ContainerEngine = "docker"
+	ContainerEnginePodman ContainerEngine = "podman"
+)
+
+// A Container running on the host that scripts are executed in.
+type Container struct {
+	// Engine is the engine running the container, docker by default. Its
+	// CLI must be installed on the host and usable by the remote user, or
+	// through sudo.
+	// +kubebuilder:validation:Enum=docker;podman
+	// +optional
+	Engine ContainerEngine `json:"engine,omitempty"`
+
+	// Name is the name or ID of the running container.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`
+	Name string `json:"name"`
+}
+
+// NamespaceType is a type of Linux namespace.
+// +kubebuilder:validation:Enum=Mount;UTS;IPC;Network;PID;User;Cgroup
+type NamespaceType string
+
+// Types of the namespaces the scripts of a Script may enter.
+const (
+	NamespaceTypeMount   NamespaceType = "Mount"
+	NamespaceTypeUTS     NamespaceType = "UTS"
+	NamespaceTypeIPC     NamespaceType = "IPC"
+	NamespaceTypeNetwork NamespaceType = "Network"
+	NamespaceTypePID     NamespaceType = "PID"
+	NamespaceTypeUser    NamespaceType = "User"
+	NamespaceTypeCgroup  NamespaceType = "Cgroup"
+)
+
+// Namespaces of a process on the host that scripts are executed in.
+type Namespaces struct {
+	// TargetPID is the PID of the process whose namespaces are entered,
+	// such as 1 for the namespaces of the init process of the host.
+	// +kubebuilder:validation:Minimum=1
+	TargetPID int32 `json:"targetPID"`
+
+	// Types of the namespaces entered, the mount namespace by default.
+	// +optional
+	Types []NamespaceType `json:"types,omitempty"`
+}
+
+// Limits bound the resources of the processes of scripts on the host.
+type Limits struct {
+	// CPUSeconds is the CPU time a process may use, after which it is
+	// killed by SIGXCPU.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CPUSeconds *int64 `json:"cpuSeconds,omitempty"`
+
+	// MaxMemoryKB is the virtual memory a process may allocate, in KiB.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxMemoryKB *int64 `json:"maxMemoryKB,omitempty"`
+
+	// MaxFileSizeKB is the size of the largest file a process may write,
+	// in KiB, beyond which it is killed by SIGXFSZ.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxFileSizeKB *int64 `json:"maxFileSizeKB,omitempty"`
+}
+
+// IONiceClass is the I/O scheduling class of ionice.
+type IONiceClass string
+
+const (
+	// IONiceClassRealtime gets the disk first, regardless of the other
+	// processes. Requires root.
+	IONiceClassRealtime IONiceClass = "Realtime"
+
+	// IONiceClassBestEffort gets the disk according to its level, through
+	// the default class of processes.
+	IONiceClassBestEffort IONiceClass = "BestEffort"
+
+	// IONiceClassIdle only gets the disk when no other process uses it.
+	IONiceClassIdle IONiceClass = "Idle"
+)
+
+// Priority is the CPU and I/O scheduling priority of scripts.
+type Priority struct {
+	// Nice is the niceness the scripts are executed with, from -20, the
+	// highest priority, to 19, the lowest. Negative values require root.
+	// +kubebuilder:validation:Minimum=-20
+	// +kubebuilder:validation:Maximum=19
+	// +optional
+	Nice *int32 `json:"nice,omitempty"`
+
+	// IONice is the I/O scheduling priority the scripts are executed with.
+	// +optional
+	IONice *IONice `json:"ionice,omitempty"`
+}
+
+// IONice is an I/O scheduling class and level of ionice.
+type IONice struct {
+	// Class is the I/O scheduling class.
+	// +kubebuilder:validation:Enum=Realtime;BestEffort;Idle
+	Class IONiceClass `json:"class"`
+
+	// Level within the Realtime and BestEffort classes, from 0, the highest
+	// priority, to 7, the lowest. Ignored by the Idle class.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=7
+	// +optional
+	Level *int32 `json:"level,omitempty"`
+}
+
+// An Operation is a script executed to change the resource managed by a
+// Script.
+type Operation string
+
+// Operations of a Script.
+const (
+	OperationInit       Operation = "Init"
+	OperationUpdate     Operation = "Update"
+	OperationRecreate   Operation = "Recreate"
+	OperationRunNow     Operation = "RunNow"
+	OperationCleanup    Operation = "Cleanup"
+	OperationPostReboot Operation = "PostReboot"
+)
+
+// A VirtualMachineInstanceTarget references a KubeVirt
+// VirtualMachineInstance whose interface address the scripts are executed
+// on.
+type VirtualMachineInstanceTarget struct {
+	// Name of the VirtualMachineInstance.
+	Name string `json:"name"`
+
+	// Namespace of the VirtualMachineInstance.
+	Namespace string `json:"namespace"`
+
+	// Interface is the name of the interface of the VirtualMachineInstance
+	// whose address is connected to. Defaults to the first interface
+	// reporting an address.
+	// +optional
+	Interface string `json:"interface,omitempty"`
+
+	// Port of the SSH server of the VirtualMachineInstance.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=22
+	// +optional
+	Port *int `json:"port,omitempty"`
+}
+
+// MachineKind is the kind of the Cluster API Machine a Script targets.
+type MachineKind string
+
+const (
+	// MachineKindMachine targets a Machine of the cluster.x-k8s.io API
+	// group.
+	MachineKindMachine MachineKind = "Machine"
+
+	// MachineKindMetal3Machine targets a Metal3Machine of the
+	// infrastructure.cluster.x-k8s.io API group, the infrastructure of a
+	// Machine provisioned on a Metal3 BareMetalHost.
+	MachineKindMetal3Machine MachineKind = "Metal3Machine"
+)
+
+// MachineAddressType is the type of an address of a Cluster API Machine.
+type MachineAddressType string
+
+const (
+	// MachineAddressExternalIP is an IP address reachable from outside the
+	// cluster of the Machine.
+	MachineAddressExternalIP MachineAddressType = "ExternalIP"
+
+	// MachineAddressInternalIP is an IP address of the network of the
+	// cluster of the Machine.
+	MachineAddressInternalIP MachineAddressType = "InternalIP"
+
+	// MachineAddressExternalDNS is a DNS name reachable from outside the
+	// cluster of the Machine.
+	MachineAddressExternalDNS MachineAddressType = "ExternalDNS"
+
+	// MachineAddressInternalDNS is a DNS name of the network of the cluster
+	// of the Machine.
+	MachineAddressInternalDNS MachineAddressType = "InternalDNS"
+
+	// MachineAddressHostname is the hostname of the Machine.
+	MachineAddressHostname MachineAddressType = "Hostname"
+)
+
+// A MachineTarget references a Cluster API Machine whose address the scripts
+// are executed on.
+type MachineTarget struct {
+	// Kind of the Machine.
+	// +kubebuilder:validation:Enum=Machine;Metal3Machine
+	// +kubebuilder:default=Machine
+	// +optional
+	Kind MachineKind `json:"kind,omitempty"`
+
+	// Name of the Machine.
+	Name string `json:"name"`
+
+	// Namespace of the Machine.
+	Namespace string `json:"namespace"`
+
+	// AddressType is the type of the address of the Machine that is
+	// connected to. Defaults to the first address of the Machine, in the
+	// order ExternalIP, InternalIP, ExternalDNS, InternalDNS and Hostname.
+	// +kubebuilder:validation:Enum=ExternalIP;InternalIP;ExternalDNS;InternalDNS;Hostname
+	// +optional
+	AddressType MachineAddressType `json:"addressType,omitempty"`
+
+	// Port of the SSH server of the Machine.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=22
+	// +optional
+	Port *int `json:"port,omitempty"`
 }
 
 // ScriptParameters are the configurable fields of a Script.
 type ScriptParameters struct {
 	Variables         []Variable `json:"variables,omitempty"`
 	InitScript        string     `json:"initScript,omitempty"`
-	StatusCheckScript string     `json:"statusCheckScript"`
+	StatusCheckScript string     `json:"statusCheckScript,omitempty"`
 	UpdateScript      string     `json:"updateScript,omitempty"`
 	CleanupScript     string     `json:"cleanupScript,omitempty"`
-	SudoEnabled       bool       `json:"sudoEnabled,omitempty"`
+
+	// SudoEnabled executes the scripts with sudo. It defaults to the
+	// sudoEnabled of the defaults of the ProviderConfig, false if unset.
+	// +optional
+	SudoEnabled *bool `json:"sudoEnabled,omitempty"`
+
+	// ExistsScript reports whether the resource exists on the remote host,
+	// exit code 0 meaning that it exists. If it or UpToDateScript is set the
+	// statusCheckScript is not executed.
+	// +optional
+	ExistsScript string `json:"existsScript,omitempty"`
+
+	// UpToDateScript reports whether an existing resource is in sync with
+	// the spec, exit code 0 meaning that it is.
+	// +optional
+	UpToDateScript string `json:"upToDateScript,omitempty"`
+
+	// DiffScript is executed whenever drift is detected. Its stdout is
+	// reported in the diff field of the status and in an Event before the
+	// updateScript is executed.
+	// +optional
+	DiffScript string `json:"diffScript,omitempty"`
+
+	// PostRebootScript is executed in place of the updateScript after the
+	// host rebooted, when the rebootPolicy is Update. It defaults to the
+	// updateScript.
+	// +optional
+	PostRebootScript string `json:"postRebootScript,omitempty"`
+
+	// DependsOn lists Scripts that must be Ready before this Script is
+	// observed or created.
+	// +optional
+	DependsOn []ScriptReference `json:"dependsOn,omitempty"`
+
+	// ExecutionPolicy of the scripts. With RunOnce the initScript is executed
+	// exactly once and never retried, the updateScript is never executed and
+	// later edits of the spec require the Script to be recreated.
+	// +kubebuilder:validation:Enum=Reconcile;RunOnce
+	// +kubebuilder:default=Reconcile
+	// +optional
+	ExecutionPolicy ExecutionPolicy `json:"executionPolicy,omitempty"`
+
+	// UpdateStrategy of the Script. With Recreate a change of the rendered
+	// initScript runs the cleanupScript followed by the initScript instead of
+	// the updateScript.
+	// +kubebuilder:validation:Enum=InPlace;Recreate
+	// +kubebuilder:default=InPlace
+	// +optional
+	UpdateStrategy UpdateStrategy `json:"updateStrategy,omitempty"`
+
+	// UpdatePolicy of the Script. OnDrift executes the updateScript whenever
+	// the status check reports drift, Always on every status check of an
+	// existing resource, and Never only reports drift through the Drifted
+	// condition. Scripts running on multiple hosts always update on drift.
+	// +kubebuilder:validation:Enum=OnDrift;Always;Never
+	// +kubebuilder:default=OnDrift
+	// +optional
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+
+	// InitRetry retries a failing initScript within the reconcile, for
+	// transient failures such as flaking package mirrors. It does not apply
+	// to RunOnce Scripts.
+	// +optional
+	InitRetry *InitRetryPolicy `json:"initRetry,omitempty"`
+
+	// CleanupPolicy of the Script, independent of its deletionPolicy. Skip
+	// never executes the cleanupScript, and BestEffort lets the deletion
+	// proceed once MaxCleanupAttempts attempts to connect to the host or to
+	// run the cleanupScript failed.
+	// +kubebuilder:validation:Enum=Run;Skip;BestEffort
+	// +kubebuilder:default=Run
+	// +optional
+	CleanupPolicy CleanupPolicy `json:"cleanupPolicy,omitempty"`
+
+	// MaxCleanupAttempts is the number of failed cleanup attempts tolerated
+	// by the BestEffort cleanupPolicy.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	MaxCleanupAttempts *int32 `json:"maxCleanupAttempts,omitempty"`
+
+	// CheckBeforeCleanup executes the existsScript, or the statusCheckScript
+	// if it is not set, before the cleanupScript, and skips the cleanupScript
+	// if it reports that the resource no longer exists on the host, for
+	// instance because the host was reimaged.
+	// +optional
+	CheckBeforeCleanup bool `json:"checkBeforeCleanup,omitempty"`
+
+	// DeleteRetry bounds the retries of a cleanup that keeps failing, for
+	// instance because the host is permanently unreachable.
+	// +optional
+	DeleteRetry *DeleteRetryPolicy `json:"deleteRetry,omitempty"`
+
+	// TTLSecondsAfterFinished limits the lifetime of a RunOnce Script after
+	// its initScript succeeded. Once the TTL expired the Script is no longer
+	// reconciled periodically.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTLSecondsAfterFinished *int64 `json:"ttlSecondsAfterFinished,omitempty"`
+
+	// DeleteAfterTTL deletes the Script once TTLSecondsAfterFinished expired.
+	// The cleanupScript is not executed for Scripts deleted this way.
+	// +optional
+	DeleteAfterTTL bool `json:"deleteAfterTTL,omitempty"`
+
+	// Hosts lists ProviderConfigs or Hosts, one per host, on which the
+	// scripts are executed instead of the host of the providerConfigRef.
+	// +optional
+	Hosts []HostReference `json:"hosts,omitempty"`
+
+	// HostSelector selects ProviderConfigs and Hosts by label, one per host,
+	// on which the scripts are executed in addition to the listed Hosts.
+	// +optional
+	HostSelector *metav1.LabelSelector `json:"hostSelector,omitempty"`
+
+	// SuccessThreshold is the number or percentage of targeted hosts that
+	// must be ready for the Script to be Ready. Defaults to all hosts.
+	// +kubebuilder:validation:XIntOrString
+	// +optional
+	SuccessThreshold *intstr.IntOrString `json:"successThreshold,omitempty"`
+
+	// Endpoint overrides the host of the ProviderConfig, whose credentials
+	// are used to authenticate. It only applies to Scripts targeting a
+	// single host.
+	// +optional
+	Endpoint *Endpoint `json:"endpoint,omitempty"`
+
+	// VirtualMachineInstance targets a KubeVirt VirtualMachineInstance
+	// instead of the host of the ProviderConfig, whose credentials are
+	// used to authenticate. Its address is resolved from the status of the
+	// VirtualMachineInstance on every connection, and takes precedence over
+	// the endpoint. It only applies to Scripts targeting a single host.
+	// +optional
+	VirtualMachineInstance *VirtualMachineInstanceTarget `json:"virtualMachineInstance,omitempty"`
+
+	// Machine targets a Cluster API Machine, or the Metal3Machine of a
+	// Metal3 host, instead of the host of the ProviderConfig, whose
+	// credentials are used to authenticate. Its address is resolved from
+	// the status of the Machine on every connection, and takes precedence
+	// over the endpoint. It cannot be combined with a
+	// virtualMachineInstance, and only applies to Scripts targeting a single
+	// host.
+	// +optional
+	Machine *MachineTarget `json:"machine,omitempty"`
+
+	// Username overrides the remote user of the credentials the scripts are
+	// executed as. The credentials must be accepted for this user.
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// Rollout controls how the scripts are rolled out across the targeted
+	// hosts. By default all hosts are executed at once.
+	// +optional
+	Rollout *RolloutStrategy `json:"rollout,omitempty"`
+
+	// Interpreter executes the scripts that do not start with a #! line.
+	// +optional
+	Interpreter string `json:"interpreter,omitempty"`
+
+	// RemoteTempDir is the directory of the host the scripts are uploaded to
+	// before they are executed, /tmp by default.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	RemoteTempDir string `json:"remoteTempDir,omitempty"`
+
+	// Platform is the platform of the host the scripts are written for, as
+	// the system and arch it reports through uname, e.g. Linux/x86_64. The
+	// initScript and updateScript are not executed on a host reporting
+	// another platform. It is late-initialized from the host, along with
+	// the interpreter and remoteTempDir.
+	// +optional
+	Platform string `json:"platform,omitempty"`
+
+	// Templating is the syntax of the references to variables in the
+	// scripts, braces by default.
+	// +kubebuilder:validation:Enum=braces;envsubst
+	// +optional
+	Templating Templating `json:"templating,omitempty"`
+
+	// StrictMode prepends the strictModePreamble to the rendered scripts, so
+	// that the failure of any command fails the script. It assumes that the
+	// scripts are executed by a shell.
+	// +optional
+	StrictMode bool `json:"strictMode,omitempty"`
+
+	// StrictModePreamble is the line prepended to the scripts in strict
+	// mode, after their #! line if any. Defaults to set -euo pipefail, with
+	// pipefail only set by the shells that support it.
+	// +optional
+	StrictModePreamble string `json:"strictModePreamble,omitempty"`
+
+	// Container executes the scripts in a container running on the host,
+	// to which the rendered scripts are piped through the exec command of
+	// its engine. The execution settings, such as the interpreter, the
+	// umask or the limits, apply in the container.
+	// +optional
+	Container *Container `json:"container,omitempty"`
+
+	// Namespaces executes the scripts in the namespaces of a process of the
+	// host through nsenter, such as the mount namespace of a host OS whose
+	// root is not the one of the remote user. It requires sudoEnabled,
+	// unless the remote user is root.
+	// +optional
+	Namespaces *Namespaces `json:"namespaces,omitempty"`
+
+	// Chroot executes the scripts with the supplied directory of the host,
+	// or of the namespaces, as their root directory, such as the root of a
+	// system mounted in a rescue environment. It requires sudoEnabled,
+	// unless the remote user is root.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	Chroot string `json:"chroot,omitempty"`
+
+	// LoginShell executes the scripts through bash -l -c, so that they get
+	// the environment of the profile of the remote user, such as a PATH
+	// extended by /etc/profile.d, as when run interactively.
+	// +optional
+	LoginShell bool `json:"loginShell,omitempty"`
+
+	// Umask is the octal file mode creation mask the scripts are executed
+	// with, e.g. 027. The umask of the remote user applies if unset.
+	// +kubebuilder:validation:Pattern=`^[0-7]{3,4}$`
+	// +optional
+	Umask string `json:"umask,omitempty"`
+
+	// Locale is the locale the scripts are executed with, set as LANG and
+	// LC_ALL, e.g. C.UTF-8, so that the commands they run format their
+	// output the same way on every host. It must be available on the host.
+	// The locale of the session applies if unset.
+	// +optional
+	Locale string `json:"locale,omitempty"`
+
+	// Timezone is the timezone the scripts are executed with, set as TZ,
+	// e.g. UTC or Europe/Paris. The timezone of the host applies if unset.
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+
+	// Priority lowers or raises the CPU and I/O scheduling priority of the
+	// scripts through nice and ionice, which must be installed on the host.
+	// +optional
+	Priority *Priority `json:"priority,omitempty"`
+
+	// Limits bound the resources of the scripts through ulimit. A Script
+	// setting limits is also killed on the host through timeout, shortly
+	// after timeoutSeconds, should the provider fail to kill it.
+	// +optional
+	Limits *Limits `json:"limits,omitempty"`
+
+	// TimeoutSeconds is the time after which a script is killed.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=600
+	// +optional
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+
+	// MaxOutputBytes is the number of bytes of the stdout and stderr of a
+	// script recorded in the status. Longer outputs are truncated to their
+	// end.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxOutputBytes *int64 `json:"maxOutputBytes,omitempty"`
+
+	// StdoutFormat of the stdout of the scripts recorded in the status. Text
+	// records it as status.atProvider.stdout, while Lines records it as
+	// status.atProvider.stdoutLines, one entry per line, so that
+	// compositions can patch from a specific line. Only its last 100 lines
+	// are recorded as lines.
+	// +kubebuilder:validation:Enum=Text;Lines
+	// +kubebuilder:default=Text
+	// +optional
+	StdoutFormat StdoutFormat `json:"stdoutFormat,omitempty"`
+
+	// RecordSession records a timestamped transcript of every execution of
+	// the scripts of an operation, the script and its interleaved stdout
+	// and stderr, in asciicast v2 format to the session recording sink of
+	// the provider, for forensics. Status checks are not recorded.
+	// +optional
+	RecordSession *bool `json:"recordSession,omitempty"`
+
+	// PollInterval overrides the poll interval of the provider for this
+	// Script, e.g. 30s or 1h.
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+
+	// StatusCheckInterval is the minimum time between two executions of the
+	// statusCheckScript, or of the existsScript and upToDateScript, on a resource
+	// that is up to date. The host is not connected to in between, unless
+	// the spec changes or a run is requested.
+	// +optional
+	StatusCheckInterval *metav1.Duration `json:"statusCheckInterval,omitempty"`
+
+	// CreateGracePeriod delays the first statusCheckScript after the resource
+	// was created, so that the services started by the initScript have time
+	// to come up before they are checked.
+	// +optional
+	CreateGracePeriod *metav1.Duration `json:"createGracePeriod,omitempty"`
+
+	// StatusCacheTTL is how long the last result of the statusCheckScript is
+	// reused by the observations of the Script, such as those triggered by
+	// updates of its status, instead of executing it again. The result is
+	// not reused once the spec changes, a script changes the resource or the
+	// Script is deleted.
+	// +optional
+	StatusCacheTTL *metav1.Duration `json:"statusCacheTTL,omitempty"`
+
+	// ReapplyInterval is the maximum time between two executions of the
+	// updateScript. Once it elapsed since a script last changed the resource
+	// the updateScript is executed, even if the resource is up to date, to
+	// repair drift the status check cannot detect.
+	// +optional
+	ReapplyInterval *metav1.Duration `json:"reapplyInterval,omitempty"`
+
+	// MaxConsecutiveFailures halts the Script once this many consecutive
+	// executions of its scripts failed. A halted Script executes no script
+	// until the resume annotation is set to a new value or its spec changes.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConsecutiveFailures *int32 `json:"maxConsecutiveFailures,omitempty"`
+
+	// ExitCodes maps the exit codes of the statusCheckScript onto the state
+	// of the resource.
+	// +optional
+	ExitCodes *StatusCheckExitCodes `json:"exitCodes,omitempty"`
+
+	// SkipIf is a CEL expression evaluated on each host before its scripts
+	// are executed, e.g. facts.kernel.startsWith("6.8"). A host on which it
+	// is true is reported Ready and executes no script, the cleanupScript
+	// included. The expression can reference the facts gathered from the
+	// host (hostname, system, kernel, arch, os, osVersion, cpus and
+	// memoryKB), the name of its ProviderConfig or Host as host, and the
+	// variables of the Script as vars.
+	// +optional
+	SkipIf string `json:"skipIf,omitempty"`
+
+	// UpToDateWhen is a CEL expression deciding whether the resource is up to
+	// date from the result of the statusCheckScript, in place of its exit code
+	// being zero, e.g. outputs.version == vars.VERSION. It is evaluated
+	// unless the exit code reports a missing or failed resource, and can
+	// reference the exitCode, stdout and stderr of the script, its outputs
+	// (the name=value lines of its stdout) and the variables of the Script
+	// as vars.
+	// +optional
+	UpToDateWhen string `json:"upToDateWhen,omitempty"`
+
+	// ReadyWhen is a CEL expression deciding whether the resource is Ready
+	// from the result of the statusCheckScript, with the variables of
+	// upToDateWhen. The resource is Ready when it is up to date by default.
+	// +optional
+	ReadyWhen string `json:"readyWhen,omitempty"`
+
+	// ExpectedOutput is compared with the stdout of the statusCheckScript to decide
+	// whether the resource is up to date, in addition to its exit code being
+	// zero or its upToDateWhen being true.
+	// +optional
+	ExpectedOutput *ExpectedOutput `json:"expectedOutput,omitempty"`
+
+	// OutputFormat of the stdout of the statusCheckScript. KeyValue reads its
+	// name=value lines, while JSON and YAML parse it as an object that is
+	// also recorded in status.atProvider.outputs. The outputs are available
+	// to the upToDateWhen and readyWhen expressions in every format.
+	// +kubebuilder:validation:Enum=KeyValue;JSON;YAML
+	// +kubebuilder:default=KeyValue
+	// +optional
+	OutputFormat OutputFormat `json:"outputFormat,omitempty"`
+
+	// RebootPolicy of the Script. Record reads the boot ID of the host on
+	// every status check and reports its reboots through an Event, and
+	// Update also reports the resource as not up to date after a reboot, as
+	// reboots commonly wipe the state established by the scripts. Reboots
+	// are not detected for Scripts running on multiple hosts.
+	// +kubebuilder:validation:Enum=Ignore;Record;Update
+	// +kubebuilder:default=Ignore
+	// +optional
+	RebootPolicy RebootPolicy `json:"rebootPolicy,omitempty"`
+}
+
+// HostStatus is the observed state of a Script on a single targeted host.
+type HostStatus struct {
+	// Kind of the object identifying the host, ProviderConfig or Host.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the object identifying the host.
+	Name string `json:"name"`
+
+	// Ready reports whether the last script succeeded on the host.
+	Ready bool `json:"ready"`
+
+	// StatusCode is the exit status code of the last script executed on the
+	// host.
+	StatusCode int `json:"statusCode"`
+
+	// OutputDigest is the SHA-256 hash of the stdout of the last script
+	// executed on the host.
+	// +optional
+	OutputDigest string `json:"outputDigest,omitempty"`
+
+	// Message describes why the host is not ready, if it is not.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Skipped reports whether the skipIf expression of the Script was true
+	// on the host when it was last checked.
+	// +optional
+	Skipped bool `json:"skipped,omitempty"`
 }
 
 // ScriptObservation are the observable fields of a Script.
@@ -47,12 +911,125 @@ type ScriptObservation struct {
 	Stdout     string `json:"stdout"`
 	Stderr     string `json:"stderr"`
 	StatusCode int    `json:"statusCode"`
+
+	// StdoutLines is the stdout of the last script executed, one entry per
+	// line, recorded instead of stdout with the Lines stdoutFormat.
+	// +kubebuilder:validation:MaxItems=100
+	// +optional
+	StdoutLines []string `json:"stdoutLines,omitempty"`
+
+	// ScriptHash is the hash of the rendered initScript that was last executed.
+	ScriptHash string `json:"scriptHash,omitempty"`
+
+	// CleanupAttempts is the number of failed attempts to clean up the
+	// resource while it is being deleted.
+	CleanupAttempts int32 `json:"cleanupAttempts,omitempty"`
+
+	// Creations is the number of times the resource was created or
+	// recreated successfully, from which the IDEMPOTENCY_TOKEN of the next
+	// creation is derived.
+	// +optional
+	Creations int64 `json:"creations,omitempty"`
+
+	// LastCleanupAttemptTime is the time of the last failed attempt to clean
+	// up the resource, from which the backoff of the deleteRetry elapses.
+	// +optional
+	LastCleanupAttemptTime *metav1.Time `json:"lastCleanupAttemptTime,omitempty"`
+
+	// LastRunNowToken is the value of the run-now annotation that was last
+	// executed.
+	LastRunNowToken string `json:"lastRunNowToken,omitempty"`
+
+	// Diff is the stdout of the diffScript for the drift that was last
+	// detected. It is cleared once the resource is up to date.
+	// +optional
+	Diff string `json:"diff,omitempty"`
+
+	// Hosts reports the state of each host targeted through hosts or
+	// hostSelector.
+	// +optional
+	Hosts []HostStatus `json:"hosts,omitempty"`
+
+	// Host is the address of the host the scripts are executed on, or the
+	// number of targeted hosts.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// LastChecked is the time the state of the resource was last checked.
+	// +optional
+	LastChecked *metav1.Time `json:"lastChecked,omitempty"`
+
+	// LastOperation is the script last executed to change the resource.
+	// +optional
+	LastOperation Operation `json:"lastOperation,omitempty"`
+
+	// LastAppliedTime is the time a script last changed the resource
+	// successfully, from which the reapplyInterval elapses.
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	// ConsecutiveFailures is the number of consecutive failed executions of
+	// the scripts changing the resource.
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// HaltedGeneration is the generation of the Script when it was halted.
+	// +optional
+	HaltedGeneration int64 `json:"haltedGeneration,omitempty"`
+
+	// LastResumeToken is the value of the resume annotation that was last
+	// applied.
+	// +optional
+	LastResumeToken string `json:"lastResumeToken,omitempty"`
+
+	// NextCheckTime is the time before which the state of the resource is
+	// not checked again, according to the statusCheckInterval.
+	// +optional
+	NextCheckTime *metav1.Time `json:"nextCheckTime,omitempty"`
+
+	// RetryTime is the time the statusCheckScript asked the state of the
+	// resource to be checked again at, through a RETRY_AFTER output.
+	// +optional
+	RetryTime *metav1.Time `json:"retryTime,omitempty"`
+
+	// CheckedGeneration is the generation of the Script when the state of
+	// the resource was last checked.
+	// +optional
+	CheckedGeneration int64 `json:"checkedGeneration,omitempty"`
+
+	// StatusCachedUntil is the time until which the observations reuse the
+	// last result of the statusCheckScript, according to the statusCacheTTL.
+	// +optional
+	StatusCachedUntil *metav1.Time `json:"statusCachedUntil,omitempty"`
+
+	// Skipped reports whether the skipIf expression of the Script was true
+	// on the host when it was last checked.
+	// +optional
+	Skipped bool `json:"skipped,omitempty"`
+
+	// BootID is the boot ID of the host the resource was last checked or
+	// repaired on, recorded unless the rebootPolicy is Ignore.
+	// +optional
+	BootID string `json:"bootID,omitempty"`
+
+	// Outputs is the object parsed from the stdout of the statusCheckScript
+	// in its JSON or YAML outputFormat, when it last reported an existing
+	// resource.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Outputs *runtime.RawExtension `json:"outputs,omitempty"`
 }
 
 // A ScriptSpec defines the desired state of a Script.
 type ScriptSpec struct {
 	xpv1.ResourceSpec `json:",inline"`
 	ForProvider       ScriptParameters `json:"forProvider"`
+
+	// Suspend freezes the reconciliation of this Script. No connection to
+	// the remote host is made and no script is executed until it is unset.
+	// Deleting a suspended Script still runs its cleanupScript.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
 }
 
 // A ScriptStatus represents the observed state of a Script.
@@ -66,8 +1043,13 @@ type ScriptStatus struct {
 // A Script is an example API type.
 // +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
 // +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="HOST",type="string",JSONPath=".status.atProvider.host"
+// +kubebuilder:printcolumn:name="EXIT-CODE",type="integer",JSONPath=".status.atProvider.statusCode"
+// +kubebuilder:printcolumn:name="LAST-OPERATION",type="string",JSONPath=".status.atProvider.lastOperation"
+// +kubebuilder:printcolumn:name="LAST-CHECKED",type="date",JSONPath=".status.atProvider.lastChecked"
 // +kubebuilder:printcolumn:name="EXTERNAL-NAME",type="string",JSONPath=".metadata.annotations.crossplane\\.io/external-name"
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:storageversion
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
 type Script struct {
@@ -0,0 +1,196 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// ExitCodePolicy maps the exit code of a step onto its outcome.
+type ExitCodePolicy struct {
+	// SuccessCodes are the exit codes that mark the step as succeeded.
+	// Defaults to 0 only.
+	// +optional
+	SuccessCodes []int `json:"successCodes,omitempty"`
+}
+
+// A ScriptStep is a single named step of a ScriptSet.
+type ScriptStep struct {
+	// Name of the step, unique within the ScriptSet.
+	Name string `json:"name"`
+
+	// Variables of the step. They take precedence over the variables of the
+	// ScriptSet.
+	// +optional
+	Variables []Variable `json:"variables,omitempty"`
+
+	// Script executed by the step.
+	Script string `json:"script"`
+
+	// StatusCheckScript is executed for a succeeded step on every
+	// observation. A non-zero exit code runs the step and all following
+	// steps again.
+	// +optional
+	StatusCheckScript string `json:"statusCheckScript,omitempty"`
+
+	// RollbackScript is executed when the Script of the step fails.
+	// +optional
+	RollbackScript string `json:"rollbackScript,omitempty"`
+
+	// CleanupScript is executed when the ScriptSet is deleted. Cleanup runs
+	// in the reverse order of the steps.
+	// +optional
+	CleanupScript string `json:"cleanupScript,omitempty"`
+
+	// ExitCodePolicy of the Script of the step.
+	// +optional
+	ExitCodePolicy *ExitCodePolicy `json:"exitCodePolicy,omitempty"`
+}
+
+// ScriptSetParameters are the configurable fields of a ScriptSet.
+type ScriptSetParameters struct {
+	// Variables shared by all steps.
+	// +optional
+	Variables []Variable `json:"variables,omitempty"`
+
+	// Steps executed sequentially against the host.
+	// +kubebuilder:validation:MinItems=1
+	Steps []ScriptStep `json:"steps"`
+
+	// Hosts lists ProviderConfigs, Hosts or HostGroups on which the steps
+	// are executed instead of the host of the providerConfigRef. Each host
+	// runs its own pipeline.
+	// +optional
+	Hosts []HostReference `json:"hosts,omitempty"`
+
+	// Rollout controls how the steps are rolled out across the hosts. By
+	// default all hosts are executed at once.
+	// +optional
+	Rollout *RolloutStrategy `json:"rollout,omitempty"`
+
+	SudoEnabled bool `json:"sudoEnabled,omitempty"`
+}
+
+// StepPhase is the phase of a step of a ScriptSet.
+type StepPhase string
+
+// Step phases.
+const (
+	StepPending    StepPhase = "Pending"
+	StepSucceeded  StepPhase = "Succeeded"
+	StepFailed     StepPhase = "Failed"
+	StepRolledBack StepPhase = "RolledBack"
+)
+
+// StepStatus is the observed state of a step of a ScriptSet.
+type StepStatus struct {
+	Name       string    `json:"name"`
+	Phase      StepPhase `json:"phase"`
+	Stdout     string    `json:"stdout,omitempty"`
+	Stderr     string    `json:"stderr,omitempty"`
+	StatusCode int       `json:"statusCode,omitempty"`
+
+	// ScriptHash is the hash of the rendered script of the step that was
+	// last executed.
+	ScriptHash string `json:"scriptHash,omitempty"`
+
+	LastExecutionTime *metav1.Time `json:"lastExecutionTime,omitempty"`
+}
+
+// ScriptSetHostStatus is the observed state of the pipeline of a ScriptSet on
+// one of its hosts.
+type ScriptSetHostStatus struct {
+	// Kind of the object identifying the host, ProviderConfig or Host.
+	Kind string `json:"kind"`
+
+	// Name of the object identifying the host.
+	Name string `json:"name"`
+
+	// Ready reports whether all steps succeeded on the host.
+	Ready bool `json:"ready"`
+
+	// Message describes why the host is not ready, if it is not.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	Steps []StepStatus `json:"steps,omitempty"`
+}
+
+// ScriptSetObservation are the observable fields of a ScriptSet.
+type ScriptSetObservation struct {
+	Steps []StepStatus `json:"steps,omitempty"`
+
+	// Hosts reports the pipeline of each host targeted through hosts.
+	// +optional
+	Hosts []ScriptSetHostStatus `json:"hosts,omitempty"`
+}
+
+// A ScriptSetSpec defines the desired state of a ScriptSet.
+type ScriptSetSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       ScriptSetParameters `json:"forProvider"`
+}
+
+// A ScriptSetStatus represents the observed state of a ScriptSet.
+type ScriptSetStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          ScriptSetObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A ScriptSet executes an ordered list of steps against a host, or against
+// each of a set of hosts.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="EXTERNAL-NAME",type="string",JSONPath=".metadata.annotations.crossplane\\.io/external-name"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type ScriptSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScriptSetSpec   `json:"spec"`
+	Status ScriptSetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ScriptSetList contains a list of ScriptSet
+type ScriptSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScriptSet `json:"items"`
+}
+
+// ScriptSet type metadata.
+var (
+	ScriptSetKind             = reflect.TypeOf(ScriptSet{}).Name()
+	ScriptSetGroupKind        = schema.GroupKind{Group: Group, Kind: ScriptSetKind}.String()
+	ScriptSetKindAPIVersion   = ScriptSetKind + "." + SchemeGroupVersion.String()
+	ScriptSetGroupVersionKind = SchemeGroupVersion.WithKind(ScriptSetKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&ScriptSet{}, &ScriptSetList{})
+}
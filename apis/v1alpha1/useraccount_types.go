@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// UserAccountParameters are the configurable fields of a UserAccount. Fields
+// that are not set are not managed.
+type UserAccountParameters struct {
+	// Name of the user.
+	Name string `json:"name"`
+
+	// UID of the user.
+	// +optional
+	UID *int `json:"uid,omitempty"`
+
+	// Groups are the supplementary groups of the user. The groups must
+	// exist on the host.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// Shell is the login shell of the user.
+	// +optional
+	Shell string `json:"shell,omitempty"`
+
+	// Home is the home directory of the user. It is created with the user
+	// and moved if it changes.
+	// +optional
+	Home string `json:"home,omitempty"`
+
+	// AuthorizedKeys replace the content of the authorized_keys file of the
+	// user if set.
+	// +optional
+	AuthorizedKeys []string `json:"authorizedKeys,omitempty"`
+
+	// Locked disables password logins of the user if true. Unlocking a user
+	// without password fails on most hosts.
+	// +optional
+	Locked *bool `json:"locked,omitempty"`
+
+	// RemoveHome removes the home directory when the user is deleted.
+	// +optional
+	RemoveHome bool `json:"removeHome,omitempty"`
+
+	SudoEnabled bool `json:"sudoEnabled,omitempty"`
+}
+
+// UserAccountObservation are the observable fields of a UserAccount.
+type UserAccountObservation struct {
+	UID    int      `json:"uid,omitempty"`
+	GID    int      `json:"gid,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	Shell  string   `json:"shell,omitempty"`
+	Home   string   `json:"home,omitempty"`
+	Locked bool     `json:"locked,omitempty"`
+}
+
+// A UserAccountSpec defines the desired state of a UserAccount.
+type UserAccountSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       UserAccountParameters `json:"forProvider"`
+}
+
+// A UserAccountStatus represents the observed state of a UserAccount.
+type UserAccountStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          UserAccountObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A UserAccount is a POSIX user on a remote host.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="USER",type="string",JSONPath=".spec.forProvider.name"
+// +kubebuilder:printcolumn:name="UID",type="integer",JSONPath=".status.atProvider.uid"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type UserAccount struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   UserAccountSpec   `json:"spec"`
+	Status UserAccountStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// UserAccountList contains a list of UserAccount
+type UserAccountList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []UserAccount `json:"items"`
+}
+
+// UserAccount type metadata.
+var (
+	UserAccountKind             = reflect.TypeOf(UserAccount{}).Name()
+	UserAccountGroupKind        = schema.GroupKind{Group: Group, Kind: UserAccountKind}.String()
+	UserAccountKindAPIVersion   = UserAccountKind + "." + SchemeGroupVersion.String()
+	UserAccountGroupVersionKind = SchemeGroupVersion.WithKind(UserAccountKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&UserAccount{}, &UserAccountList{})
+}
@@ -0,0 +1,415 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+const (
+	errNotScript         = "hub is not a v1alpha1 Script"
+	errNotProviderConfig = "hub is not a v1alpha1 ProviderConfig"
+)
+
+// ConvertTo converts this Script to the v1alpha1 hub version.
+func (s *Script) ConvertTo(hub conversion.Hub) error {
+	dst, ok := hub.(*v1alpha1.Script)
+	if !ok {
+		return errors.New(errNotScript)
+	}
+	dst.ObjectMeta = s.ObjectMeta
+	dst.Spec.ResourceSpec = s.Spec.ResourceSpec
+	dst.Spec.Suspend = s.Spec.Suspend
+	dst.Status.ResourceStatus = s.Status.ResourceStatus
+
+	p := s.Spec.ForProvider
+	dst.Spec.ForProvider = v1alpha1.ScriptParameters{
+		InitScript:              inline(p.Scripts.Init),
+		StatusCheckScript:       inline(p.Scripts.StatusCheck),
+		ExistsScript:            inline(p.Scripts.Exists),
+		UpToDateScript:          inline(p.Scripts.UpToDate),
+		DiffScript:              inline(p.Scripts.Diff),
+		UpdateScript:            inline(p.Scripts.Update),
+		CleanupScript:           inline(p.Scripts.Cleanup),
+		PostRebootScript:        inline(p.Scripts.PostReboot),
+		SudoEnabled:             p.SudoEnabled,
+		Interpreter:             p.Interpreter,
+		RemoteTempDir:           p.RemoteTempDir,
+		Platform:                p.Platform,
+		Templating:              v1alpha1.Templating(p.Templating),
+		StrictMode:              p.StrictMode,
+		StrictModePreamble:      p.StrictModePreamble,
+		Chroot:                  p.Chroot,
+		LoginShell:              p.LoginShell,
+		Umask:                   p.Umask,
+		Locale:                  p.Locale,
+		Timezone:                p.Timezone,
+		TimeoutSeconds:          p.TimeoutSeconds,
+		MaxOutputBytes:          p.MaxOutputBytes,
+		StdoutFormat:            v1alpha1.StdoutFormat(p.StdoutFormat),
+		RecordSession:           p.RecordSession,
+		MaxConsecutiveFailures:  p.MaxConsecutiveFailures,
+		PollInterval:            p.PollInterval,
+		StatusCheckInterval:     p.StatusCheckInterval,
+		CreateGracePeriod:       p.CreateGracePeriod,
+		StatusCacheTTL:          p.StatusCacheTTL,
+		ReapplyInterval:         p.ReapplyInterval,
+		ExecutionPolicy:         v1alpha1.ExecutionPolicy(p.ExecutionPolicy),
+		UpdateStrategy:          v1alpha1.UpdateStrategy(p.UpdateStrategy),
+		UpdatePolicy:            v1alpha1.UpdatePolicy(p.UpdatePolicy),
+		InitRetry:               (*v1alpha1.InitRetryPolicy)(p.InitRetry),
+		CleanupPolicy:           v1alpha1.CleanupPolicy(p.CleanupPolicy),
+		MaxCleanupAttempts:      p.MaxCleanupAttempts,
+		CheckBeforeCleanup:      p.CheckBeforeCleanup,
+		DeleteRetry:             (*v1alpha1.DeleteRetryPolicy)(p.DeleteRetry),
+		TTLSecondsAfterFinished: p.TTLSecondsAfterFinished,
+		DeleteAfterTTL:          p.DeleteAfterTTL,
+		SkipIf:                  p.SkipIf,
+		UpToDateWhen:            p.UpToDateWhen,
+		ReadyWhen:               p.ReadyWhen,
+		ExpectedOutput:          (*v1alpha1.ExpectedOutput)(p.ExpectedOutput),
+		OutputFormat:            v1alpha1.OutputFormat(p.OutputFormat),
+		RebootPolicy:            v1alpha1.RebootPolicy(p.RebootPolicy),
+		HostSelector:            p.HostSelector,
+		SuccessThreshold:        p.SuccessThreshold,
+		VirtualMachineInstance:  (*v1alpha1.VirtualMachineInstanceTarget)(p.VirtualMachineInstance),
+	}
+	if p.Endpoint != nil {
+		dst.Spec.ForProvider.Username = p.Endpoint.Username
+		if p.Endpoint.Host != "" {
+			dst.Spec.ForProvider.Endpoint = &v1alpha1.Endpoint{Host: p.Endpoint.Host, Port: p.Endpoint.Port}
+		}
+	}
+	for _, v := range p.Variables {
+		dst.Spec.ForProvider.Variables = append(dst.Spec.ForProvider.Variables, v1alpha1.Variable(v))
+	}
+	for _, r := range p.DependsOn {
+		dst.Spec.ForProvider.DependsOn = append(dst.Spec.ForProvider.DependsOn, v1alpha1.ScriptReference(r))
+	}
+	for _, r := range p.Hosts {
+		dst.Spec.ForProvider.Hosts = append(dst.Spec.ForProvider.Hosts, v1alpha1.HostReference(r))
+	}
+	if p.ExitCodes != nil {
+		dst.Spec.ForProvider.ExitCodes = &v1alpha1.StatusCheckExitCodes{Missing: p.ExitCodes.Missing, Failed: p.ExitCodes.Failed}
+	}
+	if p.Rollout != nil {
+		dst.Spec.ForProvider.Rollout = &v1alpha1.RolloutStrategy{BatchSize: p.Rollout.BatchSize, MaxUnavailable: p.Rollout.MaxUnavailable, PauseOnFailure: p.Rollout.PauseOnFailure}
+	}
+	if p.Limits != nil {
+		dst.Spec.ForProvider.Limits = &v1alpha1.Limits{CPUSeconds: p.Limits.CPUSeconds, MaxMemoryKB: p.Limits.MaxMemoryKB, MaxFileSizeKB: p.Limits.MaxFileSizeKB}
+	}
+	if m := p.Machine; m != nil {
+		dst.Spec.ForProvider.Machine = &v1alpha1.MachineTarget{Kind: v1alpha1.MachineKind(m.Kind), Name: m.Name, Namespace: m.Namespace, AddressType: v1alpha1.MachineAddressType(m.AddressType), Port: m.Port}
+	}
+	if p.Container != nil {
+		dst.Spec.ForProvider.Container = &v1alpha1.Container{Engine: v1alpha1.ContainerEngine(p.Container.Engine), Name: p.Container.Name}
+	}
+	if p.Namespaces != nil {
+		dst.Spec.ForProvider.Namespaces = &v1alpha1.Namespaces{TargetPID: p.Namespaces.TargetPID}
+		for _, t := range p.Namespaces.Types {
+			dst.Spec.ForProvider.Namespaces.Types = append(dst.Spec.ForProvider.Namespaces.Types, v1alpha1.NamespaceType(t))
+		}
+	}
+	if p.Priority != nil {
+		dst.Spec.ForProvider.Priority = &v1alpha1.Priority{Nice: p.Priority.Nice}
+		if io := p.Priority.IONice; io != nil {
+			dst.Spec.ForProvider.Priority.IONice = &v1alpha1.IONice{Class: v1alpha1.IONiceClass(io.Class), Level: io.Level}
+		}
+	}
+
+	o := s.Status.AtProvider
+	dst.Status.AtProvider = v1alpha1.ScriptObservation{
+		Host:                   o.Host,
+		LastChecked:            o.LastChecked,
+		LastAppliedTime:        o.LastAppliedTime,
+		Stdout:                 o.Stdout,
+		StdoutLines:            o.StdoutLines,
+		Stderr:                 o.Stderr,
+		StatusCode:             o.StatusCode,
+		ScriptHash:             o.ScriptHash,
+		CleanupAttempts:        o.CleanupAttempts,
+		Creations:              o.Creations,
+		LastCleanupAttemptTime: o.LastCleanupAttemptTime,
+		LastRunNowToken:        o.LastRunNowToken,
+		Diff:                   o.Diff,
+		LastOperation:          v1alpha1.Operation(o.LastOperation),
+		ConsecutiveFailures:    o.ConsecutiveFailures,
+		HaltedGeneration:       o.HaltedGeneration,
+		LastResumeToken:        o.LastResumeToken,
+		NextCheckTime:          o.NextCheckTime,
+		RetryTime:              o.RetryTime,
+		CheckedGeneration:      o.CheckedGeneration,
+		StatusCachedUntil:      o.StatusCachedUntil,
+		Skipped:                o.Skipped,
+		BootID:                 o.BootID,
+		Outputs:                o.Outputs,
+	}
+	for _, h := range o.Hosts {
+		dst.Status.AtProvider.Hosts = append(dst.Status.AtProvider.Hosts, v1alpha1.HostStatus(h))
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub version to this Script.
+func (s *Script) ConvertFrom(hub conversion.Hub) error {
+	src, ok := hub.(*v1alpha1.Script)
+	if !ok {
+		return errors.New(errNotScript)
+	}
+	s.ObjectMeta = src.ObjectMeta
+	s.Spec.ResourceSpec = src.Spec.ResourceSpec
+	s.Spec.Suspend = src.Spec.Suspend
+	s.Status.ResourceStatus = src.Status.ResourceStatus
+
+	p := src.Spec.ForProvider
+	s.Spec.ForProvider = ScriptParameters{
+		Scripts: Scripts{
+			Init:        source(p.InitScript),
+			StatusCheck: source(p.StatusCheckScript),
+			Exists:      source(p.ExistsScript),
+			UpToDate:    source(p.UpToDateScript),
+			Diff:        source(p.DiffScript),
+			Update:      source(p.UpdateScript),
+			Cleanup:     source(p.CleanupScript),
+			PostReboot:  source(p.PostRebootScript),
+		},
+		SudoEnabled:             p.SudoEnabled,
+		Interpreter:             p.Interpreter,
+		RemoteTempDir:           p.RemoteTempDir,
+		Platform:                p.Platform,
+		Templating:              Templating(p.Templating),
+		StrictMode:              p.StrictMode,
+		StrictModePreamble:      p.StrictModePreamble,
+		Chroot:                  p.Chroot,
+		LoginShell:              p.LoginShell,
+		Umask:                   p.Umask,
+		Locale:                  p.Locale,
+		Timezone:                p.Timezone,
+		TimeoutSeconds:          p.TimeoutSeconds,
+		MaxOutputBytes:          p.MaxOutputBytes,
+		StdoutFormat:            StdoutFormat(p.StdoutFormat),
+		RecordSession:           p.RecordSession,
+		MaxConsecutiveFailures:  p.MaxConsecutiveFailures,
+		PollInterval:            p.PollInterval,
+		StatusCheckInterval:     p.StatusCheckInterval,
+		CreateGracePeriod:       p.CreateGracePeriod,
+		StatusCacheTTL:          p.StatusCacheTTL,
+		ReapplyInterval:         p.ReapplyInterval,
+		ExecutionPolicy:         ExecutionPolicy(p.ExecutionPolicy),
+		UpdateStrategy:          UpdateStrategy(p.UpdateStrategy),
+		UpdatePolicy:            UpdatePolicy(p.UpdatePolicy),
+		InitRetry:               (*InitRetryPolicy)(p.InitRetry),
+		CleanupPolicy:           CleanupPolicy(p.CleanupPolicy),
+		MaxCleanupAttempts:      p.MaxCleanupAttempts,
+		CheckBeforeCleanup:      p.CheckBeforeCleanup,
+		DeleteRetry:             (*DeleteRetryPolicy)(p.DeleteRetry),
+		TTLSecondsAfterFinished: p.TTLSecondsAfterFinished,
+		DeleteAfterTTL:          p.DeleteAfterTTL,
+		SkipIf:                  p.SkipIf,
+		UpToDateWhen:            p.UpToDateWhen,
+		ReadyWhen:               p.ReadyWhen,
+		ExpectedOutput:          (*ExpectedOutput)(p.ExpectedOutput),
+		OutputFormat:            OutputFormat(p.OutputFormat),
+		RebootPolicy:            RebootPolicy(p.RebootPolicy),
+		HostSelector:            p.HostSelector,
+		SuccessThreshold:        p.SuccessThreshold,
+		VirtualMachineInstance:  (*VirtualMachineInstanceTarget)(p.VirtualMachineInstance),
+	}
+	if p.Endpoint != nil || p.Username != "" {
+		s.Spec.ForProvider.Endpoint = &Endpoint{Username: p.Username}
+		if p.Endpoint != nil {
+			s.Spec.ForProvider.Endpoint.Host = p.Endpoint.Host
+			s.Spec.ForProvider.Endpoint.Port = p.Endpoint.Port
+		}
+	}
+	for _, v := range p.Variables {
+		s.Spec.ForProvider.Variables = append(s.Spec.ForProvider.Variables, Variable(v))
+	}
+	for _, r := range p.DependsOn {
+		s.Spec.ForProvider.DependsOn = append(s.Spec.ForProvider.DependsOn, ScriptReference(r))
+	}
+	for _, r := range p.Hosts {
+		s.Spec.ForProvider.Hosts = append(s.Spec.ForProvider.Hosts, HostReference(r))
+	}
+	if p.ExitCodes != nil {
+		s.Spec.ForProvider.ExitCodes = &StatusCheckExitCodes{Missing: p.ExitCodes.Missing, Failed: p.ExitCodes.Failed}
+	}
+	if p.Rollout != nil {
+		s.Spec.ForProvider.Rollout = &RolloutStrategy{BatchSize: p.Rollout.BatchSize, MaxUnavailable: p.Rollout.MaxUnavailable, PauseOnFailure: p.Rollout.PauseOnFailure}
+	}
+	if p.Limits != nil {
+		s.Spec.ForProvider.Limits = &Limits{CPUSeconds: p.Limits.CPUSeconds, MaxMemoryKB: p.Limits.MaxMemoryKB, MaxFileSizeKB: p.Limits.MaxFileSizeKB}
+	}
+	if m := p.Machine; m != nil {
+		s.Spec.ForProvider.Machine = &MachineTarget{Kind: MachineKind(m.Kind), Name: m.Name, Namespace: m.Namespace, AddressType: MachineAddressType(m.AddressType), Port: m.Port}
+	}
+	if p.Container != nil {
+		s.Spec.ForProvider.Container = &Container{Engine: ContainerEngine(p.Container.Engine), Name: p.Container.Name}
+	}
+	if p.Namespaces != nil {
+		s.Spec.ForProvider.Namespaces = &Namespaces{TargetPID: p.Namespaces.TargetPID}
+		for _, t := range p.Namespaces.Types {
+			s.Spec.ForProvider.Namespaces.Types = append(s.Spec.ForProvider.Namespaces.Types, NamespaceType(t))
+		}
+	}
+	if p.Priority != nil {
+		s.Spec.ForProvider.Priority = &Priority{Nice: p.Priority.Nice}
+		if io := p.Priority.IONice; io != nil {
+			s.Spec.ForProvider.Priority.IONice = &IONice{Class: IONiceClass(io.Class), Level: io.Level}
+		}
+	}
+
+	o := src.Status.AtProvider
+	s.Status.AtProvider = ScriptObservation{
+		Host:                   o.Host,
+		LastChecked:            o.LastChecked,
+		LastAppliedTime:        o.LastAppliedTime,
+		Stdout:                 o.Stdout,
+		StdoutLines:            o.StdoutLines,
+		Stderr:                 o.Stderr,
+		StatusCode:             o.StatusCode,
+		ScriptHash:             o.ScriptHash,
+		CleanupAttempts:        o.CleanupAttempts,
+		Creations:              o.Creations,
+		LastCleanupAttemptTime: o.LastCleanupAttemptTime,
+		LastRunNowToken:        o.LastRunNowToken,
+		Diff:                   o.Diff,
+		LastOperation:          Operation(o.LastOperation),
+		ConsecutiveFailures:    o.ConsecutiveFailures,
+		HaltedGeneration:       o.HaltedGeneration,
+		LastResumeToken:        o.LastResumeToken,
+		NextCheckTime:          o.NextCheckTime,
+		RetryTime:              o.RetryTime,
+		CheckedGeneration:      o.CheckedGeneration,
+		StatusCachedUntil:      o.StatusCachedUntil,
+		Skipped:                o.Skipped,
+		BootID:                 o.BootID,
+		Outputs:                o.Outputs,
+	}
+	for _, h := range o.Hosts {
+		s.Status.AtProvider.Hosts = append(s.Status.AtProvider.Hosts, HostStatus(h))
+	}
+	return nil
+}
+
+// inline returns the content of the supplied script, if any.
+func inline(s *ScriptSource) string {
+	if s == nil {
+		return ""
+	}
+	return s.Inline
+}
+
+// source returns the supplied script content as a ScriptSource, or nil if it
+// is empty.
+func source(content string) *ScriptSource {
+	if content == "" {
+		return nil
+	}
+	return &ScriptSource{Inline: content}
+}
+
+// ConvertTo converts this ProviderConfig to the v1alpha1 hub version.
+func (pc *ProviderConfig) ConvertTo(hub conversion.Hub) error {
+	dst, ok := hub.(*v1alpha1.ProviderConfig)
+	if !ok {
+		return errors.New(errNotProviderConfig)
+	}
+	dst.ObjectMeta = pc.ObjectMeta
+	dst.Status.ProviderConfigStatus = pc.Status.ProviderConfigStatus
+
+	c := pc.Spec.Credentials
+	dst.Spec.Credentials = v1alpha1.ProviderCredentials{
+		Source:                    c.Source,
+		CommonCredentialSelectors: c.CommonCredentialSelectors,
+		Username:                  (*v1alpha1.CredentialValue)(c.Username),
+		Password:                  (*v1alpha1.CredentialValue)(c.Password),
+		PrivateKey:                (*v1alpha1.CredentialValue)(c.PrivateKey),
+		KnownHosts:                (*v1alpha1.CredentialValue)(c.KnownHosts),
+	}
+	if e := pc.Spec.Endpoint; e != nil {
+		dst.Spec.Credentials.HostIP = (*v1alpha1.CredentialValue)(e.Host)
+		dst.Spec.Credentials.HostPort = (*v1alpha1.CredentialValue)(e.Port)
+		dst.Spec.Service = (*v1alpha1.ServiceEndpoints)(e.Service)
+	}
+	if t := pc.Spec.Transfer; t != nil {
+		dst.Spec.Transfer = &v1alpha1.TransferSettings{Compression: v1alpha1.TransferCompression(t.Compression), BandwidthLimitKB: t.BandwidthLimitKB}
+	}
+	dst.Spec.Alias = pc.Spec.Alias
+	if t := pc.Spec.Teleport; t != nil {
+		dst.Spec.Teleport = &v1alpha1.TeleportSettings{ProxyAddress: t.ProxyAddress, Cluster: t.Cluster, Identity: v1alpha1.CredentialValue(t.Identity)}
+	}
+	if w := pc.Spec.WebSocket; w != nil {
+		dst.Spec.WebSocket = &v1alpha1.WebSocketSettings{URL: w.URL, Token: (*v1alpha1.CredentialValue)(w.Token), TokenHeader: w.TokenHeader}
+	}
+	if k := pc.Spec.KeyProvisioner; k != nil {
+		dst.Spec.KeyProvisioner = &v1alpha1.KeyProvisionerSettings{Webhook: v1alpha1.WebhookKeyProvisioner{URL: k.Webhook.URL, Token: (*v1alpha1.CredentialValue)(k.Webhook.Token)}}
+	}
+	dst.Spec.AllowedNamespaces = pc.Spec.AllowedNamespaces
+	dst.Spec.AllowedResourceSelector = pc.Spec.AllowedResourceSelector
+	dst.Spec.MaxManagedResources = pc.Spec.MaxManagedResources
+	dst.Spec.Defaults = (*v1alpha1.ScriptDefaults)(pc.Spec.Defaults)
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub version to this ProviderConfig.
+func (pc *ProviderConfig) ConvertFrom(hub conversion.Hub) error {
+	src, ok := hub.(*v1alpha1.ProviderConfig)
+	if !ok {
+		return errors.New(errNotProviderConfig)
+	}
+	pc.ObjectMeta = src.ObjectMeta
+	pc.Status.ProviderConfigStatus = src.Status.ProviderConfigStatus
+
+	c := src.Spec.Credentials
+	pc.Spec.Credentials = ProviderCredentials{
+		Source:                    c.Source,
+		CommonCredentialSelectors: c.CommonCredentialSelectors,
+		Username:                  (*CredentialValue)(c.Username),
+		Password:                  (*CredentialValue)(c.Password),
+		PrivateKey:                (*CredentialValue)(c.PrivateKey),
+		KnownHosts:                (*CredentialValue)(c.KnownHosts),
+	}
+	if c.HostIP != nil || c.HostPort != nil || src.Spec.Service != nil {
+		pc.Spec.Endpoint = &ProviderEndpoint{
+			Host:    (*CredentialValue)(c.HostIP),
+			Port:    (*CredentialValue)(c.HostPort),
+			Service: (*ServiceEndpoints)(src.Spec.Service),
+		}
+	}
+	if t := src.Spec.Transfer; t != nil {
+		pc.Spec.Transfer = &TransferSettings{Compression: TransferCompression(t.Compression), BandwidthLimitKB: t.BandwidthLimitKB}
+	}
+	pc.Spec.Alias = src.Spec.Alias
+	if t := src.Spec.Teleport; t != nil {
+		pc.Spec.Teleport = &TeleportSettings{ProxyAddress: t.ProxyAddress, Cluster: t.Cluster, Identity: CredentialValue(t.Identity)}
+	}
+	if w := src.Spec.WebSocket; w != nil {
+		pc.Spec.WebSocket = &WebSocketSettings{URL: w.URL, Token: (*CredentialValue)(w.Token), TokenHeader: w.TokenHeader}
+	}
+	if k := src.Spec.KeyProvisioner; k != nil {
+		pc.Spec.KeyProvisioner = &KeyProvisionerSettings{Webhook: WebhookKeyProvisioner{URL: k.Webhook.URL, Token: (*CredentialValue)(k.Webhook.Token)}}
+	}
+	pc.Spec.AllowedNamespaces = src.Spec.AllowedNamespaces
+	pc.Spec.AllowedResourceSelector = src.Spec.AllowedResourceSelector
+	pc.Spec.MaxManagedResources = src.Spec.MaxManagedResources
+	pc.Spec.Defaults = (*ScriptDefaults)(src.Spec.Defaults)
+	return nil
+}
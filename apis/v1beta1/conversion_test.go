@@ -0,0 +1,483 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+func TestScriptConversion(t *testing.T) {
+	port, missing, batch, attempts, nice, cpu := 2222, 3, 2, int32(5), int32(10), int64(60)
+	sudo := true
+	threshold := intstr.FromString("50%")
+	checked := metav1.Now()
+	reapply := metav1.Duration{Duration: 6 * time.Hour}
+
+	cases := map[string]struct {
+		reason string
+		hub    *v1alpha1.Script
+		spoke  *Script
+	}{
+		"Full": {
+			reason: "Every field should be converted between v1alpha1 and v1beta1.",
+			hub: &v1alpha1.Script{
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx"},
+				Spec: v1alpha1.ScriptSpec{
+					ResourceSpec: xpv1.ResourceSpec{ProviderConfigReference: &xpv1.Reference{Name: "web"}},
+					ForProvider: v1alpha1.ScriptParameters{
+						Variables:          []v1alpha1.Variable{{Name: "PKG", Value: "nginx"}},
+						InitScript:         "apt-get install -y {{PKG}}",
+						StatusCheckScript:  "dpkg -s {{PKG}}",
+						UpdateScript:       "apt-get upgrade -y {{PKG}}",
+						CleanupScript:      "apt-get remove -y {{PKG}}",
+						DiffScript:         "apt list --upgradable",
+						PostRebootScript:   "systemctl restart nginx",
+						SudoEnabled:        &sudo,
+						DependsOn:          []v1alpha1.ScriptReference{{Name: "apt"}},
+						InitRetry:          &v1alpha1.InitRetryPolicy{Attempts: 3, Delay: &reapply},
+						CleanupPolicy:      v1alpha1.CleanupPolicyBestEffort,
+						UpdatePolicy:       v1alpha1.UpdatePolicyNever,
+						ReapplyInterval:    &reapply,
+						CreateGracePeriod:  &reapply,
+						StatusCacheTTL:     &reapply,
+						MaxCleanupAttempts: &attempts,
+						CheckBeforeCleanup: true,
+						DeleteRetry:        &v1alpha1.DeleteRetryPolicy{Attempts: &attempts, Backoff: &reapply, ThenOrphan: true},
+						Hosts:              []v1alpha1.HostReference{{Kind: "Host", Name: "web-1"}},
+						SuccessThreshold:   &threshold,
+						Endpoint:           &v1alpha1.Endpoint{Host: "10.0.0.1", Port: &port},
+						Username:           "deploy",
+						Rollout:            &v1alpha1.RolloutStrategy{BatchSize: &batch},
+						Limits:             &v1alpha1.Limits{CPUSeconds: &cpu},
+						Priority:           &v1alpha1.Priority{Nice: &nice, IONice: &v1alpha1.IONice{Class: v1alpha1.IONiceClassIdle}},
+						Interpreter:        "/bin/bash",
+						RemoteTempDir:      "/var/tmp",
+						Platform:           "Linux/x86_64",
+						Templating:         v1alpha1.TemplatingBraces,
+						StrictMode:         true,
+						Container:          &v1alpha1.Container{Engine: v1alpha1.ContainerEnginePodman, Name: "gateway"},
+						Namespaces:         &v1alpha1.Namespaces{TargetPID: 1, Types: []v1alpha1.NamespaceType{v1alpha1.NamespaceTypeMount}},
+						Chroot:             "/sysroot",
+						LoginShell:         true,
+						Umask:              "027",
+						Locale:             "C.UTF-8",
+						Timezone:           "UTC",
+						RecordSession:      &sudo,
+						ExitCodes:          &v1alpha1.StatusCheckExitCodes{Missing: &missing, Failed: []int{1, 2}},
+						SkipIf:             `facts.os == "ubuntu"`,
+						UpToDateWhen:       `outputs.version == vars.VERSION`,
+						ReadyWhen:          `exitCode == 0`,
+						ExpectedOutput:     &v1alpha1.ExpectedOutput{Regex: `^1\.2\.`},
+						OutputFormat:       v1alpha1.OutputFormatYAML,
+						StdoutFormat:       v1alpha1.StdoutFormatLines,
+						RebootPolicy:       v1alpha1.RebootPolicyUpdate,
+					},
+					Suspend: true,
+				},
+				Status: v1alpha1.ScriptStatus{
+					AtProvider: v1alpha1.ScriptObservation{
+						Stdout:                 "installed",
+						StdoutLines:            []string{"installed"},
+						StatusCode:             105,
+						ScriptHash:             "abc",
+						Hosts:                  []v1alpha1.HostStatus{{Kind: "Host", Name: "web-1", Ready: true, Skipped: true}},
+						Host:                   "1 hosts",
+						LastChecked:            &checked,
+						LastAppliedTime:        &checked,
+						LastCleanupAttemptTime: &checked,
+						RetryTime:              &checked,
+						StatusCachedUntil:      &checked,
+						LastOperation:          v1alpha1.OperationUpdate,
+						Skipped:                true,
+						BootID:                 "8a1c4b1e-3f5d-4c2a-9b7e-6d0f1e2a3b4c",
+						Creations:              2,
+						Outputs:                &runtime.RawExtension{Raw: []byte(`{"version":"1.2.3"}`)},
+					},
+				},
+			},
+			spoke: &Script{
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx"},
+				Spec: ScriptSpec{
+					ResourceSpec: xpv1.ResourceSpec{ProviderConfigReference: &xpv1.Reference{Name: "web"}},
+					ForProvider: ScriptParameters{
+						Endpoint: &Endpoint{Host: "10.0.0.1", Port: &port, Username: "deploy"},
+						Scripts: Scripts{
+							Init:        &ScriptSource{Inline: "apt-get install -y {{PKG}}"},
+							StatusCheck: &ScriptSource{Inline: "dpkg -s {{PKG}}"},
+							Diff:        &ScriptSource{Inline: "apt list --upgradable"},
+							Update:      &ScriptSource{Inline: "apt-get upgrade -y {{PKG}}"},
+							Cleanup:     &ScriptSource{Inline: "apt-get remove -y {{PKG}}"},
+							PostReboot:  &ScriptSource{Inline: "systemctl restart nginx"},
+						},
+						Variables:          []Variable{{Name: "PKG", Value: "nginx"}},
+						SudoEnabled:        &sudo,
+						Interpreter:        "/bin/bash",
+						RemoteTempDir:      "/var/tmp",
+						Platform:           "Linux/x86_64",
+						Templating:         TemplatingBraces,
+						StrictMode:         true,
+						Container:          &Container{Engine: ContainerEnginePodman, Name: "gateway"},
+						Namespaces:         &Namespaces{TargetPID: 1, Types: []NamespaceType{NamespaceTypeMount}},
+						Chroot:             "/sysroot",
+						LoginShell:         true,
+						Umask:              "027",
+						Locale:             "C.UTF-8",
+						Timezone:           "UTC",
+						RecordSession:      &sudo,
+						ExitCodes:          &StatusCheckExitCodes{Missing: &missing, Failed: []int{1, 2}},
+						SkipIf:             `facts.os == "ubuntu"`,
+						UpToDateWhen:       `outputs.version == vars.VERSION`,
+						ReadyWhen:          `exitCode == 0`,
+						ExpectedOutput:     &ExpectedOutput{Regex: `^1\.2\.`},
+						OutputFormat:       OutputFormatYAML,
+						StdoutFormat:       StdoutFormatLines,
+						RebootPolicy:       RebootPolicyUpdate,
+						DependsOn:          []ScriptReference{{Name: "apt"}},
+						InitRetry:          &InitRetryPolicy{Attempts: 3, Delay: &reapply},
+						CleanupPolicy:      CleanupPolicyBestEffort,
+						UpdatePolicy:       UpdatePolicyNever,
+						ReapplyInterval:    &reapply,
+						CreateGracePeriod:  &reapply,
+						StatusCacheTTL:     &reapply,
+						MaxCleanupAttempts: &attempts,
+						CheckBeforeCleanup: true,
+						DeleteRetry:        &DeleteRetryPolicy{Attempts: &attempts, Backoff: &reapply, ThenOrphan: true},
+						Hosts:              []HostReference{{Kind: "Host", Name: "web-1"}},
+						SuccessThreshold:   &threshold,
+						Rollout:            &RolloutStrategy{BatchSize: &batch},
+						Limits:             &Limits{CPUSeconds: &cpu},
+						Priority:           &Priority{Nice: &nice, IONice: &IONice{Class: IONiceClassIdle}},
+					},
+					Suspend: true,
+				},
+				Status: ScriptStatus{
+					AtProvider: ScriptObservation{
+						Stdout:                 "installed",
+						StdoutLines:            []string{"installed"},
+						StatusCode:             105,
+						ScriptHash:             "abc",
+						Hosts:                  []HostStatus{{Kind: "Host", Name: "web-1", Ready: true, Skipped: true}},
+						Host:                   "1 hosts",
+						LastChecked:            &checked,
+						LastAppliedTime:        &checked,
+						LastCleanupAttemptTime: &checked,
+						RetryTime:              &checked,
+						StatusCachedUntil:      &checked,
+						LastOperation:          OperationUpdate,
+						Skipped:                true,
+						BootID:                 "8a1c4b1e-3f5d-4c2a-9b7e-6d0f1e2a3b4c",
+						Creations:              2,
+						Outputs:                &runtime.RawExtension{Raw: []byte(`{"version":"1.2.3"}`)},
+					},
+				},
+			},
+		},
+		"UsernameOnly": {
+			reason: "A username without an endpoint should be converted to an endpoint without a host.",
+			hub:    &v1alpha1.Script{Spec: v1alpha1.ScriptSpec{ForProvider: v1alpha1.ScriptParameters{Username: "deploy"}}},
+			spoke:  &Script{Spec: ScriptSpec{ForProvider: ScriptParameters{Endpoint: &Endpoint{Username: "deploy"}}}},
+		},
+		"VirtualMachineInstance": {
+			reason: "A VirtualMachineInstance target should be converted along with the username of the endpoint.",
+			hub: &v1alpha1.Script{Spec: v1alpha1.ScriptSpec{ForProvider: v1alpha1.ScriptParameters{
+				VirtualMachineInstance: &v1alpha1.VirtualMachineInstanceTarget{Name: "web", Namespace: "vms", Interface: "default", Port: &port},
+				Username:               "fedora",
+			}}},
+			spoke: &Script{Spec: ScriptSpec{ForProvider: ScriptParameters{
+				Endpoint:               &Endpoint{Username: "fedora"},
+				VirtualMachineInstance: &VirtualMachineInstanceTarget{Name: "web", Namespace: "vms", Interface: "default", Port: &port},
+			}}},
+		},
+		"Machine": {
+			reason: "A Machine target should be converted.",
+			hub: &v1alpha1.Script{Spec: v1alpha1.ScriptSpec{ForProvider: v1alpha1.ScriptParameters{
+				Machine: &v1alpha1.MachineTarget{Kind: v1alpha1.MachineKindMetal3Machine, Name: "edge-0", Namespace: "capi", AddressType: v1alpha1.MachineAddressInternalIP, Port: &port},
+			}}},
+			spoke: &Script{Spec: ScriptSpec{ForProvider: ScriptParameters{
+				Machine: &MachineTarget{Kind: MachineKindMetal3Machine, Name: "edge-0", Namespace: "capi", AddressType: MachineAddressInternalIP, Port: &port},
+			}}},
+		},
+		"Empty": {
+			reason: "Unset scripts and endpoints should remain unset.",
+			hub:    &v1alpha1.Script{},
+			spoke:  &Script{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			spoke := &Script{}
+			err := spoke.ConvertFrom(tc.hub)
+			if diff := cmp.Diff(nil, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nConvertFrom(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.spoke, spoke); diff != "" {
+				t.Errorf("\n%s\nConvertFrom(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+
+			hub := &v1alpha1.Script{}
+			err = tc.spoke.ConvertTo(hub)
+			if diff := cmp.Diff(nil, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nConvertTo(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.hub, hub); diff != "" {
+				t.Errorf("\n%s\nConvertTo(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestProviderConfigConversion(t *testing.T) {
+	secret := xpv1.CommonCredentialSelectors{SecretRef: &xpv1.SecretKeySelector{
+		SecretReference: xpv1.SecretReference{Name: "ssh", Namespace: "crossplane-system"},
+		Key:             "credentials",
+	}}
+	limit, quota, timeout, sudo := int64(512), int64(20), int64(120), true
+
+	cases := map[string]struct {
+		reason string
+		hub    *v1alpha1.ProviderConfig
+		spoke  *ProviderConfig
+	}{
+		"Endpoint": {
+			reason: "The host and port of the credentials should be converted to the endpoint, the alias as it is.",
+			hub: &v1alpha1.ProviderConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "web"},
+				Spec: v1alpha1.ProviderConfigSpec{
+					Alias: "paris-edge-01",
+					Credentials: v1alpha1.ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+						HostIP:                    &v1alpha1.CredentialValue{Value: "10.0.0.1"},
+						HostPort:                  &v1alpha1.CredentialValue{Value: "2222"},
+						Username:                  &v1alpha1.CredentialValue{Value: "deploy"},
+						KnownHosts:                &v1alpha1.CredentialValue{Source: xpv1.CredentialsSourceFilesystem},
+					},
+				},
+			},
+			spoke: &ProviderConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "web"},
+				Spec: ProviderConfigSpec{
+					Alias: "paris-edge-01",
+					Endpoint: &ProviderEndpoint{
+						Host: &CredentialValue{Value: "10.0.0.1"},
+						Port: &CredentialValue{Value: "2222"},
+					},
+					Credentials: ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+						Username:                  &CredentialValue{Value: "deploy"},
+						KnownHosts:                &CredentialValue{Source: xpv1.CredentialsSourceFilesystem},
+					},
+				},
+			},
+		},
+		"Service": {
+			reason: "The Service of the ProviderConfig should be converted to the endpoint.",
+			hub: &v1alpha1.ProviderConfig{
+				Spec: v1alpha1.ProviderConfigSpec{
+					Credentials: v1alpha1.ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+					},
+					Service: &v1alpha1.ServiceEndpoints{Name: "bastions", Namespace: "edge", Port: "ssh"},
+				},
+			},
+			spoke: &ProviderConfig{
+				Spec: ProviderConfigSpec{
+					Endpoint: &ProviderEndpoint{
+						Service: &ServiceEndpoints{Name: "bastions", Namespace: "edge", Port: "ssh"},
+					},
+					Credentials: ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+					},
+				},
+			},
+		},
+		"TransferAndTeleport": {
+			reason: "The transfer and Teleport settings should be converted as they are.",
+			hub: &v1alpha1.ProviderConfig{
+				Spec: v1alpha1.ProviderConfigSpec{
+					Credentials: v1alpha1.ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+					},
+					Transfer: &v1alpha1.TransferSettings{Compression: v1alpha1.TransferCompressionGzip, BandwidthLimitKB: &limit},
+					Teleport: &v1alpha1.TeleportSettings{
+						ProxyAddress: "teleport.example.com:3023",
+						Cluster:      "edge",
+						Identity:     v1alpha1.CredentialValue{Source: xpv1.CredentialsSourceSecret, CommonCredentialSelectors: secret},
+					},
+				},
+			},
+			spoke: &ProviderConfig{
+				Spec: ProviderConfigSpec{
+					Credentials: ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+					},
+					Transfer: &TransferSettings{Compression: TransferCompressionGzip, BandwidthLimitKB: &limit},
+					Teleport: &TeleportSettings{
+						ProxyAddress: "teleport.example.com:3023",
+						Cluster:      "edge",
+						Identity:     CredentialValue{Source: xpv1.CredentialsSourceSecret, CommonCredentialSelectors: secret},
+					},
+				},
+			},
+		},
+		"WebSocketAndKeyProvisioner": {
+			reason: "The WebSocket and key provisioner settings should be converted as they are.",
+			hub: &v1alpha1.ProviderConfig{
+				Spec: v1alpha1.ProviderConfigSpec{
+					Credentials: v1alpha1.ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+					},
+					WebSocket: &v1alpha1.WebSocketSettings{
+						URL:         "wss://ssh.example.com",
+						Token:       &v1alpha1.CredentialValue{Source: xpv1.CredentialsSourceSecret, CommonCredentialSelectors: secret},
+						TokenHeader: "cf-access-token",
+					},
+					KeyProvisioner: &v1alpha1.KeyProvisionerSettings{Webhook: v1alpha1.WebhookKeyProvisioner{
+						URL:   "https://keys.example.com/provision",
+						Token: &v1alpha1.CredentialValue{Source: xpv1.CredentialsSourceSecret, CommonCredentialSelectors: secret},
+					}},
+				},
+			},
+			spoke: &ProviderConfig{
+				Spec: ProviderConfigSpec{
+					Credentials: ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+					},
+					WebSocket: &WebSocketSettings{
+						URL:         "wss://ssh.example.com",
+						Token:       &CredentialValue{Source: xpv1.CredentialsSourceSecret, CommonCredentialSelectors: secret},
+						TokenHeader: "cf-access-token",
+					},
+					KeyProvisioner: &KeyProvisionerSettings{Webhook: WebhookKeyProvisioner{
+						URL:   "https://keys.example.com/provision",
+						Token: &CredentialValue{Source: xpv1.CredentialsSourceSecret, CommonCredentialSelectors: secret},
+					}},
+				},
+			},
+		},
+		"Authorization": {
+			reason: "The namespaces and the managed resources allowed to use the ProviderConfig should be converted as they are.",
+			hub: &v1alpha1.ProviderConfig{
+				Spec: v1alpha1.ProviderConfigSpec{
+					Credentials: v1alpha1.ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+					},
+					AllowedNamespaces:       []string{"team-a"},
+					AllowedResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+				},
+			},
+			spoke: &ProviderConfig{
+				Spec: ProviderConfigSpec{
+					Credentials: ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+					},
+					AllowedNamespaces:       []string{"team-a"},
+					AllowedResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+				},
+			},
+		},
+		"QuotaAndDefaults": {
+			reason: "The maximum number of managed resources using the ProviderConfig and the defaults of its Scripts should be converted as they are.",
+			hub: &v1alpha1.ProviderConfig{
+				Spec: v1alpha1.ProviderConfigSpec{
+					Credentials: v1alpha1.ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+					},
+					MaxManagedResources: &quota,
+					Defaults:            &v1alpha1.ScriptDefaults{SudoEnabled: &sudo, Interpreter: "/bin/bash", RemoteTempDir: "/var/tmp", TimeoutSeconds: &timeout, RecordSession: &sudo},
+				},
+			},
+			spoke: &ProviderConfig{
+				Spec: ProviderConfigSpec{
+					Credentials: ProviderCredentials{
+						Source:                    xpv1.CredentialsSourceSecret,
+						CommonCredentialSelectors: secret,
+					},
+					MaxManagedResources: &quota,
+					Defaults:            &ScriptDefaults{SudoEnabled: &sudo, Interpreter: "/bin/bash", RemoteTempDir: "/var/tmp", TimeoutSeconds: &timeout, RecordSession: &sudo},
+				},
+			},
+		},
+		"NoEndpoint": {
+			reason: "Credentials without a host or port should be converted without an endpoint.",
+			hub: &v1alpha1.ProviderConfig{
+				Spec: v1alpha1.ProviderConfigSpec{Credentials: v1alpha1.ProviderCredentials{
+					Source:                    xpv1.CredentialsSourceSecret,
+					CommonCredentialSelectors: secret,
+				}},
+			},
+			spoke: &ProviderConfig{
+				Spec: ProviderConfigSpec{Credentials: ProviderCredentials{
+					Source:                    xpv1.CredentialsSourceSecret,
+					CommonCredentialSelectors: secret,
+				}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			spoke := &ProviderConfig{}
+			err := spoke.ConvertFrom(tc.hub)
+			if diff := cmp.Diff(nil, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nConvertFrom(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.spoke, spoke); diff != "" {
+				t.Errorf("\n%s\nConvertFrom(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+
+			hub := &v1alpha1.ProviderConfig{}
+			err = tc.spoke.ConvertTo(hub)
+			if diff := cmp.Diff(nil, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nConvertTo(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.hub, hub); diff != "" {
+				t.Errorf("\n%s\nConvertTo(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,1047 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// An Endpoint overrides the SSH server and the remote user of the
+// ProviderConfig, whose credentials are used to authenticate.
+// +kubebuilder:validation:XValidation:rule="!has(self.port) || has(self.host)",message="port requires host"
+type Endpoint struct {
+	// Host is the IP address or DNS name of the SSH server.
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^([A-Za-z0-9]([-A-Za-z0-9]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([-A-Za-z0-9]{0,61}[A-Za-z0-9])?)*\.?|[0-9A-Fa-f.]*:[0-9A-Fa-f:.]*)$`
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// Port of the SSH server.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port *int `json:"port,omitempty"`
+
+	// Username of the remote user the scripts are executed as. The
+	// credentials must be accepted for this user.
+	// +optional
+	Username string `json:"username,omitempty"`
+}
+
+// A VirtualMachineInstanceTarget references a KubeVirt
+// VirtualMachineInstance.
+type VirtualMachineInstanceTarget struct {
+	// Name of the VirtualMachineInstance.
+	Name string `json:"name"`
+
+	// Namespace of the VirtualMachineInstance.
+	Namespace string `json:"namespace"`
+
+	// Interface whose address is connected to. Defaults to the first
+	// interface reporting an address.
+	// +optional
+	Interface string `json:"interface,omitempty"`
+
+	// Port of the SSH server.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=22
+	// +optional
+	Port *int `json:"port,omitempty"`
+}
+
+// MachineKind is the kind of a Cluster API Machine.
+type MachineKind string
+
+// The kinds of Machines.
+const (
+	MachineKindMachine       MachineKind = "Machine"
+	MachineKindMetal3Machine MachineKind = "Metal3Machine"
+)
+
+// MachineAddressType is the type of an address of a Cluster API Machine.
+type MachineAddressType string
+
+// The types of addresses of Machines.
+const (
+	MachineAddressExternalIP  MachineAddressType = "ExternalIP"
+	MachineAddressInternalIP  MachineAddressType = "InternalIP"
+	MachineAddressExternalDNS MachineAddressType = "ExternalDNS"
+	MachineAddressInternalDNS MachineAddressType = "InternalDNS"
+	MachineAddressHostname    MachineAddressType = "Hostname"
+)
+
+// A MachineTarget references a Cluster API Machine.
+type MachineTarget struct {
+	// Kind of the Machine.
+	// +kubebuilder:validation:Enum=Machine;Metal3Machine
+	// +kubebuilder:default=Machine
+	// +optional
+	Kind MachineKind `json:"kind,omitempty"`
+
+	// Name of the Machine.
+	Name string `json:"name"`
+
+	// Namespace of the Machine.
+	Namespace string `json:"namespace"`
+
+	// AddressType of the address connected to. Defaults to the first
+	// address, in the order ExternalIP, InternalIP, ExternalDNS,
+	// InternalDNS and Hostname.
+	// +kubebuilder:validation:Enum=ExternalIP;InternalIP;ExternalDNS;InternalDNS;Hostname
+	// +optional
+	AddressType MachineAddressType `json:"addressType,omitempty"`
+
+	// Port of the SSH server.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=22
+	// +optional
+	Port *int `json:"port,omitempty"`
+}
+
+// A ScriptSource is a single script of a Script.
+type ScriptSource struct {
+	// Inline is the content of the script. Variables are referenced as
+	// {{NAME}}.
+	Inline string `json:"inline"`
+}
+
+// Scripts are the scripts of a Script, each executed at a different stage
+// of its lifecycle.
+type Scripts struct {
+	// Init is executed the first time and whenever the resource is detected
+	// as non-existent.
+	// +optional
+	Init *ScriptSource `json:"init,omitempty"`
+
+	// StatusCheck is executed on every poll, its exit code reporting the
+	// state of the resource.
+	// +optional
+	StatusCheck *ScriptSource `json:"statusCheck,omitempty"`
+
+	// Exists reports whether the resource exists on the remote host, exit
+	// code 0 meaning that it exists. If it or UpToDate is set the
+	// statusCheck script is not executed.
+	// +optional
+	Exists *ScriptSource `json:"exists,omitempty"`
+
+	// UpToDate reports whether an existing resource is in sync with the
+	// spec, exit code 0 meaning that it is.
+	// +optional
+	UpToDate *ScriptSource `json:"upToDate,omitempty"`
+
+	// Diff is executed whenever drift is detected. Its stdout is reported in
+	// the diff field of the status and in an Event before the update script
+	// is executed.
+	// +optional
+	Diff *ScriptSource `json:"diff,omitempty"`
+
+	// Update repairs the drift reported by the statusCheck or upToDate
+	// script.
+	// +optional
+	Update *ScriptSource `json:"update,omitempty"`
+
+	// Cleanup is executed when the Script is deleted.
+	// +optional
+	Cleanup *ScriptSource `json:"cleanup,omitempty"`
+
+	// PostReboot is executed in place of the update script after the host
+	// rebooted, when the rebootPolicy is Update. It defaults to the update
+	// script.
+	// +optional
+	PostReboot *ScriptSource `json:"postReboot,omitempty"`
+}
+
+// A Variable is substituted for its references in the scripts.
+type Variable struct {
+	// Name of the variable
+	Name string `json:"name"`
+	// Value of the variable
+	Value string `json:"value"`
+	// Sensitive values are masked in the logs of the provider.
+	// +optional
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// A ScriptReference references another Script by name.
+type ScriptReference struct {
+	// Name of the referenced Script.
+	Name string `json:"name"`
+}
+
+// A HostReference references a ProviderConfig or a Host that identifies a
+// target host, or a HostGroup whose member Hosts are targeted.
+type HostReference struct {
+	// Kind of the referenced object.
+	// +kubebuilder:validation:Enum=ProviderConfig;Host;HostGroup
+	// +kubebuilder:default=ProviderConfig
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the referenced object.
+	Name string `json:"name"`
+}
+
+// A RolloutStrategy controls how scripts are rolled out across multiple
+// hosts. Hosts that need a script are executed in batches, one batch per
+// reconcile.
+type RolloutStrategy struct {
+	// BatchSize is the maximum number of hosts executed per batch. Defaults
+	// to all hosts.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BatchSize *int `json:"batchSize,omitempty"`
+
+	// MaxUnavailable is the number or percentage of hosts that may be not
+	// ready at the same time, including the hosts of the running batch.
+	// Defaults to all hosts.
+	// +kubebuilder:validation:XIntOrString
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// PauseOnFailure stops starting new batches while any host failed or is
+	// unreachable, until the host is ready again.
+	// +optional
+	PauseOnFailure bool `json:"pauseOnFailure,omitempty"`
+}
+
+// ExpectedOutput is the stdout of the statusCheck script of an up to date
+// resource, either an exact string or a regular expression.
+// +kubebuilder:validation:XValidation:rule="has(self.exact) != has(self.regex)",message="exactly one of exact and regex is required"
+type ExpectedOutput struct {
+	// Exact is the expected stdout, without its trailing newlines. The
+	// variables are replaced in it as in the scripts.
+	// +optional
+	Exact string `json:"exact,omitempty"`
+
+	// Regex is a regular expression in the RE2 syntax matching the expected
+	// stdout without its trailing newlines, e.g. ^1\.2\.[0-9]+$. It is not
+	// anchored unless it says so.
+	// +optional
+	Regex string `json:"regex,omitempty"`
+}
+
+// OutputFormat is the format of the stdout of the statusCheck script.
+type OutputFormat string
+
+const (
+	// OutputFormatKeyValue reads the name=value lines of the stdout.
+	OutputFormatKeyValue OutputFormat = "KeyValue"
+
+	// OutputFormatJSON parses the stdout as a JSON object.
+	OutputFormatJSON OutputFormat = "JSON"
+
+	// OutputFormatYAML parses the stdout as a YAML mapping.
+	OutputFormatYAML OutputFormat = "YAML"
+)
+
+// StdoutFormat is the format the stdout of the scripts is recorded in the
+// status with.
+type StdoutFormat string
+
+const (
+	// StdoutFormatText records the stdout as a single string.
+	StdoutFormatText StdoutFormat = "Text"
+
+	// StdoutFormatLines records the stdout as an array of its lines.
+	StdoutFormatLines StdoutFormat = "Lines"
+)
+
+// MaxStdoutLines is the number of lines of the stdout recorded with the Lines
+// stdoutFormat. The first lines of longer outputs are dropped.
+const MaxStdoutLines = 100
+
+// StatusCheckExitCodes map the exit codes of the statusCheck script onto the
+// state of the resource. Exit code 0 reports a ready resource, and other
+// exit codes drift repaired by the update script.
+type StatusCheckExitCodes struct {
+	// Missing is the exit code reporting that the resource does not exist,
+	// in which case the init script is executed.
+	// +optional
+	Missing *int `json:"missing,omitempty"`
+
+	// Failed lists the exit codes reporting a failure that cannot be
+	// repaired automatically.
+	// +optional
+	Failed []int `json:"failed,omitempty"`
+}
+
+// ExecutionPolicy controls how often the scripts of a Script are executed.
+type ExecutionPolicy string
+
+// Execution policies of a Script.
+const (
+	ExecutionPolicyReconcile ExecutionPolicy = "Reconcile"
+	ExecutionPolicyRunOnce   ExecutionPolicy = "RunOnce"
+)
+
+// UpdateStrategy controls how changes of the rendered init script are
+// applied.
+type UpdateStrategy string
+
+// Update strategies of a Script.
+const (
+	UpdateStrategyInPlace  UpdateStrategy = "InPlace"
+	UpdateStrategyRecreate UpdateStrategy = "Recreate"
+)
+
+// UpdatePolicy controls when the update script of a Script is executed.
+type UpdatePolicy string
+
+// Update policies of a Script.
+const (
+	UpdatePolicyOnDrift UpdatePolicy = "OnDrift"
+	UpdatePolicyAlways  UpdatePolicy = "Always"
+	UpdatePolicyNever   UpdatePolicy = "Never"
+)
+
+// CleanupPolicy controls whether the cleanup script is executed when a
+// Script is deleted.
+type CleanupPolicy string
+
+// Cleanup policies of a Script.
+const (
+	CleanupPolicyRun        CleanupPolicy = "Run"
+	CleanupPolicySkip       CleanupPolicy = "Skip"
+	CleanupPolicyBestEffort CleanupPolicy = "BestEffort"
+)
+
+// An InitRetryPolicy retries a failing init script within the reconcile.
+type InitRetryPolicy struct {
+	// Attempts is the number of executions of the init script, the first
+	// one included.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=5
+	Attempts int32 `json:"attempts"`
+
+	// Delay between two attempts.
+	// +optional
+	Delay *metav1.Duration `json:"delay,omitempty"`
+}
+
+// A DeleteRetryPolicy bounds the retries of the cleanup of a deleted Script.
+type DeleteRetryPolicy struct {
+	// Attempts is the number of failed cleanup attempts after which the
+	// retries are exhausted.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	Attempts *int32 `json:"attempts,omitempty"`
+
+	// Backoff is the delay before the cleanup is attempted again after a
+	// failed attempt.
+	// +optional
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+
+	// ThenOrphan lets the deletion proceed once the retries are exhausted,
+	// leaving the resource on the host, and records the OrphanedOnDelete
+	// condition.
+	// +optional
+	ThenOrphan bool `json:"thenOrphan,omitempty"`
+}
+
+// RebootPolicy controls whether a Script detects the reboots of its host.
+type RebootPolicy string
+
+// Reboot policies of a Script.
+const (
+	RebootPolicyIgnore RebootPolicy = "Ignore"
+	RebootPolicyRecord RebootPolicy = "Record"
+	RebootPolicyUpdate RebootPolicy = "Update"
+)
+
+// Templating is the syntax of the references to variables in scripts.
+type Templating string
+
+// Templating syntaxes of a Script.
+const (
+	TemplatingBraces   Templating = "braces"
+	TemplatingEnvsubst Templating = "envsubst"
+)
+
+// ContainerEngine is the engine running the container scripts are executed
+// in.
+type ContainerEngine string
+
+// Container engines of a Script.
+const (
+	ContainerEngineDocker ContainerEngine = "docker"
+	ContainerEnginePodman ContainerEngine = "podman"
+)
+
+// A Container running on the host that scripts are executed in.
+type Container struct {
+	// Engine is the engine running the container, docker by default.
+	// +kubebuilder:validation:Enum=docker;podman
+	// +optional
+	Engine ContainerEngine `json:"engine,omitempty"`
+
+	// Name is the name or ID of the running container.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`
+	Name string `json:"name"`
+}
+
+// NamespaceType is a type of Linux namespace.
+// +kubebuilder:validation:Enum=Mount;UTS;IPC;Network;PID;User;Cgroup
+type NamespaceType string
+
+// Types of the namespaces of a Script.
+const (
+	NamespaceTypeMount   NamespaceType = "Mount"
+	NamespaceTypeUTS     NamespaceType = "UTS"
+	NamespaceTypeIPC     NamespaceType = "IPC"
+	NamespaceTypeNetwork NamespaceType = "Network"
+	NamespaceTypePID     NamespaceType = "PID"
+	NamespaceTypeUser    NamespaceType = "User"
+	NamespaceTypeCgroup  NamespaceType = "Cgroup"
+)
+
+// Namespaces of a process on the host that scripts are executed in.
+type Namespaces struct {
+	// TargetPID is the PID of the process whose namespaces are entered.
+	// +kubebuilder:validation:Minimum=1
+	TargetPID int32 `json:"targetPID"`
+
+	// Types of the namespaces entered, the mount namespace by default.
+	// +optional
+	Types []NamespaceType `json:"types,omitempty"`
+}
+
+// Limits bound the resources of the processes of scripts on the host.
+type Limits struct {
+	// CPUSeconds is the CPU time a process may use.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CPUSeconds *int64 `json:"cpuSeconds,omitempty"`
+
+	// MaxMemoryKB is the virtual memory a process may allocate, in KiB.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxMemoryKB *int64 `json:"maxMemoryKB,omitempty"`
+
+	// MaxFileSizeKB is the size of the largest file a process may write,
+	// in KiB.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxFileSizeKB *int64 `json:"maxFileSizeKB,omitempty"`
+}
+
+// IONiceClass is the I/O scheduling class of ionice.
+type IONiceClass string
+
+// I/O scheduling classes of ionice.
+const (
+	IONiceClassRealtime   IONiceClass = "Realtime"
+	IONiceClassBestEffort IONiceClass = "BestEffort"
+	IONiceClassIdle       IONiceClass = "Idle"
+)
+
+// Priority is the CPU and I/O scheduling priority of scripts.
+type Priority struct {
+	// Nice is the niceness the scripts are executed with, from -20 to 19.
+	// +kubebuilder:validation:Minimum=-20
+	// +kubebuilder:validation:Maximum=19
+	// +optional
+	Nice *int32 `json:"nice,omitempty"`
+
+	// IONice is the I/O scheduling priority the scripts are executed with.
+	// +optional
+	IONice *IONice `json:"ionice,omitempty"`
+}
+
+// IONice is an I/O scheduling class and level of ionice.
+type IONice struct {
+	// Class is the I/O scheduling class.
+	// +kubebuilder:validation:Enum=Realtime;BestEffort;Idle
+	Class IONiceClass `json:"class"`
+
+	// Level within the Realtime and BestEffort classes, from 0 to 7.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=7
+	// +optional
+	Level *int32 `json:"level,omitempty"`
+}
+
+// An Operation is a script executed to change the resource managed by a
+// Script.
+type Operation string
+
+// Operations of a Script.
+const (
+	OperationInit       Operation = "Init"
+	OperationUpdate     Operation = "Update"
+	OperationRecreate   Operation = "Recreate"
+	OperationRunNow     Operation = "RunNow"
+	OperationCleanup    Operation = "Cleanup"
+	OperationPostReboot Operation = "PostReboot"
+)
+
+// ScriptParameters are the configurable fields of a Script.
+type ScriptParameters struct {
+	// Endpoint overrides the SSH server and the remote user of the
+	// ProviderConfig. The host and port only apply to Scripts targeting a
+	// single host.
+	// +optional
+	Endpoint *Endpoint `json:"endpoint,omitempty"`
+
+	// VirtualMachineInstance targets a KubeVirt VirtualMachineInstance,
+	// whose address is resolved from its status on every connection. It
+	// takes precedence over the host and port of the endpoint.
+	// +optional
+	VirtualMachineInstance *VirtualMachineInstanceTarget `json:"virtualMachineInstance,omitempty"`
+
+	// Machine targets a Cluster API Machine, whose address is resolved from
+	// its status on every connection. It takes precedence over the host and
+	// port of the endpoint, and cannot be combined with a
+	// virtualMachineInstance.
+	// +optional
+	Machine *MachineTarget `json:"machine,omitempty"`
+
+	// Scripts executed on the remote host.
+	Scripts Scripts `json:"scripts"`
+
+	// Variables substituted in the scripts.
+	// +optional
+	Variables []Variable `json:"variables,omitempty"`
+
+	// SudoEnabled executes the scripts with sudo. It defaults to the
+	// sudoEnabled of the defaults of the ProviderConfig, false if unset.
+	// +optional
+	SudoEnabled *bool `json:"sudoEnabled,omitempty"`
+
+	// Interpreter executes the scripts that do not start with a #! line.
+	// +optional
+	Interpreter string `json:"interpreter,omitempty"`
+
+	// RemoteTempDir is the directory of the host the scripts are uploaded to
+	// before they are executed, /tmp by default.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	RemoteTempDir string `json:"remoteTempDir,omitempty"`
+
+	// Platform of the host the scripts are written for, e.g. Linux/x86_64.
+	// It is late-initialized from the host.
+	// +optional
+	Platform string `json:"platform,omitempty"`
+
+	// Templating is the syntax of the references to variables in the
+	// scripts: {{VAR}} for braces, the default, or $VAR and ${VAR} for
+	// envsubst.
+	// +kubebuilder:validation:Enum=braces;envsubst
+	// +optional
+	Templating Templating `json:"templating,omitempty"`
+
+	// StrictMode prepends the strictModePreamble to the rendered scripts, so
+	// that the failure of any command fails the script.
+	// +optional
+	StrictMode bool `json:"strictMode,omitempty"`
+
+	// StrictModePreamble is the line prepended to the scripts in strict
+	// mode, set -euo pipefail by default.
+	// +optional
+	StrictModePreamble string `json:"strictModePreamble,omitempty"`
+
+	// Container executes the scripts in a container running on the host,
+	// through the exec command of its engine.
+	// +optional
+	Container *Container `json:"container,omitempty"`
+
+	// Namespaces executes the scripts in the namespaces of a process of the
+	// host through nsenter.
+	// +optional
+	Namespaces *Namespaces `json:"namespaces,omitempty"`
+
+	// Chroot executes the scripts with the supplied directory as their root
+	// directory.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	Chroot string `json:"chroot,omitempty"`
+
+	// LoginShell executes the scripts through bash -l -c, with the
+	// environment of the profile of the remote user.
+	// +optional
+	LoginShell bool `json:"loginShell,omitempty"`
+
+	// Umask is the octal file mode creation mask the scripts are executed
+	// with, e.g. 027.
+	// +kubebuilder:validation:Pattern=`^[0-7]{3,4}$`
+	// +optional
+	Umask string `json:"umask,omitempty"`
+
+	// Locale is the locale the scripts are executed with, set as LANG and
+	// LC_ALL, e.g. C.UTF-8.
+	// +optional
+	Locale string `json:"locale,omitempty"`
+
+	// Timezone is the timezone the scripts are executed with, set as TZ,
+	// e.g. UTC.
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+
+	// Priority is the CPU and I/O scheduling priority of the scripts,
+	// applied through nice and ionice.
+	// +optional
+	Priority *Priority `json:"priority,omitempty"`
+
+	// Limits bound the resources of the scripts through ulimit and
+	// timeout.
+	// +optional
+	Limits *Limits `json:"limits,omitempty"`
+
+	// TimeoutSeconds is the time after which a script is killed.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=600
+	// +optional
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+
+	// MaxOutputBytes is the number of bytes of the stdout and stderr of a
+	// script recorded in the status. Longer outputs are truncated to their
+	// end.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxOutputBytes *int64 `json:"maxOutputBytes,omitempty"`
+
+	// StdoutFormat of the stdout of the scripts recorded in the status. Text
+	// records it as status.atProvider.stdout, while Lines records it as
+	// status.atProvider.stdoutLines, one entry per line, so that
+	// compositions can patch from a specific line. Only its last 100 lines
+	// are recorded as lines.
+	// +kubebuilder:validation:Enum=Text;Lines
+	// +kubebuilder:default=Text
+	// +optional
+	StdoutFormat StdoutFormat `json:"stdoutFormat,omitempty"`
+
+	// RecordSession records a timestamped transcript of every execution of
+	// the scripts of an operation, the script and its interleaved stdout
+	// and stderr, in asciicast v2 format to the session recording sink of
+	// the provider, for forensics. Status checks are not recorded.
+	// +optional
+	RecordSession *bool `json:"recordSession,omitempty"`
+
+	// PollInterval overrides the poll interval of the provider for this
+	// Script, e.g. 30s or 1h.
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+
+	// StatusCheckInterval is the minimum time between two executions of the
+	// statusCheck script, or of the exists and upToDate scripts, on a resource
+	// that is up to date. The host is not connected to in between, unless
+	// the spec changes or a run is requested.
+	// +optional
+	StatusCheckInterval *metav1.Duration `json:"statusCheckInterval,omitempty"`
+
+	// CreateGracePeriod delays the first status check after the resource
+	// was created, so that the services started by the init script have time
+	// to come up before they are checked.
+	// +optional
+	CreateGracePeriod *metav1.Duration `json:"createGracePeriod,omitempty"`
+
+	// StatusCacheTTL is how long the last result of the statusCheck script
+	// is reused by the observations of the Script instead of executing it
+	// again.
+	// +optional
+	StatusCacheTTL *metav1.Duration `json:"statusCacheTTL,omitempty"`
+
+	// ReapplyInterval is the maximum time between two executions of the
+	// update script. Once it elapsed since a script last changed the
+	// resource the update script is executed, even if the resource is up to
+	// date.
+	// +optional
+	ReapplyInterval *metav1.Duration `json:"reapplyInterval,omitempty"`
+
+	// MaxConsecutiveFailures halts the Script once this many consecutive
+	// executions of its scripts failed. A halted Script executes no script
+	// until the resume annotation is set to a new value or its spec changes.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConsecutiveFailures *int32 `json:"maxConsecutiveFailures,omitempty"`
+
+	// ExitCodes maps the exit codes of the statusCheck script onto the state
+	// of the resource.
+	// +optional
+	ExitCodes *StatusCheckExitCodes `json:"exitCodes,omitempty"`
+
+	// SkipIf is a CEL expression evaluated on each host before its scripts
+	// are executed, e.g. facts.kernel.startsWith("6.8"). A host on which it
+	// is true is reported Ready and executes no script, the cleanup script
+	// included. The expression can reference the facts gathered from the
+	// host (hostname, system, kernel, arch, os, osVersion, cpus and
+	// memoryKB), the name of its ProviderConfig or Host as host, and the
+	// variables of the Script as vars.
+	// +optional
+	SkipIf string `json:"skipIf,omitempty"`
+
+	// UpToDateWhen is a CEL expression deciding whether the resource is up to
+	// date from the result of the statusCheck script, in place of its exit code
+	// being zero, e.g. outputs.version == vars.VERSION. It is evaluated
+	// unless the exit code reports a missing or failed resource, and can
+	// reference the exitCode, stdout and stderr of the script, its outputs
+	// (the name=value lines of its stdout) and the variables of the Script
+	// as vars.
+	// +optional
+	UpToDateWhen string `json:"upToDateWhen,omitempty"`
+
+	// ReadyWhen is a CEL expression deciding whether the resource is Ready
+	// from the result of the statusCheck script, with the variables of
+	// upToDateWhen. The resource is Ready when it is up to date by default.
+	// +optional
+	ReadyWhen string `json:"readyWhen,omitempty"`
+
+	// ExpectedOutput is compared with the stdout of the statusCheck script to decide
+	// whether the resource is up to date, in addition to its exit code being
+	// zero or its upToDateWhen being true.
+	// +optional
+	ExpectedOutput *ExpectedOutput `json:"expectedOutput,omitempty"`
+
+	// OutputFormat of the stdout of the statusCheck script. KeyValue reads its
+	// name=value lines, while JSON and YAML parse it as an object that is
+	// also recorded in status.atProvider.outputs. The outputs are available
+	// to the upToDateWhen and readyWhen expressions in every format.
+	// +kubebuilder:validation:Enum=KeyValue;JSON;YAML
+	// +kubebuilder:default=KeyValue
+	// +optional
+	OutputFormat OutputFormat `json:"outputFormat,omitempty"`
+
+	// RebootPolicy of the Script. Record reads the boot ID of the host on
+	// every status check and reports its reboots through an Event, and
+	// Update also reports the resource as not up to date after a reboot.
+	// Reboots are not detected for Scripts running on multiple hosts.
+	// +kubebuilder:validation:Enum=Ignore;Record;Update
+	// +kubebuilder:default=Ignore
+	// +optional
+	RebootPolicy RebootPolicy `json:"rebootPolicy,omitempty"`
+
+	// DependsOn lists Scripts that must be Ready before this Script is
+	// observed or created.
+	// +optional
+	DependsOn []ScriptReference `json:"dependsOn,omitempty"`
+
+	// ExecutionPolicy of the scripts. With RunOnce the init script is
+	// executed exactly once and never retried, and the update script is
+	// never executed.
+	// +kubebuilder:validation:Enum=Reconcile;RunOnce
+	// +kubebuilder:default=Reconcile
+	// +optional
+	ExecutionPolicy ExecutionPolicy `json:"executionPolicy,omitempty"`
+
+	// UpdateStrategy of the Script. With Recreate a change of the rendered
+	// init script runs the cleanup script followed by the init script
+	// instead of the update script.
+	// +kubebuilder:validation:Enum=InPlace;Recreate
+	// +kubebuilder:default=InPlace
+	// +optional
+	UpdateStrategy UpdateStrategy `json:"updateStrategy,omitempty"`
+
+	// UpdatePolicy of the Script. OnDrift executes the update script
+	// whenever the status check reports drift, Always on every status check
+	// of an existing resource, and Never only reports drift through the
+	// Drifted condition.
+	// +kubebuilder:validation:Enum=OnDrift;Always;Never
+	// +kubebuilder:default=OnDrift
+	// +optional
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+
+	// InitRetry retries a failing init script within the reconcile, for
+	// transient failures such as flaking package mirrors. It does not apply
+	// to RunOnce Scripts.
+	// +optional
+	InitRetry *InitRetryPolicy `json:"initRetry,omitempty"`
+
+	// CleanupPolicy of the Script, independent of its deletionPolicy. Skip
+	// never executes the cleanup script, and BestEffort lets the deletion
+	// proceed once MaxCleanupAttempts attempts failed.
+	// +kubebuilder:validation:Enum=Run;Skip;BestEffort
+	// +kubebuilder:default=Run
+	// +optional
+	CleanupPolicy CleanupPolicy `json:"cleanupPolicy,omitempty"`
+
+	// MaxCleanupAttempts is the number of failed cleanup attempts tolerated
+	// by the BestEffort cleanupPolicy.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	MaxCleanupAttempts *int32 `json:"maxCleanupAttempts,omitempty"`
+
+	// CheckBeforeCleanup executes the exists script, or the statusCheck
+	// script if it is not set, before the cleanup script, and skips the
+	// cleanup script if it reports that the resource no longer exists on the
+	// host.
+	// +optional
+	CheckBeforeCleanup bool `json:"checkBeforeCleanup,omitempty"`
+
+	// DeleteRetry bounds the retries of a cleanup that keeps failing, for
+	// instance because the host is permanently unreachable.
+	// +optional
+	DeleteRetry *DeleteRetryPolicy `json:"deleteRetry,omitempty"`
+
+	// TTLSecondsAfterFinished limits the lifetime of a RunOnce Script after
+	// its init script succeeded.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTLSecondsAfterFinished *int64 `json:"ttlSecondsAfterFinished,omitempty"`
+
+	// DeleteAfterTTL deletes the Script once TTLSecondsAfterFinished expired.
+	// +optional
+	DeleteAfterTTL bool `json:"deleteAfterTTL,omitempty"`
+
+	// Hosts lists ProviderConfigs or Hosts, one per host, on which the
+	// scripts are executed instead of the host of the providerConfigRef.
+	// +optional
+	Hosts []HostReference `json:"hosts,omitempty"`
+
+	// HostSelector selects ProviderConfigs and Hosts by label, one per host,
+	// on which the scripts are executed in addition to the listed Hosts.
+	// +optional
+	HostSelector *metav1.LabelSelector `json:"hostSelector,omitempty"`
+
+	// SuccessThreshold is the number or percentage of targeted hosts that
+	// must be ready for the Script to be Ready. Defaults to all hosts.
+	// +kubebuilder:validation:XIntOrString
+	// +optional
+	SuccessThreshold *intstr.IntOrString `json:"successThreshold,omitempty"`
+
+	// Rollout controls how the scripts are rolled out across the targeted
+	// hosts. By default all hosts are executed at once.
+	// +optional
+	Rollout *RolloutStrategy `json:"rollout,omitempty"`
+}
+
+// HostStatus is the observed state of a Script on a single targeted host.
+type HostStatus struct {
+	// Kind of the object identifying the host, ProviderConfig or Host.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the object identifying the host.
+	Name string `json:"name"`
+
+	// Ready reports whether the last script succeeded on the host.
+	Ready bool `json:"ready"`
+
+	// StatusCode is the exit status code of the last script executed on the
+	// host.
+	StatusCode int `json:"statusCode"`
+
+	// OutputDigest is the SHA-256 hash of the stdout of the last script
+	// executed on the host.
+	// +optional
+	OutputDigest string `json:"outputDigest,omitempty"`
+
+	// Message describes why the host is not ready, if it is not.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Skipped reports whether the skipIf expression of the Script was true
+	// on the host when it was last checked.
+	// +optional
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// ScriptObservation are the observable fields of a Script.
+type ScriptObservation struct {
+	// Stdout of the last script executed.
+	// +optional
+	Stdout string `json:"stdout,omitempty"`
+
+	// StdoutLines is the stdout of the last script executed, one entry per
+	// line, recorded instead of stdout with the Lines stdoutFormat.
+	// +kubebuilder:validation:MaxItems=100
+	// +optional
+	StdoutLines []string `json:"stdoutLines,omitempty"`
+
+	// Stderr of the last script executed.
+	// +optional
+	Stderr string `json:"stderr,omitempty"`
+
+	// StatusCode is the exit status code of the last script executed.
+	// +optional
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// ScriptHash is the hash of the rendered init script that was last
+	// executed.
+	// +optional
+	ScriptHash string `json:"scriptHash,omitempty"`
+
+	// CleanupAttempts is the number of failed attempts to clean up the
+	// resource while it is being deleted.
+	// +optional
+	CleanupAttempts int32 `json:"cleanupAttempts,omitempty"`
+
+	// Creations is the number of times the resource was created or
+	// recreated successfully, from which the IDEMPOTENCY_TOKEN of the next
+	// creation is derived.
+	// +optional
+	Creations int64 `json:"creations,omitempty"`
+
+	// LastCleanupAttemptTime is the time of the last failed attempt to clean
+	// up the resource, from which the backoff of the deleteRetry elapses.
+	// +optional
+	LastCleanupAttemptTime *metav1.Time `json:"lastCleanupAttemptTime,omitempty"`
+
+	// LastRunNowToken is the value of the run-now annotation that was last
+	// executed.
+	// +optional
+	LastRunNowToken string `json:"lastRunNowToken,omitempty"`
+
+	// Diff is the stdout of the diff script for the drift that was last
+	// detected.
+	// +optional
+	Diff string `json:"diff,omitempty"`
+
+	// Hosts reports the state of each host targeted through hosts or
+	// hostSelector.
+	// +optional
+	Hosts []HostStatus `json:"hosts,omitempty"`
+
+	// Host is the address of the host the scripts are executed on, or the
+	// number of targeted hosts.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// LastChecked is the time the state of the resource was last checked.
+	// +optional
+	LastChecked *metav1.Time `json:"lastChecked,omitempty"`
+
+	// LastOperation is the script last executed to change the resource.
+	// +optional
+	LastOperation Operation `json:"lastOperation,omitempty"`
+
+	// LastAppliedTime is the time a script last changed the resource
+	// successfully.
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	// ConsecutiveFailures is the number of consecutive failed executions of
+	// the scripts changing the resource.
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// HaltedGeneration is the generation of the Script when it was halted.
+	// +optional
+	HaltedGeneration int64 `json:"haltedGeneration,omitempty"`
+
+	// LastResumeToken is the value of the resume annotation that was last
+	// applied.
+	// +optional
+	LastResumeToken string `json:"lastResumeToken,omitempty"`
+
+	// NextCheckTime is the time before which the state of the resource is
+	// not checked again, according to the statusCheckInterval.
+	// +optional
+	NextCheckTime *metav1.Time `json:"nextCheckTime,omitempty"`
+
+	// RetryTime is the time the statusCheckScript asked the state of the
+	// resource to be checked again at, through a RETRY_AFTER output.
+	// +optional
+	RetryTime *metav1.Time `json:"retryTime,omitempty"`
+
+	// CheckedGeneration is the generation of the Script when the state of
+	// the resource was last checked.
+	// +optional
+	CheckedGeneration int64 `json:"checkedGeneration,omitempty"`
+
+	// StatusCachedUntil is the time until which the observations reuse the
+	// last result of the statusCheck script.
+	// +optional
+	StatusCachedUntil *metav1.Time `json:"statusCachedUntil,omitempty"`
+
+	// Skipped reports whether the skipIf expression of the Script was true
+	// on the host when it was last checked.
+	// +optional
+	Skipped bool `json:"skipped,omitempty"`
+
+	// BootID is the boot ID of the host the resource was last checked or
+	// repaired on.
+	// +optional
+	BootID string `json:"bootID,omitempty"`
+
+	// Outputs is the object parsed from the stdout of the statusCheck script
+	// in its JSON or YAML outputFormat, when it last reported an existing
+	// resource.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Outputs *runtime.RawExtension `json:"outputs,omitempty"`
+}
+
+// A ScriptSpec defines the desired state of a Script.
+type ScriptSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       ScriptParameters `json:"forProvider"`
+
+	// Suspend freezes the reconciliation of this Script. No connection to
+	// the remote host is made and no script is executed until it is unset.
+	// Deleting a suspended Script still runs its cleanup script.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// A ScriptStatus represents the observed state of a Script.
+type ScriptStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          ScriptObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A Script executes scripts on remote hosts over SSH to manage a resource
+// through its lifecycle.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="HOST",type="string",JSONPath=".status.atProvider.host"
+// +kubebuilder:printcolumn:name="EXIT-CODE",type="integer",JSONPath=".status.atProvider.statusCode"
+// +kubebuilder:printcolumn:name="LAST-OPERATION",type="string",JSONPath=".status.atProvider.lastOperation"
+// +kubebuilder:printcolumn:name="LAST-CHECKED",type="date",JSONPath=".status.atProvider.lastChecked"
+// +kubebuilder:printcolumn:name="EXTERNAL-NAME",type="string",JSONPath=".metadata.annotations.crossplane\\.io/external-name"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,ssh}
+type Script struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScriptSpec   `json:"spec"`
+	Status ScriptStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ScriptList contains a list of Script
+type ScriptList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Script `json:"items"`
+}
+
+// Script type metadata.
+var (
+	ScriptKind             = reflect.TypeOf(Script{}).Name()
+	ScriptGroupKind        = schema.GroupKind{Group: Group, Kind: ScriptKind}.String()
+	ScriptKindAPIVersion   = ScriptKind + "." + SchemeGroupVersion.String()
+	ScriptGroupVersionKind = SchemeGroupVersion.WithKind(ScriptKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&Script{}, &ScriptList{})
+}
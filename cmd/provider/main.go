@@ -21,18 +21,23 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
+	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/alecthomas/kingpin.v2"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/certificates"
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/feature"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
@@ -41,28 +46,61 @@ import (
 
 	"github.com/crossplane/provider-ssh/apis"
 	"github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
 	ssh "github.com/crossplane/provider-ssh/internal/controller"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+	sshdebug "github.com/crossplane/provider-ssh/internal/debug"
 	"github.com/crossplane/provider-ssh/internal/features"
+	"github.com/crossplane/provider-ssh/internal/policy"
+	"github.com/crossplane/provider-ssh/internal/recording"
+	"github.com/crossplane/provider-ssh/internal/redact"
+	"github.com/crossplane/provider-ssh/internal/signature"
+	sshwebhook "github.com/crossplane/provider-ssh/internal/webhook"
 )
 
 func main() {
 	var (
 		app            = kingpin.New(filepath.Base(os.Args[0]), "SSH support for Crossplane.").DefaultEnvars()
 		debug          = app.Flag("debug", "Run with debug logging.").Short('d').Bool()
+		logVerbosity   = app.Flag("log-verbosity", "Verbosity of the logs of the reconciles. 0 logs what changes the resources, 1 also the progress of every reconcile. Debug logging logs everything.").Short('v').Default("0").Envar("LOG_VERBOSITY").Int()
+		logChangesOnly = app.Flag("log-state-changes-only", "Only log the reconciles of a resource that found it in another state than its previous reconcile.").Default("false").Envar("LOG_STATE_CHANGES_ONLY").Bool()
 		debugLogDir    = app.Flag("debugOutput", "Debug output directory.").Default("/tmp/provider-ssh").Envar("DEBUG_OUTPUT").String()
+		debugAddress   = app.Flag("debug-bind-address", "Address serving the open SSH connections under /debug/connections and the profiles under /debug/pprof/, e.g. :6060. Disabled if unset.").Envar("DEBUG_BIND_ADDRESS").String()
 		leaderElection = app.Flag("leader-election", "Use leader election for the controller manager.").Short('l').Default("false").OverrideDefaultFromEnvar("LEADER_ELECTION").Bool()
 
-		syncInterval     = app.Flag("sync", "How often all resources will be double-checked for drift from the desired state.").Short('s').Default("1h").Duration()
-		pollInterval     = app.Flag("poll", "How often individual resources will be checked for drift from the desired state").Default("1m").Duration()
-		maxReconcileRate = app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may checked for drift from the desired state.").Default("10").Int()
+		syncInterval       = app.Flag("sync", "How often all resources will be double-checked for drift from the desired state.").Short('s').Default("1h").Duration()
+		pollInterval       = app.Flag("poll", "How often individual resources will be checked for drift from the desired state").Default("1m").Duration()
+		maxReconcileRate   = app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may checked for drift from the desired state.").Default("10").Int()
+		kindReconcileRates = app.Flag("kind-max-reconcile-rate", "The maximum rate per second at which resources of a kind may be checked, e.g. Script=5. Can be repeated.").PlaceHolder("KIND=RATE").StringMap()
+
+		pcReconcileRate      = app.Flag("provider-config-max-reconcile-rate", "The maximum rate per second at which the resources of a single ProviderConfig may be checked, across all kinds. 0 means no limit.").Default("0").Int()
+		dialTimeout          = app.Flag("ssh-dial-timeout", "How long connecting to a host, including the SSH handshake, may take. 0 means no timeout.").Default("30s").Duration()
+		knownHostsFile       = app.Flag("default-known-hosts-file", "Path of the known_hosts file, e.g. mounted from a Secret, the host keys of the credentials without knownHosts are verified against. It is read on every connection. Host keys are not verified if unset.").Envar("DEFAULT_KNOWN_HOSTS_FILE").String()
+		maxConcurrentScripts = app.Flag("max-concurrent-scripts", "The maximum number of scripts executed at the same time across all resources. 0 means no limit.").Default("0").Int()
+		scriptSigningKeyring = app.Flag("script-signing-keyring", "Path of the OpenPGP keyring of the public keys trusted to sign scripts. Scripts without a valid signature of one of them are not executed. Disabled if unset.").Envar("SCRIPT_SIGNING_KEYRING").String()
+		denyDangerous        = app.Flag("deny-dangerous-commands", "Reject the scripts running a command of the built-in denylist, such as rm -rf / or mkfs, when admitted and before executing them.").Default("false").Envar("DENY_DANGEROUS_COMMANDS").Bool()
+		allowDangerous       = app.Flag("allow-dangerous-command", "Rule of the built-in denylist not to enforce, e.g. mkfs. Can be repeated.").Envar("ALLOW_DANGEROUS_COMMANDS").Strings()
+		scriptPolicyURL      = app.Flag("script-policy-url", "URL of an Open Policy Agent rule evaluating to the reasons to reject the rendered scripts, e.g. http://opa:8181/v1/data/ssh/deny. Disabled if unset.").Envar("SCRIPT_POLICY_URL").String()
+		sessionRecordingDir  = app.Flag("session-recording-dir", "Directory, e.g. the mount of a PersistentVolume, the transcripts of the executions of the Scripts with recordSession are stored in. Sessions are not recorded if unset.").Envar("SESSION_RECORDING_DIR").String()
+		maxOutputBytes       = app.Flag("max-output-bytes", "The default maximum number of bytes of the stdout and stderr of a Script recorded in its status.").Default(strconv.Itoa(v1alpha1.DefaultMaxOutputBytes)).Int64()
+		batchWindow          = app.Flag("status-check-batch-window", "How long the status check of a Script waits for those of the other Scripts connecting to the same host, to run them over a single connection in a single session. 0 disables the batching.").Default("0").Duration()
+		maxBatch             = app.Flag("max-status-check-batch", "The maximum number of status checks run in a single batch. 0 means no limit.").Default("50").Int()
+		drainTimeout         = app.Flag("shutdown-drain-timeout", "How long the scripts killed when the provider shuts down may take to remove their temporary files before the SSH connections are closed.").Default("10s").Duration()
+		eventDedupWindow     = app.Flag("event-deduplication-window", "How long an Event of a resource is not emitted again, identical. 0 disables the deduplication.").Default("0").Duration()
 
 		namespace                  = app.Flag("namespace", "Namespace used to set as default scope in default secret store config.").Default("crossplane-system").Envar("POD_NAMESPACE").String()
 		enableExternalSecretStores = app.Flag("enable-external-secret-stores", "Enable support for ExternalSecretStores.").Default("false").Envar("ENABLE_EXTERNAL_SECRET_STORES").Bool()
+		essTLSCertsPath            = app.Flag("ess-tls-cert-dir", "Path of the mTLS certificates (ca.crt, tls.crt and tls.key) used to reach External Secret Store plugins.").Envar("ESS_TLS_CERTS_DIR").String()
+		webhookTLSCertDir          = app.Flag("webhook-tls-cert-dir", "Directory of the TLS certificate (tls.crt and tls.key) of the webhook server. Webhooks are disabled if unset.").Envar("WEBHOOK_TLS_CERT_DIR").String()
+		paused                     = app.Flag("paused", "Pause the reconciliation of every resource, no host is connected to.").Default("false").Envar("PAUSED").Bool()
+		pauseConfigMap             = app.Flag("pause-configmap", "ConfigMap in the namespace of the provider whose \"paused\" key pauses the reconciliation of every resource when set to \"true\". Disabled if empty.").Default("provider-ssh-pause").Envar("PAUSE_CONFIGMAP").String()
 		enableManagementPolicies   = app.Flag("enable-management-policies", "Enable support for Management Policies.").Default("true").Envar("ENABLE_MANAGEMENT_POLICIES").Bool()
 	)
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 
-	zl := zap.New(zap.UseDevMode(*debug))
+	// Secrets are masked in every log line, whatever their origin.
+	// The verbosity V lines are logged at the zap level -V.
+	zl := redact.NewLogger(zap.New(zap.UseDevMode(*debug), zap.Level(zapcore.Level(-*logVerbosity))))
 	if *debug {
 		// custom format logger only for development
 		// Ensure the directory exists
@@ -86,7 +124,7 @@ func main() {
 			},
 			DestWriter: file,
 		}
-		zl = zap.New(zap.UseFlagOptions(&opts))
+		zl = redact.NewLogger(zap.New(zap.UseFlagOptions(&opts)))
 		// The controller-runtime runs with a no-op logger by default. It is
 		// *very* verbose even at info level, so we only provide it a real
 		// logger when we're running in debug mode.
@@ -116,22 +154,80 @@ func main() {
 		LeaderElectionResourceLock: resourcelock.LeasesResourceLock,
 		LeaseDuration:              func() *time.Duration { d := 60 * time.Second; return &d }(),
 		RenewDeadline:              func() *time.Duration { d := 50 * time.Second; return &d }(),
+
+		WebhookServer: webhook.NewServer(webhook.Options{
+			CertDir: *webhookTLSCertDir,
+		}),
 	})
 	kingpin.FatalIfError(err, "Cannot create controller manager")
 	kingpin.FatalIfError(apis.AddToScheme(mgr.GetScheme()), "Cannot add SSH APIs to scheme")
 
-	o := controller.Options{
-		Logger:                  log,
-		MaxConcurrentReconciles: *maxReconcileRate,
-		PollInterval:            *pollInterval,
-		GlobalRateLimiter:       ratelimiter.NewGlobal(*maxReconcileRate),
-		Features:                &feature.Flags{},
+	rates, err := reconcileRates(*kindReconcileRates)
+	kingpin.FatalIfError(err, "Cannot parse the maximum reconcile rates of kinds")
+
+	o := common.Options{
+		Options: controller.Options{
+			Logger:                  log,
+			MaxConcurrentReconciles: *maxReconcileRate,
+			PollInterval:            *pollInterval,
+			GlobalRateLimiter:       ratelimiter.NewGlobal(*maxReconcileRate),
+			Features:                &feature.Flags{},
+		},
+		DialTimeout:              *dialTimeout,
+		DefaultKnownHostsFile:    *knownHostsFile,
+		MaxConcurrentScripts:     *maxConcurrentScripts,
+		MaxReconcileRates:        rates,
+		MaxOutputBytes:           *maxOutputBytes,
+		StatusCheckBatchWindow:   *batchWindow,
+		MaxStatusCheckBatch:      *maxBatch,
+		ResourceLocks:            common.NewResourceLocks(),
+		ReconcileLogger:          zl.WithName("provider-ssh"),
+		LogStateChangesOnly:      *logChangesOnly,
+		EventDeduplicationWindow: *eventDedupWindow,
+		Pause:                    common.NewPause(*paused, mgr.GetAPIReader(), types.NamespacedName{Namespace: *namespace, Name: *pauseConfigMap}),
+	}
+	if *pcReconcileRate > 0 {
+		o.ProviderConfigRateLimiter = common.NewProviderConfigRateLimiter(*pcReconcileRate)
+	}
+	if *paused {
+		log.Info("Reconciliation of every resource is paused")
+	}
+	if *scriptSigningKeyring != "" {
+		keyring, err := os.ReadFile(*scriptSigningKeyring)
+		kingpin.FatalIfError(err, "Cannot read the script signing keyring")
+		o.ScriptVerifier, err = signature.NewVerifier(keyring)
+		kingpin.FatalIfError(err, "Cannot load the script signing keyring")
+		log.Info("Scripts must be signed", "keyring", *scriptSigningKeyring)
+	}
+	if *sessionRecordingDir != "" {
+		o.SessionRecordings = recording.NewDirSink(*sessionRecordingDir)
+		log.Info("Sessions of the Scripts with recordSession are recorded", "dir", *sessionRecordingDir)
+	}
+	if *denyDangerous || *scriptPolicyURL != "" {
+		var rules []policy.Rule
+		if *denyDangerous {
+			rules, err = policy.Rules(*allowDangerous)
+			kingpin.FatalIfError(err, "Cannot configure the dangerous command denylist")
+		}
+		var hook policy.Hook
+		if *scriptPolicyURL != "" {
+			hook = policy.NewOPAHook(*scriptPolicyURL)
+		}
+		o.ScriptPolicy = policy.New(rules, hook)
 	}
 
 	if *enableExternalSecretStores {
 		o.Features.Enable(features.EnableAlphaExternalSecretStores)
 		log.Info("Alpha feature enabled", "flag", features.EnableAlphaExternalSecretStores)
 
+		o.ESSOptions = &controller.ESSOptions{}
+		if *essTLSCertsPath != "" {
+			log.Info("ESS TLS certificates path is set. Loading mTLS configuration.")
+			tCfg, err := certificates.LoadMTLSConfig(filepath.Join(*essTLSCertsPath, "ca.crt"), filepath.Join(*essTLSCertsPath, "tls.crt"), filepath.Join(*essTLSCertsPath, "tls.key"), false)
+			kingpin.FatalIfError(err, "Cannot load ESS TLS config.")
+			o.ESSOptions.TLSConfig = tCfg
+		}
+
 		// Ensure default store config exists.
 		kingpin.FatalIfError(resource.Ignore(kerrors.IsAlreadyExists, mgr.GetClient().Create(context.Background(), &v1alpha1.StoreConfig{
 			ObjectMeta: metav1.ObjectMeta{
@@ -153,7 +249,33 @@ func main() {
 	}
 
 	kingpin.FatalIfError(ssh.Setup(mgr, o), "Cannot setup SSH controllers")
-	kingpin.FatalIfError(mgr.Start(ctrl.SetupSignalHandler()), "Cannot start controller manager")
+	if *debugAddress != "" {
+		kingpin.FatalIfError(mgr.Add(&sshdebug.Server{Address: *debugAddress}), "Cannot add debug endpoints")
+	}
+	if *webhookTLSCertDir != "" {
+		kingpin.FatalIfError(sshwebhook.Setup(mgr, o), "Cannot setup SSH webhooks")
+	}
+	// The scripts are killed and the connections closed on shutdown, so that
+	// no remote process or temporary file is stranded.
+	drainer := &sshv1alpha1.Drainer{Timeout: *drainTimeout}
+	kingpin.FatalIfError(mgr.Add(drainer), "Cannot add SSH drainer")
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	// The manager does not wait for the drainer after a leadership loss.
+	drainer.Drain()
+	kingpin.FatalIfError(err, "Cannot start controller manager")
+}
+
+// reconcileRates parses the maximum reconcile rates of kinds.
+func reconcileRates(flags map[string]string) (map[string]int, error) {
+	rates := make(map[string]int, len(flags))
+	for kind, v := range flags {
+		rate, err := strconv.Atoi(v)
+		if err != nil || rate <= 0 {
+			return nil, errors.Errorf("invalid rate %q of kind %s, must be a positive integer", v, kind)
+		}
+		rates[kind] = rate
+	}
+	return rates, nil
 }
 
 func customLoggerFormat() zap.EncoderConfigOption {
@@ -0,0 +1,214 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// sshrun executes the scripts of a Script manifest against a host the way
+// the Script controller does, without a Kubernetes cluster.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/crossplane/provider-ssh/apis"
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/apis/v1beta1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+	"github.com/crossplane/provider-ssh/internal/controller/script"
+	"github.com/crossplane/provider-ssh/internal/redact"
+	"github.com/crossplane/provider-ssh/internal/signature"
+)
+
+const (
+	errReadScript  = "cannot read Script manifest"
+	errDecode      = "cannot decode Script manifest"
+	errConvert     = "cannot convert Script to v1alpha1"
+	errNotScript   = "manifest is not a Script"
+	errReadCreds   = "cannot read credentials"
+	errConnect     = "cannot connect to host"
+	errFmtNoScript = "Script has no %s script"
+)
+
+// operations maps the operations that may be run onto the scripts of a
+// Script, in the order the controller runs them.
+var operations = []struct {
+	name   string
+	script func(p apisv1alpha1.ScriptParameters) string
+}{
+	{"statusCheck", func(p apisv1alpha1.ScriptParameters) string { return p.StatusCheckScript }},
+	{"exists", func(p apisv1alpha1.ScriptParameters) string { return p.ExistsScript }},
+	{"upToDate", func(p apisv1alpha1.ScriptParameters) string { return p.UpToDateScript }},
+	{"diff", func(p apisv1alpha1.ScriptParameters) string { return p.DiffScript }},
+	{"init", func(p apisv1alpha1.ScriptParameters) string { return p.InitScript }},
+	{"update", func(p apisv1alpha1.ScriptParameters) string { return p.UpdateScript }},
+	{"postReboot", func(p apisv1alpha1.ScriptParameters) string { return p.PostRebootScript }},
+	{"cleanup", func(p apisv1alpha1.ScriptParameters) string { return p.CleanupScript }},
+}
+
+func main() {
+	var names []string
+	for _, op := range operations {
+		names = append(names, op.name)
+	}
+
+	var (
+		app         = kingpin.New(filepath.Base(os.Args[0]), "Execute the scripts of a Script against a host the way provider-ssh does.").DefaultEnvars()
+		debug       = app.Flag("debug", "Log the execution of the scripts.").Short('d').Bool()
+		credentials = app.Flag("credentials", "File holding the credentials of the host, in the JSON format of the credentials of a ProviderConfig.").Short('c').ExistingFile()
+		renderOnly  = app.Flag("render-only", "Print the rendered scripts without connecting to the host.").Bool()
+		payload     = app.Flag("signing-payload", "Print the payload of the scripts signed by the signature annotation, without connecting to the host.").Bool()
+		dialTimeout = app.Flag("ssh-dial-timeout", "How long connecting to the host, including the SSH handshake, may take. 0 means no timeout.").Default("30s").Duration()
+		knownHosts  = app.Flag("known-hosts-file", "known_hosts file the host key is verified against if the credentials have no knownHosts.").ExistingFile()
+		manifest    = app.Arg("script", "File holding the Script manifest, v1alpha1 or v1beta1.").Required().ExistingFile()
+		ops         = app.Arg("operation", "Scripts to execute, in order.").Default("statusCheck").Enums(names...)
+	)
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	zl := zap.New(zap.WriteTo(io.Discard))
+	if *debug {
+		zl = redact.NewLogger(zap.New(zap.UseDevMode(true)))
+	}
+	ctrl.SetLogger(zl)
+
+	cr, err := readScript(*manifest)
+	kingpin.FatalIfError(err, "Cannot load Script")
+	p := cr.Spec.ForProvider
+
+	if *payload {
+		scripts, err := signature.Scripts(cr)
+		kingpin.FatalIfError(err, "Cannot build signing payload")
+		_, err = os.Stdout.Write(signature.Payload(scripts))
+		kingpin.FatalIfError(err, "Cannot print signing payload")
+		return
+	}
+
+	if *renderOnly {
+		for _, name := range *ops {
+			sc, err := scriptOf(p, name)
+			kingpin.FatalIfError(err, "Cannot render script")
+			printSection(name, "rendered", sshv1alpha1.RenderScript(sc, p.Variables, p.Templating))
+		}
+		return
+	}
+
+	if *credentials == "" {
+		kingpin.Fatalf("--credentials is required unless --render-only or --signing-payload is set")
+	}
+	ctx := context.Background()
+	svc, err := connect(ctx, *credentials, *dialTimeout, *knownHosts, p)
+	kingpin.FatalIfError(err, "Cannot connect")
+	defer svc.Close() // nolint: errcheck
+
+	for _, name := range *ops {
+		sc, err := scriptOf(p, name)
+		kingpin.FatalIfError(err, "Cannot execute script")
+		printSection(name, "rendered", sshv1alpha1.RenderScript(sc, p.Variables, p.Templating))
+
+		started := time.Now()
+		stdout, stderr, err := sshv1alpha1.ExecuteScript(ctx, svc, sc, p.Variables, script.SudoEnabled(p), script.ExecOptions(p)...)
+		printSection(name, "stdout", stdout)
+		printSection(name, "stderr", stderr)
+		code := sshv1alpha1.ExitStatus(err)
+		fmt.Printf("==> %s: exit code %d after %s\n", name, code, time.Since(started).Round(time.Millisecond))
+		if err != nil {
+			// Later scripts depend on the outcome of this one, as they do
+			// in the controller.
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			_ = svc.Close()
+			os.Exit(code)
+		}
+	}
+}
+
+// readScript reads the Script manifest at the supplied path, converting it
+// to the v1alpha1 version the controller reconciles.
+func readScript(path string) (*apisv1alpha1.Script, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrap(err, errReadScript)
+	}
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		return nil, errors.Wrap(err, errDecode)
+	}
+	obj, _, err := serializer.NewCodecFactory(s).UniversalDeserializer().Decode(data, nil, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, errDecode)
+	}
+	switch cr := obj.(type) {
+	case *apisv1alpha1.Script:
+		return cr, nil
+	case *v1beta1.Script:
+		hub := &apisv1alpha1.Script{}
+		return hub, errors.Wrap(cr.ConvertTo(hub), errConvert)
+	default:
+		return nil, errors.New(errNotScript)
+	}
+}
+
+// connect connects to the host of the supplied credentials file, after
+// applying the endpoint and username of the Script like the controller does.
+func connect(ctx context.Context, path string, timeout time.Duration, knownHostsFile string, p apisv1alpha1.ScriptParameters) (sshv1alpha1.RemoteExecutor, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrap(err, errReadCreds)
+	}
+	for _, fn := range []common.CredentialsOverride{common.Endpoint(p.Endpoint), common.Username(p.Username)} {
+		if data, err = fn(data); err != nil {
+			return nil, err
+		}
+	}
+	svc, err := sshv1alpha1.NewSSHClientFn(timeout, knownHostsFile)(ctx, data)
+	if err != nil {
+		return nil, errors.Wrap(err, errConnect)
+	}
+	return sshv1alpha1.NewSSHExecutor(svc), nil
+}
+
+// scriptOf returns the script of the named operation.
+func scriptOf(p apisv1alpha1.ScriptParameters, name string) (string, error) {
+	for _, op := range operations {
+		if op.name != name {
+			continue
+		}
+		if sc := op.script(p); sc != "" {
+			return sc, nil
+		}
+	}
+	return "", errors.Errorf(errFmtNoScript, name)
+}
+
+func printSection(op, section, content string) {
+	fmt.Printf("==> %s: %s\n", op, section)
+	if content == "" {
+		return
+	}
+	fmt.Print(content)
+	if content[len(content)-1] != '\n' {
+		fmt.Println()
+	}
+}
@@ -0,0 +1,28 @@
+#!/usr/bin/env bash
+
+# Copyright 2022 The Crossplane Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Enables the conversion webhook of the CRDs serving more than one version.
+# Crossplane fills in the client config of the webhook when it installs the
+# provider package.
+set -euo pipefail
+
+for crd in "$@"; do
+  if [ "$(grep -c '^    served: true$' "${crd}")" -lt 2 ]; then
+    continue
+  fi
+  awk '{ print } /^spec:$/ && !done { print "  conversion:\n    strategy: Webhook\n    webhook:\n      conversionReviewVersions:\n      - v1"; done = 1 }' "${crd}" > "${crd}.tmp"
+  mv "${crd}.tmp" "${crd}"
+done
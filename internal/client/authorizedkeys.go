@@ -0,0 +1,130 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// AuthorizedKeysPath returns the path of the authorized_keys file of the
+// supplied user, or of the connecting user if user is empty.
+func AuthorizedKeysPath(ctx context.Context, x RemoteExecutor, user string, suEnabled bool) (string, error) {
+	cmd := `printf '%s' "$HOME"`
+	if user != "" {
+		cmd = "getent passwd " + shellQuote(user) + " | cut -d: -f6"
+	}
+	stdout, _, err := RunCommand(ctx, x, cmd, nil, suEnabled)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to look up home directory")
+	}
+	home := strings.TrimSpace(stdout)
+	if home == "" {
+		return "", errors.Errorf("Unknown user %s", user)
+	}
+	return path.Join(home, ".ssh", "authorized_keys"), nil
+}
+
+// ReadAuthorizedKeys returns the content of the authorized_keys file at the
+// supplied path, or nil if the file does not exist.
+func ReadAuthorizedKeys(ctx context.Context, x RemoteExecutor, keysPath string, suEnabled bool) ([]byte, error) {
+	p := shellQuote(keysPath)
+	stdout, _, err := RunCommand(ctx, x, "if [ -f "+p+" ]; then cat "+p+"; fi", nil, suEnabled)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read authorized keys")
+	}
+	if stdout == "" {
+		return nil, nil
+	}
+	return []byte(stdout), nil
+}
+
+// WriteAuthorizedKeys replaces the authorized_keys file at the supplied path
+// with content. The file and its directory are restricted to their owner and,
+// if user is not empty, owned by user.
+func WriteAuthorizedKeys(ctx context.Context, x RemoteExecutor, keysPath string, content []byte, user string, suEnabled bool) error {
+	dir, p := shellQuote(path.Dir(keysPath)), shellQuote(keysPath)
+	cmd := "mkdir -p " + dir + " && chmod 700 " + dir +
+		" && printf '%s' " + shellQuote(string(content)) + " > " + p + " && chmod 600 " + p
+	if user != "" {
+		cmd += " && chown " + shellQuote(user+":") + " " + dir + " " + p
+	}
+	if _, stderr, err := RunCommand(ctx, x, cmd, nil, suEnabled); err != nil {
+		return errors.Wrapf(err, "Failed to write authorized keys: %s", strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+// An AuthorizedKey is a single entry of an authorized_keys file.
+type AuthorizedKey struct {
+	Key     ssh.PublicKey
+	Comment string
+	Options []string
+}
+
+// ParseAuthorizedKey parses a single entry of an authorized_keys file.
+func ParseAuthorizedKey(line string) (*AuthorizedKey, error) {
+	key, comment, options, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	return &AuthorizedKey{Key: key, Comment: comment, Options: options}, nil
+}
+
+// String renders the entry as a line of an authorized_keys file.
+func (k *AuthorizedKey) String() string {
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(k.Key)))
+	if len(k.Options) > 0 {
+		line = strings.Join(k.Options, ",") + " " + line
+	}
+	if k.Comment != "" {
+		line += " " + k.Comment
+	}
+	return line
+}
+
+// FindAuthorizedKey returns the entries of content that authorize the same
+// public key as k.
+func FindAuthorizedKey(content []byte, k *AuthorizedKey) []string {
+	var found []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if e, err := ParseAuthorizedKey(line); err == nil && sameKey(e.Key, k.Key) {
+			found = append(found, strings.TrimSpace(line))
+		}
+	}
+	return found
+}
+
+// SetAuthorizedKey returns content with all entries of the public key of k
+// replaced by k. The entry is appended if content does not authorize the
+// key yet. If remove is true the entries are removed instead.
+func SetAuthorizedKey(content []byte, k *AuthorizedKey, remove bool) []byte {
+	var out []string
+	set := remove
+	for _, line := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+		if e, err := ParseAuthorizedKey(line); err == nil && sameKey(e.Key, k.Key) {
+			if !set {
+				out = append(out, k.String())
+				set = true
+			}
+			continue
+		}
+		if line != "" || len(out) > 0 {
+			out = append(out, line)
+		}
+	}
+	if !set {
+		out = append(out, k.String())
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+func sameKey(a, b ssh.PublicKey) bool {
+	return a.Type() == b.Type() && bytes.Equal(a.Marshal(), b.Marshal())
+}
@@ -0,0 +1,176 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+const (
+	errBatchNotRun      = "Script of the batch was not executed"
+	errBatchInterrupted = "Script of the batch was interrupted"
+)
+
+// A BatchScript is a script executed by ExecuteBatch, with the variables,
+// sudo setting and execution options ExecuteScript would execute it with.
+type BatchScript struct {
+	Script      string
+	Variables   []v1alpha1.Variable
+	SudoEnabled bool
+	Options     []ExecOption
+}
+
+// A BatchResult is the result of a script executed by ExecuteBatch, as
+// ExecuteScript would return it.
+type BatchResult struct {
+	Stdout string
+	Stderr string
+	Err    error
+}
+
+// An ExitCode is the ExitError of a script exiting with the supplied
+// non-zero code, when it is not returned by a RemoteExecutor such as for the
+// scripts of a batch.
+type ExitCode int
+
+func (c ExitCode) Error() string {
+	return fmt.Sprintf("Process exited with status %d", int(c))
+}
+
+// ExitStatus returns the exit code.
+func (c ExitCode) ExitStatus() int {
+	return int(c)
+}
+
+// ExecuteBatch uploads the supplied scripts to the remote host as a single
+// shell script executing them one after the other, and runs it in a single
+// session, so that many scripts cost one upload and one session. The output
+// of each script is delimited by random markers in the stdout and stderr of
+// the session, from which their results are split. The batch is killed
+// after the sum of the timeouts of its scripts; the scripts it did not get
+// to finish return the error it ended with.
+func ExecuteBatch(ctx context.Context, x RemoteExecutor, scripts []BatchScript) ([]BatchResult, error) {
+	logger := log.FromContext(ctx).WithName("[RunBatch]")
+	if x == nil {
+		return nil, errors.New("Not connected to remote host")
+	}
+	if !executing.start() {
+		return nil, errors.New(errShuttingDown)
+	}
+	defer executing.done()
+
+	if sem := executions; sem != nil {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "Timed out waiting for other scripts to finish")
+		}
+	}
+
+	marker := randomFileName(16)
+	var b strings.Builder
+	var timeout time.Duration
+	unbounded := false
+	for i, s := range scripts {
+		o := execOptions{}
+		for _, fn := range s.Options {
+			fn(&o)
+		}
+		timeout += o.timeout
+		unbounded = unbounded || o.timeout <= 0
+
+		// The script is written by a here-document delimited by the
+		// marker, which must start a line.
+		sc := o.render(s.Script, s.Variables)
+		if !strings.HasSuffix(sc, "\n") {
+			sc += "\n"
+		}
+		dir := o.tempDir
+		if dir == "" {
+			dir = "/tmp"
+		}
+		remoteFile := path.Join(dir, randomFileName(8))
+		fmt.Fprintf(&b, "cat > %s <<'%s'\n%s%s\n", shellQuote(remoteFile), marker, sc, marker)
+		fmt.Fprintf(&b, "chmod 755 %s\n", shellQuote(remoteFile))
+		fmt.Fprintf(&b, "echo '%s begin %d'; echo '%s begin %d' >&2\n", marker, i, marker, i)
+		fmt.Fprintf(&b, "%s\n", o.command(remoteFile, sc, s.SudoEnabled))
+		fmt.Fprintf(&b, "c=$?; rm -f %s\n", shellQuote(remoteFile))
+		fmt.Fprintf(&b, "echo \"%s end %d $c\"; echo '%s end %d' >&2\n", marker, i, marker, i)
+	}
+	if unbounded {
+		timeout = 0
+	}
+
+	remoteFile := path.Join("/tmp", randomFileName(8))
+	if err := x.Upload(ctx, remoteFile, []byte(b.String()), 0o644); err != nil {
+		return nil, errors.Wrap(err, "Failed to send script to remote host")
+	}
+	stdout, stderr, err := x.Run(killOnShutdown(ctx), "/bin/sh "+shellQuote(remoteFile), timeout)
+	if _, _, err := x.Run(ctx, "rm -f "+shellQuote(remoteFile), 0); err != nil {
+		logger.Error(err, "Failed to clean up temporary file")
+	}
+	if err == nil {
+		err = errors.New(errBatchInterrupted)
+	}
+
+	results := make([]BatchResult, len(scripts))
+	for i := range scripts {
+		results[i] = splitBatch(marker, i, stdout, stderr, err)
+	}
+	logger.V(1).Info(fmt.Sprintf("Batch of %d scripts executed, len(stdout): %d, len(stderr): %d", len(scripts), len(stdout), len(stderr)))
+	return results, nil
+}
+
+// splitBatch returns the result of the script of the supplied index from the
+// output of a batch, with the error the batch ended with if the script did
+// not finish.
+func splitBatch(marker string, i int, stdout, stderr string, ended error) BatchResult {
+	begin := fmt.Sprintf("%s begin %d\n", marker, i)
+	end := fmt.Sprintf("%s end %d", marker, i)
+
+	out, ok := between(stdout, begin, end+" ")
+	if !ok {
+		return BatchResult{Err: errors.New(errBatchNotRun)}
+	}
+	errOut, _ := between(stderr, begin, end+"\n")
+	r := BatchResult{Stdout: out.text, Stderr: errOut.text}
+	line, _, _ := strings.Cut(out.rest, "\n")
+	code, err := strconv.Atoi(line)
+	switch {
+	case !out.ended || err != nil:
+		r.Err = ended
+	case code != 0:
+		r.Err = ExitCode(code)
+	}
+	return r
+}
+
+// A section is the output of a script of a batch.
+type section struct {
+	text string
+	// Whether the end marker of the script was found, and the output that
+	// follows it.
+	ended bool
+	rest  string
+}
+
+// between returns the output between the supplied begin and end markers,
+// until the end of the output if the end marker is missing. It returns
+// false if the begin marker is missing.
+func between(output, begin, end string) (section, bool) {
+	_, after, ok := strings.Cut(output, begin)
+	if !ok {
+		return section{}, false
+	}
+	text, rest, ended := strings.Cut(after, end)
+	return section{text: text, ended: ended, rest: rest}, true
+}
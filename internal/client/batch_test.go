@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+func TestExecuteBatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("executes the batch on an in-process SSH server")
+	}
+
+	node := sshtest.NewServer(t)
+	c, err := sshv1alpha1.NewSSHClient(context.Background(), node.Credentials())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close() // nolint: errcheck
+	x := sshv1alpha1.NewSSHExecutor(c)
+
+	timeout := sshv1alpha1.WithTimeout(10 * time.Second)
+	scripts := []sshv1alpha1.BatchScript{
+		{Script: "echo {{GREETING}}; echo warning >&2", Variables: []v1alpha1.Variable{{Name: "GREETING", Value: "hello"}}, Options: []sshv1alpha1.ExecOption{timeout}},
+		{Script: "printf missing; exit 100", Options: []sshv1alpha1.ExecOption{timeout}},
+		{Script: "#!/bin/sh\necho \"$LANG\"", Options: []sshv1alpha1.ExecOption{timeout, sshv1alpha1.WithLocale("C.UTF-8")}},
+	}
+	type result struct {
+		Stdout string
+		Stderr string
+		Code   int
+	}
+	want := []result{
+		{Stdout: "hello\n", Stderr: "warning\n"},
+		{Stdout: "missing", Code: 100},
+		{Stdout: "C.UTF-8\n"},
+	}
+
+	results, err := sshv1alpha1.ExecuteBatch(context.Background(), x, scripts)
+	if err != nil {
+		t.Fatalf("ExecuteBatch(...): %v", err)
+	}
+	got := make([]result, 0, len(results))
+	for _, r := range results {
+		if r.Err != nil && !sshv1alpha1.IsExitError(r.Err) {
+			t.Fatalf("ExecuteBatch(...): script error %v", r.Err)
+		}
+		got = append(got, result{Stdout: r.Stdout, Stderr: r.Stderr, Code: sshv1alpha1.ExitStatus(r.Err)})
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nEach script should get its own output and exit code, as if executed alone.\nExecuteBatch(...): -want, +got:\n%s\n", diff)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type providerConfigKey struct{}
+
+// WithProviderConfig returns a context whose SSH clients are accounted to
+// the supplied ProviderConfig in the list of open connections.
+func WithProviderConfig(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, providerConfigKey{}, name)
+}
+
+func providerConfigOf(ctx context.Context) string {
+	name, _ := ctx.Value(providerConfigKey{}).(string)
+	return name
+}
+
+// A Connection is an open connection of an SSH client created by
+// NewSSHClient.
+type Connection struct {
+	ProviderConfig string
+	Host           string
+	Port           string
+	Username       string
+	// Alias is the human-friendly name of the host, empty if the
+	// ProviderConfig sets none.
+	Alias string
+	// Age is the time since the connection was established.
+	Age time.Duration
+	// Idle is the time since a session of the connection was last opened
+	// or closed.
+	Idle time.Duration
+	// Sessions is the number of open sessions, including SFTP sessions.
+	Sessions int64
+}
+
+// connections are the open connections of the SSH clients created by
+// NewSSHClient, from the time they are established until either side closes
+// them, such as a controller disconnecting at the end of its reconcile.
+var connections = struct {
+	sync.Mutex
+	open map[*conn]struct{}
+}{open: map[*conn]struct{}{}}
+
+// Connections returns the open connections of the SSH clients created by
+// NewSSHClient, ordered by ProviderConfig and age.
+func Connections() []Connection {
+	connections.Lock()
+	defer connections.Unlock()
+	now := time.Now()
+	cs := make([]Connection, 0, len(connections.open))
+	for c := range connections.open {
+		cs = append(cs, Connection{
+			ProviderConfig: c.providerConfig,
+			Host:           c.host,
+			Port:           c.port,
+			Username:       c.User(),
+			Alias:          c.alias,
+			Age:            now.Sub(c.established),
+			Idle:           now.Sub(time.Unix(0, c.lastUsed.Load())),
+			Sessions:       c.sessions.Load(),
+		})
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].ProviderConfig != cs[j].ProviderConfig {
+			return cs[i].ProviderConfig < cs[j].ProviderConfig
+		}
+		return cs[i].Age > cs[j].Age
+	})
+	return cs
+}
+
+// track adds the connection to the open connections until it is closed by
+// either side.
+func track(c *conn) {
+	c.established = time.Now()
+	c.lastUsed.Store(c.established.UnixNano())
+	connections.Lock()
+	connections.open[c] = struct{}{}
+	connections.Unlock()
+	go func() {
+		_ = c.Wait()
+		untrack(c)
+	}()
+}
+
+// untrack removes the connection from the open connections.
+func untrack(c *conn) {
+	c.closed.Store(true)
+	connections.Lock()
+	delete(connections.open, c)
+	connections.Unlock()
+}
+
+// Close closes the connection, removing it from the open connections right
+// away rather than once the host noticed.
+func (c *conn) Close() error {
+	untrack(c)
+	return c.Conn.Close()
+}
+
+// OpenChannel opens a channel of the connection, counting the sessions that
+// are open.
+func (c *conn) OpenChannel(name string, data []byte) (ssh.Channel, <-chan *ssh.Request, error) {
+	ch, reqs, err := c.Conn.OpenChannel(name, data)
+	if err != nil || name != "session" {
+		return ch, reqs, err
+	}
+	c.sessions.Add(1)
+	c.lastUsed.Store(time.Now().UnixNano())
+	return &channel{Channel: ch, conn: c}, reqs, nil
+}
+
+// A channel is a session channel of a tracked connection.
+type channel struct {
+	ssh.Channel
+
+	conn   *conn
+	closed sync.Once
+}
+
+func (ch *channel) Close() error {
+	ch.closed.Do(func() {
+		ch.conn.sessions.Add(-1)
+		ch.conn.lastUsed.Store(time.Now().UnixNano())
+	})
+	return ch.Channel.Close()
+}
@@ -0,0 +1,93 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DefaultPort is the port of the SSH server of the credentials without one.
+const DefaultPort Port = 22
+
+// A Port is the port of an SSH server. It is encoded as a JSON number, but
+// numeric strings such as "22" are accepted as well, the encoding of the
+// credentials written before the port was typed.
+type Port int
+
+// ParsePort parses the supplied decimal port.
+func ParsePort(s string) (Port, error) {
+	p, err := atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	return p, p.Validate()
+}
+
+func atoi(s string) (Port, error) {
+	p, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, errors.Errorf("port %q is not a number", s)
+	}
+	return Port(p), nil
+}
+
+// Validate returns an error unless the port is between 1 and 65535.
+func (p Port) Validate() error {
+	if p < 1 || p > 65535 {
+		return errors.Errorf("port %d is not between 1 and 65535", p)
+	}
+	return nil
+}
+
+// String returns the decimal port.
+func (p Port) String() string {
+	return strconv.Itoa(int(p))
+}
+
+// UnmarshalJSON decodes a port from a JSON number or a numeric string. An
+// empty string decodes to zero, an unset port.
+func (p *Port) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if strings.TrimSpace(s) == "" {
+			*p = 0
+			return nil
+		}
+		v, err := atoi(s)
+		*p = v
+		return err
+	}
+	var v int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return errors.Errorf("port %s is not a number", data)
+	}
+	*p = Port(v)
+	return nil
+}
+
+// ValidateHost returns an error unless the supplied host is an IPv4 or IPv6
+// address, or a hostname per RFC 1123. Hostnames are not case sensitive and
+// may be fully qualified, ending with a dot.
+func ValidateHost(host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+	if msgs := validation.IsDNS1123Subdomain(name); name == "" || len(msgs) > 0 {
+		return errors.Errorf("host %q is neither an IP address nor a valid hostname", host)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if msgs := validation.IsDNS1123Label(label); len(msgs) > 0 {
+			return errors.Errorf("host %q is neither an IP address nor a valid hostname", host)
+		}
+	}
+	return nil
+}
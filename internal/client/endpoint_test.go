@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+func TestValidateHost(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		host   string
+		valid  bool
+	}{
+		"IPv4":          {reason: "IPv4 addresses should be valid.", host: "10.0.0.1", valid: true},
+		"IPv6":          {reason: "IPv6 addresses should be valid.", host: "fd00::1", valid: true},
+		"SingleLabel":   {reason: "Hostnames without a domain should be valid.", host: "localhost", valid: true},
+		"FQDN":          {reason: "Fully qualified hostnames should be valid.", host: "web-01.example.com.", valid: true},
+		"UpperCase":     {reason: "Hostnames should not be case sensitive.", host: "Web01.Example.COM", valid: true},
+		"Underscore":    {reason: "Hostnames should not contain underscores.", host: "web_01", valid: false},
+		"LeadingHyphen": {reason: "Labels should not start with a hyphen.", host: "-web.example.com", valid: false},
+		"HostPort":      {reason: "A host should not include a port.", host: "10.0.0.1:22", valid: false},
+		"Empty":         {reason: "An empty host should be invalid.", host: "", valid: false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := sshv1alpha1.ValidateHost(tc.host)
+			if got := err == nil; got != tc.valid {
+				t.Errorf("\n%s\nValidateHost(%q): %v, want valid %t", tc.reason, tc.host, err, tc.valid)
+			}
+		})
+	}
+}
+
+func TestPortUnmarshalJSON(t *testing.T) {
+	type want struct {
+		port sshv1alpha1.Port
+		err  bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		data   string
+		want   want
+	}{
+		"Number": {
+			reason: "Ports should be decoded from JSON numbers.",
+			data:   `{"hostPort":2222}`,
+			want:   want{port: 2222},
+		},
+		"String": {
+			reason: "Ports should be decoded from the numeric strings of older credentials.",
+			data:   `{"hostPort":" 2222"}`,
+			want:   want{port: 2222},
+		},
+		"EmptyString": {
+			reason: "An empty port should decode to an unset port.",
+			data:   `{"hostPort":""}`,
+		},
+		"NotANumber": {
+			reason: "A port that is not a number should be rejected.",
+			data:   `{"hostPort":"ssh"}`,
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kc := sshv1alpha1.Config{}
+			err := json.Unmarshal([]byte(tc.data), &kc)
+			if got := err != nil; got != tc.want.err {
+				t.Fatalf("\n%s\njson.Unmarshal(...): %v, want error %t", tc.reason, err, tc.want.err)
+			}
+			if diff := cmp.Diff(tc.want.port, kc.RemoteHostPort); diff != "" {
+				t.Errorf("\n%s\njson.Unmarshal(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestParsePort(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		s      string
+		want   sshv1alpha1.Port
+		err    bool
+	}{
+		"Valid":      {reason: "Decimal ports should be parsed.", s: "22\n", want: 22},
+		"OutOfRange": {reason: "Ports above 65535 should be rejected.", s: "65536", err: true},
+		"NotANumber": {reason: "Ports that are not numbers should be rejected.", s: "ssh", err: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := sshv1alpha1.ParsePort(tc.s)
+			if gotErr := err != nil; gotErr != tc.err {
+				t.Fatalf("\n%s\nParsePort(%q): %v, want error %t", tc.reason, tc.s, err, tc.err)
+			}
+			if !tc.err && got != tc.want {
+				t.Errorf("\n%s\nParsePort(%q): got %d, want %d", tc.reason, tc.s, got, tc.want)
+			}
+		})
+	}
+}
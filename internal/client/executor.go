@@ -0,0 +1,256 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// A RemoteExecutor executes scripts on a remote host.
+type RemoteExecutor interface {
+	// Upload writes the content to the remote path with the supplied mode,
+	// creating the missing parent directories.
+	Upload(ctx context.Context, remotePath string, content []byte, mode os.FileMode) error
+
+	// Run runs the command on the remote host and returns its stdout and
+	// stderr. The command is killed if it does not finish within timeout,
+	// unless timeout is zero. Commands exiting with a non-zero code return
+	// an ExitError.
+	Run(ctx context.Context, cmd string, timeout time.Duration) (string, string, error)
+
+	// Fetch returns the content of the remote file at the supplied path.
+	// Files larger than maxSize bytes are rejected. The returned error wraps
+	// os.ErrNotExist if the file does not exist.
+	Fetch(ctx context.Context, remotePath string, maxSize int64) ([]byte, error)
+
+	// Close closes the connection to the remote host.
+	Close() error
+}
+
+// A StreamingExecutor is a RemoteExecutor that streams the output of the
+// commands it runs as they produce it.
+type StreamingExecutor interface {
+	RemoteExecutor
+
+	// Stream runs the command like Run, writing its stdout and stderr to the
+	// supplied writers.
+	Stream(ctx context.Context, cmd string, timeout time.Duration, stdout, stderr io.Writer) error
+}
+
+// A FileExecutor is a RemoteExecutor that also observes and manages the
+// attributes of remote files and the files below remote directories.
+type FileExecutor interface {
+	RemoteExecutor
+
+	// StatFile returns the state of the remote file at the supplied path,
+	// or nil if the file does not exist.
+	StatFile(ctx context.Context, remotePath string) (*FileInfo, error)
+
+	// WriteFile writes the content to the remote path like Upload. The
+	// owner is only changed if uid or gid is not negative.
+	WriteFile(ctx context.Context, remotePath string, content []byte, mode os.FileMode, uid, gid int) error
+
+	// WriteFiles writes the supplied files, keyed by their path relative to
+	// the remote directory, with the supplied permissions.
+	WriteFiles(ctx context.Context, dir string, files map[string][]byte, mode os.FileMode) error
+
+	// ListFiles returns the checksums of the regular files below the remote
+	// directory, keyed by their path relative to the directory, or nil if
+	// the directory does not exist.
+	ListFiles(ctx context.Context, dir string) (map[string]string, error)
+
+	// RemoveFile removes the remote file at the supplied path. Files that
+	// do not exist are ignored.
+	RemoveFile(ctx context.Context, remotePath string) error
+
+	// RemoveFiles removes the supplied files, given by their path relative
+	// to the remote directory. Files that do not exist are ignored.
+	RemoveFiles(ctx context.Context, dir string, names []string) error
+
+	// RemoveDirectory removes the remote directory and everything below it.
+	RemoveDirectory(ctx context.Context, dir string) error
+}
+
+// An ExitError is returned by a RemoteExecutor when a command exits with a
+// non-zero code.
+type ExitError interface {
+	error
+	ExitStatus() int
+}
+
+// IsExitError reports whether err carries the exit code of a command.
+func IsExitError(err error) bool {
+	_, ok := err.(ExitError)
+	return ok
+}
+
+// An SSHExecutor is a RemoteExecutor running commands in sessions of an SSH
+// client and transferring files over SFTP. An SSHExecutor using a client
+// created by NewSSHClient connects to the host again, once per operation,
+// when its connection went stale.
+type SSHExecutor struct {
+	client *ssh.Client
+}
+
+// NewSSHExecutor returns a RemoteExecutor using the supplied SSH client.
+func NewSSHExecutor(client *ssh.Client) *SSHExecutor {
+	return &SSHExecutor{client: client}
+}
+
+// Remote returns the host the executor is connected to.
+func (x *SSHExecutor) Remote() Remote {
+	return RemoteOf(x.client)
+}
+
+// Upload writes the content to the remote path over SFTP.
+func (x *SSHExecutor) Upload(ctx context.Context, remotePath string, content []byte, mode os.FileMode) error {
+	err := WriteFile(x.client, remotePath, content, mode, -1, -1)
+	if err != nil && x.reconnect(ctx, err) {
+		err = WriteFile(x.client, remotePath, content, mode, -1, -1)
+	}
+	return err
+}
+
+// Run runs the command in a new session.
+func (x *SSHExecutor) Run(ctx context.Context, cmd string, timeout time.Duration) (string, string, error) {
+	// Buffers to capture stdout and stderr separately
+	var stdoutBuf, stderrBuf bytes.Buffer
+	err := x.Stream(ctx, cmd, timeout, &stdoutBuf, &stderrBuf)
+	return stdoutBuf.String(), stderrBuf.String(), err
+}
+
+// Stream runs the command in a new session.
+func (x *SSHExecutor) Stream(ctx context.Context, cmd string, timeout time.Duration, stdout, stderr io.Writer) error {
+	session, err := x.client.NewSession()
+	if err != nil && x.reconnect(ctx, err) {
+		session, err = x.client.NewSession()
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to create session")
+	}
+	defer closeSession(session)
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+	return run(session, cmd, timeout, shutdownOf(ctx))
+}
+
+// Fetch reads the remote file over SFTP.
+func (x *SSHExecutor) Fetch(ctx context.Context, remotePath string, maxSize int64) ([]byte, error) {
+	content, err := ReadFile(x.client, remotePath, maxSize)
+	if err != nil && x.reconnect(ctx, err) {
+		content, err = ReadFile(x.client, remotePath, maxSize)
+	}
+	return content, err
+}
+
+// StatFile stats and reads the remote file over SFTP.
+func (x *SSHExecutor) StatFile(ctx context.Context, remotePath string) (*FileInfo, error) {
+	var fi *FileInfo
+	err := x.withReconnect(ctx, func(client *ssh.Client) (err error) {
+		fi, err = StatFile(client, remotePath)
+		return err
+	})
+	return fi, err
+}
+
+// WriteFile writes the remote file over SFTP.
+func (x *SSHExecutor) WriteFile(ctx context.Context, remotePath string, content []byte, mode os.FileMode, uid, gid int) error {
+	return x.withReconnect(ctx, func(client *ssh.Client) error {
+		return WriteFile(client, remotePath, content, mode, uid, gid)
+	})
+}
+
+// WriteFiles writes the remote files over SFTP.
+func (x *SSHExecutor) WriteFiles(ctx context.Context, dir string, files map[string][]byte, mode os.FileMode) error {
+	return x.withReconnect(ctx, func(client *ssh.Client) error {
+		return WriteFiles(client, dir, files, mode)
+	})
+}
+
+// ListFiles walks the remote directory over SFTP.
+func (x *SSHExecutor) ListFiles(ctx context.Context, dir string) (map[string]string, error) {
+	var files map[string]string
+	err := x.withReconnect(ctx, func(client *ssh.Client) (err error) {
+		files, err = ListFiles(client, dir)
+		return err
+	})
+	return files, err
+}
+
+// RemoveFile removes the remote file over SFTP.
+func (x *SSHExecutor) RemoveFile(ctx context.Context, remotePath string) error {
+	return x.withReconnect(ctx, func(client *ssh.Client) error {
+		return RemoveFile(client, remotePath)
+	})
+}
+
+// RemoveFiles removes the remote files over SFTP.
+func (x *SSHExecutor) RemoveFiles(ctx context.Context, dir string, names []string) error {
+	return x.withReconnect(ctx, func(client *ssh.Client) error {
+		return RemoveFiles(client, dir, names)
+	})
+}
+
+// RemoveDirectory removes the remote directory over SFTP.
+func (x *SSHExecutor) RemoveDirectory(ctx context.Context, dir string) error {
+	return x.withReconnect(ctx, func(client *ssh.Client) error {
+		return RemoveDirectory(client, dir)
+	})
+}
+
+// withReconnect calls fn with the client of the executor, and once more
+// with a new client if it failed on a stale connection.
+func (x *SSHExecutor) withReconnect(ctx context.Context, fn func(*ssh.Client) error) error {
+	err := fn(x.client)
+	if err != nil && x.reconnect(ctx, err) {
+		err = fn(x.client)
+	}
+	return err
+}
+
+// reconnect replaces the client of the executor by a new connection to its
+// host if err is the one of a stale connection, such as one the host or a
+// firewall dropped while it was idle. It reports whether it reconnected.
+func (x *SSHExecutor) reconnect(ctx context.Context, err error) bool {
+	c, ok := x.client.Conn.(*conn)
+	if !ok || c.redial == nil || !stale(err) {
+		return false
+	}
+	logger := log.FromContext(ctx).WithName("[SSHExecutor]")
+	logger.Info("Connection to remote host went stale, reconnecting...", "error", err.Error())
+	client, derr := c.redial(ctx)
+	if derr != nil {
+		logger.Error(derr, "Failed to reconnect to remote host")
+		return false
+	}
+	_ = x.client.Close()
+	x.client = client
+	return true
+}
+
+// stale reports whether err is the one of a connection that was closed,
+// rather than one reported by the host.
+func stale(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// Close closes the SSH client.
+func (x *SSHExecutor) Close() error {
+	return x.client.Close()
+}
+
+// Remove removes the remote file at the supplied path through the executor.
+// Files that do not exist are ignored.
+func Remove(ctx context.Context, x RemoteExecutor, remotePath string) error {
+	_, stderr, err := x.Run(ctx, "rm -f "+shellQuote(remotePath), 0)
+	return errors.Wrapf(err, "Failed to remove remote file: %s", stderr)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+func TestSSHExecutorReconnect(t *testing.T) {
+	if testing.Short() {
+		t.Skip("connects to an in-process SSH server")
+	}
+
+	cases := map[string]struct {
+		reason string
+		op     func(ctx context.Context, x *sshv1alpha1.SSHExecutor, dir string) (string, error)
+		want   string
+	}{
+		"Run": {
+			reason: "A command should be run over a new connection when the connection of the executor went stale.",
+			op: func(ctx context.Context, x *sshv1alpha1.SSHExecutor, _ string) (string, error) {
+				stdout, _, err := x.Run(ctx, "echo hello", 10*time.Second)
+				return stdout, err
+			},
+			want: "hello\n",
+		},
+		"Upload": {
+			reason: "A file should be uploaded over a new connection when the connection of the executor went stale.",
+			op: func(ctx context.Context, x *sshv1alpha1.SSHExecutor, dir string) (string, error) {
+				uploaded := path.Join(dir, "uploaded")
+				if err := x.Upload(ctx, uploaded, []byte("content"), 0o644); err != nil {
+					return "", err
+				}
+				b, err := x.Fetch(ctx, uploaded, 1024)
+				return string(b), err
+			},
+			want: "content",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := sshtest.NewServer(t)
+			c, err := sshv1alpha1.NewSSHClient(context.Background(), srv.Credentials())
+			if err != nil {
+				t.Fatal(err)
+			}
+			x := sshv1alpha1.NewSSHExecutor(c)
+			defer x.Close() // nolint: errcheck
+
+			srv.DropConnections()
+			// Wait for the client to notice the connection is gone.
+			_ = c.Wait()
+
+			got, err := tc.op(context.Background(), x, srv.Dir)
+			if err != nil {
+				t.Fatalf("\n%s\n%s: %v", tc.reason, name, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\n%s: -want, +got:\n%s\n", tc.reason, name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+// ResetShutdown undoes Shutdown, so that the tests following one can execute
+// scripts.
+func ResetShutdown() {
+	executing = newDrain()
+}
+
+// Command returns the command executing the remote file of the script with
+// the supplied options.
+func Command(remoteFile, script string, suEnabled bool, opts ...ExecOption) string {
+	o := execOptions{}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	return o.command(remoteFile, script, suEnabled)
+}
@@ -0,0 +1,82 @@
+package ssh
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// factsTimeout bounds the time facts are gathered for.
+const factsTimeout = 30 * time.Second
+
+// factsCommand prints the facts of a host as key=value lines. Facts that
+// cannot be determined on the host are printed empty.
+const factsCommand = `(
+printf 'hostname=%s\n' "$(hostname 2>/dev/null)"
+printf 'system=%s\n' "$(uname -s 2>/dev/null)"
+printf 'kernel=%s\n' "$(uname -r 2>/dev/null)"
+printf 'arch=%s\n' "$(uname -m 2>/dev/null)"
+ID= VERSION_ID=
+[ -r /etc/os-release ] && . /etc/os-release
+printf 'os=%s\nosVersion=%s\n' "$ID" "$VERSION_ID"
+printf 'cpus=%s\n' "$(nproc 2>/dev/null || getconf _NPROCESSORS_ONLN 2>/dev/null)"
+printf 'memoryKB=%s\n' "$(awk '/^MemTotal:/ {print $2}' /proc/meminfo 2>/dev/null)"
+printf 'shell=%s\n' "$([ -x /bin/sh ] && echo /bin/sh || command -v sh 2>/dev/null)"
+printf 'tmpDir=%s\n' "${TMPDIR:-/tmp}"
+)`
+
+// bootIDCommand prints the boot ID of Linux hosts, or the boot time of the
+// hosts without one, such as the BSDs.
+const bootIDCommand = `cat /proc/sys/kernel/random/boot_id 2>/dev/null || sysctl -n kern.boottime`
+
+// intFacts are the facts that are integers.
+var intFacts = map[string]bool{"cpus": true, "memoryKB": true}
+
+// GatherFacts returns the facts of the host of the executor: its hostname,
+// system and kernel from uname, its arch, the os and osVersion of its
+// /etc/os-release, its number of cpus and its memoryKB, the path of its sh
+// shell, /bin/sh unless the host has none, and its tmpDir.
+func GatherFacts(ctx context.Context, x RemoteExecutor) (map[string]any, error) {
+	stdout, stderr, err := x.Run(ctx, factsCommand, factsTimeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to gather facts: %s", stderr)
+	}
+	return ParseFacts(stdout), nil
+}
+
+// BootID returns an identifier of the current boot of the host of the
+// executor, that changes whenever the host reboots.
+func BootID(ctx context.Context, x RemoteExecutor) (string, error) {
+	stdout, stderr, err := x.Run(ctx, bootIDCommand, factsTimeout)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to read boot ID: %s", stderr)
+	}
+	id := strings.TrimSpace(stdout)
+	if id == "" {
+		return "", errors.New("Failed to read boot ID: empty output")
+	}
+	return id, nil
+}
+
+// ParseFacts parses the key=value lines of facts. Integer facts that are not
+// integers are omitted.
+func ParseFacts(out string) map[string]any {
+	facts := map[string]any{}
+	for _, line := range strings.Split(out, "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || k == "" {
+			continue
+		}
+		if !intFacts[k] {
+			facts[k] = v
+			continue
+		}
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			facts[k] = i
+		}
+	}
+	return facts
+}
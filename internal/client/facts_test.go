@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+func TestParseFacts(t *testing.T) {
+	out := "hostname=web-1\nsystem=Linux\nkernel=6.8.0-45-generic\narch=x86_64\nos=ubuntu\nosVersion=24.04\ncpus=4\nmemoryKB=\nnot a fact\n"
+	want := map[string]any{
+		"hostname":  "web-1",
+		"system":    "Linux",
+		"kernel":    "6.8.0-45-generic",
+		"arch":      "x86_64",
+		"os":        "ubuntu",
+		"osVersion": "24.04",
+		"cpus":      int64(4),
+	}
+	if diff := cmp.Diff(want, sshv1alpha1.ParseFacts(out)); diff != "" {
+		t.Errorf("ParseFacts(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestBootID(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		stdout string
+		err    error
+		want   string
+	}{
+		"BootID": {
+			reason: "The boot ID printed by the host should be returned without its newline.",
+			stdout: "8a1c4b1e-3f5d-4c2a-9b7e-6d0f1e2a3b4c\n",
+			want:   "8a1c4b1e-3f5d-4c2a-9b7e-6d0f1e2a3b4c",
+		},
+		"Empty": {
+			reason: "We should return an error if the host printed no boot ID.",
+		},
+		"Failed": {
+			reason: "We should return an error if the boot ID cannot be read.",
+			err:    sshfake.ExitError(1),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := &sshfake.Executor{MockRun: func(string) (string, string, error) { return tc.stdout, "", tc.err }}
+			got, err := sshv1alpha1.BootID(context.Background(), x)
+			if gotErr, wantErr := err != nil, tc.want == ""; gotErr != wantErr {
+				t.Fatalf("\n%s\nBootID(...): error %v, want error %t", tc.reason, err, wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nBootID(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides an in-memory RemoteExecutor for tests.
+package fake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+var _ sshv1alpha1.FileExecutor = &Executor{}
+
+const (
+	errClosed      = "connection closed"
+	errFmtTooLarge = "Remote file is larger than %d bytes"
+)
+
+// An ExitError is returned by an Executor for scripts exiting with the
+// supplied non-zero code.
+type ExitError int
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf("Process exited with status %d", int(e))
+}
+
+// ExitStatus returns the exit code.
+func (e ExitError) ExitStatus() int {
+	return int(e)
+}
+
+// An Executor is an in-memory FileExecutor. Uploaded files are kept in
+// memory and removed by "rm -f" commands, every other command is passed to
+// MockRun. The zero value is an Executor whose commands all succeed without
+// output.
+type Executor struct {
+	// MockRun returns the result of running the supplied script, which is
+	// the content of the uploaded file the command runs, if any, and the
+	// command itself otherwise.
+	MockRun func(script string) (stdout, stderr string, err error)
+
+	// Host is the host the Executor reports being connected to.
+	Host sshv1alpha1.Remote
+
+	mu      sync.Mutex
+	files   map[string]*file
+	scripts []string
+	closed  bool
+}
+
+// A file is a file stored on the fake host. Files are owned by root unless
+// their owner was changed.
+type file struct {
+	content []byte
+	mode    os.FileMode
+	uid     int
+	gid     int
+}
+
+// Remote returns the host the Executor reports being connected to.
+func (x *Executor) Remote() sshv1alpha1.Remote {
+	return x.Host
+}
+
+// Upload stores the content of the remote file in memory.
+func (x *Executor) Upload(ctx context.Context, remotePath string, content []byte, mode os.FileMode) error {
+	return x.WriteFile(ctx, remotePath, content, mode, -1, -1)
+}
+
+// Run records the script the command runs and returns the result of MockRun.
+func (x *Executor) Run(_ context.Context, cmd string, _ time.Duration) (string, string, error) {
+	x.mu.Lock()
+	if x.closed {
+		x.mu.Unlock()
+		return "", "", errors.New(errClosed)
+	}
+	if p, ok := strings.CutPrefix(cmd, "rm -f "); ok {
+		delete(x.files, strings.Trim(p, "'"))
+		x.mu.Unlock()
+		return "", "", nil
+	}
+	script := cmd
+	if f := strings.Fields(cmd); len(f) > 0 {
+		if uploaded, ok := x.files[strings.Trim(f[len(f)-1], "'")]; ok {
+			script = string(uploaded.content)
+		}
+	}
+	x.scripts = append(x.scripts, script)
+	run := x.MockRun
+	x.mu.Unlock()
+
+	if run == nil {
+		return "", "", nil
+	}
+	return run(script)
+}
+
+// Fetch returns the content of the remote file stored in memory.
+func (x *Executor) Fetch(_ context.Context, remotePath string, maxSize int64) ([]byte, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return nil, errors.New(errClosed)
+	}
+	f, ok := x.files[remotePath]
+	if !ok {
+		return nil, errors.Wrap(os.ErrNotExist, "Failed to open remote file")
+	}
+	if int64(len(f.content)) > maxSize {
+		return nil, errors.Errorf(errFmtTooLarge, maxSize)
+	}
+	return bytes.Clone(f.content), nil
+}
+
+// StatFile returns the state of the remote file stored in memory.
+func (x *Executor) StatFile(_ context.Context, remotePath string) (*sshv1alpha1.FileInfo, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return nil, errors.New(errClosed)
+	}
+	f, ok := x.files[remotePath]
+	if !ok {
+		return nil, nil
+	}
+	return &sshv1alpha1.FileInfo{Checksum: sshv1alpha1.Checksum(f.content), Mode: f.mode, UID: f.uid, GID: f.gid}, nil
+}
+
+// WriteFile stores the content of the remote file in memory.
+func (x *Executor) WriteFile(_ context.Context, remotePath string, content []byte, mode os.FileMode, uid, gid int) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return errors.New(errClosed)
+	}
+	x.write(remotePath, content, mode, uid, gid)
+	return nil
+}
+
+// WriteFiles stores the content of the remote files in memory.
+func (x *Executor) WriteFiles(_ context.Context, dir string, files map[string][]byte, mode os.FileMode) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return errors.New(errClosed)
+	}
+	for name, content := range files {
+		x.write(path.Join(dir, name), content, mode, -1, -1)
+	}
+	return nil
+}
+
+func (x *Executor) write(remotePath string, content []byte, mode os.FileMode, uid, gid int) {
+	if x.files == nil {
+		x.files = map[string]*file{}
+	}
+	f, ok := x.files[remotePath]
+	if !ok {
+		f = &file{}
+		x.files[remotePath] = f
+	}
+	f.content, f.mode = bytes.Clone(content), mode
+	if uid >= 0 {
+		f.uid = uid
+	}
+	if gid >= 0 {
+		f.gid = gid
+	}
+}
+
+// ListFiles returns the checksums of the remote files stored in memory below
+// the directory, or nil if there are none.
+func (x *Executor) ListFiles(_ context.Context, dir string) (map[string]string, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return nil, errors.New(errClosed)
+	}
+	var files map[string]string
+	for p, f := range x.files {
+		if name, ok := strings.CutPrefix(p, dir+"/"); ok {
+			if files == nil {
+				files = map[string]string{}
+			}
+			files[name] = sshv1alpha1.Checksum(f.content)
+		}
+	}
+	return files, nil
+}
+
+// RemoveFile removes the remote file stored in memory.
+func (x *Executor) RemoveFile(_ context.Context, remotePath string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return errors.New(errClosed)
+	}
+	delete(x.files, remotePath)
+	return nil
+}
+
+// RemoveFiles removes the remote files stored in memory.
+func (x *Executor) RemoveFiles(_ context.Context, dir string, names []string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return errors.New(errClosed)
+	}
+	for _, name := range names {
+		delete(x.files, path.Join(dir, name))
+	}
+	return nil
+}
+
+// RemoveDirectory removes the remote files stored in memory below the
+// directory.
+func (x *Executor) RemoveDirectory(_ context.Context, dir string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return errors.New(errClosed)
+	}
+	for p := range x.files {
+		if strings.HasPrefix(p, dir+"/") {
+			delete(x.files, p)
+		}
+	}
+	return nil
+}
+
+// Close closes the Executor. Every later call fails.
+func (x *Executor) Close() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.closed = true
+	return nil
+}
+
+// Closed reports whether the Executor was closed.
+func (x *Executor) Closed() bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.closed
+}
+
+// Files returns the paths of the files stored on the fake host, sorted.
+func (x *Executor) Files() []string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	paths := make([]string, 0, len(x.files))
+	for p := range x.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Scripts returns the scripts run by the Executor, in order.
+func (x *Executor) Scripts() []string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return append([]string(nil), x.scripts...)
+}
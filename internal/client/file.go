@@ -0,0 +1,403 @@
+package ssh
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+const (
+	// uploadAttempts is the number of times a write interrupted by a
+	// transient failure, such as a lost SFTP channel, is attempted.
+	uploadAttempts = 3
+
+	// uploadRetryDelay is the delay before the first retry of a write,
+	// doubled for every later retry.
+	uploadRetryDelay = time.Second
+
+	// compressionMinSize is the size of the smallest content compressed,
+	// when compression is enabled. The compression of smaller contents
+	// saves less than the session decompressing them costs.
+	compressionMinSize = 16 << 10
+)
+
+// FileInfo is the observed state of a remote file.
+type FileInfo struct {
+	Checksum string
+	Mode     os.FileMode
+	UID      int
+	GID      int
+}
+
+// Checksum returns the hex encoded SHA-256 hash of the content.
+func Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// StatFile returns the state of the remote file at the supplied path, or nil
+// if the file does not exist.
+func StatFile(client *ssh.Client, remotePath string) (*FileInfo, error) {
+	sftpClient, err := newSFTPClient(client)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create sftp client")
+	}
+	defer sftpClient.Close() // nolint: errcheck
+
+	fi, err := sftpClient.Stat(remotePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to stat remote file")
+	}
+
+	f, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open remote file")
+	}
+	defer f.Close() // nolint: errcheck
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, errors.Wrap(err, "Failed to read remote file")
+	}
+
+	info := &FileInfo{Checksum: hex.EncodeToString(h.Sum(nil)), Mode: fi.Mode().Perm(), UID: -1, GID: -1}
+	if st, ok := fi.Sys().(*sftp.FileStat); ok {
+		info.UID, info.GID = int(st.UID), int(st.GID)
+	}
+	return info, nil
+}
+
+// WriteFile writes the content to the remote file at the supplied path,
+// creating its parent directories, and sets its permissions. The owner is
+// only changed if uid or gid is not negative. Transient failures are retried,
+// resuming the partial write.
+func WriteFile(client *ssh.Client, remotePath string, content []byte, mode os.FileMode, uid, gid int) error {
+	compressed := writeCompressed(client, remotePath, content)
+	return withRetries(client, func(sftpClient *sftp.Client) error {
+		if !compressed {
+			if err := writeContent(sftpClient, remotePath, content); err != nil {
+				return err
+			}
+		}
+		return setAttributes(sftpClient, remotePath, int64(len(content)), mode, uid, gid)
+	})
+}
+
+// WriteFiles writes the supplied files, keyed by their path relative to the
+// remote directory, with the supplied permissions. Transient failures are
+// retried, skipping the files already written.
+func WriteFiles(client *ssh.Client, dir string, files map[string][]byte, mode os.FileMode) error {
+	compressed := map[string]bool{}
+	for name, content := range files {
+		compressed[name] = writeCompressed(client, path.Join(dir, name), content)
+	}
+	return withRetries(client, func(sftpClient *sftp.Client) error {
+		for name, content := range files {
+			remotePath := path.Join(dir, name)
+			if !compressed[name] {
+				if err := writeContent(sftpClient, remotePath, content); err != nil {
+					return errors.Wrapf(err, "Failed to write %s", name)
+				}
+			}
+			if err := setAttributes(sftpClient, remotePath, int64(len(content)), mode, -1, -1); err != nil {
+				return errors.Wrapf(err, "Failed to write %s", name)
+			}
+		}
+		return nil
+	})
+}
+
+// writeCompressed writes the content to the remote file through gzip on the
+// host, if compression is enabled for the connection of the client and the
+// content is large enough. It reports whether the file was written. Files
+// that were not, such as when gzip is missing, are written over SFTP, which
+// resumes a partial decompression.
+func writeCompressed(client *ssh.Client, remotePath string, content []byte) bool {
+	c, ok := client.Conn.(*conn)
+	if !ok || c.compression != string(v1alpha1.TransferCompressionGzip) || len(content) < compressionMinSize {
+		return false
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil || zw.Close() != nil || buf.Len() >= len(content) {
+		return false
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return false
+	}
+	defer closeSession(session)
+	session.Stdin = uploadReader(client, &buf)
+	cmd := "mkdir -p " + shellQuote(path.Dir(remotePath)) + " && gzip -dc > " + shellQuote(remotePath)
+	return session.Run(cmd) == nil
+}
+
+// withRetries calls fn with a new SFTP client until it succeeds, fails with
+// an error reported by the server, or was attempted uploadAttempts times.
+// It does not retry once the connection of the client is closed.
+func withRetries(client *ssh.Client, fn func(*sftp.Client) error) error {
+	delay := uploadRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := func() error {
+			sftpClient, err := newSFTPClient(client)
+			if err != nil {
+				return errors.Wrap(err, "Failed to create sftp client")
+			}
+			defer sftpClient.Close() // nolint: errcheck
+			return fn(sftpClient)
+		}()
+		if err == nil || !transient(err) || attempt == uploadAttempts || closed(client) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// closed reports whether the connection of a client created by NewSSHClient
+// was closed by either side.
+func closed(client *ssh.Client) bool {
+	c, ok := client.Conn.(*conn)
+	return ok && c.closed.Load()
+}
+
+// transient reports whether err may not happen again on a new SFTP client,
+// such as a lost channel. Errors reported by the server, such as a denied
+// permission, are not transient.
+func transient(err error) bool {
+	var se *sftp.StatusError
+	return !errors.As(err, &se) && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, os.ErrPermission)
+}
+
+// writeContent writes the content to the remote file over SFTP, creating its
+// parent directories.
+func writeContent(sftpClient *sftp.Client, remotePath string, content []byte) error {
+	if err := sftpClient.MkdirAll(path.Dir(remotePath)); err != nil {
+		return errors.Wrap(err, "Failed to create remote directory")
+	}
+
+	offset := resumeOffset(sftpClient, remotePath, content)
+	flags := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	f, err := sftpClient.OpenFile(remotePath, flags)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create remote file")
+	}
+	if _, err := f.WriteAt(content[offset:], offset); err != nil {
+		_ = f.Close()
+		return errors.Wrap(err, "Failed to write to remote file")
+	}
+	return errors.Wrap(f.Close(), "Failed to close remote file")
+}
+
+// setAttributes verifies that the remote file has the supplied size, then
+// sets its permissions. The owner is only changed if uid or gid is not
+// negative.
+func setAttributes(sftpClient *sftp.Client, remotePath string, size int64, mode os.FileMode, uid, gid int) error {
+	fi, err := sftpClient.Stat(remotePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to stat remote file")
+	}
+	if fi.Size() != size {
+		return errors.Errorf("Remote file has %d bytes instead of %d", fi.Size(), size)
+	}
+
+	if err := sftpClient.Chmod(remotePath, mode); err != nil {
+		return errors.Wrap(err, "Failed to change mode of remote file")
+	}
+	if uid >= 0 || gid >= 0 {
+		if st, ok := fi.Sys().(*sftp.FileStat); ok {
+			if uid < 0 {
+				uid = int(st.UID)
+			}
+			if gid < 0 {
+				gid = int(st.GID)
+			}
+		}
+		if err := sftpClient.Chown(remotePath, uid, gid); err != nil {
+			return errors.Wrap(err, "Failed to change owner of remote file")
+		}
+	}
+	return nil
+}
+
+// resumeOffset returns the size of the remote file if it holds the beginning
+// of the content, such as after an interrupted write, from which the write
+// resumes. It returns zero if the file must be written from the start,
+// including when it cannot be read.
+func resumeOffset(sftpClient *sftp.Client, remotePath string, content []byte) int64 {
+	fi, err := sftpClient.Stat(remotePath)
+	if err != nil {
+		return 0
+	}
+	size := fi.Size()
+	if size == 0 || size > int64(len(content)) || !fi.Mode().IsRegular() {
+		return 0
+	}
+
+	f, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return 0
+	}
+	defer f.Close() // nolint: errcheck
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, size); err != nil {
+		return 0
+	}
+	if hex.EncodeToString(h.Sum(nil)) != Checksum(content[:size]) {
+		return 0
+	}
+	return size
+}
+
+// ListFiles returns the checksums of the regular files below the remote
+// directory, keyed by their path relative to the directory. It returns nil if
+// the directory does not exist.
+func ListFiles(client *ssh.Client, dir string) (map[string]string, error) {
+	sftpClient, err := newSFTPClient(client)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create sftp client")
+	}
+	defer sftpClient.Close() // nolint: errcheck
+
+	if _, err := sftpClient.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	files := map[string]string{}
+	w := sftpClient.Walk(dir)
+	for w.Step() {
+		if err := w.Err(); err != nil {
+			return nil, errors.Wrap(err, "Failed to walk remote directory")
+		}
+		if !w.Stat().Mode().IsRegular() {
+			continue
+		}
+		f, err := sftpClient.Open(w.Path())
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to open remote file")
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		_ = f.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read remote file")
+		}
+		files[strings.TrimPrefix(strings.TrimPrefix(w.Path(), dir), "/")] = hex.EncodeToString(h.Sum(nil))
+	}
+	return files, nil
+}
+
+// RemoveFiles removes the supplied files, given by their path relative to
+// the remote directory.
+func RemoveFiles(client *ssh.Client, dir string, names []string) error {
+	sftpClient, err := newSFTPClient(client)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create sftp client")
+	}
+	defer sftpClient.Close() // nolint: errcheck
+
+	for _, name := range names {
+		if err := sftpClient.Remove(path.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.Wrapf(err, "Failed to remove %s", name)
+		}
+	}
+	return nil
+}
+
+// RemoveDirectory removes the remote directory and everything below it.
+func RemoveDirectory(client *ssh.Client, dir string) error {
+	sftpClient, err := newSFTPClient(client)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create sftp client")
+	}
+	defer sftpClient.Close() // nolint: errcheck
+
+	if err := sftpClient.RemoveAll(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "Failed to remove remote directory")
+	}
+	return nil
+}
+
+// RemoveFile removes the remote file at the supplied path. Files that do not
+// exist are ignored.
+func RemoveFile(client *ssh.Client, remotePath string) error {
+	sftpClient, err := newSFTPClient(client)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create sftp client")
+	}
+	defer sftpClient.Close() // nolint: errcheck
+
+	if err := sftpClient.Remove(remotePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "Failed to remove remote file")
+	}
+	return nil
+}
+
+// LookupID resolves a user or group name to its numeric id on the remote
+// host. Numeric names are returned as is.
+func LookupID(ctx context.Context, x RemoteExecutor, name string, group bool) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	cmd := "id -u " + shellQuote(name)
+	if group {
+		cmd = "getent group " + shellQuote(name) + " | cut -d: -f3"
+	}
+	stdout, _, err := RunCommand(ctx, x, cmd, nil, false)
+	if err != nil {
+		return -1, errors.Wrapf(err, "Failed to look up id of %s", name)
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(stdout))
+	if err != nil {
+		return -1, errors.Errorf("Unknown user or group %s", name)
+	}
+	return id, nil
+}
+
+// ReadFile returns the content of the remote file at the supplied path. Files
+// larger than maxSize bytes are rejected. The returned error wraps
+// os.ErrNotExist if the file does not exist.
+func ReadFile(client *ssh.Client, remotePath string, maxSize int64) ([]byte, error) {
+	sftpClient, err := newSFTPClient(client)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create sftp client")
+	}
+	defer sftpClient.Close() // nolint: errcheck
+
+	f, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open remote file")
+	}
+	defer f.Close() // nolint: errcheck
+
+	content, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read remote file")
+	}
+	if int64(len(content)) > maxSize {
+		return nil, errors.Errorf("Remote file is larger than %d bytes", maxSize)
+	}
+	return content, nil
+}
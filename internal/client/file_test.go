@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+func TestWriteFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes over an in-process SSH server")
+	}
+
+	size := 1 << 20
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	// overhead is the number of bytes of the SFTP requests beyond the content
+	// that the tests tolerate.
+	overhead := int64(64 << 10)
+
+	cases := map[string]struct {
+		reason      string
+		existing    []byte
+		dropSFTP    int64
+		compression v1alpha1.TransferCompression
+		// gzip replaces the gzip of the host, if set.
+		gzip             string
+		bandwidthLimitKB int64
+		// minDuration is the time the write should take at least.
+		minDuration time.Duration
+		// maxReceived is the number of bytes of content the server should
+		// receive over SFTP at most, showing that the write resumed or was
+		// compressed.
+		maxReceived int64
+	}{
+		"New": {
+			reason:      "A new file should be written in one go.",
+			maxReceived: int64(size),
+		},
+		"Retry": {
+			reason:      "A write whose SFTP channel is lost should be retried, resuming from the bytes already written.",
+			dropSFTP:    256 << 10,
+			maxReceived: int64(size),
+		},
+		"Resume": {
+			reason:      "A file holding the beginning of the content should only be completed.",
+			existing:    content[:512<<10],
+			maxReceived: int64(size - 512<<10),
+		},
+		"Gzip": {
+			reason:      "A file should be written through gzip on the host when compression is enabled, its attributes only being set over SFTP.",
+			compression: v1alpha1.TransferCompressionGzip,
+		},
+		"GzipMissing": {
+			reason:      "A file should be written over SFTP when it cannot be decompressed on the host.",
+			compression: v1alpha1.TransferCompressionGzip,
+			gzip:        "#!/bin/sh\nexit 127\n",
+			maxReceived: int64(size),
+		},
+		"BandwidthLimit": {
+			reason:           "A write should not exceed the bandwidth limit.",
+			bandwidthLimitKB: 4 << 10,
+			minDuration:      200 * time.Millisecond,
+			maxReceived:      int64(size),
+		},
+		"Rewrite": {
+			reason:      "A file holding other content should be written from the start.",
+			existing:    bytes.Repeat([]byte("x"), 2*size),
+			maxReceived: int64(size),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := sshtest.NewServer(t)
+			remotePath := filepath.Join(srv.Dir, "attachments", "blob")
+			if tc.existing != nil {
+				if err := os.MkdirAll(filepath.Dir(remotePath), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(remotePath, tc.existing, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.gzip != "" {
+				if err := os.WriteFile(filepath.Join(srv.Dir, "bin", "gzip"), []byte(tc.gzip), 0o755); err != nil { // nolint: gosec
+					t.Fatal(err)
+				}
+			}
+			transfer := &v1alpha1.TransferSettings{Compression: tc.compression}
+			if tc.bandwidthLimitKB > 0 {
+				transfer.BandwidthLimitKB = &tc.bandwidthLimitKB
+			}
+			creds, err := sshv1alpha1.WithTransfer(srv.Credentials(), transfer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client, err := sshv1alpha1.NewSSHClient(context.Background(), creds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close() // nolint: errcheck
+
+			srv.DropSFTP(tc.dropSFTP)
+			start := time.Now()
+			if err := sshv1alpha1.WriteFile(client, remotePath, content, 0o640, -1, -1); err != nil {
+				t.Fatalf("\n%s\nWriteFile(...): %v", tc.reason, err)
+			}
+			if d := time.Since(start); d < tc.minDuration {
+				t.Errorf("\n%s\nWriteFile(...): took %s, want at least %s", tc.reason, d, tc.minDuration)
+			}
+
+			got, err := os.ReadFile(remotePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(content, got) {
+				t.Errorf("\n%s\nWriteFile(...): wrote %d bytes that differ from the %d bytes of the content", tc.reason, len(got), len(content))
+			}
+			if fi, err := os.Stat(remotePath); err != nil || fi.Mode().Perm() != 0o640 {
+				t.Errorf("\n%s\nWriteFile(...): mode %v, %v, want %v", tc.reason, fi.Mode().Perm(), err, os.FileMode(0o640))
+			}
+			if got := srv.SFTPBytes(); got > tc.maxReceived+overhead {
+				t.Errorf("\n%s\nWriteFile(...): server received %d bytes, want at most %d", tc.reason, got, tc.maxReceived+overhead)
+			}
+		})
+	}
+}
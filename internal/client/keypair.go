@@ -0,0 +1,69 @@
+package ssh
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// Supported key types of GenerateKeyPair.
+const (
+	KeyTypeED25519 = "ed25519"
+	KeyTypeRSA     = "rsa"
+)
+
+// GenerateKeyPair generates a key pair of the supplied type. It returns the
+// private key in OpenSSH PEM format and the public key in authorized_keys
+// format. Bits is only used for RSA keys.
+func GenerateKeyPair(keyType string, bits int, comment string) ([]byte, string, error) {
+	var priv crypto.PrivateKey
+	var err error
+	switch keyType {
+	case KeyTypeED25519:
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	case KeyTypeRSA:
+		priv, err = rsa.GenerateKey(rand.Reader, bits)
+	default:
+		return nil, "", errors.Errorf("Unsupported key type %s", keyType)
+	}
+	if err != nil {
+		return nil, "", errors.Wrap(err, "Failed to generate key")
+	}
+
+	block, err := ssh.MarshalPrivateKey(priv, comment)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "Failed to marshal private key")
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "Failed to create signer")
+	}
+	public := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	if comment != "" {
+		public += " " + comment
+	}
+	return pem.EncodeToMemory(block), public, nil
+}
+
+// Credentials returns creds with the supplied user and private key in place
+// of the original authentication, in the format read by NewSSHClient.
+func Credentials(creds []byte, user string, privateKey []byte) ([]byte, error) {
+	kc := Config{}
+	if err := json.Unmarshal(creds, &kc); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse credentials")
+	}
+	if user != "" {
+		kc.Username = user
+	}
+	kc.Password = ""
+	kc.PrivateKey = base64.StdEncoding.EncodeToString(privateKey)
+	return json.Marshal(kc)
+}
@@ -0,0 +1,90 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyAlgorithms maps the key types accepted by ScanHostKeys to the host
+// key algorithms offered to the remote host.
+var HostKeyAlgorithms = map[string][]string{
+	"ed25519": {ssh.KeyAlgoED25519},
+	"ecdsa":   {ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521},
+	"rsa":     {ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA},
+}
+
+// errKeyScanned aborts the handshake once the host key was received.
+var errKeyScanned = errors.New("host key scanned")
+
+// ScanHostKeys returns the host keys of the supplied types offered by the
+// host at address, like ssh-keyscan does. Types the host has no key for are
+// skipped.
+func ScanHostKeys(ctx context.Context, address string, keyTypes []string) ([]ssh.PublicKey, error) {
+	keys := make([]ssh.PublicKey, 0, len(keyTypes))
+	for _, t := range keyTypes {
+		algos, ok := HostKeyAlgorithms[t]
+		if !ok {
+			return nil, errors.Errorf("Unsupported key type %s", t)
+		}
+		key, err := scanHostKey(ctx, address, algos)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to scan %s host key of %s", t, address)
+		}
+		if key != nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// scanHostKey returns the host key the host offers for one of algos, or nil
+// if the host supports none of them.
+func scanHostKey(ctx context.Context, address string, algos []string) (ssh.PublicKey, error) {
+	d := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close() // nolint: errcheck
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var key ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User:              "keyscan",
+		HostKeyAlgorithms: algos,
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errKeyScanned
+		},
+	}
+	c, _, _, err := ssh.NewClientConn(conn, address, config)
+	if err == nil {
+		_ = c.Close()
+	}
+	if key != nil {
+		return key, nil
+	}
+	// Hosts without a key of the requested algorithms fail the key exchange.
+	if err != nil && isNoCommonAlgorithm(err) {
+		return nil, nil
+	}
+	return nil, err
+}
+
+func isNoCommonAlgorithm(err error) bool {
+	return strings.Contains(err.Error(), "no common algorithm")
+}
+
+// KnownHostsLine returns the line of a known_hosts file for the key of the
+// host at address.
+func KnownHostsLine(address string, key ssh.PublicKey) string {
+	return knownhosts.Line([]string{knownhosts.Normalize(address)}, key)
+}
@@ -0,0 +1,190 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A PackageManager installs and removes packages on a remote host.
+type PackageManager interface {
+	// Installed returns the installed version of the package, or an empty
+	// string if the package is not installed.
+	Installed(ctx context.Context, name string) (string, error)
+
+	// Latest returns the latest version of the package available to the
+	// host.
+	Latest(ctx context.Context, name string) (string, error)
+
+	// Install installs the package. An empty version installs the latest
+	// version, a version ending in '*' the latest version with this prefix.
+	Install(ctx context.Context, name, version string) error
+
+	// Upgrade upgrades the installed package to the latest version.
+	Upgrade(ctx context.Context, name string) error
+
+	// Remove removes the package.
+	Remove(ctx context.Context, name string) error
+}
+
+// A packageCommands implements a PackageManager with shell commands. The
+// commands are formatted with the quoted package name, or the quoted package
+// specification in case of install.
+type packageCommands struct {
+	installed string
+	latest    string
+	install   string
+	upgrade   string
+	remove    string
+	// trimName removes the "<name>-" prefix from the queried versions.
+	trimName bool
+	// spec returns the specification of the package of the given version
+	// that is passed to install.
+	spec func(name, version string) string
+}
+
+// packageManagers are the supported package managers by name, together with
+// the executable that identifies them on a host.
+var packageManagers = map[string]struct {
+	executable string
+	commands   packageCommands
+}{
+	"apt": {executable: "apt-get", commands: packageCommands{
+		installed: `dpkg-query -W -f='${db:Status-Abbrev} ${Version}\n' %s 2>/dev/null | awk '/^ii/ {print $2}'`,
+		latest:    `apt-cache policy %s | awk '/Candidate:/ && $2 != "(none)" {print $2}'`,
+		install:   `DEBIAN_FRONTEND=noninteractive apt-get install -y --allow-downgrades %s`,
+		upgrade:   `DEBIAN_FRONTEND=noninteractive apt-get install -y --only-upgrade %s`,
+		remove:    `DEBIAN_FRONTEND=noninteractive apt-get remove -y %s`,
+		spec:      joinVersion("="),
+	}},
+	"dnf": {executable: "dnf", commands: packageCommands{
+		installed: `rpm -q %[1]s >/dev/null 2>&1 && rpm -q --qf '%%{VERSION}-%%{RELEASE}\n' %[1]s || true`,
+		latest:    `dnf -q repoquery --latest-limit 1 --qf '%%{version}-%%{release}' %s`,
+		install:   `dnf install -y %s`,
+		upgrade:   `dnf upgrade -y %s`,
+		remove:    `dnf remove -y %s`,
+		spec:      joinVersion("-"),
+	}},
+	"zypper": {executable: "zypper", commands: packageCommands{
+		installed: `rpm -q %[1]s >/dev/null 2>&1 && rpm -q --qf '%%{VERSION}-%%{RELEASE}\n' %[1]s || true`,
+		latest:    `zypper -n -q info %s | awk -F': *' '/^Version/ {print $2}'`,
+		install:   `zypper -n install %s`,
+		upgrade:   `zypper -n update %s`,
+		remove:    `zypper -n remove %s`,
+		spec:      joinVersion("="),
+	}},
+	"apk": {executable: "apk", commands: packageCommands{
+		installed: `apk info -e %[1]s >/dev/null && apk list -I %[1]s 2>/dev/null | cut -d' ' -f1 || true`,
+		latest:    `apk list %s 2>/dev/null | cut -d' ' -f1`,
+		install:   `apk add %s`,
+		upgrade:   `apk add -u %s`,
+		remove:    `apk del %s`,
+		trimName:  true,
+		spec: func(name, version string) string {
+			if strings.HasSuffix(version, "*") {
+				return name + "~" + strings.TrimSuffix(version, "*")
+			}
+			return joinVersion("=")(name, version)
+		},
+	}},
+}
+
+// PackageManagers returns the names of the supported package managers in the
+// order they are detected.
+func PackageManagers() []string {
+	return []string{"apt", "dnf", "zypper", "apk"}
+}
+
+// DetectPackageManager returns the name of the first supported package
+// manager installed on the host.
+func DetectPackageManager(ctx context.Context, x RemoteExecutor) (string, error) {
+	cmd := make([]string, 0, len(packageManagers))
+	for _, name := range PackageManagers() {
+		pm := packageManagers[name]
+		cmd = append(cmd, "command -v "+pm.executable+" >/dev/null && echo "+name+" && exit 0")
+	}
+	stdout, _, err := RunCommand(ctx, x, strings.Join(cmd, "; ")+"; exit 1", nil, false)
+	if err != nil {
+		return "", errors.New("No supported package manager found")
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// NewPackageManager returns the named package manager of the host.
+func NewPackageManager(name string, x RemoteExecutor, suEnabled bool) (PackageManager, error) {
+	pm, ok := packageManagers[name]
+	if !ok {
+		return nil, errors.Errorf("Unsupported package manager %s", name)
+	}
+	return &commandPackageManager{commands: pm.commands, executor: x, suEnabled: suEnabled}, nil
+}
+
+type commandPackageManager struct {
+	commands  packageCommands
+	executor  RemoteExecutor
+	suEnabled bool
+}
+
+func (m *commandPackageManager) Installed(ctx context.Context, name string) (string, error) {
+	return m.query(ctx, m.commands.installed, name)
+}
+
+func (m *commandPackageManager) Latest(ctx context.Context, name string) (string, error) {
+	return m.query(ctx, m.commands.latest, name)
+}
+
+func (m *commandPackageManager) Install(ctx context.Context, name, version string) error {
+	return m.run(ctx, m.commands.install, m.commands.spec(name, version))
+}
+
+func (m *commandPackageManager) Upgrade(ctx context.Context, name string) error {
+	return m.run(ctx, m.commands.upgrade, name)
+}
+
+func (m *commandPackageManager) Remove(ctx context.Context, name string) error {
+	return m.run(ctx, m.commands.remove, name)
+}
+
+func (m *commandPackageManager) query(ctx context.Context, format, name string) (string, error) {
+	stdout, stderr, err := RunCommand(ctx, m.executor, sprintfQuoted(format, name), nil, m.suEnabled)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to query package %s: %s", name, lastLine(stderr))
+	}
+	// Only the first version is used if the package is available for
+	// several architectures.
+	version := strings.TrimSpace(strings.SplitN(strings.TrimSpace(stdout), "\n", 2)[0])
+	if m.commands.trimName {
+		version = strings.TrimPrefix(version, name+"-")
+	}
+	return version, nil
+}
+
+func (m *commandPackageManager) run(ctx context.Context, format, arg string) error {
+	if _, stderr, err := RunCommand(ctx, m.executor, sprintfQuoted(format, arg), nil, m.suEnabled); err != nil {
+		return errors.Wrapf(err, "Failed to manage package %s: %s", arg, lastLine(stderr))
+	}
+	return nil
+}
+
+// sprintfQuoted formats the command with the shell quoted argument.
+func sprintfQuoted(format, arg string) string {
+	return fmt.Sprintf(format, shellQuote(arg))
+}
+
+// joinVersion returns a spec function joining name and version with sep.
+func joinVersion(sep string) func(name, version string) string {
+	return func(name, version string) string {
+		if version == "" {
+			return name
+		}
+		return name + sep + version
+	}
+}
+
+// lastLine returns the last line of the output of a command.
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,405 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+func TestPackageManagerCommands(t *testing.T) {
+	install := func(name, version string) func(ctx context.Context, pm sshv1alpha1.PackageManager) error {
+		return func(ctx context.Context, pm sshv1alpha1.PackageManager) error { return pm.Install(ctx, name, version) }
+	}
+	upgrade := func(ctx context.Context, pm sshv1alpha1.PackageManager) error { return pm.Upgrade(ctx, "nginx") }
+	remove := func(ctx context.Context, pm sshv1alpha1.PackageManager) error { return pm.Remove(ctx, "nginx") }
+	installed := func(ctx context.Context, pm sshv1alpha1.PackageManager) error {
+		_, err := pm.Installed(ctx, "nginx")
+		return err
+	}
+	latest := func(ctx context.Context, pm sshv1alpha1.PackageManager) error {
+		_, err := pm.Latest(ctx, "nginx")
+		return err
+	}
+
+	cases := map[string]struct {
+		reason  string
+		manager string
+		sudo    bool
+		op      func(ctx context.Context, pm sshv1alpha1.PackageManager) error
+		want    string
+	}{
+		"AptInstalled": {
+			reason:  "The installed version of a Debian package should be queried with dpkg-query.",
+			manager: "apt",
+			op:      installed,
+			want:    `dpkg-query -W -f='${db:Status-Abbrev} ${Version}\n' 'nginx' 2>/dev/null | awk '/^ii/ {print $2}'`,
+		},
+		"AptLatest": {
+			reason:  "The candidate version of a Debian package should be queried with apt-cache.",
+			manager: "apt",
+			op:      latest,
+			want:    `apt-cache policy 'nginx' | awk '/Candidate:/ && $2 != "(none)" {print $2}'`,
+		},
+		"AptInstall": {
+			reason:  "A version of a Debian package should be installed as name=version, downgrades allowed.",
+			manager: "apt",
+			op:      install("nginx", "1.24.0-2ubuntu7"),
+			want:    `DEBIAN_FRONTEND=noninteractive apt-get install -y --allow-downgrades 'nginx=1.24.0-2ubuntu7'`,
+		},
+		"AptInstallLatest": {
+			reason:  "A Debian package without version should be installed by name.",
+			manager: "apt",
+			op:      install("nginx", ""),
+			want:    `DEBIAN_FRONTEND=noninteractive apt-get install -y --allow-downgrades 'nginx'`,
+		},
+		"AptUpgrade": {
+			reason:  "A Debian package should only be upgraded if it is installed.",
+			manager: "apt",
+			op:      upgrade,
+			want:    `DEBIAN_FRONTEND=noninteractive apt-get install -y --only-upgrade 'nginx'`,
+		},
+		"AptRemove": {
+			reason:  "A Debian package should be removed with apt-get.",
+			manager: "apt",
+			op:      remove,
+			want:    `DEBIAN_FRONTEND=noninteractive apt-get remove -y 'nginx'`,
+		},
+		"AptSudo": {
+			reason:  "The commands should be run through sudo sh -c when sudo is enabled.",
+			manager: "apt",
+			sudo:    true,
+			op:      remove,
+			want:    `sudo sh -c 'DEBIAN_FRONTEND=noninteractive apt-get remove -y '\''nginx'\'''`,
+		},
+		"DnfInstalled": {
+			reason:  "The installed version of an RPM package should be queried with rpm, empty if it is not installed.",
+			manager: "dnf",
+			op:      installed,
+			want:    `rpm -q 'nginx' >/dev/null 2>&1 && rpm -q --qf '%{VERSION}-%{RELEASE}\n' 'nginx' || true`,
+		},
+		"DnfLatest": {
+			reason:  "The latest version of an RPM package should be queried with dnf repoquery.",
+			manager: "dnf",
+			op:      latest,
+			want:    `dnf -q repoquery --latest-limit 1 --qf '%{version}-%{release}' 'nginx'`,
+		},
+		"DnfInstall": {
+			reason:  "A version of an RPM package should be installed as name-version.",
+			manager: "dnf",
+			op:      install("nginx", "1.20.1-14.el9"),
+			want:    `dnf install -y 'nginx-1.20.1-14.el9'`,
+		},
+		"DnfUpgrade": {
+			reason:  "An RPM package should be upgraded with dnf.",
+			manager: "dnf",
+			op:      upgrade,
+			want:    `dnf upgrade -y 'nginx'`,
+		},
+		"ZypperLatest": {
+			reason:  "The latest version of a SUSE package should be read from zypper info.",
+			manager: "zypper",
+			op:      latest,
+			want:    `zypper -n -q info 'nginx' | awk -F': *' '/^Version/ {print $2}'`,
+		},
+		"ZypperInstall": {
+			reason:  "A version of a SUSE package should be installed as name=version.",
+			manager: "zypper",
+			op:      install("nginx", "1.21.5-150400.3.3.1"),
+			want:    `zypper -n install 'nginx=1.21.5-150400.3.3.1'`,
+		},
+		"ZypperRemove": {
+			reason:  "A SUSE package should be removed with zypper.",
+			manager: "zypper",
+			op:      remove,
+			want:    `zypper -n remove 'nginx'`,
+		},
+		"ApkInstalled": {
+			reason:  "The installed version of an Alpine package should be queried with apk info and apk list.",
+			manager: "apk",
+			op:      installed,
+			want:    `apk info -e 'nginx' >/dev/null && apk list -I 'nginx' 2>/dev/null | cut -d' ' -f1 || true`,
+		},
+		"ApkInstall": {
+			reason:  "A version of an Alpine package should be installed as name=version.",
+			manager: "apk",
+			op:      install("nginx", "1.24.0-r15"),
+			want:    `apk add 'nginx=1.24.0-r15'`,
+		},
+		"ApkInstallPrefix": {
+			reason:  "A version prefix of an Alpine package should be installed as name~prefix.",
+			manager: "apk",
+			op:      install("nginx", "1.24*"),
+			want:    `apk add 'nginx~1.24'`,
+		},
+		"ApkUpgrade": {
+			reason:  "An Alpine package should be upgraded with apk add -u.",
+			manager: "apk",
+			op:      upgrade,
+			want:    `apk add -u 'nginx'`,
+		},
+		"Quoted": {
+			reason:  "Package names should be passed to the commands as a single quoted argument.",
+			manager: "apk",
+			op:      install("nginx; reboot", ""),
+			want:    `apk add 'nginx; reboot'`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := &sshfake.Executor{}
+			pm, err := sshv1alpha1.NewPackageManager(tc.manager, x, tc.sudo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.op(context.Background(), pm); err != nil {
+				t.Fatalf("\n%s\n%s: %v", tc.reason, name, err)
+			}
+			if diff := cmp.Diff([]string{tc.want}, x.Scripts()); diff != "" {
+				t.Errorf("\n%s\n%s: -want command, +got command:\n%s\n", tc.reason, name, diff)
+			}
+		})
+	}
+}
+
+func TestPackageManagerVersions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("connects to an in-process SSH server")
+	}
+
+	// The tools print the output of the real ones for nginx, so that the
+	// versions are parsed by the pipelines of the commands.
+	const (
+		dpkgQuery        = "#!/bin/sh\nprintf 'ii  1.24.0-2ubuntu7\\n'\n"
+		dpkgQueryRemoved = "#!/bin/sh\nprintf 'rc  1.24.0-2ubuntu7\\n'\n"
+		aptCache         = "#!/bin/sh\nprintf 'nginx:\\n  Installed: 1.24.0-2ubuntu7\\n  Candidate: 1.24.0-2ubuntu7.1\\n  Version table:\\n'\n"
+		aptCacheNone     = "#!/bin/sh\nprintf 'nginx:\\n  Installed: (none)\\n  Candidate: (none)\\n  Version table:\\n'\n"
+		rpm              = "#!/bin/sh\n[ \"$2\" = --qf ] || { echo nginx-1.20.1-14.el9_2.1.x86_64; exit 0; }\nprintf '1.20.1-14.el9_2.1\\n'\n"
+		rpmMultiArch     = "#!/bin/sh\n[ \"$2\" = --qf ] || exit 0\nprintf '2.38-150500.4.6.1\\n2.38-150500.4.6.1\\n'\n"
+		rpmNotInstalled  = "#!/bin/sh\necho 'package nginx is not installed'\nexit 1\n"
+		zypper           = "#!/bin/sh\nprintf 'Information for package nginx:\\n-----------------------------\\nRepository     : Main Repository\\nName           : nginx\\nVersion        : 1.21.5-150400.3.3.1\\nArch           : x86_64\\n'\n"
+		apk              = "#!/bin/sh\ncase \"$1 $2\" in\n\"info -e\") echo nginx ;;\n\"list -I\") echo 'nginx-1.24.0-r15 x86_64 {nginx} (BSD-2-Clause) [installed]' ;;\n*) echo 'nginx-1.26.1-r0 x86_64 {nginx} (BSD-2-Clause) [upgradable from: nginx-1.24.0-r15]' ;;\nesac\n"
+		apkNotInstalled  = "#!/bin/sh\nexit 1\n"
+	)
+
+	cases := map[string]struct {
+		reason  string
+		manager string
+		tools   map[string]string
+		latest  bool
+		want    string
+	}{
+		"Dpkg": {
+			reason:  "The version dpkg-query prints for an installed package should be returned.",
+			manager: "apt",
+			tools:   map[string]string{"dpkg-query": dpkgQuery},
+			want:    "1.24.0-2ubuntu7",
+		},
+		"DpkgRemoved": {
+			reason:  "A package whose configuration files are left should have no installed version.",
+			manager: "apt",
+			tools:   map[string]string{"dpkg-query": dpkgQueryRemoved},
+		},
+		"AptCandidate": {
+			reason:  "The candidate version apt-cache prints should be returned.",
+			manager: "apt",
+			tools:   map[string]string{"apt-cache": aptCache},
+			latest:  true,
+			want:    "1.24.0-2ubuntu7.1",
+		},
+		"AptNoCandidate": {
+			reason:  "A package without candidate should have no latest version.",
+			manager: "apt",
+			tools:   map[string]string{"apt-cache": aptCacheNone},
+			latest:  true,
+		},
+		"Rpm": {
+			reason:  "The version and release rpm -q prints for an installed package should be returned.",
+			manager: "dnf",
+			tools:   map[string]string{"rpm": rpm},
+			want:    "1.20.1-14.el9_2.1",
+		},
+		"RpmMultiArch": {
+			reason:  "Only the first version should be returned for a package installed for several architectures.",
+			manager: "zypper",
+			tools:   map[string]string{"rpm": rpmMultiArch},
+			want:    "2.38-150500.4.6.1",
+		},
+		"RpmNotInstalled": {
+			reason:  "A package rpm -q does not know should have no installed version.",
+			manager: "dnf",
+			tools:   map[string]string{"rpm": rpmNotInstalled},
+		},
+		"Zypper": {
+			reason:  "The version zypper info prints should be returned.",
+			manager: "zypper",
+			tools:   map[string]string{"zypper": zypper},
+			latest:  true,
+			want:    "1.21.5-150400.3.3.1",
+		},
+		"Apk": {
+			reason:  "The version of the installed package apk list prints should be returned without its name.",
+			manager: "apk",
+			tools:   map[string]string{"apk": apk},
+			want:    "1.24.0-r15",
+		},
+		"ApkLatest": {
+			reason:  "The version of the available package apk list prints should be returned without its name.",
+			manager: "apk",
+			tools:   map[string]string{"apk": apk},
+			latest:  true,
+			want:    "1.26.1-r0",
+		},
+		"ApkNotInstalled": {
+			reason:  "A package apk info does not report installed should have no installed version.",
+			manager: "apk",
+			tools:   map[string]string{"apk": apkNotInstalled},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := sshtest.NewServer(t)
+			for tool, script := range tc.tools {
+				if err := os.WriteFile(filepath.Join(srv.Dir, "bin", tool), []byte(script), 0o755); err != nil { // nolint: gosec
+					t.Fatal(err)
+				}
+			}
+			c, err := sshv1alpha1.NewSSHClient(context.Background(), srv.Credentials())
+			if err != nil {
+				t.Fatal(err)
+			}
+			x := sshv1alpha1.NewSSHExecutor(c)
+			defer x.Close() // nolint: errcheck
+
+			pm, err := sshv1alpha1.NewPackageManager(tc.manager, x, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := pm.Installed
+			if tc.latest {
+				query = pm.Latest
+			}
+			got, err := query(context.Background(), "nginx")
+			if err != nil {
+				t.Fatalf("\n%s\n%s: %v", tc.reason, name, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\n%s: -want, +got:\n%s\n", tc.reason, name, diff)
+			}
+		})
+	}
+}
+
+func TestPackageManagerErrors(t *testing.T) {
+	x := &sshfake.Executor{MockRun: func(string) (string, string, error) {
+		return "", "Reading package lists...\nE: Unable to locate package nginx-full\n", sshfake.ExitError(100)
+	}}
+	pm, err := sshv1alpha1.NewPackageManager("apt", x, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Failed to manage package nginx-full: E: Unable to locate package nginx-full: Process exited with status 100"
+	if err := pm.Install(context.Background(), "nginx-full", ""); err == nil || err.Error() != want {
+		t.Errorf("\nThe last line of the stderr of a failed command should be returned.\nInstall(...): got %v, want %q", err, want)
+	}
+
+	if _, err := sshv1alpha1.NewPackageManager("pacman", x, false); err == nil {
+		t.Errorf("\nUnsupported package managers should be rejected.\nNewPackageManager(...): got no error")
+	}
+}
+
+func TestDetectPackageManager(t *testing.T) {
+	// host returns an Executor whose host has the supplied executables
+	// installed, evaluating the command -v checks of the detection command.
+	host := func(executables ...string) *sshfake.Executor {
+		return &sshfake.Executor{MockRun: func(cmd string) (string, string, error) {
+			for _, check := range strings.Split(cmd, "; ") {
+				f := strings.Fields(check)
+				if len(f) < 7 || f[0] != "command" {
+					continue
+				}
+				for _, e := range executables {
+					if f[2] == e {
+						return f[6] + "\n", "", nil
+					}
+				}
+			}
+			return "", "", sshfake.ExitError(1)
+		}}
+	}
+
+	cases := map[string]struct {
+		reason string
+		x      *sshfake.Executor
+		want   string
+		err    bool
+	}{
+		"Apt": {
+			reason: "Hosts with apt-get should be managed with apt.",
+			x:      host("apt-get"),
+			want:   "apt",
+		},
+		"Dnf": {
+			reason: "Hosts with dnf should be managed with dnf.",
+			x:      host("rpm", "dnf"),
+			want:   "dnf",
+		},
+		"Zypper": {
+			reason: "Hosts with zypper should be managed with zypper.",
+			x:      host("rpm", "zypper"),
+			want:   "zypper",
+		},
+		"Apk": {
+			reason: "Hosts with apk should be managed with apk.",
+			x:      host("apk"),
+			want:   "apk",
+		},
+		"Order": {
+			reason: "The first package manager of PackageManagers installed on the host should be detected.",
+			x:      host("apk", "dnf", "apt-get"),
+			want:   "apt",
+		},
+		"None": {
+			reason: "Hosts without supported package manager should be rejected.",
+			x:      host("pacman"),
+			err:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := sshv1alpha1.DetectPackageManager(context.Background(), tc.x)
+			if gotErr := err != nil; gotErr != tc.err {
+				t.Fatalf("\n%s\nDetectPackageManager(...): error %v, want error %t", tc.reason, err, tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nDetectPackageManager(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
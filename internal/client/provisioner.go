@@ -0,0 +1,114 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultProvisionTimeout bounds the provisioning of a key when the clients
+// have no timeout.
+const defaultProvisionTimeout = 30 * time.Second
+
+// KeyProvisionerConfig pushes a short-lived public key to the host before
+// connecting, instead of authenticating with the static credentials.
+type KeyProvisionerConfig struct {
+	// WebhookURL is the URL the provisioning requests are POSTed to.
+	WebhookURL string `json:"webhookURL"`
+	// Token is sent as a bearer token to the webhook, no token is sent if
+	// it is empty.
+	Token string `json:"token,omitempty"`
+}
+
+// A KeyProvisionRequest asks a KeyProvisioner to let the public key log in to
+// the host as the user, for long enough to connect.
+type KeyProvisionRequest struct {
+	Host     string `json:"host"`
+	Port     Port   `json:"port"`
+	Username string `json:"username"`
+	Alias    string `json:"alias,omitempty"`
+	// PublicKey is the public key in authorized_keys format, such as
+	// ssh-ed25519 AAAA....
+	PublicKey string `json:"publicKey"`
+}
+
+// A KeyProvisioner pushes the public key of a client to its host out-of-band,
+// such as with EC2 Instance Connect, before the client connects.
+type KeyProvisioner interface {
+	Provision(ctx context.Context, r KeyProvisionRequest) error
+}
+
+// A WebhookKeyProvisioner POSTs the provisioning requests as JSON to a
+// user-supplied webhook, which is expected to answer with a 2xx status once
+// the key can log in.
+type WebhookKeyProvisioner struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// Provision POSTs the supplied request to the webhook.
+func (p *WebhookKeyProvisioner) Provision(ctx context.Context, r KeyProvisionRequest) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "cannot create the request to the key provisioning webhook")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "cannot call the key provisioning webhook")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("the key provisioning webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// provisionKey generates an ephemeral key and has it provisioned to the host
+// of the credentials, returning the signer to authenticate with.
+func provisionKey(ctx context.Context, p KeyProvisioner, kc Config) (ssh.Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot generate the ephemeral key")
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot generate the ephemeral key")
+	}
+	err = p.Provision(ctx, KeyProvisionRequest{
+		Host:      kc.RemoteHostIP,
+		Port:      kc.RemoteHostPort,
+		Username:  kc.Username,
+		Alias:     kc.Alias,
+		PublicKey: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot provision the ephemeral key")
+	}
+	return signer, nil
+}
+
+// keyProvisioner returns the KeyProvisioner of the supplied settings, whose
+// calls time out after the supplied duration.
+func keyProvisioner(c *KeyProvisionerConfig, timeout time.Duration) KeyProvisioner {
+	if timeout == 0 {
+		timeout = defaultProvisionTimeout
+	}
+	return &WebhookKeyProvisioner{URL: c.WebhookURL, Token: c.Token, Client: &http.Client{Timeout: timeout}}
+}
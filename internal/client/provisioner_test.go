@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/crypto/ssh"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+func TestKeyProvisioner(t *testing.T) {
+	if testing.Short() {
+		t.Skip("provisions keys to an in-process SSH server")
+	}
+
+	node := sshtest.NewServer(t)
+	host, port, _ := net.SplitHostPort(node.Addr)
+	hostPort, _ := sshv1alpha1.ParsePort(port)
+
+	var mu sync.Mutex
+	var requests []sshv1alpha1.KeyProvisionRequest
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		req := sshv1alpha1.KeyProvisionRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req.PublicKey))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		node.AuthorizeKey(key)
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(webhook.Close)
+
+	// creds are the credentials of the server without its password, so that
+	// only the provisioned key may log in.
+	creds := func(token string) []byte {
+		kc := sshv1alpha1.Config{}
+		_ = json.Unmarshal(node.Credentials(), &kc)
+		kc.Password = ""
+		kc.Alias = "node"
+		data, _ := json.Marshal(kc)
+		data, _ = sshv1alpha1.WithKeyProvisioner(data, &sshv1alpha1.KeyProvisionerConfig{WebhookURL: webhook.URL, Token: token})
+		return data
+	}
+
+	cases := map[string]struct {
+		reason string
+		creds  []byte
+		err    bool
+	}{
+		"Provisioned": {
+			reason: "The client should log in with the key pushed by the webhook.",
+			creds:  creds("s3cr3t"),
+		},
+		"Rejected": {
+			reason: "The connection should fail if the webhook does not provision the key.",
+			creds:  creds("wrong"),
+			err:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, err := sshv1alpha1.NewSSHClient(context.Background(), tc.creds)
+			if gotErr := err != nil; gotErr != tc.err {
+				t.Fatalf("\n%s\nNewSSHClient(...): %v, want error %t", tc.reason, err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			defer c.Close() // nolint: errcheck
+			s, err := c.NewSession()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := s.Output("echo ok")
+			if err != nil {
+				t.Fatalf("\n%s\nsession.Output(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff("ok\n", string(out)); diff != "" {
+				t.Errorf("\n%s\nsession.Output(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+
+	want := []sshv1alpha1.KeyProvisionRequest{{Host: host, Port: hostPort, Username: sshtest.Username, Alias: "node"}}
+	mu.Lock()
+	defer mu.Unlock()
+	if diff := cmp.Diff(want, requests, cmpopts.IgnoreFields(sshv1alpha1.KeyProvisionRequest{}, "PublicKey")); diff != "" {
+		t.Errorf("\nThe webhook should be asked once to provision a key for the host and user.\nrequests: -want, +got:\n%s\n", diff)
+	}
+}
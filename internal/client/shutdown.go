@@ -0,0 +1,145 @@
+package ssh
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	errShuttingDown = "The provider is shutting down"
+	errKilled       = "Script killed, the provider is shutting down"
+)
+
+// A drain tracks the scripts being executed, so that they can be killed and
+// waited for when the provider shuts down.
+type drain struct {
+	mu      sync.Mutex
+	stopped bool
+	running int
+
+	// stopping is closed once the provider shuts down, idle once no script
+	// is executed anymore after that.
+	stopping chan struct{}
+	idle     chan struct{}
+}
+
+func newDrain() *drain {
+	return &drain{stopping: make(chan struct{}), idle: make(chan struct{})}
+}
+
+// executing are the scripts executed by ExecuteScript and ExecuteBatch.
+var executing = newDrain()
+
+// start records a script being executed. It returns false, and records
+// nothing, once the provider shuts down.
+func (d *drain) start() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return false
+	}
+	d.running++
+	return true
+}
+
+// done records a script started by start being done, including the removal
+// of its temporary file.
+func (d *drain) done() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.running--
+	if d.stopped && d.running == 0 {
+		close(d.idle)
+	}
+}
+
+// stop kills the scripts being executed and rejects the following ones. The
+// returned channel is closed once the scripts being executed are done.
+func (d *drain) stop() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.stopped {
+		d.stopped = true
+		close(d.stopping)
+		if d.running == 0 {
+			close(d.idle)
+		}
+	}
+	return d.idle
+}
+
+type killOnShutdownKey struct{}
+
+// killOnShutdown returns a context whose commands are killed once the
+// provider shuts down.
+func killOnShutdown(ctx context.Context) context.Context {
+	return context.WithValue(ctx, killOnShutdownKey{}, true)
+}
+
+// shutdownOf returns the channel closed once the provider shuts down if the
+// commands of the supplied context are killed then, nil otherwise.
+func shutdownOf(ctx context.Context) <-chan struct{} {
+	if ctx.Value(killOnShutdownKey{}) == nil {
+		return nil
+	}
+	return executing.stopping
+}
+
+// Shutdown kills the scripts being executed by ExecuteScript and ExecuteBatch
+// and waits for them to remove their temporary files, until the supplied
+// context is done, then closes the connections of the SSH clients created by
+// NewSSHClient that are still open, such as those of the reconciles in
+// flight. Scripts executed after Shutdown fail without being executed.
+func Shutdown(ctx context.Context) {
+	logger := log.FromContext(ctx).WithName("[Shutdown]")
+	select {
+	case <-executing.stop():
+	case <-ctx.Done():
+		logger.Info("Timed out waiting for the scripts being executed to clean up")
+	}
+
+	connections.Lock()
+	open := make([]*conn, 0, len(connections.open))
+	for c := range connections.open {
+		open = append(open, c)
+	}
+	connections.Unlock()
+	for _, c := range open {
+		_ = c.Close()
+	}
+	if len(open) > 0 {
+		logger.Info("Closed the open connections", "connections", len(open))
+	}
+}
+
+// A Drainer kills the scripts being executed and closes the connections
+// still open when the manager it is added to stops, on every replica of the
+// provider regardless of leader election.
+type Drainer struct {
+	// Timeout is how long the scripts being executed may take to clean up
+	// once killed.
+	Timeout time.Duration
+}
+
+// NeedLeaderElection reports that the drainer does not need to be the
+// leader.
+func (d *Drainer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start calls Shutdown once the supplied context is done.
+func (d *Drainer) Start(ctx context.Context) error {
+	<-ctx.Done()
+	d.Drain()
+	return nil
+}
+
+// Drain calls Shutdown, bounded by the timeout of the drainer.
+func (d *Drainer) Drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout)
+	defer cancel()
+	Shutdown(ctx)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+func TestShutdown(t *testing.T) {
+	if testing.Short() {
+		t.Skip("executes scripts on an in-process SSH server")
+	}
+	defer sshv1alpha1.ResetShutdown()
+
+	srv := sshtest.NewServer(t)
+	c, err := sshv1alpha1.NewSSHClient(context.Background(), srv.Credentials())
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := sshv1alpha1.NewSSHExecutor(c)
+	tmp := filepath.Join(srv.Dir, "tmp")
+	if err := os.Mkdir(tmp, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	started := filepath.Join(srv.Dir, "started")
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := sshv1alpha1.ExecuteScript(context.Background(), x, "touch "+started+"; sleep 30", nil, false, sshv1alpha1.WithTempDir(tmp))
+		result <- err
+	}()
+	for {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	begin := time.Now()
+	sshv1alpha1.Shutdown(ctx)
+	if d := time.Since(begin); d > 5*time.Second {
+		t.Errorf("\nThe scripts being executed should be killed on shutdown.\nShutdown(...): took %s", d)
+	}
+
+	if err := <-result; err == nil {
+		t.Errorf("\nA script killed on shutdown should fail.\nExecuteScript(...): want error, got nil")
+	}
+	if files, _ := os.ReadDir(tmp); len(files) != 0 {
+		t.Errorf("\nA script killed on shutdown should remove its temporary file.\nExecuteScript(...): %d files left", len(files))
+	}
+	if err := c.Wait(); err == nil {
+		t.Errorf("\nThe connections should be closed on shutdown.\nc.Wait(): want error, got nil")
+	}
+	if n := len(sshv1alpha1.Connections()); n != 0 {
+		t.Errorf("\nThe connections should be closed on shutdown.\nConnections(): %d open", n)
+	}
+	if _, _, err := sshv1alpha1.ExecuteScript(context.Background(), x, "true", nil, false); err == nil {
+		t.Errorf("\nScripts should not be executed after shutdown.\nExecuteScript(...): want error, got nil")
+	}
+}
+
+func TestShutdownOpenConnections(t *testing.T) {
+	if testing.Short() {
+		t.Skip("connects to an in-process SSH server")
+	}
+	defer sshv1alpha1.ResetShutdown()
+
+	srv := sshtest.NewServer(t)
+	disconnected, err := sshv1alpha1.NewSSHClient(context.Background(), srv.Credentials())
+	if err != nil {
+		t.Fatal(err)
+	}
+	open, err := sshv1alpha1.NewSSHClient(context.Background(), srv.Credentials())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The reconcile of the first client is done and disconnected it.
+	_ = sshv1alpha1.NewSSHExecutor(disconnected).Close()
+	if n := len(sshv1alpha1.Connections()); n != 1 {
+		t.Errorf("\nA closed connection should not be open anymore.\nConnections(): %d open, want 1", n)
+	}
+
+	var lines []string
+	ctx := log.IntoContext(context.Background(), funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{}))
+	sshv1alpha1.Shutdown(ctx)
+
+	if err := open.Wait(); err == nil {
+		t.Errorf("\nThe connections still open should be closed on shutdown.\nopen.Wait(): want error, got nil")
+	}
+	if n := len(sshv1alpha1.Connections()); n != 0 {
+		t.Errorf("\nThe connections still open should be closed on shutdown.\nConnections(): %d open", n)
+	}
+	if got := strings.Join(lines, "\n"); !strings.Contains(got, `"connections"=1`) {
+		t.Errorf("\nShutdown should only close the connections still open.\nShutdown(...): logged %q, want 1 connection closed", got)
+	}
+}
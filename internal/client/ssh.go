@@ -4,19 +4,26 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"path"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/recording"
+	"github.com/crossplane/provider-ssh/internal/redact"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
-	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/knownhosts"
 
@@ -26,15 +33,52 @@ import (
 // Config is a SSH client configuration
 type Config struct {
 	RemoteHostIP   string `json:"hostIP"`
-	RemoteHostPort string `json:"hostPort"`
+	RemoteHostPort Port   `json:"hostPort"`
 	Username       string `json:"username"`
 	Password       string `json:"password,omitempty"`
 	PrivateKey     string `json:"privateKey,omitempty"`
 	KnownHosts     string `json:"knownHosts,omitempty"`
+	// Compression of the uploads, such as Gzip. Uploads are not compressed
+	// if it is empty.
+	Compression string `json:"compression,omitempty"`
+	// BandwidthLimitKB bounds the file transfers of the connections of the
+	// ProviderConfig, in KiB per second in each direction. Transfers are not
+	// limited if it is zero.
+	BandwidthLimitKB int64 `json:"bandwidthLimitKB,omitempty"`
+	// Alias is the human-friendly name of the host of the ProviderConfig,
+	// used in log lines instead of its address. It is not set if empty.
+	Alias string `json:"alias,omitempty"`
+	// Teleport connects to the host through a Teleport proxy, authenticating
+	// with the identity of a Teleport user. The host is connected to
+	// directly if it is nil.
+	Teleport *TeleportConfig `json:"teleport,omitempty"`
+	// WebSocket connects to the host through a WebSocket gateway. The host
+	// is connected to directly if it is nil.
+	WebSocket *WebSocketConfig `json:"webSocket,omitempty"`
+	// KeyProvisioner pushes an ephemeral public key to the host before
+	// connecting, which then authenticates with it instead of the private
+	// key or password. The static credentials are used if it is nil.
+	KeyProvisioner *KeyProvisionerConfig `json:"keyProvisioner,omitempty"`
 }
 
 // NewSSHClient creates a new SSHClient with supplied credentials
-func NewSSHClient(ctx context.Context, data []byte) (*ssh.Client, error) { // nolint: gocyclo
+func NewSSHClient(ctx context.Context, data []byte) (*ssh.Client, error) {
+	return newSSHClient(ctx, data, 0, "")
+}
+
+// NewSSHClientFn returns a function creating SSH clients like NewSSHClient,
+// whose TCP connection and SSH handshake time out after the supplied
+// duration. Zero means no timeout. The host keys of the credentials without
+// knownHosts are verified against the known_hosts file at the supplied path,
+// read on every connection so that it can be rotated. They are not verified
+// if the path is empty.
+func NewSSHClientFn(timeout time.Duration, knownHostsFile string) func(ctx context.Context, data []byte) (*ssh.Client, error) {
+	return func(ctx context.Context, data []byte) (*ssh.Client, error) {
+		return newSSHClient(ctx, data, timeout, knownHostsFile)
+	}
+}
+
+func newSSHClient(ctx context.Context, data []byte, timeout time.Duration, knownHostsFile string) (*ssh.Client, error) { // nolint: gocyclo
 	logger := log.FromContext(ctx).WithName("[SSHClient]")
 	kc := Config{}
 	var err error
@@ -43,8 +87,17 @@ func NewSSHClient(ctx context.Context, data []byte) (*ssh.Client, error) { // no
 		return nil, errors.Wrap(err, "Cannot parse credentials")
 	}
 
+	if kc.KnownHosts == "" && knownHostsFile != "" {
+		b, err := os.ReadFile(knownHostsFile) // nolint: gosec
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot read the default known hosts")
+		}
+		kc.KnownHosts = string(b)
+	}
+
 	config := &ssh.ClientConfig{}
 	config.User = kc.Username
+	config.Timeout = timeout
 
 	if kc.Username == "" {
 		return nil, errors.New("Username key not found in the data")
@@ -52,17 +105,40 @@ func NewSSHClient(ctx context.Context, data []byte) (*ssh.Client, error) { // no
 
 	if kc.RemoteHostIP == "" {
 		return nil, errors.New("Remote host key not found in the data")
-	} else if ok := isValidIPv4(kc.RemoteHostIP); !ok {
-		return nil, errors.New("Remote host address is not a valid: " + kc.RemoteHostIP)
+	} else if err := ValidateHost(kc.RemoteHostIP); err != nil {
+		return nil, errors.Wrap(err, "Remote host address is not valid")
 	}
 
-	if kc.RemoteHostPort == "" {
+	if kc.RemoteHostPort == 0 {
 		logger.Info("Remote host port key not found in the data, using default port 22")
-		kc.RemoteHostPort = "22"
+		kc.RemoteHostPort = DefaultPort
+	} else if err := kc.RemoteHostPort.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Remote host port is not valid")
+	}
+
+	var id *teleportIdentity
+	if kc.Teleport != nil {
+		redact.Add(kc.Teleport.Identity)
+		if id, err = parseTeleportIdentity(kc.Teleport.Identity); err != nil {
+			return nil, errors.Wrap(err, "Cannot parse the Teleport identity")
+		}
+	}
+
+	if kc.WebSocket != nil && kc.WebSocket.Token != "" {
+		redact.Add(kc.WebSocket.Token)
+	}
+
+	if kc.KeyProvisioner != nil && kc.KeyProvisioner.Token != "" {
+		redact.Add(kc.KeyProvisioner.Token)
 	}
 
 	var knownHostsCallback ssh.HostKeyCallback
-	if kc.KnownHosts != "" {
+	if id != nil {
+		// The host certificates of the proxy and the nodes are signed by
+		// the host certificate authorities of the identity.
+		knownHostsCallback = id.hostKeyCallback
+	} else if kc.KnownHosts != "" || knownHostsFile != "" {
+		// An empty default known_hosts file accepts no host key.
 		tempFile, err := os.CreateTemp("", "tempfile")
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to create temp file to parse known hosts")
@@ -87,11 +163,28 @@ func NewSSHClient(ctx context.Context, data []byte) (*ssh.Client, error) { // no
 	config.HostKeyCallback = knownHostsCallback
 
 	switch {
+	case id != nil:
+		config.Auth = []ssh.AuthMethod{
+			ssh.PublicKeys(id.signer),
+		}
+
+	case kc.KeyProvisioner != nil:
+		// The key is provisioned once for all the attempts, the provisioned
+		// keys are typically valid for a minute or more.
+		signer, err := provisionKey(ctx, keyProvisioner(kc.KeyProvisioner, timeout), kc)
+		if err != nil {
+			return nil, err
+		}
+		config.Auth = []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		}
+
 	case kc.PrivateKey != "":
 		privateKeyBytes, err := base64.StdEncoding.DecodeString(kc.PrivateKey)
 		if err != nil {
 			logger.Error(err, "Error decoding base64 private key")
 		}
+		redact.Add(kc.PrivateKey, string(privateKeyBytes))
 		signer, err := ssh.ParsePrivateKey(privateKeyBytes)
 		if err != nil {
 			logger.Error(err, "Failed to parse private key")
@@ -101,6 +194,7 @@ func NewSSHClient(ctx context.Context, data []byte) (*ssh.Client, error) { // no
 		}
 
 	case kc.Password != "":
+		redact.Add(kc.Password)
 		config.Auth = []ssh.AuthMethod{
 			ssh.Password(kc.Password), // Replace with your remote server password
 		}
@@ -112,18 +206,23 @@ func NewSSHClient(ctx context.Context, data []byte) (*ssh.Client, error) { // no
 	maxAttempts := 3
 	// Delay between retries
 	delayBetweenRetries := 3 * time.Second
-	remoteHost := fmt.Sprintf("%s:%s", kc.RemoteHostIP, kc.RemoteHostPort)
+	remoteHost := net.JoinHostPort(kc.RemoteHostIP, kc.RemoteHostPort.String())
+	target := remoteHost
+	if kc.Alias != "" {
+		target = fmt.Sprintf("%s (%s)", kc.Alias, remoteHost)
+		logger = logger.WithValues("host", kc.Alias)
+	}
 
 	var client *ssh.Client
 
 	for attempts := 1; attempts <= maxAttempts; attempts++ {
-		client, err = ssh.Dial("tcp", remoteHost, config)
+		client, err = dial(ctx, remoteHost, config, kc)
 		if err == nil {
 			// Successful connection
 			break
 		}
 
-		logger.Info(fmt.Sprintf("Failed to dial: %s with username %s, attempt %d/%d, error: %s", remoteHost, config.User, attempts, maxAttempts, err.Error()))
+		logger.Info(fmt.Sprintf("Failed to dial: %s with username %s, attempt %d/%d, error: %s", target, config.User, attempts, maxAttempts, err.Error()))
 
 		// If this is not the last attempt, wait before retrying
 		if attempts < maxAttempts {
@@ -133,71 +232,119 @@ func NewSSHClient(ctx context.Context, data []byte) (*ssh.Client, error) { // no
 
 	if err != nil {
 		// Final failure after all attempts
-		logger.Info(fmt.Sprintf("All %d attempts to connect to %s failed.", maxAttempts, remoteHost))
+		logger.Info(fmt.Sprintf("All %d attempts to connect to %s failed.", maxAttempts, target))
 		return nil, err
 	}
 
 	return client, nil
 }
 
-func isValidIPv4(inputAddress string) bool {
-	// Check if the input is a valid IPv4 address
-	// Check if the input is a valid IPv4 address
-	ipv4Pattern := `^(\d{1,3}\.){3}\d{1,3}$`
-	ipv4Regex := regexp.MustCompile(ipv4Pattern)
-
-	// Regular expression pattern to match URL with anything[dot]anything
-	urlPattern := `^[^\.]+(\.[^\.]+)+$`
-	urlRegex := regexp.MustCompile(urlPattern)
+// A conn is the connection of a client created by NewSSHClient. It records
+// the endpoint and the host key of the remote host, and how the connection
+// is used.
+type conn struct {
+	ssh.Conn
+
+	host    string
+	port    string
+	alias   string
+	hostKey ssh.PublicKey
+
+	providerConfig string
+	compression    string
+	limiters       *transferLimiters
+	established    time.Time
+	lastUsed       atomic.Int64
+	sessions       atomic.Int64
+	closed         atomic.Bool
+
+	// redial connects to the host again with the same configuration.
+	redial func(ctx context.Context) (*ssh.Client, error)
+}
 
-	// Check if the input string matches IPv4 pattern or URL pattern
-	if ipv4Regex.MatchString(inputAddress) || urlRegex.MatchString(inputAddress) {
-		return true
+// dial connects to addr like ssh.Dial, recording the host key accepted by
+// the host key callback of the config.
+func dial(ctx context.Context, addr string, config *ssh.ClientConfig, kc Config) (*ssh.Client, error) {
+	c := &conn{host: kc.RemoteHostIP, port: kc.RemoteHostPort.String(), alias: kc.Alias, providerConfig: providerConfigOf(ctx), compression: kc.Compression}
+	c.limiters = limitersFor(c.providerConfig, kc.BandwidthLimitKB)
+	c.redial = func(ctx context.Context) (*ssh.Client, error) {
+		return dial(WithProviderConfig(ctx, c.providerConfig), addr, config, kc)
+	}
+	cfg := *config
+	cfg.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if err := config.HostKeyCallback(hostname, remote, key); err != nil {
+			return err
+		}
+		c.hostKey = key
+		return nil
 	}
-	return false
-}
 
-// send a file to the remote host
-func sendFile(client *ssh.Client, fileContent, remotePath string) error {
-	session, err := client.NewSession()
+	var nc net.Conn
+	var err error
+	switch {
+	case kc.Teleport != nil:
+		// The proxy is verified and authenticated to like the host.
+		nc, err = dialTeleport(addr, config, kc.Teleport)
+	case kc.WebSocket != nil:
+		nc, err = dialWebSocket(ctx, kc.WebSocket, cfg.Timeout)
+	default:
+		nc, err = net.DialTimeout("tcp", addr, cfg.Timeout)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer closeSession(session)
-
-	sftpClient, err := sftp.NewClient(client)
+	if cfg.Timeout > 0 {
+		// Bound the handshake as well, a host may accept the connection
+		// without ever answering.
+		_ = nc.SetDeadline(time.Now().Add(cfg.Timeout))
+	}
+	sc, chans, reqs, err := ssh.NewClientConn(nc, addr, &cfg)
 	if err != nil {
-		return err
+		_ = nc.Close()
+		return nil, err
 	}
-	defer func() {
-		err := session.Close()
-		if err != nil {
-			_ = fmt.Errorf("failed to close sftp session: %w", err)
-		}
-	}()
+	_ = nc.SetDeadline(time.Time{})
+	c.Conn = sc
+	track(c)
+	return ssh.NewClient(c, chans, reqs), nil
+}
 
-	// Convert the string content to a byte buffer
-	fileBuffer := bytes.NewBufferString(fileContent)
+// IsUnreachable reports whether err is a failure to reach the host over the
+// network, such as a refused or timed out connection or an unresolved name,
+// rather than a rejected authentication or host key.
+func IsUnreachable(err error) bool {
+	var oe *net.OpError
+	var de *net.DNSError
+	return errors.As(err, &oe) || errors.As(err, &de) || errors.Is(err, os.ErrDeadlineExceeded)
+}
 
-	// Open the destination file on the remote host
-	remoteFile, err := sftpClient.Create(remotePath)
-	if err != nil {
-		return errors.Wrap(err, "Failed to create remote file")
-	}
-	defer func() {
-		err = remoteFile.Close()
-		if err != nil {
-			_ = fmt.Errorf("failed to close remote file: %w", err)
-		}
-	}()
+// A Remote is the host an SSH client is connected to.
+type Remote struct {
+	Host     string
+	Port     string
+	Username string
+	// Alias is the human-friendly name of the host, empty if the
+	// ProviderConfig sets none.
+	Alias string
+	// HostKeyFingerprint is the SHA256 fingerprint of the host key, verified
+	// against the known hosts of the credentials when they are set.
+	HostKeyFingerprint string
+}
 
-	// Write the file content to the remote file
-	_, err = fileBuffer.WriteTo(remoteFile)
-	if err != nil {
-		return errors.Wrap(err, "Failed to write to remote file")
+// RemoteOf returns the host the client is connected to. The host and port
+// are the ones of the credentials for clients created by NewSSHClient, and
+// the remote address of the connection otherwise.
+func RemoteOf(client *ssh.Client) Remote {
+	r := Remote{Username: client.User()}
+	if c, ok := client.Conn.(*conn); ok {
+		r.Host, r.Port, r.Alias = c.host, c.port, c.alias
+		if c.hostKey != nil {
+			r.HostKeyFingerprint = ssh.FingerprintSHA256(c.hostKey)
+		}
+		return r
 	}
-
-	return nil
+	r.Host, r.Port, _ = net.SplitHostPort(client.RemoteAddr().String())
+	return r
 }
 
 // ReplaceVariables replaces the variables in the script with the given values
@@ -210,72 +357,505 @@ func ReplaceVariables(script string, vars []v1alpha1.Variable) string {
 	return script
 }
 
-// RunScript function execute the given script over an ssh session
-func ExecuteScript(ctx context.Context, client *ssh.Client, sc string, vars []v1alpha1.Variable, suEnabled bool) (string, string, error) {
-	logger := log.FromContext(ctx).WithName("[RunScript]")
+// envsubstRef matches the $VAR and ${VAR} references to variables.
+var envsubstRef = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))`)
 
-	// Need to create different session for each command
-	// replace the variables in the script
-	sc = ReplaceVariables(sc, vars)
+// Envsubst replaces the $VAR and ${VAR} references to the variables in the
+// script with their values. References to names that are not variables are
+// left as they are, for the shell to expand.
+func Envsubst(script string, vars []v1alpha1.Variable) string {
+	values := make(map[string]string, len(vars))
+	for _, v := range vars {
+		values[v.Name] = v.Value
+	}
+	return envsubstRef.ReplaceAllStringFunc(script, func(ref string) string {
+		m := envsubstRef.FindStringSubmatch(ref)
+		name := m[1] + m[2]
+		if v, ok := values[name]; ok {
+			return v
+		}
+		return ref
+	})
+}
 
-	// send the script to the remote host
-	remoteFile := "/tmp/" + randomFileName(8)
-	if err := sendFile(client, sc, remoteFile); err != nil {
-		return "", "", errors.Wrap(err, "Failed to send script to remote host")
+// prepend inserts the line at the start of the script, or right after its
+// #! line so that it keeps selecting the interpreter.
+func prepend(script, line string) string {
+	if !strings.HasPrefix(script, "#!") {
+		return line + "\n" + script
 	}
+	shebang, rest, _ := strings.Cut(script, "\n")
+	return shebang + "\n" + line + "\n" + rest
+}
+
+// RenderScript replaces the references to the variables in the script, in
+// the syntax of the supplied templating.
+func RenderScript(script string, vars []v1alpha1.Variable, t v1alpha1.Templating) string {
+	if t == v1alpha1.TemplatingEnvsubst {
+		return Envsubst(script, vars)
+	}
+	return ReplaceVariables(script, vars)
+}
+
+// HashScript returns the hex encoded SHA-256 hash of the script after the
+// variables have been replaced in the syntax of the supplied templating.
+func HashScript(script string, vars []v1alpha1.Variable, t v1alpha1.Templating) string {
+	sum := sha256.Sum256([]byte(RenderScript(script, vars, t)))
+	return hex.EncodeToString(sum[:])
+}
+
+// An ExecOption configures the execution of a script.
+type ExecOption func(*execOptions)
+
+type execOptions struct {
+	interpreter string
+	timeout     time.Duration
+	templating  v1alpha1.Templating
+	preamble    string
+	container   *v1alpha1.Container
+	namespaces  *v1alpha1.Namespaces
+	chroot      string
+	loginShell  bool
+	umask       string
+	locale      string
+	timezone    string
+	nice        *int32
+	ioniceClass int
+	ioniceLevel *int32
+	limits      *v1alpha1.Limits
+	tempDir     string
+	recording   *recording.Recording
+}
 
-	// make the tmpFile executable
-	cmdExec := "chmod +x " + remoteFile
+// hostTimeoutGrace is how long after its timeout a script with limits is
+// killed on the host, should the provider fail to kill it.
+const hostTimeoutGrace = 5 * time.Second
+
+// command returns the command executing the uploaded script, through
+// timeout if limits are set and through nice and ionice if a priority is
+// set. The script is executed by a shell applying the execution settings,
+// such as the umask, the locale and the ulimits, when any is set, which is
+// a bash login shell if enabled. The shell runs under sudo, if enabled, so that the
+// settings also apply to privileged scripts. In a container, namespaces or a
+// chroot, where the uploaded file is not visible, all of it runs through the
+// exec command of the engine, nsenter or chroot, the script being read from
+// stdin by its interpreter.
+func (o execOptions) command(remoteFile, script string, suEnabled bool) string {
+	var target []string
+	if c := o.container; c != nil {
+		engine := c.Engine
+		if engine == "" {
+			engine = v1alpha1.ContainerEngineDocker
+		}
+		target = append(target, string(engine)+" exec -i "+shellQuote(c.Name))
+	}
+	if ns := o.namespaces; ns != nil {
+		target = append(target, nsenter(ns))
+	}
+	if o.chroot != "" {
+		target = append(target, "chroot "+shellQuote(o.chroot))
+	}
+
+	cmd := shellQuote(remoteFile)
+	switch {
+	case len(target) > 0:
+		cmd = o.stdinInterpreter(script)
+	case o.interpreter != "" && !strings.HasPrefix(script, "#!"):
+		cmd = o.interpreter + " " + cmd
+	}
+
+	var wrappers []string
+	if o.limits != nil && o.timeout > 0 {
+		wrappers = append(wrappers, fmt.Sprintf("timeout -k 5 %d", int64((o.timeout+hostTimeoutGrace).Seconds())))
+	}
+	if o.nice != nil {
+		wrappers = append(wrappers, "nice -n "+strconv.Itoa(int(*o.nice)))
+	}
+	if o.ioniceClass != 0 {
+		ionice := "ionice -c " + strconv.Itoa(o.ioniceClass)
+		if o.ioniceLevel != nil && o.ioniceClass != ioniceClassIdle {
+			ionice += " -n " + strconv.Itoa(int(*o.ioniceLevel))
+		}
+		wrappers = append(wrappers, ionice)
+	}
+	if len(wrappers) > 0 {
+		cmd = strings.Join(wrappers, " ") + " " + cmd
+	}
+
+	var setup []string
+	if o.umask != "" {
+		setup = append(setup, "umask "+shellQuote(o.umask))
+	}
+	if o.locale != "" {
+		setup = append(setup, "export LANG="+shellQuote(o.locale)+" LC_ALL="+shellQuote(o.locale))
+	}
+	if o.timezone != "" {
+		setup = append(setup, "export TZ="+shellQuote(o.timezone))
+	}
+	if l := o.limits; l != nil {
+		if l.CPUSeconds != nil {
+			setup = append(setup, fmt.Sprintf("ulimit -t %d", *l.CPUSeconds))
+		}
+		if l.MaxMemoryKB != nil {
+			setup = append(setup, fmt.Sprintf("ulimit -v %d", *l.MaxMemoryKB))
+		}
+		if l.MaxFileSizeKB != nil {
+			// POSIX shells count file sizes in blocks of 512 bytes.
+			setup = append(setup, fmt.Sprintf("ulimit -f %d", 2**l.MaxFileSizeKB))
+		}
+	}
+	switch {
+	case o.loginShell:
+		// The settings follow the profile, which may set its own umask.
+		cmd = "bash -l -c " + shellQuote(strings.Join(append(setup, "exec "+cmd), " && "))
+	case len(setup) > 0:
+		cmd = "/bin/sh -c " + shellQuote(strings.Join(append(setup, "exec "+cmd), " && "))
+	}
 
-	// Run the script on the remote host
-	var cmd string
+	if len(target) > 0 {
+		cmd = strings.Join(target, " ") + " " + cmd + " < " + shellQuote(remoteFile)
+	}
 	if suEnabled {
-		cmd = "sudo "
+		cmd = "sudo " + cmd
 	}
-	cmd = cmdExec + " && " + cmd + remoteFile
+	return cmd
+}
 
-	session, err := client.NewSession()
-	if err != nil {
-		logger.Error(err, "Failed to create session")
-		return "", "", errors.Wrap(err, "Failed to create session")
+// nsenterFlags are the flags of nsenter entering each type of namespace.
+var nsenterFlags = map[v1alpha1.NamespaceType]string{
+	v1alpha1.NamespaceTypeMount:   "-m",
+	v1alpha1.NamespaceTypeUTS:     "-u",
+	v1alpha1.NamespaceTypeIPC:     "-i",
+	v1alpha1.NamespaceTypeNetwork: "-n",
+	v1alpha1.NamespaceTypePID:     "-p",
+	v1alpha1.NamespaceTypeUser:    "-U",
+	v1alpha1.NamespaceTypeCgroup:  "-C",
+}
+
+// nsenter returns the nsenter command entering the supplied namespaces, the
+// mount namespace if no type is set.
+func nsenter(ns *v1alpha1.Namespaces) string {
+	types := ns.Types
+	if len(types) == 0 {
+		types = []v1alpha1.NamespaceType{v1alpha1.NamespaceTypeMount}
 	}
-	defer closeSession(session)
+	cmd := "nsenter -t " + strconv.Itoa(int(ns.TargetPID))
+	for _, t := range types {
+		if f, ok := nsenterFlags[t]; ok {
+			cmd += " " + f
+		}
+	}
+	return cmd + " --"
+}
 
-	// Buffers to capture stdout and stderr separately
-	var stdoutBuf, stderrBuf bytes.Buffer
-	session.Stdout = &stdoutBuf
-	session.Stderr = &stderrBuf
+// stdinInterpreter returns the interpreter reading the script from stdin
+// where the uploaded file is not visible: the one of its #! line if any, or
+// the configured one.
+func (o execOptions) stdinInterpreter(script string) string {
+	if line, ok := strings.CutPrefix(script, "#!"); ok {
+		line, _, _ = strings.Cut(line, "\n")
+		if i := strings.TrimSpace(line); i != "" {
+			return i
+		}
+	}
+	if o.interpreter != "" {
+		return o.interpreter
+	}
+	return v1alpha1.DefaultInterpreter
+}
 
-	if err := session.Run(cmd); err != nil {
-		return "", stderrBuf.String(), err
+// WithInterpreter executes scripts that do not start with a #! line with
+// the supplied interpreter.
+func WithInterpreter(path string) ExecOption {
+	return func(o *execOptions) { o.interpreter = path }
+}
+
+// WithTemplating replaces the references to the variables in the syntax of
+// the supplied templating, braces by default.
+func WithTemplating(t v1alpha1.Templating) ExecOption {
+	return func(o *execOptions) { o.templating = t }
+}
+
+// WithPreamble prepends the supplied line to the rendered scripts, after
+// their #! line if any.
+func WithPreamble(line string) ExecOption {
+	return func(o *execOptions) { o.preamble = line }
+}
+
+// WithContainer executes the scripts in the supplied container running on
+// the host. The scripts are executed on the host itself if c is nil.
+func WithContainer(c *v1alpha1.Container) ExecOption {
+	return func(o *execOptions) { o.container = c }
+}
+
+// WithNamespaces executes the scripts in the supplied namespaces of a process
+// of the host through nsenter. No namespace is entered if ns is nil.
+func WithNamespaces(ns *v1alpha1.Namespaces) ExecOption {
+	return func(o *execOptions) { o.namespaces = ns }
+}
+
+// WithChroot executes the scripts with the supplied directory as their root
+// directory. The root is not changed if dir is empty.
+func WithChroot(dir string) ExecOption {
+	return func(o *execOptions) { o.chroot = dir }
+}
+
+// WithLoginShell executes the scripts through a bash login shell, with the
+// environment of the profile of the remote user.
+func WithLoginShell(enabled bool) ExecOption {
+	return func(o *execOptions) { o.loginShell = enabled }
+}
+
+// WithUmask executes the scripts with the supplied octal umask.
+func WithUmask(umask string) ExecOption {
+	return func(o *execOptions) { o.umask = umask }
+}
+
+// WithLocale executes the scripts with the supplied locale as LANG and
+// LC_ALL.
+func WithLocale(locale string) ExecOption {
+	return func(o *execOptions) { o.locale = locale }
+}
+
+// WithTimezone executes the scripts with the supplied timezone as TZ.
+func WithTimezone(tz string) ExecOption {
+	return func(o *execOptions) { o.timezone = tz }
+}
+
+// The I/O scheduling classes of ionice.
+const (
+	ioniceClassRealtime   = 1
+	ioniceClassBestEffort = 2
+	ioniceClassIdle       = 3
+)
+
+// WithPriority executes the scripts through nice and ionice with the
+// supplied priority. No wrapper is added if p is nil.
+func WithPriority(p *v1alpha1.Priority) ExecOption {
+	return func(o *execOptions) {
+		if p == nil {
+			return
+		}
+		o.nice = p.Nice
+		if p.IONice == nil {
+			return
+		}
+		switch p.IONice.Class {
+		case v1alpha1.IONiceClassRealtime:
+			o.ioniceClass = ioniceClassRealtime
+		case v1alpha1.IONiceClassBestEffort:
+			o.ioniceClass = ioniceClassBestEffort
+		case v1alpha1.IONiceClassIdle:
+			o.ioniceClass = ioniceClassIdle
+		}
+		o.ioniceLevel = p.IONice.Level
 	}
+}
 
-	// Clean up the temporary file
-	err = cleanUpTempFile(client, remoteFile)
-	if err != nil {
+// WithLimits bounds the resources of the scripts through ulimit, and kills
+// them on the host through timeout shortly after their own timeout.
+func WithLimits(l *v1alpha1.Limits) ExecOption {
+	return func(o *execOptions) { o.limits = l }
+}
+
+// WithTempDir uploads the script to the supplied directory of the host,
+// /tmp if it is empty.
+func WithTempDir(dir string) ExecOption {
+	return func(o *execOptions) { o.tempDir = dir }
+}
+
+// WithRecording records the rendered script and its output in the supplied
+// recording.
+func WithRecording(r *recording.Recording) ExecOption {
+	return func(o *execOptions) { o.recording = r }
+}
+
+// WithTimeout kills scripts that run longer than the supplied duration.
+func WithTimeout(d time.Duration) ExecOption {
+	return func(o *execOptions) { o.timeout = d }
+}
+
+// executions bounds the number of scripts executed at the same time by
+// ExecuteScript. It is nil if the number is not bounded.
+var executions chan struct{}
+
+// SetMaxConcurrentExecutions bounds the number of scripts executed at the
+// same time by ExecuteScript across the provider. Zero means no bound. It
+// must be called before any script is executed.
+func SetMaxConcurrentExecutions(n int) {
+	if n <= 0 {
+		executions = nil
+		return
+	}
+	executions = make(chan struct{}, n)
+}
+
+// ExecuteScript uploads the given script to the remote host and runs it
+// through the supplied executor.
+func ExecuteScript(ctx context.Context, x RemoteExecutor, sc string, vars []v1alpha1.Variable, suEnabled bool, opts ...ExecOption) (string, string, error) {
+	logger := log.FromContext(ctx).WithName("[RunScript]")
+	o := execOptions{}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	if x == nil {
+		return "", "", errors.New("Not connected to remote host")
+	}
+	if !executing.start() {
+		return "", "", errors.New(errShuttingDown)
+	}
+	defer executing.done()
+
+	if sem := executions; sem != nil {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-ctx.Done():
+			return "", "", errors.Wrap(ctx.Err(), "Timed out waiting for other scripts to finish")
+		}
+	}
+
+	sc = o.render(sc, vars)
+
+	// send the script to the remote host, executable
+	dir := o.tempDir
+	if dir == "" {
+		dir = "/tmp"
+	}
+	remoteFile := path.Join(dir, randomFileName(8))
+	if err := x.Upload(ctx, remoteFile, []byte(sc), 0o755); err != nil {
+		return "", "", errors.Wrap(err, "Failed to send script to remote host")
+	}
+
+	// Run the script on the remote host, killed if the provider shuts down
+	stdout, stderr, err := o.execute(killOnShutdown(ctx), x, o.command(remoteFile, sc, suEnabled), sc)
+
+	// Clean up the temporary file, whether the script succeeded or not, even
+	// while the provider shuts down
+	if _, _, err := x.Run(ctx, "rm -f "+shellQuote(remoteFile), 0); err != nil {
 		logger.Error(err, "Failed to clean up temporary file")
 	}
+	if err != nil {
+		return stdout, stderr, err
+	}
 
-	logger.Info(fmt.Sprintf("Script executed, len(stdout): %d, len(stderr): %d", len(stdoutBuf.String()), len(stderrBuf.String())))
-	return stdoutBuf.String(), stderrBuf.String(), nil
+	logger.V(1).Info(fmt.Sprintf("Script executed, len(stdout): %d, len(stderr): %d", len(stdout), len(stderr)))
+	return stdout, stderr, nil
 }
 
-func closeSession(session *ssh.Session) {
-	err := session.Close()
-	if err != nil {
-		_ = fmt.Errorf("failed to close session: %w", err)
+// render replaces the variables in the script and prepends the preamble,
+// masking the sensitive variables in the logs from now on.
+func (o execOptions) render(sc string, vars []v1alpha1.Variable) string {
+	for _, v := range vars {
+		if v.Sensitive {
+			redact.Add(v.Value)
+		}
 	}
+	sc = RenderScript(sc, vars, o.templating)
+	if o.preamble != "" {
+		sc = prepend(sc, o.preamble)
+	}
+	return sc
 }
 
-func cleanUpTempFile(client *ssh.Client, tmpFile string) error {
-	session, err := client.NewSession()
-	if err != nil {
+// execute runs the command of the supplied script through the executor,
+// recording the script and its output if a recording is set. The output is
+// recorded as it is produced if the executor streams it, once the command
+// finished otherwise.
+func (o execOptions) execute(ctx context.Context, x RemoteExecutor, cmd, script string) (string, string, error) {
+	r := o.recording
+	if r == nil {
+		return x.Run(ctx, cmd, o.timeout)
+	}
+	r.Input(script)
+	var stdout, stderr string
+	var err error
+	if sx, ok := x.(StreamingExecutor); ok {
+		var stdoutBuf, stderrBuf bytes.Buffer
+		err = sx.Stream(ctx, cmd, o.timeout, io.MultiWriter(&stdoutBuf, r.Stdout()), io.MultiWriter(&stderrBuf, r.Stderr()))
+		stdout, stderr = stdoutBuf.String(), stderrBuf.String()
+	} else {
+		stdout, stderr, err = x.Run(ctx, cmd, o.timeout)
+		_, _ = io.WriteString(r.Stdout(), stdout)
+		_, _ = io.WriteString(r.Stderr(), stderr)
+	}
+	if IsExitError(err) {
+		r.Exit(ExitStatus(err), nil)
+	} else {
+		r.Exit(ExitStatus(err), err)
+	}
+	return stdout, stderr, err
+}
+
+// run runs cmd in the session. The remote process is killed if it does not
+// finish within timeout, unless timeout is zero, or once stop is closed,
+// unless stop is nil.
+func run(session *ssh.Session, cmd string, timeout time.Duration, stop <-chan struct{}) error {
+	if timeout <= 0 && stop == nil {
+		return session.Run(cmd)
+	}
+	done := make(chan error, 1)
+	go func() { done <- session.Run(cmd) }()
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	kill := func() {
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+		<-done
+	}
+	select {
+	case err := <-done:
 		return err
+	case <-expired:
+		kill()
+		return errors.Errorf("Script timed out after %s", timeout)
+	case <-stop:
+		kill()
+		return errors.New(errKilled)
+	}
+}
+
+// RunCommand executes the given command directly through the executor,
+// without uploading it to the remote host first.
+func RunCommand(ctx context.Context, x RemoteExecutor, cmd string, vars []v1alpha1.Variable, suEnabled bool) (string, string, error) {
+	logger := log.FromContext(ctx).WithName("[RunCommand]")
+
+	cmd = ReplaceVariables(cmd, vars)
+	if suEnabled {
+		cmd = "sudo sh -c " + shellQuote(cmd)
 	}
-	defer closeSession(session)
 
-	cmd := "rm -f " + tmpFile
-	return session.Run(cmd)
+	stdout, stderr, err := x.Run(ctx, cmd, 0)
+	logger.V(1).Info(fmt.Sprintf("Command executed, len(stdout): %d, len(stderr): %d", len(stdout), len(stderr)))
+	return stdout, stderr, err
+}
+
+// shellQuote quotes s as a single argument of a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ExitStatus returns the exit code of a script execution. Errors that do not
+// carry an exit code are reported as a generic failure.
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(ExitError); ok {
+		return exitErr.ExitStatus()
+	}
+	return 1
+}
+
+func closeSession(session *ssh.Session) {
+	err := session.Close()
+	if err != nil {
+		_ = fmt.Errorf("failed to close session: %w", err)
+	}
 }
 
 func randomFileName(length int) string {
@@ -286,3 +866,102 @@ func randomFileName(length int) string {
 	}
 	return "tmp." + hex.EncodeToString(bytes)
 }
+
+// WithEndpoint returns creds with the remote host replaced by the supplied
+// host and port. The alias of the former host is dropped.
+func WithEndpoint(creds []byte, host string, port int) ([]byte, error) {
+	kc := Config{}
+	if err := json.Unmarshal(creds, &kc); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse credentials")
+	}
+	kc.RemoteHostIP = host
+	kc.RemoteHostPort = Port(port)
+	kc.Alias = ""
+	return json.Marshal(kc)
+}
+
+// WithUsername returns creds with the username replaced by the supplied
+// username.
+func WithUsername(creds []byte, username string) ([]byte, error) {
+	kc := Config{}
+	if err := json.Unmarshal(creds, &kc); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse credentials")
+	}
+	kc.Username = username
+	return json.Marshal(kc)
+}
+
+// WithAlias returns creds with the supplied alias of the host. The
+// credentials are left unchanged if the alias is empty.
+func WithAlias(creds []byte, alias string) ([]byte, error) {
+	if alias == "" {
+		return creds, nil
+	}
+	kc := Config{}
+	if err := json.Unmarshal(creds, &kc); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse credentials")
+	}
+	kc.Alias = alias
+	return json.Marshal(kc)
+}
+
+// WithTeleport returns creds connecting to the host through the supplied
+// Teleport proxy. The credentials are left unchanged if it is nil.
+func WithTeleport(creds []byte, t *TeleportConfig) ([]byte, error) {
+	if t == nil {
+		return creds, nil
+	}
+	kc := Config{}
+	if err := json.Unmarshal(creds, &kc); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse credentials")
+	}
+	kc.Teleport = t
+	return json.Marshal(kc)
+}
+
+// WithWebSocket returns creds connecting to the host through the supplied
+// WebSocket gateway. The credentials are left unchanged if it is nil.
+func WithWebSocket(creds []byte, w *WebSocketConfig) ([]byte, error) {
+	if w == nil {
+		return creds, nil
+	}
+	kc := Config{}
+	if err := json.Unmarshal(creds, &kc); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse credentials")
+	}
+	kc.WebSocket = w
+	return json.Marshal(kc)
+}
+
+// WithKeyProvisioner returns creds authenticating with an ephemeral key
+// pushed to the host by the supplied provisioner. The credentials are left
+// unchanged if it is nil.
+func WithKeyProvisioner(creds []byte, p *KeyProvisionerConfig) ([]byte, error) {
+	if p == nil {
+		return creds, nil
+	}
+	kc := Config{}
+	if err := json.Unmarshal(creds, &kc); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse credentials")
+	}
+	kc.KeyProvisioner = p
+	return json.Marshal(kc)
+}
+
+// WithTransfer returns creds with the supplied transfer settings. The
+// credentials are left unchanged if the settings are nil.
+func WithTransfer(creds []byte, t *v1alpha1.TransferSettings) ([]byte, error) {
+	if t == nil {
+		return creds, nil
+	}
+	kc := Config{}
+	if err := json.Unmarshal(creds, &kc); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse credentials")
+	}
+	kc.Compression = string(t.Compression)
+	kc.BandwidthLimitKB = 0
+	if t.BandwidthLimitKB != nil {
+		kc.BandwidthLimitKB = *t.BandwidthLimitKB
+	}
+	return json.Marshal(kc)
+}
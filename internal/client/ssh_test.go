@@ -0,0 +1,199 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+func TestNewSSHClientDefaultKnownHosts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("connects to an in-process SSH server")
+	}
+
+	s := sshtest.NewServer(t)
+	verified := s.Credentials()
+	kc := sshv1alpha1.Config{}
+	if err := json.Unmarshal(verified, &kc); err != nil {
+		t.Fatal(err)
+	}
+	line := kc.KnownHosts
+	kc.KnownHosts = ""
+	unverified, _ := json.Marshal(kc)
+
+	dir := t.TempDir()
+	file := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	known, empty := file("known_hosts", line), file("empty", "")
+
+	cases := map[string]struct {
+		reason string
+		creds  []byte
+		file   string
+		err    bool
+	}{
+		"Default": {
+			reason: "The host key of credentials without knownHosts should be verified against the default known_hosts file.",
+			creds:  unverified,
+			file:   known,
+		},
+		"EmptyDefault": {
+			reason: "An empty default known_hosts file should accept no host key.",
+			creds:  unverified,
+			file:   empty,
+			err:    true,
+		},
+		"MissingDefault": {
+			reason: "A default known_hosts file that cannot be read should fail the connection.",
+			creds:  unverified,
+			file:   filepath.Join(dir, "missing"),
+			err:    true,
+		},
+		"Credentials": {
+			reason: "The knownHosts of the credentials should take precedence over the default known_hosts file.",
+			creds:  verified,
+			file:   empty,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, err := sshv1alpha1.NewSSHClientFn(0, tc.file)(context.Background(), tc.creds)
+			if gotErr := err != nil; gotErr != tc.err {
+				t.Fatalf("\n%s\nNewSSHClientFn(...): %v, want error %t", tc.reason, err, tc.err)
+			}
+			if c != nil {
+				_ = c.Close()
+			}
+		})
+	}
+}
+
+func TestWithAlias(t *testing.T) {
+	creds := []byte(`{"hostIP":"10.0.0.1","hostPort":22,"username":"admin"}`)
+
+	cases := map[string]struct {
+		reason string
+		fn     func(creds []byte) ([]byte, error)
+		want   string
+	}{
+		"Alias": {
+			reason: "The alias should be recorded in the credentials.",
+			fn:     func(creds []byte) ([]byte, error) { return sshv1alpha1.WithAlias(creds, "paris-edge-01") },
+			want:   "paris-edge-01",
+		},
+		"NoAlias": {
+			reason: "The credentials should be left unchanged without alias.",
+			fn:     func(creds []byte) ([]byte, error) { return sshv1alpha1.WithAlias(creds, "") },
+		},
+		"Endpoint": {
+			reason: "The alias of the former host should be dropped when the endpoint is replaced.",
+			fn: func(creds []byte) ([]byte, error) {
+				data, err := sshv1alpha1.WithAlias(creds, "paris-edge-01")
+				if err != nil {
+					return nil, err
+				}
+				return sshv1alpha1.WithEndpoint(data, "10.0.0.2", 22)
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			data, err := tc.fn(creds)
+			if err != nil {
+				t.Fatalf("\n%s\n%v", tc.reason, err)
+			}
+			kc := sshv1alpha1.Config{}
+			if err := json.Unmarshal(data, &kc); err != nil {
+				t.Fatal(err)
+			}
+			if kc.Alias != tc.want {
+				t.Errorf("\n%s\nalias: got %q, want %q", tc.reason, kc.Alias, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithUmask(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		umask  string
+		want   string
+	}{
+		"Octal": {
+			reason: "The script should be executed with the umask.",
+			umask:  "027",
+			want:   `/bin/sh -c 'umask '\''027'\'' && exec '\''/tmp/script'\'''`,
+		},
+		"Injection": {
+			reason: "A umask that is not octal should be passed to umask as a single argument, never executed.",
+			umask:  "027; touch /tmp/pwned",
+			want:   `/bin/sh -c 'umask '\''027; touch /tmp/pwned'\'' && exec '\''/tmp/script'\'''`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := sshv1alpha1.Command("/tmp/script", "true", false, sshv1alpha1.WithUmask(tc.umask))
+			if got != tc.want {
+				t.Errorf("\n%s\nCommand(...): got %q, want %q", tc.reason, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithTempDir(t *testing.T) {
+	if testing.Short() {
+		t.Skip("connects to an in-process SSH server")
+	}
+
+	srv := sshtest.NewServer(t)
+	dir := filepath.Join(srv.Dir, "temp dir")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c, err := sshv1alpha1.NewSSHClient(context.Background(), srv.Credentials())
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := sshv1alpha1.NewSSHExecutor(c)
+	defer x.Close() // nolint: errcheck
+
+	stdout, _, err := sshv1alpha1.ExecuteScript(context.Background(), x, "echo ok", nil, false, sshv1alpha1.WithTempDir(dir))
+	if err != nil {
+		t.Fatalf("\nA script uploaded to a temporary directory whose path holds a space should be executed.\nExecuteScript(...): %v", err)
+	}
+	if stdout != "ok\n" {
+		t.Errorf("\nA script uploaded to a temporary directory whose path holds a space should be executed.\nExecuteScript(...): got %q, want %q", stdout, "ok\n")
+	}
+	if left, _ := os.ReadDir(dir); len(left) != 0 {
+		t.Errorf("\nThe script uploaded to a temporary directory whose path holds a space should be removed.\nReadDir(...): got %d files, want none", len(left))
+	}
+}
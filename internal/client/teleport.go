@@ -0,0 +1,211 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// TeleportConfig connects to the host through a Teleport proxy.
+type TeleportConfig struct {
+	// Proxy is the address of the SSH endpoint of the proxy.
+	Proxy string `json:"proxy"`
+	// Cluster is the leaf cluster of the host, empty for the cluster of the
+	// proxy.
+	Cluster string `json:"cluster,omitempty"`
+	// Identity is the content of the identity file of the Teleport user.
+	Identity string `json:"identity"`
+}
+
+// A teleportIdentity is a parsed identity file of a Teleport user.
+type teleportIdentity struct {
+	// signer authenticates with the SSH certificate of the user.
+	signer ssh.Signer
+	// hostCAs are the certificate authorities signing the host certificates
+	// of the proxy and the nodes.
+	hostCAs []ssh.PublicKey
+}
+
+// parseTeleportIdentity parses an identity file as written by tctl auth sign
+// or tbot: a PEM encoded private key, the SSH certificate of its public key,
+// and @cert-authority lines in known_hosts format. The TLS certificates of
+// the file are ignored.
+func parseTeleportIdentity(data string) (*teleportIdentity, error) {
+	var key []byte
+	var cert *ssh.Certificate
+	id := &teleportIdentity{}
+
+	var block bytes.Buffer
+	s := bufio.NewScanner(strings.NewReader(data))
+	s.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case block.Len() > 0 || strings.HasPrefix(line, "-----BEGIN "):
+			block.WriteString(line + "\n")
+			if !strings.HasPrefix(line, "-----END ") {
+				continue
+			}
+			if strings.Contains(line, "PRIVATE KEY") && key == nil {
+				key = append([]byte(nil), block.Bytes()...)
+			}
+			block.Reset()
+		case strings.HasPrefix(line, "@cert-authority "):
+			_, _, ca, _, _, err := ssh.ParseKnownHosts([]byte(line))
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot parse the host certificate authority of the identity")
+			}
+			id.hostCAs = append(id.hostCAs, ca)
+		case strings.Contains(line, "-cert-v01@openssh.com "):
+			pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot parse the SSH certificate of the identity")
+			}
+			c, ok := pub.(*ssh.Certificate)
+			if !ok || c.CertType != ssh.UserCert {
+				return nil, errors.New("the SSH certificate of the identity is not a user certificate")
+			}
+			cert = c
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "cannot read the identity")
+	}
+
+	switch {
+	case key == nil:
+		return nil, errors.New("the identity holds no private key")
+	case cert == nil:
+		return nil, errors.New("the identity holds no SSH certificate")
+	case len(id.hostCAs) == 0:
+		return nil, errors.New("the identity holds no host certificate authority")
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse the private key of the identity")
+	}
+	if !bytes.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal()) {
+		return nil, errors.New("the SSH certificate of the identity does not certify its private key")
+	}
+	if id.signer, err = ssh.NewCertSigner(cert, signer); err != nil {
+		return nil, errors.Wrap(err, "cannot use the SSH certificate of the identity")
+	}
+	return id, nil
+}
+
+// hostKeyCallback accepts the host certificates signed by a host certificate
+// authority of the identity and valid now. Their principals are not checked,
+// the proxy chooses the node the connection is routed to.
+func (id *teleportIdentity) hostKeyCallback(_ string, _ net.Addr, key ssh.PublicKey) error {
+	cert, ok := key.(*ssh.Certificate)
+	if !ok || cert.CertType != ssh.HostCert {
+		return errors.New("host key is not a host certificate of the Teleport cluster")
+	}
+	if !id.isHostAuthority(cert.SignatureKey) {
+		return errors.New("host certificate is not signed by a host certificate authority of the Teleport identity")
+	}
+	principal := ""
+	if len(cert.ValidPrincipals) > 0 {
+		principal = cert.ValidPrincipals[0]
+	}
+	return (&ssh.CertChecker{}).CheckCert(principal, cert)
+}
+
+func (id *teleportIdentity) isHostAuthority(auth ssh.PublicKey) bool {
+	for _, ca := range id.hostCAs {
+		if bytes.Equal(ca.Marshal(), auth.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialTeleport connects to addr through the SSH endpoint of the Teleport
+// proxy, as tsh does: the proxy subsystem of a session with the proxy
+// carries the SSH connection to the node.
+func dialTeleport(addr string, config *ssh.ClientConfig, t *TeleportConfig) (net.Conn, error) {
+	proxy, err := ssh.Dial("tcp", t.Proxy, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot connect to the Teleport proxy %s", t.Proxy)
+	}
+	session, err := proxy.NewSession()
+	if err != nil {
+		_ = proxy.Close()
+		return nil, errors.Wrap(err, "cannot open a session with the Teleport proxy")
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		_ = proxy.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		_ = proxy.Close()
+		return nil, err
+	}
+	subsystem := "proxy:" + addr
+	if t.Cluster != "" {
+		subsystem += "@" + t.Cluster
+	}
+	if err := session.RequestSubsystem(subsystem); err != nil {
+		_ = proxy.Close()
+		return nil, errors.Wrapf(err, "the Teleport proxy cannot route to %s", addr)
+	}
+	return &teleportConn{Reader: stdout, WriteCloser: stdin, proxy: proxy}, nil
+}
+
+// A teleportConn is a connection to a node carried by a session with a
+// Teleport proxy.
+type teleportConn struct {
+	io.Reader
+	io.WriteCloser
+
+	proxy *ssh.Client
+
+	mu       sync.Mutex
+	deadline *time.Timer
+
+	// The SSH client of the node closes its connection from several
+	// goroutines, it is only closed once.
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Close closes the connection with the proxy. Closing it again returns the
+// error of the first close.
+func (c *teleportConn) Close() error {
+	c.closeOnce.Do(func() {
+		_ = c.SetDeadline(time.Time{})
+		_ = c.WriteCloser.Close()
+		c.closeErr = c.proxy.Close()
+	})
+	return c.closeErr
+}
+
+func (c *teleportConn) LocalAddr() net.Addr  { return c.proxy.LocalAddr() }
+func (c *teleportConn) RemoteAddr() net.Addr { return c.proxy.RemoteAddr() }
+
+// SetDeadline closes the connection at the supplied time, which bounds the
+// SSH handshake with the node. A zero time cancels the deadline.
+func (c *teleportConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deadline != nil {
+		c.deadline.Stop()
+		c.deadline = nil
+	}
+	if !t.IsZero() {
+		c.deadline = time.AfterFunc(time.Until(t), func() { _ = c.proxy.Close() })
+	}
+	return nil
+}
+
+func (c *teleportConn) SetReadDeadline(t time.Time) error  { return c.SetDeadline(t) }
+func (c *teleportConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+func TestTeleport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("connects through an in-process Teleport proxy")
+	}
+
+	node := sshtest.NewServer(t)
+	tp := sshtest.NewTeleport(t, node)
+	untrusted := sshtest.NewTeleport(t, sshtest.NewServer(t))
+	host, port, _ := net.SplitHostPort(node.Addr)
+	hostPort, _ := sshv1alpha1.ParsePort(port)
+	creds := func(identity string) []byte {
+		data, _ := json.Marshal(sshv1alpha1.Config{
+			RemoteHostIP:   host,
+			RemoteHostPort: hostPort,
+			Username:       sshtest.Username,
+			Teleport:       &sshv1alpha1.TeleportConfig{Proxy: tp.ProxyAddr, Cluster: "edge", Identity: identity},
+		})
+		return data
+	}
+	// otherCA replaces the host certificate authority of the identity by the
+	// one of another cluster.
+	caLine := regexp.MustCompile(`(?m)^@cert-authority .*$`)
+	otherCA := caLine.ReplaceAllString(string(tp.Identity()), caLine.FindString(string(untrusted.Identity())))
+
+	cases := map[string]struct {
+		reason string
+		creds  []byte
+		err    bool
+	}{
+		"Node": {
+			reason: "The node should be reached through the proxy subsystem of the proxy.",
+			creds:  creds(string(tp.Identity())),
+		},
+		"UntrustedHostCA": {
+			reason: "Host certificates that are not signed by a host certificate authority of the identity should be rejected.",
+			creds:  creds(otherCA),
+			err:    true,
+		},
+		"NoCertificate": {
+			reason: "An identity without SSH certificate should be rejected.",
+			creds:  creds(regexp.MustCompile(`(?m)^.*-cert-v01@openssh.com .*$`).ReplaceAllString(string(tp.Identity()), "")),
+			err:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, err := sshv1alpha1.NewSSHClient(context.Background(), tc.creds)
+			if gotErr := err != nil; gotErr != tc.err {
+				t.Fatalf("\n%s\nNewSSHClient(...): %v, want error %t", tc.reason, err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			defer c.Close() // nolint: errcheck
+			s, err := c.NewSession()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := s.Output("echo ok")
+			if err != nil {
+				t.Fatalf("\n%s\nsession.Output(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff("ok\n", string(out)); diff != "" {
+				t.Errorf("\n%s\nsession.Output(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+
+	if diff := cmp.Diff([]string{"proxy:" + node.Addr + "@edge"}, tp.Subsystems()); diff != "" {
+		t.Errorf("\nThe proxy subsystem should name the node and its cluster.\nSubsystems(): -want, +got:\n%s\n", diff)
+	}
+}
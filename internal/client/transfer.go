@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/time/rate"
+)
+
+// transferBurst is the number of bytes a limited transfer may send or
+// receive at once, the size of the largest SFTP packet.
+const transferBurst = 32 << 10
+
+// transferLimiters bound the rate of the transfers of connections, in each
+// direction.
+type transferLimiters struct {
+	up   *rate.Limiter
+	down *rate.Limiter
+}
+
+// limiters are the transfer limiters shared by the connections of each
+// ProviderConfig.
+var limiters = struct {
+	sync.Mutex
+	byProviderConfig map[string]*transferLimiters
+}{byProviderConfig: map[string]*transferLimiters{}}
+
+// limitersFor returns the transfer limiters of the connections of the
+// supplied ProviderConfig, bounding them to the supplied KiB per second. It
+// returns nil if the transfers are not limited. Connections that are not
+// accounted to a ProviderConfig get limiters of their own.
+func limitersFor(providerConfig string, kbPerSecond int64) *transferLimiters {
+	if kbPerSecond <= 0 {
+		return nil
+	}
+	limit := rate.Limit(kbPerSecond << 10)
+	if providerConfig == "" {
+		return &transferLimiters{up: rate.NewLimiter(limit, transferBurst), down: rate.NewLimiter(limit, transferBurst)}
+	}
+
+	limiters.Lock()
+	defer limiters.Unlock()
+	l, ok := limiters.byProviderConfig[providerConfig]
+	if !ok {
+		l = &transferLimiters{up: rate.NewLimiter(limit, transferBurst), down: rate.NewLimiter(limit, transferBurst)}
+		limiters.byProviderConfig[providerConfig] = l
+	}
+	// The limit of the ProviderConfig may have changed.
+	l.up.SetLimit(limit)
+	l.down.SetLimit(limit)
+	return l
+}
+
+// transferLimitersOf returns the transfer limiters of the connection of the
+// client, or nil if its transfers are not limited.
+func transferLimitersOf(client *ssh.Client) *transferLimiters {
+	if c, ok := client.Conn.(*conn); ok {
+		return c.limiters
+	}
+	return nil
+}
+
+// newSFTPClient returns an SFTP client over a new session of the client,
+// whose transfers are bounded by the limiters of its connection, if any.
+func newSFTPClient(client *ssh.Client) (*sftp.Client, error) {
+	l := transferLimitersOf(client)
+	if l == nil {
+		return sftp.NewClient(client)
+	}
+
+	s, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.RequestSubsystem("sftp"); err != nil {
+		return nil, err
+	}
+	pw, err := s.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	pr, err := s.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	return sftp.NewClientPipe(&limitedReader{r: pr, l: l.down}, &limitedWriter{w: pw, l: l.up})
+}
+
+// uploadReader returns r, bounded by the upload limiter of the connection of
+// the client, if any.
+func uploadReader(client *ssh.Client, r io.Reader) io.Reader {
+	if l := transferLimitersOf(client); l != nil {
+		return &limitedReader{r: r, l: l.up}
+	}
+	return r
+}
+
+// A limitedReader reads at the rate of its limiter.
+type limitedReader struct {
+	r io.Reader
+	l *rate.Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > transferBurst {
+		p = p[:transferBurst]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		_ = r.l.WaitN(context.Background(), n)
+	}
+	return n, err
+}
+
+// A limitedWriter writes at the rate of its limiter.
+type limitedWriter struct {
+	w io.WriteCloser
+	l *rate.Limiter
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > transferBurst {
+			chunk = chunk[:transferBurst]
+		}
+		_ = w.l.WaitN(context.Background(), len(chunk))
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (w *limitedWriter) Close() error {
+	return w.w.Close()
+}
@@ -0,0 +1,154 @@
+package ssh
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+// exitCodeNoUser is the exit code of the lookup script if the user does not
+// exist.
+const exitCodeNoUser = 100
+
+// A User is the observed state of a POSIX user.
+type User struct {
+	Name   string
+	UID    int
+	GID    int
+	Home   string
+	Shell  string
+	Groups []string
+	Locked bool
+}
+
+// LookupUser returns the user with the supplied name, or nil if the user does
+// not exist. Whether the user is locked can only be observed with sudo.
+func LookupUser(ctx context.Context, x RemoteExecutor, name string, suEnabled bool) (*User, error) {
+	n := shellQuote(name)
+	cmd := "getent passwd " + n + " || exit 100; id -gn " + n + "; id -nG " + n + "; getent shadow " + n + " | cut -d: -f2 | cut -c1"
+	stdout, _, err := RunCommand(ctx, x, cmd, nil, suEnabled)
+	if ExitStatus(err) == exitCodeNoUser {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to look up user %s", name)
+	}
+
+	lines := strings.Split(stdout, "\n")
+	for len(lines) < 4 {
+		lines = append(lines, "")
+	}
+	fields := strings.Split(strings.TrimSpace(lines[0]), ":")
+	if len(fields) != 7 {
+		return nil, errors.Errorf("Unexpected passwd entry of user %s", name)
+	}
+	u := &User{Name: name, Home: fields[5], Shell: fields[6]}
+	if u.UID, err = strconv.Atoi(fields[2]); err != nil {
+		return nil, errors.Wrapf(err, "Unexpected uid of user %s", name)
+	}
+	if u.GID, err = strconv.Atoi(fields[3]); err != nil {
+		return nil, errors.Wrapf(err, "Unexpected gid of user %s", name)
+	}
+
+	primary := strings.TrimSpace(lines[1])
+	for _, g := range strings.Fields(lines[2]) {
+		if g != primary {
+			u.Groups = append(u.Groups, g)
+		}
+	}
+	sort.Strings(u.Groups)
+	u.Locked = strings.TrimSpace(lines[3]) == "!"
+	return u, nil
+}
+
+// CreateUser creates the user configured by p together with its home
+// directory.
+func CreateUser(ctx context.Context, x RemoteExecutor, p v1alpha1.UserAccountParameters) error {
+	args := []string{"useradd", "-m"}
+	if p.UID != nil {
+		args = append(args, "-u", strconv.Itoa(*p.UID))
+	}
+	if p.Home != "" {
+		args = append(args, "-d", p.Home)
+	}
+	if p.Shell != "" {
+		args = append(args, "-s", p.Shell)
+	}
+	if len(p.Groups) > 0 {
+		args = append(args, "-G", strings.Join(p.Groups, ","))
+	}
+	cmd := quoteArgs(append(args, p.Name))
+	if p.Locked != nil && *p.Locked {
+		cmd += " && " + quoteArgs([]string{"usermod", "-L", p.Name})
+	}
+	return runUserCommand(ctx, x, cmd, p.SudoEnabled, "Failed to create user %s", p.Name)
+}
+
+// ModifyUser applies the supplied arguments of usermod to the user.
+func ModifyUser(ctx context.Context, x RemoteExecutor, p v1alpha1.UserAccountParameters, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	cmd := quoteArgs(append(append([]string{"usermod"}, args...), p.Name))
+	return runUserCommand(ctx, x, cmd, p.SudoEnabled, "Failed to modify user %s", p.Name)
+}
+
+// DeleteUser deletes the user configured by p.
+func DeleteUser(ctx context.Context, x RemoteExecutor, p v1alpha1.UserAccountParameters) error {
+	args := []string{"userdel"}
+	if p.RemoveHome {
+		args = append(args, "-r")
+	}
+	return runUserCommand(ctx, x, quoteArgs(append(args, p.Name)), p.SudoEnabled, "Failed to delete user %s", p.Name)
+}
+
+// UserDrift returns the arguments of usermod that bring the user u to the
+// state configured by p. It returns no arguments if u is up to date.
+func UserDrift(p v1alpha1.UserAccountParameters, u *User) []string {
+	var args []string
+	if p.UID != nil && *p.UID != u.UID {
+		args = append(args, "-u", strconv.Itoa(*p.UID))
+	}
+	if p.Home != "" && p.Home != u.Home {
+		args = append(args, "-d", p.Home, "-m")
+	}
+	if p.Shell != "" && p.Shell != u.Shell {
+		args = append(args, "-s", p.Shell)
+	}
+	if len(p.Groups) > 0 {
+		groups := append([]string(nil), p.Groups...)
+		sort.Strings(groups)
+		if strings.Join(groups, ",") != strings.Join(u.Groups, ",") {
+			args = append(args, "-G", strings.Join(groups, ","))
+		}
+	}
+	if p.Locked != nil && *p.Locked != u.Locked {
+		if *p.Locked {
+			args = append(args, "-L")
+		} else {
+			args = append(args, "-U")
+		}
+	}
+	return args
+}
+
+func runUserCommand(ctx context.Context, x RemoteExecutor, cmd string, suEnabled bool, format string, name string) error {
+	if _, stderr, err := RunCommand(ctx, x, cmd, nil, suEnabled); err != nil {
+		return errors.Wrapf(err, format+": %s", name, strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+// quoteArgs joins the arguments to a command line of a POSIX shell.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
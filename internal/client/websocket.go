@@ -0,0 +1,102 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketConfig connects to the host through a WebSocket gateway.
+type WebSocketConfig struct {
+	// URL of the gateway, whose ws or wss scheme selects TLS.
+	URL string `json:"url"`
+	// Token authenticates to the gateway, no token is sent if it is empty.
+	Token string `json:"token,omitempty"`
+	// TokenHeader is the HTTP header carrying the token as it is. The token
+	// is sent as a bearer token in the Authorization header if it is empty.
+	TokenHeader string `json:"tokenHeader,omitempty"`
+}
+
+// dialWebSocket connects to the WebSocket gateway, which forwards the SSH
+// stream carried by binary messages to the host it chooses, as cloudflared
+// access ssh does. The handshake with the gateway is bounded by the supplied
+// timeout unless it is zero.
+func dialWebSocket(ctx context.Context, w *WebSocketConfig, timeout time.Duration) (net.Conn, error) {
+	location, err := url.Parse(w.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse the URL of the WebSocket gateway")
+	}
+	origin := *location
+	port := location.Port()
+	switch location.Scheme {
+	case "ws":
+		origin.Scheme = "http"
+		port = orDefault(port, "80")
+	case "wss":
+		origin.Scheme = "https"
+		port = orDefault(port, "443")
+	default:
+		return nil, errors.Errorf("the URL of the WebSocket gateway %s is neither a ws nor a wss URL", w.URL)
+	}
+	origin.Path, origin.RawQuery = "", ""
+
+	config := &websocket.Config{Location: location, Origin: &origin, Version: websocket.ProtocolVersionHybi13, Header: http.Header{}}
+	if w.Token != "" {
+		if w.TokenHeader == "" {
+			config.Header.Set("Authorization", "Bearer "+w.Token)
+		} else {
+			config.Header.Set(w.TokenHeader, w.Token)
+		}
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ws, err := config.DialContext(ctx)
+	if err != nil {
+		// The error of the dial names the URL with its query.
+		if de, ok := err.(*websocket.DialError); ok {
+			err = de.Err
+		}
+		return nil, errors.Wrapf(err, "cannot connect to the WebSocket gateway %s", redactURL(location))
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return &webSocketConn{Conn: ws, remote: gatewayAddr(net.JoinHostPort(location.Hostname(), port))}, nil
+}
+
+func orDefault(port, def string) string {
+	if port == "" {
+		return def
+	}
+	return port
+}
+
+// A webSocketConn is a connection to a host carried by a WebSocket gateway.
+type webSocketConn struct {
+	*websocket.Conn
+	remote net.Addr
+}
+
+// RemoteAddr returns the host and port of the gateway. The address of a
+// websocket.Conn is its URL, which host key callbacks cannot parse.
+func (c *webSocketConn) RemoteAddr() net.Addr { return c.remote }
+
+// A gatewayAddr is the host:port address of a WebSocket gateway.
+type gatewayAddr string
+
+func (a gatewayAddr) Network() string { return "tcp" }
+func (a gatewayAddr) String() string  { return string(a) }
+
+// redactURL returns the supplied URL without its user info and query, which
+// may hold secrets.
+func redactURL(u *url.URL) string {
+	r := *u
+	r.User, r.RawQuery = nil, ""
+	return r.String()
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+func TestWebSocket(t *testing.T) {
+	if testing.Short() {
+		t.Skip("connects through an in-process WebSocket gateway")
+	}
+
+	node := sshtest.NewServer(t)
+	bearer := sshtest.NewGateway(t, node, "Authorization", "Bearer s3cr3t")
+	access := sshtest.NewGateway(t, node, "Cf-Access-Token", "s3cr3t")
+
+	cases := map[string]struct {
+		reason string
+		ws     *sshv1alpha1.WebSocketConfig
+		err    bool
+	}{
+		"BearerToken": {
+			reason: "The token should be sent as a bearer token in the Authorization header.",
+			ws:     &sshv1alpha1.WebSocketConfig{URL: bearer.URL, Token: "s3cr3t"},
+		},
+		"TokenHeader": {
+			reason: "The token should be sent as it is in the token header.",
+			ws:     &sshv1alpha1.WebSocketConfig{URL: access.URL, Token: "s3cr3t", TokenHeader: "cf-access-token"},
+		},
+		"WrongToken": {
+			reason: "The connection should fail if the gateway rejects the token.",
+			ws:     &sshv1alpha1.WebSocketConfig{URL: bearer.URL, Token: "wrong"},
+			err:    true,
+		},
+		"NotWebSocketURL": {
+			reason: "Gateway URLs that are neither ws nor wss URLs should be rejected.",
+			ws:     &sshv1alpha1.WebSocketConfig{URL: "https://gateway.test"},
+			err:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			creds, err := sshv1alpha1.WithWebSocket(node.Credentials(), tc.ws)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := sshv1alpha1.NewSSHClient(context.Background(), creds)
+			if gotErr := err != nil; gotErr != tc.err {
+				t.Fatalf("\n%s\nNewSSHClient(...): %v, want error %t", tc.reason, err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			defer c.Close() // nolint: errcheck
+			s, err := c.NewSession()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := s.Output("echo ok")
+			if err != nil {
+				t.Fatalf("\n%s\nsession.Output(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff("ok\n", string(out)); diff != "" {
+				t.Errorf("\n%s\nsession.Output(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorizedkey
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"golang.org/x/crypto/ssh"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotAuthorizedKey = "managed resource is not an AuthorizedKey custom resource"
+	errParseKey         = "cannot parse public key"
+)
+
+// Setup adds a controller that reconciles AuthorizedKey managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.AuthorizedKeyGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.AuthorizedKeyGroupVersionKind),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			newServiceFn: o.NewServiceFn()})),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.AuthorizedKey{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.AuthorizedKeyGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	newServiceFn common.NewServiceFn
+}
+
+// Connect produces an ExternalClient for the host of the ProviderConfig
+// referenced by the AuthorizedKey.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.AuthorizedKey)
+	if !ok {
+		return nil, errors.New(errNotAuthorizedKey)
+	}
+
+	svc, err := common.Connect(ctx, c.kube, c.usage, cr, c.newServiceFn)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
+	return &external{service: sshv1alpha1.NewSSHExecutor(svc)}, nil
+}
+
+// An ExternalClient edits the authorized_keys file of an AuthorizedKey.
+type external struct {
+	// A 'client' used to connect to the external resource API.
+	service sshv1alpha1.RemoteExecutor
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.AuthorizedKey)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotAuthorizedKey)
+	}
+
+	key, path, content, err := c.load(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	cr.Status.AtProvider = apisv1alpha1.AuthorizedKeyObservation{
+		Path:        path,
+		Fingerprint: ssh.FingerprintSHA256(key.Key),
+	}
+
+	found := sshv1alpha1.FindAuthorizedKey(content, key)
+	if len(found) == 0 {
+		logger.Info(fmt.Sprintf("[%s] Observing, key is not authorized.", mg.GetName()))
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	cr.SetConditions(xpv1.Available())
+	upToDate := len(found) == 1 && found[0] == key.String()
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. Up to date: %t", mg.GetName(), upToDate))
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: upToDate}, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	logger := log.FromContext(ctx).WithName("[CREATE]")
+	logger.Info(fmt.Sprintf("[%s] Adding key...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.AuthorizedKey)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotAuthorizedKey)
+	}
+
+	return managed.ExternalCreation{}, c.set(ctx, cr, false)
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	logger.Info(fmt.Sprintf("[%s] Updating key...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.AuthorizedKey)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotAuthorizedKey)
+	}
+
+	return managed.ExternalUpdate{}, c.set(ctx, cr, false)
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Removing key...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.AuthorizedKey)
+	if !ok {
+		return errors.New(errNotAuthorizedKey)
+	}
+
+	return c.set(ctx, cr, true)
+}
+
+// Disconnect closes the connection to the host, if any.
+func (c *external) Disconnect(_ context.Context) error {
+	if c.service == nil {
+		return nil
+	}
+	return c.service.Close()
+}
+
+// load returns the desired entry of the AuthorizedKey together with the path
+// and the content of the authorized_keys file holding it.
+func (c *external) load(ctx context.Context, cr *apisv1alpha1.AuthorizedKey) (*sshv1alpha1.AuthorizedKey, string, []byte, error) {
+	key, err := desired(cr)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	p := cr.Spec.ForProvider
+	path, err := sshv1alpha1.AuthorizedKeysPath(ctx, c.service, p.User, p.SudoEnabled)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	content, err := sshv1alpha1.ReadAuthorizedKeys(ctx, c.service, path, p.SudoEnabled)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return key, path, content, nil
+}
+
+// set writes the entry of the AuthorizedKey to its authorized_keys file, or
+// removes it if remove is true.
+func (c *external) set(ctx context.Context, cr *apisv1alpha1.AuthorizedKey, remove bool) error {
+	key, path, content, err := c.load(ctx, cr)
+	if err != nil {
+		return err
+	}
+	p := cr.Spec.ForProvider
+	return sshv1alpha1.WriteAuthorizedKeys(ctx, c.service, path, sshv1alpha1.SetAuthorizedKey(content, key, remove), p.User, p.SudoEnabled)
+}
+
+// desired returns the entry of the AuthorizedKey. The options of the spec
+// replace any options embedded in the key.
+func desired(cr *apisv1alpha1.AuthorizedKey) (*sshv1alpha1.AuthorizedKey, error) {
+	key, err := sshv1alpha1.ParseAuthorizedKey(cr.Spec.ForProvider.Key)
+	if err != nil {
+		return nil, errors.Wrap(err, errParseKey)
+	}
+	key.Options = cr.Spec.ForProvider.Options
+	return key, nil
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorizedkey
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+const (
+	publicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIM4u6OQD6Hwhel8T5l1CmTNK4+v5BZWn/KNJrgLn4/EV alice@example.com"
+	otherKey  = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMJ5McJBDzBBuLVoF4u4Zg1cI+LvbVCdyN1m3TYvYdpd bob@example.com"
+)
+
+const keysPath = "/home/alice/.ssh/authorized_keys"
+
+func authorizedKey(options ...string) *apisv1alpha1.AuthorizedKey {
+	cr := &apisv1alpha1.AuthorizedKey{}
+	cr.SetName("test")
+	cr.Spec.ForProvider.User = "alice"
+	cr.Spec.ForProvider.Key = publicKey
+	cr.Spec.ForProvider.Options = options
+	return cr
+}
+
+// keysHost is a fake host holding the authorized_keys file of alice, which
+// does not exist if its content is empty.
+type keysHost struct {
+	*sshfake.Executor
+	content string
+}
+
+func newKeysHost(content string) *keysHost {
+	h := &keysHost{content: content}
+	h.Executor = &sshfake.Executor{MockRun: func(script string) (string, string, error) {
+		switch {
+		case script == "getent passwd 'alice' | cut -d: -f6":
+			return "/home/alice\n", "", nil
+		case strings.HasPrefix(script, "getent passwd "):
+			return "", "", nil
+		case strings.HasPrefix(script, "if [ -f '"+keysPath+"' ]"):
+			return h.content, "", nil
+		case strings.HasPrefix(script, "mkdir -p "):
+			// The keys never hold a single quote, so the quoted content
+			// ends at the first one.
+			_, content, _ := strings.Cut(script, "printf '%s' '")
+			h.content, _, _ = strings.Cut(content, "'")
+			return "", "", nil
+		}
+		return "", "", sshfake.ExitError(127)
+	}}
+	return h
+}
+
+func fingerprint(t *testing.T, line string) string {
+	t.Helper()
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ssh.FingerprintSHA256(key)
+}
+
+func TestObserve(t *testing.T) {
+	observed := apisv1alpha1.AuthorizedKeyObservation{Path: keysPath, Fingerprint: fingerprint(t, publicKey)}
+
+	type want struct {
+		o          managed.ExternalObservation
+		atProvider apisv1alpha1.AuthorizedKeyObservation
+		err        error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		content string
+		closed  bool
+		mg      resource.Managed
+		want    want
+	}{
+		"NotAuthorizedKey": {
+			reason: "We should return an error if the managed resource is not an AuthorizedKey.",
+			mg:     &fake.Managed{},
+			want: want{
+				err: errors.New(errNotAuthorizedKey),
+			},
+		},
+		"NoFile": {
+			reason: "A key should not exist if the user has no authorized_keys file.",
+			mg:     authorizedKey(),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}, atProvider: observed},
+		},
+		"NotAuthorized": {
+			reason:  "A key missing from the authorized_keys file should not exist.",
+			content: otherKey + "\n",
+			mg:      authorizedKey(),
+			want:    want{o: managed.ExternalObservation{ResourceExists: false}, atProvider: observed},
+		},
+		"UpToDate": {
+			reason:  "A key authorized once with the options of the spec should be up to date.",
+			content: otherKey + "\nno-pty " + publicKey + "\n",
+			mg:      authorizedKey("no-pty"),
+			want:    want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, atProvider: observed},
+		},
+		"OptionsDrift": {
+			reason:  "A key authorized with other options than those of the spec should need an update.",
+			content: `from="10.0.0.0/8" ` + publicKey + "\n",
+			mg:      authorizedKey(),
+			want:    want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, atProvider: observed},
+		},
+		"Duplicated": {
+			reason:  "A key authorized more than once should need an update.",
+			content: publicKey + "\n" + otherKey + "\n" + publicKey + "\n",
+			mg:      authorizedKey(),
+			want:    want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, atProvider: observed},
+		},
+		"UnknownUser": {
+			reason: "We should return an error if the user does not exist on the host.",
+			mg: func() *apisv1alpha1.AuthorizedKey {
+				cr := authorizedKey()
+				cr.Spec.ForProvider.User = "bob"
+				return cr
+			}(),
+			want: want{err: errors.New("Unknown user bob")},
+		},
+		"RemoteError": {
+			reason: "We should return an error if the authorized_keys file cannot be looked up on the host.",
+			closed: true,
+			mg:     authorizedKey(),
+			want:   want{err: errors.Wrap(errors.New("connection closed"), "Failed to look up home directory")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := newKeysHost(tc.content)
+			if tc.closed {
+				_ = h.Close()
+			}
+			e := external{service: h}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if cr, ok := tc.mg.(*apisv1alpha1.AuthorizedKey); ok {
+				if diff := cmp.Diff(tc.want.atProvider, cr.Status.AtProvider); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want status, +got status:\n%s\n", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type want struct {
+		content string
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		content string
+		closed  bool
+		want    want
+	}{
+		"Authorized": {
+			reason:  "Every entry of the key should be removed from the authorized_keys file, keeping the other keys.",
+			content: "no-pty " + publicKey + "\n" + otherKey + "\n" + publicKey + "\n",
+			want:    want{content: otherKey + "\n"},
+		},
+		"RemoteError": {
+			reason:  "We should return an error if the authorized_keys file cannot be looked up on the host.",
+			content: publicKey + "\n",
+			closed:  true,
+			want: want{
+				content: publicKey + "\n",
+				err:     errors.Wrap(errors.New("connection closed"), "Failed to look up home directory"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := newKeysHost(tc.content)
+			if tc.closed {
+				_ = h.Close()
+			}
+			e := external{service: h}
+			err := e.Delete(context.Background(), authorizedKey())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.content, h.content); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want authorized_keys, +got authorized_keys:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDesired(t *testing.T) {
+	type want struct {
+		line string
+		err  bool
+	}
+
+	cases := map[string]struct {
+		reason  string
+		key     string
+		options []string
+		want    want
+	}{
+		"Plain": {
+			reason: "A key without options should be rendered unchanged.",
+			key:    publicKey,
+			want:   want{line: publicKey},
+		},
+		"Options": {
+			reason:  "The options of the spec should replace the options embedded in the key.",
+			key:     `no-pty ` + publicKey,
+			options: []string{`from="10.0.0.0/8"`, "no-agent-forwarding"},
+			want:    want{line: `from="10.0.0.0/8",no-agent-forwarding ` + publicKey},
+		},
+		"Invalid": {
+			reason: "We should return an error if the key cannot be parsed.",
+			key:    "ssh-ed25519 invalid",
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &apisv1alpha1.AuthorizedKey{}
+			cr.Spec.ForProvider.Key = tc.key
+			cr.Spec.ForProvider.Options = tc.options
+			got, err := desired(cr)
+			if (err != nil) != tc.want.err {
+				t.Fatalf("\n%s\ndesired(...): unexpected error: %v\n", tc.reason, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.line, got.String()); diff != "" {
+				t.Errorf("\n%s\ndesired(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSetAuthorizedKey(t *testing.T) {
+	key, err := sshv1alpha1.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		reason  string
+		content string
+		remove  bool
+		want    string
+	}{
+		"Append": {
+			reason:  "A key that is not authorized yet should be appended.",
+			content: "# managed keys\n" + otherKey + "\n",
+			want:    "# managed keys\n" + otherKey + "\n" + publicKey + "\n",
+		},
+		"Empty": {
+			reason: "A key should be written to an empty file.",
+			want:   publicKey + "\n",
+		},
+		"Replace": {
+			reason:  "All entries of the key should be replaced by a single entry in place.",
+			content: "no-pty " + publicKey + "\n" + otherKey + "\n" + publicKey + " old\n",
+			want:    publicKey + "\n" + otherKey + "\n",
+		},
+		"Remove": {
+			reason:  "All entries of the key should be removed, keeping the other entries.",
+			content: otherKey + "\n" + "no-pty " + publicKey + "\n",
+			remove:  true,
+			want:    otherKey + "\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := sshv1alpha1.SetAuthorizedKey([]byte(tc.content), key, tc.remove)
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("\n%s\nSetAuthorizedKey(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
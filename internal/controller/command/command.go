@@ -0,0 +1,231 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+	"github.com/crossplane/provider-ssh/internal/policy"
+	"github.com/crossplane/provider-ssh/internal/signature"
+)
+
+const (
+	errNotCommand = "managed resource is not a Command custom resource"
+	errRunCheck   = "cannot run check command"
+
+	errVerifySignature = "cannot verify the signature of the commands"
+	errPolicy          = "cannot execute commands"
+)
+
+// Setup adds a controller that reconciles Command managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.CommandGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.CommandGroupVersionKind),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			verifier:     o.ScriptVerifier,
+			policy:       o.ScriptPolicy,
+			newServiceFn: o.NewServiceFn()})),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithCriticalAnnotationUpdater(common.NewStatusPreservingUpdater(mgr.GetClient())),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.Command{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.CommandGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	verifier     *signature.Verifier
+	policy       *policy.Policy
+	newServiceFn common.NewServiceFn
+}
+
+// Connect produces an ExternalClient for the host of the ProviderConfig
+// referenced by the Command.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.Command)
+	if !ok {
+		return nil, errors.New(errNotCommand)
+	}
+
+	if deleting(cr) || (meta.WasDeleted(cr) && cr.Spec.ForProvider.Delete == "") {
+		logger.Info(fmt.Sprintf("[%s] Resource is deleted. Skip the connection.", mg.GetName()))
+		return &external{}, nil
+	}
+
+	if err := c.verifier.Verify(cr); err != nil {
+		return nil, errors.Wrap(err, errVerifySignature)
+	}
+
+	if err := c.policy.Evaluate(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errPolicy)
+	}
+
+	svc, err := common.Connect(ctx, c.kube, c.usage, cr, c.newServiceFn)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
+	return &external{service: sshv1alpha1.NewSSHExecutor(svc)}, nil
+}
+
+// An ExternalClient runs the commands of a Command over an ssh session.
+type external struct {
+	// A 'client' used to connect to the external resource API.
+	service sshv1alpha1.RemoteExecutor
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.Command)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotCommand)
+	}
+
+	if deleting(cr) || (meta.WasDeleted(cr) && cr.Spec.ForProvider.Delete == "") {
+		logger.Info(fmt.Sprintf("[%s] Observing skipped. Resource is deleted.", mg.GetName()))
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	created := !meta.GetExternalCreateSucceeded(cr).IsZero() || cr.Spec.ForProvider.Create == ""
+	p := cr.Spec.ForProvider
+	if p.Check == "" {
+		logger.Info(fmt.Sprintf("[%s] Observing, no check command.", mg.GetName()))
+		if created {
+			cr.SetConditions(xpv1.Available())
+		}
+		return managed.ExternalObservation{ResourceExists: created, ResourceUpToDate: true}, nil
+	}
+
+	err := c.run(ctx, cr, p.Check)
+	if err == nil {
+		logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. Update the status.", mg.GetName()))
+		cr.SetConditions(xpv1.Available())
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+	if !sshv1alpha1.IsExitError(err) {
+		return managed.ExternalObservation{}, errors.Wrap(err, errRunCheck)
+	}
+
+	logger.Info(fmt.Sprintf("[%s] Observing failed. Exit code: %d", mg.GetName(), cr.Status.AtProvider.StatusCode))
+	cr.SetConditions(xpv1.Unavailable().WithMessage(fmt.Sprintf("check command failed with exit code %d", cr.Status.AtProvider.StatusCode)))
+	return managed.ExternalObservation{ResourceExists: created, ResourceUpToDate: false}, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	logger := log.FromContext(ctx).WithName("[CREATE]")
+	logger.Info(fmt.Sprintf("[%s] Running create command...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.Command)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotCommand)
+	}
+
+	if err := c.run(ctx, cr, cr.Spec.ForProvider.Create); err != nil {
+		cr.SetConditions(xpv1.ReconcileError(errors.Wrap(err, "Create Command failed.")))
+		return managed.ExternalCreation{}, err
+	}
+	return managed.ExternalCreation{}, nil
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	logger.Info(fmt.Sprintf("[%s] Running update command...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.Command)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotCommand)
+	}
+
+	cmd := cr.Spec.ForProvider.Update
+	if cmd == "" {
+		cmd = cr.Spec.ForProvider.Create
+	}
+	if err := c.run(ctx, cr, cmd); err != nil {
+		cr.SetConditions(xpv1.ReconcileError(errors.Wrap(err, "Update Command failed.")))
+		return managed.ExternalUpdate{}, err
+	}
+	return managed.ExternalUpdate{}, nil
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Running delete command...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.Command)
+	if !ok {
+		return errors.New(errNotCommand)
+	}
+
+	return c.run(ctx, cr, cr.Spec.ForProvider.Delete)
+}
+
+// Disconnect closes the connection to the host, if any.
+func (c *external) Disconnect(_ context.Context) error {
+	if c.service == nil {
+		return nil
+	}
+	return c.service.Close()
+}
+
+// run executes the command and records its output in the status.
+func (c *external) run(ctx context.Context, cr *apisv1alpha1.Command, cmd string) error {
+	if cmd == "" {
+		return nil
+	}
+	p := cr.Spec.ForProvider
+	stdout, stderr, err := sshv1alpha1.RunCommand(ctx, c.service, cmd, p.Variables, p.SudoEnabled)
+	cr.Status.AtProvider.Stdout = stdout
+	cr.Status.AtProvider.Stderr = stderr
+	cr.Status.AtProvider.StatusCode = sshv1alpha1.ExitStatus(err)
+	return err
+}
+
+// deleting reports whether the delete command of the Command already ran.
+func deleting(cr *apisv1alpha1.Command) bool {
+	cd := cr.GetCondition(xpv1.TypeReady)
+	return cd.Status == "False" && cd.Reason == xpv1.ReasonDeleting
+}
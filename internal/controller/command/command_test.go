@@ -0,0 +1,268 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+type commandModifier func(*apisv1alpha1.Command)
+
+func withCreate(cmd string) commandModifier {
+	return func(cr *apisv1alpha1.Command) { cr.Spec.ForProvider.Create = cmd }
+}
+
+func withCheck(cmd string) commandModifier {
+	return func(cr *apisv1alpha1.Command) { cr.Spec.ForProvider.Check = cmd }
+}
+
+func withDelete(cmd string) commandModifier {
+	return func(cr *apisv1alpha1.Command) { cr.Spec.ForProvider.Delete = cmd }
+}
+
+func withDeleted() commandModifier {
+	return func(cr *apisv1alpha1.Command) {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+	}
+}
+
+func withConditions(c ...xpv1.Condition) commandModifier {
+	return func(cr *apisv1alpha1.Command) { cr.SetConditions(c...) }
+}
+
+func withCreateSucceeded() commandModifier {
+	return func(cr *apisv1alpha1.Command) { meta.SetExternalCreateSucceeded(cr, time.Now()) }
+}
+
+func command(m ...commandModifier) *apisv1alpha1.Command {
+	cr := &apisv1alpha1.Command{}
+	cr.SetName("test")
+	for _, f := range m {
+		f(cr)
+	}
+	return cr
+}
+
+// checkHost returns a fake host whose check command prints the supplied
+// output and exits with the supplied error.
+func checkHost(stdout string, err error) *sshfake.Executor {
+	return &sshfake.Executor{MockRun: func(_ string) (string, string, error) {
+		return stdout, "", err
+	}}
+}
+
+// closedHost returns a fake host whose connection is closed.
+func closedHost() *sshfake.Executor {
+	x := &sshfake.Executor{}
+	_ = x.Close()
+	return x
+}
+
+func TestObserve(t *testing.T) {
+	type want struct {
+		o          managed.ExternalObservation
+		atProvider apisv1alpha1.CommandObservation
+		scripts    []string
+		err        error
+	}
+
+	cases := map[string]struct {
+		reason string
+		host   *sshfake.Executor
+		mg     resource.Managed
+		want   want
+	}{
+		"NotCommand": {
+			reason: "We should return an error if the managed resource is not a Command.",
+			mg:     &fake.Managed{},
+			want:   want{err: errors.New(errNotCommand)},
+		},
+		"DeletedWithoutDeleteCommand": {
+			reason: "A deleted Command without a delete command should be reported as gone.",
+			mg:     command(withCreate("touch /tmp/a"), withCreateSucceeded(), withDeleted()),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"Deleting": {
+			reason: "A Command whose delete command already ran should be reported as gone.",
+			mg:     command(withDelete("rm -f /tmp/a"), withDeleted(), withConditions(xpv1.Deleting())),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"NotCreated": {
+			reason: "A Command without a check command should not exist before its create command succeeded.",
+			mg:     command(withCreate("touch /tmp/a")),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false, ResourceUpToDate: true}},
+		},
+		"Created": {
+			reason: "A Command without a check command should exist and be up to date once its create command succeeded.",
+			mg:     command(withCreate("touch /tmp/a"), withCreateSucceeded()),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}},
+		},
+		"UpToDate": {
+			reason: "A Command whose check command succeeds on the host should exist and be up to date.",
+			host:   checkHost("present\n", nil),
+			mg:     command(withCreate("touch /tmp/a"), withCheck("test -f /tmp/a && echo present")),
+			want: want{
+				o:          managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				atProvider: apisv1alpha1.CommandObservation{Stdout: "present\n"},
+				scripts:    []string{"test -f /tmp/a && echo present"},
+			},
+		},
+		"Drift": {
+			reason: "A created Command whose check command fails on the host should exist but need an update.",
+			host:   checkHost("", sshfake.ExitError(1)),
+			mg:     command(withCreate("touch /tmp/a"), withCreateSucceeded(), withCheck("test -f /tmp/a")),
+			want: want{
+				o:          managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				atProvider: apisv1alpha1.CommandObservation{StatusCode: 1},
+				scripts:    []string{"test -f /tmp/a"},
+			},
+		},
+		"NotCreatedDrift": {
+			reason: "A Command whose check command fails on the host should not exist before its create command succeeded.",
+			host:   checkHost("", sshfake.ExitError(2)),
+			mg:     command(withCreate("touch /tmp/a"), withCheck("test -f /tmp/a")),
+			want: want{
+				o:          managed.ExternalObservation{ResourceExists: false, ResourceUpToDate: false},
+				atProvider: apisv1alpha1.CommandObservation{StatusCode: 2},
+				scripts:    []string{"test -f /tmp/a"},
+			},
+		},
+		"RemoteError": {
+			reason: "We should return an error if the check command cannot be run on the host.",
+			host:   closedHost(),
+			mg:     command(withCreate("touch /tmp/a"), withCheck("test -f /tmp/a")),
+			want: want{
+				atProvider: apisv1alpha1.CommandObservation{StatusCode: 1},
+				err:        errors.Wrap(errors.New("connection closed"), errRunCheck),
+			},
+		},
+		"Deleted": {
+			reason: "A deleted Command whose check command succeeds should exist until its delete command ran.",
+			host:   checkHost("", nil),
+			mg:     command(withCheck("test -f /tmp/a"), withDelete("rm /tmp/a"), withCreateSucceeded(), withDeleted()),
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				scripts: []string{"test -f /tmp/a"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := tc.host
+			if x == nil {
+				x = &sshfake.Executor{}
+			}
+			e := external{service: x}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if cr, ok := tc.mg.(*apisv1alpha1.Command); ok {
+				if diff := cmp.Diff(tc.want.atProvider, cr.Status.AtProvider); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want status, +got status:\n%s\n", tc.reason, diff)
+				}
+			}
+			if diff := cmp.Diff(tc.want.scripts, x.Scripts()); diff != "" {
+				t.Errorf("\n%s\nScripts run: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type want struct {
+		scripts []string
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		host   *sshfake.Executor
+		mg     resource.Managed
+		want   want
+	}{
+		"DeleteCommand": {
+			reason: "The delete command should be run on the host, with its variables replaced.",
+			host:   checkHost("", nil),
+			mg: command(withDelete("rm {{FILE}}"), withDeleted(), func(cr *apisv1alpha1.Command) {
+				cr.Spec.ForProvider.Variables = []apisv1alpha1.Variable{{Name: "FILE", Value: "/tmp/a"}}
+			}),
+			want: want{scripts: []string{"rm /tmp/a"}},
+		},
+		"Sudo": {
+			reason: "The delete command should be run through sudo if it is enabled.",
+			host:   checkHost("", nil),
+			mg: command(withDelete("rm /tmp/a"), withDeleted(), func(cr *apisv1alpha1.Command) {
+				cr.Spec.ForProvider.SudoEnabled = true
+			}),
+			want: want{scripts: []string{"sudo sh -c 'rm /tmp/a'"}},
+		},
+		"NoDeleteCommand": {
+			reason: "Nothing should be run on the host if the Command has no delete command.",
+			host:   checkHost("", nil),
+			mg:     command(withCreate("touch /tmp/a"), withDeleted()),
+		},
+		"DeleteFailed": {
+			reason: "We should return the error of a delete command failing on the host.",
+			host:   checkHost("", sshfake.ExitError(1)),
+			mg:     command(withDelete("rm /tmp/a"), withDeleted()),
+			want:   want{scripts: []string{"rm /tmp/a"}, err: sshfake.ExitError(1)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: tc.host}
+			err := e.Delete(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.scripts, tc.host.Scripts()); diff != "" {
+				t.Errorf("\n%s\nScripts run: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+const (
+	errFmtNoClaimNamespace    = "ProviderConfig %s is restricted to the claims of namespaces %s, the managed resource has no %s label"
+	errFmtNamespaceNotAllowed = "ProviderConfig %s does not allow the claims of namespace %s"
+	errFmtResourceNotAllowed  = "ProviderConfig %s does not allow the managed resource, its labels are not selected by the allowedResourceSelector"
+	errFmtInvalidSelector     = "ProviderConfig %s has an invalid allowedResourceSelector"
+	errFmtQuotaExceeded       = "ProviderConfig %s is already used by its maxManagedResources of %d managed resources"
+	errListPCUsages           = "cannot list ProviderConfig usages"
+)
+
+// AuthorizeProviderConfig returns an error unless the managed resource is
+// allowed to use the ProviderConfig, by its namespaces and its resource
+// selector.
+func AuthorizeProviderConfig(pc *apisv1alpha1.ProviderConfig, mg metav1.Object) error {
+	lbls := mg.GetLabels()
+	if allowed := pc.Spec.AllowedNamespaces; len(allowed) > 0 {
+		ns, ok := lbls[apisv1alpha1.LabelKeyClaimNamespace]
+		if !ok {
+			return errors.Errorf(errFmtNoClaimNamespace, pc.GetName(), strings.Join(allowed, ", "), apisv1alpha1.LabelKeyClaimNamespace)
+		}
+		if !contains(allowed, ns) {
+			return errors.Errorf(errFmtNamespaceNotAllowed, pc.GetName(), ns)
+		}
+	}
+	if pc.Spec.AllowedResourceSelector != nil {
+		sel, err := metav1.LabelSelectorAsSelector(pc.Spec.AllowedResourceSelector)
+		if err != nil {
+			return errors.Wrapf(err, errFmtInvalidSelector, pc.GetName())
+		}
+		if !sel.Matches(labels.Set(lbls)) {
+			return errors.Errorf(errFmtResourceNotAllowed, pc.GetName())
+		}
+	}
+	return nil
+}
+
+// CheckQuota returns an error if the managed resource does not use the
+// ProviderConfig yet and the ProviderConfig is already used by its
+// maxManagedResources, as tracked by its ProviderConfigUsages. Deleted
+// managed resources are never rejected, so that they can be cleaned up.
+func CheckQuota(ctx context.Context, kube client.Reader, pc *apisv1alpha1.ProviderConfig, mg resource.Managed) error {
+	max := pc.Spec.MaxManagedResources
+	if max == nil || meta.WasDeleted(mg) {
+		return nil
+	}
+	l := &apisv1alpha1.ProviderConfigUsageList{}
+	if err := kube.List(ctx, l, client.MatchingLabels{xpv1.LabelKeyProviderName: pc.GetName()}); err != nil {
+		return errors.Wrap(err, errListPCUsages)
+	}
+	for _, u := range l.Items {
+		// ProviderConfigUsages are named after the UID of their resource.
+		if mg.GetUID() != "" && u.GetName() == string(mg.GetUID()) {
+			return nil
+		}
+	}
+	if int64(len(l.Items)) >= *max {
+		return errors.Errorf(errFmtQuotaExceeded, pc.GetName(), *max)
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
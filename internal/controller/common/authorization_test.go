@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+func TestAuthorizeProviderConfig(t *testing.T) {
+	providerConfig := func(spec apisv1alpha1.ProviderConfigSpec) *apisv1alpha1.ProviderConfig {
+		pc := &apisv1alpha1.ProviderConfig{Spec: spec}
+		pc.SetName("team-a")
+		return pc
+	}
+	managed := func(lbls map[string]string) *apisv1alpha1.Script {
+		cr := &apisv1alpha1.Script{}
+		cr.SetLabels(lbls)
+		return cr
+	}
+
+	cases := map[string]struct {
+		reason string
+		pc     *apisv1alpha1.ProviderConfig
+		mg     metav1.Object
+		want   error
+	}{
+		"Unrestricted": {
+			reason: "A ProviderConfig without restrictions should allow every managed resource.",
+			pc:     providerConfig(apisv1alpha1.ProviderConfigSpec{}),
+			mg:     managed(nil),
+		},
+		"AllowedNamespace": {
+			reason: "The managed resources of claims of an allowed namespace should be allowed.",
+			pc:     providerConfig(apisv1alpha1.ProviderConfigSpec{AllowedNamespaces: []string{"team-a", "team-b"}}),
+			mg:     managed(map[string]string{apisv1alpha1.LabelKeyClaimNamespace: "team-b"}),
+		},
+		"NamespaceNotAllowed": {
+			reason: "The managed resources of claims of other namespaces should be rejected.",
+			pc:     providerConfig(apisv1alpha1.ProviderConfigSpec{AllowedNamespaces: []string{"team-a"}}),
+			mg:     managed(map[string]string{apisv1alpha1.LabelKeyClaimNamespace: "team-b"}),
+			want:   errors.Errorf(errFmtNamespaceNotAllowed, "team-a", "team-b"),
+		},
+		"NoClaimNamespace": {
+			reason: "Managed resources that are not composed for a claim should be rejected by a ProviderConfig restricted to namespaces.",
+			pc:     providerConfig(apisv1alpha1.ProviderConfigSpec{AllowedNamespaces: []string{"team-a", "team-b"}}),
+			mg:     managed(nil),
+			want:   errors.Errorf(errFmtNoClaimNamespace, "team-a", "team-a, team-b", apisv1alpha1.LabelKeyClaimNamespace),
+		},
+		"Selected": {
+			reason: "Managed resources selected by the allowedResourceSelector should be allowed.",
+			pc: providerConfig(apisv1alpha1.ProviderConfigSpec{
+				AllowedNamespaces:       []string{"team-a"},
+				AllowedResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "batch"}},
+			}),
+			mg: managed(map[string]string{apisv1alpha1.LabelKeyClaimNamespace: "team-a", "tier": "batch"}),
+		},
+		"NotSelected": {
+			reason: "Managed resources not selected by the allowedResourceSelector should be rejected.",
+			pc: providerConfig(apisv1alpha1.ProviderConfigSpec{
+				AllowedResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "batch"}},
+			}),
+			mg:   managed(map[string]string{"tier": "web"}),
+			want: errors.Errorf(errFmtResourceNotAllowed, "team-a"),
+		},
+		"InvalidSelector": {
+			reason: "An invalid allowedResourceSelector should reject every managed resource.",
+			pc: providerConfig(apisv1alpha1.ProviderConfigSpec{
+				AllowedResourceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "tier", Operator: "Matches"}}},
+			}),
+			mg:   managed(nil),
+			want: errors.Wrapf(errors.New(`"Matches" is not a valid label selector operator`), errFmtInvalidSelector, "team-a"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := AuthorizeProviderConfig(tc.pc, tc.mg)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nAuthorizeProviderConfig(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	errBoom := errors.New("boom")
+	quota := int64(2)
+	pc := &apisv1alpha1.ProviderConfig{Spec: apisv1alpha1.ProviderConfigSpec{MaxManagedResources: &quota}}
+	pc.SetName("appliance")
+	usages := func(err error, uids ...string) client.Reader {
+		return &test.MockClient{MockList: func(_ context.Context, obj client.ObjectList, opts ...client.ListOption) error {
+			if err != nil {
+				return err
+			}
+			lo := &client.ListOptions{}
+			lo.ApplyOptions(opts)
+			if !lo.LabelSelector.Matches(labels.Set{xpv1.LabelKeyProviderName: "appliance"}) {
+				return errors.New("usages not listed by ProviderConfig")
+			}
+			l := obj.(*apisv1alpha1.ProviderConfigUsageList)
+			for _, uid := range uids {
+				u := apisv1alpha1.ProviderConfigUsage{}
+				u.SetName(uid)
+				l.Items = append(l.Items, u)
+			}
+			return nil
+		}}
+	}
+	managed := func(uid string, deleted bool) *apisv1alpha1.Script {
+		cr := &apisv1alpha1.Script{}
+		cr.SetUID(types.UID(uid))
+		if deleted {
+			cr.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+		}
+		return cr
+	}
+
+	cases := map[string]struct {
+		reason string
+		kube   client.Reader
+		pc     *apisv1alpha1.ProviderConfig
+		mg     resource.Managed
+		want   error
+	}{
+		"NoQuota": {
+			reason: "A ProviderConfig without maxManagedResources should allow any number of managed resources.",
+			kube:   usages(errBoom),
+			pc:     &apisv1alpha1.ProviderConfig{},
+			mg:     managed("new", false),
+		},
+		"BelowQuota": {
+			reason: "A new managed resource should be allowed below the maxManagedResources.",
+			kube:   usages(nil, "a"),
+			pc:     pc,
+			mg:     managed("new", false),
+		},
+		"QuotaExceeded": {
+			reason: "A new managed resource should be rejected once the maxManagedResources are used.",
+			kube:   usages(nil, "a", "b"),
+			pc:     pc,
+			mg:     managed("new", false),
+			want:   errors.Errorf(errFmtQuotaExceeded, "appliance", 2),
+		},
+		"Tracked": {
+			reason: "A managed resource already using the ProviderConfig should be allowed, even if the maxManagedResources were lowered.",
+			kube:   usages(nil, "a", "b", "c"),
+			pc:     pc,
+			mg:     managed("b", false),
+		},
+		"Deleted": {
+			reason: "A deleted managed resource should be allowed to clean up.",
+			kube:   usages(nil, "a", "b"),
+			pc:     pc,
+			mg:     managed("new", true),
+		},
+		"ListError": {
+			reason: "Errors listing the usages of the ProviderConfig should be returned.",
+			kube:   usages(errBoom),
+			pc:     pc,
+			mg:     managed("new", false),
+			want:   errors.Wrap(errBoom, errListPCUsages),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := CheckQuota(context.Background(), tc.kube, tc.pc, tc.mg)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nCheckQuota(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	errTrackPCUsage = "cannot track ProviderConfig usage"
+	errGetPC        = "cannot get ProviderConfig"
+	errGetCreds     = "cannot get credentials"
+	errNewClient    = "cannot create new Service"
+	errNoHostCreds  = "host has neither credentials nor a providerConfigRef"
+	errSetEndpoint  = "cannot set endpoint of host"
+	errSetUsername  = "cannot set username"
+	errSetTransfer  = "cannot set transfer settings"
+	errSetAlias     = "cannot set alias"
+	errGetIdentity  = "cannot get Teleport identity"
+	errSetTeleport  = "cannot set Teleport settings"
+	errGetToken     = "cannot get WebSocket gateway token"
+	errSetWebSocket = "cannot set WebSocket settings"
+	errGetKPToken   = "cannot get key provisioner token"
+	errSetKP        = "cannot set key provisioner settings"
+)
+
+// A NewServiceFn connects to the host identified by the supplied credentials.
+type NewServiceFn func(ctx context.Context, creds []byte) (*ssh.Client, error)
+
+// Connect tracks that the managed resource is using its ProviderConfig and
+// connects to the host identified by the credentials of the ProviderConfig.
+func Connect(ctx context.Context, kube client.Client, usage resource.Tracker, mg resource.Managed, newServiceFn NewServiceFn) (*ssh.Client, error) {
+	data, err := Credentials(ctx, kube, usage, mg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClient(sshv1alpha1.WithProviderConfig(ctx, mg.GetProviderConfigReference().Name), newServiceFn, data)
+}
+
+// Credentials tracks that the managed resource is using its ProviderConfig and
+// returns the credentials of the ProviderConfig.
+func Credentials(ctx context.Context, kube client.Client, usage resource.Tracker, mg resource.Managed) ([]byte, error) {
+	pc := &apisv1alpha1.ProviderConfig{}
+	if err := kube.Get(ctx, types.NamespacedName{Name: mg.GetProviderConfigReference().Name}, pc); err != nil {
+		return nil, errors.Wrap(err, errGetPC)
+	}
+	if err := AuthorizeProviderConfig(pc, mg); err != nil {
+		return nil, err
+	}
+	if err := CheckQuota(ctx, kube, pc, mg); err != nil {
+		return nil, err
+	}
+	if err := usage.Track(ctx, mg); err != nil {
+		return nil, errors.Wrap(err, errTrackPCUsage)
+	}
+
+	return providerCredentials(ctx, kube, pc)
+}
+
+// providerCredentials returns the credentials of the ProviderConfig, with the
+// endpoint of its Service, its transfer settings, alias, Teleport, WebSocket
+// and key provisioner settings.
+func providerCredentials(ctx context.Context, kube client.Client, pc *apisv1alpha1.ProviderConfig) ([]byte, error) {
+	data, err := ExtractCredentials(ctx, kube, pc.Spec.Credentials)
+	if err != nil {
+		return nil, errors.Wrap(err, errGetCreds)
+	}
+	if svc := pc.Spec.Service; svc != nil {
+		if data, err = resolveService(ctx, kube, data, svc); err != nil {
+			return nil, err
+		}
+	}
+	if data, err = sshv1alpha1.WithTransfer(data, pc.Spec.Transfer); err != nil {
+		return nil, errors.Wrap(err, errSetTransfer)
+	}
+	if data, err = sshv1alpha1.WithAlias(data, pc.Spec.Alias); err != nil {
+		return nil, errors.Wrap(err, errSetAlias)
+	}
+	if t := pc.Spec.Teleport; t != nil {
+		identity, err := credentialValue(ctx, kube, &t.Identity)
+		if err != nil {
+			return nil, errors.Wrap(err, errGetIdentity)
+		}
+		if data, err = sshv1alpha1.WithTeleport(data, &sshv1alpha1.TeleportConfig{Proxy: t.ProxyAddress, Cluster: t.Cluster, Identity: identity}); err != nil {
+			return nil, errors.Wrap(err, errSetTeleport)
+		}
+	}
+	if w := pc.Spec.WebSocket; w != nil {
+		token, err := optionalValue(ctx, kube, w.Token)
+		if err != nil {
+			return nil, errors.Wrap(err, errGetToken)
+		}
+		if data, err = sshv1alpha1.WithWebSocket(data, &sshv1alpha1.WebSocketConfig{URL: w.URL, Token: token, TokenHeader: w.TokenHeader}); err != nil {
+			return nil, errors.Wrap(err, errSetWebSocket)
+		}
+	}
+	k := pc.Spec.KeyProvisioner
+	if k == nil {
+		return data, nil
+	}
+	token, err := optionalValue(ctx, kube, k.Webhook.Token)
+	if err != nil {
+		return nil, errors.Wrap(err, errGetKPToken)
+	}
+	data, err = sshv1alpha1.WithKeyProvisioner(data, &sshv1alpha1.KeyProvisionerConfig{WebhookURL: k.Webhook.URL, Token: token})
+	return data, errors.Wrap(err, errSetKP)
+}
+
+// optionalValue returns the value of the supplied credential field, empty if
+// it is nil.
+func optionalValue(ctx context.Context, kube client.Client, v *apisv1alpha1.CredentialValue) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	return credentialValue(ctx, kube, v)
+}
+
+// Dial connects the managed resource to the host identified by the
+// credentials of the supplied ProviderConfig, after applying the supplied
+// overrides to the credentials.
+func Dial(ctx context.Context, kube client.Client, mg resource.Managed, pc *apisv1alpha1.ProviderConfig, newServiceFn NewServiceFn, o ...CredentialsOverride) (*ssh.Client, error) {
+	if err := AuthorizeProviderConfig(pc, mg); err != nil {
+		return nil, err
+	}
+	data, err := providerCredentials(ctx, kube, pc)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = override(data, o); err != nil {
+		return nil, err
+	}
+
+	return newClient(sshv1alpha1.WithProviderConfig(ctx, pc.GetName()), newServiceFn, data)
+}
+
+// DialHost connects the managed resource to the supplied inventory Host,
+// using either its own credentials or those of the ProviderConfig it
+// references, after applying the supplied overrides to the credentials.
+func DialHost(ctx context.Context, kube client.Client, mg resource.Managed, h *apisv1alpha1.Host, newServiceFn NewServiceFn, o ...CredentialsOverride) (*ssh.Client, error) {
+	var data []byte
+	var err error
+	switch {
+	case h.Spec.Credentials != nil:
+		if data, err = ExtractCredentials(ctx, kube, *h.Spec.Credentials); err != nil {
+			return nil, errors.Wrap(err, errGetCreds)
+		}
+	case h.Spec.ProviderConfigRef != nil:
+		ctx = sshv1alpha1.WithProviderConfig(ctx, h.Spec.ProviderConfigRef.Name)
+		pc := &apisv1alpha1.ProviderConfig{}
+		if err := kube.Get(ctx, types.NamespacedName{Name: h.Spec.ProviderConfigRef.Name}, pc); err != nil {
+			return nil, errors.Wrap(err, errGetPC)
+		}
+		if err := AuthorizeProviderConfig(pc, mg); err != nil {
+			return nil, err
+		}
+		if data, err = providerCredentials(ctx, kube, pc); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New(errNoHostCreds)
+	}
+
+	port := 22
+	if h.Spec.Port != nil {
+		port = *h.Spec.Port
+	}
+	if data, err = sshv1alpha1.WithEndpoint(data, h.Spec.Address, port); err != nil {
+		return nil, errors.Wrap(err, errSetEndpoint)
+	}
+	// The name of an inventory Host is its alias.
+	if data, err = sshv1alpha1.WithAlias(data, h.GetName()); err != nil {
+		return nil, errors.Wrap(err, errSetAlias)
+	}
+	if data, err = override(data, o); err != nil {
+		return nil, err
+	}
+
+	return newClient(ctx, newServiceFn, data)
+}
+
+// newClient connects to the host identified by the supplied credentials. The
+// errors of the connections that did not reach the host are reported by
+// IsUnreachable.
+func newClient(ctx context.Context, newServiceFn NewServiceFn, creds []byte) (*ssh.Client, error) {
+	svc, err := newServiceFn(ctx, creds)
+	if err == nil {
+		return svc, nil
+	}
+	err = errors.Wrap(err, errNewClient)
+	if sshv1alpha1.IsUnreachable(err) {
+		return nil, unreachableError{err}
+	}
+	return nil, err
+}
+
+// An unreachableError is the error of a connection that did not reach its
+// host.
+type unreachableError struct{ error }
+
+func (e unreachableError) Unwrap() error { return e.error }
+
+// IsUnreachable reports whether err is the failure of a connection to reach its
+// host over the network, such as an offline host, rather than a failure to
+// read its credentials or to authenticate to it.
+func IsUnreachable(err error) bool {
+	var u unreachableError
+	return errors.As(err, &u)
+}
+
+// A CredentialsOverride modifies the credentials used to connect to a host.
+type CredentialsOverride func(creds []byte) ([]byte, error)
+
+// Endpoint overrides the host of the credentials with the supplied endpoint.
+// The credentials are left unchanged if the endpoint is nil.
+func Endpoint(ep *apisv1alpha1.Endpoint) CredentialsOverride {
+	return func(creds []byte) ([]byte, error) {
+		if ep == nil {
+			return creds, nil
+		}
+		port := 22
+		if ep.Port != nil {
+			port = *ep.Port
+		}
+		data, err := sshv1alpha1.WithEndpoint(creds, ep.Host, port)
+		return data, errors.Wrap(err, errSetEndpoint)
+	}
+}
+
+// Username overrides the username of the credentials. The credentials are
+// left unchanged if the username is empty.
+func Username(username string) CredentialsOverride {
+	return func(creds []byte) ([]byte, error) {
+		if username == "" {
+			return creds, nil
+		}
+		data, err := sshv1alpha1.WithUsername(creds, username)
+		return data, errors.Wrap(err, errSetUsername)
+	}
+}
+
+func override(creds []byte, o []CredentialsOverride) ([]byte, error) {
+	var err error
+	for _, fn := range o {
+		if creds, err = fn(creds); err != nil {
+			return nil, err
+		}
+	}
+	return creds, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	errParseCreds  = "cannot parse credentials"
+	errFmtGetField = "cannot get %s of credentials"
+	errEncodeCreds = "cannot encode credentials"
+)
+
+// ExtractCredentials returns the JSON encoded credentials identified by the
+// supplied ProviderCredentials. The fields read from separate sources
+// override those of the JSON encoded credentials of the source.
+func ExtractCredentials(ctx context.Context, kube client.Client, cd apisv1alpha1.ProviderCredentials) ([]byte, error) {
+	data, err := resource.CommonCredentialExtractor(ctx, cd.Source, kube, cd.CommonCredentialSelectors)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []struct {
+		name  string
+		value *apisv1alpha1.CredentialValue
+		set   func(kc *sshv1alpha1.Config, v string) error
+	}{
+		{"hostIP", cd.HostIP, func(kc *sshv1alpha1.Config, v string) error {
+			kc.RemoteHostIP = strings.TrimSpace(v)
+			return nil
+		}},
+		{"hostPort", cd.HostPort, func(kc *sshv1alpha1.Config, v string) (err error) {
+			kc.RemoteHostPort, err = sshv1alpha1.ParsePort(v)
+			return err
+		}},
+		{"username", cd.Username, func(kc *sshv1alpha1.Config, v string) error {
+			kc.Username = strings.TrimSpace(v)
+			return nil
+		}},
+		{"password", cd.Password, func(kc *sshv1alpha1.Config, v string) error {
+			kc.Password = strings.TrimRight(v, "\r\n")
+			return nil
+		}},
+		{"privateKey", cd.PrivateKey, func(kc *sshv1alpha1.Config, v string) error {
+			kc.PrivateKey = base64.StdEncoding.EncodeToString([]byte(v))
+			return nil
+		}},
+		{"knownHosts", cd.KnownHosts, func(kc *sshv1alpha1.Config, v string) error {
+			kc.KnownHosts = v
+			return nil
+		}},
+	}
+
+	kc := sshv1alpha1.Config{}
+	overridden := false
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		if !overridden && len(data) > 0 {
+			// The fields override the credentials of the source, if any.
+			if err := json.Unmarshal(data, &kc); err != nil {
+				return nil, errors.Wrap(err, errParseCreds)
+			}
+		}
+		overridden = true
+		v, err := credentialValue(ctx, kube, f.value)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtGetField, f.name)
+		}
+		if err := f.set(&kc, v); err != nil {
+			return nil, errors.Wrapf(err, errFmtGetField, f.name)
+		}
+	}
+	if !overridden {
+		return data, nil
+	}
+	data, err = json.Marshal(kc)
+	return data, errors.Wrap(err, errEncodeCreds)
+}
+
+// credentialValue returns the literal value of the field, or the value read
+// from its source.
+func credentialValue(ctx context.Context, kube client.Client, v *apisv1alpha1.CredentialValue) (string, error) {
+	if v.Source == "" || v.Source == xpv1.CredentialsSourceNone {
+		return v.Value, nil
+	}
+	data, err := resource.CommonCredentialExtractor(ctx, v.Source, kube, v.CommonCredentialSelectors)
+	return string(data), err
+}
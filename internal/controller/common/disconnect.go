@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+// NewExternalConnectDisconnecter returns an ExternalConnectDisconnecter that
+// disconnects the ExternalClients the supplied connecter produces once the
+// reconcile that connected them is done with them. ExternalClients are only
+// disconnected if they are an ExternalDisconnecter and were connected in a
+// reconcile of NewReconciler.
+func NewExternalConnectDisconnecter(c managed.ExternalConnecter) managed.ExternalConnectDisconnecter {
+	return &connectDisconnecter{inner: c}
+}
+
+// A connectDisconnecter records the ExternalClients it connects in the
+// context of their reconcile, since the managed reconciler only passes that
+// context back to Disconnect.
+type connectDisconnecter struct {
+	inner managed.ExternalConnecter
+}
+
+// Connect connects through the inner connecter and records the client in
+// the context of the reconcile.
+func (c *connectDisconnecter) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	ec, err := c.inner.Connect(ctx, mg)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := ec.(managed.ExternalDisconnecter); ok {
+		if cs, ok := ctx.Value(connectedKey{}).(*connected); ok {
+			cs.add(d)
+		}
+	}
+	return ec, nil
+}
+
+// Disconnect disconnects the clients connected in the reconcile of ctx.
+func (c *connectDisconnecter) Disconnect(ctx context.Context) error {
+	cs, ok := ctx.Value(connectedKey{}).(*connected)
+	if !ok {
+		return nil
+	}
+	return cs.disconnect(ctx)
+}
+
+type connectedKey struct{}
+
+// connected are the clients connected in a reconcile.
+type connected struct {
+	mu      sync.Mutex
+	clients []managed.ExternalDisconnecter
+}
+
+func (cs *connected) add(d managed.ExternalDisconnecter) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.clients = append(cs.clients, d)
+}
+
+// disconnect disconnects every client, returning the first error.
+func (cs *connected) disconnect(ctx context.Context) error {
+	cs.mu.Lock()
+	clients := cs.clients
+	cs.clients = nil
+	cs.mu.Unlock()
+
+	var err error
+	for _, d := range clients {
+		if derr := d.Disconnect(ctx); derr != nil && err == nil {
+			err = derr
+		}
+	}
+	return err
+}
+
+// A disconnectingReconciler holds the clients connected in each reconcile of
+// an inner Reconciler, which a connecter of NewExternalConnectDisconnecter
+// disconnects.
+type disconnectingReconciler struct {
+	inner reconcile.Reconciler
+}
+
+func (r *disconnectingReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	return r.inner.Reconcile(context.WithValue(ctx, connectedKey{}, &connected{}), req)
+}
+
+// CloseExecutors closes the supplied executors, returning the first error.
+func CloseExecutors(xs map[string]sshv1alpha1.RemoteExecutor) error {
+	var err error
+	for _, x := range xs {
+		if cerr := x.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+// An executorClient is an ExternalClient observing its resource through a
+// fake executor, which it closes once disconnected.
+type executorClient struct {
+	managed.ExternalClientFns
+	x *sshfake.Executor
+}
+
+func (c *executorClient) Disconnect(_ context.Context) error {
+	return c.x.Close()
+}
+
+func TestExternalConnectDisconnecter(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		observe func(x *sshfake.Executor) (managed.ExternalObservation, error)
+	}{
+		"UpToDate": {
+			reason: "The executor should be closed after a reconcile finding the resource up to date.",
+			observe: func(x *sshfake.Executor) (managed.ExternalObservation, error) {
+				_, _, err := x.Run(context.Background(), "true", 0)
+				return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, err
+			},
+		},
+		"ObserveError": {
+			reason: "The executor should be closed after a reconcile that failed to observe the resource.",
+			observe: func(_ *sshfake.Executor) (managed.ExternalObservation, error) {
+				return managed.ExternalObservation{}, errors.New("boom")
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var executors []*sshfake.Executor
+			connect := managed.ExternalConnectorFn(func(_ context.Context, _ resource.Managed) (managed.ExternalClient, error) {
+				// The executor of the previous reconcile is closed
+				// before the next one connects.
+				for i, x := range executors {
+					if !x.Closed() {
+						t.Errorf("\n%s\nThe executor of reconcile %d is still open when reconcile %d connects", tc.reason, i, len(executors))
+					}
+				}
+				x := &sshfake.Executor{}
+				executors = append(executors, x)
+				return &executorClient{
+					ExternalClientFns: managed.ExternalClientFns{
+						ObserveFn: func(_ context.Context, _ resource.Managed) (managed.ExternalObservation, error) {
+							return tc.observe(x)
+						},
+					},
+					x: x,
+				}, nil
+			})
+			mgr := &fake.Manager{
+				Client: &test.MockClient{
+					MockGet: test.NewMockGetFn(nil),
+					MockStatusUpdate: test.MockSubResourceUpdateFn(func(_ context.Context, _ client.Object, _ ...client.SubResourceUpdateOption) error {
+						return nil
+					}),
+				},
+				Scheme: fake.SchemeWith(&fake.Managed{}),
+			}
+			r := &disconnectingReconciler{inner: managed.NewReconciler(mgr,
+				resource.ManagedKind(fake.GVK(&fake.Managed{})),
+				managed.WithInitializers(),
+				managed.WithReferenceResolver(managed.ReferenceResolverFn(func(_ context.Context, _ resource.Managed) error { return nil })),
+				managed.WithExternalConnectDisconnecter(NewExternalConnectDisconnecter(connect)),
+				managed.WithConnectionPublishers(),
+				managed.WithFinalizer(resource.FinalizerFns{AddFinalizerFn: func(_ context.Context, _ resource.Object) error { return nil }}))}
+
+			for i := 0; i < 3; i++ {
+				if _, err := r.Reconcile(context.Background(), reconcile.Request{}); err != nil {
+					t.Fatalf("r.Reconcile(...): %v", err)
+				}
+			}
+			closed := make([]bool, 0, len(executors))
+			for _, x := range executors {
+				closed = append(closed, x.Closed())
+			}
+			if diff := cmp.Diff([]bool{true, true, true}, closed); diff != "" {
+				t.Errorf("\n%s\nClosed executors: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
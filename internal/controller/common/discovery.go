@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	errListEndpointSlices = "cannot list EndpointSlices of Service"
+	errFmtNoEndpoint      = "Service %s/%s has no ready endpoint serving port %q"
+	errSetServiceEndpoint = "cannot set endpoint of Service"
+
+	errGetVMI        = "cannot get VirtualMachineInstance"
+	errFmtVMINotRun  = "VirtualMachineInstance %s/%s is not running, it is %q"
+	errFmtNoVMIAddr  = "VirtualMachineInstance %s/%s reports no address for interface %q"
+	errSetVMIAddress = "cannot set address of VirtualMachineInstance"
+
+	errGetMachine        = "cannot get Machine"
+	errFmtNoMachineAddr  = "%s %s/%s reports no %s address"
+	errSetMachineAddress = "cannot set address of Machine"
+)
+
+// vmiKind is the kind of the KubeVirt VirtualMachineInstances, read as
+// unstructured objects so that the provider does not depend on KubeVirt.
+var vmiKind = schema.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: "VirtualMachineInstance"}
+
+// machineKinds are the kinds of the Cluster API Machines, read as
+// unstructured objects as well.
+var machineKinds = map[apisv1alpha1.MachineKind]schema.GroupVersionKind{
+	apisv1alpha1.MachineKindMachine:       {Group: "cluster.x-k8s.io", Version: "v1beta1", Kind: "Machine"},
+	apisv1alpha1.MachineKindMetal3Machine: {Group: "infrastructure.cluster.x-k8s.io", Version: "v1beta1", Kind: "Metal3Machine"},
+}
+
+// machineAddressTypes are the types of the addresses of a Machine in the
+// order they are connected to without an addressType.
+var machineAddressTypes = []apisv1alpha1.MachineAddressType{
+	apisv1alpha1.MachineAddressExternalIP,
+	apisv1alpha1.MachineAddressInternalIP,
+	apisv1alpha1.MachineAddressExternalDNS,
+	apisv1alpha1.MachineAddressInternalDNS,
+	apisv1alpha1.MachineAddressHostname,
+}
+
+// A serviceEndpoint is a ready address of a Service with the port to connect
+// to on it.
+type serviceEndpoint struct {
+	address string
+	port    int
+}
+
+// resolveService returns the credentials with the host and port replaced by
+// a ready endpoint of the supplied Service, the first in lexical order so
+// that every reconcile connects to the same one while it stays ready.
+func resolveService(ctx context.Context, kube client.Client, creds []byte, svc *apisv1alpha1.ServiceEndpoints) ([]byte, error) {
+	l := &discoveryv1.EndpointSliceList{}
+	if err := kube.List(ctx, l, client.InNamespace(svc.Namespace), client.MatchingLabels{discoveryv1.LabelServiceName: svc.Name}); err != nil {
+		return nil, errors.Wrap(err, errListEndpointSlices)
+	}
+
+	var eps []serviceEndpoint
+	for _, s := range l.Items {
+		port, ok := slicePort(s.Ports, svc.Port)
+		if !ok {
+			continue
+		}
+		for _, ep := range s.Endpoints {
+			if (ep.Conditions.Ready != nil && !*ep.Conditions.Ready) || len(ep.Addresses) == 0 {
+				continue
+			}
+			eps = append(eps, serviceEndpoint{address: ep.Addresses[0], port: port})
+		}
+	}
+	if len(eps) == 0 {
+		return nil, errors.Errorf(errFmtNoEndpoint, svc.Namespace, svc.Name, svc.Port)
+	}
+	sort.Slice(eps, func(i, j int) bool {
+		if eps[i].address != eps[j].address {
+			return eps[i].address < eps[j].address
+		}
+		return eps[i].port < eps[j].port
+	})
+	data, err := sshv1alpha1.WithEndpoint(creds, eps[0].address, eps[0].port)
+	return data, errors.Wrap(err, errSetServiceEndpoint)
+}
+
+// slicePort returns the port to connect to on the endpoints of an
+// EndpointSlice with the supplied ports: the supplied port if it is a
+// number, the port of that name otherwise, or the only port of the slice if
+// it is empty. It returns false if the slice does not serve the port.
+func slicePort(ports []discoveryv1.EndpointPort, want string) (int, bool) {
+	if n, err := strconv.Atoi(want); err == nil {
+		return n, true
+	}
+	if want == "" {
+		switch len(ports) {
+		case 0:
+			return int(sshv1alpha1.DefaultPort), true
+		case 1:
+			want = nameOf(ports[0])
+		default:
+			return 0, false
+		}
+	}
+	for _, p := range ports {
+		if nameOf(p) == want && p.Port != nil {
+			return int(*p.Port), true
+		}
+	}
+	return 0, false
+}
+
+func nameOf(p discoveryv1.EndpointPort) string {
+	if p.Name == nil {
+		return ""
+	}
+	return *p.Name
+}
+
+// VirtualMachineInstance overrides the host of the credentials with the
+// address the supplied KubeVirt VirtualMachineInstance reports for its
+// interface. The credentials are left unchanged if the target is nil.
+func VirtualMachineInstance(ctx context.Context, kube client.Client, t *apisv1alpha1.VirtualMachineInstanceTarget) CredentialsOverride {
+	return func(creds []byte) ([]byte, error) {
+		if t == nil {
+			return creds, nil
+		}
+		vmi := &unstructured.Unstructured{}
+		vmi.SetGroupVersionKind(vmiKind)
+		if err := kube.Get(ctx, types.NamespacedName{Namespace: t.Namespace, Name: t.Name}, vmi); err != nil {
+			return nil, errors.Wrap(err, errGetVMI)
+		}
+		if phase, _, _ := unstructured.NestedString(vmi.Object, "status", "phase"); phase != "Running" {
+			return nil, errors.Errorf(errFmtVMINotRun, t.Namespace, t.Name, phase)
+		}
+		address, ok := vmiAddress(vmi, t.Interface)
+		if !ok {
+			return nil, errors.Errorf(errFmtNoVMIAddr, t.Namespace, t.Name, t.Interface)
+		}
+		port := int(sshv1alpha1.DefaultPort)
+		if t.Port != nil {
+			port = *t.Port
+		}
+		data, err := sshv1alpha1.WithEndpoint(creds, address, port)
+		return data, errors.Wrap(err, errSetVMIAddress)
+	}
+}
+
+// vmiAddress returns the address the VirtualMachineInstance reports for the
+// interface of the supplied name, or for its first interface reporting one
+// if the name is empty.
+func vmiAddress(vmi *unstructured.Unstructured, name string) (string, bool) {
+	ifaces, _, _ := unstructured.NestedSlice(vmi.Object, "status", "interfaces")
+	for _, i := range ifaces {
+		iface, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if n, _, _ := unstructured.NestedString(iface, "name"); name != "" && n != name {
+			continue
+		}
+		if a, _, _ := unstructured.NestedString(iface, "ipAddress"); a != "" {
+			return a, true
+		}
+		if as, _, _ := unstructured.NestedStringSlice(iface, "ipAddresses"); len(as) > 0 && as[0] != "" {
+			return as[0], true
+		}
+	}
+	return "", false
+}
+
+// Machine overrides the host of the credentials with an address the supplied
+// Cluster API Machine reports in its status. The credentials are left
+// unchanged if the target is nil.
+func Machine(ctx context.Context, kube client.Client, t *apisv1alpha1.MachineTarget) CredentialsOverride {
+	return func(creds []byte) ([]byte, error) {
+		if t == nil {
+			return creds, nil
+		}
+		kind := t.Kind
+		if kind == "" {
+			kind = apisv1alpha1.MachineKindMachine
+		}
+		m := &unstructured.Unstructured{}
+		m.SetGroupVersionKind(machineKinds[kind])
+		if err := kube.Get(ctx, types.NamespacedName{Namespace: t.Namespace, Name: t.Name}, m); err != nil {
+			return nil, errors.Wrap(err, errGetMachine)
+		}
+		want := machineAddressTypes
+		if t.AddressType != "" {
+			want = []apisv1alpha1.MachineAddressType{t.AddressType}
+		}
+		address, ok := machineAddress(m, want)
+		if !ok {
+			return nil, errors.Errorf(errFmtNoMachineAddr, kind, t.Namespace, t.Name, want[0])
+		}
+		port := int(sshv1alpha1.DefaultPort)
+		if t.Port != nil {
+			port = *t.Port
+		}
+		data, err := sshv1alpha1.WithEndpoint(creds, address, port)
+		return data, errors.Wrap(err, errSetMachineAddress)
+	}
+}
+
+// machineAddress returns the first address of the Machine of the first of
+// the supplied types it reports.
+func machineAddress(m *unstructured.Unstructured, want []apisv1alpha1.MachineAddressType) (string, bool) {
+	addresses, _, _ := unstructured.NestedSlice(m.Object, "status", "addresses")
+	for _, t := range want {
+		for _, a := range addresses {
+			addr, ok := a.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			typ, _, _ := unstructured.NestedString(addr, "type")
+			v, _, _ := unstructured.NestedString(addr, "address")
+			if typ == string(t) && v != "" {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
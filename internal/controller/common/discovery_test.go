@@ -0,0 +1,315 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+func TestResolveService(t *testing.T) {
+	errBoom := errors.New("boom")
+	slice := func(ports []discoveryv1.EndpointPort, eps ...discoveryv1.Endpoint) discoveryv1.EndpointSlice {
+		return discoveryv1.EndpointSlice{Ports: ports, Endpoints: eps}
+	}
+	endpoint := func(address string, ready *bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{Addresses: []string{address}, Conditions: discoveryv1.EndpointConditions{Ready: ready}}
+	}
+	port := func(name string, n int32) discoveryv1.EndpointPort {
+		return discoveryv1.EndpointPort{Name: &name, Port: &n}
+	}
+	ready, notReady := true, false
+	slices := func(s ...discoveryv1.EndpointSlice) client.Client {
+		return &test.MockClient{MockList: func(_ context.Context, obj client.ObjectList, opts ...client.ListOption) error {
+			lo := &client.ListOptions{}
+			lo.ApplyOptions(opts)
+			if lo.Namespace != "edge" || !lo.LabelSelector.Matches(labels.Set{discoveryv1.LabelServiceName: "bastions"}) {
+				return errors.New("EndpointSlices not listed by Service")
+			}
+			obj.(*discoveryv1.EndpointSliceList).Items = s
+			return nil
+		}}
+	}
+
+	type want struct {
+		host string
+		port sshv1alpha1.Port
+		err  bool
+	}
+	cases := map[string]struct {
+		reason string
+		kube   client.Client
+		port   string
+		want   want
+	}{
+		"FirstReady": {
+			reason: "The first ready address of the Service, in lexical order across its EndpointSlices, should be connected to.",
+			kube: slices(
+				slice([]discoveryv1.EndpointPort{port("ssh", 2222)}, endpoint("10.0.0.9", nil)),
+				slice([]discoveryv1.EndpointPort{port("ssh", 2222)}, endpoint("10.0.0.1", &notReady), endpoint("10.0.0.5", &ready)),
+			),
+			want: want{host: "10.0.0.5", port: 2222},
+		},
+		"NamedPort": {
+			reason: "The port of the supplied name should be connected to.",
+			kube:   slices(slice([]discoveryv1.EndpointPort{port("http", 80), port("ssh", 2222)}, endpoint("10.0.0.1", nil))),
+			port:   "ssh",
+			want:   want{host: "10.0.0.1", port: 2222},
+		},
+		"PortNumber": {
+			reason: "A port number should be connected to as it is.",
+			kube:   slices(slice(nil, endpoint("10.0.0.1", nil))),
+			port:   "2200",
+			want:   want{host: "10.0.0.1", port: 2200},
+		},
+		"NoPorts": {
+			reason: "Port 22 should be connected to on EndpointSlices without ports, such as the ones of a headless Service.",
+			kube:   slices(slice(nil, endpoint("10.0.0.1", nil))),
+			want:   want{host: "10.0.0.1", port: 22},
+		},
+		"AmbiguousPort": {
+			reason: "EndpointSlices with many ports should not be connected to without a port.",
+			kube:   slices(slice([]discoveryv1.EndpointPort{port("http", 80), port("ssh", 2222)}, endpoint("10.0.0.1", nil))),
+			want:   want{err: true},
+		},
+		"NoReadyEndpoint": {
+			reason: "A Service without ready endpoints should fail the connection.",
+			kube:   slices(slice(nil, endpoint("10.0.0.1", &notReady))),
+			want:   want{err: true},
+		},
+		"ListError": {
+			reason: "Errors listing the EndpointSlices should be returned.",
+			kube:   &test.MockClient{MockList: test.NewMockListFn(errBoom)},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			creds := []byte(`{"username":"deploy","hostIP":"192.0.2.1","hostPort":22}`)
+			svc := &apisv1alpha1.ServiceEndpoints{Name: "bastions", Namespace: "edge", Port: tc.port}
+			data, err := resolveService(context.Background(), tc.kube, creds, svc)
+			got := want{err: err != nil}
+			if err == nil {
+				kc := sshv1alpha1.Config{}
+				if err := json.Unmarshal(data, &kc); err != nil {
+					t.Fatal(err)
+				}
+				got.host, got.port = kc.RemoteHostIP, kc.RemoteHostPort
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nresolveService(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestVirtualMachineInstance(t *testing.T) {
+	errBoom := errors.New("boom")
+	vmi := func(phase string, ifaces ...interface{}) client.Client {
+		return &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+			if key != (client.ObjectKey{Namespace: "vms", Name: "web"}) || obj.GetObjectKind().GroupVersionKind() != vmiKind {
+				return errors.New("VirtualMachineInstance not read")
+			}
+			obj.(*unstructured.Unstructured).Object["status"] = map[string]interface{}{"phase": phase, "interfaces": ifaces}
+			return nil
+		}}
+	}
+	iface := func(name string, addresses ...interface{}) map[string]interface{} {
+		i := map[string]interface{}{"name": name, "ipAddresses": addresses}
+		if len(addresses) > 0 {
+			i["ipAddress"] = addresses[0]
+		}
+		return i
+	}
+	port := 2222
+
+	type want struct {
+		host string
+		port sshv1alpha1.Port
+		err  bool
+	}
+	cases := map[string]struct {
+		reason string
+		kube   client.Client
+		target *apisv1alpha1.VirtualMachineInstanceTarget
+		want   want
+	}{
+		"NoTarget": {
+			reason: "The credentials should be left unchanged without a target.",
+			want:   want{host: "192.0.2.1", port: 22},
+		},
+		"FirstInterface": {
+			reason: "The first interface reporting an address should be connected to on port 22.",
+			kube:   vmi("Running", iface("pending"), iface("default", "10.244.0.12")),
+			target: &apisv1alpha1.VirtualMachineInstanceTarget{Name: "web", Namespace: "vms"},
+			want:   want{host: "10.244.0.12", port: 22},
+		},
+		"NamedInterface": {
+			reason: "The interface of the supplied name should be connected to on the supplied port.",
+			kube:   vmi("Running", iface("default", "10.244.0.12"), iface("bridge", "192.168.1.20")),
+			target: &apisv1alpha1.VirtualMachineInstanceTarget{Name: "web", Namespace: "vms", Interface: "bridge", Port: &port},
+			want:   want{host: "192.168.1.20", port: 2222},
+		},
+		"IPAddresses": {
+			reason: "The first of the ipAddresses of an interface should be connected to without an ipAddress.",
+			kube:   vmi("Running", map[string]interface{}{"name": "default", "ipAddresses": []interface{}{"fd10::12"}}),
+			target: &apisv1alpha1.VirtualMachineInstanceTarget{Name: "web", Namespace: "vms"},
+			want:   want{host: "fd10::12", port: 22},
+		},
+		"NoAddress": {
+			reason: "A VirtualMachineInstance reporting no address for the interface should fail the connection.",
+			kube:   vmi("Running", iface("default", "10.244.0.12")),
+			target: &apisv1alpha1.VirtualMachineInstanceTarget{Name: "web", Namespace: "vms", Interface: "bridge"},
+			want:   want{err: true},
+		},
+		"NotRunning": {
+			reason: "A VirtualMachineInstance that is not running should fail the connection, even with a stale address.",
+			kube:   vmi("Scheduled", iface("default", "10.244.0.12")),
+			target: &apisv1alpha1.VirtualMachineInstanceTarget{Name: "web", Namespace: "vms"},
+			want:   want{err: true},
+		},
+		"GetError": {
+			reason: "Errors getting the VirtualMachineInstance should be returned.",
+			kube:   &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			target: &apisv1alpha1.VirtualMachineInstanceTarget{Name: "web", Namespace: "vms"},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			creds := []byte(`{"username":"fedora","hostIP":"192.0.2.1","hostPort":22}`)
+			data, err := VirtualMachineInstance(context.Background(), tc.kube, tc.target)(creds)
+			got := want{err: err != nil}
+			if err == nil {
+				kc := sshv1alpha1.Config{}
+				if err := json.Unmarshal(data, &kc); err != nil {
+					t.Fatal(err)
+				}
+				got.host, got.port = kc.RemoteHostIP, kc.RemoteHostPort
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nVirtualMachineInstance(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestMachine(t *testing.T) {
+	errBoom := errors.New("boom")
+	machine := func(gvk schema.GroupVersionKind, addresses ...interface{}) client.Client {
+		return &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+			if key != (client.ObjectKey{Namespace: "capi", Name: "edge-0"}) || obj.GetObjectKind().GroupVersionKind() != gvk {
+				return errors.New("Machine not read")
+			}
+			obj.(*unstructured.Unstructured).Object["status"] = map[string]interface{}{"addresses": addresses}
+			return nil
+		}}
+	}
+	address := func(typ, a string) map[string]interface{} {
+		return map[string]interface{}{"type": typ, "address": a}
+	}
+	capi := machineKinds[apisv1alpha1.MachineKindMachine]
+	metal3 := machineKinds[apisv1alpha1.MachineKindMetal3Machine]
+	port := 2222
+
+	type want struct {
+		host string
+		port sshv1alpha1.Port
+		err  bool
+	}
+	cases := map[string]struct {
+		reason string
+		kube   client.Client
+		target *apisv1alpha1.MachineTarget
+		want   want
+	}{
+		"NoTarget": {
+			reason: "The credentials should be left unchanged without a target.",
+			want:   want{host: "192.0.2.1", port: 22},
+		},
+		"PreferredAddress": {
+			reason: "The ExternalIP of a Machine should be preferred to its other addresses, whatever their order.",
+			kube:   machine(capi, address("Hostname", "edge-0"), address("InternalIP", "10.0.0.7"), address("ExternalIP", "203.0.113.7")),
+			target: &apisv1alpha1.MachineTarget{Name: "edge-0", Namespace: "capi"},
+			want:   want{host: "203.0.113.7", port: 22},
+		},
+		"AddressType": {
+			reason: "The address of the supplied type should be connected to on the supplied port.",
+			kube:   machine(capi, address("InternalIP", "10.0.0.7"), address("ExternalIP", "203.0.113.7")),
+			target: &apisv1alpha1.MachineTarget{Name: "edge-0", Namespace: "capi", AddressType: apisv1alpha1.MachineAddressInternalIP, Port: &port},
+			want:   want{host: "10.0.0.7", port: 2222},
+		},
+		"Metal3Machine": {
+			reason: "The addresses of a Metal3Machine should be read from the infrastructure API group.",
+			kube:   machine(metal3, address("InternalIP", "172.22.0.10")),
+			target: &apisv1alpha1.MachineTarget{Kind: apisv1alpha1.MachineKindMetal3Machine, Name: "edge-0", Namespace: "capi"},
+			want:   want{host: "172.22.0.10", port: 22},
+		},
+		"NoAddress": {
+			reason: "A Machine reporting no address of the supplied type should fail the connection.",
+			kube:   machine(capi, address("InternalIP", "10.0.0.7")),
+			target: &apisv1alpha1.MachineTarget{Name: "edge-0", Namespace: "capi", AddressType: apisv1alpha1.MachineAddressExternalIP},
+			want:   want{err: true},
+		},
+		"NotProvisioned": {
+			reason: "A Machine reporting no address yet should fail the connection.",
+			kube:   machine(capi),
+			target: &apisv1alpha1.MachineTarget{Name: "edge-0", Namespace: "capi"},
+			want:   want{err: true},
+		},
+		"GetError": {
+			reason: "Errors getting the Machine should be returned.",
+			kube:   &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			target: &apisv1alpha1.MachineTarget{Name: "edge-0", Namespace: "capi"},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			creds := []byte(`{"username":"core","hostIP":"192.0.2.1","hostPort":22}`)
+			data, err := Machine(context.Background(), tc.kube, tc.target)(creds)
+			got := want{err: err != nil}
+			if err == nil {
+				kc := sshv1alpha1.Config{}
+				if err := json.Unmarshal(data, &kc); err != nil {
+					t.Fatal(err)
+				}
+				got.host, got.port = kc.RemoteHostIP, kc.RemoteHostPort
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nMachine(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	"github.com/crossplane/provider-ssh/internal/redact"
+)
+
+// NewEventRecorder returns the recorder of the Events of the named
+// controller, whose messages are redacted. Identical Events of a resource are
+// only emitted once within the EventDeduplicationWindow of the options, if
+// any.
+func NewEventRecorder(mgr ctrl.Manager, name string, o Options) event.Recorder {
+	r := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))
+	if o.EventDeduplicationWindow <= 0 {
+		return redactRecorder{inner: r}
+	}
+	return redactRecorder{inner: newDedupRecorder(r, o.EventDeduplicationWindow)}
+}
+
+// A redactRecorder masks the secrets in the messages of the Events it emits,
+// such as the output of a script echoing a sensitive variable.
+type redactRecorder struct {
+	inner event.Recorder
+}
+
+// Event emits the Event with its message redacted.
+func (r redactRecorder) Event(obj runtime.Object, e event.Event) {
+	e.Message = redact.String(e.Message)
+	r.inner.Event(obj, e)
+}
+
+// WithAnnotations returns a recorder annotating the Events it emits, whose
+// messages are redacted as well.
+func (r redactRecorder) WithAnnotations(keysAndValues ...string) event.Recorder {
+	return redactRecorder{inner: r.inner.WithAnnotations(keysAndValues...)}
+}
+
+// A dedupRecorder drops the Events of a resource identical to one it emitted
+// for it within its window.
+type dedupRecorder struct {
+	inner  event.Recorder
+	window time.Duration
+	seen   *seenEvents
+}
+
+// seenEvents are the times the Events were last emitted, shared by the
+// recorders derived from a dedupRecorder.
+type seenEvents struct {
+	mu    sync.Mutex
+	at    map[eventKey]time.Time
+	swept time.Time
+}
+
+// An eventKey identifies the Events of a resource that are identical.
+type eventKey struct {
+	uid     types.UID
+	kind    event.Type
+	reason  event.Reason
+	message string
+}
+
+func newDedupRecorder(r event.Recorder, window time.Duration) *dedupRecorder {
+	return &dedupRecorder{inner: r, window: window, seen: &seenEvents{at: map[eventKey]time.Time{}, swept: time.Now()}}
+}
+
+// Event emits the Event unless an identical one was emitted for the object
+// within the window. Events of objects without a UID are always emitted.
+func (r *dedupRecorder) Event(obj runtime.Object, e event.Event) {
+	m, err := meta.Accessor(obj)
+	if err != nil || m.GetUID() == "" {
+		r.inner.Event(obj, e)
+		return
+	}
+	if r.seen.emitted(eventKey{uid: m.GetUID(), kind: e.Type, reason: e.Reason, message: e.Message}, r.window) {
+		return
+	}
+	r.inner.Event(obj, e)
+}
+
+// WithAnnotations returns a recorder annotating the Events it emits, which
+// shares the Events seen by this one.
+func (r *dedupRecorder) WithAnnotations(keysAndValues ...string) event.Recorder {
+	return &dedupRecorder{inner: r.inner.WithAnnotations(keysAndValues...), window: r.window, seen: r.seen}
+}
+
+// emitted reports whether the Event of the supplied key was emitted within
+// the window, and records it as emitted now otherwise. The Events emitted
+// before the window are forgotten once per window.
+func (s *seenEvents) emitted(k eventKey, window time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.swept) > window {
+		for key, at := range s.at {
+			if now.Sub(at) > window {
+				delete(s.at, key)
+			}
+		}
+		s.swept = now
+	}
+	if at, ok := s.at[k]; ok && now.Sub(at) <= window {
+		return true
+	}
+	s.at[k] = now
+	return false
+}
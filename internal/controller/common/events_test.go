@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/redact"
+)
+
+// A fakeRecorder records the messages of the Events it emits.
+type fakeRecorder struct{ messages *[]string }
+
+func (r fakeRecorder) Event(_ runtime.Object, e event.Event) {
+	*r.messages = append(*r.messages, e.Message)
+}
+
+func (r fakeRecorder) WithAnnotations(...string) event.Recorder { return r }
+
+func TestDedupRecorder(t *testing.T) {
+	script := func(uid string) *apisv1alpha1.Script {
+		cr := &apisv1alpha1.Script{}
+		cr.SetUID(types.UID(uid))
+		return cr
+	}
+	web, db := script("web"), script("db")
+
+	var got []string
+	r := newDedupRecorder(fakeRecorder{messages: &got}, time.Minute)
+	r.Event(web, event.Normal("ScriptSucceeded", "Init script succeeded"))
+	r.Event(web, event.Normal("ScriptSucceeded", "Init script succeeded"))
+	r.WithAnnotations("host", "10.0.0.1").Event(web, event.Normal("ScriptSucceeded", "Init script succeeded"))
+	r.Event(db, event.Normal("ScriptSucceeded", "Init script succeeded"))
+	r.Event(web, event.Warning("ScriptFailed", errors.New("Update script failed")))
+	r.Event(script(""), event.Normal("ScriptSucceeded", "Init script succeeded"))
+	r.Event(script(""), event.Normal("ScriptSucceeded", "Init script succeeded"))
+
+	// The Event of web emitted before the window is emitted again.
+	for k := range r.seen.at {
+		if k.uid == "web" && k.reason == "ScriptSucceeded" {
+			r.seen.at[k] = time.Now().Add(-2 * time.Minute)
+		}
+	}
+	r.Event(web, event.Normal("ScriptSucceeded", "Init script succeeded"))
+
+	want := []string{
+		"Init script succeeded",
+		"Init script succeeded",
+		"Update script failed",
+		"Init script succeeded",
+		"Init script succeeded",
+		"Init script succeeded",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nOnly the Events of a resource identical to one emitted for it within the window should be dropped.\nEvent(...): -want messages, +got messages:\n%s\n", diff)
+	}
+}
+
+func TestRedactRecorder(t *testing.T) {
+	redact.Add("hunter2-recorder")
+
+	var got []string
+	r := redactRecorder{inner: fakeRecorder{messages: &got}}
+	r.Event(&apisv1alpha1.Script{}, event.Warning("ScriptFailed", errors.New("login failed: hunter2-recorder")))
+	r.WithAnnotations("host", "10.0.0.1").Event(&apisv1alpha1.Script{}, event.Normal("DriftDetected", "+token=hunter2-recorder"))
+
+	want := []string{"login failed: [REDACTED]", "+token=[REDACTED]"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nThe secrets in the messages of the Events should be masked.\nEvent(...): -want messages, +got messages:\n%s\n", diff)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+const (
+	errListHosts    = "cannot list host ProviderConfigs and Hosts"
+	errGetHost      = "cannot get Host"
+	errGetHostGroup = "cannot get HostGroup"
+)
+
+// HostKey returns the key identifying a target host. ProviderConfigs are
+// identified by their name, Hosts by their kind and name. Names cannot
+// contain a slash, so the keys are unique.
+func HostKey(kind, name string) string {
+	if kind == apisv1alpha1.HostKind {
+		return kind + "/" + name
+	}
+	return name
+}
+
+// SplitHostKey returns the kind and the name of the object identifying the
+// host of the key.
+func SplitHostKey(key string) (string, string) {
+	if kind, name, ok := strings.Cut(key, "/"); ok {
+		return kind, name
+	}
+	return apisv1alpha1.ProviderConfigKind, key
+}
+
+// ResolveHosts returns the sorted keys of the hosts referenced by refs, with
+// HostGroups expanded to their members, and of the ProviderConfigs and Hosts
+// selected by sel.
+func ResolveHosts(ctx context.Context, kube client.Client, refs []apisv1alpha1.HostReference, sel *metav1.LabelSelector) ([]string, error) {
+	keys := map[string]bool{}
+	for _, ref := range refs {
+		if ref.Kind != apisv1alpha1.HostGroupKind {
+			keys[HostKey(ref.Kind, ref.Name)] = true
+			continue
+		}
+		g := &apisv1alpha1.HostGroup{}
+		if err := kube.Get(ctx, types.NamespacedName{Name: ref.Name}, g); err != nil {
+			return nil, errors.Wrap(err, errGetHostGroup)
+		}
+		for _, n := range g.Spec.Hosts {
+			keys[HostKey(apisv1alpha1.HostKind, n)] = true
+		}
+		if g.Spec.HostSelector != nil {
+			if err := selectHosts(ctx, kube, g.Spec.HostSelector, &apisv1alpha1.HostList{}, keys); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if sel != nil {
+		if err := selectHosts(ctx, kube, sel, &apisv1alpha1.ProviderConfigList{}, keys); err != nil {
+			return nil, err
+		}
+		if err := selectHosts(ctx, kube, sel, &apisv1alpha1.HostList{}, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	hosts := make([]string, 0, len(keys))
+	for k := range keys {
+		hosts = append(hosts, k)
+	}
+	sort.Strings(hosts)
+	return hosts, nil
+}
+
+// selectHosts adds the keys of the ProviderConfigs or Hosts selected by sel
+// to keys.
+func selectHosts(ctx context.Context, kube client.Client, sel *metav1.LabelSelector, l client.ObjectList, keys map[string]bool) error {
+	s, err := metav1.LabelSelectorAsSelector(sel)
+	if err != nil {
+		return errors.Wrap(err, errListHosts)
+	}
+	if err := kube.List(ctx, l, client.MatchingLabelsSelector{Selector: s}); err != nil {
+		return errors.Wrap(err, errListHosts)
+	}
+	switch l := l.(type) {
+	case *apisv1alpha1.ProviderConfigList:
+		for _, pc := range l.Items {
+			keys[HostKey(apisv1alpha1.ProviderConfigKind, pc.GetName())] = true
+		}
+	case *apisv1alpha1.HostList:
+		for _, h := range l.Items {
+			keys[HostKey(apisv1alpha1.HostKind, h.GetName())] = true
+		}
+	}
+	return nil
+}
+
+// DialHostKey connects the managed resource to the host identified by the
+// key, after applying the supplied overrides to its credentials.
+func DialHostKey(ctx context.Context, kube client.Client, mg resource.Managed, key string, newServiceFn NewServiceFn, o ...CredentialsOverride) (*ssh.Client, error) {
+	kind, name := SplitHostKey(key)
+	if kind == apisv1alpha1.HostKind {
+		h := &apisv1alpha1.Host{}
+		if err := kube.Get(ctx, types.NamespacedName{Name: name}, h); err != nil {
+			return nil, errors.Wrap(err, errGetHost)
+		}
+		return DialHost(ctx, kube, mg, h, newServiceFn, o...)
+	}
+
+	pc := &apisv1alpha1.ProviderConfig{}
+	if err := kube.Get(ctx, types.NamespacedName{Name: name}, pc); err != nil {
+		return nil, errors.Wrap(err, errGetPC)
+	}
+	return Dial(ctx, kube, mg, pc, newServiceFn, o...)
+}
+
+// ForEachHost calls fn for every host concurrently and waits for all calls
+// to return.
+func ForEachHost(hosts []string, fn func(name string)) {
+	var wg sync.WaitGroup
+	for _, name := range hosts {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			fn(name)
+		}(name)
+	}
+	wg.Wait()
+}
+
+// RolloutBatch returns the pending hosts to execute next according to the
+// rollout strategy. Total is the number of targeted hosts and unavailable the
+// number of hosts that are not ready for other reasons than being pending,
+// e.g. because they failed or are unreachable.
+func RolloutBatch(r *apisv1alpha1.RolloutStrategy, total int, pending []string, unavailable int) []string {
+	if r == nil {
+		return pending
+	}
+	if r.PauseOnFailure && unavailable > 0 {
+		return nil
+	}
+
+	n := len(pending)
+	if r.BatchSize != nil && *r.BatchSize < n {
+		n = *r.BatchSize
+	}
+	if r.MaxUnavailable != nil {
+		limit, err := intstr.GetScaledValueFromIntOrPercent(r.MaxUnavailable, total, false)
+		if err != nil || limit < 1 {
+			// A rollout always makes progress, at least one host at a
+			// time.
+			limit = 1
+		}
+		if allowed := limit - unavailable; allowed < n {
+			n = allowed
+		}
+	}
+	if n <= 0 {
+		return nil
+	}
+	return pending[:n]
+}
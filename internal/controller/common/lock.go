@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// lockedRequeueInterval is how long after a reconcile finding its resource
+// locked by another reconcile the resource is reconciled again.
+const lockedRequeueInterval = 5 * time.Second
+
+// ResourceLocks are in-process locks of the resources being reconciled,
+// keyed by their UID, so that the Observe, Create, Update and Delete of a
+// resource never interleave when two of its reconciles overlap, e.g. after
+// a requeue or a leader change within the process.
+type ResourceLocks struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// NewResourceLocks returns locks none of which is held.
+func NewResourceLocks() *ResourceLocks {
+	return &ResourceLocks{held: map[string]bool{}}
+}
+
+// TryLock locks the supplied key unless it is already locked, and returns
+// the function unlocking it.
+func (l *ResourceLocks) TryLock(key string) (unlock func(), ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[key] {
+		return nil, false
+	}
+	l.held[key] = true
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, key)
+	}, true
+}
+
+// A lockingReconciler holds the lock of the reconciled resource while an
+// inner Reconciler reconciles it. A resource already locked is requeued
+// instead of waiting, so that a long script does not block a worker.
+type lockingReconciler struct {
+	kube   client.Reader
+	newObj func() (resource.Managed, error)
+	inner  reconcile.Reconciler
+	locks  *ResourceLocks
+	log    logging.Logger
+}
+
+func (r *lockingReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	mg, err := r.newObj()
+	if err != nil || mg == nil {
+		return r.inner.Reconcile(ctx, req)
+	}
+	// Resources that cannot be read are left to the inner Reconciler, which
+	// reports why.
+	if err := r.kube.Get(ctx, req.NamespacedName, mg); err != nil || mg.GetUID() == "" {
+		return r.inner.Reconcile(ctx, req)
+	}
+	unlock, ok := r.locks.TryLock(string(mg.GetUID()))
+	if !ok {
+		r.log.Debug("Resource is being reconciled by another reconcile, requeueing", "request", req, "uid", mg.GetUID())
+		return reconcile.Result{RequeueAfter: lockedRequeueInterval}, nil
+	}
+	defer unlock()
+	return r.inner.Reconcile(ctx, req)
+}
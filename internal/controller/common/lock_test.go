@@ -0,0 +1,148 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/provider-ssh/apis"
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+func TestResourceLocks(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	// The UID of a Script is its name.
+	kube := &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+		obj.SetUID(types.UID(key.Name))
+		return nil
+	}}
+	mgr := &fake.Manager{Client: kube, Scheme: s}
+
+	// The inner Reconciler reconciles the request named by nested while it
+	// reconciles the supplied request, as an overlapping reconcile would.
+	var r reconcile.Reconciler
+	nested := map[string]string{"web": "web", "db": "db-new"}
+	got := map[string]reconcile.Result{}
+	inner := reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		if n, ok := nested[req.Name]; ok {
+			res, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: n}})
+			if err != nil {
+				return res, err
+			}
+			got[n+" during "+req.Name] = res
+		}
+		return reconcile.Result{}, nil
+	})
+	o := Options{
+		Options:       controller.Options{Logger: logging.NewNopLogger(), GlobalRateLimiter: ratelimiter.NewGlobal(1000)},
+		ResourceLocks: NewResourceLocks(),
+	}
+	r = NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.ScriptGroupVersionKind), "test", inner, o)
+
+	for _, name := range []string{"web", "db", "web"} {
+		res, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: name}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(reconcile.Result{}, res); diff != "" {
+			t.Errorf("\nA resource that is not being reconciled should be reconciled.\nReconcile(%s): -want, +got:\n%s\n", name, diff)
+		}
+	}
+
+	want := map[string]reconcile.Result{
+		"web during web":   {RequeueAfter: lockedRequeueInterval},
+		"db-new during db": {},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nA resource should be requeued while another reconcile of the same UID holds its lock, others should be reconciled.\nReconcile(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestResourceLocksConcurrent(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	kube := &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+		obj.SetUID("web")
+		return nil
+	}}
+	mgr := &fake.Manager{Client: kube, Scheme: s}
+	o := Options{
+		Options:       controller.Options{Logger: logging.NewNopLogger(), GlobalRateLimiter: ratelimiter.NewGlobal(1000)},
+		ResourceLocks: NewResourceLocks(),
+	}
+
+	// The first controller reconciles the resource until it is released,
+	// while the second, sharing the locks, reconciles it too, as the
+	// controllers of the old and new leader would.
+	entered, release := make(chan struct{}), make(chan struct{})
+	first := NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.ScriptGroupVersionKind), "first", reconcile.Func(func(_ context.Context, _ reconcile.Request) (reconcile.Result, error) {
+		close(entered)
+		<-release
+		return reconcile.Result{}, nil
+	}), o)
+	reconciled := 0
+	second := NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.ScriptGroupVersionKind), "second", reconcile.Func(func(_ context.Context, _ reconcile.Request) (reconcile.Result, error) {
+		reconciled++
+		return reconcile.Result{}, nil
+	}), o)
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "web"}}
+	done := make(chan error)
+	go func() {
+		_, err := first.Reconcile(context.Background(), req)
+		done <- err
+	}()
+	<-entered
+
+	got, err := second.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(reconcile.Result{RequeueAfter: lockedRequeueInterval}, got); diff != "" {
+		t.Errorf("\nA resource should be requeued while another controller reconciles it.\nReconcile(...): -want, +got:\n%s\n", diff)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Reconcile(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(1, reconciled); diff != "" {
+		t.Errorf("\nA resource should only be reconciled once the other controller is done with it.\nReconcile(...): -want reconciles, +got:\n%s\n", diff)
+	}
+}
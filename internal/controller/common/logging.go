@@ -0,0 +1,184 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/go-logr/logr"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// A loggingReconciler logs the lines the controllers log through the context
+// of the reconciles of an inner Reconciler with its logger. If it only logs
+// state changes, the lines of a reconcile are held until it returns, and
+// dropped if they are the same as those of the previous reconcile of the
+// resource, which found it in the same state. The lines of the resources that
+// are deleted are forgotten.
+type loggingReconciler struct {
+	kube             client.Reader
+	newObj           func() (resource.Managed, error)
+	inner            reconcile.Reconciler
+	name             string
+	log              logr.Logger
+	stateChangesOnly bool
+
+	mu sync.Mutex
+	// The digest of the lines of the last reconcile of each resource.
+	last map[reconcile.Request]uint64
+}
+
+// newLoggingReconciler wraps the reconciler of the named controller with a
+// loggingReconciler, unless the options neither set a logger of the
+// reconciles nor only log state changes. The resources are read through kube
+// to find those that are deleted.
+func newLoggingReconciler(kube client.Reader, newObj func() (resource.Managed, error), name string, r reconcile.Reconciler, o Options) reconcile.Reconciler {
+	if o.ReconcileLogger.GetSink() == nil && !o.LogStateChangesOnly {
+		return r
+	}
+	return &loggingReconciler{kube: kube, newObj: newObj, inner: r, name: name, log: o.ReconcileLogger, stateChangesOnly: o.LogStateChangesOnly, last: map[reconcile.Request]uint64{}}
+}
+
+func (r *loggingReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	// Without a logger of their own, the lines are logged through the
+	// logger of the controller-runtime.
+	l := log.FromContext(ctx)
+	if r.log.GetSink() != nil {
+		l = r.log.WithValues("controller", r.name, "request", req)
+	}
+	if !r.stateChangesOnly || l.GetSink() == nil {
+		return r.inner.Reconcile(log.IntoContext(ctx, l), req)
+	}
+
+	b := &logBuffer{}
+	res, err := r.inner.Reconcile(log.IntoContext(ctx, logr.New(&bufferedSink{inner: l.GetSink(), buf: b})), req)
+	digest := b.digest()
+	deleted := r.deleted(ctx, req)
+	r.mu.Lock()
+	changed := r.last[req] != digest
+	if deleted {
+		delete(r.last, req)
+	} else {
+		r.last[req] = digest
+	}
+	r.mu.Unlock()
+	if changed {
+		b.flush()
+	}
+	return res, err
+}
+
+// deleted reports whether the resource of the request is gone or being
+// deleted once reconciled. Resources that cannot be read otherwise are not.
+func (r *loggingReconciler) deleted(ctx context.Context, req reconcile.Request) bool {
+	mg, err := r.newObj()
+	if err != nil || mg == nil {
+		return false
+	}
+	if err := r.kube.Get(ctx, req.NamespacedName, mg); err != nil {
+		return kerrors.IsNotFound(err)
+	}
+	return meta.WasDeleted(mg)
+}
+
+// A logBuffer holds the lines logged by a reconcile.
+type logBuffer struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+// A logEntry is a line logged through a sink, which logs it once flushed.
+type logEntry struct {
+	sink  logr.LogSink
+	name  string
+	level int
+	err   error
+	msg   string
+	kv    []any
+}
+
+func (b *logBuffer) add(e logEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = append(b.entries, e)
+}
+
+// digest returns a hash of the lines held, the same for the same lines.
+func (b *logBuffer) digest() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := fnv.New64a()
+	for _, e := range b.entries {
+		fmt.Fprintf(h, "%s\x00%d\x00%v\x00%s\x00%v\n", e.name, e.level, e.err, e.msg, e.kv)
+	}
+	return h.Sum64()
+}
+
+// flush logs the lines held through the sinks they were logged through.
+func (b *logBuffer) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range b.entries {
+		if e.err != nil {
+			e.sink.Error(e.err, e.msg, e.kv...)
+			continue
+		}
+		e.sink.Info(e.level, e.msg, e.kv...)
+	}
+	b.entries = nil
+}
+
+// A bufferedSink holds the lines logged through it in a buffer, until they
+// are flushed to the inner sink.
+type bufferedSink struct {
+	inner logr.LogSink
+	// The names of the logger, which apply to the digest of its lines.
+	name string
+	buf  *logBuffer
+}
+
+// Init does nothing, the inner sink being initialized by its own logger.
+func (s *bufferedSink) Init(logr.RuntimeInfo) {}
+
+func (s *bufferedSink) Enabled(level int) bool {
+	return s.inner.Enabled(level)
+}
+
+func (s *bufferedSink) Info(level int, msg string, kv ...any) {
+	s.buf.add(logEntry{sink: s.inner, name: s.name, level: level, msg: msg, kv: kv})
+}
+
+func (s *bufferedSink) Error(err error, msg string, kv ...any) {
+	s.buf.add(logEntry{sink: s.inner, name: s.name, err: err, msg: msg, kv: kv})
+}
+
+func (s *bufferedSink) WithValues(kv ...any) logr.LogSink {
+	return &bufferedSink{inner: s.inner.WithValues(kv...), name: s.name, buf: s.buf}
+}
+
+func (s *bufferedSink) WithName(name string) logr.LogSink {
+	return &bufferedSink{inner: s.inner.WithName(name), name: s.name + "/" + name, buf: s.buf}
+}
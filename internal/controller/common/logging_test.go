@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/google/go-cmp/cmp"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func newFakeManaged() (resource.Managed, error) {
+	return &fake.Managed{}, nil
+}
+
+// messages returns a logger appending the messages of the lines it logs to
+// the supplied slice.
+func messages(t *testing.T, got *[]string) logr.Logger {
+	return funcr.NewJSON(func(obj string) {
+		line := struct{ Msg string }{}
+		if err := json.Unmarshal([]byte(obj), &line); err != nil {
+			t.Fatal(err)
+		}
+		*got = append(*got, line.Msg)
+	}, funcr.Options{})
+}
+
+func TestLoggingReconciler(t *testing.T) {
+	// The reconciles of the resources, each observing the supplied state.
+	type observed struct{ name, state string }
+	reconciles := []observed{{"web", "missing"}, {"web", "ready"}, {"db", "ready"}, {"web", "ready"}, {"web", "drifted"}, {"db", "ready"}, {"web", "ready"}}
+
+	cases := map[string]struct {
+		reason           string
+		stateChangesOnly bool
+		want             []string
+	}{
+		"EveryReconcile": {
+			reason: "The lines of every reconcile should be logged through the logger of the reconciles.",
+			want:   []string{"web missing", "web ready", "db ready", "web ready", "web drifted", "db ready", "web ready"},
+		},
+		"StateChangesOnly": {
+			reason:           "The lines of a reconcile should be dropped if they are the same as those of the previous reconcile of the resource.",
+			stateChangesOnly: true,
+			want:             []string{"web missing", "web ready", "db ready", "web drifted", "web ready"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			l := messages(t, &got)
+			var state string
+			inner := reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+				logger := log.FromContext(ctx).WithName("[OBSERVE]")
+				logger.V(1).Info("Observing...")
+				logger.Info(req.Name + " " + state)
+				return reconcile.Result{}, nil
+			})
+			kube := &test.MockClient{MockGet: test.NewMockGetFn(nil)}
+			r := newLoggingReconciler(kube, newFakeManaged, "test", inner, Options{ReconcileLogger: l, LogStateChangesOnly: tc.stateChangesOnly})
+
+			for _, o := range reconciles {
+				state = o.state
+				if _, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: o.name}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nReconcile(...): -want lines, +got lines:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestLoggingReconcilerDeleted(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		get    test.MockGetFn
+	}{
+		"NotFound": {
+			reason: "The lines of a resource that is gone should be forgotten, and those of a resource recreated with its name logged.",
+			get:    test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{Resource: "scripts"}, "web")),
+		},
+		"Deleting": {
+			reason: "The lines of a resource being deleted should be forgotten, and those of a resource recreated with its name logged.",
+			get: test.NewMockGetFn(nil, func(obj client.Object) error {
+				now := metav1.Now()
+				obj.SetDeletionTimestamp(&now)
+				return nil
+			}),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			deleted := false
+			kube := &test.MockClient{MockGet: func(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+				if deleted {
+					return tc.get(ctx, key, obj)
+				}
+				return nil
+			}}
+			inner := reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+				log.FromContext(ctx).Info(req.Name + " ready")
+				return reconcile.Result{}, nil
+			})
+			r := newLoggingReconciler(kube, newFakeManaged, "test", inner, Options{ReconcileLogger: messages(t, &got), LogStateChangesOnly: true})
+
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "web"}}
+			for _, d := range []bool{false, false, true, false} {
+				deleted = d
+				if _, err := r.Reconcile(context.Background(), req); err != nil {
+					t.Fatal(err)
+				}
+				if d {
+					if diff := cmp.Diff(0, len(r.(*loggingReconciler).last)); diff != "" {
+						t.Errorf("\n%s\nReconcile(...): -want remembered resources, +got remembered resources:\n%s\n", tc.reason, diff)
+					}
+				}
+			}
+			if diff := cmp.Diff([]string{"web ready", "web ready"}, got); diff != "" {
+				t.Errorf("\n%s\nReconcile(...): -want lines, +got lines:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/policy"
+	"github.com/crossplane/provider-ssh/internal/recording"
+	"github.com/crossplane/provider-ssh/internal/signature"
+)
+
+// Options configures the SSH controllers. They extend the options shared by
+// Crossplane controllers with the tuning of the SSH clients.
+type Options struct {
+	controller.Options
+
+	// DialTimeout bounds the TCP connection and the SSH handshake with a
+	// host. Zero means no timeout.
+	DialTimeout time.Duration
+
+	// DefaultKnownHostsFile is the known_hosts file the host keys of the
+	// credentials without knownHosts are verified against. Their host keys
+	// are not verified if it is empty.
+	DefaultKnownHostsFile string
+
+	// MaxConcurrentScripts bounds the number of scripts executed at the
+	// same time across all controllers. Zero means no bound.
+	MaxConcurrentScripts int
+
+	// MaxReconcileRates overrides the maximum reconcile rate of the
+	// controllers of the supplied kinds, e.g. Script.
+	MaxReconcileRates map[string]int
+
+	// MaxOutputBytes is the maxOutputBytes of Scripts that do not set it.
+	// Zero means the default of the API.
+	MaxOutputBytes int64
+
+	// Pause pauses the reconciliation of every resource of the provider.
+	// Nothing is paused if it is nil.
+	Pause *Pause
+
+	// ProviderConfigRateLimiter limits the rate at which the resources of
+	// each ProviderConfig are reconciled. No such limit applies if it is
+	// nil.
+	ProviderConfigRateLimiter *ProviderConfigRateLimiter
+
+	// ResourceLocks are held by the reconciles of the resources, shared by
+	// all controllers. Reconciles of the same resource may overlap if it is
+	// nil.
+	ResourceLocks *ResourceLocks
+
+	// ScriptVerifier verifies the signatures of the scripts of Scripts,
+	// ScriptSets, NodeScripts and Commands before they are executed.
+	// Unsigned scripts are executed if it is nil.
+	ScriptVerifier *signature.Verifier
+
+	// ScriptPolicy rejects the dangerous commands of the scripts of
+	// Scripts, ScriptSets, NodeScripts and Commands before they are
+	// executed, and of Scripts when they are admitted. Every script is
+	// executed if it is nil.
+	ScriptPolicy *policy.Policy
+
+	// StatusCheckBatchWindow is how long the statusCheckScript of a Script
+	// waits for those of the other Scripts connecting to the same host, to
+	// be executed together over a single connection in a single session.
+	// Status checks are not batched if it is zero.
+	StatusCheckBatchWindow time.Duration
+
+	// MaxStatusCheckBatch bounds the number of statusCheckScripts executed
+	// in a single batch. Zero means no bound.
+	MaxStatusCheckBatch int
+
+	// SessionRecordings stores the recordings of the executions of the
+	// Scripts asking for them. Sessions are not recorded if it is nil.
+	SessionRecordings recording.Sink
+
+	// ReconcileLogger logs the lines the controllers log through the
+	// context of their reconciles. They are logged through the logger of
+	// the controller-runtime if it is zero.
+	ReconcileLogger logr.Logger
+
+	// LogStateChangesOnly drops the lines of the reconciles of a resource
+	// that are the same as those of its previous reconcile, which found it
+	// in the same state.
+	LogStateChangesOnly bool
+
+	// EventDeduplicationWindow is how long an Event of a resource is not
+	// emitted again, identical. Events are not deduplicated if it is zero.
+	EventDeduplicationWindow time.Duration
+}
+
+// ForKind returns the options of the controller of the supplied kind. A
+// controller with its own maximum reconcile rate is subject to both that
+// rate and the global rate limiter.
+func (o Options) ForKind(kind string) Options {
+	rate, ok := o.MaxReconcileRates[kind]
+	if !ok || rate <= 0 {
+		return o
+	}
+	o.MaxConcurrentReconciles = rate
+	o.GlobalRateLimiter = workqueue.NewMaxOfRateLimiter(o.GlobalRateLimiter, ratelimiter.NewGlobal(rate))
+	return o
+}
+
+// NewReconciler wraps the reconciler of the named controller of managed
+// resources of the supplied kind. Nothing is reconciled while the provider
+// is paused, and reconciles are subject to the rate limiter of the
+// ProviderConfig of the resource, then to the global rate limiter. A request
+// limited by its ProviderConfig does not consume the global rate. The
+// reconciles of a resource never overlap if ResourceLocks are set, and log
+// through the ReconcileLogger, if any. The clients connected by a connecter of
+// NewExternalConnectDisconnecter are disconnected at the end of each
+// reconcile.
+func NewReconciler(mgr ctrl.Manager, of resource.ManagedKind, name string, r reconcile.Reconciler, o Options) reconcile.Reconciler {
+	r = newLoggingReconciler(mgr.GetClient(), newManaged(mgr.GetScheme(), of), name, &disconnectingReconciler{inner: r}, o)
+	if o.ResourceLocks != nil {
+		// The lock of the resource is only held while it is reconciled,
+		// not while its reconcile waits for the rate limiters.
+		r = &lockingReconciler{kube: mgr.GetClient(), newObj: newManaged(mgr.GetScheme(), of), inner: r, locks: o.ResourceLocks, log: o.Logger.WithValues("controller", name)}
+	}
+	rl := reconcile.Reconciler(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+	if o.ProviderConfigRateLimiter != nil {
+		rl = newProviderConfigReconciler(name, mgr.GetClient(), mgr.GetScheme(), of, rl, o.ProviderConfigRateLimiter)
+	}
+	if o.Pause != nil {
+		rl = &pausableReconciler{
+			inner:        rl,
+			pause:        o.Pause,
+			pollInterval: o.PollInterval,
+			log:          o.Logger.WithValues("controller", name),
+		}
+	}
+	return rl
+}
+
+// NewServiceFn returns the function the controllers connect to hosts with.
+func (o Options) NewServiceFn() NewServiceFn {
+	return sshv1alpha1.NewSSHClientFn(o.DialTimeout, o.DefaultKnownHostsFile)
+}
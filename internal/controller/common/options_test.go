@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+)
+
+func TestForKind(t *testing.T) {
+	global := ratelimiter.NewGlobal(10)
+	o := Options{
+		Options:           controller.Options{MaxConcurrentReconciles: 10, GlobalRateLimiter: global},
+		MaxReconcileRates: map[string]int{"Script": 2},
+	}
+
+	type want struct {
+		maxConcurrentReconciles int
+		globalRateLimiter       bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		kind   string
+		want   want
+	}{
+		"Default": {
+			reason: "Kinds without their own rate should use the global options.",
+			kind:   "Command",
+			want:   want{maxConcurrentReconciles: 10, globalRateLimiter: true},
+		},
+		"Override": {
+			reason: "Kinds with their own rate should be limited by that rate on top of the global rate limiter.",
+			kind:   "Script",
+			want:   want{maxConcurrentReconciles: 2},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := o.ForKind(tc.kind)
+			if diff := cmp.Diff(tc.want.maxConcurrentReconciles, got.MaxConcurrentReconciles); diff != "" {
+				t.Errorf("\n%s\nForKind(...): -want max concurrent reconciles, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.globalRateLimiter, got.GlobalRateLimiter == global); diff != "" {
+				t.Errorf("\n%s\nForKind(...): -want global rate limiter, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
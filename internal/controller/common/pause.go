@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+const (
+	errGetPauseConfigMap = "cannot get pause ConfigMap"
+
+	// PauseKey is the key of the pause ConfigMap pausing the provider when
+	// its value is "true".
+	PauseKey = "paused"
+
+	// pauseCheckInterval is how long the content of the pause ConfigMap is
+	// cached.
+	pauseCheckInterval = 10 * time.Second
+)
+
+// A Pause reports whether the reconciliation of every resource of the
+// provider is paused, either through the paused flag of the provider or
+// through its pause ConfigMap.
+type Pause struct {
+	flag      bool
+	kube      client.Reader
+	configMap types.NamespacedName
+
+	mu      sync.Mutex
+	checked time.Time
+	paused  bool
+}
+
+// NewPause returns a Pause that is paused if the supplied flag is set, or
+// while the supplied ConfigMap sets the paused key to "true". The ConfigMap
+// is ignored if its name is empty.
+func NewPause(flag bool, kube client.Reader, configMap types.NamespacedName) *Pause {
+	return &Pause{flag: flag, kube: kube, configMap: configMap}
+}
+
+// IsPaused reports whether the provider is paused.
+func (p *Pause) IsPaused(ctx context.Context) (bool, error) {
+	if p.flag || p.configMap.Name == "" {
+		return p.flag, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.checked) < pauseCheckInterval {
+		return p.paused, nil
+	}
+	cm := &corev1.ConfigMap{}
+	err := p.kube.Get(ctx, p.configMap, cm)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return false, errors.Wrap(err, errGetPauseConfigMap)
+	}
+	p.checked, p.paused = time.Now(), err == nil && cm.Data[PauseKey] == "true"
+	return p.paused, nil
+}
+
+type pausableReconciler struct {
+	inner        reconcile.Reconciler
+	pause        *Pause
+	pollInterval time.Duration
+	log          logging.Logger
+}
+
+func (r *pausableReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	paused, err := r.pause.IsPaused(ctx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if paused {
+		r.log.Debug("Reconciliation is paused for the whole provider", "request", req)
+		return reconcile.Result{RequeueAfter: r.pollInterval}, nil
+	}
+	return r.inner.Reconcile(ctx, req)
+}
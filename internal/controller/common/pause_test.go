@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func configMap(data map[string]string, err error) *test.MockClient {
+	return &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+		if err != nil {
+			return err
+		}
+		obj.(*corev1.ConfigMap).Data = data
+		return nil
+	}}
+}
+
+func TestIsPaused(t *testing.T) {
+	errBoom := errors.New("boom")
+	cm := types.NamespacedName{Namespace: "crossplane-system", Name: "provider-ssh-pause"}
+
+	type want struct {
+		paused bool
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		pause  *Pause
+		want   want
+	}{
+		"Flag": {
+			reason: "The provider should be paused when its paused flag is set.",
+			pause:  NewPause(true, configMap(nil, errBoom), cm),
+			want:   want{paused: true},
+		},
+		"NoConfigMap": {
+			reason: "The provider should not be paused without the flag and a pause ConfigMap.",
+			pause:  NewPause(false, configMap(nil, errBoom), types.NamespacedName{}),
+			want:   want{paused: false},
+		},
+		"ConfigMapNotFound": {
+			reason: "The provider should not be paused while its pause ConfigMap does not exist.",
+			pause:  NewPause(false, configMap(nil, kerrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, cm.Name)), cm),
+			want:   want{paused: false},
+		},
+		"ConfigMapPaused": {
+			reason: "The provider should be paused while its pause ConfigMap sets the paused key to true.",
+			pause:  NewPause(false, configMap(map[string]string{PauseKey: "true"}, nil), cm),
+			want:   want{paused: true},
+		},
+		"ConfigMapNotPaused": {
+			reason: "The provider should not be paused while its pause ConfigMap sets the paused key to anything but true.",
+			pause:  NewPause(false, configMap(map[string]string{PauseKey: "false"}, nil), cm),
+			want:   want{paused: false},
+		},
+		"GetError": {
+			reason: "Errors getting the pause ConfigMap should be returned.",
+			pause:  NewPause(false, configMap(nil, errBoom), cm),
+			want:   want{err: errors.Wrap(errBoom, errGetPauseConfigMap)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			paused, err := tc.pause.IsPaused(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nIsPaused(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.paused, paused); diff != "" {
+				t.Errorf("\n%s\nIsPaused(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPausableReconciler(t *testing.T) {
+	cm := types.NamespacedName{Namespace: "crossplane-system", Name: "provider-ssh-pause"}
+
+	type want struct {
+		result     reconcile.Result
+		reconciled bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		pause  *Pause
+		want   want
+	}{
+		"Paused": {
+			reason: "Nothing should be reconciled while the provider is paused, the request should be retried after the poll interval.",
+			pause:  NewPause(false, configMap(map[string]string{PauseKey: "true"}, nil), cm),
+			want:   want{result: reconcile.Result{RequeueAfter: time.Minute}},
+		},
+		"NotPaused": {
+			reason: "Requests should be reconciled while the provider is not paused.",
+			pause:  NewPause(false, configMap(nil, nil), cm),
+			want:   want{reconciled: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reconciled := false
+			inner := reconcile.Func(func(context.Context, reconcile.Request) (reconcile.Result, error) {
+				reconciled = true
+				return reconcile.Result{}, nil
+			})
+			o := Options{
+				Options: controller.Options{Logger: logging.NewNopLogger(), PollInterval: time.Minute, GlobalRateLimiter: ratelimiter.NewGlobal(10)},
+				Pause:   tc.pause,
+			}
+			got, err := NewReconciler(&fake.Manager{}, resource.ManagedKind{}, "test", inner, o).Reconcile(context.Background(), reconcile.Request{})
+			if err != nil {
+				t.Fatalf("\n%s\nReconcile(...): %v\n", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.result, got); diff != "" {
+				t.Errorf("\n%s\nReconcile(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.reconciled, reconciled); diff != "" {
+				t.Errorf("\n%s\nReconcile(...): -want reconciled, +got reconciled:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/connection"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/features"
+)
+
+// ConnectionPublishers returns the publishers of the connection details of a
+// managed resource. Details are always written to the connection secret and,
+// when External Secret Stores are enabled, to the StoreConfig referenced by
+// publishConnectionDetailsTo. Plugin stores such as Vault are reached over
+// mTLS using the certificates loaded into the ESS options.
+func ConnectionPublishers(kube client.Client, o controller.Options) []managed.ConnectionPublisher {
+	cps := []managed.ConnectionPublisher{managed.NewAPISecretPublisher(kube, kube.Scheme())}
+	if !o.Features.Enabled(features.EnableAlphaExternalSecretStores) {
+		return cps
+	}
+	var opts []connection.DetailsManagerOption
+	if o.ESSOptions != nil && o.ESSOptions.TLSConfig != nil {
+		opts = append(opts, connection.WithTLSConfig(o.ESSOptions.TLSConfig))
+	}
+	return append(cps, connection.NewDetailsManager(kube, apisv1alpha1.StoreConfigGroupVersionKind, opts...))
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	essproto "github.com/crossplane/crossplane-runtime/apis/proto/v1alpha1"
+	"github.com/crossplane/crossplane-runtime/pkg/certificates"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/feature"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/provider-ssh/apis"
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/features"
+)
+
+// vaultPlugin is a fake of the Vault External Secret Store plugin that
+// records the secrets it is asked to apply.
+type vaultPlugin struct {
+	essproto.UnimplementedExternalSecretStorePluginServiceServer
+
+	mu      sync.Mutex
+	applied []*essproto.ApplySecretRequest
+}
+
+func (p *vaultPlugin) ApplySecret(_ context.Context, req *essproto.ApplySecretRequest) (*essproto.ApplySecretResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.applied = append(p.applied, req)
+	return &essproto.ApplySecretResponse{Changed: true}, nil
+}
+
+// writeCertificates writes a self-signed certificate for 127.0.0.1 to dir
+// using the file names expected by the ess-tls-cert-dir flag.
+func writeCertificates(t *testing.T, dir string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ess-plugin-vault"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	files := map[string][]byte{
+		"ca.crt":  crt,
+		"tls.crt": crt,
+		"tls.key": pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb}),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func loadCertificates(t *testing.T, dir string, isServer bool) *tls.Config {
+	t.Helper()
+	tcfg, err := certificates.LoadMTLSConfig(filepath.Join(dir, "ca.crt"), filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), isServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tcfg
+}
+
+func TestConnectionPublishers(t *testing.T) {
+	dir := t.TempDir()
+	writeCertificates(t, dir)
+
+	plugin := &vaultPlugin{}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(loadCertificates(t, dir, true))))
+	essproto.RegisterExternalSecretStorePluginServiceServer(srv, plugin)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	storeType := xpv1.SecretStorePlugin
+	kube := &test.MockClient{
+		MockScheme: test.NewMockSchemeFn(s),
+		MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+			obj.(*apisv1alpha1.StoreConfig).Spec.SecretStoreConfig = xpv1.SecretStoreConfig{
+				Type:         &storeType,
+				DefaultScope: "crossplane-system",
+				Plugin: &xpv1.PluginStoreConfig{
+					Endpoint: lis.Addr().String(),
+					ConfigRef: xpv1.Config{
+						APIVersion: "secrets.crossplane.io/v1alpha1",
+						Kind:       "VaultConfig",
+						Name:       "vault-internal",
+					},
+				},
+			}
+			return nil
+		},
+	}
+
+	ess := &feature.Flags{}
+	ess.Enable(features.EnableAlphaExternalSecretStores)
+
+	type want struct {
+		publishers int
+		published  bool
+		err        bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		o      controller.Options
+		want   want
+	}{
+		"Disabled": {
+			reason: "Only the connection secret should be published when External Secret Stores are disabled.",
+			o:      controller.Options{Features: &feature.Flags{}},
+			want:   want{publishers: 1},
+		},
+		"NoCertificates": {
+			reason: "The Vault plugin should reject connections that do not present the ESS certificates.",
+			o:      controller.Options{Features: ess},
+			want:   want{publishers: 2, err: true},
+		},
+		"Vault": {
+			reason: "Connection details should be published to the Vault plugin over mTLS.",
+			o:      controller.Options{Features: ess, ESSOptions: &controller.ESSOptions{TLSConfig: loadCertificates(t, dir, false)}},
+			want:   want{publishers: 2, published: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			plugin.mu.Lock()
+			plugin.applied = nil
+			plugin.mu.Unlock()
+
+			cps := ConnectionPublishers(kube, tc.o)
+			if diff := cmp.Diff(tc.want.publishers, len(cps)); diff != "" {
+				t.Fatalf("\n%s\nConnectionPublishers(...): -want publishers, +got publishers:\n%s\n", tc.reason, diff)
+			}
+			if len(cps) < 2 {
+				return
+			}
+
+			cr := &apisv1alpha1.Script{
+				ObjectMeta: metav1.ObjectMeta{Name: "install", UID: "uid"},
+				Spec: apisv1alpha1.ScriptSpec{
+					ResourceSpec: xpv1.ResourceSpec{
+						PublishConnectionDetailsTo: &xpv1.PublishConnectionDetailsTo{
+							Name:                 "install-outputs",
+							SecretStoreConfigRef: &xpv1.Reference{Name: "vault"},
+						},
+					},
+				},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			published, err := cps[1].PublishConnection(ctx, cr, managed.ConnectionDetails{"stdout": []byte("done")})
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want error, +got error: %v\n%s\n", tc.reason, err, diff)
+			}
+			if diff := cmp.Diff(tc.want.published, published); diff != "" {
+				t.Errorf("\n%s\nPublishConnection(...): -want published, +got published:\n%s\n", tc.reason, diff)
+			}
+			if !tc.want.published {
+				return
+			}
+
+			plugin.mu.Lock()
+			defer plugin.mu.Unlock()
+			if len(plugin.applied) != 1 {
+				t.Fatalf("\n%s\nApplySecret(...): want 1 call, got %d\n", tc.reason, len(plugin.applied))
+			}
+			req := plugin.applied[0]
+			type applied struct {
+				ScopedName string
+				Data       map[string][]byte
+				Kind       string
+				Name       string
+			}
+			got := applied{
+				ScopedName: req.GetSecret().GetScopedName(),
+				Data:       req.GetSecret().GetData(),
+				Kind:       req.GetConfig().GetKind(),
+				Name:       req.GetConfig().GetName(),
+			}
+			want := applied{
+				ScopedName: "crossplane-system/install-outputs",
+				Data:       map[string][]byte{"stdout": []byte("done")},
+				Kind:       "VaultConfig",
+				Name:       "vault-internal",
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("\n%s\nApplySecret(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
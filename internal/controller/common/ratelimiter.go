@@ -0,0 +1,162 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"sync"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+// A ProviderConfigRateLimiter limits the rate at which the resources of each
+// ProviderConfig are reconciled across all controllers, so that the failing
+// resources of one ProviderConfig cannot starve the others.
+type ProviderConfigRateLimiter struct {
+	rate int
+
+	mu       sync.Mutex
+	limiters map[string]workqueue.RateLimiter
+}
+
+// NewProviderConfigRateLimiter returns a rate limiter allowing the supplied
+// number of reconciles per second for the resources of each ProviderConfig.
+func NewProviderConfigRateLimiter(rate int) *ProviderConfigRateLimiter {
+	return &ProviderConfigRateLimiter{rate: rate, limiters: map[string]workqueue.RateLimiter{}}
+}
+
+// limiter returns the rate limiter of the named ProviderConfig.
+func (l *ProviderConfigRateLimiter) limiter(name string) workqueue.RateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rl, ok := l.limiters[name]
+	if !ok {
+		rl = ratelimiter.NewGlobal(l.rate)
+		l.limiters[name] = rl
+	}
+	return rl
+}
+
+// forget drops the rate limiter of the named ProviderConfig.
+func (l *ProviderConfigRateLimiter) forget(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.limiters, name)
+}
+
+// A providerConfigReconciler rate limits the reconciles of an inner
+// Reconciler by the ProviderConfig of the reconciled resource. The rate
+// limiters of the ProviderConfigs that are gone or being deleted are
+// forgotten, and their resources reconciled without them.
+type providerConfigReconciler struct {
+	name    string
+	kube    client.Reader
+	newObj  func() (resource.Managed, error)
+	inner   reconcile.Reconciler
+	limiter *ProviderConfigRateLimiter
+
+	mu          sync.Mutex
+	reconcilers map[string]reconcile.Reconciler
+}
+
+func newProviderConfigReconciler(name string, kube client.Reader, s *runtime.Scheme, of resource.ManagedKind, r reconcile.Reconciler, l *ProviderConfigRateLimiter) *providerConfigReconciler {
+	return &providerConfigReconciler{
+		name:        name,
+		kube:        kube,
+		newObj:      newManaged(s, of),
+		inner:       r,
+		limiter:     l,
+		reconcilers: map[string]reconcile.Reconciler{},
+	}
+}
+
+// newManaged returns a function creating empty managed resources of the
+// supplied kind.
+func newManaged(s *runtime.Scheme, of resource.ManagedKind) func() (resource.Managed, error) {
+	return func() (resource.Managed, error) {
+		obj, err := s.New(schema.GroupVersionKind(of))
+		if err != nil {
+			return nil, err
+		}
+		mg, _ := obj.(resource.Managed)
+		return mg, nil
+	}
+}
+
+// forProviderConfig returns the inner Reconciler limited by the rate of the
+// named ProviderConfig.
+func (r *providerConfigReconciler) forProviderConfig(name string) reconcile.Reconciler {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rl, ok := r.reconcilers[name]
+	if !ok {
+		rl = ratelimiter.NewReconciler(r.name, r.inner, r.limiter.limiter(name))
+		r.reconcilers[name] = rl
+	}
+	return rl
+}
+
+// forget drops the rate limiters of the named ProviderConfig.
+func (r *providerConfigReconciler) forget(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.reconcilers, name)
+	r.limiter.forget(name)
+}
+
+// gone reports whether the named ProviderConfig is gone or being deleted.
+// ProviderConfigs that cannot be read otherwise are not.
+func (r *providerConfigReconciler) gone(ctx context.Context, name string) bool {
+	pc := &apisv1alpha1.ProviderConfig{}
+	if err := r.kube.Get(ctx, types.NamespacedName{Name: name}, pc); err != nil {
+		return kerrors.IsNotFound(err)
+	}
+	return meta.WasDeleted(pc)
+}
+
+func (r *providerConfigReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	mg, err := r.newObj()
+	if err != nil || mg == nil {
+		return r.inner.Reconcile(ctx, req)
+	}
+	// Resources that cannot be read are left to the inner Reconciler, which
+	// reports why.
+	if err := r.kube.Get(ctx, req.NamespacedName, mg); err != nil {
+		return r.inner.Reconcile(ctx, req)
+	}
+	ref := mg.GetProviderConfigReference()
+	if ref == nil {
+		return r.inner.Reconcile(ctx, req)
+	}
+	if r.gone(ctx, ref.Name) {
+		r.forget(ref.Name)
+		return r.inner.Reconcile(ctx, req)
+	}
+	return r.forProviderConfig(ref.Name).Reconcile(ctx, req)
+}
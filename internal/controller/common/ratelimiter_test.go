@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/provider-ssh/apis"
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+func TestProviderConfigRateLimiter(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	// The name of a Command is prefixed with the name of its ProviderConfig.
+	kube := &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+		if cr, ok := obj.(*apisv1alpha1.Command); ok {
+			pc, _, _ := strings.Cut(key.Name, "-")
+			cr.Spec.ProviderConfigReference = &xpv1.Reference{Name: pc}
+		}
+		return nil
+	}}
+	mgr := &fake.Manager{Client: kube, Scheme: s}
+
+	reconciled := map[string]int{}
+	inner := reconcile.Func(func(_ context.Context, req reconcile.Request) (reconcile.Result, error) {
+		pc, _, _ := strings.Cut(req.Name, "-")
+		reconciled[pc]++
+		return reconcile.Result{}, nil
+	})
+	o := Options{
+		Options:                   controller.Options{Logger: logging.NewNopLogger(), GlobalRateLimiter: ratelimiter.NewGlobal(1000)},
+		ProviderConfigRateLimiter: NewProviderConfigRateLimiter(1),
+	}
+	r := NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.CommandGroupVersionKind), "test", inner, o)
+
+	// A rate of 1 allows a burst of 10 reconciles per ProviderConfig.
+	limited := 0
+	for i := 0; i < 20; i++ {
+		got, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: fmt.Sprintf("failing-%d", i)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.RequeueAfter > 0 {
+			limited++
+		}
+	}
+	if _, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "healthy-0"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(map[string]int{"failing": 10, "healthy": 1}, reconciled); diff != "" {
+		t.Errorf("Reconcile(...): -want reconciles per ProviderConfig, +got:\n%s\n", diff)
+	}
+	if diff := cmp.Diff(10, limited); diff != "" {
+		t.Errorf("Reconcile(...): -want rate limited requests, +got:\n%s\n", diff)
+	}
+}
+
+func TestProviderConfigRateLimiterGone(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		reason string
+		get    func(pc *apisv1alpha1.ProviderConfig) error
+	}{
+		"NotFound": {
+			reason: "The rate limiters of a ProviderConfig that is gone should be forgotten, and its resources reconciled without them.",
+			get: func(_ *apisv1alpha1.ProviderConfig) error {
+				return kerrors.NewNotFound(schema.GroupResource{Resource: "providerconfigs"}, "default")
+			},
+		},
+		"Deleting": {
+			reason: "The rate limiters of a ProviderConfig being deleted should be forgotten, and its resources reconciled without them.",
+			get: func(pc *apisv1alpha1.ProviderConfig) error {
+				now := metav1.Now()
+				pc.SetDeletionTimestamp(&now)
+				return nil
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gone := false
+			kube := &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+				switch o := obj.(type) {
+				case *apisv1alpha1.Command:
+					o.Spec.ProviderConfigReference = &xpv1.Reference{Name: "default"}
+				case *apisv1alpha1.ProviderConfig:
+					if gone {
+						return tc.get(o)
+					}
+				}
+				return nil
+			}}
+			reconciled := 0
+			inner := reconcile.Func(func(_ context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				reconciled++
+				return reconcile.Result{}, nil
+			})
+			l := NewProviderConfigRateLimiter(1)
+			r := newProviderConfigReconciler("test", kube, s, resource.ManagedKind(apisv1alpha1.CommandGroupVersionKind), inner, l)
+
+			// A rate of 1 allows a burst of 10 reconciles per
+			// ProviderConfig, which the first reconciles exhaust.
+			for i := 0; i < 20; i++ {
+				if i == 10 {
+					gone = true
+				}
+				got, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: fmt.Sprintf("cmd-%d", i)}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.RequeueAfter > 0 {
+					t.Errorf("\n%s\nReconcile(...): request %d was rate limited\n", tc.reason, i)
+				}
+			}
+			if diff := cmp.Diff(20, reconciled); diff != "" {
+				t.Errorf("\n%s\nReconcile(...): -want reconciles, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(0, len(r.reconcilers)+len(l.limiters)); diff != "" {
+				t.Errorf("\n%s\nReconcile(...): -want rate limiters, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common contains helpers shared by the managed resource controllers.
+package common
+
+import (
+	"context"
+	"reflect"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+)
+
+// A StatusPreservingUpdater persists the critical annotations of a managed
+// resource without discarding the status written by Create. The wrapped
+// updater refreshes the supplied object from the API server, which would
+// otherwise drop the recorded results of the scripts executed by Create.
+type StatusPreservingUpdater struct {
+	wrapped managed.CriticalAnnotationUpdater
+}
+
+// NewStatusPreservingUpdater returns a StatusPreservingUpdater that wraps the
+// default critical annotation updater of the managed reconciler.
+func NewStatusPreservingUpdater(c client.Client) *StatusPreservingUpdater {
+	return &StatusPreservingUpdater{wrapped: managed.NewRetryingCriticalAnnotationUpdater(c)}
+}
+
+// UpdateCriticalAnnotations updates the critical annotations of the supplied
+// object and restores its Status field afterwards.
+func (u *StatusPreservingUpdater) UpdateCriticalAnnotations(ctx context.Context, o client.Object) error {
+	saved := o.DeepCopyObject()
+	err := u.wrapped.UpdateCriticalAnnotations(ctx, o)
+	dst := reflect.ValueOf(o).Elem().FieldByName("Status")
+	if dst.IsValid() && dst.CanSet() {
+		dst.Set(reflect.ValueOf(saved).Elem().FieldByName("Status"))
+	}
+	return err
+}
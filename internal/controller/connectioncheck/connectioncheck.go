@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectioncheck
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotConnectionCheck = "managed resource is not a ConnectionCheck custom resource"
+)
+
+// Setup adds a controller that reconciles ConnectionCheck managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.ConnectionCheckGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.ConnectionCheckGroupVersionKind),
+		managed.WithExternalConnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			newServiceFn: o.NewServiceFn()}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithPollIntervalHook(pollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.ConnectionCheck{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.ConnectionCheckGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	newServiceFn common.NewServiceFn
+}
+
+// Connect reads the credentials of the ProviderConfig referenced by the
+// ConnectionCheck. The connection itself is made, and measured, by Observe.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*apisv1alpha1.ConnectionCheck)
+	if !ok {
+		return nil, errors.New(errNotConnectionCheck)
+	}
+
+	if meta.WasDeleted(cr) {
+		return &external{}, nil
+	}
+
+	creds, err := common.Credentials(ctx, c.kube, c.usage, cr)
+	if err != nil {
+		return nil, err
+	}
+	if creds, err = common.Endpoint(cr.Spec.ForProvider.Endpoint)(creds); err != nil {
+		return nil, err
+	}
+	return &external{creds: creds, newServiceFn: c.newServiceFn}, nil
+}
+
+// An ExternalClient connects and authenticates to a host to check that it is
+// reachable. The check owns nothing on the host, so nothing is ever created,
+// updated or deleted.
+type external struct {
+	creds        []byte
+	newServiceFn common.NewServiceFn
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.Info(fmt.Sprintf("[%s] Checking connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.ConnectionCheck)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotConnectionCheck)
+	}
+
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	start := time.Now()
+	svc, err := c.newServiceFn(ctx, c.creds)
+	now := metav1.Now()
+	st := &cr.Status.AtProvider
+	st.LastCheckTime = &now
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s] Checking connection failed: %s", mg.GetName(), err))
+		st.Reachable, st.Message = false, err.Error()
+		st.ConsecutiveFailures++
+		cr.SetConditions(xpv1.Unavailable().WithMessage(err.Error()))
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+	_ = svc.Close()
+
+	st.Reachable, st.Message, st.ConsecutiveFailures = true, "", 0
+	st.LatencyMilliseconds = now.Sub(start).Milliseconds()
+	st.LastSuccessTime = &now
+	logger.Info(fmt.Sprintf("[%s] Checking connection [okay], %dms", mg.GetName(), st.LatencyMilliseconds))
+	cr.SetConditions(xpv1.Available())
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+}
+
+func (c *external) Create(_ context.Context, _ resource.Managed) (managed.ExternalCreation, error) {
+	return managed.ExternalCreation{}, nil
+}
+
+func (c *external) Update(_ context.Context, _ resource.Managed) (managed.ExternalUpdate, error) {
+	return managed.ExternalUpdate{}, nil
+}
+
+func (c *external) Delete(_ context.Context, _ resource.Managed) error {
+	return nil
+}
+
+// pollInterval checks the connection at the interval of the ConnectionCheck.
+func pollInterval(mg resource.Managed, pollInterval time.Duration) time.Duration {
+	cr, ok := mg.(*apisv1alpha1.ConnectionCheck)
+	if !ok || cr.Spec.ForProvider.IntervalSeconds == nil {
+		return pollInterval
+	}
+	return time.Duration(*cr.Spec.ForProvider.IntervalSeconds) * time.Second
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectioncheck
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+// server starts an SSH server accepting any client and returns a
+// NewServiceFn that connects to it.
+func server(t *testing.T) common.NewServiceFn {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		config := &ssh.ServerConfig{NoClientAuth: true}
+		config.AddHostKey(signer)
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close() // nolint: errcheck
+				if _, chans, reqs, err := ssh.NewServerConn(conn, config); err == nil {
+					go ssh.DiscardRequests(reqs)
+					for ch := range chans {
+						_ = ch.Reject(ssh.Prohibited, "no channels")
+					}
+				}
+			}()
+		}
+	}()
+
+	return func(_ context.Context, _ []byte) (*ssh.Client, error) {
+		// nolint: gosec
+		return ssh.Dial("tcp", l.Addr().String(), &ssh.ClientConfig{User: "test", HostKeyCallback: ssh.InsecureIgnoreHostKey()})
+	}
+}
+
+func TestObserve(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type want struct {
+		o          managed.ExternalObservation
+		err        error
+		reachable  bool
+		failures   int
+		conditions xpv1.Condition
+	}
+
+	cases := map[string]struct {
+		reason       string
+		newServiceFn common.NewServiceFn
+		mg           resource.Managed
+		want         want
+	}{
+		"NotConnectionCheck": {
+			reason: "We should return an error if the managed resource is not a ConnectionCheck.",
+			mg:     &fake.Managed{},
+			want:   want{err: errors.New(errNotConnectionCheck)},
+		},
+		"Deleted": {
+			reason: "A deleted ConnectionCheck should not exist.",
+			mg: func() resource.Managed {
+				cr := &apisv1alpha1.ConnectionCheck{}
+				now := metav1.Now()
+				cr.SetDeletionTimestamp(&now)
+				return cr
+			}(),
+			want: want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"Reachable": {
+			reason:       "A host that accepts the connection should be reported reachable.",
+			newServiceFn: server(t),
+			mg: func() resource.Managed {
+				cr := &apisv1alpha1.ConnectionCheck{}
+				cr.Status.AtProvider.ConsecutiveFailures = 2
+				return cr
+			}(),
+			want: want{
+				o:          managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				reachable:  true,
+				conditions: xpv1.Available(),
+			},
+		},
+		"Unreachable": {
+			reason: "A failed connection should be reported in the status, not as an error.",
+			newServiceFn: func(_ context.Context, _ []byte) (*ssh.Client, error) {
+				return nil, errBoom
+			},
+			mg: &apisv1alpha1.ConnectionCheck{},
+			want: want{
+				o:          managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				failures:   1,
+				conditions: xpv1.Unavailable().WithMessage(errBoom.Error()),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{newServiceFn: tc.newServiceFn}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			cr, ok := tc.mg.(*apisv1alpha1.ConnectionCheck)
+			if !ok || !got.ResourceExists {
+				return
+			}
+			st := cr.Status.AtProvider
+			if st.Reachable != tc.want.reachable || st.ConsecutiveFailures != tc.want.failures {
+				t.Errorf("\n%s\ne.Observe(...): want reachable %t with %d failures, got %t with %d failures\n",
+					tc.reason, tc.want.reachable, tc.want.failures, st.Reachable, st.ConsecutiveFailures)
+			}
+			if tc.want.reachable && st.LastSuccessTime == nil {
+				t.Errorf("\n%s\ne.Observe(...): want last success time\n", tc.reason)
+			}
+			if diff := cmp.Diff(tc.want.conditions, cr.GetCondition(xpv1.TypeReady), test.EquateConditions()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want condition, +got condition:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	poll := time.Minute
+	thirty := int64(30)
+
+	cases := map[string]struct {
+		reason string
+		mg     resource.Managed
+		want   time.Duration
+	}{
+		"Default": {
+			reason: "Without an interval the poll interval of the provider should be used.",
+			mg:     &apisv1alpha1.ConnectionCheck{},
+			want:   poll,
+		},
+		"Interval": {
+			reason: "The interval of the ConnectionCheck should be used.",
+			mg: &apisv1alpha1.ConnectionCheck{Spec: apisv1alpha1.ConnectionCheckSpec{
+				ForProvider: apisv1alpha1.ConnectionCheckParameters{IntervalSeconds: &thirty},
+			}},
+			want: 30 * time.Second,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, pollInterval(tc.mg, poll)); diff != "" {
+				t.Errorf("\n%s\npollInterval(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
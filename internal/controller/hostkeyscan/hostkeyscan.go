@@ -0,0 +1,198 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostkeyscan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"golang.org/x/crypto/ssh"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotHostKeyScan = "managed resource is not a HostKeyScan custom resource"
+	errScan           = "cannot scan host keys"
+	errNoKeys         = "host offered none of the requested key types"
+
+	// keyKnownHosts is the connection detail the known_hosts lines are
+	// published under.
+	keyKnownHosts = "knownHosts"
+
+	reasonHostKeyChanged event.Reason = "HostKeyChanged"
+)
+
+// defaultKeyTypes are the key types scanned if none are configured.
+var defaultKeyTypes = []string{"ed25519", "ecdsa", "rsa"}
+
+// A ScanFn returns the host keys of the supplied types of the host at address.
+type ScanFn func(ctx context.Context, address string, keyTypes []string) ([]ssh.PublicKey, error)
+
+// Setup adds a controller that reconciles HostKeyScan managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.HostKeyScanGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	recorder := common.NewEventRecorder(mgr, name, o)
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.HostKeyScanGroupVersionKind),
+		managed.WithExternalConnecter(&connector{
+			scanFn:   sshv1alpha1.ScanHostKeys,
+			recorder: recorder}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(recorder),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.HostKeyScan{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.HostKeyScanGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	scanFn   ScanFn
+	recorder event.Recorder
+}
+
+// Connect produces an ExternalClient for the HostKeyScan. The host is scanned
+// without authentication, so the ProviderConfig is not used.
+func (c *connector) Connect(_ context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	if _, ok := mg.(*apisv1alpha1.HostKeyScan); !ok {
+		return nil, errors.New(errNotHostKeyScan)
+	}
+	return &external{scanFn: c.scanFn, recorder: c.recorder}, nil
+}
+
+// An ExternalClient scans the host keys of a host. The keys are owned by the
+// host, so they are never created, updated or deleted.
+type external struct {
+	scanFn ScanFn
+	// A recorder of the Events emitted for the HostKeyScan.
+	recorder event.Recorder
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.HostKeyScan)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotHostKeyScan)
+	}
+
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	address := address(cr.Spec.ForProvider)
+	keys, err := c.scanFn(ctx, address, keyTypes(cr.Spec.ForProvider))
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errScan)
+	}
+
+	lines := make([]string, 0, len(keys))
+	scanned := make([]apisv1alpha1.ScannedHostKey, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, sshv1alpha1.KnownHostsLine(address, k))
+		scanned = append(scanned, apisv1alpha1.ScannedHostKey{Type: k.Type(), Fingerprint: ssh.FingerprintSHA256(k)})
+	}
+	if changed := changedKeys(cr.Status.AtProvider.Keys, scanned); len(changed) > 0 {
+		c.recorder.Event(cr, event.Warning(reasonHostKeyChanged, errors.Errorf("host keys of %s changed: %s", address, strings.Join(changed, ", "))))
+	}
+
+	now := metav1.Now()
+	cr.Status.AtProvider = apisv1alpha1.HostKeyScanObservation{Keys: scanned, LastScanTime: &now}
+	if len(keys) == 0 {
+		cr.SetConditions(xpv1.Unavailable().WithMessage(errNoKeys))
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. Scanned %d keys.", mg.GetName(), len(keys)))
+	cr.SetConditions(xpv1.Available())
+	cd := managed.ConnectionDetails{keyKnownHosts: []byte(strings.Join(lines, "\n") + "\n")}
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true, ConnectionDetails: cd}, nil
+}
+
+func (c *external) Create(_ context.Context, _ resource.Managed) (managed.ExternalCreation, error) {
+	return managed.ExternalCreation{}, nil
+}
+
+func (c *external) Update(_ context.Context, _ resource.Managed) (managed.ExternalUpdate, error) {
+	return managed.ExternalUpdate{}, nil
+}
+
+func (c *external) Delete(_ context.Context, _ resource.Managed) error {
+	return nil
+}
+
+// address returns the address of the SSH server to scan.
+func address(p apisv1alpha1.HostKeyScanParameters) string {
+	port := 22
+	if p.Port != nil {
+		port = *p.Port
+	}
+	return net.JoinHostPort(p.Host, strconv.Itoa(port))
+}
+
+// keyTypes returns the key types to scan for.
+func keyTypes(p apisv1alpha1.HostKeyScanParameters) []string {
+	if len(p.KeyTypes) == 0 {
+		return defaultKeyTypes
+	}
+	types := make([]string, len(p.KeyTypes))
+	for i, t := range p.KeyTypes {
+		types[i] = string(t)
+	}
+	return types
+}
+
+// changedKeys returns the types of the previously observed keys whose
+// fingerprint differs from the scanned key of the same type.
+func changedKeys(observed, scanned []apisv1alpha1.ScannedHostKey) []string {
+	fingerprints := make(map[string]string, len(scanned))
+	for _, k := range scanned {
+		fingerprints[k.Type] = k.Fingerprint
+	}
+	var changed []string
+	for _, k := range observed {
+		if fp, ok := fingerprints[k.Type]; ok && fp != k.Fingerprint {
+			changed = append(changed, k.Type)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostkeyscan
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+func hostKey(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func TestObserve(t *testing.T) {
+	key := hostKey(t).PublicKey()
+	errBoom := errors.New("boom")
+
+	type want struct {
+		o   managed.ExternalObservation
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		scanFn ScanFn
+		mg     resource.Managed
+		want   want
+	}{
+		"NotHostKeyScan": {
+			reason: "We should return an error if the managed resource is not a HostKeyScan.",
+			mg:     &fake.Managed{},
+			want: want{
+				err: errors.New(errNotHostKeyScan),
+			},
+		},
+		"ScanError": {
+			reason: "We should return an error if the host cannot be scanned.",
+			scanFn: func(_ context.Context, _ string, _ []string) ([]ssh.PublicKey, error) {
+				return nil, errBoom
+			},
+			mg: &apisv1alpha1.HostKeyScan{Spec: apisv1alpha1.HostKeyScanSpec{ForProvider: apisv1alpha1.HostKeyScanParameters{Host: "example.com"}}},
+			want: want{
+				err: errors.Wrap(errBoom, errScan),
+			},
+		},
+		"Scanned": {
+			reason: "The scanned keys should be published in known_hosts format.",
+			scanFn: func(_ context.Context, address string, keyTypes []string) ([]ssh.PublicKey, error) {
+				if address != "example.com:22" || len(keyTypes) != len(defaultKeyTypes) {
+					return nil, errBoom
+				}
+				return []ssh.PublicKey{key}, nil
+			},
+			mg: &apisv1alpha1.HostKeyScan{Spec: apisv1alpha1.HostKeyScanSpec{ForProvider: apisv1alpha1.HostKeyScanParameters{Host: "example.com"}}},
+			want: want{
+				o: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: true,
+					ConnectionDetails: managed.ConnectionDetails{
+						keyKnownHosts: []byte("example.com " + string(ssh.MarshalAuthorizedKey(key))),
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{scanFn: tc.scanFn, recorder: event.NewNopRecorder()}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestChangedKeys(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		observed []apisv1alpha1.ScannedHostKey
+		scanned  []apisv1alpha1.ScannedHostKey
+		want     []string
+	}{
+		"FirstScan": {
+			reason:  "Keys scanned for the first time should not be reported as changed.",
+			scanned: []apisv1alpha1.ScannedHostKey{{Type: ssh.KeyAlgoED25519, Fingerprint: "a"}},
+		},
+		"Unchanged": {
+			reason:   "Keys with the same fingerprint should not be reported as changed.",
+			observed: []apisv1alpha1.ScannedHostKey{{Type: ssh.KeyAlgoED25519, Fingerprint: "a"}},
+			scanned:  []apisv1alpha1.ScannedHostKey{{Type: ssh.KeyAlgoED25519, Fingerprint: "a"}, {Type: ssh.KeyAlgoRSA, Fingerprint: "b"}},
+		},
+		"Changed": {
+			reason:   "Keys with a different fingerprint should be reported as changed.",
+			observed: []apisv1alpha1.ScannedHostKey{{Type: ssh.KeyAlgoED25519, Fingerprint: "a"}, {Type: ssh.KeyAlgoRSA, Fingerprint: "b"}},
+			scanned:  []apisv1alpha1.ScannedHostKey{{Type: ssh.KeyAlgoED25519, Fingerprint: "c"}, {Type: ssh.KeyAlgoRSA, Fingerprint: "b"}},
+			want:     []string{ssh.KeyAlgoED25519},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := changedKeys(tc.observed, tc.scanned)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nchangedKeys(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestScanHostKeys(t *testing.T) {
+	signer := hostKey(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint: errcheck
+
+	go func() {
+		config := &ssh.ServerConfig{NoClientAuth: true}
+		config.AddHostKey(signer)
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close() // nolint: errcheck
+				_, _, _, _ = ssh.NewServerConn(conn, config)
+			}()
+		}
+	}()
+
+	keys, err := sshv1alpha1.ScanHostKeys(context.Background(), l.Addr().String(), defaultKeyTypes)
+	if err != nil {
+		t.Fatalf("ScanHostKeys(...): unexpected error: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("ScanHostKeys(...): want 1 key, got %d", len(keys))
+	}
+	if diff := cmp.Diff(signer.PublicKey().Marshal(), keys[0].Marshal()); diff != "" {
+		t.Errorf("ScanHostKeys(...): -want, +got:\n%s\n", diff)
+	}
+}
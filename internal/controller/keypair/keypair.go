@@ -0,0 +1,318 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keypair
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"golang.org/x/crypto/ssh"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotKeyPair   = "managed resource is not a KeyPair custom resource"
+	errNoSecretRef  = "writeConnectionSecretToRef is required to store the key pair"
+	errGetSecret    = "cannot get connection secret"
+	errParseKey     = "cannot parse public key of the connection secret"
+	errNotInstalled = "public key is not installed"
+	errGenerateKey  = "cannot generate key pair"
+	errCreateCreds  = "cannot create credentials"
+	errNewClient    = "cannot create new Service"
+	errNotConnected = "cannot install public key without a connection to the host"
+
+	defaultBits = 4096
+)
+
+// Keys of the connection secret of a KeyPair.
+const (
+	keyPrivateKey  = "privateKey"
+	keyPublicKey   = "publicKey"
+	keyCredentials = "credentials"
+)
+
+// Setup adds a controller that reconciles KeyPair managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.KeyPairGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.KeyPairGroupVersionKind),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			newServiceFn: o.NewServiceFn()})),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.KeyPair{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.KeyPairGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	newServiceFn common.NewServiceFn
+}
+
+// Connect produces an ExternalClient for the KeyPair. It only connects to the
+// host of the ProviderConfig if the public key is installed on the host.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.KeyPair)
+	if !ok {
+		return nil, errors.New(errNotKeyPair)
+	}
+	if cr.GetWriteConnectionSecretToReference() == nil {
+		return nil, errors.New(errNoSecretRef)
+	}
+
+	if cr.Spec.ForProvider.Install == nil {
+		logger.Info(fmt.Sprintf("[%s] Key is not installed. Skip the connection.", mg.GetName()))
+		return &external{kube: c.kube}, nil
+	}
+
+	data, err := common.Credentials(ctx, c.kube, c.usage, cr)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := c.newServiceFn(ctx, data)
+	if err != nil {
+		return nil, errors.Wrap(err, errNewClient)
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
+	return &external{kube: c.kube, service: sshv1alpha1.NewSSHExecutor(svc), creds: data}, nil
+}
+
+// An ExternalClient generates the key pair of a KeyPair and installs its
+// public key on the host.
+type external struct {
+	kube client.Client
+	// A 'client' used to connect to the external resource API.
+	service sshv1alpha1.RemoteExecutor
+	creds   []byte
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.KeyPair)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotKeyPair)
+	}
+
+	details, err := c.stored(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	if details == nil {
+		logger.Info(fmt.Sprintf("[%s] Observing, key pair is not generated.", mg.GetName()))
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	key, err := sshv1alpha1.ParseAuthorizedKey(string(details[keyPublicKey]))
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errParseKey)
+	}
+	cr.Status.AtProvider.PublicKey = key.String()
+	cr.Status.AtProvider.Fingerprint = ssh.FingerprintSHA256(key.Key)
+
+	inst := cr.Spec.ForProvider.Install
+	if inst == nil {
+		cr.Status.AtProvider.Installed = false
+		cr.SetConditions(xpv1.Available())
+		return managed.ExternalObservation{ResourceExists: !meta.WasDeleted(cr), ResourceUpToDate: true, ConnectionDetails: details}, nil
+	}
+
+	key.Options = inst.Options
+	found, err := c.installed(ctx, inst, key)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: len(found) > 0, ResourceUpToDate: true}, nil
+	}
+
+	details[keyCredentials], err = sshv1alpha1.Credentials(c.creds, inst.User, details[keyPrivateKey])
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errCreateCreds)
+	}
+
+	upToDate := len(found) == 1 && found[0] == key.String()
+	cr.Status.AtProvider.Installed = upToDate
+	if upToDate {
+		cr.SetConditions(xpv1.Available())
+	} else {
+		cr.SetConditions(xpv1.Unavailable().WithMessage(errNotInstalled))
+	}
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. Installed: %t", mg.GetName(), upToDate))
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: upToDate, ConnectionDetails: details}, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	logger := log.FromContext(ctx).WithName("[CREATE]")
+	logger.Info(fmt.Sprintf("[%s] Generating key pair...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.KeyPair)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotKeyPair)
+	}
+
+	p := cr.Spec.ForProvider
+	keyType := p.Type
+	if keyType == "" {
+		keyType = sshv1alpha1.KeyTypeED25519
+	}
+	bits := defaultBits
+	if p.Bits != nil {
+		bits = *p.Bits
+	}
+	private, public, err := sshv1alpha1.GenerateKeyPair(keyType, bits, p.Comment)
+	if err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errGenerateKey)
+	}
+	details := managed.ConnectionDetails{
+		keyPrivateKey: private,
+		keyPublicKey:  []byte(public),
+	}
+
+	if p.Install == nil {
+		return managed.ExternalCreation{ConnectionDetails: details}, nil
+	}
+	if details[keyCredentials], err = sshv1alpha1.Credentials(c.creds, p.Install.User, private); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errCreateCreds)
+	}
+	logger.Info(fmt.Sprintf("[%s] Installing public key...", mg.GetName()))
+	return managed.ExternalCreation{ConnectionDetails: details}, c.install(ctx, p.Install, public, false)
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	logger.Info(fmt.Sprintf("[%s] Installing public key...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.KeyPair)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotKeyPair)
+	}
+
+	if cr.Spec.ForProvider.Install == nil {
+		return managed.ExternalUpdate{}, nil
+	}
+	return managed.ExternalUpdate{}, c.install(ctx, cr.Spec.ForProvider.Install, cr.Status.AtProvider.PublicKey, false)
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Deleting key pair...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.KeyPair)
+	if !ok {
+		return errors.New(errNotKeyPair)
+	}
+
+	if cr.Spec.ForProvider.Install == nil {
+		return nil
+	}
+	return c.install(ctx, cr.Spec.ForProvider.Install, cr.Status.AtProvider.PublicKey, true)
+}
+
+// Disconnect closes the connection to the host, if any.
+func (c *external) Disconnect(_ context.Context) error {
+	if c.service == nil {
+		return nil
+	}
+	return c.service.Close()
+}
+
+// stored returns the key pair stored in the connection secret of the
+// KeyPair, or nil if it has not been generated yet.
+func (c *external) stored(ctx context.Context, cr *apisv1alpha1.KeyPair) (managed.ConnectionDetails, error) {
+	ref := cr.GetWriteConnectionSecretToReference()
+	s := &corev1.Secret{}
+	if err := c.kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, s); err != nil {
+		return nil, errors.Wrap(resource.IgnoreNotFound(err), errGetSecret)
+	}
+	if len(s.Data[keyPrivateKey]) == 0 || len(s.Data[keyPublicKey]) == 0 {
+		return nil, nil
+	}
+	return managed.ConnectionDetails{
+		keyPrivateKey: s.Data[keyPrivateKey],
+		keyPublicKey:  s.Data[keyPublicKey],
+	}, nil
+}
+
+// installed returns the entries of the authorized_keys file of the host that
+// authorize the public key.
+func (c *external) installed(ctx context.Context, inst *apisv1alpha1.KeyInstallation, key *sshv1alpha1.AuthorizedKey) ([]string, error) {
+	_, content, err := c.authorizedKeys(ctx, inst)
+	if err != nil {
+		return nil, err
+	}
+	return sshv1alpha1.FindAuthorizedKey(content, key), nil
+}
+
+// install writes the public key to the authorized_keys file of the host, or
+// removes it if remove is true.
+func (c *external) install(ctx context.Context, inst *apisv1alpha1.KeyInstallation, public string, remove bool) error {
+	key, err := sshv1alpha1.ParseAuthorizedKey(public)
+	if err != nil {
+		return errors.Wrap(err, errParseKey)
+	}
+	key.Options = inst.Options
+
+	path, content, err := c.authorizedKeys(ctx, inst)
+	if err != nil {
+		return err
+	}
+	return sshv1alpha1.WriteAuthorizedKeys(ctx, c.service, path, sshv1alpha1.SetAuthorizedKey(content, key, remove), inst.User, inst.SudoEnabled)
+}
+
+// authorizedKeys returns the path and the content of the authorized_keys file
+// the public key is installed to.
+func (c *external) authorizedKeys(ctx context.Context, inst *apisv1alpha1.KeyInstallation) (string, []byte, error) {
+	if c.service == nil {
+		return "", nil, errors.New(errNotConnected)
+	}
+	path, err := sshv1alpha1.AuthorizedKeysPath(ctx, c.service, inst.User, inst.SudoEnabled)
+	if err != nil {
+		return "", nil, err
+	}
+	content, err := sshv1alpha1.ReadAuthorizedKeys(ctx, c.service, path, inst.SudoEnabled)
+	return path, content, err
+}
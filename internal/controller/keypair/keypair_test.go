@@ -0,0 +1,394 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keypair
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+const (
+	publicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIM4u6OQD6Hwhel8T5l1CmTNK4+v5BZWn/KNJrgLn4/EV alice@example.com"
+	otherKey  = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMJ5McJBDzBBuLVoF4u4Zg1cI+LvbVCdyN1m3TYvYdpd bob@example.com"
+	keysPath  = "/home/alice/.ssh/authorized_keys"
+)
+
+type keyPairModifier func(*apisv1alpha1.KeyPair)
+
+func withInstall(options ...string) keyPairModifier {
+	return func(cr *apisv1alpha1.KeyPair) {
+		cr.Spec.ForProvider.Install = &apisv1alpha1.KeyInstallation{User: "alice", Options: options}
+	}
+}
+
+func withPublicKey(public string) keyPairModifier {
+	return func(cr *apisv1alpha1.KeyPair) { cr.Status.AtProvider.PublicKey = public }
+}
+
+func withDeleted() keyPairModifier {
+	return func(cr *apisv1alpha1.KeyPair) {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+	}
+}
+
+func keyPair(m ...keyPairModifier) *apisv1alpha1.KeyPair {
+	cr := &apisv1alpha1.KeyPair{}
+	cr.SetName("test")
+	cr.SetWriteConnectionSecretToReference(&xpv1.SecretReference{Name: "key", Namespace: "default"})
+	for _, f := range m {
+		f(cr)
+	}
+	return cr
+}
+
+// generated returns a client whose connection secret holds a key pair.
+func generated() client.Client {
+	return &test.MockClient{
+		MockGet: test.NewMockGetFn(nil, func(obj client.Object) error {
+			obj.(*corev1.Secret).Data = map[string][]byte{
+				keyPrivateKey: []byte("private"),
+				keyPublicKey:  []byte(publicKey),
+			}
+			return nil
+		}),
+	}
+}
+
+// keysHost is a fake host holding the authorized_keys file of alice, which
+// does not exist if its content is empty.
+type keysHost struct {
+	*sshfake.Executor
+	content string
+}
+
+func newKeysHost(content string) *keysHost {
+	h := &keysHost{content: content}
+	h.Executor = &sshfake.Executor{MockRun: func(script string) (string, string, error) {
+		switch {
+		case script == "getent passwd 'alice' | cut -d: -f6":
+			return "/home/alice\n", "", nil
+		case strings.HasPrefix(script, "if [ -f '"+keysPath+"' ]"):
+			return h.content, "", nil
+		case strings.HasPrefix(script, "mkdir -p "):
+			// The keys never hold a single quote, so the quoted content
+			// ends at the first one.
+			_, content, _ := strings.Cut(script, "printf '%s' '")
+			h.content, _, _ = strings.Cut(content, "'")
+			return "", "", nil
+		}
+		return "", "", sshfake.ExitError(127)
+	}}
+	return h
+}
+
+func TestObserve(t *testing.T) {
+	creds := []byte(`{"username":"root","password":"secret"}`)
+	installed, err := sshv1alpha1.Credentials(creds, "alice", []byte("private"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type want struct {
+		o         managed.ExternalObservation
+		installed bool
+		status    xpv1.ConditionedStatus
+		err       error
+	}
+
+	cases := map[string]struct {
+		reason string
+		kube   client.Client
+		host   *keysHost
+		mg     resource.Managed
+		want   want
+	}{
+		"NotKeyPair": {
+			reason: "We should return an error if the managed resource is not a KeyPair.",
+			mg:     &fake.Managed{},
+			want: want{
+				err: errors.New(errNotKeyPair),
+			},
+		},
+		"NotGenerated": {
+			reason: "A KeyPair without connection secret should not exist.",
+			kube: &test.MockClient{
+				MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "key")),
+			},
+			mg: keyPair(),
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: false},
+			},
+		},
+		"Generated": {
+			reason: "A KeyPair whose connection secret holds a key pair should exist.",
+			kube: &test.MockClient{
+				MockGet: test.NewMockGetFn(nil, func(obj client.Object) error {
+					obj.(*corev1.Secret).Data = map[string][]byte{
+						keyPrivateKey: []byte("private"),
+						keyPublicKey:  []byte(publicKey),
+					}
+					return nil
+				}),
+			},
+			mg: keyPair(),
+			want: want{
+				o: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: true,
+					ConnectionDetails: managed.ConnectionDetails{
+						keyPrivateKey: []byte("private"),
+						keyPublicKey:  []byte(publicKey),
+					},
+				},
+				status: *xpv1.NewConditionedStatus(xpv1.Available()),
+			},
+		},
+		"Installed": {
+			reason: "A KeyPair whose public key is installed once with the options of the spec should be up to date.",
+			kube:   generated(),
+			host:   newKeysHost(otherKey + "\nno-pty " + publicKey + "\n"),
+			mg:     keyPair(withInstall("no-pty")),
+			want: want{
+				o: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: true,
+					ConnectionDetails: managed.ConnectionDetails{
+						keyPrivateKey:  []byte("private"),
+						keyPublicKey:   []byte(publicKey),
+						keyCredentials: installed,
+					},
+				},
+				installed: true,
+				status:    *xpv1.NewConditionedStatus(xpv1.Available()),
+			},
+		},
+		"NotInstalled": {
+			reason: "A KeyPair whose public key is missing from the authorized_keys file should need an update.",
+			kube:   generated(),
+			host:   newKeysHost(otherKey + "\n"),
+			mg:     keyPair(withInstall()),
+			want: want{
+				o: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: false,
+					ConnectionDetails: managed.ConnectionDetails{
+						keyPrivateKey:  []byte("private"),
+						keyPublicKey:   []byte(publicKey),
+						keyCredentials: installed,
+					},
+				},
+				status: *xpv1.NewConditionedStatus(xpv1.Unavailable().WithMessage(errNotInstalled)),
+			},
+		},
+		"OptionsDrift": {
+			reason: "A KeyPair whose public key is installed with other options than those of the spec should need an update.",
+			kube:   generated(),
+			host:   newKeysHost("no-pty " + publicKey + "\n"),
+			mg:     keyPair(withInstall()),
+			want: want{
+				o: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: false,
+					ConnectionDetails: managed.ConnectionDetails{
+						keyPrivateKey:  []byte("private"),
+						keyPublicKey:   []byte(publicKey),
+						keyCredentials: installed,
+					},
+				},
+				status: *xpv1.NewConditionedStatus(xpv1.Unavailable().WithMessage(errNotInstalled)),
+			},
+		},
+		"NotConnected": {
+			reason: "We should return an error if the public key is to be installed without a connection to the host.",
+			kube:   generated(),
+			mg:     keyPair(withInstall()),
+			want:   want{err: errors.New(errNotConnected)},
+		},
+		"RemoteError": {
+			reason: "We should return an error if the authorized_keys file cannot be looked up on the host.",
+			kube:   generated(),
+			host: func() *keysHost {
+				h := newKeysHost(publicKey + "\n")
+				_ = h.Close()
+				return h
+			}(),
+			mg:   keyPair(withInstall()),
+			want: want{err: errors.Wrap(errors.New("connection closed"), "Failed to look up home directory")},
+		},
+		"DeletedInstalled": {
+			reason: "A deleted KeyPair should exist until its public key is removed from the host.",
+			kube:   generated(),
+			host:   newKeysHost(publicKey + "\n"),
+			mg:     keyPair(withInstall(), withDeleted()),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}},
+		},
+		"DeletedRemoved": {
+			reason: "A deleted KeyPair should be gone once its public key is removed from the host.",
+			kube:   generated(),
+			host:   newKeysHost(otherKey + "\n"),
+			mg:     keyPair(withInstall(), withDeleted()),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false, ResourceUpToDate: true}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{kube: tc.kube, creds: creds}
+			if tc.host != nil {
+				e.service = tc.host
+			}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if cr, ok := tc.mg.(*apisv1alpha1.KeyPair); ok {
+				if diff := cmp.Diff(tc.want.installed, cr.Status.AtProvider.Installed); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want installed, +got installed:\n%s\n", tc.reason, diff)
+				}
+				if diff := cmp.Diff(tc.want.status, cr.Status.ResourceStatus.ConditionedStatus, test.EquateConditions()); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want conditions, +got conditions:\n%s\n", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type want struct {
+		content string
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		host   *keysHost
+		mg     *apisv1alpha1.KeyPair
+		want   want
+	}{
+		"Installed": {
+			reason: "The public key should be removed from the authorized_keys file, keeping the other keys.",
+			host:   newKeysHost(otherKey + "\nno-pty " + publicKey + "\n"),
+			mg:     keyPair(withInstall(), withPublicKey(publicKey), withDeleted()),
+			want:   want{content: otherKey + "\n"},
+		},
+		"NotInstalled": {
+			reason: "Nothing should be run on the host if the public key was not installed.",
+			host:   newKeysHost(publicKey + "\n"),
+			mg:     keyPair(withPublicKey(publicKey), withDeleted()),
+			want:   want{content: publicKey + "\n"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: tc.host}
+			err := e.Delete(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.content, tc.host.content); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want authorized_keys, +got authorized_keys:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	bits := 2048
+
+	cases := map[string]struct {
+		reason  string
+		keyType string
+		bits    *int
+		want    string
+	}{
+		"ED25519": {
+			reason: "An ed25519 key pair should be generated by default.",
+			want:   ssh.KeyAlgoED25519,
+		},
+		"RSA": {
+			reason:  "An RSA key pair should be generated if requested.",
+			keyType: sshv1alpha1.KeyTypeRSA,
+			bits:    &bits,
+			want:    ssh.KeyAlgoRSA,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := keyPair()
+			cr.Spec.ForProvider.Type = tc.keyType
+			cr.Spec.ForProvider.Bits = tc.bits
+			cr.Spec.ForProvider.Comment = "test"
+
+			e := external{}
+			got, err := e.Create(context.Background(), cr)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Create(...): unexpected error: %v\n", tc.reason, err)
+			}
+			signer, err := ssh.ParsePrivateKey(got.ConnectionDetails[keyPrivateKey])
+			if err != nil {
+				t.Fatalf("\n%s\ne.Create(...): cannot parse private key: %v\n", tc.reason, err)
+			}
+			public, err := sshv1alpha1.ParseAuthorizedKey(string(got.ConnectionDetails[keyPublicKey]))
+			if err != nil {
+				t.Fatalf("\n%s\ne.Create(...): cannot parse public key: %v\n", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, public.Key.Type()); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want type, +got type:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(signer.PublicKey().Marshal(), public.Key.Marshal()); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): public key does not match private key:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff("test", public.Comment); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want comment, +got comment:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
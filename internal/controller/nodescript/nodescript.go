@@ -0,0 +1,411 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodescript
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"golang.org/x/crypto/ssh"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+	"github.com/crossplane/provider-ssh/internal/policy"
+	"github.com/crossplane/provider-ssh/internal/signature"
+)
+
+const (
+	errNotNodeScript    = "managed resource is not a NodeScript custom resource"
+	errListNodes        = "cannot list Nodes"
+	errVerifySignature  = "cannot verify the signature of the scripts"
+	errPolicy           = "cannot execute scripts"
+	errSetEndpoint      = "cannot set endpoint of Node"
+	errFmtNoAddress     = "node has no %s address"
+	errFmtNodesNotReady = "%d of %d nodes ready"
+	errFmtNodesFailed   = "script failed on %d of %d nodes"
+
+	// exitCodeMissing is the exit code of the statusCheckScript that reports
+	// a Node the initScript has not been executed on.
+	exitCodeMissing = 100
+)
+
+// Setup adds a controller that reconciles NodeScript managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.NodeScriptGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.NodeScriptGroupVersionKind),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			verifier:     o.ScriptVerifier,
+			policy:       o.ScriptPolicy,
+			newServiceFn: o.NewServiceFn()})),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithCriticalAnnotationUpdater(common.NewStatusPreservingUpdater(mgr.GetClient())),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.NodeScript{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.NodeScriptGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	verifier     *signature.Verifier
+	policy       *policy.Policy
+	newServiceFn common.NewServiceFn
+}
+
+// A node is a selected Node and the address the provider connects to.
+type node struct {
+	name    string
+	address string
+}
+
+// Connect produces an ExternalClient connected to every Node selected by the
+// NodeScript, using the credentials of its ProviderConfig with the address
+// of each Node. Nodes that cannot be reached are reported as not ready.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.NodeScript)
+	if !ok {
+		return nil, errors.New(errNotNodeScript)
+	}
+
+	e := &external{clients: map[string]sshv1alpha1.RemoteExecutor{}, results: map[string]apisv1alpha1.NodeStatus{}}
+	if meta.WasDeleted(cr) && (deleting(cr) || cr.Spec.ForProvider.CleanupScript == "") {
+		logger.Info(fmt.Sprintf("[%s] Resource is deleted. Skip the connection.", mg.GetName()))
+		return e, nil
+	}
+
+	if err := c.verifier.Verify(cr); err != nil {
+		return nil, errors.Wrap(err, errVerifySignature)
+	}
+
+	if err := c.policy.Evaluate(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errPolicy)
+	}
+
+	creds, err := common.Credentials(ctx, c.kube, c.usage, cr)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := resolveNodes(ctx, c.kube, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	port := 22
+	if cr.Spec.ForProvider.Port != nil {
+		port = *cr.Spec.ForProvider.Port
+	}
+	addresses := map[string]string{}
+	for _, n := range nodes {
+		e.nodes = append(e.nodes, n.name)
+		addresses[n.name] = n.address
+	}
+	common.ForEachHost(e.nodes, func(name string) {
+		st := apisv1alpha1.NodeStatus{Name: name, Address: addresses[name]}
+		svc, err := c.dial(ctx, cr, creds, st.Address, port)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if err != nil {
+			logger.Info(fmt.Sprintf("[%s] Node %s is unreachable: %s", cr.GetName(), name, err))
+			st.StatusCode, st.Message = 1, err.Error()
+			e.results[name] = st
+			return
+		}
+		e.clients[name] = sshv1alpha1.NewSSHExecutor(svc)
+		e.results[name] = st
+	})
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay], %d of %d nodes connected", cr.GetName(), len(e.clients), len(e.nodes)))
+	return e, nil
+}
+
+// dial connects to the Node at the supplied address.
+func (c *connector) dial(ctx context.Context, cr *apisv1alpha1.NodeScript, creds []byte, address string, port int) (*ssh.Client, error) {
+	if address == "" {
+		return nil, errors.Errorf(errFmtNoAddress, addressType(cr))
+	}
+	data, err := sshv1alpha1.WithEndpoint(creds, address, port)
+	if err != nil {
+		return nil, errors.Wrap(err, errSetEndpoint)
+	}
+	return c.newServiceFn(ctx, data)
+}
+
+// resolveNodes returns the Nodes selected by the NodeScript, sorted by name,
+// with the address of the configured type of each Node.
+func resolveNodes(ctx context.Context, kube client.Client, cr *apisv1alpha1.NodeScript) ([]node, error) {
+	opts := []client.ListOption{}
+	if sel := cr.Spec.ForProvider.NodeSelector; sel != nil {
+		s, err := metav1.LabelSelectorAsSelector(sel)
+		if err != nil {
+			return nil, errors.Wrap(err, errListNodes)
+		}
+		opts = append(opts, client.MatchingLabelsSelector{Selector: s})
+	}
+	l := &corev1.NodeList{}
+	if err := kube.List(ctx, l, opts...); err != nil {
+		return nil, errors.Wrap(err, errListNodes)
+	}
+
+	nodes := make([]node, 0, len(l.Items))
+	for _, n := range l.Items {
+		nodes = append(nodes, node{name: n.GetName(), address: nodeAddress(n, addressType(cr))})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].name < nodes[j].name })
+	return nodes, nil
+}
+
+// nodeAddress returns the first address of the supplied type reported in the
+// status of the Node, or an empty string if there is none.
+func nodeAddress(n corev1.Node, t corev1.NodeAddressType) string {
+	for _, a := range n.Status.Addresses {
+		if a.Type == t {
+			return a.Address
+		}
+	}
+	return ""
+}
+
+func addressType(cr *apisv1alpha1.NodeScript) corev1.NodeAddressType {
+	if t := cr.Spec.ForProvider.AddressType; t != "" {
+		return corev1.NodeAddressType(t)
+	}
+	return corev1.NodeInternalIP
+}
+
+// An ExternalClient executes the scripts of a NodeScript on the selected
+// Nodes. It lives for a single reconcile, so the state observed on each Node
+// is carried from Observe to Create or Update.
+type external struct {
+	nodes   []string
+	clients map[string]sshv1alpha1.RemoteExecutor
+
+	mu      sync.Mutex
+	results map[string]apisv1alpha1.NodeStatus
+}
+
+// checkResult maps the exit code of the statusCheckScript onto the state of a
+// Node.
+type checkResult int
+
+const (
+	nodeReady checkResult = iota
+	nodeMissing
+	nodeStale
+)
+
+func statusCheckResult(code int) checkResult {
+	switch code {
+	case 0:
+		return nodeReady
+	case exitCodeMissing:
+		return nodeMissing
+	}
+	return nodeStale
+}
+
+// connected returns the connected Nodes whose result satisfies fn.
+func (e *external) connected(fn func(st apisv1alpha1.NodeStatus) bool) []string {
+	nodes := make([]string, 0, len(e.clients))
+	for _, name := range e.nodes {
+		if _, ok := e.clients[name]; ok && fn(e.results[name]) {
+			nodes = append(nodes, name)
+		}
+	}
+	return nodes
+}
+
+// execute runs the script on the Node and records the result.
+func (e *external) execute(ctx context.Context, cr *apisv1alpha1.NodeScript, name, sc string) {
+	p := cr.Spec.ForProvider
+	_, stderr, err := sshv1alpha1.ExecuteScript(ctx, e.clients[name], sc, p.Variables, p.SudoEnabled)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	st := e.results[name]
+	st.StatusCode = sshv1alpha1.ExitStatus(err)
+	st.Ready, st.Message = err == nil, ""
+	if err != nil {
+		st.Message = fmt.Sprintf("exit code %d: %s", st.StatusCode, stderr)
+	}
+	e.results[name] = st
+}
+
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.Info(fmt.Sprintf("[%s] Observing %d nodes...", mg.GetName(), len(e.nodes)))
+	cr, ok := mg.(*apisv1alpha1.NodeScript)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotNodeScript)
+	}
+
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: !deleting(cr) && cr.Spec.ForProvider.CleanupScript != ""}, nil
+	}
+
+	common.ForEachHost(e.connected(all), func(name string) {
+		e.execute(ctx, cr, name, cr.Spec.ForProvider.StatusCheckScript)
+	})
+
+	missing := e.connected(is(nodeMissing))
+	stale := e.connected(is(nodeStale))
+	// Nodes that are not ready are reported through the Ready condition,
+	// Observe itself succeeds.
+	_ = e.report(cr)
+	return managed.ExternalObservation{ResourceExists: len(missing) == 0, ResourceUpToDate: len(stale) == 0}, nil
+}
+
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	logger := log.FromContext(ctx).WithName("[CREATE]")
+	cr, ok := mg.(*apisv1alpha1.NodeScript)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotNodeScript)
+	}
+
+	nodes := e.batch(cr, e.connected(is(nodeMissing)))
+	logger.Info(fmt.Sprintf("[%s] Creating init script on %d nodes...", mg.GetName(), len(nodes)))
+	return managed.ExternalCreation{}, e.run(ctx, cr, nodes, cr.Spec.ForProvider.InitScript)
+}
+
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	cr, ok := mg.(*apisv1alpha1.NodeScript)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotNodeScript)
+	}
+
+	sc := cr.Spec.ForProvider.UpdateScript
+	if sc == "" {
+		sc = cr.Spec.ForProvider.InitScript
+	}
+	nodes := e.batch(cr, e.connected(is(nodeStale)))
+	logger.Info(fmt.Sprintf("[%s] Updating resource on %d nodes...", mg.GetName(), len(nodes)))
+	return managed.ExternalUpdate{}, e.run(ctx, cr, nodes, sc)
+}
+
+func (e *external) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Deleting on %d nodes...", mg.GetName(), len(e.nodes)))
+	cr, ok := mg.(*apisv1alpha1.NodeScript)
+	if !ok {
+		return errors.New(errNotNodeScript)
+	}
+	return e.run(ctx, cr, e.connected(all), cr.Spec.ForProvider.CleanupScript)
+}
+
+// Disconnect closes the connections to the Nodes.
+func (e *external) Disconnect(_ context.Context) error {
+	return common.CloseExecutors(e.clients)
+}
+
+// run executes the script on the supplied Nodes and returns an error if it
+// failed on any of them.
+func (e *external) run(ctx context.Context, cr *apisv1alpha1.NodeScript, nodes []string, sc string) error {
+	common.ForEachHost(nodes, func(name string) {
+		e.execute(ctx, cr, name, sc)
+	})
+	_ = e.report(cr)
+
+	failed := 0
+	for _, name := range nodes {
+		if !e.results[name].Ready {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf(errFmtNodesFailed, failed, len(nodes))
+	}
+	return nil
+}
+
+// batch returns the Nodes to execute next out of the supplied Nodes,
+// according to the rollout strategy of the NodeScript. Connected Nodes that
+// are not ready are pending a script, so only unreachable Nodes count as
+// unavailable.
+func (e *external) batch(cr *apisv1alpha1.NodeScript, nodes []string) []string {
+	unavailable := len(e.nodes) - len(e.clients)
+	return common.RolloutBatch(cr.Spec.ForProvider.Rollout, len(e.nodes), nodes, unavailable)
+}
+
+// report records the result of every Node in the status of the NodeScript
+// and sets its Ready condition. It returns an error if any Node is not
+// ready.
+func (e *external) report(cr *apisv1alpha1.NodeScript) error {
+	nodes := make([]apisv1alpha1.NodeStatus, 0, len(e.nodes))
+	ready := 0
+	for _, name := range e.nodes {
+		st := e.results[name]
+		if st.Ready {
+			ready++
+		}
+		nodes = append(nodes, st)
+	}
+	cr.Status.AtProvider.Nodes = nodes
+
+	if len(nodes) > 0 && ready == len(nodes) {
+		cr.SetConditions(xpv1.Available())
+		return nil
+	}
+	err := errors.Errorf(errFmtNodesNotReady, ready, len(nodes))
+	cr.SetConditions(xpv1.Unavailable().WithMessage(err.Error()))
+	return err
+}
+
+// deleting reports whether the cleanupScript already succeeded on every
+// Node. A failed cleanup leaves the NodeScript unsynced, so it is retried.
+func deleting(cr *apisv1alpha1.NodeScript) bool {
+	c := cr.GetCondition(xpv1.TypeReady)
+	return c.Reason == xpv1.ReasonDeleting && cr.GetCondition(xpv1.TypeSynced).Status == corev1.ConditionTrue
+}
+
+// is returns a filter of the connected Nodes whose statusCheckScript reported
+// the supplied state.
+func is(r checkResult) func(st apisv1alpha1.NodeStatus) bool {
+	return func(st apisv1alpha1.NodeStatus) bool { return statusCheckResult(st.StatusCode) == r }
+}
+
+func all(apisv1alpha1.NodeStatus) bool { return true }
@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodescript
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+var errBoom = errors.New("boom")
+
+type nodeScriptModifier func(*apisv1alpha1.NodeScript)
+
+func withAddressType(t string) nodeScriptModifier {
+	return func(cr *apisv1alpha1.NodeScript) { cr.Spec.ForProvider.AddressType = t }
+}
+
+func withCleanupScript(sc string) nodeScriptModifier {
+	return func(cr *apisv1alpha1.NodeScript) { cr.Spec.ForProvider.CleanupScript = sc }
+}
+
+func withDeletionTimestamp() nodeScriptModifier {
+	return func(cr *apisv1alpha1.NodeScript) {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+	}
+}
+
+func withConditions(c ...xpv1.Condition) nodeScriptModifier {
+	return func(cr *apisv1alpha1.NodeScript) { cr.SetConditions(c...) }
+}
+
+func nodeScript(m ...nodeScriptModifier) *apisv1alpha1.NodeScript {
+	cr := &apisv1alpha1.NodeScript{}
+	cr.SetName("test")
+	for _, f := range m {
+		f(cr)
+	}
+	return cr
+}
+
+func TestResolveNodes(t *testing.T) {
+	list := func(_ context.Context, obj client.ObjectList, _ ...client.ListOption) error {
+		l := obj.(*corev1.NodeList)
+		for _, n := range []string{"worker-b", "worker-a"} {
+			nd := corev1.Node{}
+			nd.SetName(n)
+			nd.Status.Addresses = []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: n},
+				{Type: corev1.NodeInternalIP, Address: "10.0.0." + n[len(n)-1:]},
+			}
+			l.Items = append(l.Items, nd)
+		}
+		return nil
+	}
+
+	type want struct {
+		nodes []node
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		kube   client.Client
+		cr     *apisv1alpha1.NodeScript
+		want   want
+	}{
+		"InternalIP": {
+			reason: "Nodes should be returned sorted with their InternalIP address by default.",
+			kube:   &test.MockClient{MockList: list},
+			cr:     nodeScript(),
+			want:   want{nodes: []node{{name: "worker-a", address: "10.0.0.a"}, {name: "worker-b", address: "10.0.0.b"}}},
+		},
+		"Hostname": {
+			reason: "The address of the configured type should be used.",
+			kube:   &test.MockClient{MockList: list},
+			cr:     nodeScript(withAddressType(string(corev1.NodeHostName))),
+			want:   want{nodes: []node{{name: "worker-a", address: "worker-a"}, {name: "worker-b", address: "worker-b"}}},
+		},
+		"NoAddress": {
+			reason: "Nodes without an address of the configured type should have an empty address.",
+			kube:   &test.MockClient{MockList: list},
+			cr:     nodeScript(withAddressType(string(corev1.NodeExternalIP))),
+			want:   want{nodes: []node{{name: "worker-a"}, {name: "worker-b"}}},
+		},
+		"ListError": {
+			reason: "We should return an error if the Nodes cannot be listed.",
+			kube:   &test.MockClient{MockList: test.NewMockListFn(errBoom)},
+			cr:     nodeScript(),
+			want:   want{err: errors.Wrap(errBoom, errListNodes)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := resolveNodes(context.Background(), tc.kube, tc.cr)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nresolveNodes(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.nodes, got, cmp.AllowUnexported(node{}), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nresolveNodes(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserve(t *testing.T) {
+	type want struct {
+		o   managed.ExternalObservation
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     resource.Managed
+		want   want
+	}{
+		"NotNodeScript": {
+			reason: "We should return an error if the managed resource is not a NodeScript.",
+			mg:     &fake.Managed{},
+			want:   want{err: errors.New(errNotNodeScript)},
+		},
+		"DeletedWithoutCleanup": {
+			reason: "A deleted NodeScript without a cleanupScript should not exist.",
+			mg:     nodeScript(withDeletionTimestamp()),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"DeletedWithCleanup": {
+			reason: "A deleted NodeScript should exist until its cleanupScript succeeded.",
+			mg:     nodeScript(withDeletionTimestamp(), withCleanupScript("rm -f /etc/motd")),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true}},
+		},
+		"CleanupFailed": {
+			reason: "A failed cleanupScript should be retried.",
+			mg: nodeScript(withDeletionTimestamp(), withCleanupScript("rm -f /etc/motd"),
+				withConditions(xpv1.Deleting(), xpv1.ReconcileError(errBoom))),
+			want: want{o: managed.ExternalObservation{ResourceExists: true}},
+		},
+		"CleanupSucceeded": {
+			reason: "A NodeScript whose cleanupScript succeeded should not exist.",
+			mg: nodeScript(withDeletionTimestamp(), withCleanupScript("rm -f /etc/motd"),
+				withConditions(xpv1.Deleting(), xpv1.ReconcileSuccess())),
+			want: want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"NoNodes": {
+			reason: "A NodeScript selecting no Nodes should exist and be up to date.",
+			mg:     nodeScript(),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := &external{clients: map[string]sshv1alpha1.RemoteExecutor{}, results: map[string]apisv1alpha1.NodeStatus{}}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	two := 2
+
+	cases := map[string]struct {
+		reason      string
+		rollout     *apisv1alpha1.RolloutStrategy
+		nodes       []string
+		unreachable string
+		want        []string
+	}{
+		"NoRollout": {
+			reason: "Without a rollout strategy every pending Node should be executed at once.",
+			nodes:  []string{"a", "b", "c"},
+			want:   []string{"a", "b", "c"},
+		},
+		"BatchSize": {
+			reason:  "No more Nodes than the batch size should be executed at once.",
+			rollout: &apisv1alpha1.RolloutStrategy{BatchSize: &two},
+			nodes:   []string{"a", "b", "c"},
+			want:    []string{"a", "b"},
+		},
+		"PauseOnFailure": {
+			reason:      "No Node should be executed while another Node is unreachable.",
+			rollout:     &apisv1alpha1.RolloutStrategy{PauseOnFailure: true},
+			nodes:       []string{"a", "b"},
+			unreachable: "c",
+			want:        nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := nodeScript()
+			cr.Spec.ForProvider.Rollout = tc.rollout
+			clients := map[string]sshv1alpha1.RemoteExecutor{"a": nil, "b": nil, "c": nil}
+			delete(clients, tc.unreachable)
+			e := &external{nodes: []string{"a", "b", "c"}, clients: clients}
+			got := e.batch(cr, tc.nodes)
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\ne.batch(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
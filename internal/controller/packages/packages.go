@@ -0,0 +1,231 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotPackage = "managed resource is not a Package custom resource"
+)
+
+// Setup adds a controller that reconciles Package managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.PackageGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.PackageGroupVersionKind),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			newServiceFn: o.NewServiceFn()})),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.Package{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.PackageGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	newServiceFn common.NewServiceFn
+}
+
+// Connect produces an ExternalClient for the package manager of the host of
+// the ProviderConfig referenced by the Package.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.Package)
+	if !ok {
+		return nil, errors.New(errNotPackage)
+	}
+
+	svc, err := common.Connect(ctx, c.kube, c.usage, cr, c.newServiceFn)
+	if err != nil {
+		return nil, err
+	}
+
+	x := sshv1alpha1.NewSSHExecutor(svc)
+	p := cr.Spec.ForProvider
+	name := p.Manager
+	if name == "" {
+		if name, err = sshv1alpha1.DetectPackageManager(ctx, x); err != nil {
+			_ = x.Close()
+			return nil, err
+		}
+	}
+	pm, err := sshv1alpha1.NewPackageManager(name, x, p.SudoEnabled)
+	if err != nil {
+		_ = x.Close()
+		return nil, err
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]. Package manager: %s", mg.GetName(), name))
+	return &external{service: x, manager: pm, managerName: name}, nil
+}
+
+// An ExternalClient installs and removes the package of a Package.
+type external struct {
+	// The connection to the host the package manager runs its commands
+	// through.
+	service     sshv1alpha1.RemoteExecutor
+	manager     sshv1alpha1.PackageManager
+	managerName string
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.Package)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotPackage)
+	}
+
+	p := cr.Spec.ForProvider
+	installed, err := c.manager.Installed(ctx, p.Name)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	cr.Status.AtProvider = apisv1alpha1.PackageObservation{Manager: c.managerName, Version: installed}
+
+	state := p.State
+	if state == "" {
+		state = apisv1alpha1.PackageStatePresent
+	}
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: installed != "" && state != apisv1alpha1.PackageStateAbsent}, nil
+	}
+
+	// An absent package always exists, so that it is removed by Update.
+	o := managed.ExternalObservation{ResourceExists: installed != ""}
+	switch state {
+	case apisv1alpha1.PackageStateAbsent:
+		o = managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: installed == ""}
+	case apisv1alpha1.PackageStateLatest:
+		latest, err := c.manager.Latest(ctx, p.Name)
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		cr.Status.AtProvider.LatestVersion = latest
+		o.ResourceUpToDate = latest == "" || installed == latest
+	default:
+		o.ResourceUpToDate = matches(installed, p.Version)
+	}
+
+	if o.ResourceExists {
+		cr.SetConditions(xpv1.Available())
+	}
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. Installed: %q, up to date: %t", mg.GetName(), installed, o.ResourceUpToDate))
+	return o, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	logger := log.FromContext(ctx).WithName("[CREATE]")
+	logger.Info(fmt.Sprintf("[%s] Installing package...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.Package)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotPackage)
+	}
+
+	p := cr.Spec.ForProvider
+	version := p.Version
+	if p.State == apisv1alpha1.PackageStateLatest {
+		version = ""
+	}
+	return managed.ExternalCreation{}, c.manager.Install(ctx, p.Name, version)
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	logger.Info(fmt.Sprintf("[%s] Updating package...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.Package)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotPackage)
+	}
+
+	p := cr.Spec.ForProvider
+	switch p.State {
+	case apisv1alpha1.PackageStateAbsent:
+		return managed.ExternalUpdate{}, c.manager.Remove(ctx, p.Name)
+	case apisv1alpha1.PackageStateLatest:
+		return managed.ExternalUpdate{}, c.manager.Upgrade(ctx, p.Name)
+	default:
+		return managed.ExternalUpdate{}, c.manager.Install(ctx, p.Name, p.Version)
+	}
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Removing package...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.Package)
+	if !ok {
+		return errors.New(errNotPackage)
+	}
+
+	return c.manager.Remove(ctx, cr.Spec.ForProvider.Name)
+}
+
+// Disconnect closes the connection to the host, if any.
+func (c *external) Disconnect(_ context.Context) error {
+	if c.service == nil {
+		return nil
+	}
+	return c.service.Close()
+}
+
+// matches reports whether the installed version satisfies the configured
+// version.
+func matches(installed, version string) bool {
+	switch {
+	case installed == "":
+		return false
+	case version == "":
+		return true
+	case strings.HasSuffix(version, "*"):
+		return strings.HasPrefix(installed, strings.TrimSuffix(version, "*"))
+	default:
+		return installed == version
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+// A mockManager is a package manager with fixed versions.
+type mockManager struct {
+	installed string
+	latest    string
+}
+
+func (m *mockManager) Installed(_ context.Context, _ string) (string, error) { return m.installed, nil }
+func (m *mockManager) Latest(_ context.Context, _ string) (string, error)    { return m.latest, nil }
+func (m *mockManager) Install(_ context.Context, _, _ string) error          { return nil }
+func (m *mockManager) Upgrade(_ context.Context, _ string) error             { return nil }
+func (m *mockManager) Remove(_ context.Context, _ string) error              { return nil }
+
+func pkg(state, version string, deleted bool) *apisv1alpha1.Package {
+	cr := &apisv1alpha1.Package{}
+	cr.Spec.ForProvider = apisv1alpha1.PackageParameters{Name: "curl", State: state, Version: version}
+	if deleted {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+	}
+	return cr
+}
+
+func TestObserve(t *testing.T) {
+	type want struct {
+		o   managed.ExternalObservation
+		err error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		manager *mockManager
+		mg      resource.Managed
+		want    want
+	}{
+		"NotPackage": {
+			reason: "We should return an error if the managed resource is not a Package.",
+			mg:     &fake.Managed{},
+			want: want{
+				err: errors.New(errNotPackage),
+			},
+		},
+		"NotInstalled": {
+			reason:  "A present package that is not installed should not exist.",
+			manager: &mockManager{},
+			mg:      pkg("", "", false),
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: false},
+			},
+		},
+		"Present": {
+			reason:  "A present package with a matching version should be up to date.",
+			manager: &mockManager{installed: "8.5.0-1"},
+			mg:      pkg(apisv1alpha1.PackageStatePresent, "8.5.*", false),
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+			},
+		},
+		"WrongVersion": {
+			reason:  "A present package with a different version should not be up to date.",
+			manager: &mockManager{installed: "8.4.0-1"},
+			mg:      pkg(apisv1alpha1.PackageStatePresent, "8.5.0-1", false),
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+			},
+		},
+		"Outdated": {
+			reason:  "A latest package with a newer version available should not be up to date.",
+			manager: &mockManager{installed: "8.4.0-1", latest: "8.5.0-1"},
+			mg:      pkg(apisv1alpha1.PackageStateLatest, "", false),
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+			},
+		},
+		"AbsentInstalled": {
+			reason:  "An absent package that is installed should exist but not be up to date.",
+			manager: &mockManager{installed: "8.4.0-1"},
+			mg:      pkg(apisv1alpha1.PackageStateAbsent, "", false),
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+			},
+		},
+		"AbsentDeleted": {
+			reason:  "A deleted absent package should not exist, so that it is not removed.",
+			manager: &mockManager{installed: "8.4.0-1"},
+			mg:      pkg(apisv1alpha1.PackageStateAbsent, "", true),
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: false},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{manager: tc.manager}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotedirectory
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotRemoteDirectory = "managed resource is not a RemoteDirectory custom resource"
+	errParseMode          = "cannot parse mode of the files"
+	errListFiles          = "cannot list remote files"
+	errWriteFiles         = "cannot write remote files"
+	errPruneFiles         = "cannot prune remote files"
+	errRemoveDirectory    = "cannot remove remote directory"
+
+	// maxOutOfSync is the number of out of sync files reported in the status.
+	maxOutOfSync = 50
+)
+
+// Setup adds a controller that reconciles RemoteDirectory managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.RemoteDirectoryGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.RemoteDirectoryGroupVersionKind),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			newServiceFn: o.NewServiceFn()})),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithCriticalAnnotationUpdater(common.NewStatusPreservingUpdater(mgr.GetClient())),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.RemoteDirectory{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.RemoteDirectoryGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	newServiceFn common.NewServiceFn
+}
+
+// Connect produces an ExternalClient for the host of the ProviderConfig
+// referenced by the RemoteDirectory.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteDirectory)
+	if !ok {
+		return nil, errors.New(errNotRemoteDirectory)
+	}
+
+	svc, err := common.Connect(ctx, c.kube, c.usage, cr, c.newServiceFn)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
+	return &external{kube: c.kube, http: &http.Client{Timeout: time.Minute}, service: sshv1alpha1.NewSSHExecutor(svc)}, nil
+}
+
+// An ExternalClient syncs a remote directory over SFTP. The desired files are
+// read once per reconcile and shared by Observe and Create or Update.
+type external struct {
+	// A client of the Kubernetes API, used to read ConfigMap sources.
+	kube client.Client
+	// A client used to fetch tarball sources.
+	http *http.Client
+	// A 'client' used to connect to the external resource API.
+	service sshv1alpha1.FileExecutor
+
+	desired map[string][]byte
+	remote  map[string]string
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteDirectory)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotRemoteDirectory)
+	}
+
+	remote, err := c.service.ListFiles(ctx, cr.Spec.ForProvider.Path)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errListFiles)
+	}
+	c.remote = remote
+	if remote == nil {
+		logger.Info(fmt.Sprintf("[%s] Observing, remote directory does not exist.", mg.GetName()))
+		cr.Status.AtProvider = apisv1alpha1.RemoteDirectoryObservation{}
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	cr.Status.AtProvider.Files = len(remote)
+
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+
+	if c.desired, err = files(ctx, c.kube, c.http, cr.Spec.ForProvider.Source); err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	write, prune := diff(c.desired, remote, pruning(cr))
+	outOfSync := make([]string, 0, len(write)+len(prune))
+	for name := range write {
+		outOfSync = append(outOfSync, name)
+	}
+	outOfSync = append(outOfSync, prune...)
+	sort.Strings(outOfSync)
+	if len(outOfSync) > maxOutOfSync {
+		outOfSync = outOfSync[:maxOutOfSync]
+	}
+	cr.Status.AtProvider.OutOfSync = outOfSync
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. %d files out of sync.", mg.GetName(), len(write)+len(prune)))
+	cr.SetConditions(xpv1.Available())
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: len(outOfSync) == 0}, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	logger := log.FromContext(ctx).WithName("[CREATE]")
+	logger.Info(fmt.Sprintf("[%s] Syncing remote directory...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteDirectory)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotRemoteDirectory)
+	}
+	return managed.ExternalCreation{}, c.sync(ctx, cr)
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	logger.Info(fmt.Sprintf("[%s] Syncing remote directory...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteDirectory)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotRemoteDirectory)
+	}
+	return managed.ExternalUpdate{}, c.sync(ctx, cr)
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Removing remote directory...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteDirectory)
+	if !ok {
+		return errors.New(errNotRemoteDirectory)
+	}
+	return errors.Wrap(c.service.RemoveDirectory(ctx, cr.Spec.ForProvider.Path), errRemoveDirectory)
+}
+
+// Disconnect closes the connection to the host, if any.
+func (c *external) Disconnect(_ context.Context) error {
+	if c.service == nil {
+		return nil
+	}
+	return c.service.Close()
+}
+
+// sync writes the files that differ from the source and prunes the files that
+// are not part of it.
+func (c *external) sync(ctx context.Context, cr *apisv1alpha1.RemoteDirectory) error {
+	p := cr.Spec.ForProvider
+	if c.desired == nil {
+		var err error
+		if c.desired, err = files(ctx, c.kube, c.http, p.Source); err != nil {
+			return err
+		}
+	}
+
+	mode := os.FileMode(0o644)
+	if p.FileMode != "" {
+		m, err := strconv.ParseUint(p.FileMode, 8, 32)
+		if err != nil {
+			return errors.Wrap(err, errParseMode)
+		}
+		mode = os.FileMode(m)
+	}
+
+	write, prune := diff(c.desired, c.remote, pruning(cr))
+	if err := c.service.WriteFiles(ctx, p.Path, write, mode); err != nil {
+		return errors.Wrap(err, errWriteFiles)
+	}
+	if err := c.service.RemoveFiles(ctx, p.Path, prune); err != nil {
+		return errors.Wrap(err, errPruneFiles)
+	}
+	cr.Status.AtProvider.Files = len(c.desired)
+	cr.Status.AtProvider.OutOfSync = nil
+	return nil
+}
+
+// diff returns the desired files whose content differs from the remote files
+// and, if pruning, the sorted remote files that are not desired.
+func diff(desired map[string][]byte, remote map[string]string, prune bool) (map[string][]byte, []string) {
+	write := map[string][]byte{}
+	for name, content := range desired {
+		if sum, ok := remote[name]; !ok || sum != sshv1alpha1.Checksum(content) {
+			write[name] = content
+		}
+	}
+
+	var pruned []string
+	if prune {
+		for name := range remote {
+			if _, ok := desired[name]; !ok {
+				pruned = append(pruned, name)
+			}
+		}
+		sort.Strings(pruned)
+	}
+	return write, pruned
+}
+
+func pruning(cr *apisv1alpha1.RemoteDirectory) bool {
+	return cr.Spec.ForProvider.Prune == nil || *cr.Spec.ForProvider.Prune
+}
@@ -0,0 +1,435 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotedirectory
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+func TestDiff(t *testing.T) {
+	desired := map[string][]byte{"a.conf": []byte("a"), "b.conf": []byte("b")}
+
+	type want struct {
+		write map[string][]byte
+		prune []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		remote map[string]string
+		prune  bool
+		want   want
+	}{
+		"Missing": {
+			reason: "All files should be written to a directory that does not exist.",
+			want:   want{write: desired},
+		},
+		"Changed": {
+			reason: "Only files whose checksum differs should be written.",
+			remote: map[string]string{"a.conf": sshv1alpha1.Checksum([]byte("a")), "b.conf": "stale"},
+			want:   want{write: map[string][]byte{"b.conf": []byte("b")}},
+		},
+		"Prune": {
+			reason: "Remote files that are not desired should be pruned.",
+			remote: map[string]string{"a.conf": sshv1alpha1.Checksum([]byte("a")), "b.conf": sshv1alpha1.Checksum([]byte("b")), "old.conf": "x"},
+			prune:  true,
+			want:   want{prune: []string{"old.conf"}},
+		},
+		"NoPrune": {
+			reason: "Remote files that are not desired should be kept without pruning.",
+			remote: map[string]string{"a.conf": sshv1alpha1.Checksum([]byte("a")), "b.conf": sshv1alpha1.Checksum([]byte("b")), "old.conf": "x"},
+			want:   want{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			write, prune := diff(desired, tc.remote, tc.prune)
+			if diff := cmp.Diff(tc.want.write, write, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\ndiff(...): -want write, +got write:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.prune, prune, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\ndiff(...): -want prune, +got prune:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func tarball(t *testing.T, compress bool, files map[string]string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	var w io.Writer = buf
+	gz := gzip.NewWriter(buf)
+	if compress {
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if compress {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestReadTarball(t *testing.T) {
+	type want struct {
+		files map[string][]byte
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason   string
+		compress bool
+		files    map[string]string
+		want     want
+	}{
+		"Plain": {
+			reason: "Files of an uncompressed tarball should be returned by their relative path.",
+			files:  map[string]string{"./etc/app.conf": "a"},
+			want:   want{files: map[string][]byte{"etc/app.conf": []byte("a")}},
+		},
+		"Gzip": {
+			reason:   "Gzip compressed tarballs should be detected by their content.",
+			compress: true,
+			files:    map[string]string{"app.conf": "a"},
+			want:     want{files: map[string][]byte{"app.conf": []byte("a")}},
+		},
+		"UnsafePath": {
+			reason: "Entries outside of the directory should be rejected.",
+			files:  map[string]string{"../etc/passwd": "x"},
+			want:   want{err: errors.Errorf(errFmtUnsafePath, "../etc/passwd")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := readTarball(bytes.NewReader(tarball(t, tc.compress, tc.files)))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nreadTarball(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.files, got); diff != "" {
+				t.Errorf("\n%s\nreadTarball(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+const appDir = "/etc/app"
+
+var errBoom = errors.New("boom")
+
+type directoryModifier func(*apisv1alpha1.RemoteDirectory)
+
+func withPrune(prune bool) directoryModifier {
+	return func(cr *apisv1alpha1.RemoteDirectory) { cr.Spec.ForProvider.Prune = &prune }
+}
+
+func withFileMode(mode string) directoryModifier {
+	return func(cr *apisv1alpha1.RemoteDirectory) { cr.Spec.ForProvider.FileMode = mode }
+}
+
+func withDeleted() directoryModifier {
+	return func(cr *apisv1alpha1.RemoteDirectory) {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+	}
+}
+
+// directory returns a RemoteDirectory syncing the ConfigMap returned by
+// sourceConfigMap to appDir.
+func directory(m ...directoryModifier) *apisv1alpha1.RemoteDirectory {
+	cr := &apisv1alpha1.RemoteDirectory{}
+	cr.SetName("app")
+	cr.Spec.ForProvider.Path = appDir
+	cr.Spec.ForProvider.Source.ConfigMapRef = &apisv1alpha1.ConfigMapReference{Name: "app", Namespace: "default"}
+	for _, f := range m {
+		f(cr)
+	}
+	return cr
+}
+
+// sourceConfigMap is a client of the Kubernetes API returning the source
+// ConfigMap of the files a.conf and b.conf.
+var sourceConfigMap = &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+	obj.(*corev1.ConfigMap).Data = map[string]string{"a.conf": "a", "b.conf": "b"}
+	return nil
+}}
+
+// directoryHost returns a fake executor of a host storing the supplied
+// files below appDir.
+func directoryHost(t *testing.T, files map[string]string) *sshfake.Executor {
+	t.Helper()
+	x := &sshfake.Executor{}
+	for name, content := range files {
+		if err := x.WriteFile(context.Background(), appDir+"/"+name, []byte(content), 0o644, -1, -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return x
+}
+
+func TestObserve(t *testing.T) {
+	type want struct {
+		o      managed.ExternalObservation
+		status apisv1alpha1.RemoteDirectoryObservation
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		kube   client.Client
+		x      func(t *testing.T) *sshfake.Executor
+		mg     *apisv1alpha1.RemoteDirectory
+		want   want
+	}{
+		"NotExists": {
+			reason: "A directory missing on the host should be reported as not existing.",
+			x:      func(t *testing.T) *sshfake.Executor { return directoryHost(t, nil) },
+			mg:     directory(),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"UpToDate": {
+			reason: "A directory holding the files of the source should be up to date.",
+			x: func(t *testing.T) *sshfake.Executor {
+				return directoryHost(t, map[string]string{"a.conf": "a", "b.conf": "b"})
+			},
+			mg: directory(),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				status: apisv1alpha1.RemoteDirectoryObservation{Files: 2, OutOfSync: []string{}},
+			},
+		},
+		"Drift": {
+			reason: "The files missing on the host or whose content differs from the source should be out of sync.",
+			x:      func(t *testing.T) *sshfake.Executor { return directoryHost(t, map[string]string{"b.conf": "stale"}) },
+			mg:     directory(),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				status: apisv1alpha1.RemoteDirectoryObservation{Files: 1, OutOfSync: []string{"a.conf", "b.conf"}},
+			},
+		},
+		"Prune": {
+			reason: "The files on the host that are not part of the source should be out of sync if pruned.",
+			x: func(t *testing.T) *sshfake.Executor {
+				return directoryHost(t, map[string]string{"a.conf": "a", "b.conf": "b", "c.conf": "c"})
+			},
+			mg: directory(),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				status: apisv1alpha1.RemoteDirectoryObservation{Files: 3, OutOfSync: []string{"c.conf"}},
+			},
+		},
+		"NoPrune": {
+			reason: "The files on the host that are not part of the source should be ignored if not pruned.",
+			x: func(t *testing.T) *sshfake.Executor {
+				return directoryHost(t, map[string]string{"a.conf": "a", "b.conf": "b", "c.conf": "c"})
+			},
+			mg: directory(withPrune(false)),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				status: apisv1alpha1.RemoteDirectoryObservation{Files: 3, OutOfSync: []string{}},
+			},
+		},
+		"Deleted": {
+			reason: "A deleted RemoteDirectory whose directory exists should only need to be removed.",
+			kube:   &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			x:      func(t *testing.T) *sshfake.Executor { return directoryHost(t, map[string]string{"c.conf": "c"}) },
+			mg:     directory(withDeleted()),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				status: apisv1alpha1.RemoteDirectoryObservation{Files: 1},
+			},
+		},
+		"SourceError": {
+			reason: "We should return an error if the source cannot be read.",
+			kube:   &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			x:      func(t *testing.T) *sshfake.Executor { return directoryHost(t, map[string]string{"a.conf": "a"}) },
+			mg:     directory(),
+			want: want{
+				status: apisv1alpha1.RemoteDirectoryObservation{Files: 1},
+				err:    errors.Wrap(errBoom, errGetConfigMap),
+			},
+		},
+		"ListError": {
+			reason: "We should return an error if the files of the directory cannot be listed.",
+			x: func(t *testing.T) *sshfake.Executor {
+				x := directoryHost(t, map[string]string{"a.conf": "a"})
+				_ = x.Close()
+				return x
+			},
+			mg:   directory(),
+			want: want{err: errors.Wrap(errors.New("connection closed"), errListFiles)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kube := tc.kube
+			if kube == nil {
+				kube = sourceConfigMap
+			}
+			e := external{kube: kube, service: tc.x(t)}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.status, tc.mg.Status.AtProvider); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want status, +got status:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	type want struct {
+		files map[string]string
+		mode  os.FileMode
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		x      func(t *testing.T) *sshfake.Executor
+		mg     *apisv1alpha1.RemoteDirectory
+		want   want
+	}{
+		"Create": {
+			reason: "A missing directory should be created with the files of the source.",
+			x:      func(t *testing.T) *sshfake.Executor { return directoryHost(t, nil) },
+			mg:     directory(),
+			want:   want{files: map[string]string{"a.conf": "a", "b.conf": "b"}, mode: 0o644},
+		},
+		"FileMode": {
+			reason: "The files should be written with the fileMode of the RemoteDirectory.",
+			x:      func(t *testing.T) *sshfake.Executor { return directoryHost(t, nil) },
+			mg:     directory(withFileMode("0600")),
+			want:   want{files: map[string]string{"a.conf": "a", "b.conf": "b"}, mode: 0o600},
+		},
+		"Prune": {
+			reason: "The drifted files should be rewritten and the files that are not part of the source removed.",
+			x: func(t *testing.T) *sshfake.Executor {
+				return directoryHost(t, map[string]string{"a.conf": "stale", "c.conf": "c"})
+			},
+			mg:   directory(),
+			want: want{files: map[string]string{"a.conf": "a", "b.conf": "b"}, mode: 0o644},
+		},
+		"NoPrune": {
+			reason: "The files that are not part of the source should be kept if not pruned.",
+			x: func(t *testing.T) *sshfake.Executor {
+				return directoryHost(t, map[string]string{"a.conf": "stale", "c.conf": "c"})
+			},
+			mg:   directory(withPrune(false)),
+			want: want{files: map[string]string{"a.conf": "a", "b.conf": "b", "c.conf": "c"}, mode: 0o644},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := tc.x(t)
+			e := external{kube: sourceConfigMap, service: x}
+			// The files of the directory are listed by Observe, as in a
+			// reconcile.
+			o, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("e.Observe(...): %v", err)
+			}
+			if o.ResourceExists {
+				_, err = e.Update(context.Background(), tc.mg)
+			} else {
+				_, err = e.Create(context.Background(), tc.mg)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.sync(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+
+			got, err := x.ListFiles(context.Background(), appDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{}
+			for name, content := range tc.want.files {
+				want[name] = sshv1alpha1.Checksum([]byte(content))
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("\n%s\nRemote files: -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if fi, _ := x.StatFile(context.Background(), appDir+"/a.conf"); fi == nil || fi.Mode != tc.want.mode {
+				t.Errorf("\n%s\nRemote file a.conf: mode %v, want %v", tc.reason, fi, tc.want.mode)
+			}
+			if diff := cmp.Diff(apisv1alpha1.RemoteDirectoryObservation{Files: 2}, tc.mg.Status.AtProvider); diff != "" {
+				t.Errorf("\n%s\ne.sync(...): -want status, +got status:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	x := directoryHost(t, map[string]string{"a.conf": "a", "conf.d/b.conf": "b"})
+	if err := x.WriteFile(context.Background(), "/etc/hosts", []byte("127.0.0.1 localhost"), 0o644, -1, -1); err != nil {
+		t.Fatal(err)
+	}
+	e := external{service: x}
+	if err := e.Delete(context.Background(), directory(withDeleted())); err != nil {
+		t.Fatalf("e.Delete(...): %v", err)
+	}
+	if diff := cmp.Diff([]string{"/etc/hosts"}, x.Files()); diff != "" {
+		t.Errorf("\nThe directory and everything below it should be removed, and nothing else.\nRemote files: -want, +got:\n%s\n", diff)
+	}
+}
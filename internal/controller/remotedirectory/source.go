@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotedirectory
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	errGetConfigMap   = "cannot get source ConfigMap"
+	errFetchTarball   = "cannot fetch tarball"
+	errReadTarball    = "cannot read tarball"
+	errFmtTarballCode = "unexpected status code %d fetching tarball"
+	errFmtTarballHash = "tarball has SHA-256 hash %s, expected %s"
+	errFmtUnsafePath  = "tarball entry %s is outside of the directory"
+	errNoSource       = "no source is set"
+
+	// maxTarballFileSize is the maximum size of a file read from a tarball.
+	maxTarballFileSize = 64 << 20
+)
+
+// files returns the desired content of the directory, keyed by the path of
+// each file relative to the directory.
+func files(ctx context.Context, kube client.Client, hc *http.Client, src apisv1alpha1.DirectorySource) (map[string][]byte, error) {
+	switch {
+	case src.ConfigMapRef != nil:
+		cm := &corev1.ConfigMap{}
+		nn := types.NamespacedName{Namespace: src.ConfigMapRef.Namespace, Name: src.ConfigMapRef.Name}
+		if err := kube.Get(ctx, nn, cm); err != nil {
+			return nil, errors.Wrap(err, errGetConfigMap)
+		}
+		out := make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+		for k, v := range cm.Data {
+			out[k] = []byte(v)
+		}
+		for k, v := range cm.BinaryData {
+			out[k] = v
+		}
+		return out, nil
+	case src.Tarball != nil:
+		return fetchTarball(ctx, hc, *src.Tarball)
+	}
+	return nil, errors.New(errNoSource)
+}
+
+func fetchTarball(ctx context.Context, hc *http.Client, src apisv1alpha1.TarballSource) (map[string][]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, errFetchTarball)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, errFetchTarball)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf(errFmtTarballCode, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, errFetchTarball)
+	}
+	if src.SHA256 != "" {
+		if sum := sshv1alpha1.Checksum(body); !strings.EqualFold(sum, src.SHA256) {
+			return nil, errors.Errorf(errFmtTarballHash, sum, src.SHA256)
+		}
+	}
+	return readTarball(bytes.NewReader(body))
+}
+
+// readTarball returns the regular files of the supplied, optionally gzip
+// compressed, tarball.
+func readTarball(r io.Reader) (map[string][]byte, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, errors.Wrap(err, errReadTarball)
+		}
+		defer gz.Close() // nolint: errcheck
+		r = gz
+	} else {
+		r = br
+	}
+
+	out := map[string][]byte{}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, errReadTarball)
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(h.Name, "./"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, errors.Errorf(errFmtUnsafePath, h.Name)
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, maxTarballFileSize))
+		if err != nil {
+			return nil, errors.Wrap(err, errReadTarball)
+		}
+		out[name] = content
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotefetch
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotRemoteFetch = "managed resource is not a RemoteFetch custom resource"
+	errFmtFetch       = "cannot fetch %s"
+	errFmtMissing     = "remote file %s does not exist"
+
+	// defaultMaxSize is the default maximum size of a downloaded file.
+	defaultMaxSize = 1 << 20
+)
+
+// Setup adds a controller that reconciles RemoteFetch managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.RemoteFetchGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.RemoteFetchGroupVersionKind),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			newServiceFn: o.NewServiceFn()})),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.RemoteFetch{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.RemoteFetchGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	newServiceFn common.NewServiceFn
+}
+
+// Connect produces an ExternalClient for the host of the ProviderConfig
+// referenced by the RemoteFetch.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteFetch)
+	if !ok {
+		return nil, errors.New(errNotRemoteFetch)
+	}
+
+	// Nothing is removed from the remote host when a RemoteFetch is deleted.
+	if meta.WasDeleted(cr) {
+		logger.Info(fmt.Sprintf("[%s] Resource is deleted. Skip the connection.", mg.GetName()))
+		return &external{}, nil
+	}
+
+	svc, err := common.Connect(ctx, c.kube, c.usage, cr, c.newServiceFn)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
+	return &external{service: sshv1alpha1.NewSSHExecutor(svc)}, nil
+}
+
+// An ExternalClient downloads remote files over SFTP. The files are owned by
+// the remote host, so they are never created, updated or deleted.
+type external struct {
+	// A 'client' used to connect to the external resource API.
+	service sshv1alpha1.RemoteExecutor
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteFetch)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotRemoteFetch)
+	}
+
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	p := cr.Spec.ForProvider
+	maxSize := int64(defaultMaxSize)
+	if p.MaxSizeBytes != nil {
+		maxSize = *p.MaxSizeBytes
+	}
+
+	cd := managed.ConnectionDetails{}
+	files := make([]apisv1alpha1.FetchedFileStatus, 0, len(p.Files))
+	for _, f := range p.Files {
+		content, err := c.service.Fetch(ctx, f.Path, maxSize)
+		if errors.Is(err, os.ErrNotExist) {
+			// The remote host may not have generated the file yet.
+			logger.Info(fmt.Sprintf("[%s] Observing, remote file %s does not exist.", mg.GetName(), f.Path))
+			cr.SetConditions(xpv1.Unavailable().WithMessage(fmt.Sprintf(errFmtMissing, f.Path)))
+			return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+		}
+		if err != nil {
+			return managed.ExternalObservation{}, errors.Wrapf(err, errFmtFetch, f.Path)
+		}
+		cd[f.Key] = content
+		files = append(files, apisv1alpha1.FetchedFileStatus{Path: f.Path, Checksum: sshv1alpha1.Checksum(content)})
+	}
+
+	now := metav1.Now()
+	cr.Status.AtProvider = apisv1alpha1.RemoteFetchObservation{Files: files, LastFetchTime: &now}
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. Fetched %d files.", mg.GetName(), len(files)))
+	cr.SetConditions(xpv1.Available())
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true, ConnectionDetails: cd}, nil
+}
+
+func (c *external) Create(_ context.Context, _ resource.Managed) (managed.ExternalCreation, error) {
+	return managed.ExternalCreation{}, nil
+}
+
+func (c *external) Update(_ context.Context, _ resource.Managed) (managed.ExternalUpdate, error) {
+	return managed.ExternalUpdate{}, nil
+}
+
+func (c *external) Delete(_ context.Context, _ resource.Managed) error {
+	return nil
+}
+
+// Disconnect closes the connection to the host, if any.
+func (c *external) Disconnect(_ context.Context) error {
+	if c.service == nil {
+		return nil
+	}
+	return c.service.Close()
+}
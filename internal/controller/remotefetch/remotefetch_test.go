@@ -0,0 +1,206 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotefetch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+const (
+	kubeconfigPath = "/etc/kubernetes/admin.conf"
+	kubeconfig     = "apiVersion: v1\nkind: Config\n"
+)
+
+type remoteFetchModifier func(*apisv1alpha1.RemoteFetch)
+
+func withMaxSize(n int64) remoteFetchModifier {
+	return func(cr *apisv1alpha1.RemoteFetch) { cr.Spec.ForProvider.MaxSizeBytes = &n }
+}
+
+func withFetched(content string) remoteFetchModifier {
+	return func(cr *apisv1alpha1.RemoteFetch) {
+		cr.Status.AtProvider.Files = []apisv1alpha1.FetchedFileStatus{{Path: kubeconfigPath, Checksum: sshv1alpha1.Checksum([]byte(content))}}
+	}
+}
+
+func withDeleted() remoteFetchModifier {
+	return func(cr *apisv1alpha1.RemoteFetch) {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+	}
+}
+
+func remoteFetch(m ...remoteFetchModifier) *apisv1alpha1.RemoteFetch {
+	cr := &apisv1alpha1.RemoteFetch{}
+	cr.SetName("test")
+	cr.Spec.ForProvider.Files = []apisv1alpha1.FetchedFile{{Path: kubeconfigPath, Key: "kubeconfig"}}
+	for _, f := range m {
+		f(cr)
+	}
+	return cr
+}
+
+// fetchHost returns a fake host storing the kubeconfig with the supplied
+// content, if any.
+func fetchHost(t *testing.T, content string) *sshfake.Executor {
+	t.Helper()
+	x := &sshfake.Executor{}
+	if content != "" {
+		if err := x.Upload(context.Background(), kubeconfigPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("x.Upload(...): %v", err)
+		}
+	}
+	return x
+}
+
+func TestObserve(t *testing.T) {
+	type want struct {
+		o      managed.ExternalObservation
+		files  []apisv1alpha1.FetchedFileStatus
+		status xpv1.ConditionedStatus
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		host   string
+		closed bool
+		mg     resource.Managed
+		want   want
+	}{
+		"NotRemoteFetch": {
+			reason: "We should return an error if the managed resource is not a RemoteFetch.",
+			mg:     &fake.Managed{},
+			want:   want{err: errors.New(errNotRemoteFetch)},
+		},
+		"Deleted": {
+			reason: "A deleted RemoteFetch should be reported as gone without fetching any file.",
+			host:   kubeconfig,
+			mg:     remoteFetch(withDeleted()),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"Fetched": {
+			reason: "The content of the remote files should be published as connection details.",
+			host:   kubeconfig,
+			mg:     remoteFetch(),
+			want: want{
+				o: managed.ExternalObservation{
+					ResourceExists:    true,
+					ResourceUpToDate:  true,
+					ConnectionDetails: managed.ConnectionDetails{"kubeconfig": []byte(kubeconfig)},
+				},
+				files:  []apisv1alpha1.FetchedFileStatus{{Path: kubeconfigPath, Checksum: sshv1alpha1.Checksum([]byte(kubeconfig))}},
+				status: *xpv1.NewConditionedStatus(xpv1.Available()),
+			},
+		},
+		"Changed": {
+			reason: "A remote file changed since the last fetch should be published with its new content and checksum.",
+			host:   kubeconfig + "current-context: admin\n",
+			mg:     remoteFetch(withFetched(kubeconfig)),
+			want: want{
+				o: managed.ExternalObservation{
+					ResourceExists:    true,
+					ResourceUpToDate:  true,
+					ConnectionDetails: managed.ConnectionDetails{"kubeconfig": []byte(kubeconfig + "current-context: admin\n")},
+				},
+				files:  []apisv1alpha1.FetchedFileStatus{{Path: kubeconfigPath, Checksum: sshv1alpha1.Checksum([]byte(kubeconfig + "current-context: admin\n"))}},
+				status: *xpv1.NewConditionedStatus(xpv1.Available()),
+			},
+		},
+		"Missing": {
+			reason: "A remote file the host did not generate yet should make the RemoteFetch unavailable, keeping what was fetched.",
+			mg:     remoteFetch(withFetched(kubeconfig)),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				files:  []apisv1alpha1.FetchedFileStatus{{Path: kubeconfigPath, Checksum: sshv1alpha1.Checksum([]byte(kubeconfig))}},
+				status: *xpv1.NewConditionedStatus(xpv1.Unavailable().WithMessage("remote file " + kubeconfigPath + " does not exist")),
+			},
+		},
+		"TooLarge": {
+			reason: "We should return an error if a remote file is larger than maxSizeBytes.",
+			host:   kubeconfig,
+			mg:     remoteFetch(withMaxSize(8)),
+			want:   want{err: errors.Wrap(errors.New("Remote file is larger than 8 bytes"), "cannot fetch "+kubeconfigPath)},
+		},
+		"RemoteError": {
+			reason: "We should return an error if the remote files cannot be fetched from the host.",
+			host:   kubeconfig,
+			closed: true,
+			mg:     remoteFetch(),
+			want:   want{err: errors.Wrap(errors.New("connection closed"), "cannot fetch "+kubeconfigPath)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := fetchHost(t, tc.host)
+			if tc.closed {
+				_ = x.Close()
+			}
+			e := external{service: x}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if cr, ok := tc.mg.(*apisv1alpha1.RemoteFetch); ok {
+				if diff := cmp.Diff(tc.want.files, cr.Status.AtProvider.Files); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want files, +got files:\n%s\n", tc.reason, diff)
+				}
+				if diff := cmp.Diff(tc.want.status, cr.Status.ResourceStatus.ConditionedStatus, test.EquateConditions()); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want conditions, +got conditions:\n%s\n", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	x := fetchHost(t, kubeconfig)
+	e := external{service: x}
+	if err := e.Delete(context.Background(), remoteFetch(withDeleted())); err != nil {
+		t.Fatalf("e.Delete(...): %v", err)
+	}
+	if diff := cmp.Diff([]string{kubeconfigPath}, x.Files()); diff != "" {
+		t.Errorf("\nThe remote files should be left on the host once the RemoteFetch is deleted.\nFiles: -want, +got:\n%s\n", diff)
+	}
+}
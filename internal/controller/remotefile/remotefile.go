@@ -0,0 +1,273 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotefile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotRemoteFile = "managed resource is not a RemoteFile custom resource"
+	errGetContent    = "cannot get content of the file"
+	errNoContent     = "neither content nor contentFrom is set"
+	errFmtNoKey      = "key %s not found in %s/%s"
+	errParseMode     = "cannot parse mode of the file"
+	errStatFile      = "cannot stat remote file"
+	errWriteFile     = "cannot write remote file"
+	errRemoveFile    = "cannot remove remote file"
+)
+
+// Setup adds a controller that reconciles RemoteFile managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.RemoteFileGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.RemoteFileGroupVersionKind),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			newServiceFn: o.NewServiceFn()})),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithCriticalAnnotationUpdater(common.NewStatusPreservingUpdater(mgr.GetClient())),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.RemoteFile{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.RemoteFileGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	newServiceFn common.NewServiceFn
+}
+
+// Connect produces an ExternalClient for the host of the ProviderConfig
+// referenced by the RemoteFile.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteFile)
+	if !ok {
+		return nil, errors.New(errNotRemoteFile)
+	}
+
+	svc, err := common.Connect(ctx, c.kube, c.usage, cr, c.newServiceFn)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
+	return &external{kube: c.kube, service: sshv1alpha1.NewSSHExecutor(svc)}, nil
+}
+
+// An ExternalClient manages a remote file over SFTP.
+type external struct {
+	// A client of the Kubernetes API, used to read the content of the file.
+	kube client.Client
+	// A 'client' used to connect to the external resource API.
+	service sshv1alpha1.FileExecutor
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteFile)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotRemoteFile)
+	}
+
+	fi, err := c.service.StatFile(ctx, cr.Spec.ForProvider.Path)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errStatFile)
+	}
+	if fi == nil {
+		logger.Info(fmt.Sprintf("[%s] Observing, remote file does not exist.", mg.GetName()))
+		cr.Status.AtProvider = apisv1alpha1.RemoteFileObservation{}
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	cr.Status.AtProvider = apisv1alpha1.RemoteFileObservation{
+		Checksum: fi.Checksum,
+		Mode:     fmt.Sprintf("%04o", uint32(fi.Mode)),
+	}
+	if fi.UID >= 0 {
+		cr.Status.AtProvider.UID, cr.Status.AtProvider.GID = &fi.UID, &fi.GID
+	}
+
+	// Files being deleted only need to be removed.
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+
+	d, err := c.desired(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	upToDate := fi.Checksum == sshv1alpha1.Checksum(d.content) && fi.Mode == d.mode &&
+		(d.uid < 0 || d.uid == fi.UID) && (d.gid < 0 || d.gid == fi.GID)
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. Up to date: %t", mg.GetName(), upToDate))
+	cr.SetConditions(xpv1.Available())
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: upToDate}, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	logger := log.FromContext(ctx).WithName("[CREATE]")
+	logger.Info(fmt.Sprintf("[%s] Writing remote file...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteFile)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotRemoteFile)
+	}
+	return managed.ExternalCreation{}, c.write(ctx, cr)
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	logger.Info(fmt.Sprintf("[%s] Writing remote file...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteFile)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotRemoteFile)
+	}
+	return managed.ExternalUpdate{}, c.write(ctx, cr)
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Removing remote file...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.RemoteFile)
+	if !ok {
+		return errors.New(errNotRemoteFile)
+	}
+	return errors.Wrap(c.service.RemoveFile(ctx, cr.Spec.ForProvider.Path), errRemoveFile)
+}
+
+// Disconnect closes the connection to the host, if any.
+func (c *external) Disconnect(_ context.Context) error {
+	if c.service == nil {
+		return nil
+	}
+	return c.service.Close()
+}
+
+func (c *external) write(ctx context.Context, cr *apisv1alpha1.RemoteFile) error {
+	d, err := c.desired(ctx, cr)
+	if err != nil {
+		return err
+	}
+	err = c.service.WriteFile(ctx, cr.Spec.ForProvider.Path, d.content, d.mode, d.uid, d.gid)
+	return errors.Wrap(err, errWriteFile)
+}
+
+// desiredFile is the desired state of the remote file. Negative ids leave the
+// owner or group unmanaged.
+type desiredFile struct {
+	content []byte
+	mode    os.FileMode
+	uid     int
+	gid     int
+}
+
+func (c *external) desired(ctx context.Context, cr *apisv1alpha1.RemoteFile) (desiredFile, error) {
+	p := cr.Spec.ForProvider
+	d := desiredFile{mode: 0o644, uid: -1, gid: -1}
+
+	content, err := resolveContent(ctx, c.kube, p)
+	if err != nil {
+		return d, err
+	}
+	d.content = content
+
+	if p.Mode != "" {
+		m, err := strconv.ParseUint(p.Mode, 8, 32)
+		if err != nil {
+			return d, errors.Wrap(err, errParseMode)
+		}
+		d.mode = os.FileMode(m)
+	}
+	if p.Owner != "" {
+		if d.uid, err = sshv1alpha1.LookupID(ctx, c.service, p.Owner, false); err != nil {
+			return d, err
+		}
+	}
+	if p.Group != "" {
+		if d.gid, err = sshv1alpha1.LookupID(ctx, c.service, p.Group, true); err != nil {
+			return d, err
+		}
+	}
+	return d, nil
+}
+
+// resolveContent returns the inline content of the file, or the content
+// referenced from a ConfigMap or Secret.
+func resolveContent(ctx context.Context, kube client.Client, p apisv1alpha1.RemoteFileParameters) ([]byte, error) {
+	switch {
+	case p.Content != nil:
+		return []byte(*p.Content), nil
+	case p.ContentFrom != nil && p.ContentFrom.ConfigMapKeyRef != nil:
+		ref := p.ContentFrom.ConfigMapKeyRef
+		cm := &corev1.ConfigMap{}
+		if err := kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, cm); err != nil {
+			return nil, errors.Wrap(err, errGetContent)
+		}
+		if v, ok := cm.Data[ref.Key]; ok {
+			return []byte(v), nil
+		}
+		if v, ok := cm.BinaryData[ref.Key]; ok {
+			return v, nil
+		}
+		return nil, errors.Errorf(errFmtNoKey, ref.Key, ref.Namespace, ref.Name)
+	case p.ContentFrom != nil && p.ContentFrom.SecretKeyRef != nil:
+		ref := p.ContentFrom.SecretKeyRef
+		s := &corev1.Secret{}
+		if err := kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, s); err != nil {
+			return nil, errors.Wrap(err, errGetContent)
+		}
+		v, ok := s.Data[ref.Key]
+		if !ok {
+			return nil, errors.Errorf(errFmtNoKey, ref.Key, ref.Namespace, ref.Name)
+		}
+		return v, nil
+	}
+	return nil, errors.New(errNoContent)
+}
@@ -0,0 +1,423 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotefile
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+var errBoom = errors.New("boom")
+
+const (
+	motdPath = "/etc/motd"
+	motd     = "Welcome\n"
+)
+
+type remoteFileModifier func(*apisv1alpha1.RemoteFile)
+
+func withContent(content string) remoteFileModifier {
+	return func(cr *apisv1alpha1.RemoteFile) { cr.Spec.ForProvider.Content = &content }
+}
+
+func withMode(mode string) remoteFileModifier {
+	return func(cr *apisv1alpha1.RemoteFile) { cr.Spec.ForProvider.Mode = mode }
+}
+
+func withOwner(owner, group string) remoteFileModifier {
+	return func(cr *apisv1alpha1.RemoteFile) {
+		cr.Spec.ForProvider.Owner = owner
+		cr.Spec.ForProvider.Group = group
+	}
+}
+
+func withDeleted() remoteFileModifier {
+	return func(cr *apisv1alpha1.RemoteFile) {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+	}
+}
+
+func remoteFile(m ...remoteFileModifier) *apisv1alpha1.RemoteFile {
+	cr := &apisv1alpha1.RemoteFile{}
+	cr.SetName("motd")
+	cr.Spec.ForProvider.Path = motdPath
+	for _, f := range m {
+		f(cr)
+	}
+	return cr
+}
+
+// remoteHost returns a fake executor of a host storing the supplied file,
+// if any, on which the user and group deploy have the id 1000.
+func remoteHost(t *testing.T, content string, mode os.FileMode, uid, gid int) *sshfake.Executor {
+	t.Helper()
+	x := &sshfake.Executor{MockRun: func(script string) (string, string, error) {
+		switch script {
+		case "id -u 'deploy'", "getent group 'deploy' | cut -d: -f3":
+			return "1000\n", "", nil
+		}
+		return "", "", sshfake.ExitError(1)
+	}}
+	if mode != 0 {
+		if err := x.WriteFile(context.Background(), motdPath, []byte(content), mode, uid, gid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return x
+}
+
+// hostFile returns the state of the file on the fake host, nil if it does
+// not exist.
+func hostFile(t *testing.T, x *sshfake.Executor) *sshv1alpha1.FileInfo {
+	t.Helper()
+	fi, err := x.StatFile(context.Background(), motdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi
+}
+
+func TestResolveContent(t *testing.T) {
+	inline := "inline"
+
+	getConfigMap := func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+		obj.(*corev1.ConfigMap).Data = map[string]string{"motd": "from configmap"}
+		return nil
+	}
+	getSecret := func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+		obj.(*corev1.Secret).Data = map[string][]byte{"key": []byte("from secret")}
+		return nil
+	}
+
+	type want struct {
+		content []byte
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		kube   client.Client
+		p      apisv1alpha1.RemoteFileParameters
+		want   want
+	}{
+		"Inline": {
+			reason: "Inline content should be returned as is.",
+			p:      apisv1alpha1.RemoteFileParameters{Content: &inline},
+			want:   want{content: []byte(inline)},
+		},
+		"ConfigMap": {
+			reason: "Content should be read from the referenced ConfigMap key.",
+			kube:   &test.MockClient{MockGet: getConfigMap},
+			p: apisv1alpha1.RemoteFileParameters{ContentFrom: &apisv1alpha1.ContentSource{
+				ConfigMapKeyRef: &apisv1alpha1.ConfigMapKeySelector{Name: "cm", Namespace: "default", Key: "motd"}}},
+			want: want{content: []byte("from configmap")},
+		},
+		"ConfigMapMissingKey": {
+			reason: "We should return an error if the referenced ConfigMap key does not exist.",
+			kube:   &test.MockClient{MockGet: getConfigMap},
+			p: apisv1alpha1.RemoteFileParameters{ContentFrom: &apisv1alpha1.ContentSource{
+				ConfigMapKeyRef: &apisv1alpha1.ConfigMapKeySelector{Name: "cm", Namespace: "default", Key: "other"}}},
+			want: want{err: errors.Errorf(errFmtNoKey, "other", "default", "cm")},
+		},
+		"Secret": {
+			reason: "Content should be read from the referenced Secret key.",
+			kube:   &test.MockClient{MockGet: getSecret},
+			p: apisv1alpha1.RemoteFileParameters{ContentFrom: &apisv1alpha1.ContentSource{
+				SecretKeyRef: &xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Name: "s", Namespace: "default"}, Key: "key"}}},
+			want: want{content: []byte("from secret")},
+		},
+		"GetError": {
+			reason: "We should return an error if the referenced Secret cannot be retrieved.",
+			kube:   &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			p: apisv1alpha1.RemoteFileParameters{ContentFrom: &apisv1alpha1.ContentSource{
+				SecretKeyRef: &xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Name: "s", Namespace: "default"}, Key: "key"}}},
+			want: want{err: errors.Wrap(errBoom, errGetContent)},
+		},
+		"NoContent": {
+			reason: "We should return an error if no content is set.",
+			want:   want{err: errors.New(errNoContent)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := resolveContent(context.Background(), tc.kube, tc.p)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nresolveContent(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.content, got); diff != "" {
+				t.Errorf("\n%s\nresolveContent(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserve(t *testing.T) {
+	uid := 1000
+
+	type want struct {
+		o      managed.ExternalObservation
+		status apisv1alpha1.RemoteFileObservation
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason string
+		x      func(t *testing.T) *sshfake.Executor
+		mg     *apisv1alpha1.RemoteFile
+		want   want
+	}{
+		"NotExists": {
+			reason: "A file missing on the host should be reported as not existing.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, "", 0, 0, 0) },
+			mg:     remoteFile(withContent(motd)),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"UpToDate": {
+			reason: "A file with the content and mode of the RemoteFile should be up to date.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, motd, 0o644, 0, 0) },
+			mg:     remoteFile(withContent(motd)),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				status: apisv1alpha1.RemoteFileObservation{Checksum: sshv1alpha1.Checksum([]byte(motd)), Mode: "0644", UID: new(int), GID: new(int)},
+			},
+		},
+		"ContentDrift": {
+			reason: "A file whose content differs from that of the RemoteFile should not be up to date.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, "Go away\n", 0o644, 0, 0) },
+			mg:     remoteFile(withContent(motd)),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				status: apisv1alpha1.RemoteFileObservation{Checksum: sshv1alpha1.Checksum([]byte("Go away\n")), Mode: "0644", UID: new(int), GID: new(int)},
+			},
+		},
+		"ModeDrift": {
+			reason: "A file whose mode differs from that of the RemoteFile should not be up to date.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, motd, 0o644, 0, 0) },
+			mg:     remoteFile(withContent(motd), withMode("0600")),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				status: apisv1alpha1.RemoteFileObservation{Checksum: sshv1alpha1.Checksum([]byte(motd)), Mode: "0644", UID: new(int), GID: new(int)},
+			},
+		},
+		"OwnerDrift": {
+			reason: "A file whose owner differs from that of the RemoteFile should not be up to date.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, motd, 0o644, 0, 0) },
+			mg:     remoteFile(withContent(motd), withOwner("deploy", "")),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				status: apisv1alpha1.RemoteFileObservation{Checksum: sshv1alpha1.Checksum([]byte(motd)), Mode: "0644", UID: new(int), GID: new(int)},
+			},
+		},
+		"OwnerUpToDate": {
+			reason: "A file owned by the owner and group of the RemoteFile should be up to date.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, motd, 0o644, 1000, 1000) },
+			mg:     remoteFile(withContent(motd), withOwner("deploy", "deploy")),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				status: apisv1alpha1.RemoteFileObservation{Checksum: sshv1alpha1.Checksum([]byte(motd)), Mode: "0644", UID: &uid, GID: &uid},
+			},
+		},
+		"UnknownOwner": {
+			reason: "We should return an error if the owner of the RemoteFile does not exist on the host.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, motd, 0o644, 0, 0) },
+			mg:     remoteFile(withContent(motd), withOwner("nobody", "")),
+			want: want{
+				status: apisv1alpha1.RemoteFileObservation{Checksum: sshv1alpha1.Checksum([]byte(motd)), Mode: "0644", UID: new(int), GID: new(int)},
+				err:    errors.Wrap(sshfake.ExitError(1), "Failed to look up id of nobody"),
+			},
+		},
+		"Deleted": {
+			reason: "A deleted RemoteFile whose file exists should only need to be removed.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, "Go away\n", 0o600, 0, 0) },
+			mg:     remoteFile(withContent(motd), withDeleted()),
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				status: apisv1alpha1.RemoteFileObservation{Checksum: sshv1alpha1.Checksum([]byte("Go away\n")), Mode: "0600", UID: new(int), GID: new(int)},
+			},
+		},
+		"StatError": {
+			reason: "We should return an error if the file cannot be stat'ed on the host.",
+			x: func(t *testing.T) *sshfake.Executor {
+				x := remoteHost(t, motd, 0o644, 0, 0)
+				_ = x.Close()
+				return x
+			},
+			mg:   remoteFile(withContent(motd)),
+			want: want{err: errors.Wrap(errors.New("connection closed"), errStatFile)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: tc.x(t)}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.status, tc.mg.Status.AtProvider); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want status, +got status:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	_, errMode := strconv.ParseUint("rw-r--r--", 8, 32)
+
+	type want struct {
+		file *sshv1alpha1.FileInfo
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		x      func(t *testing.T) *sshfake.Executor
+		mg     *apisv1alpha1.RemoteFile
+		update bool
+		want   want
+	}{
+		"Create": {
+			reason: "A missing file should be written with the content and the default mode of the RemoteFile.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, "", 0, 0, 0) },
+			mg:     remoteFile(withContent(motd)),
+			want:   want{file: &sshv1alpha1.FileInfo{Checksum: sshv1alpha1.Checksum([]byte(motd)), Mode: 0o644}},
+		},
+		"CreateOwned": {
+			reason: "A missing file should be written with the mode, owner and group of the RemoteFile.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, "", 0, 0, 0) },
+			mg:     remoteFile(withContent(motd), withMode("0640"), withOwner("deploy", "deploy")),
+			want:   want{file: &sshv1alpha1.FileInfo{Checksum: sshv1alpha1.Checksum([]byte(motd)), Mode: 0o640, UID: 1000, GID: 1000}},
+		},
+		"Update": {
+			reason: "A drifted file should be rewritten with the content and mode of the RemoteFile, keeping its unmanaged owner.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, "Go away\n", 0o666, 1000, 1000) },
+			mg:     remoteFile(withContent(motd), withMode("0600")),
+			update: true,
+			want:   want{file: &sshv1alpha1.FileInfo{Checksum: sshv1alpha1.Checksum([]byte(motd)), Mode: 0o600, UID: 1000, GID: 1000}},
+		},
+		"InvalidMode": {
+			reason: "We should return an error if the mode of the RemoteFile is not octal, leaving the host untouched.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, "", 0, 0, 0) },
+			mg:     remoteFile(withContent(motd), withMode("rw-r--r--")),
+			want:   want{err: errors.Wrap(errMode, errParseMode)},
+		},
+		"WriteError": {
+			reason: "We should return an error if the file cannot be written to the host.",
+			x: func(t *testing.T) *sshfake.Executor {
+				x := remoteHost(t, "", 0, 0, 0)
+				_ = x.Close()
+				return x
+			},
+			mg:   remoteFile(withContent(motd)),
+			want: want{err: errors.Wrap(errors.New("connection closed"), errWriteFile)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := tc.x(t)
+			e := external{service: x}
+			var err error
+			if tc.update {
+				_, err = e.Update(context.Background(), tc.mg)
+			} else {
+				_, err = e.Create(context.Background(), tc.mg)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.write(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if x.Closed() {
+				return
+			}
+			if diff := cmp.Diff(tc.want.file, hostFile(t, x)); diff != "" {
+				t.Errorf("\n%s\nRemote file: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		x      func(t *testing.T) *sshfake.Executor
+		err    error
+	}{
+		"Exists": {
+			reason: "The file should be removed from the host.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, motd, 0o644, 0, 0) },
+		},
+		"NotExists": {
+			reason: "Removing a file missing on the host should succeed.",
+			x:      func(t *testing.T) *sshfake.Executor { return remoteHost(t, "", 0, 0, 0) },
+		},
+		"RemoveError": {
+			reason: "We should return an error if the file cannot be removed from the host.",
+			x: func(t *testing.T) *sshfake.Executor {
+				x := remoteHost(t, motd, 0o644, 0, 0)
+				_ = x.Close()
+				return x
+			},
+			err: errors.Wrap(errors.New("connection closed"), errRemoveFile),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := tc.x(t)
+			e := external{service: x}
+			err := e.Delete(context.Background(), remoteFile(withContent(motd), withDeleted()))
+			if diff := cmp.Diff(tc.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if x.Closed() {
+				return
+			}
+			if diff := cmp.Diff([]string{}, x.Files()); diff != "" {
+				t.Errorf("\n%s\nRemote files: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reversetunnel
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotReverseTunnel = "managed resource is not a ReverseTunnel custom resource"
+	errNoTarget         = "neither a service nor an address is set as target"
+
+	// defaultKeepAlive is the default interval of keepalive requests.
+	defaultKeepAlive = 30 * time.Second
+)
+
+// Setup adds a controller that reconciles ReverseTunnel managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.ReverseTunnelGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.ReverseTunnelGroupVersionKind),
+		managed.WithExternalConnecter(&connector{
+			kube:    mgr.GetClient(),
+			usage:   resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			tunnels: NewManager(o.Logger.WithValues("controller", name), o.NewServiceFn())}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.ReverseTunnel{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.ReverseTunnelGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube    client.Client
+	usage   resource.Tracker
+	tunnels *Manager
+}
+
+// Connect produces an ExternalClient that manages the tunnel of the
+// ReverseTunnel. It does not connect to the remote host itself, since the
+// tunnel keeps its own connection.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.ReverseTunnel)
+	if !ok {
+		return nil, errors.New(errNotReverseTunnel)
+	}
+
+	if meta.WasDeleted(cr) {
+		return &external{tunnels: c.tunnels}, nil
+	}
+
+	data, err := common.Credentials(ctx, c.kube, c.usage, cr)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := desired(cr, data)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
+	return &external{tunnels: c.tunnels, spec: spec}, nil
+}
+
+// An ExternalClient starts, restarts and stops the tunnel of a ReverseTunnel.
+type external struct {
+	tunnels *Manager
+	spec    tunnelSpec
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.ReverseTunnel)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotReverseTunnel)
+	}
+
+	spec, state, ok := c.tunnels.Get(cr.GetName())
+	if !ok {
+		logger.Info(fmt.Sprintf("[%s] Observing, tunnel is not running.", mg.GetName()))
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	cr.Status.AtProvider = apisv1alpha1.ReverseTunnelObservation{
+		Established: state.established,
+		Reconnects:  state.reconnects,
+		LastError:   state.lastError,
+	}
+	if !state.lastEstablished.IsZero() {
+		t := metav1.NewTime(state.lastEstablished)
+		cr.Status.AtProvider.LastEstablishedTime = &t
+	}
+	if state.established {
+		cr.SetConditions(xpv1.Available())
+	} else {
+		cr.SetConditions(xpv1.Unavailable().WithMessage(state.lastError))
+	}
+
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: spec == c.spec}, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	logger := log.FromContext(ctx).WithName("[CREATE]")
+	logger.Info(fmt.Sprintf("[%s] Starting tunnel...", mg.GetName()))
+	c.tunnels.Start(mg.GetName(), c.spec)
+	return managed.ExternalCreation{}, nil
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	logger.Info(fmt.Sprintf("[%s] Restarting tunnel...", mg.GetName()))
+	c.tunnels.Start(mg.GetName(), c.spec)
+	return managed.ExternalUpdate{}, nil
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Stopping tunnel...", mg.GetName()))
+	c.tunnels.Stop(mg.GetName())
+	return nil
+}
+
+// desired returns the spec of the tunnel of the ReverseTunnel.
+func desired(cr *apisv1alpha1.ReverseTunnel, creds []byte) (tunnelSpec, error) {
+	p := cr.Spec.ForProvider
+	spec := tunnelSpec{keepAlive: defaultKeepAlive, credentials: string(creds)}
+
+	bind := p.RemoteBindAddress
+	if bind == "" {
+		bind = "127.0.0.1"
+	}
+	spec.remoteAddress = net.JoinHostPort(bind, strconv.Itoa(p.RemotePort))
+
+	switch {
+	case p.Target.Service != nil:
+		svc := p.Target.Service
+		spec.targetAddress = net.JoinHostPort(fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace), strconv.Itoa(svc.Port))
+	case p.Target.Address != "":
+		spec.targetAddress = p.Target.Address
+	default:
+		return spec, errors.New(errNoTarget)
+	}
+
+	if p.KeepAliveIntervalSeconds != nil {
+		spec.keepAlive = time.Duration(*p.KeepAliveIntervalSeconds) * time.Second
+	}
+	return spec, nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reversetunnel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+func TestObserve(t *testing.T) {
+	type want struct {
+		o   managed.ExternalObservation
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     resource.Managed
+		want   want
+	}{
+		"NotReverseTunnel": {
+			reason: "We should return an error if the managed resource is not a ReverseTunnel.",
+			mg:     &fake.Managed{},
+			want: want{
+				err: errors.New(errNotReverseTunnel),
+			},
+		},
+		"NotRunning": {
+			reason: "A ReverseTunnel without a running tunnel should not exist.",
+			mg:     &apisv1alpha1.ReverseTunnel{},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: false},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{tunnels: NewManager(logging.NewNopLogger(), nil)}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDesired(t *testing.T) {
+	keepAlive := int64(10)
+
+	type want struct {
+		spec tunnelSpec
+		err  error
+	}
+
+	cases := map[string]struct {
+		reason string
+		p      apisv1alpha1.ReverseTunnelParameters
+		want   want
+	}{
+		"Service": {
+			reason: "A service target should resolve to the cluster DNS name of the service.",
+			p: apisv1alpha1.ReverseTunnelParameters{
+				RemotePort: 8080,
+				Target: apisv1alpha1.TunnelTarget{
+					Service: &apisv1alpha1.ServiceReference{Name: "web", Namespace: "default", Port: 80},
+				},
+			},
+			want: want{
+				spec: tunnelSpec{
+					remoteAddress: "127.0.0.1:8080",
+					targetAddress: "web.default.svc:80",
+					keepAlive:     defaultKeepAlive,
+					credentials:   "creds",
+				},
+			},
+		},
+		"Address": {
+			reason: "An address target should be used as is.",
+			p: apisv1alpha1.ReverseTunnelParameters{
+				RemoteBindAddress:        "0.0.0.0",
+				RemotePort:               2222,
+				Target:                   apisv1alpha1.TunnelTarget{Address: "10.0.0.1:22"},
+				KeepAliveIntervalSeconds: &keepAlive,
+			},
+			want: want{
+				spec: tunnelSpec{
+					remoteAddress: "0.0.0.0:2222",
+					targetAddress: "10.0.0.1:22",
+					keepAlive:     10 * time.Second,
+					credentials:   "creds",
+				},
+			},
+		},
+		"NoTarget": {
+			reason: "We should return an error if no target is set.",
+			p:      apisv1alpha1.ReverseTunnelParameters{RemotePort: 8080},
+			want: want{
+				err: errors.New(errNoTarget),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &apisv1alpha1.ReverseTunnel{}
+			cr.Spec.ForProvider = tc.p
+			got, err := desired(cr, []byte("creds"))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ndesired(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if tc.want.err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.spec, got, cmp.AllowUnexported(tunnelSpec{})); diff != "" {
+				t.Errorf("\n%s\ndesired(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
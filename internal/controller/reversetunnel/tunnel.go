@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reversetunnel
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	minBackoff  = time.Second
+	maxBackoff  = time.Minute
+	dialTimeout = 10 * time.Second
+)
+
+// A tunnelSpec is the desired state of a tunnel. Tunnels whose spec changed
+// are restarted.
+type tunnelSpec struct {
+	remoteAddress string
+	targetAddress string
+	keepAlive     time.Duration
+	credentials   string
+}
+
+// A tunnelState is a snapshot of the observed state of a tunnel.
+type tunnelState struct {
+	established     bool
+	lastEstablished time.Time
+	reconnects      int32
+	lastError       string
+}
+
+// A tunnel keeps a remote port forwarded to its target, re-establishing the
+// SSH connection whenever it breaks.
+type tunnel struct {
+	spec   tunnelSpec
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu    sync.Mutex
+	state tunnelState
+}
+
+func (t *tunnel) snapshot() tunnelState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
+
+func (t *tunnel) up() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.state.lastEstablished.IsZero() {
+		t.state.reconnects++
+	}
+	t.state.established = true
+	t.state.lastEstablished = time.Now()
+}
+
+func (t *tunnel) down(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.established = false
+	if err != nil {
+		t.state.lastError = err.Error()
+	}
+}
+
+// A Manager runs the tunnels of all ReverseTunnels independently of their
+// reconciles, which only start, restart and stop them.
+type Manager struct {
+	log          logging.Logger
+	newServiceFn common.NewServiceFn
+
+	mu      sync.Mutex
+	tunnels map[string]*tunnel
+}
+
+// NewManager returns a Manager that connects through the supplied function.
+func NewManager(log logging.Logger, fn common.NewServiceFn) *Manager {
+	return &Manager{log: log, newServiceFn: fn, tunnels: map[string]*tunnel{}}
+}
+
+// Get returns the spec and state of the named tunnel, if it is running.
+func (m *Manager) Get(name string) (tunnelSpec, tunnelState, bool) {
+	m.mu.Lock()
+	t, ok := m.tunnels[name]
+	m.mu.Unlock()
+	if !ok {
+		return tunnelSpec{}, tunnelState{}, false
+	}
+	return t.spec, t.snapshot(), true
+}
+
+// Start (re)starts the named tunnel with the supplied spec. The state of a
+// restarted tunnel is kept.
+func (m *Manager) Start(name string, spec tunnelSpec) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := &tunnel{spec: spec, done: make(chan struct{})}
+	if old, ok := m.tunnels[name]; ok {
+		old.cancel()
+		<-old.done
+		t.state = old.snapshot()
+		t.state.established = false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	m.tunnels[name] = t
+	go m.run(ctx, name, t)
+}
+
+// Stop stops the named tunnel, if it is running.
+func (m *Manager) Stop(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.tunnels[name]; ok {
+		t.cancel()
+		<-t.done
+		delete(m.tunnels, name)
+	}
+}
+
+// run serves the tunnel until its context is cancelled, backing off
+// exponentially between failed attempts.
+func (m *Manager) run(ctx context.Context, name string, t *tunnel) {
+	defer close(t.done)
+	log := m.log.WithValues("tunnel", name)
+	backoff := minBackoff
+	for {
+		start := time.Now()
+		err := m.serve(ctx, t)
+		t.down(err)
+		if ctx.Err() != nil {
+			return
+		}
+		// Tunnels that were established start backing off from scratch.
+		if t.snapshot().lastEstablished.After(start) {
+			backoff = minBackoff
+		}
+		log.Info("Tunnel broken, reconnecting", "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// serve establishes the tunnel and blocks until it breaks or its context is
+// cancelled.
+func (m *Manager) serve(ctx context.Context, t *tunnel) error {
+	c, err := m.newServiceFn(ctx, []byte(t.spec.credentials))
+	if err != nil {
+		return err
+	}
+	defer c.Close() // nolint: errcheck
+
+	l, err := c.Listen("tcp", t.spec.remoteAddress)
+	if err != nil {
+		return err
+	}
+	defer l.Close() // nolint: errcheck
+	t.up()
+
+	errs := make(chan error, 2)
+	go func() {
+		tick := time.NewTicker(t.spec.keepAlive)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				if _, _, err := c.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				errs <- err
+				return
+			}
+			go forward(conn, t.spec.targetAddress)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
+
+// forward copies data between a connection accepted on the remote host and a
+// new connection to the target.
+func forward(remote net.Conn, target string) {
+	defer remote.Close() // nolint: errcheck
+	local, err := net.DialTimeout("tcp", target, dialTimeout)
+	if err != nil {
+		return
+	}
+	defer local.Close() // nolint: errcheck
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	<-done
+}
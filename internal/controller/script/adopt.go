@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	errReadMarker   = "cannot read state marker"
+	errWriteMarker  = "cannot write state marker"
+	errRemoveMarker = "cannot remove state marker"
+
+	// maxMarkerSize is the size in bytes above which a state marker is
+	// rejected.
+	maxMarkerSize = 64 << 10
+)
+
+// A marker is the content of the state marker the provider writes on the
+// host once the initScript succeeded. Markers that are not JSON, e.g. empty
+// files, are accepted as well.
+type marker struct {
+	ScriptHash string `json:"scriptHash,omitempty"`
+}
+
+// markerPath returns the path of the state marker of the Script on the host.
+// It is the external name of the Script, provided it is an absolute path.
+func markerPath(cr *apisv1alpha1.Script) string {
+	p := meta.GetExternalName(cr)
+	if !path.IsAbs(p) {
+		return ""
+	}
+	return path.Clean(p)
+}
+
+// adopt late-initializes the status of the Script from the content of its
+// state marker.
+func adopt(cr *apisv1alpha1.Script, content []byte) {
+	m := marker{}
+	if err := json.Unmarshal(content, &m); err != nil {
+		return
+	}
+	if cr.Status.AtProvider.ScriptHash == "" {
+		cr.Status.AtProvider.ScriptHash = m.ScriptHash
+	}
+}
+
+// markerFor returns the content of the state marker of the Script.
+func markerFor(cr *apisv1alpha1.Script) []byte {
+	b, _ := json.Marshal(marker{ScriptHash: cr.Status.AtProvider.ScriptHash})
+	return b
+}
+
+// observeMarker reports whether the state marker of the Script exists on the
+// host, in which case the Script is adopted from it.
+func (c *external) observeMarker(ctx context.Context, cr *apisv1alpha1.Script) (bool, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	p := markerPath(cr)
+	if p == "" {
+		return false, nil
+	}
+	if c.service == nil {
+		return false, errors.New(errNotConnected)
+	}
+	content, err := c.service.Fetch(ctx, p, maxMarkerSize)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, errReadMarker)
+	}
+	logger.Info(fmt.Sprintf("[%s] State marker %s found.", cr.GetName(), p))
+	adopt(cr, content)
+	return true, nil
+}
+
+// writeMarker records the state of the Script in its state marker.
+func (c *external) writeMarker(ctx context.Context, cr *apisv1alpha1.Script) error {
+	p := markerPath(cr)
+	if p == "" {
+		return nil
+	}
+	if c.service == nil {
+		return errors.New(errNotConnected)
+	}
+	return errors.Wrap(c.service.Upload(ctx, p, markerFor(cr), 0o644), errWriteMarker)
+}
+
+// removeMarker removes the state marker of the Script.
+func (c *external) removeMarker(ctx context.Context, cr *apisv1alpha1.Script) error {
+	p := markerPath(cr)
+	if p == "" {
+		return nil
+	}
+	if c.service == nil {
+		return errors.New(errNotConnected)
+	}
+	return errors.Wrap(sshv1alpha1.Remove(ctx, c.service, p), errRemoveMarker)
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	errBatchWait = "timed out waiting for the batch of the status check"
+)
+
+// A dialFn connects to the host of a Script.
+type dialFn func(ctx context.Context) (sshv1alpha1.RemoteExecutor, error)
+
+// A batcher executes the statusCheckScripts of the Scripts connecting to the
+// same host with the same credentials in batches, each over a single
+// connection and in a single session. The first statusCheckScript submitted
+// for a host waits for the window of the batcher for others to join it.
+type batcher struct {
+	window time.Duration
+	max    int
+
+	mu      sync.Mutex
+	pending map[string]*batch
+}
+
+// A batch is the statusCheckScripts submitted for a host within a window.
+type batch struct {
+	dial    dialFn
+	scripts []sshv1alpha1.BatchScript
+	started sync.Once
+
+	// The results of the batch, set once done is closed.
+	done    chan struct{}
+	results []sshv1alpha1.BatchResult
+	remote  *sshv1alpha1.Remote
+	err     error
+}
+
+// newBatcher returns a batcher collecting the statusCheckScripts submitted
+// within the supplied window, up to max of them if max is positive. It
+// returns nil, which batches nothing, if the window is not positive.
+func newBatcher(window time.Duration, max int) *batcher {
+	if window <= 0 {
+		return nil
+	}
+	return &batcher{window: window, max: max, pending: map[string]*batch{}}
+}
+
+// execute submits the script to the batch of the host of the supplied key,
+// and returns its result and the host it was executed on once the batch was
+// executed. The batch connects through the dial function of the first
+// script submitted to it.
+func (b *batcher) execute(ctx context.Context, key string, dial dialFn, s sshv1alpha1.BatchScript) (sshv1alpha1.BatchResult, *sshv1alpha1.Remote, error) {
+	b.mu.Lock()
+	bt, ok := b.pending[key]
+	if !ok {
+		bt = &batch{dial: dial, done: make(chan struct{})}
+		b.pending[key] = bt
+		time.AfterFunc(b.window, func() { b.run(key, bt) })
+	}
+	i := len(bt.scripts)
+	bt.scripts = append(bt.scripts, s)
+	full := b.max > 0 && len(bt.scripts) >= b.max
+	b.mu.Unlock()
+	if full {
+		go b.run(key, bt)
+	}
+
+	select {
+	case <-bt.done:
+	case <-ctx.Done():
+		return sshv1alpha1.BatchResult{}, nil, errors.Wrap(ctx.Err(), errBatchWait)
+	}
+	if bt.err != nil {
+		return sshv1alpha1.BatchResult{}, nil, bt.err
+	}
+	return bt.results[i], bt.remote, nil
+}
+
+// run executes the batch once, closing the connection it made afterwards.
+// Scripts submitted for the host after it started join the next batch.
+func (b *batcher) run(key string, bt *batch) {
+	bt.started.Do(func() {
+		defer close(bt.done)
+		b.mu.Lock()
+		if b.pending[key] == bt {
+			delete(b.pending, key)
+		}
+		scripts := bt.scripts
+		b.mu.Unlock()
+
+		// The batch outlives the reconciles of the Scripts waiting for it.
+		ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+		defer cancel()
+		logger := log.FromContext(ctx).WithName("[BATCH]")
+		logger.Info(fmt.Sprintf("Executing a batch of %d status checks...", len(scripts)))
+
+		x, err := bt.dial(ctx)
+		if err != nil {
+			bt.err = err
+			return
+		}
+		defer x.Close() // nolint: errcheck
+		if r, ok := x.(remoter); ok {
+			remote := r.Remote()
+			bt.remote = &remote
+		}
+		bt.results, bt.err = sshv1alpha1.ExecuteBatch(ctx, x, scripts)
+	})
+}
+
+// batches reports whether the status checks of a Script may be batched: it
+// has a statusCheckScript, rather than an existsScript and upToDateScript.
+func batches(p apisv1alpha1.ScriptParameters) bool {
+	return p.StatusCheckScript != "" && !separateChecks(p)
+}
+
+// batchKey returns the key of the batches of the Scripts connecting to the
+// host of the supplied ProviderConfig with the overrides of the supplied
+// parameters. A VirtualMachineInstance or Machine is keyed by name, its
+// address being resolved when the batch connects.
+func batchKey(pc *apisv1alpha1.ProviderConfig, p apisv1alpha1.ScriptParameters) string {
+	key := pc.GetName() + "/" + pc.GetResourceVersion() + "/" + p.Username
+	port := func(p *int) int {
+		if p == nil {
+			return 22
+		}
+		return *p
+	}
+	switch {
+	case p.VirtualMachineInstance != nil:
+		vmi := p.VirtualMachineInstance
+		key += fmt.Sprintf("@vmi/%s/%s/%s:%d", vmi.Namespace, vmi.Name, vmi.Interface, port(vmi.Port))
+	case p.Machine != nil:
+		m := p.Machine
+		key += fmt.Sprintf("@%s/%s/%s/%s:%d", m.Kind, m.Namespace, m.Name, m.AddressType, port(m.Port))
+	case p.Endpoint != nil:
+		key += fmt.Sprintf("@%s:%d", p.Endpoint.Host, port(p.Endpoint.Port))
+	}
+	return key
+}
+
+// statusCheck executes the statusCheckScript of the Script, in the batch of
+// its host if its status checks are batched.
+func (c *external) statusCheck(ctx context.Context, p apisv1alpha1.ScriptParameters) (string, string, error) {
+	l, ok := c.service.(*lazyExecutor)
+	if !ok || c.batch == nil || l.x != nil {
+		return sshv1alpha1.ExecuteScript(ctx, c.service, p.StatusCheckScript, p.Variables, SudoEnabled(p), ExecOptions(p)...)
+	}
+	r, remote, err := c.batch.execute(ctx, l.key, l.dial, sshv1alpha1.BatchScript{
+		Script:      p.StatusCheckScript,
+		Variables:   p.Variables,
+		SudoEnabled: SudoEnabled(p),
+		Options:     ExecOptions(p),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	l.remote = remote
+	return r.Stdout, r.Stderr, r.Err
+}
+
+// A lazyExecutor connects to the host of a Script on first use, so that a
+// Script whose statusCheckScript is executed in a batch only connects to its
+// host if it executes another script.
+type lazyExecutor struct {
+	// The key of the batches of the host.
+	key  string
+	dial dialFn
+	// The host the batch of the statusCheckScript was executed on, nil if
+	// it was not executed in a batch.
+	remote *sshv1alpha1.Remote
+	// The executor connected to the host, nil until first used.
+	x sshv1alpha1.RemoteExecutor
+}
+
+func (l *lazyExecutor) connect(ctx context.Context) (sshv1alpha1.RemoteExecutor, error) {
+	if l.x == nil {
+		x, err := l.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		l.x = x
+	}
+	return l.x, nil
+}
+
+// Remote returns the host the executor is connected to, or the one the
+// batch of the statusCheckScript was executed on.
+func (l *lazyExecutor) Remote() sshv1alpha1.Remote {
+	if r, ok := l.x.(remoter); ok {
+		return r.Remote()
+	}
+	if l.remote != nil {
+		return *l.remote
+	}
+	return sshv1alpha1.Remote{}
+}
+
+// Upload connects to the host and uploads the content.
+func (l *lazyExecutor) Upload(ctx context.Context, remotePath string, content []byte, mode os.FileMode) error {
+	x, err := l.connect(ctx)
+	if err != nil {
+		return err
+	}
+	return x.Upload(ctx, remotePath, content, mode)
+}
+
+// Run connects to the host and runs the command.
+func (l *lazyExecutor) Run(ctx context.Context, cmd string, timeout time.Duration) (string, string, error) {
+	x, err := l.connect(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return x.Run(ctx, cmd, timeout)
+}
+
+// Stream connects to the host and runs the command, streaming its output if
+// the connected executor streams it.
+func (l *lazyExecutor) Stream(ctx context.Context, cmd string, timeout time.Duration, stdout, stderr io.Writer) error {
+	x, err := l.connect(ctx)
+	if err != nil {
+		return err
+	}
+	if sx, ok := x.(sshv1alpha1.StreamingExecutor); ok {
+		return sx.Stream(ctx, cmd, timeout, stdout, stderr)
+	}
+	o, e, err := x.Run(ctx, cmd, timeout)
+	_, _ = io.WriteString(stdout, o)
+	_, _ = io.WriteString(stderr, e)
+	return err
+}
+
+// Fetch connects to the host and reads the remote file.
+func (l *lazyExecutor) Fetch(ctx context.Context, remotePath string, maxSize int64) ([]byte, error) {
+	x, err := l.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return x.Fetch(ctx, remotePath, maxSize)
+}
+
+// Close closes the connection to the host, if any was made.
+func (l *lazyExecutor) Close() error {
+	if l.x == nil {
+		return nil
+	}
+	return l.x.Close()
+}
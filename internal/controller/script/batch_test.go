@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+func TestObserveBatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("executes the batch on an in-process SSH server")
+	}
+
+	srv := sshtest.NewServer(t)
+	var dials atomic.Int32
+	dial := func(ctx context.Context) (sshv1alpha1.RemoteExecutor, error) {
+		dials.Add(1)
+		c, err := sshv1alpha1.NewSSHClient(ctx, srv.Credentials())
+		if err != nil {
+			return nil, err
+		}
+		return sshv1alpha1.NewSSHExecutor(c), nil
+	}
+
+	type want struct {
+		o      managed.ExternalObservation
+		stdout string
+		code   int
+	}
+	cases := map[string]struct {
+		cr   *apisv1alpha1.Script
+		want want
+	}{
+		"UpToDate": {
+			cr:   script(withStatusCheck("echo running")),
+			want: want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, stdout: "running\n"},
+		},
+		"Missing": {
+			cr:   script(withStatusCheck("exit 100")),
+			want: want{o: managed.ExternalObservation{ResourceExists: false}, code: 100},
+		},
+		"Drifted": {
+			cr:   script(withStatusCheck("printf drifted; exit 3")),
+			want: want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, stdout: "drifted", code: 3},
+		},
+	}
+
+	// The Scripts are observed at the same time, so that their checks
+	// share a batch.
+	b := newBatcher(time.Second, len(cases))
+	var wg sync.WaitGroup
+	got := make(map[string]want, len(cases))
+	var mu sync.Mutex
+	for name, tc := range cases {
+		wg.Add(1)
+		go func(name string, cr *apisv1alpha1.Script) {
+			defer wg.Done()
+			l := &lazyExecutor{key: "host", dial: dial}
+			e := external{service: l, batch: b, recorder: event.NewNopRecorder()}
+			o, err := e.Observe(context.Background(), cr)
+			if err != nil {
+				t.Errorf("\n%s\ne.Observe(...): %v", name, err)
+			}
+			if l.x != nil {
+				t.Errorf("\n%s\ne.Observe(...): the Script connected to its host, want its check executed in the batch", name)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			got[name] = want{o: managed.ExternalObservation{ResourceExists: o.ResourceExists, ResourceUpToDate: o.ResourceUpToDate}, stdout: cr.Status.AtProvider.Stdout, code: cr.Status.AtProvider.StatusCode}
+		}(name, tc.cr)
+	}
+	wg.Wait()
+
+	for name, tc := range cases {
+		if diff := cmp.Diff(tc.want, got[name], cmp.AllowUnexported(want{})); diff != "" {
+			t.Errorf("\n%s\nThe Script should be observed from its own output in the batch.\ne.Observe(...): -want, +got:\n%s\n", name, diff)
+		}
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("\nThe checks of the Scripts of a host should share a single connection.\ndials: %d, want 1", n)
+	}
+}
+
+func TestBatchDialFailed(t *testing.T) {
+	errBoom := errors.New("boom")
+	b := newBatcher(time.Millisecond, 0)
+	l := &lazyExecutor{key: "host", dial: func(context.Context) (sshv1alpha1.RemoteExecutor, error) { return nil, errBoom }}
+	e := external{service: l, batch: b, recorder: event.NewNopRecorder()}
+	_, _, err := e.statusCheck(context.Background(), apisv1alpha1.ScriptParameters{StatusCheckScript: "true"})
+	if diff := cmp.Diff(errBoom, err, test.EquateErrors()); diff != "" {
+		t.Errorf("\nA batch that cannot connect should fail the checks waiting for it.\ne.statusCheck(...): -want error, +got error:\n%s\n", diff)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+// statusCached reports whether the next observation of the Script reuses the
+// last result of its statusCheckScript: its statusCacheTTL did not elapse
+// since it was executed, and neither did the spec change, nor a script run,
+// nor the retryTime it requested pass since, and the Script is not deleted.
+func statusCached(cr *apisv1alpha1.Script) bool {
+	a := cr.Status.AtProvider
+	if a.StatusCachedUntil == nil || a.CheckedGeneration != cr.GetGeneration() || meta.WasDeleted(cr) {
+		return false
+	}
+	if a.RetryTime != nil && !time.Now().Before(a.RetryTime.Time) {
+		return false
+	}
+	return time.Now().Before(a.StatusCachedUntil.Time)
+}
+
+// cacheStatus records until when the result of the statusCheckScript that
+// was just executed is reused, if the Script sets a statusCacheTTL. The
+// results of statusCheckScripts that could not be executed, e.g. because the
+// host is unreachable, are not reused.
+func cacheStatus(cr *apisv1alpha1.Script, err error) {
+	a := &cr.Status.AtProvider
+	ttl := cr.Spec.ForProvider.StatusCacheTTL
+	if ttl == nil || (err != nil && !sshv1alpha1.IsExitError(err)) {
+		a.StatusCachedUntil = nil
+		return
+	}
+	until := metav1.NewTime(time.Now().Add(ttl.Duration))
+	a.StatusCachedUntil, a.CheckedGeneration = &until, cr.GetGeneration()
+}
+
+// cachedStatus returns the last result of the statusCheckScript, as it is
+// recorded in the status of the Script. A stdout recorded as lines is joined
+// back, with a trailing newline.
+func cachedStatus(cr *apisv1alpha1.Script) (string, string, error) {
+	a := cr.Status.AtProvider
+	stdout := a.Stdout
+	if len(a.StdoutLines) > 0 {
+		stdout = strings.Join(a.StdoutLines, "\n") + "\n"
+	}
+	if a.StatusCode != 0 {
+		return stdout, a.Stderr, sshv1alpha1.ExitCode(a.StatusCode)
+	}
+	return stdout, a.Stderr, nil
+}
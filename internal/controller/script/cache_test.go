@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+func withStatusCache(ttl, until time.Duration, generation int64, stdout string, code int) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.StatusCacheTTL = &metav1.Duration{Duration: ttl}
+		a := &cr.Status.AtProvider
+		if until != 0 {
+			t := metav1.NewTime(time.Now().Add(until))
+			a.StatusCachedUntil = &t
+		}
+		a.CheckedGeneration, a.Stdout, a.StatusCode = generation, stdout, code
+	}
+}
+
+func TestObserveStatusCache(t *testing.T) {
+	type want struct {
+		o      managed.ExternalObservation
+		err    bool
+		runs   int
+		cached bool
+		stdout string
+	}
+	cases := map[string]struct {
+		reason string
+		cr     *apisv1alpha1.Script
+		x      *sshfake.Executor
+		want   want
+	}{
+		"Reused": {
+			reason: "The last result of the statusCheckScript should be reused within the TTL, without executing it.",
+			cr:     script(withStatusCheck("systemctl is-active nginx"), withStatusCache(time.Minute, time.Minute, 0, "active\n", 0)),
+			x:      outputExecutor("inactive\n", 3),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, cached: true, stdout: "active\n"},
+		},
+		"ReusedLines": {
+			reason: "A stdout recorded as lines should be reused as the stdout of the statusCheckScript.",
+			cr: script(withStatusCheck("systemctl is-active nginx"), withStatusCache(time.Minute, time.Minute, 0, "", 0), func(cr *apisv1alpha1.Script) {
+				cr.Spec.ForProvider.StdoutFormat = apisv1alpha1.StdoutFormatLines
+				cr.Spec.ForProvider.ExpectedOutput = &apisv1alpha1.ExpectedOutput{Exact: "active"}
+				cr.Status.AtProvider.StdoutLines = []string{"active"}
+			}),
+			x:    outputExecutor("inactive\n", 3),
+			want: want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, cached: true},
+		},
+		"ReusedFailure": {
+			reason: "A cached failure should fail the observation again without executing the statusCheckScript.",
+			cr:     script(withStatusCheck("systemctl is-active nginx"), withStatusCache(time.Minute, time.Minute, 0, "broken\n", 1)),
+			x:      outputExecutor("active\n", 0),
+			want:   want{err: true, cached: true, stdout: "broken\n"},
+		},
+		"Expired": {
+			reason: "The statusCheckScript should be executed again once the TTL elapsed, and its result cached.",
+			cr:     script(withStatusCheck("systemctl is-active nginx"), withStatusCache(time.Minute, -time.Second, 0, "active\n", 0)),
+			x:      outputExecutor("inactive\n", 3),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, runs: 1, cached: true, stdout: "inactive\n"},
+		},
+		"SpecChanged": {
+			reason: "The cached result should not be reused once the spec changed.",
+			cr:     script(withStatusCheck("systemctl is-active nginx"), withGeneration(2), withStatusCache(time.Minute, time.Minute, 1, "active\n", 0)),
+			x:      outputExecutor("inactive\n", 3),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, runs: 1, cached: true, stdout: "inactive\n"},
+		},
+		"NotExecuted": {
+			reason: "The result of a statusCheckScript that could not be executed should not be cached.",
+			cr:     script(withStatusCheck("systemctl is-active nginx"), withStatusCache(time.Minute, 0, 0, "", 0)),
+			x: &sshfake.Executor{MockRun: func(string) (string, string, error) {
+				return "", "", errors.New("connection reset")
+			}},
+			want: want{err: true, runs: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: tc.x, recorder: event.NewNopRecorder()}
+			o, err := e.Observe(context.Background(), tc.cr)
+			got := want{
+				o:      managed.ExternalObservation{ResourceExists: o.ResourceExists, ResourceUpToDate: o.ResourceUpToDate},
+				err:    err != nil,
+				runs:   len(tc.x.Scripts()),
+				cached: tc.cr.Status.AtProvider.StatusCachedUntil != nil,
+				stdout: tc.cr.Status.AtProvider.Stdout,
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUpdateInvalidatesStatusCache(t *testing.T) {
+	cr := script(withStatusCheck("systemctl is-active nginx"), withUpdate("systemctl restart nginx"), withStatusCache(time.Minute, time.Minute, 0, "inactive\n", 3))
+	e := external{service: executor(t, 0), recorder: event.NewNopRecorder()}
+	if _, err := e.Update(context.Background(), cr); err != nil {
+		t.Fatalf("e.Update(...): %v", err)
+	}
+	if statusCached(cr) {
+		t.Errorf("\nThe result of the statusCheckScript should not be reused once a script changed the resource.\nstatusCached(...): true")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	reasonCleanupSkipped event.Reason = "CleanupSkipped"
+
+	errCheckBeforeCleanup = "cannot check whether the resource exists before the cleanup"
+	errFmtCleanupBackoff  = "cleanup failed, retried in %s"
+
+	// defaultDeleteRetryAttempts is the number of failed attempts of a
+	// deleteRetry that sets none.
+	defaultDeleteRetryAttempts = int32(3)
+
+	msgFmtCleanupSkipped = "Cleanup script skipped%s, the resource no longer exists on the host"
+)
+
+// checksBeforeCleanup reports whether the existence of the resource is
+// checked on the host before its cleanupScript is executed.
+func checksBeforeCleanup(p apisv1alpha1.ScriptParameters) bool {
+	return p.CheckBeforeCleanup && (p.ExistsScript != "" || p.StatusCheckScript != "")
+}
+
+// gone reports whether the resource no longer exists on the host: the
+// existsScript fails, or the statusCheckScript exits with the missing exit
+// code if no existsScript is set. Any other result of the statusCheckScript,
+// including a failure, lets the cleanupScript run. Errors that do not carry
+// an exit code are returned, since they say nothing about the resource.
+func gone(ctx context.Context, svc sshv1alpha1.RemoteExecutor, p apisv1alpha1.ScriptParameters) (bool, error) {
+	sc := p.ExistsScript
+	if sc == "" {
+		sc = p.StatusCheckScript
+	}
+	_, _, err := sshv1alpha1.ExecuteScript(ctx, svc, sc, p.Variables, SudoEnabled(p), ExecOptions(p)...)
+	switch {
+	case err == nil:
+		return false, nil
+	case !sshv1alpha1.IsExitError(err):
+		return false, errors.Wrap(err, errCheckBeforeCleanup)
+	case p.ExistsScript != "":
+		return true, nil
+	}
+	return missing(p, sshv1alpha1.ExitStatus(err)), nil
+}
+
+// orphaned reports whether the deleteRetry of the Script orphans the resource
+// because its cleanup failed too many times.
+func orphaned(cr *apisv1alpha1.Script) bool {
+	r := cr.Spec.ForProvider.DeleteRetry
+	if r == nil || !r.ThenOrphan {
+		return false
+	}
+	attempts := defaultDeleteRetryAttempts
+	if r.Attempts != nil {
+		attempts = *r.Attempts
+	}
+	return cr.Status.AtProvider.CleanupAttempts >= attempts
+}
+
+// cleanupBackoff returns the time left before the cleanup of the Script is
+// attempted again after a failed attempt, as set by its deleteRetry.
+func cleanupBackoff(cr *apisv1alpha1.Script) time.Duration {
+	r, last := cr.Spec.ForProvider.DeleteRetry, cr.Status.AtProvider.LastCleanupAttemptTime
+	if r == nil || r.Backoff == nil || last == nil {
+		return 0
+	}
+	return time.Until(last.Add(r.Backoff.Duration))
+}
+
+// cleanup executes the cleanupScript of the Script, unless the resource was
+// checked to no longer exist on the host.
+func (c *external) cleanup(ctx context.Context, cr *apisv1alpha1.Script) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	if p := c.params(cr); checksBeforeCleanup(p) {
+		g, err := gone(ctx, c.service, p)
+		if err != nil {
+			return err
+		}
+		if g {
+			logger.Info(fmt.Sprintf("[%s] Cleanup skipped. The resource no longer exists on the host.", cr.GetName()))
+			c.recorder.Event(cr, event.Normal(reasonCleanupSkipped, fmt.Sprintf(msgFmtCleanupSkipped, "")))
+			return nil
+		}
+	}
+	_, _, err := c.run(ctx, cr, apisv1alpha1.OperationCleanup, cr.Spec.ForProvider.CleanupScript)
+	return err
+}
+
+// skipCleanup checks whether the resource still exists on the host before
+// its cleanup, and records the host as ready if it does not. Hosts on which
+// it cannot be checked are recorded as not ready, and skipped as well.
+func (e *fanOutExternal) skipCleanup(ctx context.Context, cr *apisv1alpha1.Script, name string) bool {
+	p := e.params(cr)
+	if !checksBeforeCleanup(p) {
+		return false
+	}
+	g, err := gone(ctx, e.clients[name], p)
+	switch {
+	case err != nil:
+		e.set(apisv1alpha1.HostStatus{Name: name, StatusCode: exitCodeFailed, Message: err.Error()})
+		return true
+	case g:
+		e.set(apisv1alpha1.HostStatus{Name: name, Ready: true})
+		e.recorder.Event(cr, event.Normal(reasonCleanupSkipped, fmt.Sprintf(msgFmtCleanupSkipped, " on "+name)))
+		return true
+	}
+	return false
+}
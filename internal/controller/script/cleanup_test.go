@@ -0,0 +1,275 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+const (
+	cleanupScript = "rm -f /etc/nginx/conf.d/web.conf"
+	existsScript  = "test -f /etc/nginx/conf.d/web.conf"
+)
+
+func withCheckBeforeCleanup() scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.CheckBeforeCleanup = true }
+}
+
+func withExists(script string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.ExistsScript = script }
+}
+
+// checkExecutor returns a fake executor whose check scripts return the
+// supplied error, and whose other scripts succeed.
+func checkExecutor(err error) *sshfake.Executor {
+	return &sshfake.Executor{MockRun: func(script string) (string, string, error) {
+		if script == existsScript {
+			return "", "", err
+		}
+		return "", "", nil
+	}}
+}
+
+func TestDeleteCheckBeforeCleanup(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		cr      *apisv1alpha1.Script
+		err     error
+		want    []string
+		wantErr error
+		reasons []event.Reason
+	}{
+		"ExistsScriptFailed": {
+			reason:  "The cleanupScript should be skipped if the existsScript reports that the resource is gone.",
+			cr:      script(withExists(existsScript), withCheckBeforeCleanup()),
+			err:     sshfake.ExitError(1),
+			want:    []string{existsScript},
+			reasons: []event.Reason{reasonCleanupSkipped},
+		},
+		"Exists": {
+			reason:  "The cleanupScript should run if the resource still exists.",
+			cr:      script(withExists(existsScript), withCheckBeforeCleanup()),
+			want:    []string{existsScript, cleanupScript},
+			reasons: []event.Reason{reasonScriptSucceeded},
+		},
+		"StatusCheckMissing": {
+			reason:  "The cleanupScript should be skipped if the statusCheckScript exits with the missing exit code.",
+			cr:      script(withStatusCheck(existsScript), withCheckBeforeCleanup()),
+			err:     sshfake.ExitError(apisv1alpha1.DefaultExitCodeMissing),
+			want:    []string{existsScript},
+			reasons: []event.Reason{reasonCleanupSkipped},
+		},
+		"StatusCheckFailed": {
+			reason:  "The cleanupScript should run if the statusCheckScript fails with another exit code.",
+			cr:      script(withStatusCheck(existsScript), withCheckBeforeCleanup()),
+			err:     sshfake.ExitError(apisv1alpha1.DefaultExitCodeFailed),
+			want:    []string{existsScript, cleanupScript},
+			reasons: []event.Reason{reasonScriptSucceeded},
+		},
+		"NotRequested": {
+			reason:  "The cleanupScript should run without a check unless the Script asks for it.",
+			cr:      script(withExists(existsScript)),
+			want:    []string{cleanupScript},
+			reasons: []event.Reason{reasonScriptSucceeded},
+		},
+		"CheckError": {
+			reason:  "Errors that say nothing about the resource should fail the deletion.",
+			cr:      script(withExists(existsScript), withCheckBeforeCleanup()),
+			err:     errors.New("connection lost"),
+			want:    []string{existsScript},
+			wantErr: errors.Wrap(errors.New("connection lost"), errCheckBeforeCleanup),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			withDeleted()(tc.cr)
+			withCleanup(cleanupScript, apisv1alpha1.CleanupPolicyRun)(tc.cr)
+			tc.cr.SetDeletionPolicy(xpv1.DeletionDelete)
+			x := checkExecutor(tc.err)
+			r := &reasons{}
+			e := external{service: x, recorder: r}
+			err := e.Delete(context.Background(), tc.cr)
+			if diff := cmp.Diff(tc.wantErr, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want, x.Scripts()); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want scripts, +got scripts:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.reasons, []event.Reason(*r), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want event reasons, +got event reasons:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveCheckBeforeCleanup(t *testing.T) {
+	x := checkExecutor(sshfake.ExitError(apisv1alpha1.DefaultExitCodeFailed))
+	cr := script(withDeleted(), withCleanup(cleanupScript, apisv1alpha1.CleanupPolicyRun),
+		withStatusCheck(existsScript), withCheckBeforeCleanup())
+	e := external{service: x, recorder: event.NewNopRecorder()}
+	if _, err := e.Observe(context.Background(), cr); err != nil {
+		t.Errorf("e.Observe(...): a failing statusCheckScript should not block the cleanup: %v", err)
+	}
+	if len(x.Scripts()) > 0 {
+		t.Errorf("e.Observe(...): the existence of a deleted Script should be checked by Delete, ran %q", x.Scripts())
+	}
+}
+
+func TestFanOutCheckBeforeCleanup(t *testing.T) {
+	clients := map[string]*sshfake.Executor{
+		"Host/web-1": checkExecutor(sshfake.ExitError(1)),
+		"Host/web-2": checkExecutor(nil),
+	}
+	e := &fanOutExternal{
+		recorder: event.NewNopRecorder(),
+		hosts:    []string{"Host/web-1", "Host/web-2"},
+		clients:  map[string]sshv1alpha1.RemoteExecutor{},
+		results:  map[string]apisv1alpha1.HostStatus{},
+		observed: map[string]checkResult{},
+	}
+	for name, x := range clients {
+		e.clients[name] = x
+	}
+	cr := script(withDeleted(), withHosts("web-1", "web-2"), withCleanup(cleanupScript, apisv1alpha1.CleanupPolicyRun),
+		withExists(existsScript), withCheckBeforeCleanup())
+	cr.SetDeletionPolicy(xpv1.DeletionDelete)
+
+	if err := e.Delete(context.Background(), cr); err != nil {
+		t.Fatalf("e.Delete(...): %v", err)
+	}
+	want := map[string][]string{
+		"Host/web-1": {existsScript},
+		"Host/web-2": {existsScript, cleanupScript},
+	}
+	for name, x := range clients {
+		if diff := cmp.Diff(want[name], x.Scripts()); diff != "" {
+			t.Errorf("e.Delete(...): the cleanupScript should only run on the hosts the resource exists on: %s: -want scripts, +got scripts:\n%s\n", name, diff)
+		}
+	}
+}
+
+func withDeleteRetry(r apisv1alpha1.DeleteRetryPolicy) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.DeleteRetry = &r }
+}
+
+func TestDeleteRetry(t *testing.T) {
+	attempts := int32(2)
+	cases := map[string]struct {
+		reason   string
+		retry    apisv1alpha1.DeleteRetryPolicy
+		wantErrs []bool
+		orphaned bool
+	}{
+		"ThenOrphan": {
+			reason:   "The deletion should proceed and be reported as orphaned once the attempts are exhausted.",
+			retry:    apisv1alpha1.DeleteRetryPolicy{Attempts: &attempts, ThenOrphan: true},
+			wantErrs: []bool{true, false},
+			orphaned: true,
+		},
+		"DefaultAttempts": {
+			reason:   "The deletion should be orphaned after three attempts by default.",
+			retry:    apisv1alpha1.DeleteRetryPolicy{ThenOrphan: true},
+			wantErrs: []bool{true, true, false},
+			orphaned: true,
+		},
+		"Retried": {
+			reason:   "The deletion should remain blocked unless the resource is orphaned.",
+			retry:    apisv1alpha1.DeleteRetryPolicy{Attempts: &attempts},
+			wantErrs: []bool{true, true, true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := script(withDeleted(), withCleanup(cleanupScript, apisv1alpha1.CleanupPolicyRun), withDeleteRetry(tc.retry))
+			cr.SetDeletionPolicy(xpv1.DeletionDelete)
+			x := &sshfake.Executor{MockRun: func(string) (string, string, error) { return "", "", sshfake.ExitError(1) }}
+			e := external{service: x, recorder: event.NewNopRecorder()}
+			for i, wantErr := range tc.wantErrs {
+				if err := e.Delete(context.Background(), cr); (err != nil) != wantErr {
+					t.Errorf("\n%s\ne.Delete(...) attempt %d: %v, want error %t", tc.reason, i+1, err, wantErr)
+				}
+			}
+			if got := runsCleanup(cr); got == tc.orphaned {
+				t.Errorf("\n%s\nrunsCleanup(...): %t, want %t", tc.reason, got, !tc.orphaned)
+			}
+			if c := cr.GetCondition(apisv1alpha1.TypeOrphanedOnDelete); (c.Status == corev1.ConditionTrue) != tc.orphaned {
+				t.Errorf("\n%s\ne.Delete(...): OrphanedOnDelete condition %s, want orphaned %t", tc.reason, c.Status, tc.orphaned)
+			}
+		})
+	}
+}
+
+func TestCleanupBackoff(t *testing.T) {
+	backoff := metav1.Duration{Duration: time.Minute}
+	recent, old := metav1.NewTime(time.Now().Add(-10*time.Second)), metav1.NewTime(time.Now().Add(-2*time.Minute))
+	cases := map[string]struct {
+		reason  string
+		retry   *apisv1alpha1.DeleteRetryPolicy
+		last    *metav1.Time
+		waiting bool
+	}{
+		"Waiting": {
+			reason:  "The cleanup should wait for the backoff after a recent failed attempt.",
+			retry:   &apisv1alpha1.DeleteRetryPolicy{Backoff: &backoff},
+			last:    &recent,
+			waiting: true,
+		},
+		"Elapsed": {
+			reason: "The cleanup should be attempted again once the backoff elapsed.",
+			retry:  &apisv1alpha1.DeleteRetryPolicy{Backoff: &backoff},
+			last:   &old,
+		},
+		"NoFailedAttempt": {
+			reason: "The first cleanup should not wait.",
+			retry:  &apisv1alpha1.DeleteRetryPolicy{Backoff: &backoff},
+		},
+		"NoBackoff": {
+			reason: "The cleanup should not wait without a backoff.",
+			retry:  &apisv1alpha1.DeleteRetryPolicy{},
+			last:   &recent,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := script()
+			cr.Spec.ForProvider.DeleteRetry = tc.retry
+			cr.Status.AtProvider.LastCleanupAttemptTime = tc.last
+			if got := cleanupBackoff(cr) > 0; got != tc.waiting {
+				t.Errorf("\n%s\ncleanupBackoff(...): %s, want waiting %t", tc.reason, cleanupBackoff(cr), tc.waiting)
+			}
+		})
+	}
+}
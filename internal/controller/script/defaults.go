@@ -0,0 +1,173 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+// The defaults below apply to Scripts stored before the defaulting webhook
+// was installed, or when the webhook is disabled.
+
+// withDefaults returns the parameters of a Script with the execution settings
+// it does not set taken from the supplied defaults of its ProviderConfig.
+func withDefaults(p apisv1alpha1.ScriptParameters, d *apisv1alpha1.ScriptDefaults) apisv1alpha1.ScriptParameters {
+	if d == nil {
+		return p
+	}
+	if p.SudoEnabled == nil {
+		p.SudoEnabled = d.SudoEnabled
+	}
+	if p.Interpreter == "" {
+		p.Interpreter = d.Interpreter
+	}
+	if p.RemoteTempDir == "" {
+		p.RemoteTempDir = d.RemoteTempDir
+	}
+	if p.TimeoutSeconds == nil {
+		p.TimeoutSeconds = d.TimeoutSeconds
+	}
+	if p.MaxOutputBytes == nil {
+		p.MaxOutputBytes = d.MaxOutputBytes
+	}
+	if p.RecordSession == nil {
+		p.RecordSession = d.RecordSession
+	}
+	return p
+}
+
+// SudoEnabled reports whether the scripts of a Script are executed with sudo.
+func SudoEnabled(p apisv1alpha1.ScriptParameters) bool {
+	return p.SudoEnabled != nil && *p.SudoEnabled
+}
+
+// ExecOptions returns the options the scripts of a Script are executed with.
+func ExecOptions(p apisv1alpha1.ScriptParameters) []sshv1alpha1.ExecOption {
+	interpreter := p.Interpreter
+	if interpreter == "" {
+		interpreter = apisv1alpha1.DefaultInterpreter
+	}
+	timeout := int64(apisv1alpha1.DefaultTimeoutSeconds)
+	if p.TimeoutSeconds != nil {
+		timeout = *p.TimeoutSeconds
+	}
+	o := []sshv1alpha1.ExecOption{
+		sshv1alpha1.WithInterpreter(interpreter),
+		sshv1alpha1.WithTimeout(time.Duration(timeout) * time.Second),
+		sshv1alpha1.WithTempDir(p.RemoteTempDir),
+		sshv1alpha1.WithTemplating(p.Templating),
+		sshv1alpha1.WithContainer(p.Container),
+		sshv1alpha1.WithNamespaces(p.Namespaces),
+		sshv1alpha1.WithChroot(p.Chroot),
+		sshv1alpha1.WithLoginShell(p.LoginShell),
+		sshv1alpha1.WithUmask(p.Umask),
+		sshv1alpha1.WithLocale(p.Locale),
+		sshv1alpha1.WithTimezone(p.Timezone),
+		sshv1alpha1.WithPriority(p.Priority),
+		sshv1alpha1.WithLimits(p.Limits),
+	}
+	if p.StrictMode {
+		preamble := p.StrictModePreamble
+		if preamble == "" {
+			preamble = apisv1alpha1.DefaultStrictModePreamble
+		}
+		o = append(o, sshv1alpha1.WithPreamble(preamble))
+	}
+	return o
+}
+
+// initScriptHash returns the hash of the rendered initScript of a Script.
+func initScriptHash(p apisv1alpha1.ScriptParameters) string {
+	return sshv1alpha1.HashScript(p.InitScript, p.Variables, p.Templating)
+}
+
+// limitOutput returns the end of the supplied output, short enough to be
+// recorded in the status of the Script. The supplied default applies to
+// Scripts without maxOutputBytes, unless it is zero. The output is cut at the
+// start of a character, so that a UTF-8 output stays valid.
+func limitOutput(p apisv1alpha1.ScriptParameters, def int64, out string) string {
+	limit := int64(apisv1alpha1.DefaultMaxOutputBytes)
+	if def > 0 {
+		limit = def
+	}
+	if p.MaxOutputBytes != nil {
+		limit = *p.MaxOutputBytes
+	}
+	if int64(len(out)) <= limit {
+		return out
+	}
+	start := int64(len(out)) - limit
+	for start < int64(len(out)) && !utf8.RuneStart(out[start]) {
+		start++
+	}
+	return out[start:]
+}
+
+// recordStdout records the end of the supplied stdout in the status of the
+// Script, as a string or as its lines with the Lines stdoutFormat.
+func (c *external) recordStdout(cr *apisv1alpha1.Script, stdout string) {
+	a := &cr.Status.AtProvider
+	p := c.params(cr)
+	out := limitOutput(p, c.maxOutputBytes, stdout)
+	if p.StdoutFormat != apisv1alpha1.StdoutFormatLines {
+		a.Stdout, a.StdoutLines = out, nil
+		return
+	}
+	a.Stdout, a.StdoutLines = "", stdoutLines(out)
+}
+
+// stdoutLines splits the supplied stdout into its last lines, without their
+// newlines.
+func stdoutLines(out string) []string {
+	out = strings.TrimSuffix(out, "\n")
+	if out == "" {
+		return nil
+	}
+	lines := strings.Split(out, "\n")
+	if len(lines) > apisv1alpha1.MaxStdoutLines {
+		lines = lines[len(lines)-apisv1alpha1.MaxStdoutLines:]
+	}
+	return lines
+}
+
+// missing reports whether the exit code of the statusCheckScript reports a
+// resource that does not exist.
+func missing(p apisv1alpha1.ScriptParameters, code int) bool {
+	if p.ExitCodes != nil && p.ExitCodes.Missing != nil {
+		return code == *p.ExitCodes.Missing
+	}
+	return code == apisv1alpha1.DefaultExitCodeMissing
+}
+
+// failed reports whether the exit code of the statusCheckScript reports a
+// failure that requires user intervention.
+func failed(p apisv1alpha1.ScriptParameters, code int) bool {
+	if p.ExitCodes == nil || p.ExitCodes.Failed == nil {
+		return code == apisv1alpha1.DefaultExitCodeFailed
+	}
+	for _, c := range p.ExitCodes.Failed {
+		if code == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+const (
+	errGetDependency = "cannot get dependency"
+)
+
+// pendingDependencies returns the names of the Scripts the supplied Script
+// depends on that are not Ready yet. A deleted Script never waits for its
+// dependencies.
+func pendingDependencies(ctx context.Context, kube client.Client, cr *apisv1alpha1.Script) ([]string, error) {
+	if meta.WasDeleted(cr) {
+		return nil, nil
+	}
+	var pending []string
+	for _, ref := range cr.Spec.ForProvider.DependsOn {
+		dep := &apisv1alpha1.Script{}
+		err := kube.Get(ctx, types.NamespacedName{Name: ref.Name}, dep)
+		if resource.IgnoreNotFound(err) != nil {
+			return nil, errors.Wrap(err, errGetDependency)
+		}
+		if err != nil || dep.GetCondition(xpv1.TypeReady).Status != corev1.ConditionTrue {
+			pending = append(pending, ref.Name)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,508 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+
+	"github.com/crossplane/provider-ssh/apis"
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+	"github.com/crossplane/provider-ssh/internal/sshtest"
+)
+
+// e2eReconciles is how many times a Script is reconciled by the end-to-end
+// tests, enough for it to be created and observed again.
+const e2eReconciles = 4
+
+// e2eScript returns a Script of the ProviderConfig of the end-to-end tests
+// whose scripts may reference the directory of the server as {{DIR}}.
+func e2eScript(dir string, m ...scriptModifier) *apisv1alpha1.Script {
+	cr := &apisv1alpha1.Script{ObjectMeta: metav1.ObjectMeta{Name: "e2e", UID: "e2e-uid"}}
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "e2e"})
+	cr.SetManagementPolicies(xpv1.ManagementPolicies{xpv1.ManagementActionAll})
+	cr.SetDeletionPolicy(xpv1.DeletionDelete)
+	cr.Spec.ForProvider.Variables = []apisv1alpha1.Variable{{Name: "DIR", Value: dir}}
+	for _, f := range m {
+		f(cr)
+	}
+	return cr
+}
+
+func withUpdate(script string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.UpdateScript = script }
+}
+
+func withSudo() scriptModifier {
+	sudo := true
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.SudoEnabled = &sudo }
+}
+
+func withTimeoutSeconds(s int64) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.TimeoutSeconds = &s }
+}
+
+func withEnvsubst(vars ...apisv1alpha1.Variable) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.Templating = apisv1alpha1.TemplatingEnvsubst
+		cr.Spec.ForProvider.Variables = append(cr.Spec.ForProvider.Variables, vars...)
+	}
+}
+
+func withStrictMode(preamble string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.StrictMode = true
+		cr.Spec.ForProvider.StrictModePreamble = preamble
+	}
+}
+
+func withUmask(umask string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.Umask = umask }
+}
+
+func withLocale(locale, tz string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.Locale = locale
+		cr.Spec.ForProvider.Timezone = tz
+	}
+}
+
+func withPriority(p *apisv1alpha1.Priority) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.Priority = p }
+}
+
+func withLimits(l *apisv1alpha1.Limits) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.Limits = l }
+}
+
+func withContainer(name string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.Container = &apisv1alpha1.Container{Name: name}
+	}
+}
+
+func withNamespaces(ns *apisv1alpha1.Namespaces) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.Namespaces = ns }
+}
+
+func withChroot(dir string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.Chroot = dir }
+}
+
+func withLoginShell() scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.LoginShell = true }
+}
+
+// e2eReconciler returns a reconciler of Scripts connecting to the supplied
+// server, and the client of the fake API server holding the supplied Script.
+func e2eReconciler(t *testing.T, srv *sshtest.Server, cr *apisv1alpha1.Script) (reconcile.Reconciler, client.Client) {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "crossplane-system", Name: "e2e"},
+		Data:       map[string][]byte{"credentials": srv.Credentials()},
+	}
+	pc := &apisv1alpha1.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: "e2e"}}
+	pc.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+	pc.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{
+		SecretReference: xpv1.SecretReference{Namespace: secret.Namespace, Name: secret.Name},
+		Key:             "credentials",
+	}
+
+	kube := clientfake.NewClientBuilder().
+		WithScheme(s).
+		WithStatusSubresource(&apisv1alpha1.Script{}).
+		WithObjects(secret, pc, cr).
+		Build()
+	o := common.Options{
+		Options:     controller.Options{Logger: logging.NewNopLogger(), PollInterval: time.Minute},
+		DialTimeout: 10 * time.Second,
+	}
+	return newReconciler(&fake.Manager{Client: kube, Scheme: s}, o, event.NewNopRecorder()), kube
+}
+
+// reconcileScript reconciles the named Script the supplied number of times,
+// returning it as it is afterwards, or nil if it was deleted.
+func reconcileScript(t *testing.T, r reconcile.Reconciler, kube client.Client, times int) *apisv1alpha1.Script {
+	t.Helper()
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "e2e"}}
+	for i := 0; i < times; i++ {
+		if _, err := r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("Reconcile(...): %v", err)
+		}
+	}
+	cr := &apisv1alpha1.Script{}
+	if err := kube.Get(context.Background(), req.NamespacedName, cr); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		t.Fatalf("Get(...): %v", err)
+	}
+	return cr
+}
+
+func TestEndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end tests run scripts on the local host")
+	}
+
+	type want struct {
+		ready   corev1.ConditionStatus
+		synced  corev1.ConditionStatus
+		message string
+		code    int
+		stdout  string
+		stderr  string
+		content string
+		sudo    bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		script func(dir string) *apisv1alpha1.Script
+		setup  func(t *testing.T, dir string)
+		want   want
+	}{
+		"Created": {
+			reason: "A resource reported missing by the statusCheckScript should be created by the initScript, then be ready.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`test -f {{DIR}}/file || exit 100; cat {{DIR}}/file`),
+					withInit(`echo v1 > {{DIR}}/file`))
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, stdout: "v1\n", content: "v1\n"},
+		},
+		"UpToDate": {
+			reason: "A resource reported ready by the statusCheckScript should be left untouched.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`test -f {{DIR}}/file || exit 100`),
+					withInit(`echo init > {{DIR}}/file`),
+					withUpdate(`echo update > {{DIR}}/file`))
+			},
+			setup: func(t *testing.T, dir string) {
+				if err := os.WriteFile(filepath.Join(dir, "file"), []byte("v0\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, content: "v0\n"},
+		},
+		"Drifted": {
+			reason: "Drift reported by the statusCheckScript should be repaired by the updateScript.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`test -f {{DIR}}/file || exit 100; grep -q v2 {{DIR}}/file || exit 3`),
+					withInit(`echo v2 > {{DIR}}/file`),
+					withUpdate(`echo v2 > {{DIR}}/file`))
+			},
+			setup: func(t *testing.T, dir string) {
+				if err := os.WriteFile(filepath.Join(dir, "file"), []byte("v1\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, content: "v2\n"},
+		},
+		"Failed": {
+			reason: "A failure reported by the statusCheckScript should make the resource unsynced, with its exit code and output.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`echo broken >&2; exit 1`),
+					withInit(`echo v1 > {{DIR}}/file`))
+			},
+			want: want{synced: corev1.ConditionFalse, code: 1, message: "exit code 1", stderr: "broken\n"},
+		},
+		"Sudo": {
+			reason: "The scripts of a resource enabling sudo should run through sudo.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`test -f {{DIR}}/file || exit 100`),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withSudo())
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, content: "v1\n", sudo: true},
+		},
+		"Envsubst": {
+			reason: "The $VAR and ${VAR} references to variables should be substituted with envsubst templating, other references left to the shell.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`test -f "$DIR/file" || exit 100`),
+					withInit(`X=shell; echo "$NAME $X" > ${DIR}/file`),
+					withEnvsubst(apisv1alpha1.Variable{Name: "NAME", Value: "world"}))
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, content: "world shell\n"},
+		},
+		"StrictMode": {
+			reason: "A command failing in the middle of a script in strict mode should fail the script.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`false | true; false; echo unreachable`),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withStrictMode(""))
+			},
+			want: want{synced: corev1.ConditionFalse, code: 1, message: "exit code 1"},
+		},
+		"StrictModeShebang": {
+			reason: "The preamble should follow the #! line of a script in strict mode, and may be replaced.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck("#!/bin/sh\ntest -f {{DIR}}/file || exit 100"),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withStrictMode("echo preamble"))
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, stdout: "preamble\n", content: "v1\n"},
+		},
+		"Umask": {
+			reason: "The files created by the scripts should get the permissions of the umask, including through sudo.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`test -f {{DIR}}/file || exit 100; stat -c %a {{DIR}}/file`),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withUmask("077"),
+					withSudo())
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, stdout: "600\n", content: "v1\n", sudo: true},
+		},
+		"LocaleTimezone": {
+			reason: "The scripts should be executed with the locale and timezone of the Script, including through sudo.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`test -f {{DIR}}/file || exit 100; echo "$LANG $LC_ALL"; date -d @0 +%H`),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withLocale("C.UTF-8", "UTC-2"),
+					withSudo())
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, stdout: "C.UTF-8 C.UTF-8\n02\n", content: "v1\n", sudo: true},
+		},
+		"Priority": {
+			reason: "The scripts should be executed with the CPU and I/O priority of the Script.",
+			script: func(dir string) *apisv1alpha1.Script {
+				nice, level := int32(5), int32(7)
+				return e2eScript(dir,
+					withStatusCheck(`test -f {{DIR}}/file || exit 100; nice; ionice`),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withPriority(&apisv1alpha1.Priority{Nice: &nice, IONice: &apisv1alpha1.IONice{Class: apisv1alpha1.IONiceClassBestEffort, Level: &level}}),
+					withUmask("022"))
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, stdout: "5\nbest-effort: prio 7\n", content: "v1\n"},
+		},
+		"Limits": {
+			reason: "The scripts should be executed with the ulimits of the Script, processes exceeding them being killed.",
+			script: func(dir string) *apisv1alpha1.Script {
+				cpu, mem, size := int64(10), int64(1<<20), int64(1)
+				return e2eScript(dir,
+					withStatusCheck(`test -f {{DIR}}/file || exit 100; ulimit -t; ulimit -v; sh -c 'head -c 4096 /dev/zero > {{DIR}}/big' 2>/dev/null; echo $?`),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withLimits(&apisv1alpha1.Limits{CPUSeconds: &cpu, MaxMemoryKB: &mem, MaxFileSizeKB: &size}))
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, stdout: "10\n1048576\n153\n", content: "v1\n"},
+		},
+		"LoginShell": {
+			reason: "The scripts of a Script enabling the login shell should get the environment of the profile, and the umask of the Script.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`test -f {{DIR}}/file || exit 100; echo "$GREETING"; umask`),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withLoginShell(),
+					withUmask("077"))
+			},
+			setup: func(t *testing.T, dir string) {
+				profile := "export GREETING=hello\numask 022\n"
+				if err := os.WriteFile(filepath.Join(dir, ".bash_profile"), []byte(profile), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, stdout: "hello\n0077\n", content: "v1\n"},
+		},
+		"Container": {
+			reason: "The scripts of a Script executed in a container should be piped into the exec command of the engine, with the interpreter of their #! line if any.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck("#!/bin/sh -eu\ntest -f {{DIR}}/file || exit 100; echo \"$CONTAINER\"; umask"),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withContainer("gateway"),
+					withUmask("077"),
+					withSudo())
+			},
+			setup: func(t *testing.T, dir string) {
+				// docker exec -i NAME COMMAND... runs the command on the host.
+				docker := "#!/bin/sh\nCONTAINER=\"$3\" && export CONTAINER && shift 3 && exec \"$@\"\n"
+				if err := os.WriteFile(filepath.Join(dir, "bin", "docker"), []byte(docker), 0o755); err != nil { // nolint: gosec
+					t.Fatal(err)
+				}
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, stdout: "gateway\n0077\n", content: "v1\n", sudo: true},
+		},
+		"NamespacesChroot": {
+			reason: "The scripts of a Script executed in namespaces and a chroot should be piped into nsenter then chroot.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`test -f {{DIR}}/file || exit 100; echo "$NSENTER"; echo "$ROOT"`),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withNamespaces(&apisv1alpha1.Namespaces{TargetPID: 1, Types: []apisv1alpha1.NamespaceType{apisv1alpha1.NamespaceTypeMount, apisv1alpha1.NamespaceTypeNetwork}}),
+					withChroot("/sysroot"),
+					withSudo())
+			},
+			setup: func(t *testing.T, dir string) {
+				// nsenter FLAGS... -- COMMAND... and chroot DIR COMMAND... run
+				// the command on the host.
+				tools := map[string]string{
+					"nsenter": "#!/bin/sh\nNSENTER=\nwhile [ \"$1\" != -- ]; do NSENTER=\"${NSENTER:+$NSENTER }$1\"; shift; done\nshift\nexport NSENTER\nexec \"$@\"\n",
+					"chroot":  "#!/bin/sh\nROOT=\"$1\" && export ROOT && shift && exec \"$@\"\n",
+				}
+				for name, content := range tools {
+					if err := os.WriteFile(filepath.Join(dir, "bin", name), []byte(content), 0o755); err != nil { // nolint: gosec
+						t.Fatal(err)
+					}
+				}
+			},
+			want: want{ready: corev1.ConditionTrue, synced: corev1.ConditionTrue, stdout: "-t 1 -m -n\n/sysroot\n", content: "v1\n", sudo: true},
+		},
+		"TimedOut": {
+			reason: "A statusCheckScript running longer than its timeout should be killed and reported.",
+			script: func(dir string) *apisv1alpha1.Script {
+				return e2eScript(dir,
+					withStatusCheck(`sleep 30`),
+					withInit(`echo v1 > {{DIR}}/file`),
+					withTimeoutSeconds(1))
+			},
+			want: want{synced: corev1.ConditionFalse, code: 1, message: "timed out"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := sshtest.NewServer(t)
+			if tc.setup != nil {
+				tc.setup(t, srv.Dir)
+			}
+			r, kube := e2eReconciler(t, srv, tc.script(srv.Dir))
+			got := reconcileScript(t, r, kube, e2eReconciles)
+			if got == nil {
+				t.Fatalf("\n%s\nThe Script was deleted", tc.reason)
+			}
+
+			if tc.want.ready != "" {
+				if diff := cmp.Diff(tc.want.ready, got.GetCondition(xpv1.TypeReady).Status); diff != "" {
+					t.Errorf("\n%s\nReady: -want, +got:\n%s\n", tc.reason, diff)
+				}
+			}
+			synced := got.GetCondition(xpv1.TypeSynced)
+			if diff := cmp.Diff(tc.want.synced, synced.Status); diff != "" {
+				t.Errorf("\n%s\nSynced: -want, +got:\n%s\n%s\n", tc.reason, diff, synced.Message)
+			}
+			if !strings.Contains(synced.Message, tc.want.message) {
+				t.Errorf("\n%s\nSynced message %q does not contain %q\n", tc.reason, synced.Message, tc.want.message)
+			}
+			if diff := cmp.Diff(tc.want.code, got.Status.AtProvider.StatusCode); diff != "" {
+				t.Errorf("\n%s\nStatusCode: -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.stdout, got.Status.AtProvider.Stdout); diff != "" {
+				t.Errorf("\n%s\nStdout: -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.stderr, got.Status.AtProvider.Stderr); diff != "" {
+				t.Errorf("\n%s\nStderr: -want, +got:\n%s\n", tc.reason, diff)
+			}
+
+			content, _ := os.ReadFile(filepath.Join(srv.Dir, "file"))
+			if diff := cmp.Diff(tc.want.content, string(content)); diff != "" {
+				t.Errorf("\n%s\nFile content: -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.sudo, len(srv.SudoCalls()) > 0); diff != "" {
+				t.Errorf("\n%s\nRan through sudo: -want, +got:\n%s\n%v\n", tc.reason, diff, srv.SudoCalls())
+			}
+			checkTemporaryFiles(t, srv)
+		})
+	}
+}
+
+func TestEndToEndCleanup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end tests run scripts on the local host")
+	}
+
+	srv := sshtest.NewServer(t)
+	if err := os.WriteFile(filepath.Join(srv.Dir, "file"), []byte("v1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cr := e2eScript(srv.Dir,
+		withStatusCheck(`test -f {{DIR}}/file || exit 100`),
+		withInit(`echo v1 > {{DIR}}/file`),
+		withCleanup(`rm {{DIR}}/file`, apisv1alpha1.CleanupPolicyRun))
+	r, kube := e2eReconciler(t, srv, cr)
+
+	got := reconcileScript(t, r, kube, e2eReconciles)
+	if err := kube.Delete(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	if got := reconcileScript(t, r, kube, e2eReconciles); got != nil {
+		t.Errorf("The Script was not deleted: %v", got.Status.Conditions)
+	}
+	if _, err := os.Stat(filepath.Join(srv.Dir, "file")); !os.IsNotExist(err) {
+		t.Errorf("The cleanupScript did not remove the file: %v", err)
+	}
+	checkTemporaryFiles(t, srv)
+
+}
+
+// checkTemporaryFiles fails the test unless the temporary file of every
+// script run on the server was removed afterwards.
+func checkTemporaryFiles(t *testing.T, srv *sshtest.Server) {
+	t.Helper()
+	scripts, removals := 0, 0
+	for _, cmd := range srv.Commands() {
+		if strings.HasPrefix(cmd, "rm -f '/tmp/") {
+			removals++
+			continue
+		}
+		if cmd == factsScript {
+			// The facts of the host are gathered without a temporary file.
+			continue
+		}
+		scripts++
+	}
+	if scripts == 0 || scripts != removals {
+		t.Errorf("Ran %d scripts but removed %d temporary files:\n%s", scripts, removals, strings.Join(srv.Commands(), "\n"))
+	}
+}
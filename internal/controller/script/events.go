@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/redact"
+)
+
+const (
+	reasonScriptSucceeded event.Reason = "ScriptSucceeded"
+	reasonScriptFailed    event.Reason = "ScriptFailed"
+
+	// maxEventStderrLines is the number of lines at the end of the stderr
+	// of a failed script attached to its Event.
+	maxEventStderrLines = 10
+)
+
+// An execution is the result of a script executed on a host.
+type execution struct {
+	operation apisv1alpha1.Operation
+	// host the script was executed on: its name for Scripts targeting
+	// multiple hosts, its alias for Scripts targeting a single host, empty
+	// if it has none.
+	host     string
+	duration time.Duration
+	stderr   string
+	err      error
+}
+
+// executionEvent returns the Event reporting the supplied execution, with
+// the end of its redacted stderr if it failed.
+func executionEvent(x execution) event.Event {
+	on := ""
+	if x.host != "" {
+		on = " on " + x.host
+	}
+	d := x.duration.Round(time.Millisecond)
+	if x.err == nil {
+		return event.Normal(reasonScriptSucceeded, fmt.Sprintf("%s script succeeded%s in %s, exit code 0", x.operation, on, d))
+	}
+	msg := fmt.Sprintf("%s script failed%s in %s, exit code %d", x.operation, on, d, sshv1alpha1.ExitStatus(x.err))
+	if lines := tailLines(redact.String(x.stderr), maxEventStderrLines); lines != "" {
+		msg += ":\n" + lines
+	}
+	return event.Warning(reasonScriptFailed, errors.New(msg))
+}
+
+// tailLines returns the last n lines of the supplied output, short enough to
+// be attached to an Event.
+func tailLines(out string, n int) string {
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return tail(strings.Join(lines, "\n"))
+}
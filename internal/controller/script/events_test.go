@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/redact"
+)
+
+func TestExecutionEvent(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	stderr := strings.Join(lines, "\n") + "\n"
+	redact.Add("hunter2-event")
+
+	cases := map[string]struct {
+		reason string
+		x      execution
+		want   event.Event
+	}{
+		"Succeeded": {
+			reason: "A successful script should be reported through a Normal Event.",
+			x:      execution{operation: apisv1alpha1.OperationInit, duration: 1500 * time.Millisecond},
+			want:   event.Normal(reasonScriptSucceeded, "Init script succeeded in 1.5s, exit code 0"),
+		},
+		"SucceededOnHost": {
+			reason: "The host should be named for Scripts targeting multiple hosts.",
+			x:      execution{operation: apisv1alpha1.OperationUpdate, host: "Host/web-1", duration: time.Second},
+			want:   event.Normal(reasonScriptSucceeded, "Update script succeeded on Host/web-1 in 1s, exit code 0"),
+		},
+		"SucceededOnAlias": {
+			reason: "The alias of the host should be named for Scripts targeting a single host.",
+			x:      execution{operation: apisv1alpha1.OperationInit, host: "paris-edge-01", duration: time.Second},
+			want:   event.Normal(reasonScriptSucceeded, "Init script succeeded on paris-edge-01 in 1s, exit code 0"),
+		},
+		"Failed": {
+			reason: "A failed script should be reported through a Warning Event carrying the end of its stderr.",
+			x:      execution{operation: apisv1alpha1.OperationCleanup, duration: 2 * time.Second, stderr: stderr, err: errors.New("boom")},
+			want:   event.Warning(reasonScriptFailed, errors.New("Cleanup script failed in 2s, exit code 1:\n"+strings.Join(lines[2:], "\n"))),
+		},
+		"FailedRedacted": {
+			reason: "The secrets in the stderr of a failed script should be masked in its Event.",
+			x:      execution{operation: apisv1alpha1.OperationInit, duration: time.Second, stderr: "login failed: hunter2-event\n", err: errors.New("boom")},
+			want:   event.Warning(reasonScriptFailed, errors.New("Init script failed in 1s, exit code 1:\nlogin failed: [REDACTED]")),
+		},
+		"FailedWithoutStderr": {
+			reason: "A failed script without stderr should be reported without output.",
+			x:      execution{operation: apisv1alpha1.OperationRunNow, duration: time.Second, err: errors.New("boom")},
+			want:   event.Warning(reasonScriptFailed, errors.New("RunNow script failed in 1s, exit code 1")),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, executionEvent(tc.x)); diff != "" {
+				t.Errorf("\n%s\nexecutionEvent(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
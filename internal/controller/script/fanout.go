@@ -0,0 +1,471 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+	"github.com/crossplane/provider-ssh/internal/recording"
+	"github.com/crossplane/provider-ssh/internal/redact"
+)
+
+const (
+	errFmtHostsNotReady = "%d of %d hosts ready, %d required"
+	errInitFailed       = "init script failed on the host"
+
+	// exitCodeFailed is the status code of hosts that could not be reached.
+	exitCodeFailed = 1
+)
+
+// fanOut reports whether the Script targets multiple hosts.
+func fanOut(cr *apisv1alpha1.Script) bool {
+	p := cr.Spec.ForProvider
+	return len(p.Hosts) > 0 || p.HostSelector != nil
+}
+
+// resolveHosts returns the sorted keys of the ProviderConfigs and Hosts listed
+// by, or selected through, the Script.
+func resolveHosts(ctx context.Context, kube client.Client, cr *apisv1alpha1.Script) ([]string, error) {
+	p := cr.Spec.ForProvider
+	return common.ResolveHosts(ctx, kube, p.Hosts, p.HostSelector)
+}
+
+// successThreshold returns the number of hosts that must be ready for the
+// Script to be Ready.
+func successThreshold(cr *apisv1alpha1.Script, total int) int {
+	t := cr.Spec.ForProvider.SuccessThreshold
+	if t == nil {
+		return total
+	}
+	n, err := intstr.GetScaledValueFromIntOrPercent(t, total, true)
+	if err != nil {
+		return total
+	}
+	return n
+}
+
+// connectHosts produces an ExternalClient connected to every host targeted by
+// the Script. Hosts that cannot be reached are reported as not ready.
+func (c *connector) connectHosts(ctx context.Context, cr *apisv1alpha1.Script, defaults *apisv1alpha1.ScriptDefaults) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	hosts, err := resolveHosts(ctx, c.kube, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &fanOutExternal{
+		recorder: c.recorder,
+		sink:     c.sink,
+		defaults: defaults,
+		hosts:    hosts,
+		clients:  map[string]sshv1alpha1.RemoteExecutor{},
+		results:  map[string]apisv1alpha1.HostStatus{},
+		observed: map[string]checkResult{},
+	}
+	common.ForEachHost(hosts, func(name string) {
+		svc, err := common.DialHostKey(ctx, c.kube, cr, name, c.newServiceFn, common.Username(cr.Spec.ForProvider.Username))
+		if err != nil {
+			logger.Info(fmt.Sprintf("[%s] Host %s is unreachable: %s", cr.GetName(), name, err))
+			e.set(apisv1alpha1.HostStatus{Name: name, StatusCode: exitCodeFailed, Message: err.Error()})
+			return
+		}
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.clients[name] = sshv1alpha1.NewSSHExecutor(svc)
+	})
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay], %d of %d hosts connected", cr.GetName(), len(e.clients), len(hosts)))
+	return e, nil
+}
+
+// A fanOutExternal executes the scripts of a Script on multiple hosts. It
+// lives for a single reconcile, so the results observed on each host are
+// carried from Observe to Create or Update.
+type fanOutExternal struct {
+	recorder event.Recorder
+	sink     recording.Sink
+	defaults *apisv1alpha1.ScriptDefaults
+	hosts    []string
+	clients  map[string]sshv1alpha1.RemoteExecutor
+
+	mu       sync.Mutex
+	results  map[string]apisv1alpha1.HostStatus
+	observed map[string]checkResult
+	// The earliest check requested through RETRY_AFTER by the hosts.
+	retryTime *metav1.Time
+}
+
+// params returns the parameters of the Script, with the defaults of its
+// ProviderConfig.
+func (e *fanOutExternal) params(cr *apisv1alpha1.Script) apisv1alpha1.ScriptParameters {
+	return withDefaults(cr.Spec.ForProvider, e.defaults)
+}
+
+// set records the status of a host, its message redacted.
+func (e *fanOutExternal) set(st apisv1alpha1.HostStatus) {
+	st.Message = redact.String(st.Message)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.results[st.Name] = st
+}
+
+func (e *fanOutExternal) observe(name string, r checkResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.observed[name] = r
+}
+
+// connected returns the connected hosts whose observed state satisfies fn.
+func (e *fanOutExternal) connected(fn func(r checkResult) bool) []string {
+	hosts := make([]string, 0, len(e.clients))
+	for _, name := range e.hosts {
+		if _, ok := e.clients[name]; ok && fn(e.observed[name]) {
+			hosts = append(hosts, name)
+		}
+	}
+	return hosts
+}
+
+// batch returns the hosts to execute next out of the supplied hosts,
+// according to the rollout strategy of the Script.
+func (e *fanOutExternal) batch(cr *apisv1alpha1.Script, hosts []string) []string {
+	pending := map[string]bool{}
+	for _, name := range e.connected(func(r checkResult) bool { return !r.exists || !r.upToDate }) {
+		pending[name] = true
+	}
+	unavailable := 0
+	for _, name := range e.hosts {
+		if !pending[name] && !e.results[name].Ready {
+			unavailable++
+		}
+	}
+	return common.RolloutBatch(cr.Spec.ForProvider.Rollout, len(e.hosts), hosts, unavailable)
+}
+
+// execute runs the script on the host, records the result and returns the
+// stdout and stderr of the script. Scripts of an operation are reported
+// through an Event, checks are not.
+func (e *fanOutExternal) execute(ctx context.Context, cr *apisv1alpha1.Script, name string, op apisv1alpha1.Operation, sc string) (stdout, stderr string) {
+	p := e.params(cr)
+	if creates(op) {
+		p.Variables = withIdempotencyToken(cr, p.Variables)
+	}
+	st := apisv1alpha1.HostStatus{Name: name, Ready: true}
+	if sc != "" {
+		started := time.Now()
+		var err error
+		opts, store := ExecOptions(p), func(context.Context) {}
+		if op != "" {
+			opts, store = recorded(e.sink, e.recorder, cr, p, op, name)
+		}
+		stdout, stderr, err = sshv1alpha1.ExecuteScript(ctx, e.clients[name], sc, p.Variables, SudoEnabled(p), opts...)
+		store(ctx)
+		if op != "" {
+			e.recorder.Event(cr, executionEvent(execution{operation: op, host: name, duration: time.Since(started), stderr: stderr, err: err}))
+		}
+		st.StatusCode = sshv1alpha1.ExitStatus(err)
+		st.OutputDigest = digest(stdout)
+		if err != nil {
+			st.Ready = false
+			st.Message = fmt.Sprintf("exit code %d: %s", st.StatusCode, stderr)
+		}
+	}
+	e.set(st)
+	return stdout, stderr
+}
+
+// skip evaluates the skipIf expression of the Script on the host, and records
+// the host as ready if it is true. Hosts on which it cannot be evaluated are
+// recorded as not ready, and skipped as well since their state is unknown.
+func (e *fanOutExternal) skip(ctx context.Context, cr *apisv1alpha1.Script, name string) bool {
+	_, host := common.SplitHostKey(name)
+	skip, err := skipped(ctx, e.clients[name], e.params(cr), host)
+	switch {
+	case err != nil:
+		e.set(apisv1alpha1.HostStatus{Name: name, StatusCode: exitCodeFailed, Message: err.Error()})
+		return true
+	case skip:
+		e.set(apisv1alpha1.HostStatus{Name: name, Ready: true, Skipped: true})
+		return true
+	}
+	return false
+}
+
+// check observes the state of the resource on the host, either through the
+// existsScript and upToDateScript or through the statusCheckScript. Skipped
+// hosts are observed as up to date.
+func (e *fanOutExternal) check(ctx context.Context, cr *apisv1alpha1.Script, name string) {
+	if e.skip(ctx, cr, name) {
+		e.observe(name, checkResult{exists: true, upToDate: true})
+		return
+	}
+	p := e.params(cr)
+	if !separateChecks(p) {
+		stdout, stderr := e.execute(ctx, cr, name, "", p.StatusCheckScript)
+		e.mu.Lock()
+		st := e.results[name]
+		if t := retryTime(stdout); t != nil && (e.retryTime == nil || t.Before(e.retryTime)) {
+			e.retryTime = t
+		}
+		e.mu.Unlock()
+		r := statusCheckResult(p, st.StatusCode)
+		if !r.exists || failed(p, st.StatusCode) {
+			e.observe(name, r)
+			return
+		}
+		msg := statusMessage(stdout)
+		if evaluatesWhen(p) {
+			upToDate, ready, whenMsg, err := evaluateWhen(p, st.StatusCode, stdout, stderr)
+			switch {
+			case err != nil:
+				// Hosts whose state is unknown are left untouched.
+				upToDate, ready, msg = true, false, err.Error()
+			case msg == "":
+				msg = whenMsg
+			}
+			r.upToDate = upToDate
+			st.Ready, st.Message = ready, msg
+		} else if msg != "" {
+			st.Message = msg
+		}
+		e.set(st)
+		e.observe(name, r)
+		return
+	}
+
+	r, err := runChecks(ctx, e.clients[name], p)
+	st := apisv1alpha1.HostStatus{
+		Name:         name,
+		Ready:        err == nil && r.exists && r.upToDate,
+		StatusCode:   r.statusCode,
+		OutputDigest: digest(r.stdout),
+	}
+	switch {
+	case err != nil:
+		// Hosts whose state is unknown are left untouched.
+		st.Message = err.Error()
+		r = checkResult{exists: true, upToDate: true}
+	case !st.Ready:
+		st.Message = fmt.Sprintf("exit code %d: %s", r.statusCode, r.stderr)
+	}
+	e.set(st)
+	e.observe(name, r)
+}
+
+func (e *fanOutExternal) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.Info(fmt.Sprintf("[%s] Observing %d hosts...", mg.GetName(), len(e.hosts)))
+	cr, ok := mg.(*apisv1alpha1.Script)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotScript)
+	}
+
+	// A deleted Script only reaches this client if its cleanupScript runs.
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: true}, nil
+	}
+
+	common.ForEachHost(e.connected(all), func(name string) {
+		e.check(ctx, cr, name)
+	})
+	now := metav1.Now()
+	cr.Status.AtProvider.Host = fmt.Sprintf("%d hosts", len(e.hosts))
+	cr.Status.AtProvider.LastChecked = &now
+	cr.Status.AtProvider.RetryTime = e.retryTime
+
+	missing := e.connected(func(r checkResult) bool { return !r.exists })
+	stale := e.connected(func(r checkResult) bool { return r.exists && !r.upToDate })
+	// Hosts below the success threshold are reported through the Ready
+	// condition, Observe itself succeeds.
+	_ = e.report(cr)
+	// Unreachable hosts are checked again by the next observation.
+	scheduleCheck(cr, len(missing) == 0 && len(stale) == 0 && len(e.connected(all)) == len(e.hosts))
+	return managed.ExternalObservation{ResourceExists: len(missing) == 0, ResourceUpToDate: len(stale) == 0}, nil
+}
+
+func (e *fanOutExternal) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	logger := log.FromContext(ctx).WithName("[CREATE]")
+	cr, ok := mg.(*apisv1alpha1.Script)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotScript)
+	}
+	if !common.Policies(cr).ShouldCreate() {
+		return managed.ExternalCreation{}, errors.Errorf(errFmtNotAllowed, "init")
+	}
+
+	missing := e.connected(func(r checkResult) bool { return !r.exists })
+	hosts := e.batch(cr, missing)
+	logger.Info(fmt.Sprintf("[%s] Creating init script on %d hosts...", mg.GetName(), len(hosts)))
+	common.ForEachHost(hosts, func(name string) {
+		_ = retryInit(ctx, cr, func() error {
+			e.execute(ctx, cr, name, apisv1alpha1.OperationInit, cr.Spec.ForProvider.InitScript)
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			if !e.results[name].Ready {
+				return errors.New(errInitFailed)
+			}
+			return nil
+		})
+	})
+	cr.Status.AtProvider.LastOperation = apisv1alpha1.OperationInit
+	cr.Status.AtProvider.NextCheckTime = nil
+	recordResult(cr, e.failed(hosts))
+	if !e.failed(hosts) && len(hosts) == len(missing) {
+		// The hosts of the later batches of a rollout share the token.
+		cr.Status.AtProvider.Creations++
+		deferFirstCheck(cr)
+	}
+	cr.Status.AtProvider.ScriptHash = initScriptHash(cr.Spec.ForProvider)
+	return managed.ExternalCreation{}, e.report(cr)
+}
+
+func (e *fanOutExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	cr, ok := mg.(*apisv1alpha1.Script)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotScript)
+	}
+	if !common.Policies(cr).ShouldUpdate() {
+		return managed.ExternalUpdate{}, errors.Errorf(errFmtNotAllowed, "update")
+	}
+
+	hosts := e.batch(cr, e.connected(func(r checkResult) bool { return r.exists && !r.upToDate }))
+	logger.Info(fmt.Sprintf("[%s] Updating resource on %d hosts...", mg.GetName(), len(hosts)))
+	common.ForEachHost(hosts, func(name string) {
+		e.execute(ctx, cr, name, apisv1alpha1.OperationUpdate, cr.Spec.ForProvider.UpdateScript)
+	})
+	cr.Status.AtProvider.LastOperation = apisv1alpha1.OperationUpdate
+	cr.Status.AtProvider.NextCheckTime = nil
+	recordResult(cr, e.failed(hosts))
+	return managed.ExternalUpdate{}, e.report(cr)
+}
+
+func (e *fanOutExternal) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Deleting on %d hosts...", mg.GetName(), len(e.hosts)))
+	cr, ok := mg.(*apisv1alpha1.Script)
+	if !ok {
+		return errors.New(errNotScript)
+	}
+	if !common.Policies(cr).ShouldDelete() {
+		return errors.Errorf(errFmtNotAllowed, "cleanup")
+	}
+
+	common.ForEachHost(e.connected(all), func(name string) {
+		if e.skip(ctx, cr, name) || e.skipCleanup(ctx, cr, name) {
+			return
+		}
+		e.execute(ctx, cr, name, apisv1alpha1.OperationCleanup, cr.Spec.ForProvider.CleanupScript)
+	})
+	cr.Status.AtProvider.LastOperation = apisv1alpha1.OperationCleanup
+	_ = e.report(cr)
+
+	failed := 0
+	for _, st := range cr.Status.AtProvider.Hosts {
+		if !st.Ready {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("[%s] Deleting failed on %d hosts.", mg.GetName(), failed))
+	if cleanupFailed(cr) {
+		logger.Info(fmt.Sprintf("[%s] Cleanup abandoned after %d attempts.", mg.GetName(), cr.Status.AtProvider.CleanupAttempts))
+		return nil
+	}
+	return errors.Errorf("cleanup failed on %d of %d hosts", failed, len(e.hosts))
+}
+
+// Disconnect closes the connections to the hosts.
+func (e *fanOutExternal) Disconnect(_ context.Context) error {
+	return common.CloseExecutors(e.clients)
+}
+
+// report records the result of every host in the status of the Script and
+// sets its Ready condition according to the success threshold. It returns an
+// error if fewer hosts than required are ready.
+func (e *fanOutExternal) report(cr *apisv1alpha1.Script) error {
+	hosts := make([]apisv1alpha1.HostStatus, 0, len(e.hosts))
+	ready := 0
+	for _, key := range e.hosts {
+		st := e.results[key]
+		st.Kind, st.Name = common.SplitHostKey(key)
+		if st.Ready {
+			ready++
+		}
+		hosts = append(hosts, st)
+	}
+	cr.Status.AtProvider.Hosts = hosts
+
+	required := successThreshold(cr, len(hosts))
+	if len(hosts) > 0 && ready >= required {
+		cr.SetConditions(xpv1.Available())
+		return nil
+	}
+	err := errors.Errorf(errFmtHostsNotReady, ready, len(hosts), required)
+	cr.SetConditions(xpv1.Unavailable().WithMessage(err.Error()))
+	return err
+}
+
+func all(checkResult) bool { return true }
+
+// failed reports whether the last script failed on any of the supplied hosts.
+func (e *fanOutExternal) failed(hosts []string) bool {
+	for _, name := range hosts {
+		if !e.results[name].Ready {
+			return true
+		}
+	}
+	return false
+}
+
+// statusCheckResult maps the exit code of a statusCheckScript onto the state
+// of the resource. Hosts that failed with one of the failed exit codes require
+// user intervention, so no script is executed on them.
+func statusCheckResult(p apisv1alpha1.ScriptParameters, code int) checkResult {
+	switch {
+	case code == 0, failed(p, code):
+		return checkResult{exists: true, upToDate: true}
+	case missing(p, code):
+		return checkResult{}
+	}
+	return checkResult{exists: true}
+}
+
+func digest(stdout string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(stdout)))
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+// IdempotencyTokenVariable is the name of the variable holding the
+// idempotency token of the current creation of a Script.
+const IdempotencyTokenVariable = "IDEMPOTENCY_TOKEN"
+
+// creates reports whether the supplied operation creates the resource, and
+// so is executed with the idempotency token of the creation.
+func creates(op apisv1alpha1.Operation) bool {
+	return op == apisv1alpha1.OperationInit || op == apisv1alpha1.OperationRecreate
+}
+
+// idempotencyToken returns the token of the current creation of the Script.
+// It is derived from the UID of the Script and the number of its successful
+// creations, so that every attempt of a creation, including the attempts
+// retried after a crash of the provider, gets the same token, and the next
+// creation a new one.
+func idempotencyToken(cr *apisv1alpha1.Script) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", cr.GetUID(), cr.Status.AtProvider.Creations)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// withIdempotencyToken returns the supplied variables with the idempotency
+// token of the current creation of the Script, which takes precedence over a
+// variable of the same name.
+func withIdempotencyToken(cr *apisv1alpha1.Script, vars []apisv1alpha1.Variable) []apisv1alpha1.Variable {
+	return append([]apisv1alpha1.Variable{{Name: IdempotencyTokenVariable, Value: idempotencyToken(cr)}}, vars...)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+func TestCreateIdempotencyToken(t *testing.T) {
+	codes := []int{3, 0, 0}
+	x := &sshfake.Executor{MockRun: func(string) (string, string, error) {
+		code := codes[0]
+		codes = codes[1:]
+		if code != 0 {
+			return "", "", sshfake.ExitError(code)
+		}
+		return "", "", nil
+	}}
+	cr := script(withInit("resume-install --token {{IDEMPOTENCY_TOKEN}}"))
+	cr.SetUID(types.UID("6a3c1f0e-5b2d-4e8a-9c7f-1d2e3f4a5b6c"))
+	e := external{service: x, recorder: event.NewNopRecorder()}
+
+	// The first creation fails and is retried, then the resource is created
+	// again once it went missing.
+	for range codes {
+		_, _ = e.Create(context.Background(), cr)
+	}
+
+	scripts := x.Scripts()
+	if len(scripts) != 3 {
+		t.Fatalf("e.Create(...): executed %d scripts, want 3", len(scripts))
+	}
+	if diff := cmp.Diff(scripts[0], scripts[1]); diff != "" {
+		t.Errorf("e.Create(...): the retried creation should get the token of the failed attempt: -first, +retry:\n%s\n", diff)
+	}
+	if scripts[1] == scripts[2] {
+		t.Errorf("e.Create(...): the next creation should get a new token, got %q twice", scripts[2])
+	}
+	want := "resume-install --token " + idempotencyToken(&apisv1alpha1.Script{ObjectMeta: cr.ObjectMeta})
+	if diff := cmp.Diff(want, scripts[0]); diff != "" {
+		t.Errorf("e.Create(...): the token should replace the variable: -want, +got:\n%s\n", diff)
+	}
+	if diff := cmp.Diff(int64(2), cr.Status.AtProvider.Creations); diff != "" {
+		t.Errorf("e.Create(...): -want creations, +got creations:\n%s\n", diff)
+	}
+}
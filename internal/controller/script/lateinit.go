@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errLateInit    = "cannot late-initialize from the facts of the host"
+	errPlatform    = "cannot verify the platform of the host"
+	errFmtPlatform = "host reports platform %q, the scripts are written for %q"
+)
+
+// lateInitialize fills the unset interpreter, remoteTempDir and platform of
+// the Script with the values discovered on its host, and reports whether it
+// filled any. The interpreter and remoteTempDir defaulted by the
+// ProviderConfig are left unset, so that they keep following its defaults.
+func (c *external) lateInitialize(ctx context.Context, cr *apisv1alpha1.Script) (bool, error) {
+	p := &cr.Spec.ForProvider
+	d := c.defaults
+	if d == nil {
+		d = &apisv1alpha1.ScriptDefaults{}
+	}
+	interpreter := p.Interpreter == "" && d.Interpreter == ""
+	tempDir := p.RemoteTempDir == "" && d.RemoteTempDir == ""
+	if !c.lateInit || c.service == nil || !common.Policies(cr).ShouldLateInitialize() || !interpreter && !tempDir && p.Platform != "" {
+		return false, nil
+	}
+
+	facts, err := sshv1alpha1.GatherFacts(ctx, c.service)
+	if err != nil {
+		return false, errors.Wrap(err, errLateInit)
+	}
+	filled := false
+	if sh, _ := facts["shell"].(string); interpreter && sh != "" {
+		p.Interpreter, filled = sh, true
+	}
+	if dir, _ := facts["tmpDir"].(string); tempDir && dir != "" {
+		p.RemoteTempDir, filled = dir, true
+	}
+	if pl := platform(facts); p.Platform == "" && pl != "" {
+		p.Platform, filled = pl, true
+	}
+	return filled, nil
+}
+
+// verifyPlatform returns an error if the host of the executor reports
+// another platform than the one the scripts are written for, if any.
+func verifyPlatform(ctx context.Context, x sshv1alpha1.RemoteExecutor, p apisv1alpha1.ScriptParameters) error {
+	if p.Platform == "" {
+		return nil
+	}
+	if x == nil {
+		return errors.New(errNotConnected)
+	}
+	facts, err := sshv1alpha1.GatherFacts(ctx, x)
+	if err != nil {
+		return errors.Wrap(err, errPlatform)
+	}
+	if pl := platform(facts); pl != p.Platform {
+		return errors.Errorf(errFmtPlatform, pl, p.Platform)
+	}
+	return nil
+}
+
+// platform returns the platform of a host from its facts, as its system and
+// arch, empty if the host reports neither.
+func platform(facts map[string]any) string {
+	system, _ := facts["system"].(string)
+	arch, _ := facts["arch"].(string)
+	if system == "" && arch == "" {
+		return ""
+	}
+	return system + "/" + arch
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+// platformExecutor returns a fake executor of a Linux x86_64 host whose
+// scripts succeed.
+func platformExecutor() *sshfake.Executor {
+	return &sshfake.Executor{MockRun: func(script string) (string, string, error) {
+		if script == factsScript {
+			return "system=Linux\narch=x86_64\nshell=/bin/sh\ntmpDir=/var/tmp\n", "", nil
+		}
+		return "", "", nil
+	}}
+}
+
+func withPlatform(platform string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.Platform = platform }
+}
+
+func TestLateInitialize(t *testing.T) {
+	type want struct {
+		filled      bool
+		interpreter string
+		tempDir     string
+		platform    string
+		scripts     []string
+	}
+
+	cases := map[string]struct {
+		reason   string
+		mg       *apisv1alpha1.Script
+		defaults *apisv1alpha1.ScriptDefaults
+		want     want
+	}{
+		"Unset": {
+			reason: "The unset interpreter, remoteTempDir and platform should be filled from the facts of the host.",
+			mg:     script(withStatusCheck("true")),
+			want: want{
+				filled:      true,
+				interpreter: "/bin/sh",
+				tempDir:     "/var/tmp",
+				platform:    "Linux/x86_64",
+				scripts:     []string{factsScript},
+			},
+		},
+		"Set": {
+			reason: "The facts of the host should not be gathered if every field is set.",
+			mg: script(withStatusCheck("true"), withPlatform("Linux/aarch64"), func(cr *apisv1alpha1.Script) {
+				cr.Spec.ForProvider.Interpreter = "/bin/bash"
+				cr.Spec.ForProvider.RemoteTempDir = "/run/scripts"
+			}),
+			want: want{interpreter: "/bin/bash", tempDir: "/run/scripts", platform: "Linux/aarch64"},
+		},
+		"ProviderDefaults": {
+			reason: "The interpreter and remoteTempDir defaulted by the ProviderConfig should be left unset.",
+			mg:     script(withStatusCheck("true")),
+			defaults: &apisv1alpha1.ScriptDefaults{
+				Interpreter:   "/bin/bash",
+				RemoteTempDir: "/run/scripts",
+			},
+			want: want{filled: true, platform: "Linux/x86_64", scripts: []string{factsScript}},
+		},
+		"Policy": {
+			reason: "Nothing should be late-initialized unless the management policies allow it.",
+			mg: script(withStatusCheck("true"), func(cr *apisv1alpha1.Script) {
+				cr.SetManagementPolicies(xpv1.ManagementPolicies{xpv1.ManagementActionObserve})
+			}),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := platformExecutor()
+			e := external{service: x, recorder: event.NewNopRecorder(), defaults: tc.defaults, lateInit: true}
+			filled, err := e.lateInitialize(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("e.lateInitialize(...): %v", err)
+			}
+			p := tc.mg.Spec.ForProvider
+			got := want{filled: filled, interpreter: p.Interpreter, tempDir: p.RemoteTempDir, platform: p.Platform, scripts: x.Scripts()}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ne.lateInitialize(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCreatePlatform(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		mg     *apisv1alpha1.Script
+		err    bool
+		runs   int
+	}{
+		"Unset": {
+			reason: "The initScript should be executed without gathering facts if the platform is unset.",
+			mg:     script(withInit("make install")),
+			runs:   1,
+		},
+		"Matching": {
+			reason: "The initScript should be executed on a host reporting the platform of the Script.",
+			mg:     script(withInit("make install"), withPlatform("Linux/x86_64")),
+			runs:   2,
+		},
+		"Mismatched": {
+			reason: "The initScript should not be executed on a host reporting another platform.",
+			mg:     script(withInit("make install"), withPlatform("Linux/aarch64")),
+			err:    true,
+			runs:   1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := platformExecutor()
+			e := external{service: x, recorder: event.NewNopRecorder()}
+			_, err := e.Create(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.err, err != nil); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want error, +got error:\n%s\n%v\n", tc.reason, diff, err)
+			}
+			if diff := cmp.Diff(tc.runs, len(x.Scripts())); diff != "" {
+				t.Errorf("\n%s\nScripts run: -want, +got:\n%s\n%v\n", tc.reason, diff, x.Scripts())
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	"github.com/crossplane/provider-ssh/internal/redact"
+)
+
+const (
+	// statusMessageOutput is the output through which the statusCheckScript
+	// explains the state of the resource.
+	statusMessageOutput = "STATUS_MESSAGE"
+
+	// maxStatusMessage is the length in bytes above which a STATUS_MESSAGE is
+	// truncated.
+	maxStatusMessage = 256
+
+	// maxConditionStderrLines is the number of lines at the end of the
+	// stderr of a failed script embedded in its ReconcileError condition.
+	maxConditionStderrLines = 5
+)
+
+// statusMessage returns the redacted STATUS_MESSAGE=<text> line of the
+// supplied stdout, empty if there is none.
+func statusMessage(stdout string) string {
+	msg, _ := parseOutputs(stdout)[statusMessageOutput].(string)
+	msg = strings.TrimSpace(redact.String(msg))
+	if len(msg) > maxStatusMessage {
+		msg = strings.ToValidUTF8(msg[:maxStatusMessage], "") + "..."
+	}
+	return msg
+}
+
+// withStatusMessage returns the supplied Ready condition with the
+// STATUS_MESSAGE of the supplied stdout as its message, if there is one.
+func withStatusMessage(c xpv1.Condition, stdout string) xpv1.Condition {
+	if msg := statusMessage(stdout); msg != "" {
+		return c.WithMessage(msg)
+	}
+	return c
+}
+
+// scriptFailed returns the ReconcileError condition of a script that failed
+// with the supplied error, its message ending with the last lines of the
+// redacted stderr of the script so that the cause shows in kubectl describe.
+func scriptFailed(err error, stderr string) xpv1.Condition {
+	c := xpv1.ReconcileError(err)
+	if lines := tailLines(redact.String(stderr), maxConditionStderrLines); lines != "" {
+		c.Message += ":\n" + lines
+	}
+	return c
+}
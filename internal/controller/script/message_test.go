@@ -0,0 +1,291 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+	"github.com/crossplane/provider-ssh/internal/redact"
+)
+
+func TestStatusMessage(t *testing.T) {
+	redact.Add("hunter2-status")
+	cases := map[string]struct {
+		reason string
+		stdout string
+		want   string
+	}{
+		"Message": {
+			reason: "The STATUS_MESSAGE line should be returned without surrounding spaces.",
+			stdout: "joined=2\nSTATUS_MESSAGE= waiting for cluster join, 2/3 nodes \n",
+			want:   "waiting for cluster join, 2/3 nodes",
+		},
+		"Truncated": {
+			reason: "A long STATUS_MESSAGE should be truncated.",
+			stdout: "STATUS_MESSAGE=" + strings.Repeat("a", maxStatusMessage+10) + "\n",
+			want:   strings.Repeat("a", maxStatusMessage) + "...",
+		},
+		"Redacted": {
+			reason: "The secrets in a STATUS_MESSAGE should be masked.",
+			stdout: "STATUS_MESSAGE=joined with token hunter2-status\n",
+			want:   "joined with token [REDACTED]",
+		},
+		"None": {
+			reason: "A stdout without STATUS_MESSAGE should have no message.",
+			stdout: "active\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, statusMessage(tc.stdout)); diff != "" {
+				t.Errorf("\n%s\nstatusMessage(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveStatusMessage(t *testing.T) {
+	type want struct {
+		status  corev1.ConditionStatus
+		message string
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     *apisv1alpha1.Script
+		stdout string
+		code   int
+		want   want
+	}{
+		"Ready": {
+			reason: "The STATUS_MESSAGE of a successful statusCheckScript should be the message of the Ready condition.",
+			mg:     script(withStatusCheck("cluster-status")),
+			stdout: "STATUS_MESSAGE=3/3 nodes joined\n",
+			want:   want{status: corev1.ConditionTrue, message: "3/3 nodes joined"},
+		},
+		"NoMessage": {
+			reason: "The Ready condition should have no message without STATUS_MESSAGE.",
+			mg:     script(withStatusCheck("cluster-status")),
+			stdout: "3/3 nodes joined\n",
+			want:   want{status: corev1.ConditionTrue},
+		},
+		"Drift": {
+			reason: "A resource whose statusCheckScript exits with a custom code and a STATUS_MESSAGE should not be Ready, with that message.",
+			mg:     script(withStatusCheck("cluster-status")),
+			stdout: "STATUS_MESSAGE=waiting for cluster join, 2/3 nodes\n",
+			code:   3,
+			want:   want{status: corev1.ConditionFalse, message: "waiting for cluster join, 2/3 nodes"},
+		},
+		"ReadyWhen": {
+			reason: "The STATUS_MESSAGE should replace the message of a false readyWhen.",
+			mg:     script(withStatusCheck("cluster-status"), withWhen("", `int(outputs.joined) == 3`)),
+			stdout: "joined=2\nSTATUS_MESSAGE=waiting for cluster join, 2/3 nodes\n",
+			want:   want{status: corev1.ConditionFalse, message: "waiting for cluster join, 2/3 nodes"},
+		},
+		"ReadyWhenNoMessage": {
+			reason: "A false readyWhen should be explained without STATUS_MESSAGE.",
+			mg:     script(withStatusCheck("cluster-status"), withWhen("", `int(outputs.joined) == 3`)),
+			stdout: "joined=2\n",
+			want:   want{status: corev1.ConditionFalse, message: msgReadyWhenFalse},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: outputExecutor(tc.stdout, tc.code)}
+			if _, err := e.Observe(context.Background(), tc.mg); err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			c := tc.mg.GetCondition(xpv1.TypeReady)
+			if diff := cmp.Diff(tc.want, want{status: c.Status, message: c.Message}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want Ready, +got Ready:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFanOutStatusMessage(t *testing.T) {
+	e := &fanOutExternal{
+		recorder: event.NewNopRecorder(),
+		hosts:    []string{"Host/db-1", "Host/db-2"},
+		clients: map[string]sshv1alpha1.RemoteExecutor{
+			"Host/db-1": outputExecutor("STATUS_MESSAGE=primary\n", 0),
+			"Host/db-2": outputExecutor("STATUS_MESSAGE=replica lagging by 42s\n", 3),
+		},
+		results:  map[string]apisv1alpha1.HostStatus{},
+		observed: map[string]checkResult{},
+	}
+	cr := script(withHosts("db-1", "db-2"), withStatusCheck("db-status"))
+	if _, err := e.Observe(context.Background(), cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	want := []apisv1alpha1.HostStatus{
+		{Kind: "Host", Name: "db-1", Ready: true, Message: "primary"},
+		{Kind: "Host", Name: "db-2", StatusCode: 3, Message: "replica lagging by 42s"},
+	}
+	if diff := cmp.Diff(want, cr.Status.AtProvider.Hosts, cmpopts.IgnoreFields(apisv1alpha1.HostStatus{}, "OutputDigest")); diff != "" {
+		t.Errorf("e.Observe(...): -want hosts, +got hosts:\n%s\n", diff)
+	}
+}
+
+func TestFanOutRedactedMessage(t *testing.T) {
+	redact.Add("hunter2-host")
+	e := &fanOutExternal{
+		recorder: event.NewNopRecorder(),
+		hosts:    []string{"Host/db-1"},
+		clients: map[string]sshv1alpha1.RemoteExecutor{
+			"Host/db-1": &sshfake.Executor{MockRun: func(string) (string, string, error) {
+				return "", "psql: password hunter2-host rejected\n", sshfake.ExitError(2)
+			}},
+		},
+		results:  map[string]apisv1alpha1.HostStatus{},
+		observed: map[string]checkResult{},
+	}
+	cr := script(withHosts("db-1"), withStatusCheck("db-status"))
+	if _, err := e.Observe(context.Background(), cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	want := []apisv1alpha1.HostStatus{
+		{Kind: "Host", Name: "db-1", StatusCode: 2, Message: "exit code 2: psql: password [REDACTED] rejected\n"},
+	}
+	if diff := cmp.Diff(want, cr.Status.AtProvider.Hosts, cmpopts.IgnoreFields(apisv1alpha1.HostStatus{}, "OutputDigest")); diff != "" {
+		t.Errorf("\nThe secrets in the stderr of a host should be masked in its status message.\ne.Observe(...): -want hosts, +got hosts:\n%s\n", diff)
+	}
+}
+
+// A messages recorder records the messages of the Events.
+type messages []string
+
+func (m *messages) Event(_ runtime.Object, e event.Event)    { *m = append(*m, e.Message) }
+func (m *messages) WithAnnotations(...string) event.Recorder { return m }
+
+func TestReportDriftRedacted(t *testing.T) {
+	redact.Add("hunter2-diff")
+	cases := map[string]struct {
+		reason     string
+		x          *sshfake.Executor
+		wantDiff   string
+		wantEvents []string
+	}{
+		"Diff": {
+			reason: "The secrets in the stdout of the diffScript should be masked in the status and the Event.",
+			x: &sshfake.Executor{MockRun: func(string) (string, string, error) {
+				return "-token=old\n+token=hunter2-diff\n", "", nil
+			}},
+			wantDiff:   "-token=old\n+token=[REDACTED]\n",
+			wantEvents: []string{"-token=old\n+token=[REDACTED]\n"},
+		},
+		"Failed": {
+			reason: "The secrets in the stderr of a failed diffScript should be masked in the Event.",
+			x: &sshfake.Executor{MockRun: func(string) (string, string, error) {
+				return "", "cannot read hunter2-diff\n", sshfake.ExitError(2)
+			}},
+			wantEvents: []string{"Diff Script failed: cannot read [REDACTED]\n: Process exited with status 2"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got messages
+			cr := script()
+			cr.Spec.ForProvider.DiffScript = "diff -u /etc/app.conf /tmp/app.conf"
+			e := &external{service: tc.x, recorder: &got}
+			e.reportDrift(context.Background(), cr)
+			if diff := cmp.Diff(tc.wantDiff, cr.Status.AtProvider.Diff); diff != "" {
+				t.Errorf("\n%s\nreportDrift(...): -want diff, +got diff:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.wantEvents, []string(got)); diff != "" {
+				t.Errorf("\n%s\nreportDrift(...): -want events, +got events:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestScriptFailedCondition(t *testing.T) {
+	redact.Add("hunter2-token")
+	stderr := "line 1\nline 2\nline 3\nline 4\nline 5\nauth failed for hunter2-token\nline 7\n"
+	failing := func(stderr string) *sshfake.Executor {
+		return &sshfake.Executor{MockRun: func(string) (string, string, error) {
+			return "", stderr, sshfake.ExitError(1)
+		}}
+	}
+
+	cases := map[string]struct {
+		reason string
+		cr     *apisv1alpha1.Script
+		stderr string
+		op     func(e *external, cr *apisv1alpha1.Script) error
+		want   string
+	}{
+		"Update": {
+			reason: "The ReconcileError of a failed updateScript should end with the last lines of its stderr, redacted.",
+			cr:     script(withUpdate("apply")),
+			stderr: stderr,
+			op: func(e *external, cr *apisv1alpha1.Script) error {
+				_, err := e.Update(context.Background(), cr)
+				return err
+			},
+			want: "Update Script failed.: Process exited with status 1:\nline 3\nline 4\nline 5\nauth failed for [REDACTED]\nline 7",
+		},
+		"StatusCheck": {
+			reason: "The ReconcileError of a failed statusCheckScript should end with the last lines of its stderr.",
+			cr:     script(withStatusCheck("check")),
+			stderr: "disk full\n",
+			op: func(e *external, cr *apisv1alpha1.Script) error {
+				_, err := e.Observe(context.Background(), cr)
+				return err
+			},
+			want: "Script failed with exit code 1.: Process exited with status 1:\ndisk full",
+		},
+		"NoStderr": {
+			reason: "The ReconcileError of a failed script without stderr should carry the error only.",
+			cr:     script(withUpdate("apply")),
+			op: func(e *external, cr *apisv1alpha1.Script) error {
+				_, err := e.Update(context.Background(), cr)
+				return err
+			},
+			want: "Update Script failed.: Process exited with status 1",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := &external{service: failing(tc.stderr), recorder: event.NewNopRecorder()}
+			if err := tc.op(e, tc.cr); err == nil {
+				t.Fatalf("\n%s\n%s: want error, got nil", tc.reason, name)
+			}
+			c := tc.cr.GetCondition(xpv1.TypeSynced)
+			if diff := cmp.Diff(tc.want, c.Message); diff != "" {
+				t.Errorf("\n%s\n%s: -want message, +got message:\n%s\n", tc.reason, name, diff)
+			}
+		})
+	}
+}
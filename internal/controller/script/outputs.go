@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+const (
+	errFmtParseOutputs = "cannot parse the stdout of the statusCheckScript as %s"
+	errFmtNotObject    = "the stdout of the statusCheckScript is not a %s object"
+)
+
+// structured reports whether the stdout of the statusCheckScript is parsed
+// as an object recorded in the status of the Script.
+func structured(p apisv1alpha1.ScriptParameters) bool {
+	return p.OutputFormat == apisv1alpha1.OutputFormatJSON || p.OutputFormat == apisv1alpha1.OutputFormatYAML
+}
+
+// outputs returns the outputs of the supplied stdout in the outputFormat of
+// the Script: its name=value lines by default, or the object it encodes in
+// JSON or YAML. Integers are returned as int64 and other numbers as float64,
+// as the expressions expect.
+func outputs(p apisv1alpha1.ScriptParameters, stdout string) (map[string]any, error) {
+	if !structured(p) {
+		return parseOutputs(stdout), nil
+	}
+	data := []byte(stdout)
+	if p.OutputFormat == apisv1alpha1.OutputFormatYAML {
+		var err error
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, errors.Wrapf(err, errFmtParseOutputs, p.OutputFormat)
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.Wrapf(err, errFmtParseOutputs, p.OutputFormat)
+	}
+	m, ok := numbers(v).(map[string]any)
+	if !ok {
+		return nil, errors.Errorf(errFmtNotObject, p.OutputFormat)
+	}
+	return m, nil
+}
+
+// numbers replaces the JSON numbers in the supplied value by int64 or
+// float64.
+func numbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = numbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = numbers(e)
+		}
+	}
+	return v
+}
+
+// recordOutputs records the structured outputs of the supplied stdout of the
+// statusCheckScript in the status of the Script. Outputs encoding to more
+// than the maxOutputBytes of the Script are not recorded.
+func (c *external) recordOutputs(cr *apisv1alpha1.Script, stdout string) error {
+	p := c.params(cr)
+	if !structured(p) {
+		cr.Status.AtProvider.Outputs = nil
+		return nil
+	}
+	m, err := outputs(p, stdout)
+	if err != nil {
+		cr.Status.AtProvider.Outputs = nil
+		return err
+	}
+	raw, err := json.Marshal(m)
+	if err != nil || len(limitOutput(p, c.maxOutputBytes, string(raw))) < len(raw) {
+		cr.Status.AtProvider.Outputs = nil
+		return nil
+	}
+	cr.Status.AtProvider.Outputs = &runtime.RawExtension{Raw: raw}
+	return nil
+}
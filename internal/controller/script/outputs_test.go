@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+func withOutputFormat(f apisv1alpha1.OutputFormat) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.OutputFormat = f }
+}
+
+func withMaxOutputBytes(n int64) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.MaxOutputBytes = &n }
+}
+
+func TestOutputs(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		format apisv1alpha1.OutputFormat
+		stdout string
+		want   map[string]any
+		err    bool
+	}{
+		"KeyValue": {
+			reason: "The name=value lines should be read by default.",
+			stdout: "version=1.2.3\n",
+			want:   map[string]any{"version": "1.2.3"},
+		},
+		"JSON": {
+			reason: "A JSON object should be parsed, with integers as int64 and other numbers as float64.",
+			format: apisv1alpha1.OutputFormatJSON,
+			stdout: `{"version": "1.2.3", "nodes": [{"ready": true, "pods": 12}], "load": 0.5}`,
+			want:   map[string]any{"version": "1.2.3", "nodes": []any{map[string]any{"ready": true, "pods": int64(12)}}, "load": 0.5},
+		},
+		"YAML": {
+			reason: "A YAML mapping such as the status of cloud-init should be parsed.",
+			format: apisv1alpha1.OutputFormatYAML,
+			stdout: "status: done\nerrors: []\nstage:\n  boot: 3\n",
+			want:   map[string]any{"status": "done", "errors": []any{}, "stage": map[string]any{"boot": int64(3)}},
+		},
+		"NotObject": {
+			reason: "A stdout that is not an object should be rejected.",
+			format: apisv1alpha1.OutputFormatYAML,
+			stdout: "- a\n- b\n",
+			err:    true,
+		},
+		"InvalidJSON": {
+			reason: "A stdout that is not valid JSON should be rejected.",
+			format: apisv1alpha1.OutputFormatJSON,
+			stdout: "version=1.2.3\n",
+			err:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := outputs(apisv1alpha1.ScriptParameters{OutputFormat: tc.format}, tc.stdout)
+			if gotErr := err != nil; gotErr != tc.err {
+				t.Fatalf("\n%s\noutputs(...): %v, want error %t", tc.reason, err, tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\noutputs(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveOutputs(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		mg       *apisv1alpha1.Script
+		stdout   string
+		upToDate bool
+		want     string
+	}{
+		"Recorded": {
+			reason:   "The outputs should be recorded in the status and evaluated by the expressions.",
+			mg:       script(withStatusCheck("cloud-init status --format yaml"), withOutputFormat(apisv1alpha1.OutputFormatYAML), withWhen(`outputs.status == "done"`, "")),
+			stdout:   "status: done\nstage:\n  boot: 3\n",
+			upToDate: true,
+			want:     `{"stage":{"boot":3},"status":"done"}`,
+		},
+		"TooLarge": {
+			reason:   "Outputs larger than the maxOutputBytes of the Script should not be recorded.",
+			mg:       script(withStatusCheck("cloud-init status --format yaml"), withOutputFormat(apisv1alpha1.OutputFormatYAML), withMaxOutputBytes(8)),
+			stdout:   "status: done\n",
+			upToDate: true,
+		},
+		"KeyValue": {
+			reason:   "The name=value lines should not be recorded in the status.",
+			mg:       script(withStatusCheck("app --version")),
+			stdout:   "version=1.2.3\n",
+			upToDate: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: outputExecutor(tc.stdout, 0)}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if got.ResourceUpToDate != tc.upToDate {
+				t.Errorf("\n%s\ne.Observe(...): up to date %t, want %t", tc.reason, got.ResourceUpToDate, tc.upToDate)
+			}
+			recorded := ""
+			if o := tc.mg.Status.AtProvider.Outputs; o != nil {
+				recorded = string(o.Raw)
+			}
+			if diff := cmp.Diff(tc.want, recorded); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want outputs, +got outputs:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
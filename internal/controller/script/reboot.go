@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	errBootID = "cannot read the boot ID of the host"
+
+	reasonHostRebooted event.Reason = "HostRebooted"
+)
+
+// detectsReboots reports whether the boot ID of the host of the Script is
+// read on every status check.
+func detectsReboots(p apisv1alpha1.ScriptParameters) bool {
+	return p.RebootPolicy == apisv1alpha1.RebootPolicyRecord || p.RebootPolicy == apisv1alpha1.RebootPolicyUpdate
+}
+
+// observeReboot reads the boot ID of the host and reports whether the
+// postRebootScript is due, because the host rebooted since the boot ID
+// recorded in the status and the rebootPolicy is Update. The boot ID is then
+// only recorded once the postRebootScript succeeded.
+func (c *external) observeReboot(ctx context.Context, cr *apisv1alpha1.Script) (bool, error) {
+	p := cr.Spec.ForProvider
+	if !detectsReboots(p) {
+		return false, nil
+	}
+	if c.service == nil {
+		return false, errors.New(errNotConnected)
+	}
+	id, err := sshv1alpha1.BootID(ctx, c.service)
+	if err != nil {
+		err = errors.Wrap(err, errBootID)
+		cr.SetConditions(xpv1.ReconcileError(err))
+		return false, err
+	}
+	c.bootID = id
+
+	a := &cr.Status.AtProvider
+	switch {
+	case a.BootID == id:
+		return false, nil
+	case a.BootID == "":
+		// The first boot ID observed is the baseline of later reboots.
+		a.BootID = id
+		return false, nil
+	}
+	c.recorder.Event(cr, event.Normal(reasonHostRebooted, fmt.Sprintf("Host rebooted, boot ID changed from %s to %s", a.BootID, id)))
+	if p.RebootPolicy != apisv1alpha1.RebootPolicyUpdate {
+		a.BootID = id
+		return false, nil
+	}
+	return true, nil
+}
+
+// postReboot executes the postRebootScript, or the updateScript if no
+// postRebootScript is set, after the host rebooted, and records the boot ID
+// it was executed on.
+func (c *external) postReboot(ctx context.Context, cr *apisv1alpha1.Script) error {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	p := cr.Spec.ForProvider
+	sc := p.PostRebootScript
+	if sc == "" {
+		sc = p.UpdateScript
+	}
+	if sc != "" {
+		logger.Info(fmt.Sprintf("[%s] Host rebooted, running post-reboot script...", cr.GetName()))
+		if _, stderr, err := c.run(ctx, cr, apisv1alpha1.OperationPostReboot, sc); err != nil {
+			cr.SetConditions(scriptFailed(errors.Wrap(err, "Post-reboot Script failed."), stderr))
+			return err
+		}
+	}
+	cr.Status.AtProvider.BootID = c.bootID
+	return nil
+}
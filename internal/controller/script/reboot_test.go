@@ -0,0 +1,224 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+const (
+	bootA = "8a1c4b1e-3f5d-4c2a-9b7e-6d0f1e2a3b4c"
+	bootB = "f0e1d2c3-b4a5-4968-8776-655443322110"
+)
+
+func withRebootPolicy(rp apisv1alpha1.RebootPolicy, postReboot string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.RebootPolicy = rp
+		cr.Spec.ForProvider.PostRebootScript = postReboot
+	}
+}
+
+func withBootID(id string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Status.AtProvider.BootID = id }
+}
+
+// bootExecutor returns a fake executor of a host booted as the supplied boot
+// ID, whose other scripts exit with the supplied code.
+func bootExecutor(id string, code int) *sshfake.Executor {
+	return &sshfake.Executor{MockRun: func(script string) (string, string, error) {
+		switch {
+		case script == bootIDScript:
+			return id + "\n", "", nil
+		case code != 0:
+			return "", "", sshfake.ExitError(code)
+		}
+		return "", "", nil
+	}}
+}
+
+// bootIDScript is the command reading the boot ID of a host, as recorded by
+// the fake executor.
+var bootIDScript = func() string {
+	x := &sshfake.Executor{}
+	_, _ = sshv1alpha1.BootID(context.Background(), x)
+	return x.Scripts()[0]
+}()
+
+func TestObserveReboot(t *testing.T) {
+	type want struct {
+		o       managed.ExternalObservation
+		bootID  string
+		scripts []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     *apisv1alpha1.Script
+		code   int
+		want   want
+	}{
+		"Ignore": {
+			reason: "The boot ID of the host should not be read unless the rebootPolicy detects reboots.",
+			mg:     script(withStatusCheck("systemctl is-active app"), withBootID(bootA)),
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				bootID:  bootA,
+				scripts: []string{"systemctl is-active app"},
+			},
+		},
+		"FirstBoot": {
+			reason: "The first boot ID observed should be recorded without reporting a reboot.",
+			mg:     script(withStatusCheck("systemctl is-active app"), withRebootPolicy(apisv1alpha1.RebootPolicyUpdate, "")),
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				bootID:  bootB,
+				scripts: []string{"systemctl is-active app", bootIDScript},
+			},
+		},
+		"SameBoot": {
+			reason: "A resource whose host did not reboot should be up to date.",
+			mg:     script(withStatusCheck("systemctl is-active app"), withRebootPolicy(apisv1alpha1.RebootPolicyUpdate, ""), withBootID(bootB)),
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				bootID:  bootB,
+				scripts: []string{"systemctl is-active app", bootIDScript},
+			},
+		},
+		"RebootRecorded": {
+			reason: "A reboot should only be recorded with the Record rebootPolicy.",
+			mg:     script(withStatusCheck("systemctl is-active app"), withRebootPolicy(apisv1alpha1.RebootPolicyRecord, ""), withBootID(bootA)),
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				bootID:  bootB,
+				scripts: []string{"systemctl is-active app", bootIDScript},
+			},
+		},
+		"RebootUpdate": {
+			reason: "A resource whose host rebooted should not be up to date with the Update rebootPolicy, until the postRebootScript succeeded.",
+			mg:     script(withStatusCheck("systemctl is-active app"), withRebootPolicy(apisv1alpha1.RebootPolicyUpdate, "systemctl restart app"), withBootID(bootA)),
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				bootID:  bootA,
+				scripts: []string{"systemctl is-active app", bootIDScript},
+			},
+		},
+		"Missing": {
+			reason: "The boot ID should be read for a missing resource, so that its initScript records it.",
+			mg:     script(withStatusCheck("systemctl is-active app"), withRebootPolicy(apisv1alpha1.RebootPolicyUpdate, ""), withBootID(bootA)),
+			code:   apisv1alpha1.DefaultExitCodeMissing,
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: false},
+				bootID:  bootA,
+				scripts: []string{"systemctl is-active app", bootIDScript},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := bootExecutor(bootB, tc.code)
+			e := external{service: x, recorder: event.NewNopRecorder()}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.o, got, cmpopts.IgnoreFields(managed.ExternalObservation{}, "ConnectionDetails")); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.bootID, tc.mg.Status.AtProvider.BootID); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want boot ID, +got boot ID:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.scripts, x.Scripts()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want scripts, +got scripts:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUpdateReboot(t *testing.T) {
+	type want struct {
+		bootID string
+		op     apisv1alpha1.Operation
+		script string
+		err    bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     *apisv1alpha1.Script
+		code   int
+		want   want
+	}{
+		"PostRebootScript": {
+			reason: "The postRebootScript should be executed in place of the updateScript after a reboot, and the new boot ID recorded.",
+			mg: script(withStatusCheck("systemctl is-active app"), withUpdate("apt-get install -y app"),
+				withRebootPolicy(apisv1alpha1.RebootPolicyUpdate, "systemctl restart app"), withBootID(bootA)),
+			want: want{bootID: bootB, op: apisv1alpha1.OperationPostReboot, script: "systemctl restart app"},
+		},
+		"UpdateScript": {
+			reason: "The updateScript should be executed after a reboot if no postRebootScript is set.",
+			mg: script(withStatusCheck("systemctl is-active app"), withUpdate("apt-get install -y app"),
+				withRebootPolicy(apisv1alpha1.RebootPolicyUpdate, ""), withBootID(bootA)),
+			want: want{bootID: bootB, op: apisv1alpha1.OperationPostReboot, script: "apt-get install -y app"},
+		},
+		"Failed": {
+			reason: "The boot ID should not be recorded if the postRebootScript failed, so that it is executed again.",
+			mg: script(withStatusCheck("systemctl is-active app"),
+				withRebootPolicy(apisv1alpha1.RebootPolicyUpdate, "systemctl restart app"), withBootID(bootA)),
+			code: 1,
+			want: want{bootID: bootA, op: apisv1alpha1.OperationPostReboot, script: "systemctl restart app", err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := bootExecutor(bootB, 0)
+			e := external{service: x, recorder: event.NewNopRecorder()}
+			if _, err := e.Observe(context.Background(), tc.mg); err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if tc.code != 0 {
+				x.MockRun = bootExecutor(bootB, tc.code).MockRun
+			}
+			_, err := e.Update(context.Background(), tc.mg)
+			if gotErr := err != nil; gotErr != tc.want.err {
+				t.Fatalf("\n%s\ne.Update(...): error %v, want error %t", tc.reason, err, tc.want.err)
+			}
+			if diff := cmp.Diff(tc.want.bootID, tc.mg.Status.AtProvider.BootID); diff != "" {
+				t.Errorf("\n%s\ne.Update(...): -want boot ID, +got boot ID:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.op, tc.mg.Status.AtProvider.LastOperation); diff != "" {
+				t.Errorf("\n%s\ne.Update(...): -want operation, +got operation:\n%s\n", tc.reason, diff)
+			}
+			scripts := x.Scripts()
+			if diff := cmp.Diff(tc.want.script, scripts[len(scripts)-1]); diff != "" {
+				t.Errorf("\n%s\ne.Update(...): -want script, +got script:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/recording"
+)
+
+const (
+	reasonRecordingFailed event.Reason = "RecordingFailed"
+
+	errRecordingDisabled = "cannot record the session, the provider has no session recording sink"
+	errStoreRecording    = "cannot store the session recording"
+)
+
+// recordSession reports whether the executions of the scripts of a Script
+// are recorded.
+func recordSession(p apisv1alpha1.ScriptParameters) bool {
+	return p.RecordSession != nil && *p.RecordSession
+}
+
+// recorded returns the options the script of the supplied operation is
+// executed with on the supplied host, recording the execution if the Script
+// asks for it, and the function storing the recording to the supplied sink
+// once the script finished. Recordings that cannot be stored are reported
+// through a warning Event, the execution is not failed.
+func recorded(sink recording.Sink, recorder event.Recorder, cr *apisv1alpha1.Script, p apisv1alpha1.ScriptParameters, op apisv1alpha1.Operation, host string) ([]sshv1alpha1.ExecOption, func(ctx context.Context)) {
+	opts := ExecOptions(p)
+	if !recordSession(p) {
+		return opts, func(context.Context) {}
+	}
+	if sink == nil {
+		return opts, func(context.Context) {
+			recorder.Event(cr, event.Warning(reasonRecordingFailed, errors.New(errRecordingDisabled)))
+		}
+	}
+	started := time.Now()
+	title := fmt.Sprintf("%s script of Script %s", op, cr.GetName())
+	if host != "" {
+		title += " on " + host
+	}
+	r := recording.New(title, started)
+	return append(opts, sshv1alpha1.WithRecording(r)), func(ctx context.Context) {
+		name := recording.Name(cr.GetName(), string(op), host, started)
+		if err := sink.Store(ctx, name, r.Bytes()); err != nil {
+			log.FromContext(ctx).WithName("[RECORD]").Info(fmt.Sprintf("[%s] Cannot store the session recording %s: %s", cr.GetName(), name, err))
+			recorder.Event(cr, event.Warning(reasonRecordingFailed, errors.Wrap(err, errStoreRecording)))
+		}
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+	"github.com/crossplane/provider-ssh/internal/recording"
+)
+
+// A sink stores the recordings in memory, or fails with err.
+type sink struct {
+	stored map[string]string
+	err    error
+}
+
+func (s *sink) Store(_ context.Context, name string, data []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.stored[name] = string(data)
+	return nil
+}
+
+// A reasons recorder records the reasons of the Events.
+type reasons []event.Reason
+
+func (r *reasons) Event(_ runtime.Object, e event.Event)    { *r = append(*r, e.Reason) }
+func (r *reasons) WithAnnotations(...string) event.Recorder { return r }
+
+func TestRunRecordSession(t *testing.T) {
+	record := true
+	cases := map[string]struct {
+		reason  string
+		record  *bool
+		sink    *sink
+		want    []string
+		reasons []event.Reason
+	}{
+		"Recorded": {
+			reason:  "The script and its output should be stored to the sink.",
+			record:  &record,
+			sink:    &sink{stored: map[string]string{}},
+			want:    []string{`"i","echo installing\n"`, `"o","installing\n"`, `"o","warning\n"`, `"m","exit code 0"`},
+			reasons: []event.Reason{reasonScriptSucceeded},
+		},
+		"NotRequested": {
+			reason:  "Scripts should not be recorded unless they ask for it.",
+			sink:    &sink{stored: map[string]string{}},
+			reasons: []event.Reason{reasonScriptSucceeded},
+		},
+		"NoSink": {
+			reason:  "A Script asking for a recording the provider cannot store should be warned about.",
+			record:  &record,
+			reasons: []event.Reason{reasonRecordingFailed, reasonScriptSucceeded},
+		},
+		"StoreFailed": {
+			reason:  "A recording that cannot be stored should be warned about without failing the script.",
+			record:  &record,
+			sink:    &sink{stored: map[string]string{}, err: errors.New("disk full")},
+			reasons: []event.Reason{reasonRecordingFailed, reasonScriptSucceeded},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := script()
+			cr.Spec.ForProvider.RecordSession = tc.record
+			r := &reasons{}
+			e := external{
+				service: &sshfake.Executor{MockRun: func(string) (string, string, error) {
+					return "installing\n", "warning\n", nil
+				}},
+				recorder: r,
+			}
+			if tc.sink != nil {
+				e.sink = tc.sink
+			}
+			if _, _, err := e.run(context.Background(), cr, apisv1alpha1.OperationInit, "echo installing\n"); err != nil {
+				t.Fatalf("\n%s\nrun(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.reasons, []event.Reason(*r)); diff != "" {
+				t.Errorf("\n%s\nrun(...): -want event reasons, +got event reasons:\n%s\n", tc.reason, diff)
+			}
+			if tc.sink == nil {
+				return
+			}
+			if tc.want == nil {
+				if len(tc.sink.stored) > 0 {
+					t.Errorf("\n%s\nrun(...): stored %d recordings, want none", tc.reason, len(tc.sink.stored))
+				}
+				return
+			}
+			for name, data := range tc.sink.stored {
+				if !regexp.MustCompile(`^test/\d{8}T\d{6}\.\d{3}Z-Init\.cast$`).MatchString(name) {
+					t.Errorf("\n%s\nrun(...): stored recording %q, want test/<time>-Init%s", tc.reason, name, recording.Extension)
+				}
+				var got []string
+				for _, l := range strings.Split(strings.TrimSpace(data), "\n")[1:] {
+					got = append(got, l[strings.Index(l, ",")+1:len(l)-1])
+				}
+				if diff := cmp.Diff(tc.want, got); diff != "" {
+					t.Errorf("\n%s\nrun(...): -want events, +got events:\n%s\n", tc.reason, diff)
+				}
+			}
+			if len(tc.sink.stored) != 1 {
+				t.Errorf("\n%s\nrun(...): stored %d recordings, want 1", tc.reason, len(tc.sink.stored))
+			}
+		})
+	}
+}
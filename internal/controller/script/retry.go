@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// retryAfterOutput is the output through which the statusCheckScript
+	// asks the state of the resource to be checked again.
+	retryAfterOutput = "RETRY_AFTER"
+
+	// minRetryAfter is the shortest delay a statusCheckScript can ask for,
+	// so that a script cannot requeue its Script in a tight loop.
+	minRetryAfter = time.Second
+)
+
+// retryAfter returns the delay of the RETRY_AFTER=<duration> line of the
+// supplied stdout, a duration such as 15s or a number of seconds, zero if
+// there is none or it is not a positive duration.
+func retryAfter(stdout string) time.Duration {
+	v, ok := parseOutputs(stdout)[retryAfterOutput].(string)
+	if !ok {
+		return 0
+	}
+	v = strings.TrimSpace(v)
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		s, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return 0
+		}
+		d = time.Duration(s) * time.Second
+	}
+	if d <= 0 {
+		return 0
+	}
+	if d < minRetryAfter {
+		return minRetryAfter
+	}
+	return d
+}
+
+// retryTime returns the time of the next check requested by the supplied
+// stdout of the statusCheckScript, nil if it requests none.
+func retryTime(stdout string) *metav1.Time {
+	d := retryAfter(stdout)
+	if d == 0 {
+		return nil
+	}
+	t := metav1.NewTime(time.Now().Add(d))
+	return &t
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+func withRetryTime(in time.Duration) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		t := metav1.NewTime(time.Now().Add(in))
+		cr.Status.AtProvider.RetryTime = &t
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		stdout string
+		want   time.Duration
+	}{
+		"Duration": {
+			reason: "A RETRY_AFTER duration should be parsed.",
+			stdout: "warming up\nRETRY_AFTER=15s\n",
+			want:   15 * time.Second,
+		},
+		"Seconds": {
+			reason: "A RETRY_AFTER number should be parsed as seconds.",
+			stdout: "RETRY_AFTER=90\n",
+			want:   90 * time.Second,
+		},
+		"Minimum": {
+			reason: "A RETRY_AFTER shorter than a second should be raised to a second.",
+			stdout: "RETRY_AFTER=10ms\n",
+			want:   minRetryAfter,
+		},
+		"LastWins": {
+			reason: "The last RETRY_AFTER line should win.",
+			stdout: "RETRY_AFTER=1m\nRETRY_AFTER=30s\n",
+			want:   30 * time.Second,
+		},
+		"Invalid": {
+			reason: "An invalid RETRY_AFTER should be ignored.",
+			stdout: "RETRY_AFTER=soon\n",
+		},
+		"NotPositive": {
+			reason: "A RETRY_AFTER that is not positive should be ignored.",
+			stdout: "RETRY_AFTER=-5s\n",
+		},
+		"None": {
+			reason: "A stdout without RETRY_AFTER should request no check.",
+			stdout: "active\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, retryAfter(tc.stdout)); diff != "" {
+				t.Errorf("\n%s\nretryAfter(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveRetryAfter(t *testing.T) {
+	cr := script(withStatusCheck("systemctl is-active app"), withWhen("", `outputs.state == "active"`))
+	e := external{service: outputExecutor("state=activating\nRETRY_AFTER=15s\n", 0)}
+	if _, err := e.Observe(context.Background(), cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if got := pollInterval(cr, time.Minute); got <= 0 || got > 15*time.Second {
+		t.Errorf("pollInterval(...): want the time until the check requested through RETRY_AFTER, got %s", got)
+	}
+
+	e = external{service: outputExecutor("state=active\n", 0)}
+	if _, err := e.Observe(context.Background(), cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if cr.Status.AtProvider.RetryTime != nil {
+		t.Errorf("e.Observe(...): a statusCheckScript that requests no check should clear the retryTime, got %s", cr.Status.AtProvider.RetryTime)
+	}
+}
+
+func TestFanOutRetryAfter(t *testing.T) {
+	e := &fanOutExternal{
+		recorder: event.NewNopRecorder(),
+		hosts:    []string{"Host/web-1", "Host/web-2"},
+		clients: map[string]sshv1alpha1.RemoteExecutor{
+			"Host/web-1": outputExecutor("RETRY_AFTER=1m\n", 0),
+			"Host/web-2": outputExecutor("RETRY_AFTER=15s\n", 0),
+		},
+		results:  map[string]apisv1alpha1.HostStatus{},
+		observed: map[string]checkResult{},
+	}
+	cr := script(withHosts("web-1", "web-2"), withStatusCheck("systemctl is-active app"))
+	if _, err := e.Observe(context.Background(), cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if got := pollInterval(cr, time.Hour); got <= 0 || got > 15*time.Second {
+		t.Errorf("pollInterval(...): want the time until the earliest check requested through RETRY_AFTER, got %s", got)
+	}
+}
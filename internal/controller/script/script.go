@@ -19,70 +19,123 @@ package script
 import (
 	"context"
 	"fmt"
+	"time"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
-	"github.com/crossplane/crossplane-runtime/pkg/connection"
-	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
-	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 
-	"golang.org/x/crypto/ssh"
-
 	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
 	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
-	"github.com/crossplane/provider-ssh/internal/features"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+	"github.com/crossplane/provider-ssh/internal/policy"
+	"github.com/crossplane/provider-ssh/internal/recording"
+	"github.com/crossplane/provider-ssh/internal/redact"
+	"github.com/crossplane/provider-ssh/internal/signature"
 )
 
 const (
 	errNotScript    = "managed resource is not a Script custom resource"
 	errTrackPCUsage = "cannot track ProviderConfig usage"
 	errGetPC        = "cannot get ProviderConfig"
-	errGetCreds     = "cannot get credentials"
 
-	errNewClient = "cannot create new Service"
+	errVerifySignature = "cannot verify the signature of the scripts"
+	errPolicy          = "cannot execute scripts"
+
+	errDeleteTTL = "cannot delete Script after its TTL expired"
+	errRunCheck  = "cannot run check script"
+
+	errNotConnected    = "not connected to the host"
+	errHostUnreachable = "host is unreachable"
+
+	errWaitingForDependencies = "waiting for dependencies"
+	errFmtNotAllowed          = "management policies do not allow running the %s script"
+
+	reasonDriftDetected event.Reason = "DriftDetected"
+
+	// maxEventOutput is the number of bytes of script output attached to an
+	// Event.
+	maxEventOutput = 1024
+)
+
+// reconcileTimeout bounds a reconcile of a Script. It leaves room for the
+// statusCheckScript, diffScript and one init, update or cleanup script to
+// run to the maximum timeoutSeconds of 600.
+const reconcileTimeout = 3*600*time.Second + time.Minute
+
+// Keys of the connection secret of a Script.
+const (
+	keyHost               = "host"
+	keyPort               = "port"
+	keyUsername           = "username"
+	keyHostKeyFingerprint = "hostKeyFingerprint"
 )
 
 // Setup adds a controller that reconciles Script managed resources.
-func Setup(mgr ctrl.Manager, o controller.Options) error {
+func Setup(mgr ctrl.Manager, o common.Options) error {
 	name := managed.ControllerName(apisv1alpha1.ScriptGroupKind)
+	r := newReconciler(mgr, o, common.NewEventRecorder(mgr, name, o))
 
-	cps := []managed.ConnectionPublisher{managed.NewAPISecretPublisher(mgr.GetClient(), mgr.GetScheme())}
-	if o.Features.Enabled(features.EnableAlphaExternalSecretStores) {
-		cps = append(cps, connection.NewDetailsManager(mgr.GetClient(), apisv1alpha1.StoreConfigGroupVersionKind))
-	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.Script{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.ScriptGroupVersionKind), name, r, o))
+}
+
+// newReconciler returns the managed resource reconciler of Scripts, emitting
+// Events through the supplied recorder.
+func newReconciler(mgr ctrl.Manager, o common.Options, recorder event.Recorder) *managed.Reconciler {
+	name := managed.ControllerName(apisv1alpha1.ScriptGroupKind)
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
 
-	r := managed.NewReconciler(mgr,
+	return managed.NewReconciler(mgr,
 		resource.ManagedKind(apisv1alpha1.ScriptGroupVersionKind),
-		managed.WithExternalConnecter(&connector{
-			kube:         mgr.GetClient(),
-			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
-			newServiceFn: sshv1alpha1.NewSSHClient}),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:           mgr.GetClient(),
+			usage:          resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			recorder:       recorder,
+			maxOutputBytes: o.MaxOutputBytes,
+			verifier:       o.ScriptVerifier,
+			policy:         o.ScriptPolicy,
+			sink:           o.SessionRecordings,
+			batch:          newBatcher(o.StatusCheckBatchWindow, o.MaxStatusCheckBatch),
+			newServiceFn:   o.NewServiceFn()})),
 		managed.WithLogger(o.Logger.WithValues("controller", name)),
 		managed.WithPollInterval(o.PollInterval),
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithPollIntervalHook(pollInterval),
+		managed.WithRecorder(recorder),
 		managed.WithConnectionPublishers(cps...),
+		managed.WithCriticalAnnotationUpdater(common.NewStatusPreservingUpdater(mgr.GetClient())),
+		// The external name is the path of the state marker, it is not
+		// defaulted to the name of the Script.
+		managed.WithInitializers(),
+		managed.WithTimeout(reconcileTimeout),
 		managed.WithManagementPolicies())
-
-	return ctrl.NewControllerManagedBy(mgr).
-		Named(name).
-		WithOptions(o.ForControllerRuntime()).
-		WithEventFilter(resource.DesiredStateChanged()).
-		For(&apisv1alpha1.Script{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
 }
 
 type connector struct {
-	kube         client.Client
-	usage        resource.Tracker
-	newServiceFn func(ctx context.Context, creds []byte) (*ssh.Client, error)
+	kube           client.Client
+	usage          resource.Tracker
+	recorder       event.Recorder
+	maxOutputBytes int64
+	verifier       *signature.Verifier
+	policy         *policy.Policy
+	sink           recording.Sink
+	batch          *batcher
+	newServiceFn   common.NewServiceFn
 }
 
 // Connect typically produces an ExternalClient by:
@@ -92,21 +145,25 @@ type connector struct {
 // 4. Using the credentials to form a client.
 func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
 	logger := log.FromContext(ctx).WithName("[CONNECT]")
-	logger.Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
 	cr, ok := mg.(*apisv1alpha1.Script)
 	if !ok {
 		return nil, errors.New(errNotScript)
 	}
 
-	if err := c.usage.Track(ctx, mg); err != nil {
-		return nil, errors.Wrap(err, errTrackPCUsage)
-	}
-
 	pc := &apisv1alpha1.ProviderConfig{}
 	if err := c.kube.Get(ctx, types.NamespacedName{Name: cr.GetProviderConfigReference().Name}, pc); err != nil {
 		return nil, errors.Wrap(err, errGetPC)
 	}
 
+	if err := common.CheckQuota(ctx, c.kube, pc, cr); err != nil {
+		return nil, err
+	}
+
+	if err := c.usage.Track(ctx, mg); err != nil {
+		return nil, errors.Wrap(err, errTrackPCUsage)
+	}
+
 	// if the resource is being deleted, we just return
 	cond := cr.GetCondition(xpv1.Deleting().Type)
 	if cond.Type == xpv1.TypeReady && cond.Status == "False" && cond.Reason == xpv1.ReasonDeleting {
@@ -114,31 +171,153 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		return &external{}, nil
 	}
 
-	cd := pc.Spec.Credentials
-	data, err := resource.CommonCredentialExtractor(ctx, cd.Source, c.kube, cd.CommonCredentialSelectors)
+	if meta.WasDeleted(cr) && !runsCleanup(cr) {
+		logger.Info(fmt.Sprintf("[%s] Resource is deleted without cleanup. Skip the connection.", mg.GetName()))
+		return &external{}, nil
+	}
+	if wait := cleanupBackoff(cr); meta.WasDeleted(cr) && wait > 0 {
+		return nil, errors.Errorf(errFmtCleanupBackoff, wait.Round(time.Second))
+	}
+
+	if suspended(cr) {
+		logger.Info(fmt.Sprintf("[%s] Resource is suspended. Skip the connection.", mg.GetName()))
+		return &external{}, nil
+	}
+
+	if halted(cr) {
+		logger.Info(fmt.Sprintf("[%s] Resource is halted. Skip the connection.", mg.GetName()))
+		return &external{}, nil
+	}
+
+	pending, err := pendingDependencies(ctx, c.kube, cr)
 	if err != nil {
-		return nil, errors.Wrap(err, errGetCreds)
+		return nil, err
+	}
+	if len(pending) > 0 {
+		logger.Info(fmt.Sprintf("[%s] Resource is waiting for dependencies. Skip the connection.", mg.GetName()))
+		return &external{kube: c.kube}, nil
+	}
+
+	// A finished RunOnce Script only needs a connection to run its cleanupScript.
+	if runOnceFinished(cr) && !(meta.WasDeleted(cr) && runsCleanup(cr)) {
+		logger.Info(fmt.Sprintf("[%s] RunOnce script already executed. Skip the connection.", mg.GetName()))
+		return &external{kube: c.kube}, nil
 	}
 
-	svc, err := c.newServiceFn(ctx, data)
+	if _, ok := runNowPending(cr); !ok && !checkDue(cr) {
+		logger.Info(fmt.Sprintf("[%s] Status check is not due yet. Skip the connection.", mg.GetName()))
+		return &external{kube: c.kube}, nil
+	}
+
+	// The signatures and the policy cover the execution settings the scripts
+	// are executed with, including the defaults of the ProviderConfig.
+	effective := cr.DeepCopy()
+	effective.Spec.ForProvider = withDefaults(cr.Spec.ForProvider, pc.Spec.Defaults)
+	if err := c.verifier.Verify(effective); err != nil {
+		return nil, errors.Wrap(err, errVerifySignature)
+	}
+
+	if err := c.policy.Evaluate(ctx, effective); err != nil {
+		return nil, errors.Wrap(err, errPolicy)
+	}
+
+	if fanOut(cr) {
+		return c.connectHosts(ctx, cr, pc.Spec.Defaults)
+	}
+
+	if statusCached(cr) || c.batch != nil && !meta.WasDeleted(cr) && batches(effective.Spec.ForProvider) {
+		// The Script only connects to its host to execute its other scripts:
+		// the result of its statusCheckScript is cached, or it is executed in
+		// the batch of the host.
+		if err := common.AuthorizeProviderConfig(pc, cr); err != nil {
+			return nil, err
+		}
+		mg, p := cr.DeepCopy(), cr.Spec.ForProvider
+		dial := func(ctx context.Context) (sshv1alpha1.RemoteExecutor, error) {
+			svc, err := common.Dial(ctx, c.kube, mg, pc, c.newServiceFn, targets(ctx, c.kube, p)...)
+			if err != nil {
+				return nil, err
+			}
+			return sshv1alpha1.NewSSHExecutor(svc), nil
+		}
+		logger.Info(fmt.Sprintf("[%s] Status check is cached or batched. Defer the connection.", mg.GetName()))
+		return &external{kube: c.kube, service: &lazyExecutor{key: batchKey(pc, p), dial: dial}, batch: c.batch, recorder: c.recorder, sink: c.sink, maxOutputBytes: c.maxOutputBytes, defaults: pc.Spec.Defaults, lateInit: true}, nil
+	}
+
+	svc, err := common.Dial(ctx, c.kube, cr, pc, c.newServiceFn, targets(ctx, c.kube, cr.Spec.ForProvider)...)
 	if err != nil {
-		return nil, errors.Wrap(err, errNewClient)
+		if meta.WasDeleted(cr) && cleanupFailed(cr) {
+			logger.Info(fmt.Sprintf("[%s] Cleanup abandoned, the host is unreachable.", mg.GetName()))
+			return &external{kube: c.kube}, nil
+		}
+		if unreachable(cr, err) {
+			// An offline host is an expected state rather than a failure
+			// of the reconcile, Observe reports the Script Unavailable.
+			logger.Info(fmt.Sprintf("[%s] Host is unreachable: %s", mg.GetName(), err))
+			return &external{kube: c.kube, unreachable: err}, nil
+		}
+		return nil, err
 	}
 
-	logger.Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
-	return &external{service: svc}, nil
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
+	return &external{kube: c.kube, service: sshv1alpha1.NewSSHExecutor(svc), recorder: c.recorder, sink: c.sink, maxOutputBytes: c.maxOutputBytes, defaults: pc.Spec.Defaults, lateInit: true}, nil
+}
+
+// targets returns the overrides of the credentials of a Script connecting to
+// a single host: its endpoint, VirtualMachineInstance or Machine, and
+// username.
+func targets(ctx context.Context, kube client.Client, p apisv1alpha1.ScriptParameters) []common.CredentialsOverride {
+	return []common.CredentialsOverride{
+		common.Endpoint(p.Endpoint),
+		common.VirtualMachineInstance(ctx, kube, p.VirtualMachineInstance),
+		common.Machine(ctx, kube, p.Machine),
+		common.Username(p.Username),
+	}
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
 // external resource to ensure it reflects the managed resource's desired state.
 type external struct {
-	// A 'client' used to connect to the external resource API.
-	service interface{}
+	// A client of the Kubernetes API, used to manage the Script itself.
+	kube client.Client
+	// The executor running the scripts on the host, nil if the Script was
+	// not connected to its host.
+	service sshv1alpha1.RemoteExecutor
+	// A recorder of the Events emitted for the Script.
+	recorder event.Recorder
+	// The sink the recordings of the executions are stored to, nil if they
+	// are not recorded.
+	sink recording.Sink
+	// The maxOutputBytes of Scripts that do not set it, zero for the
+	// default of the API.
+	maxOutputBytes int64
+	// The defaults of the ProviderConfig of the Script.
+	defaults *apisv1alpha1.ScriptDefaults
+	// The batcher executing the statusCheckScript, nil if it is not
+	// executed in a batch.
+	batch *batcher
+	// The boot ID of the host read by Observe, empty if reboots are not
+	// detected.
+	bootID string
+	// Whether Observe found the host rebooted and the postRebootScript due.
+	rebooted bool
+	// The error of the connection that did not reach the host, nil if the
+	// host was reached or not connected to.
+	unreachable error
+	// Whether Observe late-initializes the spec from the facts of the host,
+	// for Scripts connected to a single host.
+	lateInit bool
+}
+
+// params returns the parameters of the Script, with the defaults of its
+// ProviderConfig.
+func (c *external) params(cr *apisv1alpha1.Script) apisv1alpha1.ScriptParameters {
+	return withDefaults(cr.Spec.ForProvider, c.defaults)
 }
 
 func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
 	logger := log.FromContext(ctx).WithName("[OBSERVE]")
-	logger.Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
 	cr, ok := mg.(*apisv1alpha1.Script)
 	if !ok {
 		return managed.ExternalObservation{}, errors.New(errNotScript)
@@ -151,39 +330,303 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{ResourceExists: false}, nil
 	}
 
+	if meta.WasDeleted(cr) && !runsCleanup(cr) {
+		logger.Info(fmt.Sprintf("[%s] Observing skipped. Resource is deleted without cleanup.", mg.GetName()))
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	if suspended(cr) {
+		logger.Info(fmt.Sprintf("[%s] Observing skipped. Resource is suspended.", mg.GetName()))
+		cr.SetConditions(apisv1alpha1.Suspended())
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+	if cr.GetCondition(apisv1alpha1.TypeSuspended).Status == corev1.ConditionTrue {
+		cr.SetConditions(apisv1alpha1.Resumed())
+	}
+
+	if halted(cr) {
+		logger.Info(fmt.Sprintf("[%s] Observing skipped. Resource is halted.", mg.GetName()))
+		cr.SetConditions(apisv1alpha1.Halted(cr.Status.AtProvider.ConsecutiveFailures))
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+	resume(cr)
+
+	pending, err := pendingDependencies(ctx, c.kube, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	if len(pending) > 0 {
+		// Report the resource as existing so that neither Create nor Update
+		// is called before the dependencies are Ready.
+		logger.Info(fmt.Sprintf("[%s] Observing skipped. Waiting for dependencies.", mg.GetName()))
+		cr.SetConditions(apisv1alpha1.WaitingForDependencies(pending), xpv1.Unavailable().WithMessage(errWaitingForDependencies))
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+	if len(cr.Spec.ForProvider.DependsOn) > 0 {
+		cr.SetConditions(apisv1alpha1.DependenciesReady())
+	}
+
+	if c.unreachable != nil {
+		logger.Info(fmt.Sprintf("[%s] Observing skipped. The host is unreachable.", mg.GetName()))
+		return observeUnreachable(cr, c.unreachable), nil
+	}
+
+	if runOnceFinished(cr) {
+		logger.Info(fmt.Sprintf("[%s] Observing, RunOnce script already executed.", mg.GetName()))
+		if ttlExpired(cr) && cr.Spec.ForProvider.DeleteAfterTTL && !meta.WasDeleted(cr) {
+			logger.Info(fmt.Sprintf("[%s] TTL expired. Deleting the resource.", mg.GetName()))
+			if err := c.kube.Delete(ctx, cr); resource.IgnoreNotFound(err) != nil {
+				return managed.ExternalObservation{}, errors.Wrap(err, errDeleteTTL)
+			}
+		}
+		return observeRunOnce(cr), nil
+	}
+
+	if _, ok := runNowPending(cr); ok && common.Policies(cr).ShouldUpdate() {
+		logger.Info(fmt.Sprintf("[%s] Observing skipped. Run requested through annotation.", mg.GetName()))
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, nil
+	}
+
+	if !checkDue(cr) {
+		logger.Info(fmt.Sprintf("[%s] Observing skipped. Next status check at %s.", mg.GetName(), cr.Status.AtProvider.NextCheckTime))
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+
+	skip, err := skipped(ctx, c.service, c.params(cr), providerConfigName(cr))
+	if unreachable(cr, err) {
+		logger.Info(fmt.Sprintf("[%s] Observing failed. The host is unreachable: %s", mg.GetName(), err))
+		return observeUnreachable(cr, err), nil
+	}
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	if skip {
+		logger.Info(fmt.Sprintf("[%s] Observing skipped. The skipIf expression is true on the host.", mg.GetName()))
+		recordCheck(cr, c.service)
+		cr.Status.AtProvider.Skipped = true
+		cr.SetConditions(apisv1alpha1.Skipped(), xpv1.Available())
+		scheduleCheck(cr, true)
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+	if cr.Status.AtProvider.Skipped {
+		cr.Status.AtProvider.Skipped = false
+		cr.SetConditions(apisv1alpha1.NotSkipped())
+	}
+
+	if meta.WasDeleted(cr) && checksBeforeCleanup(c.params(cr)) {
+		// Delete checks whether the resource still exists, so that a failing
+		// statusCheckScript does not block the deletion.
+		logger.Info(fmt.Sprintf("[%s] Observing skipped. The resource is checked before its cleanup.", mg.GetName()))
+		return managed.ExternalObservation{ResourceExists: true}, nil
+	}
+
+	adopted, err := c.observeMarker(ctx, cr)
+	if unreachable(cr, err) {
+		logger.Info(fmt.Sprintf("[%s] Observing failed. The host is unreachable: %s", mg.GetName(), err))
+		return observeUnreachable(cr, err), nil
+	}
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	// A cached result was not checked on the host, which may not have been
+	// connected to.
+	cached := statusCached(cr)
+	o, err := c.observeStatusCheck(ctx, cr)
+	if unreachable(cr, err) {
+		logger.Info(fmt.Sprintf("[%s] Observing failed. The host is unreachable: %s", mg.GetName(), err))
+		return observeUnreachable(cr, err), nil
+	}
+	if !cached {
+		recordCheck(cr, c.service)
+	}
+	if err != nil {
+		return o, err
+	}
+	if !cached {
+		o.ConnectionDetails = connectionDetails(c.service)
+	}
+	if adopted && !o.ResourceExists {
+		// An adopted host is never provisioned again, the updateScript
+		// repairs it instead.
+		logger.Info(fmt.Sprintf("[%s] Host is adopted from its state marker. Skip the init script.", mg.GetName()))
+		o.ResourceExists, o.ResourceUpToDate = true, false
+	}
+	if c.rebooted, err = c.observeReboot(ctx, cr); err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	if !o.ResourceExists {
+		return o, nil
+	}
+	// The spec is only updated with the late-initialized fields once the
+	// resource exists.
+	o.ResourceLateInitialized, err = c.lateInitialize(ctx, cr)
+	if unreachable(cr, err) {
+		logger.Info(fmt.Sprintf("[%s] Observing failed. The host is unreachable: %s", mg.GetName(), err))
+		return observeUnreachable(cr, err), nil
+	}
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	hash := initScriptHash(cr.Spec.ForProvider)
+	if cr.Status.AtProvider.ScriptHash == "" {
+		// Adopt the current initScript of resources created before the hash was recorded.
+		cr.Status.AtProvider.ScriptHash = hash
+	}
+	if cr.Spec.ForProvider.ReapplyInterval != nil && cr.Status.AtProvider.LastAppliedTime == nil {
+		// The reapplyInterval of resources applied before the time was
+		// recorded elapses from now.
+		now := metav1.Now()
+		cr.Status.AtProvider.LastAppliedTime = &now
+	}
+	recreating := recreate(cr) && cr.Status.AtProvider.ScriptHash != hash
+	if recreating {
+		logger.Info(fmt.Sprintf("[%s] Rendered init script changed. Recreate the resource.", mg.GetName()))
+		o.ResourceUpToDate = false
+	}
+
+	if o.ResourceUpToDate {
+		cr.Status.AtProvider.Diff = ""
+	} else {
+		c.reportDrift(ctx, cr)
+	}
+	if !recreating {
+		o.ResourceUpToDate = observeDrift(cr, o.ResourceUpToDate)
+	}
+	if o.ResourceUpToDate && reapplyDue(cr) {
+		logger.Info(fmt.Sprintf("[%s] Reapply interval elapsed. Run the update script.", mg.GetName()))
+		o.ResourceUpToDate = false
+	}
+	if c.rebooted && o.ResourceUpToDate {
+		logger.Info(fmt.Sprintf("[%s] Host rebooted. Run the post-reboot script.", mg.GetName()))
+		o.ResourceUpToDate = false
+	}
+	scheduleCheck(cr, o.ResourceUpToDate)
+	if cr.Spec.ForProvider.UpdatePolicy == apisv1alpha1.UpdatePolicyAlways && o.ResourceUpToDate {
+		logger.Info(fmt.Sprintf("[%s] Update policy is Always. Run the update script.", mg.GetName()))
+		o.ResourceUpToDate = false
+	}
+	return o, nil
+}
+
+// observeDrift applies the Never updatePolicy to the supplied state of an
+// existing resource: drift is reported through the Drifted condition, and
+// the resource is reported as up to date so that its updateScript is not
+// executed.
+func observeDrift(cr *apisv1alpha1.Script, upToDate bool) bool {
+	if cr.Spec.ForProvider.UpdatePolicy == apisv1alpha1.UpdatePolicyNever && !upToDate {
+		cr.SetConditions(apisv1alpha1.Drifted())
+		return true
+	}
+	if upToDate && cr.GetCondition(apisv1alpha1.TypeDrifted).Status == corev1.ConditionTrue {
+		cr.SetConditions(apisv1alpha1.NotDrifted())
+	}
+	return upToDate
+}
+
+// reportDrift executes the diffScript and reports its redacted stdout through
+// the status and an Event.
+func (c *external) reportDrift(ctx context.Context, cr *apisv1alpha1.Script) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	p := c.params(cr)
+	if p.DiffScript == "" || common.Policies(cr).ShouldOnlyObserve() {
+		return
+	}
+
+	stdout, stderr, err := sshv1alpha1.ExecuteScript(ctx, c.service, p.DiffScript, p.Variables, SudoEnabled(p), ExecOptions(p)...)
+	if err != nil {
+		logger.Info(fmt.Sprintf("[%s] Diff script failed. Exit code: %d", cr.GetName(), sshv1alpha1.ExitStatus(err)))
+		c.recorder.Event(cr, event.Warning(reasonDriftDetected, errors.Wrap(err, fmt.Sprintf("Diff Script failed: %s", tail(redact.String(stderr))))))
+		return
+	}
+	stdout = redact.String(stdout)
+	cr.Status.AtProvider.Diff = stdout
+	c.recorder.Event(cr, event.Normal(reasonDriftDetected, tail(stdout)))
+}
+
+// tail returns the end of the supplied output, short enough to be attached
+// to an Event. The output is cut at the start of a character, so that a
+// UTF-8 output stays valid.
+func tail(out string) string {
+	if len(out) <= maxEventOutput {
+		return out
+	}
+	start := len(out) - maxEventOutput
+	for start < len(out) && !utf8.RuneStart(out[start]) {
+		start++
+	}
+	return "..." + out[start:]
+}
+
+// unreachable reports whether err is the failure of the connection to the
+// host of a Script that is not deleted, which is deferred to the first script
+// executed when its status check is cached or batched.
+func unreachable(cr *apisv1alpha1.Script, err error) bool {
+	return common.IsUnreachable(err) && !meta.WasDeleted(cr)
+}
+
+// observeUnreachable reports the Script Unavailable because its host could
+// not be reached with the supplied error. The resource is reported as
+// existing and up to date, so that no script is executed and its status is
+// left as last observed until the host is reachable again.
+func observeUnreachable(cr *apisv1alpha1.Script, err error) managed.ExternalObservation {
+	cr.SetConditions(xpv1.Unavailable().WithMessage(errors.Wrap(err, errHostUnreachable).Error()))
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}
+}
+
+// observeStatusCheck runs the statusCheckScript and maps its exit code onto
+// the observation of the remote state.
+func (c *external) observeStatusCheck(ctx context.Context, cr *apisv1alpha1.Script) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+
+	if separateChecks(cr.Spec.ForProvider) {
+		return c.observeChecks(ctx, cr)
+	}
+
 	// We expect to have the CheckStatusScript
-	if cr.Spec.ForProvider.StatusCheckScript != "" {
-		stdout, stderr, err := sshv1alpha1.ExecuteScript(
-			ctx, c.service.(*ssh.Client), cr.Spec.ForProvider.StatusCheckScript, cr.Spec.ForProvider.Variables, cr.Spec.ForProvider.SudoEnabled)
+	if p := c.params(cr); p.StatusCheckScript != "" {
+		stdout, stderr, err := cachedStatus(cr)
+		if statusCached(cr) {
+			logger.Info(fmt.Sprintf("[%s] Reusing the cached result of the status check.", cr.GetName()))
+		} else {
+			stdout, stderr, err = c.statusCheck(ctx, p)
+			cacheStatus(cr, err)
+			cr.Status.AtProvider.RetryTime = retryTime(stdout)
+		}
 
+		if common.IsUnreachable(err) {
+			// The host was not reached, there is no exit code to observe.
+			return managed.ExternalObservation{}, err
+		}
 		// nolint:nilerr
 		if err != nil {
 			// If the script fails, it means there is either an issue with the
 			// init script and the target is not ready yet, or the init script is not
 			// executed at all. In both cases, we request to run init script again
 			// by returning ResourceExists: false
-			var exitStatus int
-			if exitErr, ok := err.(*ssh.ExitError); ok {
-				exitStatus = exitErr.ExitStatus()
-			} else {
-				exitStatus = 1
-				logger.Info(fmt.Sprintf("[%s] Unable to detect exit code", mg.GetName()))
+			exitStatus := sshv1alpha1.ExitStatus(err)
+			if !sshv1alpha1.IsExitError(err) {
+				logger.Info(fmt.Sprintf("[%s] Unable to detect exit code", cr.GetName()))
 			}
 
-			logger.Info(fmt.Sprintf("[%s] Observing failed. Exit code: %d", mg.GetName(), exitStatus))
-			cr.Status.AtProvider.Stdout = stdout
-			cr.Status.AtProvider.Stderr = stderr
+			logger.Info(fmt.Sprintf("[%s] Observing failed. Exit code: %d", cr.GetName(), exitStatus))
+			c.recordStdout(cr, stdout)
+			cr.Status.AtProvider.Stderr = limitOutput(c.params(cr), c.maxOutputBytes, stderr)
 			cr.Status.AtProvider.StatusCode = exitStatus
 
-			// if the exit code is 1, it means the script failed. This type of failure
-			// is not recoverable automatically, so we set the status to ReconcileError.
-			if exitStatus == 1 {
-				cr.SetConditions(xpv1.ReconcileError(errors.Wrap(err, "Script failed with exit code 1.")))
-				return managed.ExternalObservation{}, errors.Wrap(err, "Script failed with exit code 1.")
+			// if the exit code is one of the failed exit codes (1 by default), it means
+			// the script failed. This type of failure is not recoverable automatically,
+			// so we set the status to ReconcileError.
+			if failed(cr.Spec.ForProvider, exitStatus) {
+				err = errors.Wrap(err, fmt.Sprintf("Script failed with exit code %d.", exitStatus))
+				cr.SetConditions(scriptFailed(err, stderr))
+				return managed.ExternalObservation{}, err
 			}
 
-			// If the exit code is 100, it means the resources does not exist yet.
-			if exitStatus == 100 {
+			// If the exit code is the missing exit code (100 by default), it means the
+			// resources does not exist yet.
+			if missing(cr.Spec.ForProvider, exitStatus) {
 				return managed.ExternalObservation{ResourceExists: false}, nil
 			}
 
@@ -191,18 +634,36 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 			// failed but the failure may be recoverable. The recovery should be handled by
 			// the update script. We don't return error here, as the update does not get called
 			// instead we update resource status fields with returned stdout, stderr and exit code.
+			if err := c.recordOutputs(cr, stdout); err != nil {
+				cr.SetConditions(xpv1.ReconcileError(err))
+				return managed.ExternalObservation{}, err
+			}
+			if evaluatesWhen(p) {
+				return c.observeWhen(cr, exitStatus, stdout, stderr)
+			}
+			if msg := statusMessage(stdout); msg != "" {
+				cr.SetConditions(xpv1.Unavailable().WithMessage(msg))
+			}
 			return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, nil
 		}
 
-		logger.Info(fmt.Sprintf("[%s] Observing was [okay]. Update the status.", mg.GetName()))
-		cr.Status.AtProvider.Stdout = stdout
-		cr.Status.AtProvider.Stderr = stderr
-		cr.SetConditions(xpv1.Available())
+		logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. Update the status.", cr.GetName()))
+		c.recordStdout(cr, stdout)
+		cr.Status.AtProvider.Stderr = limitOutput(c.params(cr), c.maxOutputBytes, stderr)
+		cr.Status.AtProvider.StatusCode = 0
+		if err := c.recordOutputs(cr, stdout); err != nil {
+			cr.SetConditions(xpv1.ReconcileError(err))
+			return managed.ExternalObservation{}, err
+		}
+		if evaluatesWhen(p) {
+			return c.observeWhen(cr, 0, stdout, stderr)
+		}
+		cr.SetConditions(withStatusMessage(xpv1.Available(), stdout))
 		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
 
 	}
 
-	logger.Info(fmt.Sprintf("[%s] Observing, no status check script.", mg.GetName()))
+	logger.Info(fmt.Sprintf("[%s] Observing, no status check script.", cr.GetName()))
 
 	return managed.ExternalObservation{
 		ResourceExists:    true,
@@ -211,6 +672,198 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	}, nil
 }
 
+// observeWhen decides whether the existing resource is up to date and ready
+// through the upToDateWhen and readyWhen expressions of the Script.
+func (c *external) observeWhen(cr *apisv1alpha1.Script, code int, stdout, stderr string) (managed.ExternalObservation, error) {
+	upToDate, ready, msg, err := evaluateWhen(c.params(cr), code, stdout, stderr)
+	if err != nil {
+		cr.SetConditions(xpv1.ReconcileError(err))
+		return managed.ExternalObservation{}, err
+	}
+	if ready {
+		cr.SetConditions(withStatusMessage(xpv1.Available(), stdout))
+	} else {
+		cr.SetConditions(withStatusMessage(xpv1.Unavailable().WithMessage(msg), stdout))
+	}
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: upToDate}, nil
+}
+
+// recordCheck records the host and the time of the last check of the
+// resource in the status of the Script.
+func recordCheck(cr *apisv1alpha1.Script, service sshv1alpha1.RemoteExecutor) {
+	if svc, ok := service.(remoter); ok {
+		cr.Status.AtProvider.Host = svc.Remote().Host
+	}
+	now := metav1.Now()
+	cr.Status.AtProvider.LastChecked = &now
+	cr.Status.AtProvider.NextCheckTime = nil
+}
+
+// scheduleCheck defers the next check of an up to date resource by the
+// statusCheckInterval of the Script.
+func scheduleCheck(cr *apisv1alpha1.Script, upToDate bool) {
+	a := &cr.Status.AtProvider
+	i := cr.Spec.ForProvider.StatusCheckInterval
+	if i == nil || !upToDate {
+		a.NextCheckTime = nil
+		return
+	}
+	next := metav1.NewTime(time.Now().Add(i.Duration))
+	a.NextCheckTime = &next
+	a.CheckedGeneration = cr.GetGeneration()
+}
+
+// deferFirstCheck defers the first check of a resource that was just created
+// by the createGracePeriod of the Script.
+func deferFirstCheck(cr *apisv1alpha1.Script) {
+	g := cr.Spec.ForProvider.CreateGracePeriod
+	if g == nil {
+		return
+	}
+	a := &cr.Status.AtProvider
+	next := metav1.NewTime(time.Now().Add(g.Duration))
+	a.NextCheckTime, a.CheckedGeneration, a.RetryTime = &next, cr.GetGeneration(), nil
+}
+
+// checkDue reports whether the state of the resource is checked on the host
+// by the next observation. A check is always due once the spec changed or
+// the Script is deleted, and once the retryTime requested by the
+// statusCheckScript or the reapplyInterval passed.
+func checkDue(cr *apisv1alpha1.Script) bool {
+	a := cr.Status.AtProvider
+	if a.NextCheckTime == nil || a.CheckedGeneration != cr.GetGeneration() || meta.WasDeleted(cr) {
+		return true
+	}
+	for _, t := range []*metav1.Time{a.RetryTime, reapplyTime(cr)} {
+		if t != nil && !time.Now().Before(t.Time) {
+			return true
+		}
+	}
+	return !time.Now().Before(a.NextCheckTime.Time)
+}
+
+// reapplyTime returns the time the reapplyInterval of the Script elapses,
+// nil if it has none.
+func reapplyTime(cr *apisv1alpha1.Script) *metav1.Time {
+	i, last := cr.Spec.ForProvider.ReapplyInterval, cr.Status.AtProvider.LastAppliedTime
+	if i == nil || last == nil {
+		return nil
+	}
+	t := metav1.NewTime(last.Add(i.Duration))
+	return &t
+}
+
+// reapplyDue reports whether the updateScript of an up to date resource is
+// executed because its reapplyInterval elapsed.
+func reapplyDue(cr *apisv1alpha1.Script) bool {
+	t := reapplyTime(cr)
+	return t != nil && !time.Now().Before(t.Time)
+}
+
+// A remoter is an executor that knows the host it is connected to.
+type remoter interface {
+	Remote() sshv1alpha1.Remote
+}
+
+// alias returns the alias of the host the service is connected to, empty if
+// it has none.
+func alias(service sshv1alpha1.RemoteExecutor) string {
+	if svc, ok := service.(remoter); ok {
+		return svc.Remote().Alias
+	}
+	return ""
+}
+
+// connectionDetails returns the endpoint and the host key fingerprint of the
+// host the service is connected to.
+func connectionDetails(service sshv1alpha1.RemoteExecutor) managed.ConnectionDetails {
+	svc, ok := service.(remoter)
+	if !ok {
+		return managed.ConnectionDetails{}
+	}
+	r := svc.Remote()
+	cd := managed.ConnectionDetails{
+		keyHost:     []byte(r.Host),
+		keyPort:     []byte(r.Port),
+		keyUsername: []byte(r.Username),
+	}
+	if r.HostKeyFingerprint != "" {
+		cd[keyHostKeyFingerprint] = []byte(r.HostKeyFingerprint)
+	}
+	return cd
+}
+
+// A checkResult is the state of the resource reported by the existsScript
+// and upToDateScript.
+type checkResult struct {
+	exists     bool
+	upToDate   bool
+	stdout     string
+	stderr     string
+	statusCode int
+}
+
+// separateChecks reports whether the existsScript and upToDateScript are
+// executed in place of the statusCheckScript.
+func separateChecks(p apisv1alpha1.ScriptParameters) bool {
+	return p.ExistsScript != "" || p.UpToDateScript != ""
+}
+
+// runChecks executes the existsScript followed by the upToDateScript. Errors
+// that do not carry an exit code are returned, since they say nothing about
+// the state of the resource.
+func runChecks(ctx context.Context, svc sshv1alpha1.RemoteExecutor, p apisv1alpha1.ScriptParameters) (checkResult, error) {
+	r := checkResult{exists: true, upToDate: true}
+	checks := []struct {
+		script string
+		result *bool
+	}{{p.ExistsScript, &r.exists}, {p.UpToDateScript, &r.upToDate}}
+
+	for _, chk := range checks {
+		if chk.script == "" {
+			continue
+		}
+		stdout, stderr, err := sshv1alpha1.ExecuteScript(ctx, svc, chk.script, p.Variables, SudoEnabled(p), ExecOptions(p)...)
+		r.stdout, r.stderr, r.statusCode = stdout, stderr, sshv1alpha1.ExitStatus(err)
+		if err == nil {
+			continue
+		}
+		if !sshv1alpha1.IsExitError(err) {
+			return r, errors.Wrap(err, errRunCheck)
+		}
+		// A resource that does not exist is never checked for drift.
+		*chk.result = false
+		break
+	}
+	return r, nil
+}
+
+// observeChecks maps the exit codes of the existsScript and upToDateScript
+// directly onto the observation of the remote state.
+func (c *external) observeChecks(ctx context.Context, cr *apisv1alpha1.Script) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+
+	r, err := runChecks(ctx, c.service, c.params(cr))
+	c.recordStdout(cr, r.stdout)
+	cr.Status.AtProvider.Stderr = limitOutput(c.params(cr), c.maxOutputBytes, r.stderr)
+	cr.Status.AtProvider.StatusCode = r.statusCode
+	if err != nil {
+		cr.SetConditions(scriptFailed(err, r.stderr))
+		return managed.ExternalObservation{}, err
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. Exists: %t, up to date: %t", cr.GetName(), r.exists, r.upToDate))
+	switch {
+	case !r.exists:
+		cr.SetConditions(xpv1.Unavailable().WithMessage("existsScript reported that the resource does not exist"))
+	case !r.upToDate:
+		cr.SetConditions(xpv1.Unavailable().WithMessage("upToDateScript reported that the resource is not up to date"))
+	default:
+		cr.SetConditions(xpv1.Available())
+	}
+	return managed.ExternalObservation{ResourceExists: r.exists, ResourceUpToDate: r.upToDate}, nil
+}
+
 func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
 	logger := log.FromContext(ctx).WithName("[CREATE]")
 	logger.Info(fmt.Sprintf("[%s] Creating init script...", mg.GetName()))
@@ -218,19 +871,56 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 	if !ok {
 		return managed.ExternalCreation{}, errors.New(errNotScript)
 	}
+	if !common.Policies(cr).ShouldCreate() {
+		return managed.ExternalCreation{}, errors.Errorf(errFmtNotAllowed, "init")
+	}
+
+	pending, err := pendingDependencies(ctx, c.kube, cr)
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	if len(pending) > 0 {
+		return managed.ExternalCreation{}, errors.New(errWaitingForDependencies)
+	}
+	if err := verifyPlatform(ctx, c.service, c.params(cr)); err != nil {
+		return managed.ExternalCreation{}, err
+	}
 
 	if cr.Spec.ForProvider.InitScript != "" {
-		_, _, err := sshv1alpha1.ExecuteScript(
-			ctx, c.service.(*ssh.Client), cr.Spec.ForProvider.InitScript, cr.Spec.ForProvider.Variables, cr.Spec.ForProvider.SudoEnabled)
+		var stdout, stderr string
+		err := retryInit(ctx, cr, func() (err error) {
+			stdout, stderr, err = c.run(ctx, cr, apisv1alpha1.OperationInit, cr.Spec.ForProvider.InitScript)
+			return err
+		})
+		if runOnce(cr) {
+			// The result of a RunOnce script is reported by every later observation.
+			c.recordStdout(cr, stdout)
+			cr.Status.AtProvider.Stderr = limitOutput(c.params(cr), c.maxOutputBytes, stderr)
+			cr.Status.AtProvider.StatusCode = sshv1alpha1.ExitStatus(err)
+		}
 		if err != nil {
 			// If the script fails, it means there is either an issue with the
 			// init script and the target is not ready yet, or the init script is not
 			// executed at all. By returning error here, the reconciler will not proceed,
 			// and user intervention is required.
-			cr.SetConditions(xpv1.ReconcileError(errors.Wrap(err, "Init Script failed.")))
+			cr.SetConditions(scriptFailed(errors.Wrap(err, "Init Script failed."), stderr))
 			return managed.ExternalCreation{}, err
 		}
 	}
+	cr.Status.AtProvider.ScriptHash = initScriptHash(cr.Spec.ForProvider)
+	cr.Status.AtProvider.Creations++
+	deferFirstCheck(cr)
+	if c.bootID != "" {
+		// The initScript establishes the state wiped by earlier reboots.
+		cr.Status.AtProvider.BootID = c.bootID
+	}
+	if err := c.writeMarker(ctx, cr); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	if token, ok := runNowPending(cr); ok {
+		// The initScript satisfies a run requested before the resource existed.
+		cr.Status.AtProvider.LastRunNowToken = token
+	}
 	return managed.ExternalCreation{
 		ConnectionDetails: managed.ConnectionDetails{},
 	}, nil
@@ -243,14 +933,48 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 	if !ok {
 		return managed.ExternalUpdate{}, errors.New(errNotScript)
 	}
+	if !common.Policies(cr).ShouldUpdate() {
+		return managed.ExternalUpdate{}, errors.Errorf(errFmtNotAllowed, "update")
+	}
+
+	if runOnce(cr) {
+		logger.Info(fmt.Sprintf("[%s] RunOnce script is never updated.", mg.GetName()))
+		return managed.ExternalUpdate{}, nil
+	}
+	if err := verifyPlatform(ctx, c.service, c.params(cr)); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	if token, ok := runNowPending(cr); ok {
+		if err := c.runNow(ctx, cr); err != nil {
+			return managed.ExternalUpdate{}, err
+		}
+		cr.Status.AtProvider.LastRunNowToken = token
+		return managed.ExternalUpdate{}, nil
+	}
+
+	hash := initScriptHash(cr.Spec.ForProvider)
+	if recreate(cr) && cr.Status.AtProvider.ScriptHash != hash {
+		if err := c.recreate(ctx, cr); err != nil {
+			return managed.ExternalUpdate{}, err
+		}
+		cr.Status.AtProvider.ScriptHash = hash
+		if c.bootID != "" {
+			cr.Status.AtProvider.BootID = c.bootID
+		}
+		return managed.ExternalUpdate{}, c.writeMarker(ctx, cr)
+	}
+
+	if c.rebooted {
+		return managed.ExternalUpdate{}, c.postReboot(ctx, cr)
+	}
 
 	if cr.Spec.ForProvider.UpdateScript != "" {
-		_, _, err := sshv1alpha1.ExecuteScript(
-			ctx, c.service.(*ssh.Client), cr.Spec.ForProvider.UpdateScript, cr.Spec.ForProvider.Variables, cr.Spec.ForProvider.SudoEnabled)
+		_, stderr, err := c.run(ctx, cr, apisv1alpha1.OperationUpdate, cr.Spec.ForProvider.UpdateScript)
 		if err != nil {
 			// the update script is supposed to return error if the update fails and is not recoverable.
 			// If we return error here, the reconcile will not proceed, and user intervention is required.
-			cr.SetConditions(xpv1.ReconcileError(errors.Wrap(err, "Update Script failed.")))
+			cr.SetConditions(scriptFailed(errors.Wrap(err, "Update Script failed."), stderr))
 			return managed.ExternalUpdate{}, err
 		}
 	}
@@ -261,6 +985,99 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 	}, nil
 }
 
+// run executes the script of the supplied operation, records the operation
+// in the status of the Script and reports it through an Event. The session
+// is recorded if the Script asks for it.
+func (c *external) run(ctx context.Context, cr *apisv1alpha1.Script, op apisv1alpha1.Operation, sc string) (string, string, error) {
+	p := c.params(cr)
+	if creates(op) {
+		p.Variables = withIdempotencyToken(cr, p.Variables)
+	}
+	started := time.Now()
+	opts, store := recorded(c.sink, c.recorder, cr, p, op, alias(c.service))
+	stdout, stderr, err := sshv1alpha1.ExecuteScript(ctx, c.service, sc, p.Variables, SudoEnabled(p), opts...)
+	store(ctx)
+	cr.Status.AtProvider.LastOperation = op
+	if err == nil && op != apisv1alpha1.OperationCleanup {
+		now := metav1.Now()
+		cr.Status.AtProvider.LastAppliedTime = &now
+	}
+	// The result of an operation is always checked by the next observation.
+	cr.Status.AtProvider.NextCheckTime, cr.Status.AtProvider.StatusCachedUntil = nil, nil
+	c.recorder.Event(cr, executionEvent(execution{operation: op, host: alias(c.service), duration: time.Since(started), stderr: stderr, err: err}))
+	if op != apisv1alpha1.OperationCleanup {
+		recordResult(cr, err != nil)
+	}
+	return stdout, stderr, err
+}
+
+// runNow executes the updateScript, or the initScript if no updateScript is
+// set, as requested through the run-now annotation.
+func (c *external) runNow(ctx context.Context, cr *apisv1alpha1.Script) error {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	p := cr.Spec.ForProvider
+	sc := p.UpdateScript
+	if sc == "" {
+		sc = p.InitScript
+	}
+	if sc == "" {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("[%s] Running script requested through annotation...", cr.GetName()))
+	if _, stderr, err := c.run(ctx, cr, apisv1alpha1.OperationRunNow, sc); err != nil {
+		cr.SetConditions(scriptFailed(errors.Wrap(err, "Requested Script failed."), stderr))
+		return err
+	}
+	return nil
+}
+
+// retryInit executes the initScript through the supplied function until it
+// succeeds, as many times as the initRetry of the Script allows. It returns
+// the error of the last attempt, and stops early once the context is done.
+func retryInit(ctx context.Context, cr *apisv1alpha1.Script, run func() error) error {
+	attempts, delay := int32(1), time.Duration(0)
+	if r := cr.Spec.ForProvider.InitRetry; r != nil && !runOnce(cr) {
+		attempts = r.Attempts
+		if r.Delay != nil {
+			delay = r.Delay.Duration
+		}
+	}
+	err := run()
+	for i := int32(1); i < attempts && err != nil; i++ {
+		log.FromContext(ctx).WithName("[CREATE]").Info(fmt.Sprintf("[%s] Init script failed, attempt %d of %d. Retrying in %s.", cr.GetName(), i, attempts, delay))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		err = run()
+	}
+	return err
+}
+
+// recreate runs the cleanupScript followed by the initScript, in place of the
+// updateScript, after the rendered initScript changed.
+func (c *external) recreate(ctx context.Context, cr *apisv1alpha1.Script) error {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	p := cr.Spec.ForProvider
+	if p.CleanupScript != "" {
+		logger.Info(fmt.Sprintf("[%s] Recreating, running cleanup script...", cr.GetName()))
+		if _, stderr, err := c.run(ctx, cr, apisv1alpha1.OperationRecreate, p.CleanupScript); err != nil {
+			cr.SetConditions(scriptFailed(errors.Wrap(err, "Cleanup Script failed."), stderr))
+			return err
+		}
+	}
+	if p.InitScript != "" {
+		logger.Info(fmt.Sprintf("[%s] Recreating, running init script...", cr.GetName()))
+		if _, stderr, err := c.run(ctx, cr, apisv1alpha1.OperationRecreate, p.InitScript); err != nil {
+			cr.SetConditions(scriptFailed(errors.Wrap(err, "Init Script failed."), stderr))
+			return err
+		}
+	}
+	cr.Status.AtProvider.Creations++
+	return nil
+}
+
 func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
 	logger := log.FromContext(ctx).WithName("[DELETE]")
 	logger.Info(fmt.Sprintf("[%s] Deleting...", mg.GetName()))
@@ -268,16 +1085,207 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
 	if !ok {
 		return errors.New(errNotScript)
 	}
+	if !common.Policies(cr).ShouldDelete() {
+		return errors.Errorf(errFmtNotAllowed, "cleanup")
+	}
 
-	if cr.Spec.ForProvider.CleanupScript != "" {
-		_, _, err := sshv1alpha1.ExecuteScript(
-			ctx, c.service.(*ssh.Client), cr.Spec.ForProvider.CleanupScript, cr.Spec.ForProvider.Variables, cr.Spec.ForProvider.SudoEnabled)
-
-		if err != nil {
+	// The Observe of this reconcile evaluated skipIf on the host.
+	if runsCleanup(cr) && !cr.Status.AtProvider.Skipped {
+		if err := c.cleanup(ctx, cr); err != nil {
 			logger.Info(fmt.Sprintf("[%s] Deleting failed.", mg.GetName()))
+			if cleanupFailed(cr) {
+				logger.Info(fmt.Sprintf("[%s] Cleanup abandoned after %d attempts.", mg.GetName(), cr.Status.AtProvider.CleanupAttempts))
+				return nil
+			}
 			return err
 		}
+		return c.removeMarker(ctx, cr)
 	}
 
 	return nil
 }
+
+// Disconnect closes the connection to the host, if any.
+func (c *external) Disconnect(_ context.Context) error {
+	if c.service == nil {
+		return nil
+	}
+	return c.service.Close()
+}
+
+// suspended reports whether the reconciliation of the Script is suspended.
+// Deletion is never suspended so that the cleanupScript still runs.
+func suspended(cr *apisv1alpha1.Script) bool {
+	return cr.Spec.Suspend && !meta.WasDeleted(cr)
+}
+
+// halted reports whether the Script executes no script because it reached
+// its maxConsecutiveFailures, and no resumption was requested since. Deletion
+// is never halted so that the cleanupScript still runs.
+func halted(cr *apisv1alpha1.Script) bool {
+	max := cr.Spec.ForProvider.MaxConsecutiveFailures
+	if max == nil || cr.Status.AtProvider.ConsecutiveFailures < *max || meta.WasDeleted(cr) {
+		return false
+	}
+	token := cr.GetAnnotations()[apisv1alpha1.AnnotationKeyResume]
+	resumed := token != "" && token != cr.Status.AtProvider.LastResumeToken
+	return !resumed && cr.GetGeneration() == cr.Status.AtProvider.HaltedGeneration
+}
+
+// recordResult counts the consecutive failed executions of the scripts of
+// the Script and halts it once it reaches its maxConsecutiveFailures.
+func recordResult(cr *apisv1alpha1.Script, failed bool) {
+	a := &cr.Status.AtProvider
+	if !failed {
+		a.ConsecutiveFailures = 0
+		return
+	}
+	a.ConsecutiveFailures++
+	if max := cr.Spec.ForProvider.MaxConsecutiveFailures; max != nil && a.ConsecutiveFailures >= *max {
+		// A resume token set before the Script was halted does not resume it.
+		a.HaltedGeneration = cr.GetGeneration()
+		a.LastResumeToken = cr.GetAnnotations()[apisv1alpha1.AnnotationKeyResume]
+		cr.SetConditions(apisv1alpha1.Halted(a.ConsecutiveFailures))
+	}
+}
+
+// resume resets the consecutive failures of a halted Script once its
+// resumption was requested through the resume annotation or a spec change.
+func resume(cr *apisv1alpha1.Script) {
+	if cr.GetCondition(apisv1alpha1.TypeHalted).Status != corev1.ConditionTrue {
+		return
+	}
+	a := &cr.Status.AtProvider
+	a.ConsecutiveFailures = 0
+	a.HaltedGeneration = 0
+	a.LastResumeToken = cr.GetAnnotations()[apisv1alpha1.AnnotationKeyResume]
+	cr.SetConditions(apisv1alpha1.HaltResumed())
+}
+
+// runOnce reports whether the initScript of the Script is executed only once.
+func runOnce(cr *apisv1alpha1.Script) bool {
+	return cr.Spec.ForProvider.ExecutionPolicy == apisv1alpha1.ExecutionPolicyRunOnce
+}
+
+// runOnceFinished reports whether the single execution of a RunOnce Script
+// has already happened, regardless of its outcome.
+func runOnceFinished(cr *apisv1alpha1.Script) bool {
+	return runOnce(cr) && (!meta.GetExternalCreateSucceeded(cr).IsZero() || !meta.GetExternalCreateFailed(cr).IsZero())
+}
+
+// ttlRemaining returns the time left until the TTL of a successfully
+// finished RunOnce Script expires. It returns false if no TTL applies.
+func ttlRemaining(cr *apisv1alpha1.Script) (time.Duration, bool) {
+	ttl := cr.Spec.ForProvider.TTLSecondsAfterFinished
+	finished := meta.GetExternalCreateSucceeded(cr)
+	if !runOnce(cr) || ttl == nil || finished.IsZero() {
+		return 0, false
+	}
+	return time.Until(finished.Add(time.Duration(*ttl) * time.Second)), true
+}
+
+// ttlExpired reports whether the TTL of a successfully finished RunOnce
+// Script has expired.
+func ttlExpired(cr *apisv1alpha1.Script) bool {
+	remaining, ok := ttlRemaining(cr)
+	return ok && remaining <= 0
+}
+
+// runsCleanup reports whether the cleanupScript is executed when the Script
+// is deleted. Scripts whose TTL expired are removed without cleanup.
+func runsCleanup(cr *apisv1alpha1.Script) bool {
+	p := cr.Spec.ForProvider
+	if p.CleanupScript == "" || p.CleanupPolicy == apisv1alpha1.CleanupPolicySkip || ttlExpired(cr) {
+		return false
+	}
+	return !cleanupAbandoned(cr)
+}
+
+// cleanupAbandoned reports whether a BestEffort cleanup, or a cleanup whose
+// deleteRetry orphans the resource, has failed often enough for the deletion
+// to proceed.
+func cleanupAbandoned(cr *apisv1alpha1.Script) bool {
+	p := cr.Spec.ForProvider
+	if orphaned(cr) {
+		return true
+	}
+	if p.CleanupPolicy != apisv1alpha1.CleanupPolicyBestEffort {
+		return false
+	}
+	maxAttempts := int32(3)
+	if p.MaxCleanupAttempts != nil {
+		maxAttempts = *p.MaxCleanupAttempts
+	}
+	return cr.Status.AtProvider.CleanupAttempts >= maxAttempts
+}
+
+// cleanupFailed records a failed cleanup attempt and reports whether the
+// cleanup has been abandoned as a result.
+func cleanupFailed(cr *apisv1alpha1.Script) bool {
+	cr.Status.AtProvider.CleanupAttempts++
+	now := metav1.Now()
+	cr.Status.AtProvider.LastCleanupAttemptTime = &now
+	if orphaned(cr) {
+		cr.SetConditions(apisv1alpha1.OrphanedOnDelete(cr.Status.AtProvider.CleanupAttempts))
+	}
+	return cleanupAbandoned(cr)
+}
+
+// pollInterval applies the pollInterval of the Script in place of the one of
+// the provider and requeues Scripts whose next status check, the check
+// requested through RETRY_AFTER or the next reapply is due earlier.
+// It stops the periodic reconciliation of Scripts whose TTL expired, and
+// requeues Scripts whose TTL is about to expire in time. Expired Scripts are
+// still reconciled when their spec changes.
+func pollInterval(mg resource.Managed, pollInterval time.Duration) time.Duration {
+	cr, ok := mg.(*apisv1alpha1.Script)
+	if !ok {
+		return pollInterval
+	}
+	if p := cr.Spec.ForProvider.PollInterval; p != nil {
+		pollInterval = p.Duration
+	}
+	for _, next := range []*metav1.Time{cr.Status.AtProvider.NextCheckTime, cr.Status.AtProvider.RetryTime, reapplyTime(cr)} {
+		if next == nil {
+			continue
+		}
+		if until := time.Until(next.Time); until > 0 && until < pollInterval {
+			pollInterval = until
+		}
+	}
+	remaining, ok := ttlRemaining(cr)
+	switch {
+	case !ok:
+		return pollInterval
+	case remaining <= 0:
+		return 0
+	case remaining < pollInterval:
+		return remaining
+	}
+	return pollInterval
+}
+
+// observeRunOnce reports the recorded result of the initScript of a RunOnce
+// Script without touching the remote host.
+func observeRunOnce(cr *apisv1alpha1.Script) managed.ExternalObservation {
+	if meta.GetExternalCreateSucceeded(cr).IsZero() {
+		cr.SetConditions(xpv1.Unavailable().WithMessage(fmt.Sprintf(
+			"initScript failed with exit code %d, recreate the Script to run it again", cr.Status.AtProvider.StatusCode)))
+	} else {
+		cr.SetConditions(xpv1.Available())
+	}
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}
+}
+
+// runNowPending returns the token of the run-now annotation if it has not
+// been executed yet.
+func runNowPending(cr *apisv1alpha1.Script) (string, bool) {
+	token := cr.GetAnnotations()[apisv1alpha1.AnnotationKeyRunNow]
+	return token, token != "" && token != cr.Status.AtProvider.LastRunNowToken
+}
+
+// recreate reports whether a change of the rendered initScript is applied by
+// running the cleanupScript and the initScript instead of the updateScript.
+func recreate(cr *apisv1alpha1.Script) bool {
+	return cr.Spec.ForProvider.UpdateStrategy == apisv1alpha1.UpdateStrategyRecreate
+}
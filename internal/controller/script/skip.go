@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/expr"
+)
+
+const errSkipIf = "cannot evaluate skipIf"
+
+// skipIfVariables returns the variables a skipIf expression is evaluated
+// with: the facts of the host, the name of the ProviderConfig or Host
+// identifying it, and the variables of the Script.
+func skipIfVariables(facts map[string]any, host string, vars []apisv1alpha1.Variable) map[string]any {
+	return map[string]any{"facts": facts, "host": host, "vars": variables(vars)}
+}
+
+// variables returns the values of the variables of a Script by name, as
+// referenced by vars in expressions.
+func variables(vars []apisv1alpha1.Variable) map[string]any {
+	v := make(map[string]any, len(vars))
+	for _, vr := range vars {
+		v[vr.Name] = vr.Value
+	}
+	return v
+}
+
+// skipped reports whether the skipIf expression of the Script is true on the
+// host of the executor. The facts of the host are only gathered for Scripts
+// setting skipIf.
+func skipped(ctx context.Context, x sshv1alpha1.RemoteExecutor, p apisv1alpha1.ScriptParameters, host string) (bool, error) {
+	if p.SkipIf == "" {
+		return false, nil
+	}
+	if x == nil {
+		return false, errors.New(errNotConnected)
+	}
+	prg, err := expr.Compile(p.SkipIf)
+	if err != nil {
+		return false, errors.Wrap(err, errSkipIf)
+	}
+	facts, err := sshv1alpha1.GatherFacts(ctx, x)
+	if err != nil {
+		return false, errors.Wrap(err, errSkipIf)
+	}
+	skip, err := prg.EvalBool(skipIfVariables(facts, host, p.Variables))
+	return skip, errors.Wrap(err, errSkipIf)
+}
+
+// providerConfigName returns the name of the ProviderConfig identifying the
+// host of a Script targeting a single host.
+func providerConfigName(cr *apisv1alpha1.Script) string {
+	if ref := cr.GetProviderConfigReference(); ref != nil {
+		return ref.Name
+	}
+	return ""
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+func withSkipIf(expr string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.SkipIf = expr }
+}
+
+func withSkipped() scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Status.AtProvider.Skipped = true }
+}
+
+// factsExecutor returns a fake executor of a host running the supplied
+// kernel, whose scripts succeed.
+func factsExecutor(kernel string) *sshfake.Executor {
+	return &sshfake.Executor{MockRun: func(script string) (string, string, error) {
+		if script == factsScript {
+			return "hostname=web-1\nkernel=" + kernel + "\nos=ubuntu\ncpus=4\n", "", nil
+		}
+		return "", "", nil
+	}}
+}
+
+// factsScript is the command gathering the facts of a host, as recorded by
+// the fake executor.
+var factsScript = func() string {
+	x := &sshfake.Executor{}
+	_, _ = sshv1alpha1.GatherFacts(context.Background(), x)
+	return x.Scripts()[0]
+}()
+
+func TestObserveSkipIf(t *testing.T) {
+	type want struct {
+		o       managed.ExternalObservation
+		skipped bool
+		status  corev1.ConditionStatus
+		scripts []string
+		err     bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     *apisv1alpha1.Script
+		kernel string
+		want   want
+	}{
+		"Skipped": {
+			reason: "A Script whose skipIf is true on its host should be up to date without running its statusCheckScript.",
+			mg:     script(withStatusCheck("uname -r | grep -q 6.8"), withSkipIf(`facts.kernel.startsWith("6.8") && facts.cpus >= 2`)),
+			kernel: "6.8.0-45-generic",
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				skipped: true,
+				status:  corev1.ConditionTrue,
+				scripts: []string{factsScript},
+			},
+		},
+		"NotSkipped": {
+			reason: "A Script whose skipIf is false on its host should run its statusCheckScript and no longer be reported as skipped.",
+			mg:     script(withStatusCheck("uname -r | grep -q 6.8"), withSkipIf(`facts.kernel.startsWith("6.8")`), withSkipped()),
+			kernel: "5.15.0-91-generic",
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				status:  corev1.ConditionFalse,
+				scripts: []string{factsScript, "uname -r | grep -q 6.8"},
+			},
+		},
+		"NoSkipIf": {
+			reason: "The facts of the host should not be gathered for a Script without skipIf.",
+			mg:     script(withStatusCheck("uname -r | grep -q 6.8")),
+			want: want{
+				o:       managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				scripts: []string{"uname -r | grep -q 6.8"},
+			},
+		},
+		"EvaluationError": {
+			reason: "We should return an error, and run no script, if skipIf cannot be evaluated on the host.",
+			mg:     script(withStatusCheck("uname -r | grep -q 6.8"), withSkipIf(`facts.gpu == "a100"`)),
+			want: want{
+				scripts: []string{factsScript},
+				err:     true,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			x := factsExecutor(tc.kernel)
+			e := external{service: x}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if gotErr := err != nil; gotErr != tc.want.err {
+				t.Fatalf("\n%s\ne.Observe(...): error %v, want error %t", tc.reason, err, tc.want.err)
+			}
+			if diff := cmp.Diff(tc.want.o, got, cmpopts.IgnoreFields(managed.ExternalObservation{}, "ConnectionDetails")); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.skipped, tc.mg.Status.AtProvider.Skipped); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want skipped, +got skipped:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.status, tc.mg.GetCondition(apisv1alpha1.TypeSkipped).Status); tc.want.status != "" && diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want Skipped condition, +got Skipped condition:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.scripts, x.Scripts()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want scripts, +got scripts:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDeleteSkipIf(t *testing.T) {
+	x := factsExecutor("6.8.0-45-generic")
+	cr := script(withDeleted(), withCleanup("rm -f /etc/sysctl.d/90-tuning.conf", apisv1alpha1.CleanupPolicyRun),
+		withStatusCheck("test -f /etc/sysctl.d/90-tuning.conf"), withSkipIf(`facts.kernel.startsWith("6.8")`))
+	cr.SetDeletionPolicy(xpv1.DeletionDelete)
+	e := external{service: x, recorder: event.NewNopRecorder()}
+	if _, err := e.Observe(context.Background(), cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if err := e.Delete(context.Background(), cr); err != nil {
+		t.Fatalf("e.Delete(...): %v", err)
+	}
+	if diff := cmp.Diff([]string{factsScript}, x.Scripts()); diff != "" {
+		t.Errorf("e.Delete(...): the cleanupScript should not run on a skipped host: -want scripts, +got scripts:\n%s\n", diff)
+	}
+}
+
+func TestFanOutSkipIf(t *testing.T) {
+	hosts := []string{"Host/web-1", "Host/web-2"}
+	clients := map[string]*sshfake.Executor{
+		"Host/web-1": factsExecutor("6.8.0-45-generic"),
+		"Host/web-2": factsExecutor("5.15.0-91-generic"),
+	}
+	e := &fanOutExternal{
+		recorder: event.NewNopRecorder(),
+		hosts:    hosts,
+		clients:  map[string]sshv1alpha1.RemoteExecutor{},
+		results:  map[string]apisv1alpha1.HostStatus{},
+		observed: map[string]checkResult{},
+	}
+	for name, x := range clients {
+		e.clients[name] = x
+	}
+	cr := script(withHosts("web-1", "web-2"), withStatusCheck("test -f /etc/sysctl.d/90-tuning.conf"),
+		withSkipIf(`host == "web-1" && facts.kernel.startsWith("6.8")`))
+
+	got, err := e.Observe(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if diff := cmp.Diff(managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, got); diff != "" {
+		t.Errorf("e.Observe(...): -want, +got:\n%s\n", diff)
+	}
+	want := []apisv1alpha1.HostStatus{
+		{Kind: "Host", Name: "web-1", Ready: true, Skipped: true},
+		{Kind: "Host", Name: "web-2", Ready: true},
+	}
+	if diff := cmp.Diff(want, cr.Status.AtProvider.Hosts, cmpopts.IgnoreFields(apisv1alpha1.HostStatus{}, "OutputDigest")); diff != "" {
+		t.Errorf("e.Observe(...): -want hosts, +got hosts:\n%s\n", diff)
+	}
+	if c := cr.GetCondition(xpv1.TypeReady); c.Status != corev1.ConditionTrue {
+		t.Errorf("e.Observe(...): a Script whose hosts are ready or skipped should be Ready, got %s", c.Status)
+	}
+	for name, x := range clients {
+		ran := false
+		for _, s := range x.Scripts() {
+			ran = ran || strings.HasPrefix(s, "test -f")
+		}
+		if ran != (name == "Host/web-2") {
+			t.Errorf("e.Observe(...): statusCheckScript executed on %s: %t, want %t", name, ran, name == "Host/web-2")
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/expr"
+)
+
+const (
+	errUpToDateWhen  = "cannot evaluate upToDateWhen"
+	errReadyWhen     = "cannot evaluate readyWhen"
+	errExpectedRegex = "cannot compile the regex of expectedOutput"
+
+	msgUpToDateWhenFalse = "upToDateWhen is false"
+	msgReadyWhenFalse    = "readyWhen is false"
+	msgUnexpectedOutput  = "stdout does not match expectedOutput"
+)
+
+// outputLine matches the name=value lines of the stdout of a script.
+var outputLine = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+
+// parseOutputs returns the values of the name=value lines of the supplied
+// stdout. The last line of a name wins.
+func parseOutputs(stdout string) map[string]any {
+	outputs := map[string]any{}
+	for _, line := range strings.Split(stdout, "\n") {
+		if m := outputLine.FindStringSubmatch(strings.TrimRight(line, "\r")); m != nil {
+			outputs[m[1]] = m[2]
+		}
+	}
+	return outputs
+}
+
+// evaluatesWhen reports whether the state of the resource is decided by the
+// upToDateWhen or readyWhen expressions of the Script, or its expectedOutput.
+func evaluatesWhen(p apisv1alpha1.ScriptParameters) bool {
+	return p.UpToDateWhen != "" || p.ReadyWhen != "" || p.ExpectedOutput != nil
+}
+
+// evaluateWhen evaluates the upToDateWhen and readyWhen expressions of the
+// Script against the result of its statusCheckScript. Without upToDateWhen
+// the resource is up to date if the exit code is zero, in either case only if
+// its stdout matches the expectedOutput, and without readyWhen it is ready if
+// it is up to date. The returned message explains why the resource is not
+// ready, if it is not. The outputs are read in the outputFormat of the Script.
+func evaluateWhen(p apisv1alpha1.ScriptParameters, code int, stdout, stderr string) (upToDate, ready bool, msg string, err error) {
+	outs, err := outputs(p, stdout)
+	if err != nil {
+		return false, false, "", err
+	}
+	vs := map[string]any{
+		"exitCode": int64(code),
+		"stdout":   stdout,
+		"stderr":   stderr,
+		"outputs":  outs,
+		"vars":     variables(p.Variables),
+	}
+
+	upToDate, msg = code == 0, ""
+	if p.UpToDateWhen != "" {
+		if upToDate, err = evalBool(p.UpToDateWhen, vs); err != nil {
+			return false, false, "", errors.Wrap(err, errUpToDateWhen)
+		}
+		msg = msgUpToDateWhenFalse
+	}
+	if upToDate && p.ExpectedOutput != nil {
+		if upToDate, err = expectedOutput(p, stdout); err != nil {
+			return false, false, "", err
+		}
+		msg = msgUnexpectedOutput
+	}
+
+	ready = upToDate
+	if p.ReadyWhen != "" {
+		if ready, err = evalBool(p.ReadyWhen, vs); err != nil {
+			return false, false, "", errors.Wrap(err, errReadyWhen)
+		}
+		msg = msgReadyWhenFalse
+	}
+	if ready {
+		msg = ""
+	}
+	return upToDate, ready, msg, nil
+}
+
+// expectedOutput reports whether the supplied stdout without its trailing
+// newlines is the exact expectedOutput of the Script, with its variables
+// replaced, or matches its regex.
+func expectedOutput(p apisv1alpha1.ScriptParameters, stdout string) (bool, error) {
+	stdout = strings.TrimRight(stdout, "\r\n")
+	if p.ExpectedOutput.Regex == "" {
+		return stdout == sshv1alpha1.RenderScript(p.ExpectedOutput.Exact, p.Variables, p.Templating), nil
+	}
+	re, err := regexp.Compile(p.ExpectedOutput.Regex)
+	if err != nil {
+		return false, errors.Wrap(err, errExpectedRegex)
+	}
+	return re.MatchString(stdout), nil
+}
+
+func evalBool(src string, vars map[string]any) (bool, error) {
+	prg, err := expr.Compile(src)
+	if err != nil {
+		return false, err
+	}
+	return prg.EvalBool(vars)
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package script
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+func withWhen(upToDate, ready string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.UpToDateWhen = upToDate
+		cr.Spec.ForProvider.ReadyWhen = ready
+	}
+}
+
+func withExpectedOutput(o apisv1alpha1.ExpectedOutput) scriptModifier {
+	return func(cr *apisv1alpha1.Script) { cr.Spec.ForProvider.ExpectedOutput = &o }
+}
+
+func withVariable(name, value string) scriptModifier {
+	return func(cr *apisv1alpha1.Script) {
+		cr.Spec.ForProvider.Variables = append(cr.Spec.ForProvider.Variables, apisv1alpha1.Variable{Name: name, Value: value})
+	}
+}
+
+// outputExecutor returns a fake executor whose scripts print the supplied
+// stdout and exit with the supplied code.
+func outputExecutor(stdout string, code int) *sshfake.Executor {
+	return &sshfake.Executor{MockRun: func(string) (string, string, error) {
+		if code != 0 {
+			return stdout, "", sshfake.ExitError(code)
+		}
+		return stdout, "", nil
+	}}
+}
+
+func TestParseOutputs(t *testing.T) {
+	got := parseOutputs("installed\nversion=1.2.3\r\nchannel=stable\nnot an=output\nversion=1.2.4\nEMPTY=\n")
+	want := map[string]any{"version": "1.2.4", "channel": "stable", "EMPTY": ""}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseOutputs(...): -want, +got:\n%s\n", diff)
+	}
+}
+
+func TestObserveWhen(t *testing.T) {
+	type want struct {
+		o      managed.ExternalObservation
+		ready  corev1.ConditionStatus
+		reason xpv1.ConditionReason
+		err    bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     *apisv1alpha1.Script
+		stdout string
+		code   int
+		want   want
+	}{
+		"UpToDate": {
+			reason: "A resource whose upToDateWhen is true should be up to date and Ready.",
+			mg:     script(withStatusCheck("app --version"), withVariable("VERSION", "1.2.3"), withWhen(`outputs.version == vars.VERSION`, "")),
+			stdout: "version=1.2.3\n",
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				ready: corev1.ConditionTrue,
+			},
+		},
+		"NotUpToDate": {
+			reason: "A resource whose upToDateWhen is false should not be up to date, even though its statusCheckScript succeeds.",
+			mg:     script(withStatusCheck("app --version"), withVariable("VERSION", "1.2.4"), withWhen(`outputs.version == vars.VERSION`, "")),
+			stdout: "version=1.2.3\n",
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				ready: corev1.ConditionFalse,
+			},
+		},
+		"CustomExitCode": {
+			reason: "The expressions should decide the state of a resource whose statusCheckScript exits with a custom code.",
+			mg:     script(withStatusCheck("app --version"), withWhen(`exitCode == 3 && stdout.contains("degraded")`, `false`)),
+			stdout: "degraded\n",
+			code:   3,
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				ready: corev1.ConditionFalse,
+			},
+		},
+		"ReadyWhen": {
+			reason: "A resource whose readyWhen is true should be Ready, and up to date as its exit code is zero.",
+			mg:     script(withStatusCheck("systemctl is-active app"), withWhen("", `outputs.state == "active"`)),
+			stdout: "state=active\n",
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				ready: corev1.ConditionTrue,
+			},
+		},
+		"Missing": {
+			reason: "The expressions should not be evaluated for a resource reported as missing.",
+			mg:     script(withStatusCheck("app --version"), withWhen(`outputs.version == "1.2.3"`, "")),
+			code:   apisv1alpha1.DefaultExitCodeMissing,
+			want: want{
+				o: managed.ExternalObservation{ResourceExists: false},
+			},
+		},
+		"ExpectedOutput": {
+			reason: "A resource whose stdout is the expectedOutput, with its variables replaced, should be up to date.",
+			mg:     script(withStatusCheck("app --version"), withVariable("VERSION", "1.2.3"), withExpectedOutput(apisv1alpha1.ExpectedOutput{Exact: "{{VERSION}}"})),
+			stdout: "1.2.3\n",
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				ready: corev1.ConditionTrue,
+			},
+		},
+		"UnexpectedOutput": {
+			reason: "A resource whose stdout differs from the expectedOutput should not be up to date.",
+			mg:     script(withStatusCheck("app --version"), withExpectedOutput(apisv1alpha1.ExpectedOutput{Exact: "1.2.4"})),
+			stdout: "1.2.3\n",
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				ready: corev1.ConditionFalse,
+			},
+		},
+		"ExpectedOutputRegex": {
+			reason: "A resource whose stdout matches the regex of the expectedOutput should be up to date.",
+			mg:     script(withStatusCheck("app --version"), withExpectedOutput(apisv1alpha1.ExpectedOutput{Regex: `^1\.2\.[0-9]+$`})),
+			stdout: "1.2.7\n",
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				ready: corev1.ConditionTrue,
+			},
+		},
+		"ExpectedOutputFailedCheck": {
+			reason: "A resource whose statusCheckScript exits with a custom code should not be up to date, even though its stdout is expected.",
+			mg:     script(withStatusCheck("app --version"), withExpectedOutput(apisv1alpha1.ExpectedOutput{Exact: "1.2.3"})),
+			stdout: "1.2.3\n",
+			code:   3,
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				ready: corev1.ConditionFalse,
+			},
+		},
+		"InvalidRegex": {
+			reason: "We should return an error if the regex of the expectedOutput cannot be compiled.",
+			mg:     script(withStatusCheck("app --version"), withExpectedOutput(apisv1alpha1.ExpectedOutput{Regex: `1.2.(`})),
+			stdout: "1.2.3\n",
+			want: want{
+				ready:  corev1.ConditionFalse,
+				reason: xpv1.ReasonReconcileError,
+				err:    true,
+			},
+		},
+		"EvaluationError": {
+			reason: "We should return an error if an expression cannot be evaluated against the result of the statusCheckScript.",
+			mg:     script(withStatusCheck("app --version"), withWhen(`outputs.version == "1.2.3"`, "")),
+			stdout: "1.2.3\n",
+			want: want{
+				ready:  corev1.ConditionFalse,
+				reason: xpv1.ReasonReconcileError,
+				err:    true,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: outputExecutor(tc.stdout, tc.code)}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if gotErr := err != nil; gotErr != tc.want.err {
+				t.Fatalf("\n%s\ne.Observe(...): error %v, want error %t", tc.reason, err, tc.want.err)
+			}
+			if diff := cmp.Diff(tc.want.o, got, cmpopts.IgnoreFields(managed.ExternalObservation{}, "ConnectionDetails")); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if tc.want.ready != "" && !tc.want.err {
+				if diff := cmp.Diff(tc.want.ready, tc.mg.GetCondition(xpv1.TypeReady).Status); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want Ready, +got Ready:\n%s\n", tc.reason, diff)
+				}
+			}
+			if tc.want.reason != "" {
+				if diff := cmp.Diff(tc.want.reason, tc.mg.GetCondition(xpv1.TypeSynced).Reason); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want Synced reason, +got Synced reason:\n%s\n", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestFanOutWhen(t *testing.T) {
+	clients := map[string]*sshfake.Executor{
+		"Host/web-1": outputExecutor("version=1.2.3\n", 0),
+		"Host/web-2": outputExecutor("version=1.2.2\n", 0),
+		"Host/web-3": outputExecutor("no version\n", 0),
+	}
+	e := &fanOutExternal{
+		recorder: event.NewNopRecorder(),
+		hosts:    []string{"Host/web-1", "Host/web-2", "Host/web-3"},
+		clients:  map[string]sshv1alpha1.RemoteExecutor{},
+		results:  map[string]apisv1alpha1.HostStatus{},
+		observed: map[string]checkResult{},
+	}
+	for name, x := range clients {
+		e.clients[name] = x
+	}
+	cr := script(withHosts("web-1", "web-2", "web-3"), withStatusCheck("app --version"), withVariable("VERSION", "1.2.3"),
+		withWhen(`outputs.version == vars.VERSION`, ""))
+
+	got, err := e.Observe(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if diff := cmp.Diff(managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, got); diff != "" {
+		t.Errorf("e.Observe(...): -want, +got:\n%s\n", diff)
+	}
+	want := []apisv1alpha1.HostStatus{
+		{Kind: "Host", Name: "web-1", Ready: true},
+		{Kind: "Host", Name: "web-2", Message: msgUpToDateWhenFalse},
+		{Kind: "Host", Name: "web-3", Message: "cannot evaluate upToDateWhen: no such key: version"},
+	}
+	if diff := cmp.Diff(want, cr.Status.AtProvider.Hosts, cmpopts.IgnoreFields(apisv1alpha1.HostStatus{}, "OutputDigest")); diff != "" {
+		t.Errorf("e.Observe(...): -want hosts, +got hosts:\n%s\n", diff)
+	}
+	if diff := cmp.Diff([]string{"Host/web-2"}, e.connected(func(r checkResult) bool { return r.exists && !r.upToDate })); diff != "" {
+		t.Errorf("e.Observe(...): -want stale hosts, +got stale hosts:\n%s\n", diff)
+	}
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scriptset
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errFmtHostsNotReady = "%d of %d hosts ready"
+	errFmtHostsFailed   = "pipeline failed on %d of %d hosts"
+)
+
+// connectHosts produces an ExternalClient connected to every host targeted by
+// the ScriptSet. Hosts that cannot be reached are reported as not ready.
+func (c *connector) connectHosts(ctx context.Context, cr *apisv1alpha1.ScriptSet) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	hosts, err := common.ResolveHosts(ctx, c.kube, cr.Spec.ForProvider.Hosts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &fanOutExternal{
+		hosts:   hosts,
+		clients: map[string]sshv1alpha1.RemoteExecutor{},
+		results: map[string]apisv1alpha1.ScriptSetHostStatus{},
+	}
+	for _, st := range cr.Status.AtProvider.Hosts {
+		e.results[common.HostKey(st.Kind, st.Name)] = st
+	}
+
+	// A ScriptSet whose cleanup finished needs no connection.
+	if meta.WasDeleted(cr) && deleting(cr) {
+		return e, nil
+	}
+
+	common.ForEachHost(hosts, func(name string) {
+		svc, err := common.DialHostKey(ctx, c.kube, cr, name, c.newServiceFn)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if err != nil {
+			logger.Info(fmt.Sprintf("[%s] Host %s is unreachable: %s", cr.GetName(), name, err))
+			st := e.results[name]
+			st.Ready, st.Message = false, err.Error()
+			e.results[name] = st
+			return
+		}
+		e.clients[name] = sshv1alpha1.NewSSHExecutor(svc)
+	})
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay], %d of %d hosts connected", cr.GetName(), len(e.clients), len(hosts)))
+	return e, nil
+}
+
+// A fanOutExternal runs the pipeline of a ScriptSet on each of its hosts. The
+// step statuses of every host are recorded in the status of the ScriptSet,
+// from which the pipeline of the host is resumed.
+type fanOutExternal struct {
+	hosts   []string
+	clients map[string]sshv1alpha1.RemoteExecutor
+
+	mu      sync.Mutex
+	results map[string]apisv1alpha1.ScriptSetHostStatus
+}
+
+// update calls fn with the result of the host and records the result fn
+// returns.
+func (e *fanOutExternal) update(name string, fn func(st apisv1alpha1.ScriptSetHostStatus) apisv1alpha1.ScriptSetHostStatus) {
+	e.mu.Lock()
+	st := e.results[name]
+	e.mu.Unlock()
+
+	st = fn(st)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.results[name] = st
+}
+
+// connected returns the connected hosts whose result satisfies fn.
+func (e *fanOutExternal) connected(fn func(st apisv1alpha1.ScriptSetHostStatus) bool) []string {
+	hosts := make([]string, 0, len(e.clients))
+	for _, name := range e.hosts {
+		if _, ok := e.clients[name]; ok && fn(e.results[name]) {
+			hosts = append(hosts, name)
+		}
+	}
+	return hosts
+}
+
+func (e *fanOutExternal) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.Info(fmt.Sprintf("[%s] Observing %d hosts...", mg.GetName(), len(e.hosts)))
+	cr, ok := mg.(*apisv1alpha1.ScriptSet)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotScriptSet)
+	}
+
+	if meta.WasDeleted(cr) {
+		return managed.ExternalObservation{ResourceExists: !deleting(cr)}, nil
+	}
+
+	common.ForEachHost(e.connected(all), func(name string) {
+		e.update(name, func(st apisv1alpha1.ScriptSetHostStatus) apisv1alpha1.ScriptSetHostStatus {
+			st.Message = "steps have not run"
+			if len(st.Steps) > 0 {
+				st.Message = observeSteps(ctx, e.clients[name], cr, st.Steps)
+			}
+			st.Ready = st.Message == ""
+			return st
+		})
+	})
+
+	exists := false
+	for _, st := range e.results {
+		exists = exists || len(st.Steps) > 0
+	}
+	pending := e.connected(func(st apisv1alpha1.ScriptSetHostStatus) bool { return !st.Ready })
+	// Hosts that are not ready are reported through the Ready condition,
+	// Observe itself succeeds.
+	_ = e.report(cr)
+	return managed.ExternalObservation{ResourceExists: exists, ResourceUpToDate: len(pending) == 0}, nil
+}
+
+func (e *fanOutExternal) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*apisv1alpha1.ScriptSet)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotScriptSet)
+	}
+	return managed.ExternalCreation{}, e.run(ctx, cr)
+}
+
+func (e *fanOutExternal) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*apisv1alpha1.ScriptSet)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotScriptSet)
+	}
+	return managed.ExternalUpdate{}, e.run(ctx, cr)
+}
+
+func (e *fanOutExternal) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Deleting on %d hosts...", mg.GetName(), len(e.hosts)))
+	cr, ok := mg.(*apisv1alpha1.ScriptSet)
+	if !ok {
+		return errors.New(errNotScriptSet)
+	}
+
+	failed := 0
+	common.ForEachHost(e.connected(all), func(name string) {
+		e.update(name, func(st apisv1alpha1.ScriptSetHostStatus) apisv1alpha1.ScriptSetHostStatus {
+			if err := cleanupSteps(ctx, e.clients[name], cr, st.Steps); err != nil {
+				st.Ready, st.Message = false, err.Error()
+				e.mu.Lock()
+				failed++
+				e.mu.Unlock()
+			}
+			return st
+		})
+	})
+	_ = e.report(cr)
+
+	if failed > 0 {
+		return errors.Errorf("cleanup failed on %d of %d hosts", failed, len(e.hosts))
+	}
+	return nil
+}
+
+// Disconnect closes the connections to the hosts.
+func (e *fanOutExternal) Disconnect(_ context.Context) error {
+	return common.CloseExecutors(e.clients)
+}
+
+// run executes the pipeline on the next batch of hosts.
+func (e *fanOutExternal) run(ctx context.Context, cr *apisv1alpha1.ScriptSet) error {
+	logger := log.FromContext(ctx).WithName("[RUN]")
+
+	hosts := e.batch(cr)
+	logger.Info(fmt.Sprintf("[%s] Running pipeline on %d hosts...", cr.GetName(), len(hosts)))
+
+	common.ForEachHost(hosts, func(name string) {
+		e.update(name, func(st apisv1alpha1.ScriptSetHostStatus) apisv1alpha1.ScriptSetHostStatus {
+			steps, err := runSteps(ctx, e.clients[name], cr, st.Steps)
+			st.Steps, st.Ready, st.Message = steps, err == nil, ""
+			if err != nil {
+				st.Message = err.Error()
+			}
+			return st
+		})
+	})
+
+	_ = e.report(cr)
+	failed := 0
+	for _, name := range hosts {
+		if !e.results[name].Ready {
+			failed++
+		}
+	}
+	if failed > 0 {
+		err := errors.Errorf(errFmtHostsFailed, failed, len(hosts))
+		cr.SetConditions(xpv1.ReconcileError(err))
+		return err
+	}
+	return nil
+}
+
+// batch returns the hosts whose pipeline runs next, according to the rollout
+// strategy of the ScriptSet. Hosts whose pipeline failed are retried first.
+// They are already unavailable, so retrying them is only limited by the batch
+// size.
+func (e *fanOutExternal) batch(cr *apisv1alpha1.ScriptSet) []string {
+	r := cr.Spec.ForProvider.Rollout
+	retry := e.connected(func(st apisv1alpha1.ScriptSetHostStatus) bool { return !st.Ready && failedSteps(st.Steps) })
+	pending := e.connected(func(st apisv1alpha1.ScriptSetHostStatus) bool { return !st.Ready && !failedSteps(st.Steps) })
+	unavailable := len(e.hosts) - len(e.clients) + len(retry)
+
+	hosts := append(retry, common.RolloutBatch(r, len(e.hosts), pending, unavailable)...)
+	if r != nil && r.BatchSize != nil && len(hosts) > *r.BatchSize {
+		hosts = hosts[:*r.BatchSize]
+	}
+	return hosts
+}
+
+// report records the result of every host in the status of the ScriptSet and
+// sets its Ready condition. It returns an error if any host is not ready.
+func (e *fanOutExternal) report(cr *apisv1alpha1.ScriptSet) error {
+	hosts := make([]apisv1alpha1.ScriptSetHostStatus, 0, len(e.hosts))
+	ready := 0
+	for _, key := range e.hosts {
+		st := e.results[key]
+		st.Kind, st.Name = common.SplitHostKey(key)
+		if st.Ready {
+			ready++
+		}
+		hosts = append(hosts, st)
+	}
+	cr.Status.AtProvider.Hosts = hosts
+
+	if len(hosts) > 0 && ready == len(hosts) {
+		cr.SetConditions(xpv1.Available())
+		return nil
+	}
+	err := errors.Errorf(errFmtHostsNotReady, ready, len(hosts))
+	cr.SetConditions(xpv1.Unavailable().WithMessage(err.Error()))
+	return err
+}
+
+// failedSteps reports whether the pipeline of a host stopped at a failed step.
+func failedSteps(steps []apisv1alpha1.StepStatus) bool {
+	for _, st := range steps {
+		if st.Phase == apisv1alpha1.StepFailed || st.Phase == apisv1alpha1.StepRolledBack {
+			return true
+		}
+	}
+	return false
+}
+
+// deleting reports whether the cleanup of the ScriptSet already succeeded on
+// every host. A failed cleanup leaves the ScriptSet unsynced, so it is
+// retried.
+func deleting(cr *apisv1alpha1.ScriptSet) bool {
+	c := cr.GetCondition(xpv1.TypeReady)
+	return c.Reason == xpv1.ReasonDeleting && cr.GetCondition(xpv1.TypeSynced).Status == corev1.ConditionTrue
+}
+
+func all(apisv1alpha1.ScriptSetHostStatus) bool { return true }
@@ -0,0 +1,332 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scriptset
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+	"github.com/crossplane/provider-ssh/internal/policy"
+	"github.com/crossplane/provider-ssh/internal/signature"
+)
+
+const (
+	errNotScriptSet = "managed resource is not a ScriptSet custom resource"
+	errFmtStep      = "step %s failed with exit code %d"
+
+	errVerifySignature = "cannot verify the signature of the scripts"
+	errPolicy          = "cannot execute scripts"
+)
+
+// Setup adds a controller that reconciles ScriptSet managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.ScriptSetGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.ScriptSetGroupVersionKind),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			verifier:     o.ScriptVerifier,
+			policy:       o.ScriptPolicy,
+			newServiceFn: o.NewServiceFn()})),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithCriticalAnnotationUpdater(common.NewStatusPreservingUpdater(mgr.GetClient())),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.ScriptSet{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.ScriptSetGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	verifier     *signature.Verifier
+	policy       *policy.Policy
+	newServiceFn common.NewServiceFn
+}
+
+// Connect produces an ExternalClient for the host of the ProviderConfig
+// referenced by the ScriptSet, or for each of its hosts.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.ScriptSet)
+	if !ok {
+		return nil, errors.New(errNotScriptSet)
+	}
+
+	if err := c.verifier.Verify(cr); err != nil {
+		return nil, errors.Wrap(err, errVerifySignature)
+	}
+
+	if err := c.policy.Evaluate(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errPolicy)
+	}
+
+	if len(cr.Spec.ForProvider.Hosts) > 0 {
+		return c.connectHosts(ctx, cr)
+	}
+
+	svc, err := common.Connect(ctx, c.kube, c.usage, cr, c.newServiceFn)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
+	return &external{service: sshv1alpha1.NewSSHExecutor(svc)}, nil
+}
+
+// An ExternalClient observes the steps of a ScriptSet and executes them
+// sequentially, resuming from the first step that has not succeeded.
+type external struct {
+	// A 'client' used to connect to the external resource API.
+	service sshv1alpha1.RemoteExecutor
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.ScriptSet)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotScriptSet)
+	}
+
+	// The pipeline exists once any of its steps has been executed.
+	if len(cr.Status.AtProvider.Steps) == 0 {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	if msg := observeSteps(ctx, c.service, cr, cr.Status.AtProvider.Steps); msg != "" {
+		cr.SetConditions(xpv1.Unavailable().WithMessage(msg))
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, nil
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. All steps succeeded.", mg.GetName()))
+	cr.SetConditions(xpv1.Available())
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*apisv1alpha1.ScriptSet)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotScriptSet)
+	}
+	return managed.ExternalCreation{}, c.run(ctx, cr)
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*apisv1alpha1.ScriptSet)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotScriptSet)
+	}
+	return managed.ExternalUpdate{}, c.run(ctx, cr)
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Deleting...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.ScriptSet)
+	if !ok {
+		return errors.New(errNotScriptSet)
+	}
+
+	return cleanupSteps(ctx, c.service, cr, cr.Status.AtProvider.Steps)
+}
+
+// Disconnect closes the connection to the host, if any.
+func (c *external) Disconnect(_ context.Context) error {
+	if c.service == nil {
+		return nil
+	}
+	return c.service.Close()
+}
+
+// run executes the pipeline of the ScriptSet on its host.
+func (c *external) run(ctx context.Context, cr *apisv1alpha1.ScriptSet) error {
+	steps, failed := runSteps(ctx, c.service, cr, cr.Status.AtProvider.Steps)
+	cr.Status.AtProvider.Steps = steps
+
+	if failed != nil {
+		cr.SetConditions(xpv1.ReconcileError(failed))
+	}
+	return failed
+}
+
+// observeSteps checks the supplied step statuses of a pipeline against the
+// steps of the ScriptSet and runs the statusCheckScript of every succeeded
+// step. It returns why the pipeline is not up to date, or an empty string if
+// it is.
+func observeSteps(ctx context.Context, svc sshv1alpha1.RemoteExecutor, cr *apisv1alpha1.ScriptSet, steps []apisv1alpha1.StepStatus) string {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	p := cr.Spec.ForProvider
+	for _, step := range p.Steps {
+		st := stepStatus(steps, step.Name)
+		if st == nil || st.Phase != apisv1alpha1.StepSucceeded || st.ScriptHash != stepHash(p, step) {
+			return fmt.Sprintf("step %s has not succeeded", step.Name)
+		}
+		if step.StatusCheckScript == "" {
+			continue
+		}
+		stdout, stderr, err := sshv1alpha1.ExecuteScript(ctx, svc, step.StatusCheckScript, stepVariables(p, step), p.SudoEnabled)
+		if err != nil {
+			logger.Info(fmt.Sprintf("[%s] Status check of step %s failed.", cr.GetName(), step.Name))
+			st.Phase = apisv1alpha1.StepPending
+			st.Stdout, st.Stderr, st.StatusCode = stdout, stderr, sshv1alpha1.ExitStatus(err)
+			return fmt.Sprintf("status check of step %s failed", step.Name)
+		}
+	}
+	return ""
+}
+
+// runSteps executes the steps of the ScriptSet in order and returns their
+// new statuses. Steps that already succeeded with their current script are
+// skipped until the first step that needs to run, after which all following
+// steps run as well. The pipeline stops at the first failing step, which is
+// resumed by the next run.
+func runSteps(ctx context.Context, svc sshv1alpha1.RemoteExecutor, cr *apisv1alpha1.ScriptSet, prev []apisv1alpha1.StepStatus) ([]apisv1alpha1.StepStatus, error) {
+	logger := log.FromContext(ctx).WithName("[RUN]")
+	p := cr.Spec.ForProvider
+
+	steps := make([]apisv1alpha1.StepStatus, 0, len(p.Steps))
+	var failed error
+	rerun := false
+	for _, step := range p.Steps {
+		st := apisv1alpha1.StepStatus{Name: step.Name, Phase: apisv1alpha1.StepPending}
+		if prev := stepStatus(prev, step.Name); prev != nil {
+			st = *prev
+		}
+		hash := stepHash(p, step)
+		if failed == nil && (rerun || st.Phase != apisv1alpha1.StepSucceeded || st.ScriptHash != hash) {
+			rerun = true
+			logger.Info(fmt.Sprintf("[%s] Running step %s...", cr.GetName(), step.Name))
+			failed = runStep(ctx, svc, p, step, &st)
+			st.ScriptHash = hash
+		}
+		steps = append(steps, st)
+	}
+	return steps, failed
+}
+
+// cleanupSteps runs the cleanupScript of every step that was executed, in the
+// reverse order of the steps.
+func cleanupSteps(ctx context.Context, svc sshv1alpha1.RemoteExecutor, cr *apisv1alpha1.ScriptSet, steps []apisv1alpha1.StepStatus) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	p := cr.Spec.ForProvider
+	for i := len(p.Steps) - 1; i >= 0; i-- {
+		step := p.Steps[i]
+		st := stepStatus(steps, step.Name)
+		if step.CleanupScript == "" || st == nil || st.Phase == apisv1alpha1.StepPending {
+			continue
+		}
+		logger.Info(fmt.Sprintf("[%s] Cleaning up step %s...", cr.GetName(), step.Name))
+		if _, _, err := sshv1alpha1.ExecuteScript(ctx, svc, step.CleanupScript, stepVariables(p, step), p.SudoEnabled); err != nil {
+			logger.Info(fmt.Sprintf("[%s] Cleanup of step %s failed.", cr.GetName(), step.Name))
+			return err
+		}
+	}
+	return nil
+}
+
+// runStep executes a single step and runs its rollback hook if it fails.
+func runStep(ctx context.Context, svc sshv1alpha1.RemoteExecutor, p apisv1alpha1.ScriptSetParameters, step apisv1alpha1.ScriptStep, st *apisv1alpha1.StepStatus) error {
+	vars := stepVariables(p, step)
+	stdout, stderr, err := sshv1alpha1.ExecuteScript(ctx, svc, step.Script, vars, p.SudoEnabled)
+	now := metav1.Now()
+	st.Stdout, st.Stderr, st.StatusCode, st.LastExecutionTime = stdout, stderr, sshv1alpha1.ExitStatus(err), &now
+
+	if succeeded(step, st.StatusCode, err) {
+		st.Phase = apisv1alpha1.StepSucceeded
+		return nil
+	}
+
+	st.Phase = apisv1alpha1.StepFailed
+	failed := errors.Errorf(errFmtStep, step.Name, st.StatusCode)
+	if err != nil {
+		failed = errors.Wrapf(err, errFmtStep, step.Name, st.StatusCode)
+	}
+	if step.RollbackScript != "" {
+		if _, _, rerr := sshv1alpha1.ExecuteScript(ctx, svc, step.RollbackScript, vars, p.SudoEnabled); rerr != nil {
+			return errors.Wrapf(failed, "rollback of step %s failed: %s", step.Name, rerr)
+		}
+		st.Phase = apisv1alpha1.StepRolledBack
+	}
+	return failed
+}
+
+// succeeded reports whether the supplied exit code marks the step as
+// succeeded. Errors that do not carry an exit code always fail the step.
+func succeeded(step apisv1alpha1.ScriptStep, code int, err error) bool {
+	if err != nil {
+		if !sshv1alpha1.IsExitError(err) {
+			return false
+		}
+	}
+	codes := []int{0}
+	if step.ExitCodePolicy != nil && len(step.ExitCodePolicy.SuccessCodes) > 0 {
+		codes = step.ExitCodePolicy.SuccessCodes
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// stepVariables returns the variables of a step followed by the variables of
+// the ScriptSet, so that those of the step take precedence.
+func stepVariables(p apisv1alpha1.ScriptSetParameters, step apisv1alpha1.ScriptStep) []apisv1alpha1.Variable {
+	vars := make([]apisv1alpha1.Variable, 0, len(step.Variables)+len(p.Variables))
+	vars = append(vars, step.Variables...)
+	return append(vars, p.Variables...)
+}
+
+func stepHash(p apisv1alpha1.ScriptSetParameters, step apisv1alpha1.ScriptStep) string {
+	return sshv1alpha1.HashScript(step.Script, stepVariables(p, step), apisv1alpha1.TemplatingBraces)
+}
+
+func stepStatus(steps []apisv1alpha1.StepStatus, name string) *apisv1alpha1.StepStatus {
+	for i := range steps {
+		if steps[i].Name == name {
+			return &steps[i]
+		}
+	}
+	return nil
+}
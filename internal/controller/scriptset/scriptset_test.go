@@ -0,0 +1,234 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scriptset
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+func scriptSet(steps []apisv1alpha1.StepStatus) *apisv1alpha1.ScriptSet {
+	cr := &apisv1alpha1.ScriptSet{}
+	cr.SetName("test")
+	cr.Spec.ForProvider.Variables = []apisv1alpha1.Variable{{Name: "A", Value: "1"}}
+	cr.Spec.ForProvider.Steps = []apisv1alpha1.ScriptStep{
+		{Name: "first", Script: "echo {{A}}"},
+		{Name: "second", Script: "echo second"},
+	}
+	cr.Status.AtProvider.Steps = steps
+	return cr
+}
+
+func hashOf(cr *apisv1alpha1.ScriptSet, i int) string {
+	return stepHash(cr.Spec.ForProvider, cr.Spec.ForProvider.Steps[i])
+}
+
+func TestObserve(t *testing.T) {
+	type args struct {
+		mg resource.Managed
+	}
+
+	type want struct {
+		o   managed.ExternalObservation
+		err error
+	}
+
+	succeededSet := scriptSet(nil)
+	succeededSet.Status.AtProvider.Steps = []apisv1alpha1.StepStatus{
+		{Name: "first", Phase: apisv1alpha1.StepSucceeded, ScriptHash: hashOf(succeededSet, 0)},
+		{Name: "second", Phase: apisv1alpha1.StepSucceeded, ScriptHash: hashOf(succeededSet, 1)},
+	}
+
+	changedSet := succeededSet.DeepCopy()
+	changedSet.Spec.ForProvider.Variables[0].Value = "2"
+
+	failedSet := succeededSet.DeepCopy()
+	failedSet.Status.AtProvider.Steps[1].Phase = apisv1alpha1.StepFailed
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotScriptSet": {
+			reason: "We should return an error if the managed resource is not a ScriptSet.",
+			args:   args{mg: &fake.Managed{}},
+			want:   want{err: errors.New(errNotScriptSet)},
+		},
+		"NotRun": {
+			reason: "A ScriptSet without step statuses should not exist yet.",
+			args:   args{mg: scriptSet(nil)},
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"AllSucceeded": {
+			reason: "A ScriptSet whose steps all succeeded with their current scripts should be up to date.",
+			args:   args{mg: succeededSet},
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}},
+		},
+		"VariablesChanged": {
+			reason: "A change to the variables of a succeeded step should make the ScriptSet out of date.",
+			args:   args{mg: changedSet},
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}},
+		},
+		"StepFailed": {
+			reason: "A ScriptSet with a failed step should be out of date so the pipeline is resumed.",
+			args:   args{mg: failedSet},
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{}
+			got, err := e.Observe(context.Background(), tc.args.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSucceeded(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		step   apisv1alpha1.ScriptStep
+		code   int
+		err    error
+		want   bool
+	}{
+		"DefaultZero": {
+			reason: "Exit code zero should succeed when no policy is set.",
+			want:   true,
+		},
+		"DefaultNonZero": {
+			reason: "A non-zero exit code should fail when no policy is set.",
+			code:   2,
+			err:    &ssh.ExitError{},
+			want:   false,
+		},
+		"PolicyCode": {
+			reason: "An exit code listed in the policy should succeed.",
+			step:   apisv1alpha1.ScriptStep{ExitCodePolicy: &apisv1alpha1.ExitCodePolicy{SuccessCodes: []int{0, 2}}},
+			code:   2,
+			err:    &ssh.ExitError{},
+			want:   true,
+		},
+		"TransportError": {
+			reason: "Errors without an exit code should always fail the step.",
+			step:   apisv1alpha1.ScriptStep{ExitCodePolicy: &apisv1alpha1.ExitCodePolicy{SuccessCodes: []int{1}}},
+			code:   1,
+			err:    errors.New("boom"),
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := succeeded(tc.step, tc.code, tc.err); got != tc.want {
+				t.Errorf("\n%s\nsucceeded(...): want %t, got %t\n", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	one := 1
+	failed := []apisv1alpha1.StepStatus{{Name: "first", Phase: apisv1alpha1.StepFailed}}
+	succeeded := []apisv1alpha1.StepStatus{{Name: "first", Phase: apisv1alpha1.StepSucceeded}}
+
+	cases := map[string]struct {
+		reason  string
+		rollout *apisv1alpha1.RolloutStrategy
+		results map[string]apisv1alpha1.ScriptSetHostStatus
+		want    []string
+	}{
+		"NoRollout": {
+			reason: "Without a rollout strategy the pipeline should run on every host that is not ready.",
+			results: map[string]apisv1alpha1.ScriptSetHostStatus{
+				"a": {Ready: true, Steps: succeeded},
+			},
+			want: []string{"b", "c"},
+		},
+		"RetryFirst": {
+			reason:  "Hosts whose pipeline failed should be retried before new hosts are started.",
+			rollout: &apisv1alpha1.RolloutStrategy{BatchSize: &one},
+			results: map[string]apisv1alpha1.ScriptSetHostStatus{
+				"c": {Steps: failed},
+			},
+			want: []string{"c"},
+		},
+		"PauseOnFailure": {
+			reason:  "No new host should be started while the pipeline of another host failed.",
+			rollout: &apisv1alpha1.RolloutStrategy{PauseOnFailure: true},
+			results: map[string]apisv1alpha1.ScriptSetHostStatus{
+				"a": {Ready: true, Steps: succeeded},
+				"b": {Steps: failed},
+			},
+			want: []string{"b"},
+		},
+		"MaxUnavailable": {
+			reason: "Failed hosts should count against maxUnavailable.",
+			rollout: &apisv1alpha1.RolloutStrategy{
+				MaxUnavailable: func() *intstr.IntOrString { v := intstr.FromInt(2); return &v }(),
+			},
+			results: map[string]apisv1alpha1.ScriptSetHostStatus{
+				"a": {Steps: failed},
+			},
+			want: []string{"a", "b"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := scriptSet(nil)
+			cr.Spec.ForProvider.Rollout = tc.rollout
+			e := &fanOutExternal{
+				hosts:   []string{"a", "b", "c"},
+				clients: map[string]sshv1alpha1.RemoteExecutor{"a": nil, "b": nil, "c": nil},
+				results: tc.results,
+			}
+			got := e.batch(cr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ne.batch(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -17,21 +17,53 @@ limitations under the License.
 package controller
 
 import (
-	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	ctrl "sigs.k8s.io/controller-runtime"
 
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/authorizedkey"
+	"github.com/crossplane/provider-ssh/internal/controller/command"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
 	"github.com/crossplane/provider-ssh/internal/controller/config"
+	"github.com/crossplane/provider-ssh/internal/controller/connectioncheck"
+	"github.com/crossplane/provider-ssh/internal/controller/hostkeyscan"
+	"github.com/crossplane/provider-ssh/internal/controller/keypair"
+	"github.com/crossplane/provider-ssh/internal/controller/nodescript"
+	"github.com/crossplane/provider-ssh/internal/controller/packages"
+	"github.com/crossplane/provider-ssh/internal/controller/remotedirectory"
+	"github.com/crossplane/provider-ssh/internal/controller/remotefetch"
+	"github.com/crossplane/provider-ssh/internal/controller/remotefile"
+	"github.com/crossplane/provider-ssh/internal/controller/reversetunnel"
 	"github.com/crossplane/provider-ssh/internal/controller/script"
+	"github.com/crossplane/provider-ssh/internal/controller/scriptset"
+	"github.com/crossplane/provider-ssh/internal/controller/useraccount"
 )
 
 // Setup creates all SSH controllers with the supplied logger and adds them to
 // the supplied manager.
-func Setup(mgr ctrl.Manager, o controller.Options) error {
-	for _, setup := range []func(ctrl.Manager, controller.Options) error{
-		config.Setup,
-		script.Setup,
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	sshv1alpha1.SetMaxConcurrentExecutions(o.MaxConcurrentScripts)
+	for _, c := range []struct {
+		kind  string
+		setup func(ctrl.Manager, common.Options) error
+	}{
+		{apisv1alpha1.ProviderConfigKind, config.Setup},
+		{apisv1alpha1.AuthorizedKeyKind, authorizedkey.Setup},
+		{apisv1alpha1.CommandKind, command.Setup},
+		{apisv1alpha1.ConnectionCheckKind, connectioncheck.Setup},
+		{apisv1alpha1.HostKeyScanKind, hostkeyscan.Setup},
+		{apisv1alpha1.KeyPairKind, keypair.Setup},
+		{apisv1alpha1.NodeScriptKind, nodescript.Setup},
+		{apisv1alpha1.PackageKind, packages.Setup},
+		{apisv1alpha1.RemoteFileKind, remotefile.Setup},
+		{apisv1alpha1.RemoteDirectoryKind, remotedirectory.Setup},
+		{apisv1alpha1.RemoteFetchKind, remotefetch.Setup},
+		{apisv1alpha1.ReverseTunnelKind, reversetunnel.Setup},
+		{apisv1alpha1.ScriptKind, script.Setup},
+		{apisv1alpha1.ScriptSetKind, scriptset.Setup},
+		{apisv1alpha1.UserAccountKind, useraccount.Setup},
 	} {
-		if err := setup(mgr, o); err != nil {
+		if err := c.setup(mgr, o.ForKind(c.kind)); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,257 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package useraccount
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotUserAccount = "managed resource is not a UserAccount custom resource"
+	errFmtParseKey    = "cannot parse authorized key %d"
+	errUserNotFound   = "user does not exist after it was created"
+)
+
+// Setup adds a controller that reconciles UserAccount managed resources.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	name := managed.ControllerName(apisv1alpha1.UserAccountGroupKind)
+
+	cps := common.ConnectionPublishers(mgr.GetClient(), o.Options)
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(apisv1alpha1.UserAccountGroupVersionKind),
+		managed.WithExternalConnectDisconnecter(common.NewExternalConnectDisconnecter(&connector{
+			kube:         mgr.GetClient(),
+			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			newServiceFn: o.NewServiceFn()})),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithRecorder(common.NewEventRecorder(mgr, name, o)),
+		managed.WithConnectionPublishers(cps...),
+		managed.WithManagementPolicies())
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&apisv1alpha1.UserAccount{}).
+		Complete(common.NewReconciler(mgr, resource.ManagedKind(apisv1alpha1.UserAccountGroupVersionKind), name, r, o))
+}
+
+type connector struct {
+	kube         client.Client
+	usage        resource.Tracker
+	newServiceFn common.NewServiceFn
+}
+
+// Connect produces an ExternalClient for the host of the ProviderConfig
+// referenced by the UserAccount.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	logger := log.FromContext(ctx).WithName("[CONNECT]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.UserAccount)
+	if !ok {
+		return nil, errors.New(errNotUserAccount)
+	}
+
+	svc, err := common.Connect(ctx, c.kube, c.usage, cr, c.newServiceFn)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.V(1).Info(fmt.Sprintf("[%s] Creating connection [okay]", mg.GetName()))
+	return &external{service: sshv1alpha1.NewSSHExecutor(svc)}, nil
+}
+
+// An ExternalClient manages the user of a UserAccount with useradd, usermod
+// and userdel.
+type external struct {
+	// A 'client' used to connect to the external resource API.
+	service sshv1alpha1.RemoteExecutor
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	logger := log.FromContext(ctx).WithName("[OBSERVE]")
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.UserAccount)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotUserAccount)
+	}
+
+	p := cr.Spec.ForProvider
+	u, err := sshv1alpha1.LookupUser(ctx, c.service, p.Name, p.SudoEnabled)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	if u == nil {
+		logger.Info(fmt.Sprintf("[%s] Observing, user %s does not exist.", mg.GetName(), p.Name))
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	cr.Status.AtProvider = apisv1alpha1.UserAccountObservation{
+		UID:    u.UID,
+		GID:    u.GID,
+		Groups: u.Groups,
+		Shell:  u.Shell,
+		Home:   u.Home,
+		Locked: u.Locked,
+	}
+	cr.SetConditions(xpv1.Available())
+
+	drift := sshv1alpha1.UserDrift(p, u)
+	keysUpToDate, err := c.keysUpToDate(ctx, p, u.Home)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	upToDate := len(drift) == 0 && keysUpToDate
+	logger.V(1).Info(fmt.Sprintf("[%s] Observing was [okay]. Up to date: %t", mg.GetName(), upToDate))
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: upToDate}, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	logger := log.FromContext(ctx).WithName("[CREATE]")
+	logger.Info(fmt.Sprintf("[%s] Creating user...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.UserAccount)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotUserAccount)
+	}
+
+	p := cr.Spec.ForProvider
+	if _, err := authorizedKeys(p); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	if err := sshv1alpha1.CreateUser(ctx, c.service, p); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	return managed.ExternalCreation{}, c.sync(ctx, p, false)
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	logger := log.FromContext(ctx).WithName("[UPDATE]")
+	logger.Info(fmt.Sprintf("[%s] Updating user...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.UserAccount)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotUserAccount)
+	}
+
+	return managed.ExternalUpdate{}, c.sync(ctx, cr.Spec.ForProvider, true)
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	logger := log.FromContext(ctx).WithName("[DELETE]")
+	logger.Info(fmt.Sprintf("[%s] Deleting user...", mg.GetName()))
+	cr, ok := mg.(*apisv1alpha1.UserAccount)
+	if !ok {
+		return errors.New(errNotUserAccount)
+	}
+
+	return sshv1alpha1.DeleteUser(ctx, c.service, cr.Spec.ForProvider)
+}
+
+// Disconnect closes the connection to the host, if any.
+func (c *external) Disconnect(_ context.Context) error {
+	if c.service == nil {
+		return nil
+	}
+	return c.service.Close()
+}
+
+// sync brings the existing user to the configured state. The attributes of
+// the user are only modified if modify is true, since a created user has them
+// already.
+func (c *external) sync(ctx context.Context, p apisv1alpha1.UserAccountParameters, modify bool) error {
+	u, err := sshv1alpha1.LookupUser(ctx, c.service, p.Name, p.SudoEnabled)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return errors.New(errUserNotFound)
+	}
+	if modify {
+		if err := sshv1alpha1.ModifyUser(ctx, c.service, p, sshv1alpha1.UserDrift(p, u)); err != nil {
+			return err
+		}
+		if p.Home != "" {
+			u.Home = p.Home
+		}
+	}
+
+	upToDate, err := c.keysUpToDate(ctx, p, u.Home)
+	if err != nil || upToDate {
+		return err
+	}
+	content, err := authorizedKeys(p)
+	if err != nil {
+		return err
+	}
+	return sshv1alpha1.WriteAuthorizedKeys(ctx, c.service, keysPath(u.Home), content, p.Name, p.SudoEnabled)
+}
+
+// keysUpToDate reports whether the authorized_keys file of the user holds
+// exactly the configured keys. Users without configured keys are always up
+// to date.
+func (c *external) keysUpToDate(ctx context.Context, p apisv1alpha1.UserAccountParameters, home string) (bool, error) {
+	if len(p.AuthorizedKeys) == 0 {
+		return true, nil
+	}
+	want, err := authorizedKeys(p)
+	if err != nil {
+		return false, err
+	}
+	got, err := sshv1alpha1.ReadAuthorizedKeys(ctx, c.service, keysPath(home), p.SudoEnabled)
+	if err != nil {
+		return false, err
+	}
+	return string(got) == string(want), nil
+}
+
+// authorizedKeys returns the content of the authorized_keys file of the
+// user, or nil if no keys are configured.
+func authorizedKeys(p apisv1alpha1.UserAccountParameters) ([]byte, error) {
+	if len(p.AuthorizedKeys) == 0 {
+		return nil, nil
+	}
+	lines := make([]string, len(p.AuthorizedKeys))
+	for i, k := range p.AuthorizedKeys {
+		key, err := sshv1alpha1.ParseAuthorizedKey(k)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtParseKey, i)
+		}
+		lines[i] = key.String()
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+func keysPath(home string) string {
+	return path.Join(home, ".ssh", "authorized_keys")
+}
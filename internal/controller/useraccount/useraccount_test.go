@@ -0,0 +1,343 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package useraccount
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+	sshfake "github.com/crossplane/provider-ssh/internal/client/fake"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+const publicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIM4u6OQD6Hwhel8T5l1CmTNK4+v5BZWn/KNJrgLn4/EV alice@example.com"
+
+const (
+	// passwd is what the host reports about the deploy user when it is
+	// looked up: its passwd entry, primary group, groups and password.
+	passwd = "deploy:x:1000:1000::/home/deploy:/bin/sh\ndeploy\ndeploy adm docker\n$\n"
+
+	lookupDeploy = "getent passwd 'deploy' || exit 100; id -gn 'deploy'; id -nG 'deploy'; getent shadow 'deploy' | cut -d: -f2 | cut -c1"
+	readKeys     = "if [ -f '/home/deploy/.ssh/authorized_keys' ]; then cat '/home/deploy/.ssh/authorized_keys'; fi"
+)
+
+type userAccountModifier func(*apisv1alpha1.UserAccount)
+
+func withShell(shell string) userAccountModifier {
+	return func(cr *apisv1alpha1.UserAccount) { cr.Spec.ForProvider.Shell = shell }
+}
+
+func withGroups(groups ...string) userAccountModifier {
+	return func(cr *apisv1alpha1.UserAccount) { cr.Spec.ForProvider.Groups = groups }
+}
+
+func withAuthorizedKeys(keys ...string) userAccountModifier {
+	return func(cr *apisv1alpha1.UserAccount) { cr.Spec.ForProvider.AuthorizedKeys = keys }
+}
+
+func userAccount(m ...userAccountModifier) *apisv1alpha1.UserAccount {
+	cr := &apisv1alpha1.UserAccount{}
+	cr.SetName("test")
+	cr.Spec.ForProvider.Name = "deploy"
+	for _, f := range m {
+		f(cr)
+	}
+	return cr
+}
+
+// userHost returns a fake host reporting the supplied passwd of the deploy
+// user, which does not exist if it is empty, and authorized_keys file.
+func userHost(passwd, keys string) *sshfake.Executor {
+	return &sshfake.Executor{MockRun: func(script string) (string, string, error) {
+		switch {
+		case script == lookupDeploy && passwd == "":
+			return "", "", sshfake.ExitError(100)
+		case script == lookupDeploy:
+			return passwd, "", nil
+		case script == readKeys:
+			return keys, "", nil
+		case strings.HasPrefix(script, "'userdel' "):
+			return "", "", nil
+		}
+		return "", "", sshfake.ExitError(127)
+	}}
+}
+
+func TestObserve(t *testing.T) {
+	observed := apisv1alpha1.UserAccountObservation{
+		UID:    1000,
+		GID:    1000,
+		Groups: []string{"adm", "docker"},
+		Shell:  "/bin/sh",
+		Home:   "/home/deploy",
+	}
+
+	type want struct {
+		o          managed.ExternalObservation
+		atProvider apisv1alpha1.UserAccountObservation
+		status     xpv1.ConditionedStatus
+		err        error
+	}
+
+	cases := map[string]struct {
+		reason string
+		host   *sshfake.Executor
+		mg     resource.Managed
+		want   want
+	}{
+		"NotUserAccount": {
+			reason: "We should return an error if the managed resource is not a UserAccount.",
+			mg:     &fake.Managed{},
+			want: want{
+				err: errors.New(errNotUserAccount),
+			},
+		},
+		"NotExists": {
+			reason: "A user unknown to the host should not exist.",
+			host:   userHost("", ""),
+			mg:     userAccount(),
+			want:   want{o: managed.ExternalObservation{ResourceExists: false}},
+		},
+		"UpToDate": {
+			reason: "A user in the configured state with the configured keys should be up to date.",
+			host:   userHost(passwd, publicKey+"\n"),
+			mg:     userAccount(withShell("/bin/sh"), withGroups("docker", "adm"), withAuthorizedKeys(publicKey)),
+			want: want{
+				o:          managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				atProvider: observed,
+				status:     *xpv1.NewConditionedStatus(xpv1.Available()),
+			},
+		},
+		"UserDrift": {
+			reason: "A user whose attributes drifted from the configured state should need an update.",
+			host:   userHost(passwd, ""),
+			mg:     userAccount(withShell("/bin/bash")),
+			want: want{
+				o:          managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				atProvider: observed,
+				status:     *xpv1.NewConditionedStatus(xpv1.Available()),
+			},
+		},
+		"KeysDrift": {
+			reason: "A user whose authorized_keys file does not hold exactly the configured keys should need an update.",
+			host:   userHost(passwd, publicKey+"\nno-pty "+publicKey+"\n"),
+			mg:     userAccount(withAuthorizedKeys(publicKey)),
+			want: want{
+				o:          managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false},
+				atProvider: observed,
+				status:     *xpv1.NewConditionedStatus(xpv1.Available()),
+			},
+		},
+		"RemoteError": {
+			reason: "We should return an error if the user cannot be looked up on the host.",
+			host: func() *sshfake.Executor {
+				x := userHost(passwd, "")
+				_ = x.Close()
+				return x
+			}(),
+			mg:   userAccount(),
+			want: want{err: errors.Wrap(errors.New("connection closed"), "Failed to look up user deploy")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{}
+			if tc.host != nil {
+				e.service = tc.host
+			}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if cr, ok := tc.mg.(*apisv1alpha1.UserAccount); ok {
+				if diff := cmp.Diff(tc.want.atProvider, cr.Status.AtProvider); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want status, +got status:\n%s\n", tc.reason, diff)
+				}
+				if diff := cmp.Diff(tc.want.status, cr.Status.ResourceStatus.ConditionedStatus, test.EquateConditions()); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want conditions, +got conditions:\n%s\n", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type want struct {
+		scripts []string
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason string
+		host   *sshfake.Executor
+		mg     *apisv1alpha1.UserAccount
+		want   want
+	}{
+		"KeepHome": {
+			reason: "The user should be deleted, keeping its home directory.",
+			host:   userHost(passwd, ""),
+			mg:     userAccount(),
+			want:   want{scripts: []string{"'userdel' 'deploy'"}},
+		},
+		"RemoveHome": {
+			reason: "The user should be deleted together with its home directory if removeHome is set.",
+			host:   userHost(passwd, ""),
+			mg:     userAccount(func(cr *apisv1alpha1.UserAccount) { cr.Spec.ForProvider.RemoveHome = true }),
+			want:   want{scripts: []string{"'userdel' '-r' 'deploy'"}},
+		},
+		"DeleteFailed": {
+			reason: "We should return an error if userdel fails on the host.",
+			host: &sshfake.Executor{MockRun: func(_ string) (string, string, error) {
+				return "", "userdel: user deploy is currently used by process 42\n", sshfake.ExitError(8)
+			}},
+			mg: userAccount(),
+			want: want{
+				scripts: []string{"'userdel' 'deploy'"},
+				err:     errors.Wrap(sshfake.ExitError(8), "Failed to delete user deploy: userdel: user deploy is currently used by process 42"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{service: tc.host}
+			err := e.Delete(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.scripts, tc.host.Scripts()); diff != "" {
+				t.Errorf("\n%s\nScripts run: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUserDrift(t *testing.T) {
+	uid := 1001
+	locked := true
+	user := &sshv1alpha1.User{
+		Name:   "deploy",
+		UID:    1000,
+		GID:    1000,
+		Home:   "/home/deploy",
+		Shell:  "/bin/sh",
+		Groups: []string{"adm", "docker"},
+	}
+
+	cases := map[string]struct {
+		reason string
+		p      apisv1alpha1.UserAccountParameters
+		want   []string
+	}{
+		"Unmanaged": {
+			reason: "Fields that are not set should not be managed.",
+			p:      apisv1alpha1.UserAccountParameters{Name: "deploy"},
+		},
+		"UpToDate": {
+			reason: "A user in the configured state should not be modified, regardless of the order of its groups.",
+			p: apisv1alpha1.UserAccountParameters{
+				Name:   "deploy",
+				Home:   "/home/deploy",
+				Shell:  "/bin/sh",
+				Groups: []string{"docker", "adm"},
+			},
+		},
+		"Drifted": {
+			reason: "Every drifted field should be modified.",
+			p: apisv1alpha1.UserAccountParameters{
+				Name:   "deploy",
+				UID:    &uid,
+				Home:   "/srv/deploy",
+				Shell:  "/bin/bash",
+				Groups: []string{"docker"},
+				Locked: &locked,
+			},
+			want: []string{"-u", "1001", "-d", "/srv/deploy", "-m", "-s", "/bin/bash", "-G", "docker", "-L"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := sshv1alpha1.UserDrift(tc.p, user)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nUserDrift(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAuthorizedKeys(t *testing.T) {
+	type want struct {
+		content string
+		err     bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		keys   []string
+		want   want
+	}{
+		"None": {
+			reason: "No content should be returned if no keys are configured.",
+		},
+		"Keys": {
+			reason: "The configured keys should be rendered one per line.",
+			keys:   []string{publicKey, "no-pty " + publicKey},
+			want:   want{content: publicKey + "\nno-pty " + publicKey + "\n"},
+		},
+		"Invalid": {
+			reason: "We should return an error if a key cannot be parsed.",
+			keys:   []string{"invalid"},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := authorizedKeys(apisv1alpha1.UserAccountParameters{AuthorizedKeys: tc.keys})
+			if (err != nil) != tc.want.err {
+				t.Fatalf("\n%s\nauthorizedKeys(...): unexpected error: %v\n", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.content, string(got)); diff != "" {
+				t.Errorf("\n%s\nauthorizedKeys(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
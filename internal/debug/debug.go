@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package debug serves the debug endpoints of the SSH provider.
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/pkg/errors"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	errServe = "cannot serve debug endpoints"
+)
+
+// A connection is an open SSH connection as reported by the debug endpoint.
+type connection struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	Alias    string `json:"alias,omitempty"`
+	Age      string `json:"age"`
+	Idle     string `json:"idle"`
+	Sessions int64  `json:"sessions"`
+}
+
+// A providerConfig lists the open SSH connections of a ProviderConfig.
+type providerConfig struct {
+	// Name of the ProviderConfig, empty for connections made with the
+	// credentials of a resource itself.
+	Name        string       `json:"name"`
+	Connections []connection `json:"connections"`
+}
+
+// Handler returns the handler of the debug endpoints: the open SSH
+// connections under /debug/connections, and the profiles of the provider
+// under /debug/pprof/.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/connections", connections)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// connections lists the open SSH connections per ProviderConfig.
+func connections(w http.ResponseWriter, _ *http.Request) {
+	pcs := []providerConfig{}
+	for _, c := range sshv1alpha1.Connections() {
+		if len(pcs) == 0 || pcs[len(pcs)-1].Name != c.ProviderConfig {
+			pcs = append(pcs, providerConfig{Name: c.ProviderConfig})
+		}
+		pc := &pcs[len(pcs)-1]
+		pc.Connections = append(pc.Connections, connection{
+			Host:     c.Host,
+			Port:     c.Port,
+			Username: c.Username,
+			Alias:    c.Alias,
+			Age:      c.Age.Round(time.Second).String(),
+			Idle:     c.Idle.Round(time.Second).String(),
+			Sessions: c.Sessions,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(struct {
+		ProviderConfigs []providerConfig `json:"providerConfigs"`
+	}{ProviderConfigs: pcs})
+}
+
+// A Server serves the debug endpoints on every replica of the provider,
+// regardless of leader election.
+type Server struct {
+	// Address the server listens on, e.g. :6060.
+	Address string
+}
+
+// NeedLeaderElection reports that the server does not need to be the leader.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves the debug endpoints until the supplied context is done.
+func (s *Server) Start(ctx context.Context) error {
+	srv := &http.Server{Addr: s.Address, Handler: Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrap(err, errServe)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/crypto/ssh"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+// sshServer starts an SSH server accepting any password and any session, and
+// returns its address.
+func sshServer(t *testing.T) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		config := &ssh.ServerConfig{
+			PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+		}
+		config.AddHostKey(signer)
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close() // nolint: errcheck
+				if sc, chans, reqs, err := ssh.NewServerConn(conn, config); err == nil {
+					go ssh.DiscardRequests(reqs)
+					for nc := range chans {
+						if ch, reqs, err := nc.Accept(); err == nil {
+							go ssh.DiscardRequests(reqs)
+							defer ch.Close() // nolint: errcheck
+						}
+					}
+					_ = sc.Wait()
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func list(t *testing.T) []providerConfig {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/connections", nil))
+	got := struct {
+		ProviderConfigs []providerConfig `json:"providerConfigs"`
+	}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("/debug/connections: cannot parse %q: %v", rec.Body.String(), err)
+	}
+	return got.ProviderConfigs
+}
+
+func TestConnections(t *testing.T) {
+	addr := sshServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+	hostPort, _ := sshv1alpha1.ParsePort(port)
+	creds, _ := json.Marshal(sshv1alpha1.Config{RemoteHostIP: host, RemoteHostPort: hostPort, Username: "deploy", Password: "secret"})
+	svc, err := sshv1alpha1.NewSSHClient(sshv1alpha1.WithProviderConfig(context.Background(), "default"), creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := svc.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ignoreTimes := cmpopts.IgnoreFields(connection{}, "Age", "Idle")
+	want := []providerConfig{{
+		Name:        "default",
+		Connections: []connection{{Host: host, Port: port, Username: "deploy", Sessions: 1}},
+	}}
+	if diff := cmp.Diff(want, list(t), ignoreTimes); diff != "" {
+		t.Errorf("/debug/connections: -want, +got:\n%s\n", diff)
+	}
+
+	_ = session.Close()
+	want[0].Connections[0].Sessions = 0
+	if diff := cmp.Diff(want, list(t), ignoreTimes); diff != "" {
+		t.Errorf("/debug/connections after closing the session: -want, +got:\n%s\n", diff)
+	}
+
+	// The controllers close the executor of the client once their reconcile
+	// disconnects.
+	_ = sshv1alpha1.NewSSHExecutor(svc).Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(list(t)) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if diff := cmp.Diff([]providerConfig{}, list(t)); diff != "" {
+		t.Errorf("/debug/connections after closing the client: -want, +got:\n%s\n", diff)
+	}
+}
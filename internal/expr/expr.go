@@ -0,0 +1,604 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package expr evaluates expressions written in a subset of the Common
+// Expression Language (CEL), https://github.com/google/cel-spec.
+//
+// The subset has the literals of strings, integers, doubles, booleans, null
+// and lists, variables, field selection and indexing of maps and lists, the
+// operators ! - * / % + == != < <= > >= in && || and ?:, the has() macro, and
+// the functions size, int, double, string, startsWith, endsWith, contains and
+// matches. Maps are map[string]any and lists []any, integers are int64.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errParse             = "cannot parse expression"
+	errNotBool           = "expression does not evaluate to a boolean"
+	errDivisionByZero    = "division by zero"
+	errFmtUndeclared     = "undeclared reference to %q"
+	errFmtNoSuchKey      = "no such key: %s"
+	errFmtNoSuchOverload = "no such overload: %s(%s)"
+	errFmtIndexRange     = "index %d out of range"
+	errFmtUnknownFunc    = "unknown function %q"
+	errFmtInvalidRegexp  = "invalid regular expression %q"
+	errFmtConvert        = "cannot convert %q to %s"
+)
+
+// A Program is a parsed expression.
+type Program struct {
+	root node
+}
+
+// Compile parses the supplied expression.
+func Compile(src string) (*Program, error) {
+	n, err := parse(src)
+	if err != nil {
+		return nil, errors.Wrap(err, errParse)
+	}
+	return &Program{root: n}, nil
+}
+
+// Eval evaluates the expression with the supplied variables.
+func (p *Program) Eval(vars map[string]any) (any, error) {
+	return p.root.eval(vars)
+}
+
+// EvalBool evaluates the expression with the supplied variables, and returns
+// an error unless its value is a boolean.
+func (p *Program) EvalBool(vars map[string]any) (bool, error) {
+	v, err := p.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, errors.New(errNotBool)
+	}
+	return b, nil
+}
+
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type literalNode struct{ value any }
+
+func (n *literalNode) eval(_ map[string]any) (any, error) { return n.value, nil }
+
+type identNode struct{ name string }
+
+func (n *identNode) eval(vars map[string]any) (any, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, errors.Errorf(errFmtUndeclared, n.name)
+	}
+	return normalize(v), nil
+}
+
+type selectNode struct {
+	x     node
+	field string
+}
+
+func (n *selectNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := x.(map[string]any)
+	if !ok {
+		return nil, errors.Errorf(errFmtNoSuchOverload, "_."+n.field, typeName(x))
+	}
+	v, ok := m[n.field]
+	if !ok {
+		return nil, errors.Errorf(errFmtNoSuchKey, n.field)
+	}
+	return normalize(v), nil
+}
+
+type hasNode struct {
+	x     node
+	field string
+}
+
+func (n *hasNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := x.(map[string]any)
+	if !ok {
+		return nil, errors.Errorf(errFmtNoSuchOverload, "has", typeName(x))
+	}
+	_, ok = m[n.field]
+	return ok, nil
+}
+
+type indexNode struct{ x, index node }
+
+func (n *indexNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	i, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case []any:
+		if i, ok := i.(int64); ok {
+			if i < 0 || i >= int64(len(x)) {
+				return nil, errors.Errorf(errFmtIndexRange, i)
+			}
+			return normalize(x[i]), nil
+		}
+	case map[string]any:
+		if k, ok := i.(string); ok {
+			v, ok := x[k]
+			if !ok {
+				return nil, errors.Errorf(errFmtNoSuchKey, k)
+			}
+			return normalize(v), nil
+		}
+	}
+	return nil, errors.Errorf(errFmtNoSuchOverload, "_[_]", typeName(x)+", "+typeName(i))
+}
+
+type listNode struct{ elems []node }
+
+func (n *listNode) eval(vars map[string]any) (any, error) {
+	l := make([]any, len(n.elems))
+	for i, e := range n.elems {
+		v, err := e.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		l[i] = v
+	}
+	return l, nil
+}
+
+type condNode struct{ cond, then, otherwise node }
+
+func (n *condNode) eval(vars map[string]any) (any, error) {
+	c, err := n.cond.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := c.(bool)
+	if !ok {
+		return nil, errors.Errorf(errFmtNoSuchOverload, "_?_:_", typeName(c))
+	}
+	if b {
+		return n.then.eval(vars)
+	}
+	return n.otherwise.eval(vars)
+}
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+func (n *unaryNode) eval(vars map[string]any) (any, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case bool:
+		if n.op == "!" {
+			return !x, nil
+		}
+	case int64:
+		if n.op == "-" {
+			return -x, nil
+		}
+	case float64:
+		if n.op == "-" {
+			return -x, nil
+		}
+	}
+	return nil, errors.Errorf(errFmtNoSuchOverload, n.op+"_", typeName(x))
+}
+
+type binaryNode struct {
+	op   string
+	l, r node
+}
+
+func (n *binaryNode) eval(vars map[string]any) (any, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		return n.logical(l, vars)
+	}
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "in":
+		return in(l, r)
+	case "<", "<=", ">", ">=":
+		return n.compare(l, r)
+	}
+	return n.arithmetic(l, r)
+}
+
+// logical evaluates the right operand only if the left operand does not
+// decide the result.
+func (n *binaryNode) logical(l any, vars map[string]any) (any, error) {
+	lb, ok := l.(bool)
+	if !ok {
+		return nil, errors.Errorf(errFmtNoSuchOverload, "_"+n.op+"_", typeName(l))
+	}
+	if (n.op == "&&" && !lb) || (n.op == "||" && lb) {
+		return lb, nil
+	}
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	rb, ok := r.(bool)
+	if !ok {
+		return nil, errors.Errorf(errFmtNoSuchOverload, "_"+n.op+"_", typeName(l)+", "+typeName(r))
+	}
+	return rb, nil
+}
+
+func (n *binaryNode) compare(l, r any) (any, error) {
+	var c int
+	switch {
+	case isNumber(l) && isNumber(r):
+		c = compareNumbers(l, r)
+	default:
+		ls, lok := l.(string)
+		rs, rok := r.(string)
+		if !lok || !rok {
+			return nil, errors.Errorf(errFmtNoSuchOverload, "_"+n.op+"_", typeName(l)+", "+typeName(r))
+		}
+		c = strings.Compare(ls, rs)
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func (n *binaryNode) arithmetic(l, r any) (any, error) {
+	switch l := l.(type) {
+	case int64:
+		if r, ok := r.(int64); ok {
+			switch n.op {
+			case "+":
+				return l + r, nil
+			case "-":
+				return l - r, nil
+			case "*":
+				return l * r, nil
+			case "/", "%":
+				if r == 0 {
+					return nil, errors.New(errDivisionByZero)
+				}
+				if n.op == "/" {
+					return l / r, nil
+				}
+				return l % r, nil
+			}
+		}
+	case float64:
+		if r, ok := r.(float64); ok {
+			switch n.op {
+			case "+":
+				return l + r, nil
+			case "-":
+				return l - r, nil
+			case "*":
+				return l * r, nil
+			case "/":
+				return l / r, nil
+			}
+		}
+	case string:
+		if r, ok := r.(string); ok && n.op == "+" {
+			return l + r, nil
+		}
+	case []any:
+		if r, ok := r.([]any); ok && n.op == "+" {
+			return append(append([]any{}, l...), r...), nil
+		}
+	}
+	return nil, errors.Errorf(errFmtNoSuchOverload, "_"+n.op+"_", typeName(l)+", "+typeName(r))
+}
+
+type callNode struct {
+	fn string
+	// target is the receiver of the function, if it is called as a method.
+	target node
+	args   []node
+}
+
+func (n *callNode) eval(vars map[string]any) (any, error) {
+	var args []any
+	if n.target != nil {
+		t, err := n.target.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, t)
+	}
+	for _, a := range n.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	switch n.fn {
+	case "size":
+		if len(args) == 1 {
+			switch x := args[0].(type) {
+			case string:
+				return int64(len([]rune(x))), nil
+			case []any:
+				return int64(len(x)), nil
+			case map[string]any:
+				return int64(len(x)), nil
+			}
+		}
+	case "startsWith", "endsWith", "contains", "matches":
+		if n.target == nil || len(args) != 2 {
+			break
+		}
+		s, sok := args[0].(string)
+		t, tok := args[1].(string)
+		if !sok || !tok {
+			break
+		}
+		switch n.fn {
+		case "startsWith":
+			return strings.HasPrefix(s, t), nil
+		case "endsWith":
+			return strings.HasSuffix(s, t), nil
+		case "contains":
+			return strings.Contains(s, t), nil
+		}
+		re, err := regexp.Compile(t)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtInvalidRegexp, t)
+		}
+		return re.MatchString(s), nil
+	case "int", "double", "string":
+		if n.target == nil && len(args) == 1 {
+			return convert(n.fn, args[0])
+		}
+	default:
+		return nil, errors.Errorf(errFmtUnknownFunc, n.fn)
+	}
+
+	types := make([]string, len(args))
+	for i, a := range args {
+		types[i] = typeName(a)
+	}
+	return nil, errors.Errorf(errFmtNoSuchOverload, n.fn, strings.Join(types, ", "))
+}
+
+func convert(to string, v any) (any, error) {
+	switch to {
+	case "int":
+		switch v := v.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			return int64(v), nil
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, errors.Errorf(errFmtConvert, v, to)
+			}
+			return i, nil
+		}
+	case "double":
+		switch v := v.(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, errors.Errorf(errFmtConvert, v, to)
+			}
+			return f, nil
+		}
+	case "string":
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	}
+	return nil, errors.Errorf(errFmtNoSuchOverload, to, typeName(v))
+}
+
+// equal is the heterogeneous equality of CEL: values of different types are
+// not equal, except numbers which are compared by their value.
+func equal(l, r any) bool {
+	if isNumber(l) && isNumber(r) {
+		li, lok := l.(int64)
+		ri, rok := r.(int64)
+		if lok && rok {
+			return li == ri
+		}
+		return toFloat(l) == toFloat(r)
+	}
+	switch l := l.(type) {
+	case []any:
+		r, ok := r.([]any)
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !equal(normalize(l[i]), normalize(r[i])) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		r, ok := r.(map[string]any)
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for k, lv := range l {
+			rv, ok := r[k]
+			if !ok || !equal(normalize(lv), normalize(rv)) {
+				return false
+			}
+		}
+		return true
+	case nil, bool, string:
+		return l == r
+	}
+	return false
+}
+
+func in(v, collection any) (any, error) {
+	switch c := collection.(type) {
+	case []any:
+		for _, e := range c {
+			if equal(v, normalize(e)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		k, ok := v.(string)
+		if !ok {
+			return false, nil
+		}
+		_, ok = c[k]
+		return ok, nil
+	}
+	return nil, errors.Errorf(errFmtNoSuchOverload, "@in", typeName(v)+", "+typeName(collection))
+}
+
+// normalize converts the values of variables to the types of expressions.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		l := make([]any, len(v))
+		for i, s := range v {
+			l[i] = s
+		}
+		return l
+	case map[string]string:
+		m := make(map[string]any, len(v))
+		for k, s := range v {
+			m[k] = s
+		}
+		return m
+	}
+	return v
+}
+
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int64, float64:
+		return true
+	}
+	return false
+}
+
+func toFloat(v any) float64 {
+	if i, ok := v.(int64); ok {
+		return float64(i)
+	}
+	return v.(float64)
+}
+
+// compareNumbers compares integers exactly, beyond the precision of doubles,
+// and other numbers as doubles.
+func compareNumbers(l, r any) int {
+	li, lok := l.(int64)
+	ri, rok := r.(int64)
+	switch {
+	case lok && rok && li < ri:
+		return -1
+	case lok && rok && li > ri:
+		return 1
+	case lok && rok:
+		return 0
+	case toFloat(l) < toFloat(r):
+		return -1
+	case toFloat(l) > toFloat(r):
+		return 1
+	}
+	return 0
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
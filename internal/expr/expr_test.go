@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expr
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEval(t *testing.T) {
+	vars := map[string]any{
+		"facts": map[string]any{
+			"os":       "ubuntu",
+			"kernel":   "6.8.0-45-generic",
+			"cpus":     int64(4),
+			"memoryKB": int64(8000000),
+		},
+		"host": "web-1",
+		"vars": map[string]string{"KERNEL": "6.8.0-45-generic"},
+		"tags": []string{"web", "eu"},
+	}
+
+	cases := map[string]struct {
+		expr    string
+		want    any
+		wantErr bool
+	}{
+		"Equal":             {expr: `facts.kernel == vars.KERNEL`, want: true},
+		"NotEqual":          {expr: `facts.os != "ubuntu"`, want: false},
+		"SingleQuotes":      {expr: `facts.os == 'ubuntu'`, want: true},
+		"StartsWith":        {expr: `facts.kernel.startsWith("6.8")`, want: true},
+		"EndsWith":          {expr: `host.endsWith("-1")`, want: true},
+		"Contains":          {expr: `facts.kernel.contains("generic")`, want: true},
+		"Matches":           {expr: `facts.kernel.matches("^6\\.[5-9]\\.")`, want: true},
+		"InvalidRegexp":     {expr: `host.matches("[")`, wantErr: true},
+		"And":               {expr: `facts.os == "ubuntu" && facts.cpus >= 4`, want: true},
+		"Or":                {expr: `facts.os == "debian" || facts.cpus > 8`, want: false},
+		"ShortCircuitAnd":   {expr: `has(facts.gpu) && facts.gpu == "a100"`, want: false},
+		"ShortCircuitOr":    {expr: `facts.os == "ubuntu" || facts.gpu == "a100"`, want: true},
+		"Not":               {expr: `!(facts.os in ["debian", "ubuntu"])`, want: false},
+		"InList":            {expr: `"eu" in tags`, want: true},
+		"InMap":             {expr: `"KERNEL" in vars`, want: true},
+		"Index":             {expr: `tags[1] == "eu" && facts["os"] == "ubuntu"`, want: true},
+		"IndexOutOfRange":   {expr: `tags[2]`, wantErr: true},
+		"Arithmetic":        {expr: `facts.memoryKB / 1000 - 2 * (facts.cpus + 1) % 3`, want: int64(7999)},
+		"DivisionByZero":    {expr: `facts.cpus / 0`, wantErr: true},
+		"Doubles":           {expr: `facts.memoryKB / 1e6 > 7.5`, wantErr: true},
+		"MixedComparison":   {expr: `facts.cpus > 3.5 && facts.cpus == 4.0`, want: true},
+		"Conversions":       {expr: `double(facts.memoryKB) / 1e6 > 7.5 && int("12") == 12 && string(facts.cpus) == "4"`, want: true},
+		"InvalidConversion": {expr: `int(facts.os)`, wantErr: true},
+		"Size":              {expr: `size(tags) + host.size()`, want: int64(7)},
+		"Concat":            {expr: `host + "." + facts.os`, want: "web-1.ubuntu"},
+		"Ternary":           {expr: `facts.cpus > 2 ? "large" : "small"`, want: "large"},
+		"Negative":          {expr: `-facts.cpus < -3`, want: true},
+		"StringCompare":     {expr: `facts.kernel >= "6.1"`, want: true},
+		"HeterogeneousEq":   {expr: `facts.cpus == "4"`, want: false},
+		"ListEqual":         {expr: `tags == ["web", "eu"]`, want: true},
+		"Null":              {expr: `null == null`, want: true},
+		"Undeclared":        {expr: `kernel == "6.8"`, wantErr: true},
+		"NoSuchKey":         {expr: `facts.gpu == "a100"`, wantErr: true},
+		"NoSuchOverload":    {expr: `facts.cpus && true`, wantErr: true},
+		"UnknownFunction":   {expr: `facts.kernel.lowerAscii()`, wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := Compile(tc.expr)
+			if err != nil {
+				t.Fatalf("Compile(%q): %v", tc.expr, err)
+			}
+			got, err := p.Eval(vars)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Eval(%q): error %v, want error %t", tc.expr, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Eval(%q): -want, +got:\n%s\n", tc.expr, diff)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	cases := map[string]struct {
+		expr    string
+		wantErr bool
+	}{
+		"Valid":            {expr: `has(facts.kernel) && facts.kernel.startsWith("6.") ? true : false`},
+		"Empty":            {expr: ``, wantErr: true},
+		"Unbalanced":       {expr: `(facts.os == "ubuntu"`, wantErr: true},
+		"Trailing":         {expr: `facts.os == "ubuntu" "debian"`, wantErr: true},
+		"UnterminatedStr":  {expr: `facts.os == "ubuntu`, wantErr: true},
+		"InvalidEscape":    {expr: `facts.os == "\d"`, wantErr: true},
+		"InvalidCharacter": {expr: `facts.os = "ubuntu"`, wantErr: true},
+		"HasWithoutField":  {expr: `has(facts)`, wantErr: true},
+		"MissingOperand":   {expr: `facts.cpus >`, wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Compile(tc.expr)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("Compile(%q): error %v, want error %t", tc.expr, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvalBool(t *testing.T) {
+	p, err := Compile(`size(host)`)
+	if err != nil {
+		t.Fatalf("Compile(...): %v", err)
+	}
+	if _, err := p.EvalBool(map[string]any{"host": "web-1"}); err == nil {
+		t.Errorf("EvalBool(...): an expression of an int should be an error")
+	}
+}
@@ -0,0 +1,436 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expr
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errFmtUnexpected    = "unexpected %s at offset %d"
+	errFmtUnterminated  = "unterminated string at offset %d"
+	errFmtInvalidNumber = "invalid number %q at offset %d"
+	errFmtInvalidEscape = "invalid escape sequence at offset %d"
+	errFmtHasArgument   = "has() requires a field selection, at offset %d"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenInt
+	tokenDouble
+	tokenString
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// value is the decoded value of string and number literals.
+	value any
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// puncts are the operators and delimiters, longest first.
+var puncts = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", ".", "(", ")", "[", "]", ",", "?", ":"}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[i:j], pos: i})
+			i = j
+		case unicode.IsDigit(c):
+			t, err := lexNumber(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i += len(t.text)
+		case c == '"' || c == '\'':
+			t, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i += len(t.text)
+		default:
+			p := ""
+			for _, candidate := range puncts {
+				if strings.HasPrefix(src[i:], candidate) {
+					p = candidate
+					break
+				}
+			}
+			if p == "" {
+				return nil, errors.Errorf(errFmtUnexpected, strconv.Quote(string(c)), i)
+			}
+			tokens = append(tokens, token{kind: tokenPunct, text: p, pos: i})
+			i += len(p)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+func lexNumber(src string, start int) (token, error) {
+	j, double := start, false
+	for j < len(src) {
+		c := src[j]
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.' && !double && j+1 < len(src) && src[j+1] >= '0' && src[j+1] <= '9':
+			double = true
+		case (c == 'e' || c == 'E') && j > start:
+			double = true
+			if j+1 < len(src) && (src[j+1] == '+' || src[j+1] == '-') {
+				j++
+			}
+		default:
+			goto done
+		}
+		j++
+	}
+done:
+	text := src[start:j]
+	if double {
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return token{}, errors.Errorf(errFmtInvalidNumber, text, start)
+		}
+		return token{kind: tokenDouble, text: text, value: v, pos: start}, nil
+	}
+	v, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return token{}, errors.Errorf(errFmtInvalidNumber, text, start)
+	}
+	return token{kind: tokenInt, text: text, value: v, pos: start}, nil
+}
+
+func lexString(src string, start int) (token, error) {
+	quote := src[start]
+	var b strings.Builder
+	for j := start + 1; j < len(src); j++ {
+		c := src[j]
+		switch c {
+		case quote:
+			return token{kind: tokenString, text: src[start : j+1], value: b.String(), pos: start}, nil
+		case '\\':
+			if j+1 >= len(src) {
+				return token{}, errors.Errorf(errFmtUnterminated, start)
+			}
+			j++
+			switch src[j] {
+			case '\\', '"', '\'':
+				b.WriteByte(src[j])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				return token{}, errors.Errorf(errFmtInvalidEscape, j-1)
+			}
+		case '\n':
+			return token{}, errors.Errorf(errFmtUnterminated, start)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return token{}, errors.Errorf(errFmtUnterminated, start)
+}
+
+// A parser builds the syntax tree of an expression by recursive descent,
+// with the operator precedence of CEL.
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token { return p.tokens[p.i] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the supplied punctuation.
+func (p *parser) accept(punct string) bool {
+	if t := p.peek(); t.kind == tokenPunct && t.text == punct {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.accept(punct) {
+		t := p.peek()
+		return errors.Errorf(errFmtUnexpected, t, t.pos)
+	}
+	return nil
+}
+
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errors.Errorf(errFmtUnexpected, t, t.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) expr() (node, error) {
+	c, err := p.or()
+	if err != nil || !p.accept("?") {
+		return c, err
+	}
+	t, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	f, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{cond: c, then: t, otherwise: f}, nil
+}
+
+func (p *parser) or() (node, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *parser) and() (node, error) {
+	return p.binary(p.relation, "&&")
+}
+
+func (p *parser) relation() (node, error) {
+	l, err := p.add()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		switch t := p.peek(); {
+		case t.kind == tokenPunct && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+			op = t.text
+		case t.kind == tokenIdent && t.text == "in":
+			op = "in"
+		default:
+			return l, nil
+		}
+		p.next()
+		r, err := p.add()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) add() (node, error) {
+	return p.binary(p.mul, "+", "-")
+}
+
+func (p *parser) mul() (node, error) {
+	return p.binary(p.unary, "*", "/", "%")
+}
+
+// binary parses the left associative operators of a precedence level.
+func (p *parser) binary(operand func() (node, error), ops ...string) (node, error) {
+	l, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, o := range ops {
+			if p.accept(o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return l, nil
+		}
+		r, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			x, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return &unaryNode{op: op, x: x}, nil
+		}
+	}
+	return p.member()
+}
+
+func (p *parser) member() (node, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokenIdent {
+				return nil, errors.Errorf(errFmtUnexpected, t, t.pos)
+			}
+			if p.accept("(") {
+				args, err := p.args(")")
+				if err != nil {
+					return nil, err
+				}
+				x = &callNode{fn: t.text, target: x, args: args}
+				continue
+			}
+			x = &selectNode{x: x, field: t.text}
+		case p.accept("["):
+			i, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &indexNode{x: x, index: i}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenInt, tokenDouble, tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{}, nil
+		}
+		if !p.accept("(") {
+			return &identNode{name: t.text}, nil
+		}
+		args, err := p.args(")")
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "has" {
+			// has(x.f) tests the presence of the field f of the map x.
+			sel, ok := onlyArg(args).(*selectNode)
+			if !ok {
+				return nil, errors.Errorf(errFmtHasArgument, t.pos)
+			}
+			return &hasNode{x: sel.x, field: sel.field}, nil
+		}
+		return &callNode{fn: t.text, args: args}, nil
+	case tokenPunct:
+		switch t.text {
+		case "(":
+			x, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "[":
+			elems, err := p.args("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{elems: elems}, nil
+		}
+	}
+	return nil, errors.Errorf(errFmtUnexpected, t, t.pos)
+}
+
+// args parses the comma separated expressions up to the closing delimiter.
+func (p *parser) args(closing string) ([]node, error) {
+	var args []node
+	if p.accept(closing) {
+		return args, nil
+	}
+	for {
+		a, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+		if p.accept(closing) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func onlyArg(args []node) node {
+	if len(args) != 1 {
+		return nil
+	}
+	return args[0]
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errEncodeInput   = "cannot encode policy input"
+	errQuery         = "cannot query policy engine"
+	errFmtStatusCode = "policy engine responded with status %d: %s"
+	errDecodeResult  = "cannot decode policy result, want a list of denial messages"
+
+	// opaTimeout bounds a query of the policy engine.
+	opaTimeout = 10 * time.Second
+)
+
+// An OPAHook queries a rule of the Data API of Open Policy Agent, e.g.
+// http://opa:8181/v1/data/ssh/deny, with the Input as the input document.
+// The rule evaluates to the messages of its denials, such as a partial set
+// rule deny contains msg if {...}. An undefined rule denies nothing.
+type OPAHook struct {
+	url    string
+	client *http.Client
+}
+
+// NewOPAHook returns an OPAHook querying the rule at the supplied URL.
+func NewOPAHook(url string) *OPAHook {
+	return &OPAHook{url: url, client: &http.Client{Timeout: opaTimeout}}
+}
+
+// Evaluate returns the denials of the rule for the supplied input.
+func (h *OPAHook) Evaluate(ctx context.Context, in Input) ([]string, error) {
+	body, err := json.Marshal(struct {
+		Input Input `json:"input"`
+	}{Input: in})
+	if err != nil {
+		return nil, errors.Wrap(err, errEncodeInput)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, errQuery)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, errQuery)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrap(err, errQuery)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf(errFmtStatusCode, resp.StatusCode, bytes.TrimSpace(content))
+	}
+	result := struct {
+		Result []string `json:"result"`
+	}{}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, errors.Wrap(err, errDecodeResult)
+	}
+	return result.Result, nil
+}
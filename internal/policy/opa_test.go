@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOPAHook(t *testing.T) {
+	in := Input{Kind: "Script", Name: "wipe", Scripts: map[string]string{"initScript": "shred -u /etc/hosts"}}
+
+	cases := map[string]struct {
+		reason  string
+		status  int
+		body    string
+		want    []string
+		wantErr bool
+	}{
+		"Denied": {
+			reason: "The messages of the rule should be returned as denials.",
+			status: http.StatusOK,
+			body:   `{"result": ["initScript shreds files"]}`,
+			want:   []string{"initScript shreds files"},
+		},
+		"Undefined": {
+			reason: "An undefined rule should deny nothing.",
+			status: http.StatusOK,
+			body:   `{}`,
+		},
+		"NotAList": {
+			reason:  "A rule that does not evaluate to messages should be an error, not an acceptance.",
+			status:  http.StatusOK,
+			body:    `{"result": true}`,
+			wantErr: true,
+		},
+		"ServerError": {
+			reason:  "A failed query should be an error.",
+			status:  http.StatusInternalServerError,
+			body:    `{"code": "internal_error"}`,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got := struct {
+					Input Input `json:"input"`
+				}{}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil || r.URL.Path != "/v1/data/ssh/deny" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if diff := cmp.Diff(in, got.Input); diff != "" {
+					t.Errorf("\n%s\nEvaluate(...): -want input, +got input:\n%s\n", tc.reason, diff)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			got, err := NewOPAHook(srv.URL+"/v1/data/ssh/deny").Evaluate(context.Background(), in)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("\n%s\nEvaluate(...): error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nEvaluate(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy rejects the scripts of managed resources that run obviously
+// catastrophic commands, such as deleting the root filesystem or formatting a
+// disk. The rendered scripts are matched against a denylist of regular
+// expressions, then submitted to an optional external policy engine.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	errUnsupported    = "scripts of a %T cannot be evaluated"
+	errFmtUnknownRule = "unknown rule %q, the rules are %s"
+	errHook           = "cannot evaluate the scripts with the policy hook"
+	errFmtViolations  = "scripts violate the policy: %s"
+)
+
+// cmdStart matches the start of a command: the start of a line, or a
+// separator of a list of commands, a pipeline or a subshell, followed by
+// keywords and commands running their arguments, such as sudo.
+const cmdStart = `(^|[;&|({` + "`" + `])\s*((sudo|exec|nohup|time|command|then|do|else|!)(\s+-\S+(\s+[^-\s]\S*)?)*\s+)*`
+
+// cmdEnd matches the end of the argument of a command.
+const cmdEnd = `(\s|[;&|)` + "`" + `]|$)`
+
+// A Rule rejects the scripts matching its pattern.
+type Rule struct {
+	// Name of the rule, used to allow the commands it rejects.
+	Name string
+
+	// Pattern matched against each line of the scripts, comments excluded.
+	Pattern *regexp.Regexp
+
+	// Message describing the danger of the rejected commands.
+	Message string
+}
+
+// DefaultRules is the built-in denylist.
+var DefaultRules = []Rule{
+	{
+		Name:    "rm-root",
+		Pattern: regexp.MustCompile(cmdStart + `rm(\s+[^\s;&|]+)*?\s+(--no-preserve-root|["']?/\*?["']?` + cmdEnd + `)`),
+		Message: "deletes the root filesystem",
+	},
+	{
+		Name:    "mkfs",
+		Pattern: regexp.MustCompile(cmdStart + `(mkfs(\.\w+)?|mke2fs)` + cmdEnd),
+		Message: "formats a filesystem",
+	},
+	{
+		Name:    "dd-device",
+		Pattern: regexp.MustCompile(cmdStart + `dd\s[^;&|]*\bof=/dev/(sd|hd|vd|xvd|nvme|mmcblk|dm-|md|disk|mapper/)`),
+		Message: "overwrites a block device",
+	},
+	{
+		Name:    "device-redirect",
+		Pattern: regexp.MustCompile(`>\s*/dev/(sd|hd|vd|xvd|nvme|mmcblk|dm-|md|disk|mapper/)`),
+		Message: "overwrites a block device",
+	},
+	{
+		Name:    "fork-bomb",
+		Pattern: regexp.MustCompile(`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`),
+		Message: "exhausts the processes of the host",
+	},
+	{
+		Name:    "chmod-root",
+		Pattern: regexp.MustCompile(cmdStart + `(chmod|chown)(\s+-\S+)*\s+-[a-zA-Z]*R[a-zA-Z]*(\s+\S+)?\s+["']?/["']?` + cmdEnd),
+		Message: "changes the permissions of the root filesystem",
+	},
+}
+
+// Rules returns the built-in rules but the allowed ones.
+func Rules(allowed []string) ([]Rule, error) {
+	names := make([]string, 0, len(DefaultRules))
+	for _, r := range DefaultRules {
+		names = append(names, r.Name)
+	}
+	skip := map[string]bool{}
+	for _, a := range allowed {
+		if !contains(names, a) {
+			return nil, errors.Errorf(errFmtUnknownRule, a, strings.Join(names, ", "))
+		}
+		skip[a] = true
+	}
+	rules := make([]Rule, 0, len(DefaultRules))
+	for _, r := range DefaultRules {
+		if !skip[r.Name] {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// A Script is a rendered script of a managed resource together with its
+// field.
+type Script struct {
+	Field   string
+	Content string
+}
+
+// Scripts returns the non-empty scripts of the supplied managed resource,
+// with their variables replaced as they are when executed.
+func Scripts(mg resource.Managed) ([]Script, error) {
+	var all []Script
+	switch cr := mg.(type) {
+	case *apisv1alpha1.Script:
+		p := cr.Spec.ForProvider
+		render := func(s string) string { return sshv1alpha1.RenderScript(s, p.Variables, p.Templating) }
+		all = []Script{
+			{Field: "initScript", Content: render(p.InitScript)},
+			{Field: "statusCheckScript", Content: render(p.StatusCheckScript)},
+			{Field: "updateScript", Content: render(p.UpdateScript)},
+			{Field: "cleanupScript", Content: render(p.CleanupScript)},
+			{Field: "existsScript", Content: render(p.ExistsScript)},
+			{Field: "upToDateScript", Content: render(p.UpToDateScript)},
+			{Field: "diffScript", Content: render(p.DiffScript)},
+			{Field: "postRebootScript", Content: render(p.PostRebootScript)},
+		}
+		if p.StrictMode {
+			// The preamble is evaluated as it is executed, defaulted
+			// when the webhook did not set it.
+			preamble := p.StrictModePreamble
+			if preamble == "" {
+				preamble = apisv1alpha1.DefaultStrictModePreamble
+			}
+			all = append(all, Script{Field: "strictModePreamble", Content: preamble})
+		}
+	case *apisv1alpha1.ScriptSet:
+		p := cr.Spec.ForProvider
+		for i, st := range p.Steps {
+			// The variables of a step take precedence over the variables
+			// of the ScriptSet.
+			vars := append(append([]apisv1alpha1.Variable{}, st.Variables...), p.Variables...)
+			field := fmt.Sprintf("steps[%d].", i)
+			all = append(all,
+				Script{Field: field + "script", Content: sshv1alpha1.ReplaceVariables(st.Script, vars)},
+				Script{Field: field + "statusCheckScript", Content: sshv1alpha1.ReplaceVariables(st.StatusCheckScript, vars)},
+				Script{Field: field + "rollbackScript", Content: sshv1alpha1.ReplaceVariables(st.RollbackScript, vars)},
+				Script{Field: field + "cleanupScript", Content: sshv1alpha1.ReplaceVariables(st.CleanupScript, vars)})
+		}
+	case *apisv1alpha1.NodeScript:
+		p := cr.Spec.ForProvider
+		all = []Script{
+			{Field: "statusCheckScript", Content: sshv1alpha1.ReplaceVariables(p.StatusCheckScript, p.Variables)},
+			{Field: "initScript", Content: sshv1alpha1.ReplaceVariables(p.InitScript, p.Variables)},
+			{Field: "updateScript", Content: sshv1alpha1.ReplaceVariables(p.UpdateScript, p.Variables)},
+			{Field: "cleanupScript", Content: sshv1alpha1.ReplaceVariables(p.CleanupScript, p.Variables)},
+		}
+	case *apisv1alpha1.Command:
+		p := cr.Spec.ForProvider
+		all = []Script{
+			{Field: "check", Content: sshv1alpha1.ReplaceVariables(p.Check, p.Variables)},
+			{Field: "create", Content: sshv1alpha1.ReplaceVariables(p.Create, p.Variables)},
+			{Field: "update", Content: sshv1alpha1.ReplaceVariables(p.Update, p.Variables)},
+			{Field: "delete", Content: sshv1alpha1.ReplaceVariables(p.Delete, p.Variables)},
+		}
+	default:
+		return nil, errors.Errorf(errUnsupported, mg)
+	}
+
+	s := make([]Script, 0, len(all))
+	for _, sc := range all {
+		if sc.Content != "" {
+			s = append(s, sc)
+		}
+	}
+	return s, nil
+}
+
+// A Violation of the policy by a script.
+type Violation struct {
+	// Field of the violating script, empty if the violation was reported
+	// by the hook for the whole resource.
+	Field string
+
+	// Rule that was violated, empty if the violation was reported by the
+	// hook.
+	Rule string
+
+	// Message describing the violation.
+	Message string
+}
+
+func (v Violation) String() string {
+	switch {
+	case v.Field == "":
+		return v.Message
+	case v.Rule == "":
+		return fmt.Sprintf("%s: %s", v.Field, v.Message)
+	}
+	return fmt.Sprintf("%s: %s (%s)", v.Field, v.Message, v.Rule)
+}
+
+// An Input is the document a Hook evaluates.
+type Input struct {
+	Kind      string            `json:"kind"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+
+	// Scripts maps the fields of the rendered scripts to their content.
+	Scripts map[string]string `json:"scripts"`
+}
+
+// A Hook is an external policy engine.
+type Hook interface {
+	// Evaluate returns the reasons to reject the supplied scripts, none if
+	// they are accepted.
+	Evaluate(ctx context.Context, in Input) ([]string, error)
+}
+
+// A Policy rejects the scripts violating its rules or denied by its hook. A
+// nil Policy accepts every script.
+type Policy struct {
+	rules []Rule
+	hook  Hook
+}
+
+// New returns a Policy enforcing the supplied rules, then the supplied hook
+// unless it is nil.
+func New(rules []Rule, hook Hook) *Policy {
+	return &Policy{rules: rules, hook: hook}
+}
+
+// Violations returns the violations of the policy by the scripts of the
+// supplied managed resource.
+func (p *Policy) Violations(ctx context.Context, mg resource.Managed) ([]Violation, error) {
+	if p == nil {
+		return nil, nil
+	}
+	scripts, err := Scripts(mg)
+	if err != nil {
+		return nil, err
+	}
+
+	var vs []Violation
+	for _, s := range scripts {
+		for _, r := range p.rules {
+			if matches(r.Pattern, s.Content) {
+				vs = append(vs, Violation{Field: s.Field, Rule: r.Name, Message: r.Message})
+			}
+		}
+	}
+	if p.hook == nil {
+		return vs, nil
+	}
+
+	in := Input{
+		Kind:      kindOf(mg),
+		Name:      mg.GetName(),
+		Namespace: mg.GetNamespace(),
+		Labels:    mg.GetLabels(),
+		Scripts:   map[string]string{},
+	}
+	for _, s := range scripts {
+		in.Scripts[s.Field] = s.Content
+	}
+	denied, err := p.hook.Evaluate(ctx, in)
+	if err != nil {
+		return nil, errors.Wrap(err, errHook)
+	}
+	for _, msg := range denied {
+		vs = append(vs, Violation{Message: msg})
+	}
+	return vs, nil
+}
+
+// Evaluate returns an error listing the violations of the policy by the
+// scripts of the supplied managed resource, if any.
+func (p *Policy) Evaluate(ctx context.Context, mg resource.Managed) error {
+	vs, err := p.Violations(ctx, mg)
+	if err != nil {
+		return err
+	}
+	if len(vs) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(vs))
+	for _, v := range vs {
+		msgs = append(msgs, v.String())
+	}
+	return errors.Errorf(errFmtViolations, strings.Join(msgs, "; "))
+}
+
+// matches returns whether a line of the script matches the pattern. Lines
+// holding only a comment are skipped.
+func matches(pattern *regexp.Regexp, script string) bool {
+	for _, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// kindOf returns the kind of a managed resource whose scripts are evaluated,
+// which the typed clients do not set.
+func kindOf(mg resource.Managed) string {
+	switch mg.(type) {
+	case *apisv1alpha1.Script:
+		return apisv1alpha1.ScriptKind
+	case *apisv1alpha1.ScriptSet:
+		return apisv1alpha1.ScriptSetKind
+	case *apisv1alpha1.NodeScript:
+		return apisv1alpha1.NodeScriptKind
+	case *apisv1alpha1.Command:
+		return apisv1alpha1.CommandKind
+	}
+	return ""
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
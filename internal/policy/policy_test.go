@@ -0,0 +1,224 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+var errBoom = errors.New("boom")
+
+func TestDefaultRules(t *testing.T) {
+	cases := map[string]struct {
+		script string
+		want   []string
+	}{
+		"RmRoot":             {script: "rm -rf /", want: []string{"rm-root"}},
+		"RmRootGlob":         {script: "sudo rm -rf /*", want: []string{"rm-root"}},
+		"RmRootSudoUser":     {script: "sudo -u root rm -rf /", want: []string{"rm-root"}},
+		"RmRootSubshell":     {script: "echo $(rm -rf /)", want: []string{"rm-root"}},
+		"RmRootQuoted":       {script: `rm -r -f "/"`, want: []string{"rm-root"}},
+		"RmRootAfterPath":    {script: "cd /tmp && rm -rf /tmp/build / ; echo done", want: []string{"rm-root"}},
+		"RmNoPreserveRoot":   {script: "rm -rf --no-preserve-root $DIR", want: []string{"rm-root"}},
+		"RmDirectory":        {script: "rm -rf /opt/app /var/cache/app/*"},
+		"RmRootOfDirectory":  {script: "rm -rf ./build/"},
+		"Mkfs":               {script: "mkfs.ext4 /dev/sdb1", want: []string{"mkfs"}},
+		"Mke2fs":             {script: "if true; then mke2fs -t ext4 /dev/vdb; fi", want: []string{"mkfs"}},
+		"MkfsInName":         {script: "echo skip-mkfs-step"},
+		"DdDevice":           {script: "dd if=/dev/zero of=/dev/sda bs=1M", want: []string{"dd-device"}},
+		"DdNvme":             {script: "sudo dd if=image.raw of=/dev/nvme0n1", want: []string{"dd-device"}},
+		"DdFile":             {script: "dd if=/dev/zero of=/swapfile bs=1M count=1024"},
+		"DdNull":             {script: "dd if=/dev/sda of=/dev/null count=1"},
+		"DeviceRedirect":     {script: "cat image > /dev/sdb", want: []string{"device-redirect"}},
+		"NullRedirect":       {script: "command -v curl > /dev/null 2>&1"},
+		"ForkBomb":           {script: ":(){ :|:& };:", want: []string{"fork-bomb"}},
+		"ChmodRoot":          {script: "chmod -R 777 /", want: []string{"chmod-root"}},
+		"ChownRoot":          {script: "chown -R nobody:nogroup / && true", want: []string{"chmod-root"}},
+		"ChmodDirectory":     {script: "chmod -R 755 /opt/app"},
+		"Comment":            {script: "# never run rm -rf / here\ntrue"},
+		"Several":            {script: "mkfs.xfs /dev/sdc\nrm -rf /", want: []string{"rm-root", "mkfs"}},
+		"MultilineScript":    {script: "set -eu\nrm -rf /var/lib/app\nmkdir -p /var/lib/app"},
+		"ArgumentOfEcho":     {script: "echo use mkfs.ext4 to format"},
+		"SudoPasswordAndDir": {script: "echo secret | sudo -u root -S rm -rf /opt/app"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			for _, r := range DefaultRules {
+				if matches(r.Pattern, tc.script) {
+					got = append(got, r.Name)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\nDefaultRules matching %q: -want, +got:\n%s\n", tc.script, diff)
+			}
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		allowed []string
+		want    int
+		wantErr bool
+	}{
+		"All": {
+			reason: "Every built-in rule should be enforced unless allowed.",
+			want:   len(DefaultRules),
+		},
+		"Allowed": {
+			reason:  "Allowed rules should not be enforced.",
+			allowed: []string{"mkfs", "dd-device"},
+			want:    len(DefaultRules) - 2,
+		},
+		"Unknown": {
+			reason:  "Allowing a rule that does not exist should be rejected.",
+			allowed: []string{"mkswap"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Rules(tc.allowed)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("\n%s\nRules(...): error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+			if len(got) != tc.want {
+				t.Errorf("\n%s\nRules(...): %d rules, want %d", tc.reason, len(got), tc.want)
+			}
+		})
+	}
+}
+
+type hookFn func(ctx context.Context, in Input) ([]string, error)
+
+func (fn hookFn) Evaluate(ctx context.Context, in Input) ([]string, error) { return fn(ctx, in) }
+
+func TestViolations(t *testing.T) {
+	cr := &apisv1alpha1.Script{Spec: apisv1alpha1.ScriptSpec{ForProvider: apisv1alpha1.ScriptParameters{
+		Variables:         []apisv1alpha1.Variable{{Name: "DIR", Value: "/"}},
+		InitScript:        "rm -rf {{DIR}}",
+		StatusCheckScript: "test -d /opt/app",
+	}}}
+	cr.SetName("cleanup")
+
+	type want struct {
+		vs  []Violation
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		policy *Policy
+		mg     resource.Managed
+		want   want
+	}{
+		"NoPolicy": {
+			reason: "A nil Policy should accept every script.",
+			mg:     cr,
+		},
+		"Rendered": {
+			reason: "Scripts should be evaluated after their variables are replaced.",
+			policy: New(DefaultRules, nil),
+			mg:     cr,
+			want:   want{vs: []Violation{{Field: "initScript", Rule: "rm-root", Message: "deletes the root filesystem"}}},
+		},
+		"ScriptSet": {
+			reason: "The scripts of the steps of a ScriptSet should be evaluated with their own variables.",
+			policy: New(DefaultRules, nil),
+			mg: &apisv1alpha1.ScriptSet{Spec: apisv1alpha1.ScriptSetSpec{ForProvider: apisv1alpha1.ScriptSetParameters{
+				Variables: []apisv1alpha1.Variable{{Name: "DISK", Value: "/dev/null"}},
+				Steps: []apisv1alpha1.ScriptStep{
+					{Name: "probe", Script: "dd if=/dev/zero of={{DISK}} count=1"},
+					{Name: "wipe", Script: "dd if=/dev/zero of={{DISK}}", Variables: []apisv1alpha1.Variable{{Name: "DISK", Value: "/dev/vda"}}},
+				},
+			}}},
+			want: want{vs: []Violation{{Field: "steps[1].script", Rule: "dd-device", Message: "overwrites a block device"}}},
+		},
+		"Hook": {
+			reason: "The denials of the hook should be violations, the hook receiving the rendered scripts.",
+			policy: New(nil, hookFn(func(_ context.Context, in Input) ([]string, error) {
+				want := Input{Kind: "Script", Name: "cleanup", Scripts: map[string]string{
+					"initScript":        "rm -rf /",
+					"statusCheckScript": "test -d /opt/app",
+				}}
+				if diff := cmp.Diff(want, in); diff != "" {
+					return nil, errors.Errorf("-want input, +got input:\n%s", diff)
+				}
+				return []string{"Scripts of team a must not delete directories"}, nil
+			})),
+			mg:   cr,
+			want: want{vs: []Violation{{Message: "Scripts of team a must not delete directories"}}},
+		},
+		"HookError": {
+			reason: "Scripts should not be accepted if the hook cannot evaluate them.",
+			policy: New(DefaultRules, hookFn(func(_ context.Context, _ Input) ([]string, error) { return nil, errBoom })),
+			mg:     cr,
+			want:   want{err: errors.Wrap(errBoom, errHook)},
+		},
+		"Unsupported": {
+			reason: "Resources without scripts should be rejected.",
+			policy: New(DefaultRules, nil),
+			mg:     &apisv1alpha1.RemoteFile{},
+			want:   want{err: errors.Errorf(errUnsupported, &apisv1alpha1.RemoteFile{})},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			vs, err := tc.policy.Violations(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nViolations(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.vs, vs); diff != "" {
+				t.Errorf("\n%s\nViolations(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	cr := &apisv1alpha1.Command{Spec: apisv1alpha1.CommandSpec{ForProvider: apisv1alpha1.CommandParameters{
+		Create: "mkfs.ext4 /dev/sdb",
+		Delete: "rm -rf /",
+	}}}
+	err := New(DefaultRules, nil).Evaluate(context.Background(), cr)
+	want := errors.Errorf(errFmtViolations, "create: formats a filesystem (mkfs); delete: deletes the root filesystem (rm-root)")
+	if diff := cmp.Diff(want, err, test.EquateErrors()); diff != "" {
+		t.Errorf("Evaluate(...): -want error, +got error:\n%s\n", diff)
+	}
+}
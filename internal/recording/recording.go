@@ -0,0 +1,217 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package recording records the executions of scripts as timestamped
+// transcripts in the asciicast v2 format of asciinema, and stores them in a
+// Sink.
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/provider-ssh/internal/redact"
+)
+
+const (
+	errMkdir = "cannot create the directory of the recording"
+	errWrite = "cannot write the recording"
+
+	// Extension is the file extension of the recordings.
+	Extension = ".cast"
+
+	// The size of the terminal recorded in the header. Scripts are not run
+	// in a terminal, it only sizes the players.
+	width  = 120
+	height = 40
+)
+
+// A Recording is the transcript of a script execution: the script as input,
+// and its stdout and stderr interleaved as output in the order they were
+// received, each timestamped relative to the start of the execution. The
+// secrets known to the redact package are masked in every event.
+type Recording struct {
+	started time.Time
+	title   string
+
+	mu      sync.Mutex
+	events  []event
+	pending map[string][]byte
+}
+
+type event struct {
+	at   time.Duration
+	code string
+	data string
+}
+
+// New returns a recording of an execution started at the supplied time.
+func New(title string, started time.Time) *Recording {
+	return &Recording{started: started, title: title, pending: map[string][]byte{}}
+}
+
+// Input records the script fed to the execution.
+func (r *Recording) Input(script string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.add("i", script)
+}
+
+// Stdout returns a writer recording the stdout of the execution.
+func (r *Recording) Stdout() io.Writer {
+	return output{r: r, stream: "stdout"}
+}
+
+// Stderr returns a writer recording the stderr of the execution.
+func (r *Recording) Stderr() io.Writer {
+	return output{r: r, stream: "stderr"}
+}
+
+// Exit records the exit code of the execution, and the error ending it if
+// it did not exit.
+func (r *Recording) Exit(code int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flush()
+	msg := fmt.Sprintf("exit code %d", code)
+	if err != nil {
+		msg += ": " + err.Error()
+	}
+	r.add("m", msg)
+}
+
+// Bytes returns the recording in asciicast v2 format: a header line followed
+// by a line per event.
+func (r *Recording) Bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flush()
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	_ = enc.Encode(map[string]any{
+		"version":   2,
+		"width":     width,
+		"height":    height,
+		"timestamp": r.started.Unix(),
+		"title":     r.title,
+	})
+	for _, e := range r.events {
+		_ = enc.Encode([]any{e.at.Seconds(), e.code, redact.String(e.data)})
+	}
+	return b.Bytes()
+}
+
+// add records an event, the lock being held.
+func (r *Recording) add(code, data string) {
+	r.events = append(r.events, event{at: time.Since(r.started), code: code, data: data})
+}
+
+// flush records the incomplete runes held back by the outputs, the lock
+// being held.
+func (r *Recording) flush() {
+	for _, stream := range []string{"stdout", "stderr"} {
+		if p := r.pending[stream]; len(p) > 0 {
+			r.add("o", string(p))
+			delete(r.pending, stream)
+		}
+	}
+}
+
+// An output records the writes of a stream as output events. A rune split
+// across writes is held back until it is complete, so that it is not
+// replaced when it is encoded.
+type output struct {
+	r      *Recording
+	stream string
+}
+
+func (o output) Write(p []byte) (int, error) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	b := append(o.r.pending[o.stream], p...)
+	n := complete(b)
+	if n > 0 {
+		o.r.add("o", string(b[:n]))
+	}
+	o.r.pending[o.stream] = append([]byte(nil), b[n:]...)
+	return len(p), nil
+}
+
+// complete returns the length of the supplied bytes without the incomplete
+// rune they may end with.
+func complete(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+// A Sink stores recordings.
+type Sink interface {
+	// Store stores the supplied recording under the supplied name, a slash
+	// separated relative path such as script/20240102T150405.000Z-Create.cast.
+	Store(ctx context.Context, name string, data []byte) error
+}
+
+// A DirSink stores recordings as files below a directory, such as the mount
+// of a PersistentVolume.
+type DirSink struct {
+	dir string
+}
+
+// NewDirSink returns a Sink storing recordings below the supplied directory.
+func NewDirSink(dir string) *DirSink {
+	return &DirSink{dir: dir}
+}
+
+// Store writes the recording to the file of the supplied name, readable by
+// the provider only.
+func (s *DirSink) Store(_ context.Context, name string, data []byte) error {
+	file := filepath.Join(s.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return errors.Wrap(err, errMkdir)
+	}
+	return errors.Wrap(os.WriteFile(file, data, 0o600), errWrite)
+}
+
+var unsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Name returns the name of the recording of an operation of a resource
+// started at the supplied time, on the supplied host if it is not empty:
+// <resource>/<UTC time>-<operation>[-<host>].cast.
+func Name(resource, operation, host string, started time.Time) string {
+	name := started.UTC().Format("20060102T150405.000Z") + "-" + operation
+	if host != "" {
+		name += "-" + host
+	}
+	return unsafe.ReplaceAllString(resource, "_") + "/" + unsafe.ReplaceAllString(name, "_") + Extension
+}
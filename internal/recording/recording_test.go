@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/provider-ssh/internal/redact"
+)
+
+// events returns the code and data of the events of the supplied recording,
+// without their times.
+func events(t *testing.T, data []byte) (map[string]any, [][]string) {
+	t.Helper()
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	header := map[string]any{}
+	if err := json.Unmarshal(lines[0], &header); err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, l := range lines[1:] {
+		var e []any
+		if err := json.Unmarshal(l, &e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, []string{e[1].(string), e[2].(string)})
+	}
+	return header, got
+}
+
+func TestRecording(t *testing.T) {
+	redact.Add("hunter2")
+	started := time.Unix(1700000000, 0)
+	snowman := []byte("☃")
+
+	cases := map[string]struct {
+		reason string
+		record func(r *Recording)
+		want   [][]string
+	}{
+		"Interleaved": {
+			reason: "The stdout and stderr should be recorded in the order they were written, after the script.",
+			record: func(r *Recording) {
+				r.Input("echo a; echo b >&2; exit 3\n")
+				_, _ = r.Stdout().Write([]byte("a\n"))
+				_, _ = r.Stderr().Write([]byte("b\n"))
+				r.Exit(3, nil)
+			},
+			want: [][]string{{"i", "echo a; echo b >&2; exit 3\n"}, {"o", "a\n"}, {"o", "b\n"}, {"m", "exit code 3"}},
+		},
+		"SplitRune": {
+			reason: "A rune split across writes should be recorded once complete.",
+			record: func(r *Recording) {
+				_, _ = r.Stdout().Write(append([]byte("x"), snowman[:1]...))
+				_, _ = r.Stdout().Write(snowman[1:])
+			},
+			want: [][]string{{"o", "x"}, {"o", "☃"}},
+		},
+		"Redacted": {
+			reason: "The known secrets should be masked in the script and the output.",
+			record: func(r *Recording) {
+				r.Input("login hunter2\n")
+				_, _ = r.Stdout().Write([]byte("logged in with hunter2\n"))
+				r.Exit(1, errors.New("connection lost"))
+			},
+			want: [][]string{{"i", "login " + redact.Mask + "\n"}, {"o", "logged in with " + redact.Mask + "\n"}, {"m", "exit code 1: connection lost"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := New("Create script of Script web", started)
+			tc.record(r)
+			header, got := events(t, r.Bytes())
+			wantHeader := map[string]any{"version": 2.0, "width": 120.0, "height": 40.0, "timestamp": 1700000000.0, "title": "Create script of Script web"}
+			if diff := cmp.Diff(wantHeader, header); diff != "" {
+				t.Errorf("\n%s\nBytes(): -want header, +got header:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nBytes(): -want events, +got events:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDirSink(t *testing.T) {
+	dir := t.TempDir()
+	name := Name("web", "Create", "paris/edge 01", time.Date(2024, 1, 2, 15, 4, 5, 6e6, time.UTC))
+	if diff := cmp.Diff("web/20240102T150405.006Z-Create-paris_edge_01.cast", name); diff != "" {
+		t.Errorf("\nThe name should be made of the resource, time, operation and host, with unsafe characters replaced.\nName(...): -want, +got:\n%s\n", diff)
+	}
+
+	if err := NewDirSink(dir).Store(context.Background(), name, []byte("cast")); err != nil {
+		t.Fatalf("\nStore(...): %v", err)
+	}
+	file := filepath.Join(dir, "web", "20240102T150405.006Z-Create-paris_edge_01.cast")
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("cast", string(got)); diff != "" {
+		t.Errorf("\nThe recording should be written below the directory.\nStore(...): -want, +got:\n%s\n", diff)
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("\nThe recording should only be readable by the provider.\nStat(...): %v, %v", fi.Mode(), err)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package redact masks secrets in log lines. Secrets are either registered
+// when they are read, such as the password of a host or the value of a
+// sensitive variable, or recognised by their shape, such as PEM private keys.
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// Mask replaces the secrets found in log lines.
+const Mask = "[REDACTED]"
+
+// minLength is the length of the shortest secret registered. Shorter values
+// would mask unrelated parts of most log lines.
+const minLength = 4
+
+var (
+	mu       sync.RWMutex
+	secrets  = map[string]struct{}{}
+	replacer = strings.NewReplacer()
+
+	// patterns match secrets that are masked whether they were registered
+	// or not, keeping the text of their first group.
+	patterns = []*regexp.Regexp{
+		regexp.MustCompile(`(-----BEGIN [A-Z ]*PRIVATE KEY-----)[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+		regexp.MustCompile(`(?i)((?:password|passphrase|privateKey)"?\s*[:=]\s*"?)[^\s",}]+`),
+	}
+)
+
+// Add registers the supplied secrets, masked from then on. Values shorter
+// than four bytes are ignored.
+func Add(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	added := false
+	for _, v := range values {
+		if _, ok := secrets[v]; ok || len(v) < minLength {
+			continue
+		}
+		secrets[v] = struct{}{}
+		added = true
+	}
+	if !added {
+		return
+	}
+
+	// The longest secrets come first so that secrets containing others are
+	// masked as a whole.
+	sorted := make([]string, 0, len(secrets))
+	for s := range secrets {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	oldnew := make([]string, 0, 2*len(sorted))
+	for _, s := range sorted {
+		oldnew = append(oldnew, s, Mask)
+	}
+	replacer = strings.NewReplacer(oldnew...)
+}
+
+// String returns s with its secrets masked.
+func String(s string) string {
+	mu.RLock()
+	r := replacer
+	mu.RUnlock()
+	s = r.Replace(s)
+	for _, p := range patterns {
+		s = p.ReplaceAllString(s, "${1}"+Mask)
+	}
+	return s
+}
+
+// Error returns err with the secrets of its message masked, or err itself
+// if its message holds none.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if masked := String(msg); masked != msg {
+		return redacted(masked)
+	}
+	return err
+}
+
+type redacted string
+
+func (e redacted) Error() string {
+	return string(e)
+}
+
+// NewLogger returns a logger masking the secrets of the messages, errors and
+// values logged through the supplied logger.
+func NewLogger(l logr.Logger) logr.Logger {
+	return l.WithSink(NewLogSink(l.GetSink()))
+}
+
+// NewLogSink returns a sink masking the secrets of the messages, errors and
+// values it passes on to the supplied sink.
+func NewLogSink(s logr.LogSink) logr.LogSink {
+	if cd, ok := s.(logr.CallDepthLogSink); ok {
+		// Skip the frame of the redacting sink.
+		s = cd.WithCallDepth(1)
+	}
+	return &sink{LogSink: s}
+}
+
+type sink struct {
+	logr.LogSink
+}
+
+func (s *sink) Info(level int, msg string, kv ...any) {
+	s.LogSink.Info(level, String(msg), values(kv)...)
+}
+
+func (s *sink) Error(err error, msg string, kv ...any) {
+	s.LogSink.Error(Error(err), String(msg), values(kv)...)
+}
+
+func (s *sink) WithValues(kv ...any) logr.LogSink {
+	return &sink{LogSink: s.LogSink.WithValues(values(kv)...)}
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	return &sink{LogSink: s.LogSink.WithName(name)}
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	if cd, ok := s.LogSink.(logr.CallDepthLogSink); ok {
+		return &sink{LogSink: cd.WithCallDepth(depth)}
+	}
+	return s
+}
+
+// values masks the secrets of the strings, byte slices and errors of the
+// supplied key and value pairs. Keys are left as they are.
+func values(kv []any) []any {
+	out := make([]any, len(kv))
+	for i, v := range kv {
+		if i%2 == 0 {
+			out[i] = v
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			out[i] = String(v)
+		case []byte:
+			out[i] = String(string(v))
+		case error:
+			out[i] = Error(v)
+		default:
+			out[i] = v
+		}
+	}
+	return out
+}
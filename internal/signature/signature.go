@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signature verifies the OpenPGP detached signatures of the scripts of
+// managed resources, set in their signature annotation, against a keyring of
+// trusted public keys. The signatures cover the payload of the scripts and of
+// the settings they are executed with, in which each non-empty script or
+// setting is preceded by a line holding its field and its length in bytes.
+package signature
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	// The detached signatures of GnuPG are verified by the deprecated but
+	// frozen openpgp package.
+	"golang.org/x/crypto/openpgp"                  // nolint: staticcheck
+	"golang.org/x/crypto/openpgp/armor"            // nolint: staticcheck
+	pgperrors "golang.org/x/crypto/openpgp/errors" // nolint: staticcheck
+	"golang.org/x/crypto/openpgp/packet"           // nolint: staticcheck
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+const (
+	errNoKeys        = "keyring holds no public key"
+	errReadKeyring   = "cannot read keyring"
+	errUnsupported   = "scripts of a %T cannot be signed"
+	errFmtNoSig      = "scripts are not signed, the %s annotation is not set"
+	errInvalidSig    = "signature of the scripts is not valid, or not made by a trusted key"
+	errFmtUnknownKey = "signature of the scripts was made by untrusted key %X"
+)
+
+// A Script is a script of a managed resource together with its field.
+type Script struct {
+	Field   string
+	Content string
+}
+
+// Payload returns the signed payload of the supplied scripts, in order.
+// Empty scripts are left out.
+func Payload(scripts []Script) []byte {
+	var b bytes.Buffer
+	for _, s := range scripts {
+		if s.Content == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d\n%s\n", s.Field, len(s.Content), s.Content)
+	}
+	return b.Bytes()
+}
+
+// Scripts returns the scripts of the supplied managed resource, in the order
+// of their fields, followed by its variables and the settings changing how
+// they are executed.
+func Scripts(mg resource.Managed) ([]Script, error) {
+	switch cr := mg.(type) {
+	case *apisv1alpha1.Script:
+		// The interpreter and the preamble are signed with the defaults the
+		// webhook sets, so that a manifest signs the same before and after
+		// admission.
+		p := cr.Spec.ForProvider
+		interpreter := p.Interpreter
+		if interpreter == "" {
+			interpreter = apisv1alpha1.DefaultInterpreter
+		}
+		preamble := ""
+		if p.StrictMode {
+			preamble = p.StrictModePreamble
+			if preamble == "" {
+				preamble = apisv1alpha1.DefaultStrictModePreamble
+			}
+		}
+		sudo := ""
+		if p.SudoEnabled != nil {
+			sudo = strconv.FormatBool(*p.SudoEnabled)
+		}
+		s := []Script{
+			{Field: "initScript", Content: p.InitScript},
+			{Field: "statusCheckScript", Content: p.StatusCheckScript},
+			{Field: "updateScript", Content: p.UpdateScript},
+			{Field: "cleanupScript", Content: p.CleanupScript},
+			{Field: "existsScript", Content: p.ExistsScript},
+			{Field: "upToDateScript", Content: p.UpToDateScript},
+			{Field: "diffScript", Content: p.DiffScript},
+			{Field: "postRebootScript", Content: p.PostRebootScript},
+			{Field: "interpreter", Content: interpreter},
+			{Field: "strictModePreamble", Content: preamble},
+		}
+		s = append(s, variables("", p.Variables)...)
+		return append(s,
+			Script{Field: "sudoEnabled", Content: sudo},
+			Script{Field: "templating", Content: string(p.Templating)},
+			Script{Field: "container", Content: encode(p.Container)},
+			Script{Field: "namespaces", Content: encode(p.Namespaces)},
+			Script{Field: "chroot", Content: p.Chroot},
+			flag("loginShell", p.LoginShell)), nil
+	case *apisv1alpha1.ScriptSet:
+		var s []Script
+		for i, st := range cr.Spec.ForProvider.Steps {
+			field := fmt.Sprintf("steps[%d].", i)
+			s = append(s,
+				Script{Field: field + "script", Content: st.Script},
+				Script{Field: field + "statusCheckScript", Content: st.StatusCheckScript},
+				Script{Field: field + "rollbackScript", Content: st.RollbackScript},
+				Script{Field: field + "cleanupScript", Content: st.CleanupScript})
+			s = append(s, variables(field, st.Variables)...)
+		}
+		s = append(s, variables("", cr.Spec.ForProvider.Variables)...)
+		return append(s, flag("sudoEnabled", cr.Spec.ForProvider.SudoEnabled)), nil
+	case *apisv1alpha1.NodeScript:
+		p := cr.Spec.ForProvider
+		s := []Script{
+			{Field: "statusCheckScript", Content: p.StatusCheckScript},
+			{Field: "initScript", Content: p.InitScript},
+			{Field: "updateScript", Content: p.UpdateScript},
+			{Field: "cleanupScript", Content: p.CleanupScript},
+		}
+		s = append(s, variables("", p.Variables)...)
+		return append(s, flag("sudoEnabled", p.SudoEnabled)), nil
+	case *apisv1alpha1.Command:
+		p := cr.Spec.ForProvider
+		s := []Script{
+			{Field: "check", Content: p.Check},
+			{Field: "create", Content: p.Create},
+			{Field: "update", Content: p.Update},
+			{Field: "delete", Content: p.Delete},
+		}
+		s = append(s, variables("", p.Variables)...)
+		return append(s, flag("sudoEnabled", p.SudoEnabled)), nil
+	}
+	return nil, errors.Errorf(errUnsupported, mg)
+}
+
+// variables returns the variables replaced in the scripts, each as a
+// NAME=value line, since tampering with them changes the rendered scripts.
+func variables(prefix string, vars []apisv1alpha1.Variable) []Script {
+	s := make([]Script, len(vars))
+	for i, v := range vars {
+		s[i] = Script{Field: fmt.Sprintf("%svariables[%d]", prefix, i), Content: v.Name + "=" + v.Value}
+	}
+	return s
+}
+
+// flag returns a setting that is only signed when it is enabled.
+func flag(field string, enabled bool) Script {
+	if !enabled {
+		return Script{Field: field}
+	}
+	return Script{Field: field, Content: "true"}
+}
+
+// encode returns the JSON encoding of the supplied setting, empty if it is
+// nil.
+func encode(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return ""
+	}
+	return string(b)
+}
+
+// A Verifier verifies the signatures of the scripts of managed resources. A
+// nil Verifier accepts every resource.
+type Verifier struct {
+	keyring openpgp.EntityList
+}
+
+// NewVerifier returns a Verifier trusting the public keys of the supplied
+// keyring, ASCII armored or binary.
+func NewVerifier(keyring []byte) (*Verifier, error) {
+	keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keyring))
+	if err != nil {
+		if keys, err = openpgp.ReadKeyRing(bytes.NewReader(keyring)); err != nil {
+			return nil, errors.Wrap(err, errReadKeyring)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New(errNoKeys)
+	}
+	return &Verifier{keyring: keys}, nil
+}
+
+// Verify returns an error unless the scripts of the supplied managed resource
+// are signed by a trusted key, or the Verifier is nil.
+func (v *Verifier) Verify(mg resource.Managed) error {
+	if v == nil {
+		return nil
+	}
+	scripts, err := Scripts(mg)
+	if err != nil {
+		return err
+	}
+	sig := mg.GetAnnotations()[apisv1alpha1.AnnotationKeySignature]
+	if strings.TrimSpace(sig) == "" {
+		return errors.Errorf(errFmtNoSig, apisv1alpha1.AnnotationKeySignature)
+	}
+	_, err = openpgp.CheckArmoredDetachedSignature(v.keyring, bytes.NewReader(Payload(scripts)), strings.NewReader(sig))
+	if errors.Is(err, pgperrors.ErrUnknownIssuer) {
+		return errors.Errorf(errFmtUnknownKey, issuer(sig))
+	}
+	if err != nil {
+		return errors.New(errInvalidSig)
+	}
+	return nil
+}
+
+// issuer returns the ID of the key that made the supplied armored signature,
+// or 0 if it cannot be read.
+func issuer(sig string) uint64 {
+	block, err := armor.Decode(strings.NewReader(sig))
+	if err != nil {
+		return 0
+	}
+	p, err := packet.Read(block.Body)
+	if err != nil {
+		return 0
+	}
+	switch s := p.(type) {
+	case *packet.Signature:
+		if s.IssuerKeyId != nil {
+			return *s.IssuerKeyId
+		}
+	case *packet.SignatureV3:
+		return s.IssuerKeyId
+	}
+	return 0
+}
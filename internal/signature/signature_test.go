@@ -0,0 +1,282 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"       // nolint: staticcheck
+	"golang.org/x/crypto/openpgp/armor" // nolint: staticcheck
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+func TestPayload(t *testing.T) {
+	enabled := true
+	cases := map[string]struct {
+		reason string
+		mg     resource.Managed
+		want   string
+	}{
+		"Script": {
+			reason: "The non-empty scripts of a Script should be preceded by their field and length.",
+			mg: &apisv1alpha1.Script{Spec: apisv1alpha1.ScriptSpec{ForProvider: apisv1alpha1.ScriptParameters{
+				InitScript:        "touch /tmp/a",
+				StatusCheckScript: "test -f /tmp/a",
+			}}},
+			want: "initScript 12\ntouch /tmp/a\nstatusCheckScript 14\ntest -f /tmp/a\ninterpreter 7\n/bin/sh\n",
+		},
+		"StrictMode": {
+			reason: "The preamble of strict mode should be signed with its default, as the interpreter is.",
+			mg: &apisv1alpha1.Script{Spec: apisv1alpha1.ScriptSpec{ForProvider: apisv1alpha1.ScriptParameters{
+				StatusCheckScript: "true",
+				Interpreter:       "/bin/bash",
+				StrictMode:        true,
+			}}},
+			want: fmt.Sprintf("statusCheckScript 4\ntrue\ninterpreter 9\n/bin/bash\nstrictModePreamble %d\n%s\n",
+				len(apisv1alpha1.DefaultStrictModePreamble), apisv1alpha1.DefaultStrictModePreamble),
+		},
+		"Settings": {
+			reason: "The variables of a Script and the settings its scripts are executed with should be signed after its scripts.",
+			mg: &apisv1alpha1.Script{Spec: apisv1alpha1.ScriptSpec{ForProvider: apisv1alpha1.ScriptParameters{
+				Variables:         []apisv1alpha1.Variable{{Name: "DIR", Value: "/opt/app"}},
+				StatusCheckScript: "test -d {{DIR}}",
+				SudoEnabled:       &enabled,
+				Templating:        apisv1alpha1.TemplatingBraces,
+				Container:         &apisv1alpha1.Container{Name: "app"},
+				Namespaces:        &apisv1alpha1.Namespaces{TargetPID: 1},
+				Chroot:            "/mnt",
+				LoginShell:        true,
+			}}},
+			want: "statusCheckScript 15\ntest -d {{DIR}}\ninterpreter 7\n/bin/sh\nvariables[0] 12\nDIR=/opt/app\n" +
+				"sudoEnabled 4\ntrue\ntemplating 6\nbraces\ncontainer 14\n{\"name\":\"app\"}\n" +
+				"namespaces 15\n{\"targetPID\":1}\nchroot 4\n/mnt\nloginShell 4\ntrue\n",
+		},
+		"ScriptSet": {
+			reason: "The scripts of the steps of a ScriptSet should be qualified by the index of their step.",
+			mg: &apisv1alpha1.ScriptSet{Spec: apisv1alpha1.ScriptSetSpec{ForProvider: apisv1alpha1.ScriptSetParameters{
+				Steps: []apisv1alpha1.ScriptStep{{Name: "a", Script: "true"}, {Name: "b", Script: "false", RollbackScript: "true"}},
+			}}},
+			want: "steps[0].script 4\ntrue\nsteps[1].script 5\nfalse\nsteps[1].rollbackScript 4\ntrue\n",
+		},
+		"ScriptSetVariables": {
+			reason: "The variables of the steps of a ScriptSet should be qualified by the index of their step, and followed by the shared ones.",
+			mg: &apisv1alpha1.ScriptSet{Spec: apisv1alpha1.ScriptSetSpec{ForProvider: apisv1alpha1.ScriptSetParameters{
+				Variables:   []apisv1alpha1.Variable{{Name: "A", Value: "1"}},
+				Steps:       []apisv1alpha1.ScriptStep{{Name: "a", Script: "true", Variables: []apisv1alpha1.Variable{{Name: "B", Value: "2"}}}},
+				SudoEnabled: true,
+			}}},
+			want: "steps[0].script 4\ntrue\nsteps[0].variables[0] 3\nB=2\nvariables[0] 3\nA=1\nsudoEnabled 4\ntrue\n",
+		},
+		"Command": {
+			reason: "The commands of a Command should be signed.",
+			mg: &apisv1alpha1.Command{Spec: apisv1alpha1.CommandSpec{ForProvider: apisv1alpha1.CommandParameters{
+				Create: "useradd app",
+			}}},
+			want: "create 11\nuseradd app\n",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scripts, err := Scripts(tc.mg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, string(Payload(scripts))); diff != "" {
+				t.Errorf("\n%s\nPayload(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	trusted := newEntity(t)
+	untrusted := newEntity(t)
+	params := apisv1alpha1.ScriptParameters{
+		Variables:         []apisv1alpha1.Variable{{Name: "FILE", Value: "/tmp/a"}},
+		InitScript:        "rm -f {{FILE}}",
+		StatusCheckScript: "test ! -f {{FILE}}",
+	}
+	scripts, _ := Scripts(script(params, ""))
+	payload := Payload(scripts)
+
+	var keyring bytes.Buffer
+	if err := trusted.Serialize(&keyring); err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifier(keyring.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := params
+	tampered.InitScript = "rm -rf /"
+	tamperedVariable := params
+	tamperedVariable.Variables = []apisv1alpha1.Variable{{Name: "FILE", Value: "-r /"}}
+	sudo := true
+	tamperedSudo := params
+	tamperedSudo.SudoEnabled = &sudo
+
+	cases := map[string]struct {
+		reason string
+		v      *Verifier
+		mg     resource.Managed
+		want   error
+	}{
+		"Valid": {
+			reason: "Scripts signed by a trusted key should be verified.",
+			v:      v,
+			mg:     script(params, sign(t, trusted, payload)),
+		},
+		"Unsigned": {
+			reason: "Scripts without a signature should be rejected.",
+			v:      v,
+			mg:     script(params, ""),
+			want:   errors.Errorf(errFmtNoSig, apisv1alpha1.AnnotationKeySignature),
+		},
+		"Tampered": {
+			reason: "Scripts that differ from the signed ones should be rejected.",
+			v:      v,
+			mg:     script(tampered, sign(t, trusted, payload)),
+			want:   errors.New(errInvalidSig),
+		},
+		"TamperedVariable": {
+			reason: "Scripts whose variables differ from the signed ones should be rejected, since they render other scripts.",
+			v:      v,
+			mg:     script(tamperedVariable, sign(t, trusted, payload)),
+			want:   errors.New(errInvalidSig),
+		},
+		"TamperedSudo": {
+			reason: "Scripts executed with sudo when the signed ones are not should be rejected.",
+			v:      v,
+			mg:     script(tamperedSudo, sign(t, trusted, payload)),
+			want:   errors.New(errInvalidSig),
+		},
+		"UnknownKey": {
+			reason: "Scripts signed by an untrusted key should be rejected.",
+			v:      v,
+			mg:     script(params, sign(t, untrusted, payload)),
+			want:   errors.Errorf(errFmtUnknownKey, untrusted.PrimaryKey.KeyId),
+		},
+		"NoPolicy": {
+			reason: "A nil Verifier should accept unsigned scripts.",
+			mg:     script(tampered, ""),
+		},
+		"Unsupported": {
+			reason: "Resources without scripts should be rejected.",
+			v:      v,
+			mg:     &apisv1alpha1.RemoteFile{},
+			want:   errors.Errorf(errUnsupported, &apisv1alpha1.RemoteFile{}),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.v.Verify(tc.mg)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nVerify(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := newEntity(t).Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		reason  string
+		keyring []byte
+		wantErr bool
+	}{
+		"Armored": {
+			reason:  "An ASCII armored keyring should be read.",
+			keyring: armored.Bytes(),
+		},
+		"Empty": {
+			reason:  "An empty keyring should be rejected.",
+			keyring: nil,
+			wantErr: true,
+		},
+		"Garbage": {
+			reason:  "A keyring that is not OpenPGP should be rejected.",
+			keyring: []byte("not a keyring"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewVerifier(tc.keyring)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("\n%s\nNewVerifier(...): error %v, want error %t", tc.reason, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func script(p apisv1alpha1.ScriptParameters, sig string) *apisv1alpha1.Script {
+	s := &apisv1alpha1.Script{Spec: apisv1alpha1.ScriptSpec{ForProvider: p}}
+	if sig != "" {
+		s.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{apisv1alpha1.AnnotationKeySignature: sig}}
+	}
+	return s
+}
+
+func newEntity(t *testing.T) *openpgp.Entity {
+	t.Helper()
+	e, err := openpgp.NewEntity("ops", "", fmt.Sprintf("ops-%s@example.org", t.Name()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func sign(t *testing.T, e *openpgp.Entity, payload []byte) string {
+	t.Helper()
+	var sig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sig, e, bytes.NewReader(payload), nil); err != nil {
+		t.Fatal(err)
+	}
+	return sig.String()
+}
@@ -0,0 +1,406 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sshtest provides an in-process SSH server for end-to-end tests. It
+// runs the commands of its clients on the local host and serves SFTP, so that
+// the scripts executed by the controllers really run.
+package sshtest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	sshv1alpha1 "github.com/crossplane/provider-ssh/internal/client"
+)
+
+const (
+	// Username and Password are the credentials the server accepts.
+	Username = "test"
+	Password = "test"
+
+	// killDelay is how long a killed command may hold its output open, e.g.
+	// through the processes it started.
+	killDelay = time.Second
+)
+
+// sudo records its arguments in the sudo log of the server then runs them,
+// without changing the user.
+const sudo = `#!/bin/sh
+echo "$*" >> "$SSHTEST_SUDO_LOG"
+exec "$@"
+`
+
+// A Server is an in-process SSH server. Commands run with /bin/sh on the
+// local host as the current user, with a sudo that records its arguments and
+// runs them unprivileged.
+type Server struct {
+	// Addr is the address the server listens on.
+	Addr string
+
+	// Dir is a directory private to the server, removed when the test ends.
+	// It is the working and home directory of the commands.
+	Dir string
+
+	hostKey  ssh.Signer
+	listener net.Listener
+
+	mu         sync.Mutex
+	config     *ssh.ServerConfig
+	authorized []ssh.PublicKey
+	commands   []string
+	dropAfter  int64
+	received   int64
+	conns      map[net.Conn]struct{}
+	closed     bool
+	wg         sync.WaitGroup
+}
+
+// NewServer starts a Server that stops when the supplied test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{Dir: t.TempDir(), hostKey: signer, conns: map[net.Conn]struct{}{}}
+	if err := os.Mkdir(filepath.Join(s.Dir, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, "bin", "sudo"), []byte(sudo), 0o755); err != nil { // nolint: gosec
+		t.Fatal(err)
+	}
+
+	s.config = &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() != Username || string(password) != Password {
+				return nil, errors.New("invalid credentials")
+			}
+			return nil, nil
+		},
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if c.User() == Username && s.isAuthorized(key) {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized key")
+		},
+	}
+	s.config.AddHostKey(signer)
+
+	if s.listener, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	s.Addr = s.listener.Addr().String()
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close stops the server, closes the connections of its clients and waits
+// for the running commands to be killed.
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.mu.Lock()
+	s.closed = true
+	for nc := range s.conns {
+		_ = nc.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Credentials returns the JSON encoded credentials of the server, whose host
+// key is verified.
+func (s *Server) Credentials() []byte {
+	host, port, _ := net.SplitHostPort(s.Addr)
+	hostPort, _ := sshv1alpha1.ParsePort(port)
+	creds, _ := json.Marshal(sshv1alpha1.Config{
+		RemoteHostIP:   host,
+		RemoteHostPort: hostPort,
+		Username:       Username,
+		Password:       Password,
+		KnownHosts:     knownhosts.Line([]string{knownhosts.Normalize(s.Addr)}, s.hostKey.PublicKey()),
+	})
+	return creds
+}
+
+// AuthorizeKey lets the supplied public key log in as Username, like an
+// authorized_keys entry.
+func (s *Server) AuthorizeKey(key ssh.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorized = append(s.authorized, key)
+}
+
+func (s *Server) isAuthorized(key ssh.PublicKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range s.authorized {
+		if bytes.Equal(k.Marshal(), key.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Commands returns the commands run by the clients of the server, in order.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// DropSFTP closes the channel of the next SFTP session once it received the
+// supplied number of bytes, as a flaky link would.
+func (s *Server) DropSFTP(after int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropAfter = after
+}
+
+// DropConnections closes the connections of the clients of the server
+// without stopping it, as a restarted sshd or a firewall expiring idle
+// connections would.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for nc := range s.conns {
+		_ = nc.Close()
+	}
+}
+
+// SFTPBytes returns the number of bytes received by the SFTP sessions.
+func (s *Server) SFTPBytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received
+}
+
+// SudoCalls returns the commands run through sudo, in order.
+func (s *Server) SudoCalls() []string {
+	content, err := os.ReadFile(s.sudoLog())
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func (s *Server) sudoLog() string {
+	return filepath.Join(s.Dir, "sudo.log")
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		nc, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = nc.Close()
+			return
+		}
+		s.conns[nc] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.wg.Done()
+			s.handle(nc)
+			s.mu.Lock()
+			delete(s.conns, nc)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) handle(nc net.Conn) {
+	defer nc.Close() // nolint: errcheck
+	s.mu.Lock()
+	config := s.config
+	s.mu.Unlock()
+	sc, chans, reqs, err := ssh.NewServerConn(nc, config)
+	if err != nil {
+		return
+	}
+	defer sc.Close() // nolint: errcheck
+	go ssh.DiscardRequests(reqs)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			_ = newCh.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, chReqs, err := newCh.Accept()
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.session(ch, chReqs)
+		}()
+	}
+}
+
+// session serves the exec and sftp requests of a session. Other requests,
+// such as a pty or environment variables, are rejected.
+func (s *Server) session(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	defer ch.Close() // nolint: errcheck
+
+	done := make(chan struct{})
+	var cmd *exec.Cmd
+	for {
+		select {
+		case <-done:
+			return
+		case req, ok := <-reqs:
+			if !ok {
+				// The client closed the session, e.g. after a timeout.
+				if cmd != nil {
+					_ = cmd.Process.Kill()
+					<-done
+				}
+				return
+			}
+			switch {
+			case req.Type == "exec" && cmd == nil:
+				payload := struct{ Command string }{}
+				if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				cmd = s.command(ch, payload.Command)
+				if err := cmd.Start(); err != nil {
+					_ = req.Reply(false, nil)
+					return
+				}
+				_ = req.Reply(true, nil)
+				go func() {
+					defer close(done)
+					exit(ch, cmd.Wait())
+				}()
+			case req.Type == "subsystem" && cmd == nil && subsystem(req.Payload) == "sftp":
+				_ = req.Reply(true, nil)
+				server, err := sftp.NewServer(s.sftpChannel(ch))
+				if err != nil {
+					return
+				}
+				_ = server.Serve()
+				return
+			case req.Type == "signal" && cmd != nil:
+				_ = cmd.Process.Kill()
+			default:
+				if req.WantReply {
+					_ = req.Reply(false, nil)
+				}
+			}
+		}
+	}
+}
+
+// command returns the command running cmd with /bin/sh, its input read from
+// and its output sent to the channel.
+func (s *Server) command(ch ssh.Channel, cmd string) *exec.Cmd {
+	s.mu.Lock()
+	s.commands = append(s.commands, cmd)
+	s.mu.Unlock()
+
+	c := exec.Command("/bin/sh", "-c", cmd)
+	c.Dir = s.Dir
+	c.Env = append(os.Environ(),
+		"HOME="+s.Dir,
+		"PATH="+filepath.Join(s.Dir, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"SSHTEST_SUDO_LOG="+s.sudoLog())
+	c.Stdin = ch
+	c.Stdout = ch
+	c.Stderr = ch.Stderr()
+	c.WaitDelay = killDelay
+	return c
+}
+
+// sftpChannel returns the channel of an SFTP session, counting the bytes it
+// receives and closed once it received the bytes set by DropSFTP, if any.
+func (s *Server) sftpChannel(ch ssh.Channel) io.ReadWriteCloser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &countingChannel{Channel: ch, server: s, dropAfter: s.dropAfter}
+	s.dropAfter = 0
+	return c
+}
+
+type countingChannel struct {
+	ssh.Channel
+	server    *Server
+	dropAfter int64
+	received  int64
+}
+
+func (c *countingChannel) Read(p []byte) (int, error) {
+	if c.dropAfter > 0 && c.received >= c.dropAfter {
+		_ = c.Channel.Close()
+		return 0, io.EOF
+	}
+	n, err := c.Channel.Read(p)
+	c.received += int64(n)
+	c.server.mu.Lock()
+	c.server.received += int64(n)
+	c.server.mu.Unlock()
+	return n, err
+}
+
+// exit reports the exit code of a command to the client.
+func exit(ch ssh.Channel, err error) {
+	code := 0
+	if err != nil {
+		code = 255
+		if e, ok := err.(*exec.ExitError); ok && e.ExitCode() >= 0 {
+			code = e.ExitCode()
+		}
+	}
+	_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(code)}))
+}
+
+func subsystem(payload []byte) string {
+	p := struct{ Name string }{}
+	if err := ssh.Unmarshal(payload, &p); err != nil {
+		return ""
+	}
+	return p.Name
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sshtest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// A Teleport is an in-process Teleport cluster whose single node is a
+// Server. Its proxy routes the proxy:<host>:<port> subsystem of its sessions
+// to the node, and both present host certificates of the host certificate
+// authority of the cluster and accept the users certified by its user
+// certificate authority.
+type Teleport struct {
+	// ProxyAddr is the address of the SSH endpoint of the proxy.
+	ProxyAddr string
+
+	identity   []byte
+	listener   net.Listener
+	config     *ssh.ServerConfig
+	mu         sync.Mutex
+	subsystems []string
+	wg         sync.WaitGroup
+}
+
+// NewTeleport starts a Teleport cluster in front of the supplied node, which
+// stops when the supplied test ends. The node then only presents its host
+// certificate.
+func NewTeleport(t testing.TB, node *Server) *Teleport {
+	t.Helper()
+	hostCA, userCA := newSigner(t), newSigner(t)
+	checker := &ssh.CertChecker{IsUserAuthority: func(auth ssh.PublicKey) bool {
+		return bytes.Equal(auth.Marshal(), userCA.PublicKey().Marshal())
+	}}
+
+	config := &ssh.ServerConfig{PublicKeyCallback: checker.Authenticate}
+	config.AddHostKey(hostCertificate(t, hostCA, node.hostKey, "node"))
+	node.mu.Lock()
+	node.config = config
+	node.mu.Unlock()
+
+	tp := &Teleport{config: &ssh.ServerConfig{PublicKeyCallback: checker.Authenticate}}
+	tp.config.AddHostKey(hostCertificate(t, hostCA, newSigner(t), "proxy"))
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &ssh.Certificate{
+		Key:             user.PublicKey(),
+		CertType:        ssh.UserCert,
+		KeyId:           Username,
+		ValidPrincipals: []string{Username},
+		ValidAfter:      uint64(time.Now().Add(-time.Minute).Unix()),
+		ValidBefore:     uint64(time.Now().Add(time.Hour).Unix()),
+	}
+	if err := cert.SignCert(rand.Reader, userCA); err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp.identity = append(tp.identity, pem.EncodeToMemory(block)...)
+	tp.identity = append(tp.identity, ssh.MarshalAuthorizedKey(cert)...)
+	tp.identity = append(tp.identity, "@cert-authority *.test "+string(ssh.MarshalAuthorizedKey(hostCA.PublicKey()))...)
+
+	if tp.listener, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	tp.ProxyAddr = tp.listener.Addr().String()
+	tp.wg.Add(1)
+	go tp.serve()
+	t.Cleanup(func() {
+		_ = tp.listener.Close()
+		tp.wg.Wait()
+	})
+	return tp
+}
+
+// Identity returns an identity file of a user of the cluster, logging in as
+// Username.
+func (tp *Teleport) Identity() []byte {
+	return tp.identity
+}
+
+// Subsystems returns the proxy subsystems requested from the proxy, such as
+// proxy:127.0.0.1:2222@leaf.
+func (tp *Teleport) Subsystems() []string {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return append([]string(nil), tp.subsystems...)
+}
+
+func (tp *Teleport) serve() {
+	defer tp.wg.Done()
+	for {
+		nc, err := tp.listener.Accept()
+		if err != nil {
+			return
+		}
+		go tp.handle(nc)
+	}
+}
+
+func (tp *Teleport) handle(nc net.Conn) {
+	defer nc.Close() // nolint: errcheck
+	sc, chans, reqs, err := ssh.NewServerConn(nc, tp.config)
+	if err != nil {
+		return
+	}
+	defer sc.Close() // nolint: errcheck
+	go ssh.DiscardRequests(reqs)
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			_ = newCh.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, chReqs, err := newCh.Accept()
+		if err != nil {
+			continue
+		}
+		go tp.session(ch, chReqs)
+	}
+}
+
+// session routes the proxy subsystem of a session to the node it names.
+func (tp *Teleport) session(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	defer ch.Close() // nolint: errcheck
+	for req := range reqs {
+		name := subsystem(req.Payload)
+		if req.Type != "subsystem" || !strings.HasPrefix(name, "proxy:") {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		tp.mu.Lock()
+		tp.subsystems = append(tp.subsystems, name)
+		tp.mu.Unlock()
+		addr, _, _ := strings.Cut(strings.TrimPrefix(name, "proxy:"), "@")
+		node, err := net.Dial("tcp", addr)
+		if err != nil {
+			_ = req.Reply(false, nil)
+			return
+		}
+		_ = req.Reply(true, nil)
+		go ssh.DiscardRequests(reqs)
+		go func() {
+			_, _ = io.Copy(node, ch)
+			_ = node.Close()
+		}()
+		_, _ = io.Copy(ch, node)
+		_ = node.Close()
+		return
+	}
+}
+
+func newSigner(t testing.TB) ssh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// hostCertificate returns the supplied host key certified by the supplied
+// certificate authority.
+func hostCertificate(t testing.TB, ca, key ssh.Signer, principal string) ssh.Signer {
+	t.Helper()
+	cert := &ssh.Certificate{
+		Key:             key.PublicKey(),
+		CertType:        ssh.HostCert,
+		KeyId:           principal,
+		ValidPrincipals: []string{principal},
+		ValidAfter:      uint64(time.Now().Add(-time.Minute).Unix()),
+		ValidBefore:     uint64(time.Now().Add(time.Hour).Unix()),
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewCertSigner(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
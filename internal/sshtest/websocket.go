@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sshtest
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+// A Gateway is an in-process WebSocket gateway forwarding the binary
+// messages of its connections to a Server.
+type Gateway struct {
+	// URL is the ws URL of the gateway.
+	URL string
+}
+
+// NewGateway starts a WebSocket gateway in front of the supplied node, which
+// stops when the supplied test ends. The gateway rejects the handshakes
+// without the supplied header value.
+func NewGateway(t testing.TB, node *Server, header, value string) *Gateway {
+	t.Helper()
+	srv := httptest.NewServer(websocket.Server{
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			if r.Header.Get(header) != value {
+				return websocket.ErrBadRequestMethod
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			ws.PayloadType = websocket.BinaryFrame
+			nc, err := net.Dial("tcp", node.Addr)
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(nc, ws)
+				_ = nc.Close()
+			}()
+			_, _ = io.Copy(ws, nc)
+			_ = ws.Close()
+		},
+	})
+	t.Cleanup(srv.Close)
+	return &Gateway{URL: "ws" + strings.TrimPrefix(srv.URL, "http") + "/ssh"}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+const (
+	errNotManaged = "object is not a managed resource"
+	errGetPC      = "cannot get ProviderConfig"
+	errGetHost    = "cannot get Host"
+)
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-authorizedkey,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=authorizedkeys,versions=v1alpha1,name=authorizedkeys.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-command,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=commands,versions=v1alpha1,name=commands.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-connectioncheck,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=connectionchecks,versions=v1alpha1,name=connectionchecks.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-hostkeyscan,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=hostkeyscans,versions=v1alpha1,name=hostkeyscans.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-keypair,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=keypairs,versions=v1alpha1,name=keypairs.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-nodescript,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=nodescripts,versions=v1alpha1,name=nodescripts.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-package,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=packages,versions=v1alpha1,name=packages.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-remotedirectory,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=remotedirectories,versions=v1alpha1,name=remotedirectories.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-remotefetch,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=remotefetches,versions=v1alpha1,name=remotefetches.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-remotefile,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=remotefiles,versions=v1alpha1,name=remotefiles.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-reversetunnel,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=reversetunnels,versions=v1alpha1,name=reversetunnels.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-scriptset,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=scriptsets,versions=v1alpha1,name=scriptsets.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-useraccount,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=useraccounts,versions=v1alpha1,name=useraccounts.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+
+// A ProviderConfigAuthorizer rejects the managed resources that are not
+// allowed to use the ProviderConfigs they reference, directly or through
+// their target hosts, or that exceed the maxManagedResources of their
+// ProviderConfig.
+type ProviderConfigAuthorizer struct {
+	Kube client.Reader
+}
+
+// ValidateCreate validates a created managed resource.
+func (a *ProviderConfigAuthorizer) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, a.validate(ctx, obj)
+}
+
+// ValidateUpdate validates an updated managed resource. Deleting resources
+// and updates leaving the spec and labels unchanged are accepted, so that the
+// finalizers and status of resources their ProviderConfig does not allow any
+// more can still be updated.
+func (a *ProviderConfigAuthorizer) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	if skipUpdate(oldObj, newObj) {
+		return nil, nil
+	}
+	return nil, a.validate(ctx, newObj)
+}
+
+// ValidateDelete accepts every deleted managed resource.
+func (a *ProviderConfigAuthorizer) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (a *ProviderConfigAuthorizer) validate(ctx context.Context, obj runtime.Object) error {
+	mg, ok := obj.(resource.Managed)
+	if !ok {
+		return errors.New(errNotManaged)
+	}
+	errs, err := a.forbidden(ctx, mg)
+	if err != nil || len(errs) == 0 {
+		return err
+	}
+	gk := schema.GroupKind{Group: apisv1alpha1.Group, Kind: reflect.TypeOf(mg).Elem().Name()}
+	return kerrors.NewInvalid(gk, mg.GetName(), errs)
+}
+
+// forbidden returns the errors of the references of the managed resource to
+// ProviderConfigs it is not allowed to use, or which are already used by
+// their maxManagedResources. ProviderConfigs and Hosts that do not exist yet
+// are not checked, the controllers check them when connecting.
+func (a *ProviderConfigAuthorizer) forbidden(ctx context.Context, mg resource.Managed) (field.ErrorList, error) {
+	if a == nil {
+		return nil, nil
+	}
+	var errs field.ErrorList
+	check := func(path *field.Path, name string, quota bool) error {
+		pc := &apisv1alpha1.ProviderConfig{}
+		if err := a.Kube.Get(ctx, types.NamespacedName{Name: name}, pc); err != nil {
+			return errors.Wrap(resource.IgnoreNotFound(err), errGetPC)
+		}
+		if err := common.AuthorizeProviderConfig(pc, mg); err != nil {
+			errs = append(errs, field.Forbidden(path, err.Error()))
+			return nil
+		}
+		if !quota {
+			return nil
+		}
+		if err := common.CheckQuota(ctx, a.Kube, pc, mg); err != nil {
+			errs = append(errs, field.Forbidden(path, err.Error()))
+		}
+		return nil
+	}
+
+	// Only the resources using a ProviderConfig through their
+	// providerConfigRef are counted by its maxManagedResources.
+	if ref := mg.GetProviderConfigReference(); ref != nil {
+		if err := check(field.NewPath("spec", "providerConfigRef", "name"), ref.Name, true); err != nil {
+			return nil, err
+		}
+	}
+
+	var hosts []apisv1alpha1.HostReference
+	switch cr := mg.(type) {
+	case *apisv1alpha1.Script:
+		hosts = cr.Spec.ForProvider.Hosts
+	case *apisv1alpha1.ScriptSet:
+		hosts = cr.Spec.ForProvider.Hosts
+	}
+	for i, ref := range hosts {
+		path := field.NewPath("spec", "forProvider", "hosts").Index(i).Child("name")
+		switch ref.Kind {
+		case apisv1alpha1.HostKind:
+			h := &apisv1alpha1.Host{}
+			if err := a.Kube.Get(ctx, types.NamespacedName{Name: ref.Name}, h); err != nil {
+				if kerrors.IsNotFound(err) {
+					continue
+				}
+				return nil, errors.Wrap(err, errGetHost)
+			}
+			if h.Spec.ProviderConfigRef == nil {
+				continue
+			}
+			if err := check(path, h.Spec.ProviderConfigRef.Name, false); err != nil {
+				return nil, err
+			}
+		case apisv1alpha1.HostGroupKind:
+			// The members of HostGroups change over time, the controllers
+			// check them when connecting.
+		default:
+			if err := check(path, ref.Name, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return errs, nil
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+)
+
+func TestAuthorizeProviderConfig(t *testing.T) {
+	errBoom := errors.New("boom")
+	quota := int64(1)
+
+	// The ProviderConfig restricted is only allowed for the claims of
+	// namespace team-a, the Host restricted-host uses it. The ProviderConfig
+	// full is used by its maxManagedResources of one managed resource, whose
+	// UID is tracked.
+	kube := func(err error) client.Reader {
+		return &test.MockClient{MockList: func(_ context.Context, obj client.ObjectList, _ ...client.ListOption) error {
+			u := apisv1alpha1.ProviderConfigUsage{}
+			u.SetName("tracked")
+			obj.(*apisv1alpha1.ProviderConfigUsageList).Items = []apisv1alpha1.ProviderConfigUsage{u}
+			return nil
+		}, MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+			if err != nil {
+				return err
+			}
+			switch o := obj.(type) {
+			case *apisv1alpha1.ProviderConfig:
+				switch key.Name {
+				case "restricted":
+					o.SetName(key.Name)
+					o.Spec.AllowedNamespaces = []string{"team-a"}
+				case "open":
+					o.SetName(key.Name)
+				case "full":
+					o.SetName(key.Name)
+					o.Spec.MaxManagedResources = &quota
+				default:
+					return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+				}
+			case *apisv1alpha1.Host:
+				if key.Name != "restricted-host" {
+					return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+				}
+				o.Spec.ProviderConfigRef = &xpv1.Reference{Name: "restricted"}
+			}
+			return nil
+		}}
+	}
+	claimed := func(obj interface {
+		runtime.Object
+		SetName(string)
+		SetLabels(map[string]string)
+	}, ns string) runtime.Object {
+		obj.SetName("test")
+		obj.SetLabels(map[string]string{apisv1alpha1.LabelKeyClaimNamespace: ns})
+		return obj
+	}
+	command := func(pc string) *apisv1alpha1.Command {
+		cr := &apisv1alpha1.Command{}
+		cr.SetProviderConfigReference(&xpv1.Reference{Name: pc})
+		return cr
+	}
+	scriptSet := func(hosts ...apisv1alpha1.HostReference) *apisv1alpha1.ScriptSet {
+		return &apisv1alpha1.ScriptSet{Spec: apisv1alpha1.ScriptSetSpec{ForProvider: apisv1alpha1.ScriptSetParameters{Hosts: hosts}}}
+	}
+	invalid := func(gk schema.GroupKind, errs ...*field.Error) error {
+		return kerrors.NewInvalid(gk, "test", errs)
+	}
+	commandGK := schema.GroupKind{Group: apisv1alpha1.Group, Kind: apisv1alpha1.CommandKind}
+	scriptSetGK := schema.GroupKind{Group: apisv1alpha1.Group, Kind: apisv1alpha1.ScriptSetKind}
+
+	cases := map[string]struct {
+		reason string
+		kube   client.Reader
+		obj    runtime.Object
+		want   error
+	}{
+		"Allowed": {
+			reason: "Managed resources of an allowed namespace should be accepted.",
+			kube:   kube(nil),
+			obj:    claimed(command("restricted"), "team-a"),
+		},
+		"NotAllowed": {
+			reason: "Managed resources of another namespace should be rejected.",
+			kube:   kube(nil),
+			obj:    claimed(command("restricted"), "team-b"),
+			want: invalid(commandGK, field.Forbidden(field.NewPath("spec", "providerConfigRef", "name"),
+				"ProviderConfig restricted does not allow the claims of namespace team-b")),
+		},
+		"Unrestricted": {
+			reason: "Managed resources should be accepted by a ProviderConfig without restrictions.",
+			kube:   kube(nil),
+			obj:    claimed(command("open"), "team-b"),
+		},
+		"NotFound": {
+			reason: "References to ProviderConfigs that do not exist yet should be accepted, the controller checks them when connecting.",
+			kube:   kube(nil),
+			obj:    claimed(command("missing"), "team-b"),
+		},
+		"GetError": {
+			reason: "Errors getting the ProviderConfig should be returned.",
+			kube:   kube(errBoom),
+			obj:    claimed(command("restricted"), "team-a"),
+			want:   errors.Wrap(errBoom, errGetPC),
+		},
+		"QuotaExceeded": {
+			reason: "Managed resources should be rejected if their ProviderConfig is used by its maxManagedResources.",
+			kube:   kube(nil),
+			obj:    claimed(command("full"), "team-b"),
+			want: invalid(commandGK, field.Forbidden(field.NewPath("spec", "providerConfigRef", "name"),
+				"ProviderConfig full is already used by its maxManagedResources of 1 managed resources")),
+		},
+		"QuotaTracked": {
+			reason: "Managed resources already using their ProviderConfig should be accepted, they count towards its maxManagedResources.",
+			kube:   kube(nil),
+			obj: func() runtime.Object {
+				cr := command("full")
+				cr.SetUID("tracked")
+				return claimed(cr, "team-b")
+			}(),
+		},
+		"Hosts": {
+			reason: "The ProviderConfigs of the hosts of a ScriptSet should be checked, directly or through their Host.",
+			kube:   kube(nil),
+			obj: claimed(scriptSet(
+				apisv1alpha1.HostReference{Kind: apisv1alpha1.ProviderConfigKind, Name: "open"},
+				apisv1alpha1.HostReference{Kind: apisv1alpha1.ProviderConfigKind, Name: "restricted"},
+				apisv1alpha1.HostReference{Kind: apisv1alpha1.HostKind, Name: "restricted-host"},
+				apisv1alpha1.HostReference{Kind: apisv1alpha1.HostKind, Name: "missing"},
+				apisv1alpha1.HostReference{Kind: apisv1alpha1.HostGroupKind, Name: "workers"},
+			), "team-b"),
+			want: invalid(scriptSetGK,
+				field.Forbidden(field.NewPath("spec", "forProvider", "hosts").Index(1).Child("name"),
+					"ProviderConfig restricted does not allow the claims of namespace team-b"),
+				field.Forbidden(field.NewPath("spec", "forProvider", "hosts").Index(2).Child("name"),
+					"ProviderConfig restricted does not allow the claims of namespace team-b"),
+			),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a := &ProviderConfigAuthorizer{Kube: tc.kube}
+			_, err := a.ValidateCreate(context.Background(), tc.obj)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\na.ValidateCreate(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAuthorizeProviderConfigUpdate(t *testing.T) {
+	// The ProviderConfig restricted is only allowed for the claims of
+	// namespace team-a.
+	kube := &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+		pc := obj.(*apisv1alpha1.ProviderConfig)
+		pc.SetName(key.Name)
+		pc.Spec.AllowedNamespaces = []string{"team-a"}
+		return nil
+	}}
+	command := func(ns, cmd string, mod ...func(cr *apisv1alpha1.Command)) *apisv1alpha1.Command {
+		cr := &apisv1alpha1.Command{Spec: apisv1alpha1.CommandSpec{ForProvider: apisv1alpha1.CommandParameters{Create: cmd}}}
+		cr.SetName("test")
+		cr.SetLabels(map[string]string{apisv1alpha1.LabelKeyClaimNamespace: ns})
+		cr.SetProviderConfigReference(&xpv1.Reference{Name: "restricted"})
+		for _, m := range mod {
+			m(cr)
+		}
+		return cr
+	}
+	withFinalizer := func(cr *apisv1alpha1.Command) {
+		meta.AddFinalizer(cr, "finalizer.managedresource.crossplane.io")
+	}
+	deleting := func(cr *apisv1alpha1.Command) {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+	}
+	forbidden := kerrors.NewInvalid(schema.GroupKind{Group: apisv1alpha1.Group, Kind: apisv1alpha1.CommandKind}, "test",
+		field.ErrorList{field.Forbidden(field.NewPath("spec", "providerConfigRef", "name"),
+			"ProviderConfig restricted does not allow the claims of namespace team-b")})
+
+	cases := map[string]struct {
+		reason string
+		old    runtime.Object
+		obj    runtime.Object
+		want   error
+	}{
+		"RemoveFinalizer": {
+			reason: "The finalizer of a deleting managed resource should be removable even if its ProviderConfig does not allow it any more.",
+			old:    command("team-b", "uptime", deleting, withFinalizer),
+			obj:    command("team-b", "uptime", deleting),
+		},
+		"Unchanged": {
+			reason: "Updates leaving the spec and labels of a managed resource unchanged should be accepted.",
+			old:    command("team-b", "uptime"),
+			obj:    command("team-b", "uptime", withFinalizer),
+		},
+		"SpecChanged": {
+			reason: "Updates changing the spec of a managed resource should be authorized.",
+			old:    command("team-b", "uptime"),
+			obj:    command("team-b", "hostname"),
+			want:   forbidden,
+		},
+		"LabelsChanged": {
+			reason: "Updates changing the claim namespace of a managed resource should be authorized.",
+			old:    command("team-a", "uptime"),
+			obj:    command("team-b", "uptime"),
+			want:   forbidden,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a := &ProviderConfigAuthorizer{Kube: kube}
+			_, err := a.ValidateUpdate(context.Background(), tc.old, tc.obj)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\na.ValidateUpdate(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,437 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/expr"
+	"github.com/crossplane/provider-ssh/internal/policy"
+)
+
+const (
+	errNotScript = "object is not a Script"
+
+	errNoStatusCheck          = "an updateScript is never executed without a statusCheckScript or upToDateScript to detect drift"
+	errWhenWithoutStatusCheck = "is only evaluated against the result of a statusCheckScript, without existsScript and upToDateScript"
+	errFmtScriptTooLong       = "must not be longer than %d bytes"
+	errVariableName           = "must consist of letters, digits and underscores, and must not start with a digit"
+	errSudoPassword           = "sudo cannot read a password, scripts are executed without a terminal or stdin; configure passwordless sudo and set sudoEnabled instead"
+	errNotPositive            = "must be positive"
+	errContainerTarget        = "cannot be combined with namespaces or chroot, which apply on the host"
+	errPostRebootScript       = "is only executed with the Update rebootPolicy"
+	errReapplyNoUpdate        = "requires an updateScript to reapply"
+	errReapplyNever           = "cannot be combined with the Never updatePolicy"
+	errRebootPolicyFanOut     = "reboots are not detected for Scripts running on multiple hosts"
+	errMachineTarget          = "cannot be combined with a virtualMachineInstance"
+	errPolicy                 = "cannot evaluate the script policy"
+	errImmutable              = "is immutable once the Script was created on its host; delete and recreate the Script to change it"
+
+	warnFmtUndefinedVariable = "%s references undefined variable %s"
+
+	// MaxScriptSize is the size in bytes above which a script of a Script
+	// is rejected.
+	MaxScriptSize = 128 << 10
+)
+
+var (
+	variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// variableRef matches the references to variables in a script.
+	variableRef = regexp.MustCompile(`{{([^{}]+)}}`)
+	// sudoPassword matches the sudo invocations reading a password from
+	// stdin or from an askpass program.
+	sudoPassword = regexp.MustCompile(`(^|[\s;&|(])sudo(\s+-\S+(\s+[^-\s]\S*)?)*?\s+(-[a-zA-Z]*[SA][a-zA-Z]*|--stdin|--askpass)(\s|$)`)
+)
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-ssh-crossplane-io-v1alpha1-script,mutating=false,failurePolicy=fail,groups=ssh.crossplane.io,resources=scripts,versions=v1alpha1,name=scripts.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+
+// A ScriptValidator rejects Scripts that are known to fail when they are
+// reconciled.
+type ScriptValidator struct {
+	// Policy rejects the Scripts running dangerous commands. No command is
+	// rejected if it is nil.
+	Policy *policy.Policy
+
+	// Authorizer rejects the Scripts that are not allowed to use the
+	// ProviderConfigs of their hosts. No Script is rejected if it is nil.
+	Authorizer *ProviderConfigAuthorizer
+}
+
+// ValidateCreate validates a created Script.
+func (v *ScriptValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return validateScript(ctx, obj, v.Policy, v.Authorizer)
+}
+
+// ValidateUpdate validates an updated Script, rejecting changes to the fields
+// identifying the state of its host once it was created. Deleting Scripts and
+// updates leaving the spec and labels unchanged are not validated further, so
+// that the finalizers and status of Scripts the policy rejects since they
+// were created can still be updated.
+func (v *ScriptValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	if err := validateImmutable(oldObj, newObj); err != nil {
+		return nil, err
+	}
+	if skipUpdate(oldObj, newObj) {
+		return nil, nil
+	}
+	return validateScript(ctx, newObj, v.Policy, v.Authorizer)
+}
+
+// ValidateDelete accepts every deleted Script.
+func (v *ScriptValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/mutate-ssh-crossplane-io-v1alpha1-script,mutating=true,failurePolicy=fail,groups=ssh.crossplane.io,resources=scripts,versions=v1alpha1,name=defaults.scripts.ssh.crossplane.io,sideEffects=None,admissionReviewVersions=v1
+
+// A ScriptDefaulter fills the unset execution settings of Scripts, so that
+// the stored object records how its scripts are executed. The settings with
+// a default in the ProviderConfig of a Script are left unset, so that the
+// Script follows changes to the defaults of the ProviderConfig.
+type ScriptDefaulter struct {
+	// MaxOutputBytes is the default maxOutputBytes of Scripts, zero for the
+	// default of the API.
+	MaxOutputBytes int64
+
+	// Kube reads the ProviderConfigs of the Scripts. Their defaults are
+	// ignored if it is nil.
+	Kube client.Reader
+}
+
+// Default sets the defaults of a Script.
+func (d *ScriptDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	cr, ok := obj.(*apisv1alpha1.Script)
+	if !ok {
+		return errors.New(errNotScript)
+	}
+	pd, err := d.providerDefaults(ctx, cr)
+	if err != nil {
+		return err
+	}
+	p := &cr.Spec.ForProvider
+	if p.Endpoint != nil && p.Endpoint.Port == nil {
+		port := apisv1alpha1.DefaultPort
+		p.Endpoint.Port = &port
+	}
+	if p.Interpreter == "" && pd.Interpreter == "" {
+		p.Interpreter = apisv1alpha1.DefaultInterpreter
+	}
+	if p.StrictMode && p.StrictModePreamble == "" {
+		p.StrictModePreamble = apisv1alpha1.DefaultStrictModePreamble
+	}
+	if p.TimeoutSeconds == nil && pd.TimeoutSeconds == nil {
+		timeout := int64(apisv1alpha1.DefaultTimeoutSeconds)
+		p.TimeoutSeconds = &timeout
+	}
+	if p.MaxOutputBytes == nil && pd.MaxOutputBytes == nil {
+		limit := int64(apisv1alpha1.DefaultMaxOutputBytes)
+		if d.MaxOutputBytes > 0 {
+			limit = d.MaxOutputBytes
+		}
+		p.MaxOutputBytes = &limit
+	}
+	if p.ExitCodes == nil {
+		p.ExitCodes = &apisv1alpha1.StatusCheckExitCodes{}
+	}
+	if p.ExitCodes.Missing == nil {
+		missing := apisv1alpha1.DefaultExitCodeMissing
+		p.ExitCodes.Missing = &missing
+	}
+	if p.ExitCodes.Failed == nil {
+		p.ExitCodes.Failed = []int{apisv1alpha1.DefaultExitCodeFailed}
+	}
+	return nil
+}
+
+// providerDefaults returns the defaults of the ProviderConfig of the Script,
+// empty if it has none or does not exist yet.
+func (d *ScriptDefaulter) providerDefaults(ctx context.Context, cr *apisv1alpha1.Script) (*apisv1alpha1.ScriptDefaults, error) {
+	ref := cr.GetProviderConfigReference()
+	if d.Kube == nil || ref == nil {
+		return &apisv1alpha1.ScriptDefaults{}, nil
+	}
+	pc := &apisv1alpha1.ProviderConfig{}
+	if err := d.Kube.Get(ctx, types.NamespacedName{Name: ref.Name}, pc); resource.IgnoreNotFound(err) != nil {
+		return nil, errors.Wrap(err, errGetPC)
+	}
+	if pc.Spec.Defaults == nil {
+		return &apisv1alpha1.ScriptDefaults{}, nil
+	}
+	return pc.Spec.Defaults, nil
+}
+
+func validateScript(ctx context.Context, obj runtime.Object, pol *policy.Policy, auth *ProviderConfigAuthorizer) (admission.Warnings, error) {
+	cr, ok := obj.(*apisv1alpha1.Script)
+	if !ok {
+		return nil, errors.New(errNotScript)
+	}
+	p := cr.Spec.ForProvider
+	path := field.NewPath("spec", "forProvider")
+	var errs field.ErrorList
+
+	if p.UpdateScript != "" && p.StatusCheckScript == "" && p.UpToDateScript == "" && p.UpdatePolicy != apisv1alpha1.UpdatePolicyAlways {
+		errs = append(errs, field.Invalid(path.Child("updateScript"), truncate(p.UpdateScript), errNoStatusCheck))
+	}
+
+	if d := p.PollInterval; d != nil && d.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("pollInterval"), d.Duration.String(), errNotPositive))
+	}
+	if d := p.StatusCheckInterval; d != nil && d.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("statusCheckInterval"), d.Duration.String(), errNotPositive))
+	}
+	if d := p.ReapplyInterval; d != nil {
+		switch {
+		case d.Duration <= 0:
+			errs = append(errs, field.Invalid(path.Child("reapplyInterval"), d.Duration.String(), errNotPositive))
+		case p.UpdateScript == "":
+			errs = append(errs, field.Invalid(path.Child("reapplyInterval"), d.Duration.String(), errReapplyNoUpdate))
+		case p.UpdatePolicy == apisv1alpha1.UpdatePolicyNever:
+			errs = append(errs, field.Invalid(path.Child("reapplyInterval"), d.Duration.String(), errReapplyNever))
+		}
+	}
+
+	if m := p.Machine; m != nil && p.VirtualMachineInstance != nil {
+		errs = append(errs, field.Invalid(path.Child("machine"), m.Name, errMachineTarget))
+	}
+
+	if c := p.Container; c != nil && (p.Namespaces != nil || p.Chroot != "") {
+		errs = append(errs, field.Invalid(path.Child("container"), c.Name, errContainerTarget))
+	}
+
+	if p.PostRebootScript != "" && p.RebootPolicy != apisv1alpha1.RebootPolicyUpdate {
+		errs = append(errs, field.Invalid(path.Child("postRebootScript"), truncate(p.PostRebootScript), errPostRebootScript))
+	}
+	if rp := p.RebootPolicy; rp != "" && rp != apisv1alpha1.RebootPolicyIgnore && (len(p.Hosts) > 0 || p.HostSelector != nil) {
+		errs = append(errs, field.Invalid(path.Child("rebootPolicy"), rp, errRebootPolicyFanOut))
+	}
+
+	defined := map[string]bool{}
+	for i, vr := range p.Variables {
+		if !variableName.MatchString(vr.Name) {
+			errs = append(errs, field.Invalid(path.Child("variables").Index(i).Child("name"), vr.Name, errVariableName))
+		}
+		if defined[vr.Name] {
+			errs = append(errs, field.Duplicate(path.Child("variables").Index(i).Child("name"), vr.Name))
+		}
+		defined[vr.Name] = true
+	}
+
+	for _, e := range []struct{ name, expr string }{
+		{"skipIf", p.SkipIf},
+		{"upToDateWhen", p.UpToDateWhen},
+		{"readyWhen", p.ReadyWhen},
+	} {
+		if e.expr == "" {
+			continue
+		}
+		if _, err := expr.Compile(e.expr); err != nil {
+			errs = append(errs, field.Invalid(path.Child(e.name), e.expr, err.Error()))
+			continue
+		}
+		if e.name != "skipIf" && (p.StatusCheckScript == "" || p.ExistsScript != "" || p.UpToDateScript != "") {
+			errs = append(errs, field.Invalid(path.Child(e.name), e.expr, errWhenWithoutStatusCheck))
+		}
+	}
+
+	var warnings admission.Warnings
+	for _, s := range scripts(p) {
+		if len(s.script) > MaxScriptSize {
+			errs = append(errs, field.TooLong(path.Child(s.name), truncate(s.script), MaxScriptSize))
+		}
+		if sudoPassword.MatchString(s.script) {
+			errs = append(errs, field.Invalid(path.Child(s.name), truncate(s.script), errSudoPassword))
+		}
+		if p.Templating == apisv1alpha1.TemplatingEnvsubst {
+			// $VAR references to other names are expanded by the shell.
+			continue
+		}
+		for _, ref := range undefined(s.script, defined) {
+			warnings = append(warnings, fmt.Sprintf(warnFmtUndefinedVariable, path.Child(s.name), ref))
+		}
+	}
+
+	violations, err := pol.Violations(ctx, cr)
+	if err != nil {
+		return warnings, errors.Wrap(err, errPolicy)
+	}
+	for _, v := range violations {
+		if v.Field == "" {
+			errs = append(errs, field.Forbidden(path, v.Message))
+			continue
+		}
+		errs = append(errs, field.Forbidden(path.Child(v.Field), fmt.Sprintf("%s (%s)", v.Message, v.Rule)))
+	}
+
+	forbidden, err := auth.forbidden(ctx, cr)
+	if err != nil {
+		return warnings, err
+	}
+	errs = append(errs, forbidden...)
+
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+	return warnings, kerrors.NewInvalid(apisv1alpha1.ScriptGroupVersionKind.GroupKind(), cr.GetName(), errs)
+}
+
+// skipUpdate returns true if the updated object is being deleted, or if the
+// update leaves its spec and labels unchanged. The labels are compared since
+// they record the claim namespace the ProviderConfigs are authorized for.
+func skipUpdate(oldObj, newObj runtime.Object) bool {
+	o, ok := newObj.(metav1.Object)
+	if !ok {
+		return false
+	}
+	if meta.WasDeleted(o) {
+		return true
+	}
+	old, ok := oldObj.(metav1.Object)
+	if !ok || !equality.Semantic.DeepEqual(old.GetLabels(), o.GetLabels()) {
+		return false
+	}
+	oldSpec, err := spec(oldObj)
+	if err != nil {
+		return false
+	}
+	newSpec, err := spec(newObj)
+	if err != nil {
+		return false
+	}
+	return equality.Semantic.DeepEqual(oldSpec, newSpec)
+}
+
+// spec returns the spec of the supplied object.
+func spec(obj runtime.Object) (interface{}, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return u["spec"], nil
+}
+
+// validateImmutable rejects changes to the endpoint, username,
+// executionPolicy and external name of a Script that was created or adopted,
+// which would silently detach the Script from the state it established on its
+// host.
+func validateImmutable(oldObj, newObj runtime.Object) error {
+	old, ok := oldObj.(*apisv1alpha1.Script)
+	if !ok {
+		return errors.New(errNotScript)
+	}
+	cr, ok := newObj.(*apisv1alpha1.Script)
+	if !ok {
+		return errors.New(errNotScript)
+	}
+	if meta.GetExternalCreateSucceeded(old).IsZero() && old.Status.AtProvider.ScriptHash == "" {
+		// Nothing was executed on the host yet.
+		return nil
+	}
+
+	o, p := old.Spec.ForProvider, cr.Spec.ForProvider
+	path := field.NewPath("spec", "forProvider")
+	var errs field.ErrorList
+	if !equality.Semantic.DeepEqual(o.Endpoint, p.Endpoint) {
+		errs = append(errs, field.Invalid(path.Child("endpoint"), p.Endpoint, errImmutable))
+	}
+	if !equality.Semantic.DeepEqual(o.VirtualMachineInstance, p.VirtualMachineInstance) {
+		errs = append(errs, field.Invalid(path.Child("virtualMachineInstance"), p.VirtualMachineInstance, errImmutable))
+	}
+	if !equality.Semantic.DeepEqual(o.Machine, p.Machine) {
+		errs = append(errs, field.Invalid(path.Child("machine"), p.Machine, errImmutable))
+	}
+	if o.Username != p.Username {
+		errs = append(errs, field.Invalid(path.Child("username"), p.Username, errImmutable))
+	}
+	if o.ExecutionPolicy != p.ExecutionPolicy {
+		errs = append(errs, field.Invalid(path.Child("executionPolicy"), p.ExecutionPolicy, errImmutable))
+	}
+	if n := meta.GetExternalName(cr); n != meta.GetExternalName(old) {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "annotations").Key(meta.AnnotationKeyExternalName), n, errImmutable))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return kerrors.NewInvalid(apisv1alpha1.ScriptGroupVersionKind.GroupKind(), cr.GetName(), errs)
+}
+
+// A namedScript is a script of a Script together with its field name.
+type namedScript struct {
+	name   string
+	script string
+}
+
+// scripts returns the non-empty scripts of a Script.
+func scripts(p apisv1alpha1.ScriptParameters) []namedScript {
+	all := []namedScript{
+		{name: "initScript", script: p.InitScript},
+		{name: "statusCheckScript", script: p.StatusCheckScript},
+		{name: "updateScript", script: p.UpdateScript},
+		{name: "cleanupScript", script: p.CleanupScript},
+		{name: "existsScript", script: p.ExistsScript},
+		{name: "upToDateScript", script: p.UpToDateScript},
+		{name: "diffScript", script: p.DiffScript},
+		{name: "postRebootScript", script: p.PostRebootScript},
+	}
+	s := make([]namedScript, 0, len(all))
+	for _, ns := range all {
+		if ns.script != "" {
+			s = append(s, ns)
+		}
+	}
+	return s
+}
+
+// undefined returns the sorted names of the variables referenced by the
+// script that are not defined.
+func undefined(script string, defined map[string]bool) []string {
+	seen := map[string]bool{}
+	var refs []string
+	for _, m := range variableRef.FindAllStringSubmatch(script, -1) {
+		if name := m[1]; variableName.MatchString(name) && !defined[name] && !seen[name] {
+			seen[name] = true
+			refs = append(refs, name)
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// truncate shortens a script to be reported as the value of an invalid field.
+func truncate(script string) string {
+	const maxLen = 64
+	if len(script) <= maxLen {
+		return script
+	}
+	return script[:maxLen] + "..."
+}
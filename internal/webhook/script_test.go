@@ -0,0 +1,543 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/expr"
+	"github.com/crossplane/provider-ssh/internal/policy"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+func script(p apisv1alpha1.ScriptParameters) *apisv1alpha1.Script {
+	cr := &apisv1alpha1.Script{Spec: apisv1alpha1.ScriptSpec{ForProvider: p}}
+	cr.SetName("test")
+	return cr
+}
+
+func withProviderConfig(cr *apisv1alpha1.Script) *apisv1alpha1.Script {
+	cr.SetProviderConfigReference(&xpv1.Reference{Name: "default"})
+	return cr
+}
+
+func invalid(errs ...*field.Error) error {
+	return kerrors.NewInvalid(apisv1alpha1.ScriptGroupVersionKind.GroupKind(), "test", errs)
+}
+
+// compileErr returns the error message of compiling the supplied expression.
+func compileErr(src string) string {
+	_, err := expr.Compile(src)
+	return err.Error()
+}
+
+func TestValidateScript(t *testing.T) {
+	path := field.NewPath("spec", "forProvider")
+	long := strings.Repeat("#", MaxScriptSize+1)
+	sudo := true
+
+	type want struct {
+		warnings admission.Warnings
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason     string
+		policy     *policy.Policy
+		authorizer *ProviderConfigAuthorizer
+		obj        runtime.Object
+		want       want
+	}{
+		"NotScript": {
+			reason: "We should return an error if the object is not a Script.",
+			obj:    &fake.Managed{},
+			want:   want{err: errors.New(errNotScript)},
+		},
+		"Valid": {
+			reason: "A Script using its defined variables should be accepted.",
+			obj: script(apisv1alpha1.ScriptParameters{
+				Variables:         []apisv1alpha1.Variable{{Name: "PKG", Value: "nginx"}},
+				InitScript:        "sudo -n apt-get install -y {{PKG}}",
+				StatusCheckScript: "dpkg -s {{PKG}} || exit 100",
+				UpdateScript:      "apt-get install --only-upgrade -S {{PKG}}",
+				SudoEnabled:       &sudo,
+			}),
+		},
+		"UpdateWithoutStatusCheck": {
+			reason: "An updateScript without a statusCheckScript or upToDateScript is never executed.",
+			obj:    script(apisv1alpha1.ScriptParameters{InitScript: "touch /tmp/a", UpdateScript: "touch /tmp/a"}),
+			want:   want{err: invalid(field.Invalid(path.Child("updateScript"), "touch /tmp/a", errNoStatusCheck))},
+		},
+		"UpdateWithUpToDateScript": {
+			reason: "An upToDateScript detects the drift repaired by the updateScript.",
+			obj:    script(apisv1alpha1.ScriptParameters{UpToDateScript: "test -f /tmp/a", UpdateScript: "touch /tmp/a"}),
+		},
+		"NotPositiveInterval": {
+			reason: "A pollInterval that is not positive would stop the periodic reconciliation of the Script.",
+			obj:    script(apisv1alpha1.ScriptParameters{InitScript: "touch /tmp/a", PollInterval: &metav1.Duration{}}),
+			want:   want{err: invalid(field.Invalid(path.Child("pollInterval"), "0s", errNotPositive))},
+		},
+		"ContainerTarget": {
+			reason: "A container should not be combined with namespaces or a chroot of the host.",
+			obj: script(apisv1alpha1.ScriptParameters{
+				InitScript: "touch /tmp/a",
+				Container:  &apisv1alpha1.Container{Name: "gateway"},
+				Chroot:     "/sysroot",
+			}),
+			want: want{err: invalid(field.Invalid(path.Child("container"), "gateway", errContainerTarget))},
+		},
+		"MachineAndVirtualMachineInstance": {
+			reason: "A Script should target either a Machine or a VirtualMachineInstance.",
+			obj: script(apisv1alpha1.ScriptParameters{
+				InitScript:             "true",
+				Machine:                &apisv1alpha1.MachineTarget{Name: "edge-0", Namespace: "capi"},
+				VirtualMachineInstance: &apisv1alpha1.VirtualMachineInstanceTarget{Name: "web", Namespace: "vms"},
+			}),
+			want: want{err: invalid(field.Invalid(path.Child("machine"), "edge-0", errMachineTarget))},
+		},
+		"InvalidSkipIf": {
+			reason: "A skipIf that is not a valid expression should be rejected rather than fail on every host.",
+			obj:    script(apisv1alpha1.ScriptParameters{InitScript: "touch /tmp/a", SkipIf: `facts.os = "ubuntu"`}),
+			want:   want{err: invalid(field.Invalid(path.Child("skipIf"), `facts.os = "ubuntu"`, compileErr(`facts.os = "ubuntu"`)))},
+		},
+		"InvalidReadyWhen": {
+			reason: "A readyWhen that is not a valid expression should be rejected.",
+			obj:    script(apisv1alpha1.ScriptParameters{StatusCheckScript: "test -f /tmp/a", ReadyWhen: `exitCode == `}),
+			want:   want{err: invalid(field.Invalid(path.Child("readyWhen"), `exitCode == `, compileErr(`exitCode == `)))},
+		},
+		"WhenWithoutStatusCheck": {
+			reason: "An upToDateWhen is never evaluated without a statusCheckScript, or with an upToDateScript.",
+			obj:    script(apisv1alpha1.ScriptParameters{UpToDateScript: "test -f /tmp/a", UpToDateWhen: `exitCode == 0`}),
+			want:   want{err: invalid(field.Invalid(path.Child("upToDateWhen"), `exitCode == 0`, errWhenWithoutStatusCheck))},
+		},
+		"When": {
+			reason: "Expressions evaluated against the result of the statusCheckScript should be accepted.",
+			obj: script(apisv1alpha1.ScriptParameters{
+				Variables:         []apisv1alpha1.Variable{{Name: "VERSION", Value: "1.2.3"}},
+				StatusCheckScript: "echo version=$(app --version)",
+				UpToDateWhen:      `outputs.version == vars.VERSION`,
+				ReadyWhen:         `exitCode == 0`,
+			}),
+		},
+		"AlwaysWithoutStatusCheck": {
+			reason: "An updateScript executed on every status check should be accepted without a statusCheckScript.",
+			obj:    script(apisv1alpha1.ScriptParameters{UpdateScript: "sysctl -w vm.swappiness=10", UpdatePolicy: apisv1alpha1.UpdatePolicyAlways}),
+		},
+		"ReapplyWithoutUpdate": {
+			reason: "A reapplyInterval without an updateScript has nothing to reapply.",
+			obj:    script(apisv1alpha1.ScriptParameters{StatusCheckScript: "test -f /etc/app.conf", ReapplyInterval: &metav1.Duration{Duration: time.Hour}}),
+			want:   want{err: invalid(field.Invalid(path.Child("reapplyInterval"), "1h0m0s", errReapplyNoUpdate))},
+		},
+		"ReapplyNever": {
+			reason: "A reapplyInterval contradicts the Never updatePolicy.",
+			obj: script(apisv1alpha1.ScriptParameters{
+				StatusCheckScript: "test -f /etc/app.conf",
+				UpdateScript:      "install -m 644 app.conf /etc/app.conf",
+				UpdatePolicy:      apisv1alpha1.UpdatePolicyNever,
+				ReapplyInterval:   &metav1.Duration{Duration: time.Hour},
+			}),
+			want: want{err: invalid(field.Invalid(path.Child("reapplyInterval"), "1h0m0s", errReapplyNever))},
+		},
+		"PostRebootScriptWithoutUpdate": {
+			reason: "A postRebootScript is never executed unless the rebootPolicy is Update.",
+			obj:    script(apisv1alpha1.ScriptParameters{PostRebootScript: "systemctl restart app", RebootPolicy: apisv1alpha1.RebootPolicyRecord}),
+			want:   want{err: invalid(field.Invalid(path.Child("postRebootScript"), "systemctl restart app", errPostRebootScript))},
+		},
+		"RebootPolicyFanOut": {
+			reason: "Reboots are not detected for Scripts running on multiple hosts.",
+			obj: script(apisv1alpha1.ScriptParameters{
+				Hosts:        []apisv1alpha1.HostReference{{Kind: "Host", Name: "web-1"}},
+				RebootPolicy: apisv1alpha1.RebootPolicyRecord,
+			}),
+			want: want{err: invalid(field.Invalid(path.Child("rebootPolicy"), apisv1alpha1.RebootPolicyRecord, errRebootPolicyFanOut))},
+		},
+		"RebootPolicy": {
+			reason: "A postRebootScript executed after the host rebooted should be accepted.",
+			obj: script(apisv1alpha1.ScriptParameters{
+				StatusCheckScript: "systemctl is-active app",
+				PostRebootScript:  "systemctl restart app",
+				RebootPolicy:      apisv1alpha1.RebootPolicyUpdate,
+			}),
+		},
+		"TooLong": {
+			reason: "Scripts exceeding the size limit should be rejected.",
+			obj:    script(apisv1alpha1.ScriptParameters{InitScript: long}),
+			want:   want{err: invalid(field.TooLong(path.Child("initScript"), truncate(long), MaxScriptSize))},
+		},
+		"VariableName": {
+			reason: "Variables whose names cannot be referenced should be rejected.",
+			obj: script(apisv1alpha1.ScriptParameters{Variables: []apisv1alpha1.Variable{
+				{Name: "1ST", Value: "a"},
+				{Name: "NAME", Value: "b"},
+				{Name: "NAME", Value: "c"},
+			}}),
+			want: want{err: invalid(
+				field.Invalid(path.Child("variables").Index(0).Child("name"), "1ST", errVariableName),
+				field.Duplicate(path.Child("variables").Index(2).Child("name"), "NAME"),
+			)},
+		},
+		"SudoPassword": {
+			reason: "Scripts asking sudo for a password should be rejected.",
+			obj:    script(apisv1alpha1.ScriptParameters{CleanupScript: "echo secret | sudo -u root -S rm -rf /opt/app"}),
+			want: want{err: invalid(
+				field.Invalid(path.Child("cleanupScript"), "echo secret | sudo -u root -S rm -rf /opt/app", errSudoPassword),
+			)},
+		},
+		"DangerousCommand": {
+			reason: "Scripts running a command of the denylist once rendered should be rejected.",
+			policy: policy.New(policy.DefaultRules, nil),
+			obj: script(apisv1alpha1.ScriptParameters{
+				Variables:     []apisv1alpha1.Variable{{Name: "DISK", Value: "/dev/sdb"}},
+				InitScript:    "mkfs.ext4 {{DISK}}",
+				CleanupScript: "rm -rf /opt/app",
+			}),
+			want: want{err: invalid(field.Forbidden(path.Child("initScript"), "formats a filesystem (mkfs)"))},
+		},
+		"ProviderConfigNotAllowed": {
+			reason: "Scripts should be rejected if they are not allowed to use the ProviderConfigs of their hosts.",
+			authorizer: &ProviderConfigAuthorizer{Kube: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+				pc := obj.(*apisv1alpha1.ProviderConfig)
+				pc.SetName("team-a")
+				pc.Spec.AllowedNamespaces = []string{"team-a"}
+				return nil
+			}}},
+			obj: script(apisv1alpha1.ScriptParameters{
+				Hosts:      []apisv1alpha1.HostReference{{Kind: apisv1alpha1.ProviderConfigKind, Name: "team-a"}},
+				InitScript: "true",
+			}),
+			want: want{err: invalid(field.Forbidden(path.Child("hosts").Index(0).Child("name"),
+				"ProviderConfig team-a is restricted to the claims of namespaces team-a, the managed resource has no crossplane.io/claim-namespace label"))},
+		},
+		"DangerousCommandNoPolicy": {
+			reason: "Scripts should not be evaluated without a policy.",
+			obj:    script(apisv1alpha1.ScriptParameters{InitScript: "mkfs.ext4 /dev/sdb"}),
+		},
+		"UndefinedVariable": {
+			reason: "References to undefined variables should be reported as warnings.",
+			obj:    script(apisv1alpha1.ScriptParameters{InitScript: "echo {{B}} {{A}} {{B}}"}),
+			want: want{warnings: admission.Warnings{
+				"spec.forProvider.initScript references undefined variable A",
+				"spec.forProvider.initScript references undefined variable B",
+			}},
+		},
+		"Envsubst": {
+			reason: "References to undefined variables should not be reported with envsubst templating, the shell expands them.",
+			obj: script(apisv1alpha1.ScriptParameters{
+				Variables:  []apisv1alpha1.Variable{{Name: "PKG", Value: "nginx"}},
+				InitScript: "apt-get install -y $PKG && echo $HOME {{B}}",
+				Templating: apisv1alpha1.TemplatingEnvsubst,
+			}),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := &ScriptValidator{Policy: tc.policy, Authorizer: tc.authorizer}
+			warnings, err := v.ValidateCreate(context.Background(), tc.obj)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nv.ValidateCreate(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.warnings, warnings); diff != "" {
+				t.Errorf("\n%s\nv.ValidateCreate(...): -want warnings, +got warnings:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestValidateScriptUpdate(t *testing.T) {
+	path := field.NewPath("spec", "forProvider")
+	created := func(p apisv1alpha1.ScriptParameters) *apisv1alpha1.Script {
+		cr := script(p)
+		meta.SetExternalCreateSucceeded(cr, time.Now())
+		return cr
+	}
+	adopted := func(p apisv1alpha1.ScriptParameters) *apisv1alpha1.Script {
+		cr := script(p)
+		cr.Status.AtProvider.ScriptHash = "0123abcd"
+		return cr
+	}
+	withExternalName := func(cr *apisv1alpha1.Script, name string) *apisv1alpha1.Script {
+		meta.SetExternalName(cr, name)
+		return cr
+	}
+	hostA := &apisv1alpha1.Endpoint{Host: "10.0.0.1"}
+	hostB := &apisv1alpha1.Endpoint{Host: "10.0.0.2"}
+	vmA := &apisv1alpha1.VirtualMachineInstanceTarget{Name: "web-a", Namespace: "vms"}
+	vmB := &apisv1alpha1.VirtualMachineInstanceTarget{Name: "web-b", Namespace: "vms"}
+	machineA := &apisv1alpha1.MachineTarget{Name: "edge-0", Namespace: "capi"}
+	machineB := &apisv1alpha1.MachineTarget{Name: "edge-1", Namespace: "capi"}
+	withFinalizer := func(cr *apisv1alpha1.Script) *apisv1alpha1.Script {
+		meta.AddFinalizer(cr, "finalizer.managedresource.crossplane.io")
+		return cr
+	}
+	deleting := func(cr *apisv1alpha1.Script) *apisv1alpha1.Script {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+		return cr
+	}
+	withLabel := func(cr *apisv1alpha1.Script) *apisv1alpha1.Script {
+		cr.SetLabels(map[string]string{apisv1alpha1.LabelKeyClaimNamespace: "team-a"})
+		return cr
+	}
+	dangerous := apisv1alpha1.ScriptParameters{InitScript: "rm -rf /"}
+	denied := invalid(field.Forbidden(path.Child("initScript"), "deletes the root filesystem (rm-root)"))
+
+	cases := map[string]struct {
+		reason string
+		policy *policy.Policy
+		old    *apisv1alpha1.Script
+		obj    *apisv1alpha1.Script
+		want   error
+	}{
+		"NotCreated": {
+			reason: "The fields of a Script that was not created yet should be mutable.",
+			old:    script(apisv1alpha1.ScriptParameters{Endpoint: hostA, InitScript: "true"}),
+			obj: script(apisv1alpha1.ScriptParameters{
+				Endpoint:        hostB,
+				Username:        "admin",
+				ExecutionPolicy: apisv1alpha1.ExecutionPolicyRunOnce,
+				InitScript:      "true",
+			}),
+		},
+		"Scripts": {
+			reason: "The scripts of a created Script should be mutable.",
+			old:    created(apisv1alpha1.ScriptParameters{Endpoint: hostA, InitScript: "true"}),
+			obj:    script(apisv1alpha1.ScriptParameters{Endpoint: &apisv1alpha1.Endpoint{Host: "10.0.0.1"}, InitScript: "false"}),
+		},
+		"Endpoint": {
+			reason: "The endpoint and username of a created Script should be immutable.",
+			old:    created(apisv1alpha1.ScriptParameters{Endpoint: hostA, InitScript: "true"}),
+			obj:    script(apisv1alpha1.ScriptParameters{Endpoint: hostB, Username: "admin", InitScript: "true"}),
+			want: invalid(
+				field.Invalid(path.Child("endpoint"), hostB, errImmutable),
+				field.Invalid(path.Child("username"), "admin", errImmutable),
+			),
+		},
+		"VirtualMachineInstance": {
+			reason: "The VirtualMachineInstance targeted by a created Script should be immutable.",
+			old:    created(apisv1alpha1.ScriptParameters{VirtualMachineInstance: vmA, InitScript: "true"}),
+			obj:    script(apisv1alpha1.ScriptParameters{VirtualMachineInstance: vmB, InitScript: "true"}),
+			want:   invalid(field.Invalid(path.Child("virtualMachineInstance"), vmB, errImmutable)),
+		},
+		"Machine": {
+			reason: "The Machine targeted by a created Script should be immutable.",
+			old:    created(apisv1alpha1.ScriptParameters{Machine: machineA, InitScript: "true"}),
+			obj:    script(apisv1alpha1.ScriptParameters{Machine: machineB, InitScript: "true"}),
+			want:   invalid(field.Invalid(path.Child("machine"), machineB, errImmutable)),
+		},
+		"ExecutionPolicy": {
+			reason: "The executionPolicy of an adopted Script should be immutable.",
+			old:    adopted(apisv1alpha1.ScriptParameters{InitScript: "true"}),
+			obj:    script(apisv1alpha1.ScriptParameters{ExecutionPolicy: apisv1alpha1.ExecutionPolicyRunOnce, InitScript: "true"}),
+			want:   invalid(field.Invalid(path.Child("executionPolicy"), apisv1alpha1.ExecutionPolicyRunOnce, errImmutable)),
+		},
+		"ExternalName": {
+			reason: "The external name of a created Script, the path of its state marker, should be immutable.",
+			old:    withExternalName(created(apisv1alpha1.ScriptParameters{InitScript: "true"}), "/var/lib/provider-ssh/nginx.json"),
+			obj:    withExternalName(script(apisv1alpha1.ScriptParameters{InitScript: "true"}), "/var/lib/provider-ssh/app.json"),
+			want: invalid(field.Invalid(field.NewPath("metadata", "annotations").Key(meta.AnnotationKeyExternalName),
+				"/var/lib/provider-ssh/app.json", errImmutable)),
+		},
+		"RemoveFinalizer": {
+			reason: "The finalizer of a deleting Script should be removable even if the policy rejects its scripts since it was created.",
+			policy: policy.New(policy.DefaultRules, nil),
+			old:    withFinalizer(deleting(created(dangerous))),
+			obj:    deleting(created(dangerous)),
+		},
+		"Unchanged": {
+			reason: "Updates leaving the spec and labels of a Script unchanged should not be validated against the policy.",
+			policy: policy.New(policy.DefaultRules, nil),
+			old:    created(dangerous),
+			obj:    withFinalizer(created(dangerous)),
+		},
+		"LabelsChanged": {
+			reason: "Updates changing the labels of a Script should be validated against the policy.",
+			policy: policy.New(policy.DefaultRules, nil),
+			old:    created(dangerous),
+			obj:    withLabel(created(dangerous)),
+			want:   denied,
+		},
+		"SpecChanged": {
+			reason: "Updates changing the spec of a Script should be validated against the policy.",
+			policy: policy.New(policy.DefaultRules, nil),
+			old:    created(apisv1alpha1.ScriptParameters{InitScript: "true"}),
+			obj:    created(dangerous),
+			want:   denied,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := &ScriptValidator{Policy: tc.policy}
+			_, err := v.ValidateUpdate(context.Background(), tc.old, tc.obj)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nv.ValidateUpdate(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDefaultScript(t *testing.T) {
+	port, port22, custom, timeout, limit, missing := 2222, apisv1alpha1.DefaultPort, 23, int64(30), int64(0), 3
+	providerLimit := int64(1 << 10)
+	defaulted := func(p apisv1alpha1.ScriptParameters) *apisv1alpha1.Script {
+		p.Interpreter = apisv1alpha1.DefaultInterpreter
+		timeout, limit, missing := int64(apisv1alpha1.DefaultTimeoutSeconds), int64(apisv1alpha1.DefaultMaxOutputBytes), apisv1alpha1.DefaultExitCodeMissing
+		p.TimeoutSeconds = &timeout
+		p.MaxOutputBytes = &limit
+		p.ExitCodes = &apisv1alpha1.StatusCheckExitCodes{Missing: &missing, Failed: []int{apisv1alpha1.DefaultExitCodeFailed}}
+		return script(p)
+	}
+
+	type want struct {
+		obj runtime.Object
+		err error
+	}
+
+	cases := map[string]struct {
+		reason         string
+		maxOutputBytes int64
+		kube           client.Reader
+		obj            runtime.Object
+		want           want
+	}{
+		"NotScript": {
+			reason: "Objects that are not Scripts should be rejected.",
+			obj:    &fake.Managed{},
+			want:   want{obj: &fake.Managed{}, err: errors.New(errNotScript)},
+		},
+		"Empty": {
+			reason: "Every unset execution setting should be defaulted.",
+			obj:    script(apisv1alpha1.ScriptParameters{}),
+			want:   want{obj: defaulted(apisv1alpha1.ScriptParameters{})},
+		},
+		"ProviderMaxOutputBytes": {
+			reason:         "The maxOutputBytes should default to the one of the provider when it is set.",
+			maxOutputBytes: providerLimit,
+			obj:            script(apisv1alpha1.ScriptParameters{}),
+			want: want{obj: func() runtime.Object {
+				cr := defaulted(apisv1alpha1.ScriptParameters{})
+				cr.Spec.ForProvider.MaxOutputBytes = &providerLimit
+				return cr
+			}()},
+		},
+		"ProviderConfigDefaults": {
+			reason:         "The settings with a default in the ProviderConfig should be left unset, so that the Script follows the ProviderConfig.",
+			maxOutputBytes: providerLimit,
+			kube: &test.MockClient{MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+				obj.(*apisv1alpha1.ProviderConfig).Spec.Defaults = &apisv1alpha1.ScriptDefaults{
+					Interpreter:    "/bin/bash",
+					TimeoutSeconds: &timeout,
+					MaxOutputBytes: &limit,
+				}
+				return nil
+			}},
+			obj: withProviderConfig(script(apisv1alpha1.ScriptParameters{})),
+			want: want{obj: func() runtime.Object {
+				cr := withProviderConfig(defaulted(apisv1alpha1.ScriptParameters{}))
+				cr.Spec.ForProvider.Interpreter = ""
+				cr.Spec.ForProvider.TimeoutSeconds = nil
+				cr.Spec.ForProvider.MaxOutputBytes = nil
+				return cr
+			}()},
+		},
+		"ProviderConfigNotFound": {
+			reason: "Every unset execution setting should be defaulted if the ProviderConfig does not exist yet.",
+			kube:   &test.MockClient{MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "default"))},
+			obj:    withProviderConfig(script(apisv1alpha1.ScriptParameters{})),
+			want:   want{obj: withProviderConfig(defaulted(apisv1alpha1.ScriptParameters{}))},
+		},
+		"Endpoint": {
+			reason: "The port of an endpoint should default to 22.",
+			obj:    script(apisv1alpha1.ScriptParameters{Endpoint: &apisv1alpha1.Endpoint{Host: "10.0.0.1"}}),
+			want:   want{obj: defaulted(apisv1alpha1.ScriptParameters{Endpoint: &apisv1alpha1.Endpoint{Host: "10.0.0.1", Port: &port22}})},
+		},
+		"StrictMode": {
+			reason: "The preamble of a Script in strict mode should default to set -euo pipefail.",
+			obj:    script(apisv1alpha1.ScriptParameters{StrictMode: true}),
+			want: want{obj: defaulted(apisv1alpha1.ScriptParameters{
+				StrictMode:         true,
+				StrictModePreamble: apisv1alpha1.DefaultStrictModePreamble,
+			})},
+		},
+		"Set": {
+			reason: "Settings of the Script should not be overridden.",
+			obj: script(apisv1alpha1.ScriptParameters{
+				Endpoint:       &apisv1alpha1.Endpoint{Host: "10.0.0.1", Port: &port},
+				Interpreter:    "/bin/bash",
+				TimeoutSeconds: &timeout,
+				MaxOutputBytes: &limit,
+				ExitCodes:      &apisv1alpha1.StatusCheckExitCodes{Missing: &missing, Failed: []int{custom}},
+			}),
+			want: want{obj: script(apisv1alpha1.ScriptParameters{
+				Endpoint:       &apisv1alpha1.Endpoint{Host: "10.0.0.1", Port: &port},
+				Interpreter:    "/bin/bash",
+				TimeoutSeconds: &timeout,
+				MaxOutputBytes: &limit,
+				ExitCodes:      &apisv1alpha1.StatusCheckExitCodes{Missing: &missing, Failed: []int{custom}},
+			})},
+		},
+		"NoFailedExitCodes": {
+			reason: "An empty list of failed exit codes should be kept, so that no exit code requires user intervention.",
+			obj:    script(apisv1alpha1.ScriptParameters{ExitCodes: &apisv1alpha1.StatusCheckExitCodes{Missing: &missing, Failed: []int{}}}),
+			want: want{obj: func() runtime.Object {
+				cr := defaulted(apisv1alpha1.ScriptParameters{})
+				cr.Spec.ForProvider.ExitCodes = &apisv1alpha1.StatusCheckExitCodes{Missing: &missing, Failed: []int{}}
+				return cr
+			}()},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := &ScriptDefaulter{MaxOutputBytes: tc.maxOutputBytes, Kube: tc.kube}
+			err := d.Default(context.Background(), tc.obj)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nd.Default(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.obj, tc.obj); diff != "" {
+				t.Errorf("\n%s\nd.Default(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
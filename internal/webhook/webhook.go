@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook contains the admission webhooks of the SSH provider.
+package webhook
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	apisv1alpha1 "github.com/crossplane/provider-ssh/apis/v1alpha1"
+	"github.com/crossplane/provider-ssh/internal/controller/common"
+)
+
+// Setup registers the admission and conversion webhooks of the SSH provider
+// with the webhook server of the supplied manager. Objects of every served
+// version are converted through the v1alpha1 storage version.
+func Setup(mgr ctrl.Manager, o common.Options) error {
+	auth := &ProviderConfigAuthorizer{Kube: mgr.GetClient()}
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&apisv1alpha1.Script{}).
+		WithDefaulter(&ScriptDefaulter{MaxOutputBytes: o.MaxOutputBytes, Kube: mgr.GetClient()}).
+		WithValidator(&ScriptValidator{Policy: o.ScriptPolicy, Authorizer: auth}).
+		Complete(); err != nil {
+		return err
+	}
+	for _, mg := range []runtime.Object{
+		&apisv1alpha1.AuthorizedKey{},
+		&apisv1alpha1.Command{},
+		&apisv1alpha1.ConnectionCheck{},
+		&apisv1alpha1.HostKeyScan{},
+		&apisv1alpha1.KeyPair{},
+		&apisv1alpha1.NodeScript{},
+		&apisv1alpha1.Package{},
+		&apisv1alpha1.RemoteDirectory{},
+		&apisv1alpha1.RemoteFetch{},
+		&apisv1alpha1.RemoteFile{},
+		&apisv1alpha1.ReverseTunnel{},
+		&apisv1alpha1.ScriptSet{},
+		&apisv1alpha1.UserAccount{},
+	} {
+		if err := ctrl.NewWebhookManagedBy(mgr).
+			For(mg).
+			WithValidator(auth).
+			Complete(); err != nil {
+			return err
+		}
+	}
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&apisv1alpha1.ProviderConfig{}).
+		Complete()
+}